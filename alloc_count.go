@@ -0,0 +1,371 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// allocRunMarker starts one benchmark run's allocation log: the benchmark's name and iterations
+const allocRunMarker = "bjarne-alloc-run:"
+
+// allocSymbolsMarker starts the symbolized allocation call sites
+const allocSymbolsMarker = "bjarne-alloc-symbols:"
+
+// allocCounterFile is the allocation counter's name in the mounted source directory
+const allocCounterFile = "bjarne_alloc_count.c"
+
+// allocCounter is LD_PRELOADed into the benchmark. It counts every malloc, calloc, realloc and aligned
+// allocation (operator new calls malloc), keyed by the first three return addresses in the program,
+// and at exit writes "total N" and one "site COUNT ADDR..." line per call site to $BJARNE_ALLOC_LOG
+// Addresses are offsets into the PIE binary, minus one to point into the call
+const allocCounter = `#define _GNU_SOURCE
+#include <dlfcn.h>
+#include <errno.h>
+#include <execinfo.h>
+#include <fcntl.h>
+#include <link.h>
+#include <stdint.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <unistd.h>
+
+extern void* __libc_malloc(size_t);
+extern void* __libc_calloc(size_t, size_t);
+extern void* __libc_realloc(void*, size_t);
+extern void* __libc_memalign(size_t, size_t);
+
+#define BJARNE_SITES 4096
+#define BJARNE_FRAMES 3
+
+struct bjarne_site {
+    uintptr_t frames[BJARNE_FRAMES];
+    unsigned long count;
+    int state; /* 0 free, 1 being claimed, 2 in use */
+};
+
+static struct bjarne_site bjarne_sites[BJARNE_SITES];
+static unsigned long bjarne_total;
+static uintptr_t bjarne_base;
+static int bjarne_ready;
+static __thread int bjarne_in_hook;
+
+static int bjarne_first_object(struct dl_phdr_info* info, size_t size, void* data) {
+    (void)size;
+    *(uintptr_t*)data = info->dlpi_addr;
+    return 1;
+}
+
+static void bjarne_record(void) {
+    if (!bjarne_ready || bjarne_in_hook) return;
+    bjarne_in_hook = 1;
+    __atomic_add_fetch(&bjarne_total, 1ul, __ATOMIC_RELAXED);
+    void* stack[32];
+    int depth = backtrace(stack, 32);
+    uintptr_t frames[BJARNE_FRAMES] = {0};
+    int found = 0;
+    for (int i = 1; i < depth && found < BJARNE_FRAMES; i++) {
+        Dl_info info;
+        if (dladdr(stack[i], &info) && (uintptr_t)info.dli_fbase == bjarne_base) {
+            frames[found++] = (uintptr_t)stack[i] - bjarne_base - 1;
+        }
+    }
+    uintptr_t hash = frames[0] * 31 + frames[1] * 17 + frames[2];
+    for (unsigned i = 0; i < BJARNE_SITES; i++) {
+        struct bjarne_site* s = &bjarne_sites[(hash + i) % BJARNE_SITES];
+        int free_state = 0;
+        if (__atomic_compare_exchange_n(&s->state, &free_state, 1, 0, __ATOMIC_ACQ_REL, __ATOMIC_ACQUIRE)) {
+            memcpy(s->frames, frames, sizeof frames);
+            __atomic_store_n(&s->state, 2, __ATOMIC_RELEASE);
+        }
+        while (__atomic_load_n(&s->state, __ATOMIC_ACQUIRE) == 1) {
+        }
+        if (memcmp(s->frames, frames, sizeof frames) == 0) {
+            __atomic_add_fetch(&s->count, 1ul, __ATOMIC_RELAXED);
+            break;
+        }
+    }
+    bjarne_in_hook = 0;
+}
+
+__attribute__((constructor)) static void bjarne_start(void) {
+    dl_iterate_phdr(bjarne_first_object, &bjarne_base);
+    void* warm[1];
+    backtrace(warm, 1); /* Loads the unwinder now rather than inside a counted allocation */
+    bjarne_ready = 1;
+}
+
+__attribute__((destructor)) static void bjarne_report(void) {
+    bjarne_ready = 0;
+    const char* path = getenv("BJARNE_ALLOC_LOG");
+    int fd = path ? open(path, O_WRONLY | O_CREAT | O_TRUNC, 0644) : 2;
+    if (fd < 0) return;
+    char line[128];
+    int n = snprintf(line, sizeof line, "total %lu\n", bjarne_total);
+    if (write(fd, line, (size_t)n) < 0) return;
+    for (unsigned i = 0; i < BJARNE_SITES; i++) {
+        struct bjarne_site* s = &bjarne_sites[i];
+        if (s->state != 2 || s->frames[0] == 0) continue;
+        n = snprintf(line, sizeof line, "site %lu", s->count);
+        for (int f = 0; f < BJARNE_FRAMES && s->frames[f]; f++) {
+            n += snprintf(line + n, sizeof line - (size_t)n, " 0x%lx", (unsigned long)s->frames[f]);
+        }
+        line[n++] = '\n';
+        if (write(fd, line, (size_t)n) < 0) return;
+    }
+    if (path) close(fd);
+}
+
+void* malloc(size_t size) {
+    bjarne_record();
+    return __libc_malloc(size);
+}
+
+void* calloc(size_t count, size_t size) {
+    bjarne_record();
+    return __libc_calloc(count, size);
+}
+
+void* realloc(void* p, size_t size) {
+    bjarne_record();
+    return __libc_realloc(p, size);
+}
+
+void* aligned_alloc(size_t align, size_t size) {
+    bjarne_record();
+    return __libc_memalign(align, size);
+}
+
+void* memalign(size_t align, size_t size) {
+    bjarne_record();
+    return __libc_memalign(align, size);
+}
+
+int posix_memalign(void** p, size_t align, size_t size) {
+    bjarne_record();
+    *p = __libc_memalign(align, size);
+    return *p ? 0 : ENOMEM;
+}
+`
+
+// allocDiscriminatorPattern matches the discriminator addr2line appends to some locations
+var allocDiscriminatorPattern = regexp.MustCompile(`\s*\(discriminator \d+\)$`)
+
+// AllocSite is a call site whose allocations grow with the benchmark's iterations
+type AllocSite struct {
+	Frames       []string // "function /src/file.cpp:12", innermost first
+	PerIteration float64
+}
+
+// AllocBenchmark is one benchmark's allocations per iteration
+type AllocBenchmark struct {
+	Name         string
+	PerIteration float64
+	Sites        []AllocSite // Largest first
+}
+
+// allocRun is the counter's log of one run
+type allocRun struct {
+	total int
+	sites map[string]int // Space-separated addresses to count
+}
+
+// allocCountCommand builds the benchmark and the counter, then runs each benchmark for n and 2n
+// iterations under the counter: the difference leaves out setup and the framework's own allocations
+func (c *ContainerRuntime) allocCountCommand(filename string, n int) string {
+	build := c.cxx(c.stdFlag() + " -O2 -g -fno-omit-frame-pointer -fPIE -pie -o /tmp/alloc_bench /src/" + filename + " -lbenchmark -lpthread")
+	return fmt.Sprintf("%s && cc -shared -fPIC -O1 -o /tmp/bjarne_alloc.so /src/%s -ldl && "+
+		"names=$(/tmp/alloc_bench --benchmark_list_tests) || exit 1; : > /tmp/alloc_all.log; "+
+		"for b in $names; do f=$(printf '%%s' \"$b\" | sed 's/[][\\.*^$+?(){}|]/\\\\&/g'); for n in %d %d; do "+
+		"BJARNE_ALLOC_LOG=/tmp/alloc.log LD_PRELOAD=/tmp/bjarne_alloc.so /tmp/alloc_bench --benchmark_filter=\"^$f\\$\" --benchmark_min_time=${n}x > /tmp/alloc_run.log 2>&1 "+
+		"|| { echo \"benchmark $b failed:\"; cat /tmp/alloc_run.log; exit 1; }; "+
+		"echo \"%s $b $n\"; cat /tmp/alloc.log; cat /tmp/alloc.log >> /tmp/alloc_all.log; done; done; "+
+		"echo '%s'; A2L=$(command -v llvm-addr2line || command -v addr2line); [ -n \"$A2L\" ] || exit 0; "+
+		"for a in $(sed -n 's/^site [0-9]* //p' /tmp/alloc_all.log | tr ' ' '\\n' | sort -u); do "+
+		"$A2L -C -f -i -e /tmp/alloc_bench $a | paste -d' ' - - | sed \"s/^/$a /\"; done",
+		build, allocCounterFile, n, 2*n, allocRunMarker, allocSymbolsMarker)
+}
+
+// parseAllocCounts reads the runs of every benchmark from the alloc-count stage's output and
+// works out its allocations per iteration, and the call sites they come from, from runs of n and 2n iterations
+func parseAllocCounts(output string, n int) []AllocBenchmark {
+	var names []string
+	runs := make(map[string]map[int]*allocRun)
+	symbols := make(map[string][]string) // Address to its frames, inlined functions first
+	var current *allocRun
+	inSymbols := false
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		fields := strings.Fields(line)
+		switch {
+		case strings.HasPrefix(line, allocRunMarker) && len(fields) == 3:
+			iterations, _ := strconv.Atoi(fields[2])
+			if runs[fields[1]] == nil {
+				runs[fields[1]] = make(map[int]*allocRun)
+				names = append(names, fields[1])
+			}
+			current = &allocRun{sites: make(map[string]int)}
+			runs[fields[1]][iterations] = current
+		case line == allocSymbolsMarker:
+			inSymbols, current = true, nil
+		case inSymbols && len(fields) > 1:
+			frame := allocDiscriminatorPattern.ReplaceAllString(strings.TrimPrefix(line, fields[0]+" "), "")
+			symbols[fields[0]] = append(symbols[fields[0]], frame)
+		case current != nil && len(fields) == 2 && fields[0] == "total":
+			current.total, _ = strconv.Atoi(fields[1])
+		case current != nil && len(fields) > 2 && fields[0] == "site":
+			count, _ := strconv.Atoi(fields[1])
+			current.sites[strings.Join(fields[2:], " ")] = count
+		}
+	}
+
+	var benchmarks []AllocBenchmark
+	for _, name := range names {
+		low, high := runs[name][n], runs[name][2*n]
+		if low == nil || high == nil || n <= 0 {
+			continue
+		}
+		b := AllocBenchmark{Name: name, PerIteration: float64(high.total-low.total) / float64(n)}
+		for _, key := range sortedKeys(high.sites) {
+			if grown := high.sites[key] - low.sites[key]; grown > 0 {
+				site := AllocSite{PerIteration: float64(grown) / float64(n)}
+				for _, addr := range strings.Fields(key) {
+					if frames := symbols[addr]; len(frames) > 0 {
+						site.Frames = append(site.Frames, frames...)
+					} else {
+						site.Frames = append(site.Frames, addr)
+					}
+				}
+				b.Sites = append(b.Sites, site)
+			}
+		}
+		sort.SliceStable(b.Sites, func(i, j int) bool { return b.Sites[i].PerIteration > b.Sites[j].PerIteration })
+		benchmarks = append(benchmarks, b)
+	}
+	return benchmarks
+}
+
+// location is where the site is in the project: the innermost frame with a source line in /src,
+// past standard library code such as std::vector::push_back inlined into the program
+func (s AllocSite) location() (function, file string, line int) {
+	for _, frame := range s.Frames {
+		i := strings.LastIndex(frame, " ")
+		if i < 0 {
+			continue
+		}
+		path, lineText, ok := strings.Cut(frame[i+1:], ":")
+		if n, err := strconv.Atoi(lineText); ok && err == nil && n > 0 && strings.HasPrefix(path, "/src/") {
+			return frame[:i], path, n
+		}
+	}
+	if len(s.Frames) > 0 {
+		function, _, _ = strings.Cut(s.Frames[0], " /")
+	}
+	return function, "", 0
+}
+
+// allocCountFindings reports the benchmarks over the per-iteration budget with the call sites of their
+// allocations, and the others as within it
+func allocCountFindings(benchmarks []AllocBenchmark, maxPerIteration int) []string {
+	var lines []string
+	for _, b := range benchmarks {
+		if b.PerIteration <= float64(maxPerIteration) {
+			lines = append(lines, fmt.Sprintf("%s: %s allocation(s) per iteration, within the budget of %d", b.Name, formatPerIteration(b.PerIteration), maxPerIteration))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s: %s allocation(s) per iteration, over the budget of %d [alloc-budget]", LevelError, b.Name, formatPerIteration(b.PerIteration), maxPerIteration))
+		// Sites on one line, such as a new object and the buffer its constructor allocates, are reported together
+		type siteLine struct {
+			function, file string
+			line           int
+		}
+		var order []siteLine
+		perLine := make(map[siteLine]float64)
+		for _, s := range b.Sites {
+			var key siteLine
+			key.function, key.file, key.line = s.location()
+			if _, ok := perLine[key]; !ok {
+				order = append(order, key)
+			}
+			perLine[key] += s.PerIteration
+		}
+		for _, key := range order {
+			if key.file == "" {
+				lines = append(lines, fmt.Sprintf("%s: %s allocation(s) per iteration of %s in %s [hot-path-allocation]",
+					LevelError, formatPerIteration(perLine[key]), b.Name, key.function))
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("%s:%d:1: %s: %s allocation(s) per iteration of %s in %s; preallocate outside the loop, reuse buffers or use an arena [hot-path-allocation]",
+				key.file, key.line, LevelError, formatPerIteration(perLine[key]), b.Name, key.function))
+		}
+	}
+	return lines
+}
+
+// formatPerIteration prints a per-iteration count without trailing zeros
+func formatPerIteration(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// allocCountMetrics are the counts for the domain validation result
+func allocCountMetrics(benchmarks []AllocBenchmark, maxPerIteration, iterations int) map[string]interface{} {
+	worst, over, sites := 0.0, 0, 0
+	for _, b := range benchmarks {
+		worst = max(worst, b.PerIteration)
+		if b.PerIteration > float64(maxPerIteration) {
+			over++
+			sites += len(b.Sites)
+		}
+	}
+	return map[string]interface{}{
+		"max_per_iter":         maxPerIteration,
+		"iterations":           iterations,
+		"benchmarks":           len(benchmarks),
+		"over_budget":          over,
+		"worst_per_iteration":  worst,
+		"hot_allocation_sites": sites,
+	}
+}
+
+// runAllocCountValidator counts the allocations each Google Benchmark iteration makes and fails
+// when any benchmark makes more than max_per_iter, naming the call sites
+func (c *ContainerRuntime) runAllocCountValidator(ctx context.Context, tmpDir, code, filename, arg string) DomainValidationResult {
+	maxPerIteration, iterations := 0, 1000
+	if n, err := parseArg(arg, "max_per_iter"); err == nil {
+		maxPerIteration = n
+	}
+	if n, err := parseArg(arg, "iterations"); err == nil && n > 0 {
+		iterations = n
+	}
+	if !usesGoogleBenchmark(code) {
+		return DomainValidationResult{ValidatorID: ValidatorAllocCount, Success: true, Output: "No Google Benchmark code found - skipping"}
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, allocCounterFile), []byte(allocCounter), 0600); err != nil {
+		return DomainValidationResult{ValidatorID: ValidatorAllocCount, Output: fmt.Sprintf("failed to write allocation counter: %v", err)}
+	}
+
+	result := c.runValidationStage(ctx, tmpDir, "alloc-count", "sh", "-c", c.allocCountCommand(filename, iterations))
+	if !result.Success {
+		return DomainValidationResult{ValidatorID: ValidatorAllocCount, Output: result.Output + result.Error}
+	}
+	benchmarks := parseAllocCounts(result.Output, iterations)
+	if len(benchmarks) == 0 {
+		return DomainValidationResult{ValidatorID: ValidatorAllocCount, Success: true, Output: "No benchmark runs were counted - skipping"}
+	}
+	success := true
+	for _, b := range benchmarks {
+		success = success && b.PerIteration <= float64(maxPerIteration)
+	}
+	return DomainValidationResult{
+		ValidatorID: ValidatorAllocCount,
+		Success:     success,
+		Output:      strings.Join(allocCountFindings(benchmarks, maxPerIteration), "\n"),
+		Metrics:     allocCountMetrics(benchmarks, maxPerIteration, iterations),
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// allocOutput is the alloc-count stage's output for a benchmark that allocates a std::vector<int>(4)
+// with new on every iteration (two allocations), and one that allocates nothing
+const allocOutput = `bjarne-alloc-run: BM_Push 1000
+total 2002
+site 1000 0x13c9 0x1297 0x12d0
+site 1 0x1147 0x12d0
+site 1000 0x13e5 0x1297 0x12d0
+bjarne-alloc-run: BM_Push 2000
+total 4002
+site 2000 0x13c9 0x1297 0x12d0
+site 1 0x1147 0x12d0
+site 2000 0x13e5 0x1297 0x12d0
+bjarne-alloc-run: BM_Idle/8 1000
+total 2
+site 1 0x1147 0x12d0
+bjarne-alloc-run: BM_Idle/8 2000
+total 2
+site 1 0x1147 0x12d0
+bjarne-alloc-symbols:
+0x1147 main /src/bench.cpp:14
+0x1297 main /src/bench.cpp:21 (discriminator 1)
+0x12d0 _start ??:0
+0x13c9 push_many(long) /src/bench.cpp:9
+0x13e5 std::__new_allocator<int>::allocate(unsigned long, void const*) /usr/include/c++/12/bits/new_allocator.h:137
+0x13e5 std::vector<int, std::allocator<int> >::vector(unsigned long, std::allocator<int> const&) /usr/include/c++/12/bits/stl_vector.h:552
+0x13e5 push_many(long) /src/bench.cpp:9
+`
+
+func TestParseAllocCounts(t *testing.T) {
+	got := parseAllocCounts(allocOutput, 1000)
+	if len(got) != 2 || got[0].Name != "BM_Push" || got[0].PerIteration != 2 || got[1].Name != "BM_Idle/8" || got[1].PerIteration != 0 {
+		t.Fatalf("parseAllocCounts() = %+v; want BM_Push at 2 and BM_Idle/8 at 0 per iteration", got)
+	}
+	if len(got[0].Sites) != 2 || len(got[1].Sites) != 0 {
+		t.Fatalf("sites = %+v, %+v; want the two growing sites of BM_Push", got[0].Sites, got[1].Sites)
+	}
+	want := []string{
+		"push_many(long) /src/bench.cpp:9",
+		"main /src/bench.cpp:21",
+		"_start ??:0",
+	}
+	if !reflect.DeepEqual(got[0].Sites[0].Frames, want) {
+		t.Errorf("frames = %q, want %q", got[0].Sites[0].Frames, want)
+	}
+	if got := parseAllocCounts(allocOutput, 500); len(got) != 0 {
+		t.Errorf("parseAllocCounts() with other iterations = %+v, want none", got)
+	}
+}
+
+func TestAllocSiteLocation(t *testing.T) {
+	tests := []struct {
+		name     string
+		frames   []string
+		function string
+		file     string
+		line     int
+	}{
+		{"inlined library code", []string{"std::__new_allocator<int>::allocate(unsigned long, void const*) /usr/include/c++/12/bits/new_allocator.h:137", "push_many(long) /src/bench.cpp:9"}, "push_many(long)", "/src/bench.cpp", 9},
+		{"no source lines", []string{"std::vector<int, std::allocator<int> >::_M_realloc_insert(int&&) /usr/include/c++/12/bits/vector.tcc:439", "0x1234"}, "std::vector<int, std::allocator<int> >::_M_realloc_insert(int&&)", "", 0},
+		{"unsymbolized", []string{"0x13c9"}, "0x13c9", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			function, file, line := AllocSite{Frames: tt.frames}.location()
+			if function != tt.function || file != tt.file || line != tt.line {
+				t.Errorf("location() = %q, %q, %d; want %q, %q, %d", function, file, line, tt.function, tt.file, tt.line)
+			}
+		})
+	}
+}
+
+func TestAllocCountFindings(t *testing.T) {
+	benchmarks := parseAllocCounts(allocOutput, 1000)
+	got := allocCountFindings(benchmarks, 0)
+	want := []string{
+		"error: BM_Push: 2 allocation(s) per iteration, over the budget of 0 [alloc-budget]",
+		"/src/bench.cpp:9:1: error: 2 allocation(s) per iteration of BM_Push in push_many(long); preallocate outside the loop, reuse buffers or use an arena [hot-path-allocation]",
+		"BM_Idle/8: 0 allocation(s) per iteration, within the budget of 0",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("allocCountFindings() = %q, want %q", got, want)
+	}
+	if got := allocCountFindings(benchmarks, 2); len(got) != 2 || strings.Contains(strings.Join(got, "\n"), "[alloc-budget]") {
+		t.Errorf("allocCountFindings() within the budget = %q", got)
+	}
+
+	metrics := allocCountMetrics(benchmarks, 0, 1000)
+	if metrics["over_budget"] != 1 || metrics["worst_per_iteration"] != 2.0 || metrics["hot_allocation_sites"] != 2 {
+		t.Errorf("allocCountMetrics() = %v", metrics)
+	}
+}
+
+func TestAllocCountLoop(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs /bin/sh")
+	}
+	dir := t.TempDir()
+	// Stands in for the benchmark: lists two benchmarks, logs 3 allocations per iteration of BM_Push/8
+	// and fails unless the other's filter has its brackets escaped
+	program := filepath.Join(dir, "bench.sh")
+	stub := `case "$1" in
+--benchmark_list_tests) echo BM_Push/8; echo 'BM_Fill<float[4]>' ;;
+*) n=${2#--benchmark_min_time=}; n=${n%x}
+   case "$1" in
+   *Push*) printf 'total %d\nsite %d 0x10\n' $((3*n+1)) $((3*n)) > "$BJARNE_ALLOC_LOG" ;;
+   *'<float\[4\]>$') printf 'total 1\n' > "$BJARNE_ALLOC_LOG" ;;
+   *) exit 3 ;;
+   esac ;;
+esac
+`
+	if err := os.WriteFile(program, []byte(stub), 0700); err != nil { //nolint:gosec // the test runs it
+		t.Fatal(err)
+	}
+	c := &ContainerRuntime{}
+	script := c.allocCountCommand("bench.cpp", 10)
+	script = strings.Replace(script, c.cxx(c.stdFlag()+" -O2 -g -fno-omit-frame-pointer -fPIE -pie -o /tmp/alloc_bench /src/bench.cpp -lbenchmark -lpthread"), "true", 1)
+	script = strings.Replace(script, "cc -shared -fPIC -O1 -o /tmp/bjarne_alloc.so /src/"+allocCounterFile+" -ldl", "true", 1)
+	script = strings.ReplaceAll(script, "LD_PRELOAD=/tmp/bjarne_alloc.so ", "")
+	script = strings.ReplaceAll(script, "/tmp/", dir+"/")
+	script = strings.ReplaceAll(script, dir+"/alloc_bench ", "sh "+program+" ")
+	script = strings.ReplaceAll(script, "command -v llvm-addr2line || command -v addr2line", "true")
+	out, err := exec.Command("sh", "-c", script).CombinedOutput()
+	if err != nil {
+		t.Fatalf("alloc-count loop = %v, %q", err, out)
+	}
+	got := parseAllocCounts(string(out), 10)
+	if len(got) != 2 || got[0].Name != "BM_Push/8" || got[0].PerIteration != 3 || got[1].Name != "BM_Fill<float[4]>" || got[1].PerIteration != 0 {
+		t.Errorf("parseAllocCounts() = %+v from %q; want BM_Push/8 at 3 and BM_Fill<float[4]> at 0", got, out)
+	}
+}
+
+func TestRunAllocCountValidatorSkips(t *testing.T) {
+	c := &ContainerRuntime{}
+	result := c.runAllocCountValidator(context.Background(), t.TempDir(), "int main() {}", "main.cpp", "max_per_iter=0")
+	if !result.Success || result.ValidatorID != ValidatorAllocCount || !strings.Contains(result.Output, "skipping") {
+		t.Errorf("runAllocCountValidator() = %+v; want skipped without Google Benchmark code", result)
+	}
+}
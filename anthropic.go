@@ -19,15 +19,18 @@ type AnthropicClient struct {
 	apiKey       string
 	defaultModel string
 	httpClient   *http.Client
+	generation   GenerationSettings
 }
 
 // AnthropicRequest represents a request to the Anthropic Messages API
 type AnthropicRequest struct {
-	Model     string    `json:"model"`
-	MaxTokens int       `json:"max_tokens"`
-	System    string    `json:"system,omitempty"`
-	Messages  []Message `json:"messages"`
-	Stream    bool      `json:"stream,omitempty"`
+	Model       string          `json:"model"`
+	MaxTokens   int             `json:"max_tokens"`
+	System      string          `json:"system,omitempty"`
+	Messages    []ClaudeMessage `json:"messages"`
+	Stream      bool            `json:"stream,omitempty"`
+	Temperature *float64        `json:"temperature,omitempty"`
+	TopP        *float64        `json:"top_p,omitempty"`
 }
 
 // AnthropicResponse represents a response from the Anthropic Messages API
@@ -58,13 +61,24 @@ func NewAnthropicProvider(cfg *ProviderConfig) (LLMProvider, error) {
 		defaultModel = AnthropicModelMap[ModelSonnet]
 	}
 
+	httpClient, err := NewHTTPClient(endpointAnthropic, 0)
+	if err != nil {
+		return nil, err
+	}
+
 	return &AnthropicClient{
 		apiKey:       cfg.APIKey,
 		defaultModel: defaultModel,
-		httpClient:   &http.Client{},
+		httpClient:   httpClient,
+		generation:   cfg.Generation,
 	}, nil
 }
 
+// SetGeneration sets temperature and top-p for subsequent requests (Claude has no seed)
+func (c *AnthropicClient) SetGeneration(gen GenerationSettings) {
+	c.generation = gen
+}
+
 // Name returns the provider name
 func (c *AnthropicClient) Name() string {
 	return "Anthropic"
@@ -87,11 +101,14 @@ func (c *AnthropicClient) Generate(ctx context.Context, model, systemPrompt stri
 		model = c.MapModel(model)
 	}
 
+	temperature, topP := c.generation.claudeSampling()
 	req := AnthropicRequest{
-		Model:     model,
-		MaxTokens: maxTokens,
-		System:    systemPrompt,
-		Messages:  messages,
+		Model:       model,
+		MaxTokens:   maxTokens,
+		System:      systemPrompt,
+		Messages:    toClaudeMessages(messages),
+		Temperature: temperature,
+		TopP:        topP,
 	}
 
 	body, err := json.Marshal(req)
@@ -154,12 +171,15 @@ func (c *AnthropicClient) GenerateStreaming(ctx context.Context, model, systemPr
 		model = c.MapModel(model)
 	}
 
+	temperature, topP := c.generation.claudeSampling()
 	req := AnthropicRequest{
-		Model:     model,
-		MaxTokens: maxTokens,
-		System:    systemPrompt,
-		Messages:  messages,
-		Stream:    true,
+		Model:       model,
+		MaxTokens:   maxTokens,
+		System:      systemPrompt,
+		Messages:    toClaudeMessages(messages),
+		Stream:      true,
+		Temperature: temperature,
+		TopP:        topP,
 	}
 
 	body, err := json.Marshal(req)
@@ -189,7 +209,7 @@ func (c *AnthropicClient) GenerateStreaming(ctx context.Context, model, systemPr
 
 	// Process SSE stream
 	var fullText string
-	var outputTokens int
+	var inputTokens, outputTokens int
 
 	decoder := json.NewDecoder(resp.Body)
 	for {
@@ -203,6 +223,11 @@ func (c *AnthropicClient) GenerateStreaming(ctx context.Context, model, systemPr
 			Usage struct {
 				OutputTokens int `json:"output_tokens"`
 			} `json:"usage"`
+			Message struct {
+				Usage struct {
+					InputTokens int `json:"input_tokens"`
+				} `json:"usage"`
+			} `json:"message"`
 		}
 
 		if err := decoder.Decode(&event); err != nil {
@@ -221,6 +246,10 @@ func (c *AnthropicClient) GenerateStreaming(ctx context.Context, model, systemPr
 			}
 		}
 
+		if event.Type == "message_start" {
+			inputTokens = event.Message.Usage.InputTokens
+		}
+
 		if event.Type == "message_delta" && event.Usage.OutputTokens > 0 {
 			outputTokens = event.Usage.OutputTokens
 		}
@@ -230,8 +259,11 @@ func (c *AnthropicClient) GenerateStreaming(ctx context.Context, model, systemPr
 		}
 	}
 
-	return &GenerateResult{
+	result := &GenerateResult{
 		Text:         fullText,
+		InputTokens:  inputTokens,
 		OutputTokens: outputTokens,
-	}, nil
+	}
+	fillUsageEstimate(result, systemPrompt, messages)
+	return result, nil
 }
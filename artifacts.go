@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// projectArtifactsDir holds the binaries of the last validation that passed, relative to the project root
+var projectArtifactsDir = filepath.Join(".bjarne", "artifacts")
+
+// artifactsManifest records how the exported binaries were built
+const artifactsManifest = "build.json"
+
+// artifactProgram names the optimized binary; sanitizer builds add their gate, e.g. program.asan
+const artifactProgram = "program"
+
+// artifactCopyPattern matches the copy keepBinary adds to a build
+var artifactCopyPattern = regexp.MustCompile(` && cp /tmp/test /artifacts/\S+`)
+
+// artifactStaging collects the binaries one validation builds until every gate has passed
+type artifactStaging struct {
+	dir        string          // Host directory mounted writable at /artifacts
+	sanitizers bool            // Keep the sanitizer builds as well as the optimized one
+	builds     []ArtifactBuild // In build order
+}
+
+// ArtifactBuild is an exported binary and the command that built it in the validation container
+type ArtifactBuild struct {
+	File    string `json:"file"`
+	Stage   string `json:"stage"`
+	Command string `json:"command"`
+}
+
+// ArtifactManifest is .bjarne/artifacts/build.json
+type ArtifactManifest struct {
+	Image    string          `json:"image"`
+	Digest   string          `json:"digest,omitempty"`
+	Standard string          `json:"standard"`
+	Sources  []string        `json:"sources"`
+	Created  time.Time       `json:"created"`
+	Binaries []ArtifactBuild `json:"binaries"`
+}
+
+// WithArtifacts returns a copy of the runtime that keeps the binaries its gates build for ExportArtifacts
+// The caller must call DiscardArtifacts when done with it
+func (c *ContainerRuntime) WithArtifacts(settings ArtifactSettings) (*ContainerRuntime, error) {
+	dir, err := os.MkdirTemp("", "bjarne-artifacts-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create artifacts dir: %w", err)
+	}
+	withArtifacts := *c
+	withArtifacts.artifacts = &artifactStaging{dir: dir, sanitizers: settings.Sanitizers}
+	return &withArtifacts, nil
+}
+
+// DiscardArtifacts removes the binaries kept by a WithArtifacts runtime
+func (c *ContainerRuntime) DiscardArtifacts() {
+	if c.artifacts != nil {
+		_ = os.RemoveAll(c.artifacts.dir)
+	}
+}
+
+// keepBinary makes a stage's build also copy /tmp/test out of the container when the runtime keeps binaries
+func (c *ContainerRuntime) keepBinary(stage, build string) string {
+	if c.artifacts == nil || (stage != "run" && !c.artifacts.sanitizers) {
+		return build
+	}
+	file := artifactProgram
+	if stage != "run" {
+		file += "." + stage
+	}
+	c.artifacts.builds = append(c.artifacts.builds, ArtifactBuild{File: file, Stage: stage, Command: build})
+	return build + " && cp /tmp/test /artifacts/" + file
+}
+
+// withoutArtifactCopy removes keepBinary's copy from a stage's command; it does not change what the stage checks
+func withoutArtifactCopy(command []string) []string {
+	stripped := make([]string, len(command))
+	for i, arg := range command {
+		stripped[i] = artifactCopyPattern.ReplaceAllString(arg, "")
+	}
+	return stripped
+}
+
+// ExportArtifacts replaces root's .bjarne/artifacts with the kept binaries and a build.json recording
+// the image and commands that built them. It returns the exported file names
+func (c *ContainerRuntime) ExportArtifacts(ctx context.Context, root string, files []CodeFile) ([]string, error) {
+	if c.artifacts == nil {
+		return nil, nil
+	}
+	var builds []ArtifactBuild
+	for _, b := range c.artifacts.builds {
+		if _, err := os.Stat(filepath.Join(c.artifacts.dir, b.File)); err == nil {
+			builds = append(builds, b) // A skipped gate built nothing
+		}
+	}
+	if len(builds) == 0 {
+		return nil, nil
+	}
+
+	dest := filepath.Join(root, projectArtifactsDir)
+	if err := os.RemoveAll(dest); err != nil {
+		return nil, fmt.Errorf("failed to clear %s: %w", projectArtifactsDir, err)
+	}
+	if err := os.MkdirAll(dest, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", projectArtifactsDir, err)
+	}
+	manifest := ArtifactManifest{
+		Image:    c.imageName,
+		Digest:   c.GetLocalImageDigest(ctx),
+		Standard: strings.TrimPrefix(c.stdFlag(), "-std="),
+		Created:  time.Now().UTC(),
+		Binaries: builds,
+	}
+	for _, f := range files {
+		manifest.Sources = append(manifest.Sources, f.Filename)
+	}
+	var exported []string
+	for _, b := range builds {
+		data, err := os.ReadFile(filepath.Join(c.artifacts.dir, b.File))
+		if err != nil {
+			return exported, fmt.Errorf("failed to read %s: %w", b.File, err)
+		}
+		if err := os.WriteFile(filepath.Join(dest, b.File), data, 0700); err != nil { // Executable, for running locally
+			return exported, fmt.Errorf("failed to write %s: %w", b.File, err)
+		}
+		exported = append(exported, b.File)
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return exported, fmt.Errorf("failed to encode %s: %w", artifactsManifest, err)
+	}
+	if err := os.WriteFile(filepath.Join(dest, artifactsManifest), append(data, '\n'), 0600); err != nil {
+		return exported, fmt.Errorf("failed to write %s: %w", artifactsManifest, err)
+	}
+	return exported, nil
+}
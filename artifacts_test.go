@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestKeepBinary(t *testing.T) {
+	build := "clang++ -std=c++17 -O2 -g -o /tmp/test /src/code.cpp"
+	c := &ContainerRuntime{}
+	if got := c.keepBinary("run", build); got != build {
+		t.Errorf("keepBinary() without export = %q, want the build unchanged", got)
+	}
+
+	c.artifacts = &artifactStaging{}
+	if got := c.keepBinary("run", build); got != build+" && cp /tmp/test /artifacts/program" {
+		t.Errorf("keepBinary(run) = %q", got)
+	}
+	if got := c.keepBinary("asan", build); got != build {
+		t.Errorf("keepBinary(asan) without sanitizers = %q, want the build unchanged", got)
+	}
+	c.artifacts.sanitizers = true
+	if got := c.keepBinary("asan", build); !strings.HasSuffix(got, "cp /tmp/test /artifacts/program.asan") {
+		t.Errorf("keepBinary(asan) = %q", got)
+	}
+	if len(c.artifacts.builds) != 2 || c.artifacts.builds[0].Command != build {
+		t.Errorf("builds = %+v, want run and asan", c.artifacts.builds)
+	}
+}
+
+func TestExportArtifacts(t *testing.T) {
+	c, err := (&ContainerRuntime{imageName: "bjarne-validator:test"}).WithArtifacts(ArtifactSettings{Export: true, Sanitizers: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.DiscardArtifacts()
+	root := t.TempDir()
+	if files, err := c.ExportArtifacts(context.Background(), root, nil); err != nil || files != nil {
+		t.Fatalf("ExportArtifacts(nothing kept) = %v, %v", files, err)
+	}
+
+	c.keepBinary("asan", "clang++ -fsanitize=address")
+	c.keepBinary("tsan", "clang++ -fsanitize=thread") // Skipped: never copied out
+	c.keepBinary("run", "clang++ -O2")
+	for _, name := range []string{"program.asan", "program"} {
+		if err := os.WriteFile(filepath.Join(c.artifacts.dir, name), []byte("ELF"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dest := filepath.Join(root, projectArtifactsDir)
+	if err := os.MkdirAll(dest, 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dest, "program.msan"), []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := c.ExportArtifacts(context.Background(), root, []CodeFile{{Filename: "code.cpp"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(files, ",") != "program.asan,program" {
+		t.Errorf("ExportArtifacts() = %v, want program.asan and program", files)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "program.msan")); !os.IsNotExist(err) {
+		t.Error("ExportArtifacts() kept a binary from an earlier validation")
+	}
+	if info, err := os.Stat(filepath.Join(dest, "program")); err != nil || info.Mode().Perm()&0100 == 0 {
+		t.Errorf("exported program = %v, %v, want an executable", info, err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dest, artifactsManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest ArtifactManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Image != "bjarne-validator:test" || manifest.Standard != "c++17" || len(manifest.Binaries) != 2 || manifest.Binaries[1].Command != "clang++ -O2" {
+		t.Errorf("manifest = %+v", manifest)
+	}
+}
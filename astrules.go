@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// projectASTRulesDir holds the project's clang-query rules, relative to the project root
+var projectASTRulesDir = filepath.Join(".bjarne", "ast-rules")
+
+// astRulesDir is where the rules are written in the validation directory
+const astRulesDir = "bjarne_ast_rules"
+
+// astRuleMarker starts each rule's section of the gate's output
+const astRuleMarker = "bjarne: ast-rule:"
+
+// astRulesHint explains an ast-rules failure in the fix prompt
+const astRulesHint = "The project's rules (.bjarne/ast-rules) forbid these constructs. " +
+	"Change the code so the rule no longer matches; do not hide the construct behind a macro, cast or wrapper."
+
+// astRuleNamePattern limits rule names to ones safe as file names and check names
+var astRuleNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// astMatchCommandPattern matches a clang-query match command ("match" or its abbreviation "m")
+var astMatchCommandPattern = regexp.MustCompile(`(?m)^\s*(?:match|m)\s+\S`)
+
+// astBindingPattern matches a node clang-query reports with "set output diag"
+var astBindingPattern = regexp.MustCompile(`^(/src/[^:]+):(\d+):(\d+): note: "(\w+)" binds here$`)
+
+// astQueryErrorPattern matches clang-query's report of a matcher it cannot parse, e.g. "1:7: Matcher not found: fooCall"
+var astQueryErrorPattern = regexp.MustCompile(`^\d+:\d+: .+`)
+
+// ASTRule is a clang-query rule from .bjarne/ast-rules: every node its matchers find is a violation
+type ASTRule struct {
+	Name     string `json:"name"`               // The file name without .query; findings report it as their check
+	Message  string `json:"message"`            // The first comment line, e.g. "Never call Foo::bar before Foo::init"
+	Advisory bool   `json:"advisory,omitempty"` // "# severity: warning" reports findings without failing the gate
+	Script   string `json:"script"`             // clang-query commands: let, match and set
+}
+
+// LoadASTRules reads the project's rules (none if the directory does not exist)
+func LoadASTRules(root string) ([]ASTRule, error) {
+	dir := filepath.Join(root, projectASTRulesDir)
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", projectASTRulesDir, err)
+	}
+	var rules []ASTRule
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".query" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", e.Name(), err)
+		}
+		rule, err := parseASTRule(strings.TrimSuffix(e.Name(), ".query"), string(data))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Join(projectASTRulesDir, e.Name()), err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// parseASTRule reads a rule's message and severity from its leading comments
+func parseASTRule(name, script string) (ASTRule, error) {
+	if !astRuleNamePattern.MatchString(name) {
+		return ASTRule{}, fmt.Errorf("rule name %q may only use letters, digits, '.', '_' and '-'", name)
+	}
+	if !astMatchCommandPattern.MatchString(script) {
+		return ASTRule{}, fmt.Errorf("no match command")
+	}
+	rule := ASTRule{Name: name, Script: script}
+	for _, line := range strings.Split(script, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "#") {
+			continue
+		}
+		comment := strings.TrimSpace(strings.TrimPrefix(line, "#"))
+		if severity, ok := strings.CutPrefix(comment, "severity:"); ok {
+			switch strings.TrimSpace(severity) {
+			case "error":
+			case "warning":
+				rule.Advisory = true
+			default:
+				return ASTRule{}, fmt.Errorf("unknown severity %q (use error or warning)", strings.TrimSpace(severity))
+			}
+		} else if rule.Message == "" && comment != "" {
+			rule.Message = comment
+		}
+	}
+	if rule.Message == "" {
+		rule.Message = "matches project rule " + name
+	}
+	return rule, nil
+}
+
+// SetASTRules sets the project's clang-query rules
+func (c *ContainerRuntime) SetASTRules(rules []ASTRule) {
+	c.astRules = rules
+}
+
+// loadProjectASTRules gives the container the working directory's clang-query rules
+func loadProjectASTRules(container *ContainerRuntime) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+	rules, err := LoadASTRules(cwd)
+	if err != nil {
+		return err
+	}
+	container.SetASTRules(rules)
+	return nil
+}
+
+// astRulesGate runs the project's clang-query rules over the sources
+// It reports false when the project has no rules
+func (c *ContainerRuntime) astRulesGate(tmpDir string, sources []string, runStage func(stage string, command ...string) ValidationResult) (ValidationResult, bool, error) {
+	if len(c.astRules) == 0 {
+		return ValidationResult{}, false, nil
+	}
+	dir := filepath.Join(tmpDir, astRulesDir)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return ValidationResult{}, false, fmt.Errorf("failed to create %s: %w", astRulesDir, err)
+	}
+	for _, rule := range c.astRules {
+		if err := os.WriteFile(filepath.Join(dir, rule.Name+".query"), []byte(astRuleScript(rule)), 0600); err != nil {
+			return ValidationResult{}, false, fmt.Errorf("failed to write rule %s: %w", rule.Name, err)
+		}
+	}
+	return runStage("ast-rules", "sh", "-c", c.astRulesCommand(sources)), true, nil
+}
+
+// astRuleScript makes clang-query print each match as a located note; a rule that binds
+// names reports those nodes instead of the whole match
+func astRuleScript(rule ASTRule) string {
+	script := "set output diag\n"
+	if strings.Contains(rule.Script, ".bind(") {
+		script += "set bind-root false\n"
+	}
+	return script + rule.Script + "\n"
+}
+
+// astRulesCommand runs clang-query with every rule over the sources, each rule's output after astRuleMarker
+func (c *ContainerRuntime) astRulesCommand(sources []string) string {
+	compileArgs := append([]string{c.stdFlag(), "-I/src"}, c.compileFlags()...)
+	var sb strings.Builder
+	sb.WriteString("command -v clang-query >/dev/null 2>&1 || { echo 'clang-query not installed, skipping'; exit 0; }; ")
+	for _, rule := range c.astRules {
+		fmt.Fprintf(&sb, "echo '%s %s'; clang-query -f /src/%s/%s.query %s -- %s 2>&1; ",
+			astRuleMarker, rule.Name, astRulesDir, rule.Name, strings.Join(sources, " "), strings.Join(compileArgs, " "))
+	}
+	sb.WriteString("true")
+	return sb.String()
+}
+
+// applyASTRules turns clang-query's matches into findings: an error for each match of a rule,
+// or a warning for an advisory one. Only matches in the project's files count, and a rule
+// whose matcher does not parse fails the gate rather than silently enforcing nothing
+func (c *ContainerRuntime) applyASTRules(r ValidationResult) ValidationResult {
+	if r.Skipped || strings.Contains(r.Output, "clang-query not installed") {
+		return r
+	}
+	rules := make(map[string]ASTRule, len(c.astRules))
+	for _, rule := range c.astRules {
+		rules[rule.Name] = rule
+	}
+
+	var findings, broken []string
+	seen := make(map[string]bool)
+	var rule ASTRule
+	for _, line := range strings.Split(r.Output+"\n"+r.Error, "\n") {
+		line = strings.TrimSpace(line)
+		if name, ok := strings.CutPrefix(line, astRuleMarker+" "); ok {
+			rule = rules[name]
+			continue
+		}
+		if rule.Name == "" {
+			continue
+		}
+		if m := astBindingPattern.FindStringSubmatch(line); m != nil {
+			level := LevelError
+			if rule.Advisory {
+				level = LevelWarning
+			}
+			finding := fmt.Sprintf("%s:%s:%s: %s: %s [%s]", m[1], m[2], m[3], level, rule.Message, rule.Name)
+			if !seen[finding] {
+				seen[finding] = true
+				findings = append(findings, finding)
+			}
+		} else if astQueryErrorPattern.MatchString(line) {
+			broken = append(broken, fmt.Sprintf("%s%s.query: %s", filepath.ToSlash(projectASTRulesDir)+"/", rule.Name, line))
+		}
+	}
+	sort.Strings(findings)
+	if !r.Success && len(findings) == 0 && len(broken) == 0 {
+		return r // The container itself failed
+	}
+
+	report := strings.Join(append(findings, broken...), "\n")
+	failed := len(broken) > 0
+	for _, f := range findings {
+		failed = failed || strings.Contains(f, ": "+string(LevelError)+": ")
+	}
+	if failed {
+		r.Success, r.Output, r.Error = false, "", report
+	} else {
+		r.Success, r.Output, r.Error = true, report, ""
+	}
+	return r
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const barBeforeInitRule = `# Never call Foo::bar before Foo::init
+# severity: error
+match cxxMemberCallExpr(callee(cxxMethodDecl(hasName("bar"), ofClass(hasName("Foo")))))
+`
+
+func TestParseASTRule(t *testing.T) {
+	tests := []struct {
+		name         string
+		script       string
+		wantMessage  string
+		wantAdvisory bool
+		wantErr      string
+	}{
+		{"bar-before-init", barBeforeInitRule, "Never call Foo::bar before Foo::init", false, ""},
+		{"no-goto", "# severity: warning\n# Avoid goto\nm gotoStmt()\n", "Avoid goto", true, ""},
+		{"unnamed", "match gotoStmt()\n", "matches project rule unnamed", false, ""},
+		{"no-match", "# Nothing\nlet x gotoStmt()\n", "", false, "no match command"},
+		{"bad-severity", "# severity: fatal\nmatch gotoStmt()\n", "", false, "unknown severity"},
+		{"bad name", "match gotoStmt()\n", "", false, "may only use"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := parseASTRule(tt.name, tt.script)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseASTRule() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rule.Message != tt.wantMessage || rule.Advisory != tt.wantAdvisory {
+				t.Errorf("parseASTRule() = %+v, want message %q, advisory %v", rule, tt.wantMessage, tt.wantAdvisory)
+			}
+		})
+	}
+}
+
+func TestLoadASTRules(t *testing.T) {
+	root := t.TempDir()
+	if rules, err := LoadASTRules(root); err != nil || rules != nil {
+		t.Fatalf("LoadASTRules(no directory) = %v, %v", rules, err)
+	}
+	dir := filepath.Join(root, projectASTRulesDir)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"bar-before-init.query": barBeforeInitRule,
+		"README.md":             "not a rule",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rules, err := LoadASTRules(root)
+	if err != nil || len(rules) != 1 || rules[0].Name != "bar-before-init" {
+		t.Fatalf("LoadASTRules() = %+v, %v", rules, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "broken.query"), []byte("# No matcher\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadASTRules(root); err == nil || !strings.Contains(err.Error(), "broken.query") {
+		t.Errorf("LoadASTRules(broken rule) error = %v, want the file named", err)
+	}
+}
+
+func TestASTRuleScript(t *testing.T) {
+	c := &ContainerRuntime{astRules: []ASTRule{{Name: "bar-before-init", Script: barBeforeInitRule}}}
+	got := c.astRulesCommand([]string{"/src/main.cpp", "/src/foo.cpp"})
+	for _, want := range []string{"echo '" + astRuleMarker + " bar-before-init'", "clang-query -f /src/" + astRulesDir + "/bar-before-init.query /src/main.cpp /src/foo.cpp -- -std=c++17 -I/src"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("astRulesCommand() = %q, missing %q", got, want)
+		}
+	}
+	if script := astRuleScript(c.astRules[0]); !strings.HasPrefix(script, "set output diag\n#") {
+		t.Errorf("astRuleScript() = %q", script)
+	}
+	if script := astRuleScript(ASTRule{Script: `match callExpr().bind("call")`}); !strings.Contains(script, "set bind-root false") {
+		t.Errorf("astRuleScript(bind) = %q, want bind-root off", script)
+	}
+}
+
+func TestApplyASTRules(t *testing.T) {
+	c := &ContainerRuntime{astRules: []ASTRule{
+		{Name: "bar-before-init", Message: "Never call Foo::bar before Foo::init"},
+		{Name: "no-goto", Message: "Avoid goto", Advisory: true},
+	}}
+	output := astRuleMarker + " bar-before-init\n\nMatch #1:\n\n" +
+		"/src/main.cpp:12:5: note: \"root\" binds here\n    f.bar();\n    ^~~~~~~\n" +
+		"/usr/include/c++/v1/foo.h:3:1: note: \"root\" binds here\n" +
+		"/src/main.cpp:12:5: note: \"root\" binds here\n2 matches.\n" +
+		astRuleMarker + " no-goto\n\nMatch #1:\n\n/src/main.cpp:20:3: note: \"root\" binds here\n1 match.\n"
+
+	r := c.applyASTRules(ValidationResult{Stage: "ast-rules", Success: true, Output: output})
+	if r.Success {
+		t.Fatalf("applyASTRules() passed with a violation: %+v", r)
+	}
+	want := "/src/main.cpp:12:5: error: Never call Foo::bar before Foo::init [bar-before-init]\n" +
+		"/src/main.cpp:20:3: warning: Avoid goto [no-goto]"
+	if r.Error != want {
+		t.Errorf("applyASTRules().Error = %q, want %q", r.Error, want)
+	}
+	if diags := stageDiagnostics(r); len(diags) != 2 || diags[0].Check != "bar-before-init" || diags[0].Line != 12 {
+		t.Errorf("stageDiagnostics() = %+v", diags)
+	}
+
+	advisory := astRuleMarker + " no-goto\n/src/main.cpp:20:3: note: \"root\" binds here\n"
+	if r := c.applyASTRules(ValidationResult{Stage: "ast-rules", Success: true, Output: advisory}); !r.Success || !strings.Contains(r.Output, "warning: Avoid goto") {
+		t.Errorf("applyASTRules(advisory) = %+v, want a pass with the warning", r)
+	}
+
+	broken := astRuleMarker + " bar-before-init\n1:7: Matcher not found: cxxMemberCal\n"
+	if r := c.applyASTRules(ValidationResult{Stage: "ast-rules", Success: true, Output: broken}); r.Success || !strings.Contains(r.Error, "bar-before-init.query: 1:7: Matcher not found") {
+		t.Errorf("applyASTRules(broken) = %+v, want a failure naming the rule", r)
+	}
+
+	if r := c.applyASTRules(ValidationResult{Stage: "ast-rules", Success: true, Output: astRuleMarker + " no-goto\n0 matches.\n"}); !r.Success {
+		t.Errorf("applyASTRules(no matches) = %+v, want a pass", r)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// maxAttachmentChars caps how much of one @file is inlined (~3000 tokens)
+const maxAttachmentChars = 12000
+
+// maxAttachments caps the number of @file mentions expanded per prompt
+const maxAttachments = 8
+
+// attachmentPattern matches @path mentions at the start of the prompt or after whitespace
+var attachmentPattern = regexp.MustCompile(`(?:^|\s)@([^\s@]+)`)
+
+// Attachment is a file referenced with @path in a prompt
+type Attachment struct {
+	Path      string
+	Lines     int
+	Truncated bool
+}
+
+// ExpandAttachments appends the contents of @path mentions in prompt as <attached_file> blocks
+// Paths are relative to baseDir; mentions that aren't readable files are reported in errs and left as-is
+func ExpandAttachments(prompt, baseDir string) (string, []Attachment, []error) {
+	var attached []Attachment
+	var errs []error
+	var blocks strings.Builder
+	seen := make(map[string]bool)
+
+	for _, match := range attachmentPattern.FindAllStringSubmatch(prompt, -1) {
+		name, path, ok := resolveAttachment(match[1], baseDir)
+		if !ok {
+			errs = append(errs, fmt.Errorf("@%s: file not found", match[1]))
+			continue
+		}
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+		if len(attached) == maxAttachments {
+			errs = append(errs, fmt.Errorf("@%s: too many attachments (max %d)", name, maxAttachments))
+			continue
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("@%s: %w", name, err))
+			continue
+		}
+
+		content, truncated := attachmentContent(name, string(data))
+		attached = append(attached, Attachment{
+			Path:      name,
+			Lines:     strings.Count(string(data), "\n") + 1,
+			Truncated: truncated,
+		})
+
+		blocks.WriteString(fmt.Sprintf("\n\n<attached_file path=%q>\n", name))
+		blocks.WriteString(content)
+		if !strings.HasSuffix(content, "\n") {
+			blocks.WriteString("\n")
+		}
+		blocks.WriteString("</attached_file>")
+	}
+
+	if len(attached) == 0 {
+		return prompt, nil, errs
+	}
+	return prompt + blocks.String(), attached, errs
+}
+
+// resolveAttachment finds the file for an @mention, tolerating trailing punctuation ("see @a.h.")
+// Returns the mention without that punctuation and the file path
+func resolveAttachment(mention, baseDir string) (string, string, bool) {
+	for candidate := mention; candidate != ""; candidate = candidate[:len(candidate)-1] {
+		path := candidate
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			return candidate, path, true
+		}
+		if !strings.ContainsAny(candidate[len(candidate)-1:], ".,;:!?)'\"") {
+			break
+		}
+	}
+	return "", "", false
+}
+
+// attachmentContent returns the text to inline for a file
+// Oversized files are cut at a line boundary and followed by an outline of the remaining declarations
+func attachmentContent(name, text string) (string, bool) {
+	if len(text) <= maxAttachmentChars {
+		return text, false
+	}
+
+	head := text[:maxAttachmentChars]
+	if i := strings.LastIndex(head, "\n"); i > 0 {
+		head = head[:i+1]
+	}
+	cutLine := strings.Count(head, "\n")
+
+	var sb strings.Builder
+	sb.WriteString(head)
+	sb.WriteString(fmt.Sprintf("// ... %s truncated after line %d\n", name, cutLine))
+
+	idx := parseSourceContent(text)
+	var outline []string
+	for _, c := range idx.Classes {
+		if c.Line > cutLine {
+			outline = append(outline, fmt.Sprintf("//   line %d: class %s", c.Line, c.Name))
+		}
+	}
+	for _, s := range idx.Structs {
+		if s.Line > cutLine {
+			outline = append(outline, fmt.Sprintf("//   line %d: struct %s", s.Line, s.Name))
+		}
+	}
+	for _, f := range idx.Functions {
+		if f.Line > cutLine {
+			outline = append(outline, fmt.Sprintf("//   line %d: %s", f.Line, f.Signature))
+		}
+	}
+	if len(outline) > 0 {
+		sb.WriteString("// Declarations in the rest of the file:\n")
+		sb.WriteString(strings.Join(outline, "\n"))
+		sb.WriteString("\n")
+	}
+	return sb.String(), true
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExpandAttachments(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "widget.h"), []byte("class Widget {\npublic:\n    virtual void draw() = 0;\n};\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	prompt := "implement the interface in @widget.h. Mail me@example.com, ignore @missing.h"
+	expanded, attached, errs := ExpandAttachments(prompt, dir)
+
+	if len(attached) != 1 || attached[0].Path != "widget.h" {
+		t.Fatalf("attached = %+v, want widget.h", attached)
+	}
+	if !strings.HasPrefix(expanded, prompt) {
+		t.Error("original prompt should be kept")
+	}
+	if !strings.Contains(expanded, "virtual void draw() = 0;") || !strings.Contains(expanded, "</attached_file>") {
+		t.Errorf("file content not inlined:\n%s", expanded)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "missing.h") {
+		t.Errorf("errs = %v, want one error for missing.h", errs)
+	}
+}
+
+func TestAttachmentContentTruncates(t *testing.T) {
+	var sb strings.Builder
+	for sb.Len() < maxAttachmentChars {
+		sb.WriteString("// filler line\n")
+	}
+	sb.WriteString("int compute_total(int a, int b) {\n    return a + b;\n}\n")
+
+	content, truncated := attachmentContent("big.cpp", sb.String())
+	if !truncated {
+		t.Fatal("expected oversized file to be truncated")
+	}
+	if len(content) > maxAttachmentChars+500 {
+		t.Errorf("truncated content too long: %d chars", len(content))
+	}
+	if !strings.Contains(content, "compute_total") {
+		t.Errorf("outline should list declarations after the cut:\n%s", content[len(content)-300:])
+	}
+}
@@ -0,0 +1,541 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// In-toto statement and DSSE envelope identifiers
+const (
+	inTotoStatementType     = "https://in-toto.io/Statement/v1"
+	inTotoPayloadType       = "application/vnd.in-toto+json"
+	validationPredicateType = "https://github.com/3rg0n/bjarne/validation/v1"
+	bjarneBuilderID         = "https://github.com/3rg0n/bjarne"
+)
+
+// attestationSuffix replaces a manifest's .json in the name of its attestation
+const attestationSuffix = ".intoto.json"
+
+// sigstoreBundleSuffix is added to an attestation's name for its cosign bundle
+const sigstoreBundleSuffix = ".sigstore.json"
+
+// attestUsage is printed for bad `bjarne attest` arguments
+const attestUsage = `Usage:
+  bjarne attest <manifest.json> [--key <private.pem> | --sigstore] [-o <file>]
+  bjarne attest keygen <name>      Write <name>.pem (private) and <name>.pub.pem
+  bjarne attest verify <attestation> [--key <public.pem> | --sigstore <identity> --issuer <url> | --allow-unsigned] [files...]`
+
+// InTotoStatement is an in-toto v1 statement: the validated files and how they were validated
+type InTotoStatement struct {
+	Type          string               `json:"_type"`
+	Subject       []ResourceDescriptor `json:"subject"`
+	PredicateType string               `json:"predicateType"`
+	Predicate     ValidationPredicate  `json:"predicate"`
+}
+
+// ResourceDescriptor names an artifact by its digests
+type ResourceDescriptor struct {
+	Name   string            `json:"name,omitempty"`
+	URI    string            `json:"uri,omitempty"`
+	Digest map[string]string `json:"digest"`
+}
+
+// ValidationPredicate is what bjarne attests about the subject: who generated it and which gates it passed where
+type ValidationPredicate struct {
+	Builder     AttestationBuilder   `json:"builder"`
+	Invocation  string               `json:"invocation"` // The manifest ID, for bjarne reproduce
+	ValidatedOn time.Time            `json:"validatedOn"`
+	Image       ResourceDescriptor   `json:"image"`
+	Compiler    string               `json:"compiler,omitempty"`
+	Standard    string               `json:"standard"`
+	Generator   AttestationGenerator `json:"generator"`
+	Gates       []AttestationGate    `json:"gates"`
+}
+
+// AttestationBuilder identifies the bjarne that validated the code
+type AttestationBuilder struct {
+	ID      string `json:"id"`
+	Version string `json:"version"`
+}
+
+// AttestationGenerator is the LLM side of the code's origin
+type AttestationGenerator struct {
+	Provider    string            `json:"provider,omitempty"`
+	Models      map[string]string `json:"models,omitempty"`
+	PromptsHash string            `json:"promptsHash,omitempty"`
+}
+
+// AttestationGate is one gate and its result: PASS, FAIL or SKIP
+type AttestationGate struct {
+	Name   string `json:"name"`
+	Result string `json:"result"`
+}
+
+// DSSEEnvelope carries a statement and its signatures (unsigned when Signatures is empty)
+type DSSEEnvelope struct {
+	PayloadType string          `json:"payloadType"`
+	Payload     string          `json:"payload"` // Base64 of the statement
+	Signatures  []DSSESignature `json:"signatures"`
+}
+
+// DSSESignature is an Ed25519 signature over the envelope's pre-authentication encoding
+type DSSESignature struct {
+	KeyID string `json:"keyid"`
+	Sig   string `json:"sig"`
+}
+
+// NewAttestation builds the statement for a validation manifest with the given ID
+func NewAttestation(m *ValidationManifest, id string) InTotoStatement {
+	p := ValidationPredicate{
+		Builder:     AttestationBuilder{ID: bjarneBuilderID, Version: m.Bjarne},
+		Invocation:  id,
+		ValidatedOn: m.Created,
+		Image:       ResourceDescriptor{URI: imageRepo(m.Image), Digest: map[string]string{}},
+		Compiler:    m.Compiler,
+		Standard:    m.Config.Standard,
+		Generator:   AttestationGenerator{Provider: m.Provider, Models: m.Models, PromptsHash: m.PromptsHash},
+	}
+	if algo, digest, ok := strings.Cut(m.Digest, ":"); ok {
+		p.Image.Digest[algo] = digest
+	}
+	for _, s := range m.Stages {
+		p.Gates = append(p.Gates, AttestationGate{Name: s.Stage, Result: stageStatus(s.Success, s.Skipped)})
+	}
+	st := InTotoStatement{Type: inTotoStatementType, PredicateType: validationPredicateType, Predicate: p}
+	for _, f := range m.Files {
+		st.Subject = append(st.Subject, ResourceDescriptor{Name: f.Name, Digest: map[string]string{"sha256": f.SHA256}})
+	}
+	return st
+}
+
+// dssePAE is DSSE's pre-authentication encoding, the bytes a signature covers
+func dssePAE(payloadType string, payload []byte) []byte {
+	return []byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload))
+}
+
+// keyID identifies a public key by the SHA-256 of its DER encoding
+func keyID(pub ed25519.PublicKey) string {
+	der, _ := x509.MarshalPKIXPublicKey(pub)
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:])
+}
+
+// NewEnvelope wraps a statement, signed with key unless it is nil
+func NewEnvelope(st InTotoStatement, key ed25519.PrivateKey) (DSSEEnvelope, error) {
+	payload, err := json.Marshal(st)
+	if err != nil {
+		return DSSEEnvelope{}, fmt.Errorf("failed to encode statement: %w", err)
+	}
+	env := DSSEEnvelope{PayloadType: inTotoPayloadType, Payload: base64.StdEncoding.EncodeToString(payload), Signatures: []DSSESignature{}}
+	if key != nil {
+		sig := ed25519.Sign(key, dssePAE(inTotoPayloadType, payload))
+		env.Signatures = append(env.Signatures, DSSESignature{
+			KeyID: keyID(key.Public().(ed25519.PublicKey)),
+			Sig:   base64.StdEncoding.EncodeToString(sig),
+		})
+	}
+	return env, nil
+}
+
+// Statement decodes the envelope's payload, checking it is a bjarne validation statement
+func (e DSSEEnvelope) Statement() (InTotoStatement, error) {
+	var st InTotoStatement
+	if e.PayloadType != inTotoPayloadType {
+		return st, fmt.Errorf("payload type %q is not an in-toto statement", e.PayloadType)
+	}
+	payload, err := base64.StdEncoding.DecodeString(e.Payload)
+	if err != nil {
+		return st, fmt.Errorf("invalid payload: %w", err)
+	}
+	if err := json.Unmarshal(payload, &st); err != nil {
+		return st, fmt.Errorf("invalid statement: %w", err)
+	}
+	if st.Type != inTotoStatementType || st.PredicateType != validationPredicateType {
+		return st, fmt.Errorf("not a bjarne validation attestation (%s)", st.PredicateType)
+	}
+	return st, nil
+}
+
+// Verify checks that pub signed the envelope
+func (e DSSEEnvelope) Verify(pub ed25519.PublicKey) error {
+	payload, err := base64.StdEncoding.DecodeString(e.Payload)
+	if err != nil {
+		return fmt.Errorf("invalid payload: %w", err)
+	}
+	id := keyID(pub)
+	for _, s := range e.Signatures {
+		if s.KeyID != "" && s.KeyID != id {
+			continue
+		}
+		sig, err := base64.StdEncoding.DecodeString(s.Sig)
+		if err == nil && ed25519.Verify(pub, dssePAE(e.PayloadType, payload), sig) {
+			return nil
+		}
+	}
+	if len(e.Signatures) == 0 {
+		return fmt.Errorf("the attestation is not signed")
+	}
+	return fmt.Errorf("no valid signature by key %s", shortHash(id))
+}
+
+// verifySubjects reports which subject each file's content matches, or an error for a file matching none
+func verifySubjects(st InTotoStatement, files map[string]string) ([]string, error) {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	var lines []string
+	for _, path := range paths {
+		sum := sha256Hex(files[path])
+		match := ""
+		for _, s := range st.Subject {
+			if s.Digest["sha256"] == sum {
+				match = s.Name
+				break
+			}
+		}
+		if match == "" {
+			return lines, fmt.Errorf("%s does not match any validated file", path)
+		}
+		lines = append(lines, fmt.Sprintf("%s matches %s", path, match))
+	}
+	return lines, nil
+}
+
+// GenerateSigningKey writes an Ed25519 key pair to <name>.pem and <name>.pub.pem
+func GenerateSigningKey(name string) error {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate key: %w", err)
+	}
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return fmt.Errorf("failed to encode private key: %w", err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return fmt.Errorf("failed to encode public key: %w", err)
+	}
+	if _, err := os.Stat(name + ".pem"); err == nil {
+		return fmt.Errorf("%s.pem already exists", name)
+	}
+	if err := os.WriteFile(name+".pem", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}), 0600); err != nil {
+		return fmt.Errorf("failed to write private key: %w", err)
+	}
+	if err := os.WriteFile(name+".pub.pem", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0600); err != nil {
+		return fmt.Errorf("failed to write public key: %w", err)
+	}
+	return nil
+}
+
+// readPEM returns the DER bytes of the first PEM block of the given type in path
+func readPEM(path, blockType string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != blockType {
+		return nil, fmt.Errorf("%s is not a PEM %s", path, blockType)
+	}
+	return block.Bytes, nil
+}
+
+// LoadSigningKey reads an Ed25519 private key in PKCS#8 PEM
+func LoadSigningKey(path string) (ed25519.PrivateKey, error) {
+	der, err := readPEM(path, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key %s: %w", path, err)
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an Ed25519 key", path)
+	}
+	return priv, nil
+}
+
+// LoadVerifyKey reads an Ed25519 public key in PKIX PEM
+func LoadVerifyKey(path string) (ed25519.PublicKey, error) {
+	der, err := readPEM(path, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key %s: %w", path, err)
+	}
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an Ed25519 key", path)
+	}
+	return pub, nil
+}
+
+// attestationPath is where a manifest's attestation goes by default
+func attestationPath(manifestPath string) string {
+	return strings.TrimSuffix(manifestPath, ".json") + attestationSuffix
+}
+
+// WriteAttestation writes the attestation of the manifest at manifestPath to out, signed with the
+// private key at keyPath if given, then with sigstore (cosign sign-blob) if asked
+func WriteAttestation(manifestPath string, m *ValidationManifest, out, keyPath string, sigstore bool) error {
+	var key ed25519.PrivateKey
+	if keyPath != "" {
+		var err error
+		if key, err = LoadSigningKey(keyPath); err != nil {
+			return err
+		}
+	}
+	id := strings.TrimSuffix(filepath.Base(manifestPath), ".json")
+	env, err := NewEnvelope(NewAttestation(m, id), key)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(env, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode attestation: %w", err)
+	}
+	if err := writeFileAtomic(out, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write attestation: %w", err)
+	}
+	if sigstore {
+		return signWithSigstore(out)
+	}
+	return nil
+}
+
+// signWithSigstore signs a file with cosign's keyless flow, writing the bundle next to it
+func signWithSigstore(path string) error {
+	if _, err := exec.LookPath("cosign"); err != nil {
+		return fmt.Errorf("signing with sigstore needs cosign on PATH")
+	}
+	output, err := exec.Command("cosign", "sign-blob", "--yes", "--bundle", path+sigstoreBundleSuffix, path).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("cosign sign-blob failed: %w\n%s", err, lastLines(string(output), 10))
+	}
+	return nil
+}
+
+// verifySigstoreBundle checks a file's cosign bundle was signed by identity, as vouched for by the
+// OIDC issuer
+func verifySigstoreBundle(path, identity, issuer string) error {
+	if _, err := exec.LookPath("cosign"); err != nil {
+		return fmt.Errorf("checking a sigstore bundle needs cosign on PATH")
+	}
+	bundle := path + sigstoreBundleSuffix
+	if _, err := os.Stat(bundle); err != nil {
+		return fmt.Errorf("no sigstore bundle: %w", err)
+	}
+	output, err := exec.Command("cosign", "verify-blob", "--bundle", bundle,
+		"--certificate-identity", identity, "--certificate-oidc-issuer", issuer, path).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("cosign verify-blob failed: %w\n%s", err, lastLines(string(output), 10))
+	}
+	return nil
+}
+
+// runAttest implements `bjarne attest`
+func runAttest(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, attestUsage)
+		return 1
+	}
+	switch args[0] {
+	case "keygen":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, attestUsage)
+			return 1
+		}
+		if err := GenerateSigningKey(args[1]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Wrote %s.pem (keep it private) and %s.pub.pem (give it to those who verify)\n", args[1], args[1])
+		return 0
+	case "verify":
+		return runAttestVerify(args[1:])
+	}
+
+	var out, key string
+	var sigstore bool
+	manifestPath := ""
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case (arg == "--key" || arg == "-o") && i+1 < len(args):
+			if arg == "--key" {
+				key = args[i+1]
+			} else {
+				out = args[i+1]
+			}
+			i++
+		case arg == "--sigstore":
+			sigstore = true
+		case !strings.HasPrefix(arg, "-") && manifestPath == "":
+			manifestPath = arg
+		default:
+			fmt.Fprintf(os.Stderr, "Error: unexpected argument %s\n%s\n", arg, attestUsage)
+			return 1
+		}
+	}
+	if manifestPath == "" {
+		fmt.Fprintln(os.Stderr, attestUsage)
+		return 1
+	}
+	if key != "" && sigstore {
+		fmt.Fprintf(os.Stderr, "Error: --key and --sigstore cannot be used together\n%s\n", attestUsage)
+		return 1
+	}
+	m, err := LoadValidationManifest(manifestPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if out == "" {
+		out = attestationPath(manifestPath)
+	}
+	if err := WriteAttestation(manifestPath, m, out, key, sigstore); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	switch {
+	case key != "":
+		fmt.Printf("Wrote %s, signed with %s\n", out, key)
+	case sigstore:
+		fmt.Printf("Wrote %s and its sigstore bundle %s\n", out, out+sigstoreBundleSuffix)
+	default:
+		fmt.Printf("Wrote %s (unsigned)\n", out)
+	}
+	return 0
+}
+
+// runAttestVerify implements `bjarne attest verify`
+func runAttestVerify(args []string) int {
+	var keyPath, identity, issuer, path string
+	allowUnsigned := false
+	files := map[string]string{}
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case (arg == "--key" || arg == "--sigstore" || arg == "--issuer") && i+1 < len(args):
+			switch arg {
+			case "--key":
+				keyPath = args[i+1]
+			case "--sigstore":
+				identity = args[i+1]
+			default:
+				issuer = args[i+1]
+			}
+			i++
+		case arg == "--allow-unsigned":
+			allowUnsigned = true
+		case strings.HasPrefix(arg, "-"):
+			fmt.Fprintf(os.Stderr, "Error: unexpected argument %s\n%s\n", arg, attestUsage)
+			return 1
+		case path == "":
+			path = arg
+		default:
+			data, err := os.ReadFile(arg)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+			files[arg] = string(data)
+		}
+	}
+	if path == "" || (identity == "") != (issuer == "") {
+		fmt.Fprintln(os.Stderr, attestUsage)
+		return 1
+	}
+	if keyPath != "" && identity != "" {
+		fmt.Fprintf(os.Stderr, "Error: --key and --sigstore cannot be used together\n%s\n", attestUsage)
+		return 1
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	var env DSSEEnvelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid attestation %s: %v\n", path, err)
+		return 1
+	}
+	st, err := env.Statement()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	// Without a checked signature the attestation proves nothing about who validated the code
+	switch {
+	case keyPath != "":
+		pub, err := LoadVerifyKey(keyPath)
+		if err == nil {
+			err = env.Verify(pub)
+		}
+		if err != nil {
+			fmt.Printf("\033[91m✗\033[0m signature: %v\n", err)
+			return 1
+		}
+		fmt.Printf("\033[92m✓\033[0m signed by %s\n", keyPath)
+	case identity != "":
+		if err := verifySigstoreBundle(path, identity, issuer); err != nil {
+			fmt.Printf("\033[91m✗\033[0m sigstore: %v\n", err)
+			return 1
+		}
+		fmt.Printf("\033[92m✓\033[0m signed with sigstore by %s (%s)\n", identity, issuer)
+	case allowUnsigned:
+		fmt.Println("\033[93m!\033[0m signature not checked (--allow-unsigned)")
+	case len(env.Signatures) > 0:
+		fmt.Println("\033[91m✗\033[0m signature not checked; pass --key <public.pem>, or --allow-unsigned to check only the files")
+		return 1
+	default:
+		if _, err := os.Stat(path + sigstoreBundleSuffix); err == nil {
+			fmt.Println("\033[91m✗\033[0m signature not checked; pass --sigstore <identity> --issuer <url> for its sigstore bundle, or --allow-unsigned to check only the files")
+			return 1
+		}
+		fmt.Println("\033[91m✗\033[0m attestation is not signed; pass --allow-unsigned to check only the files")
+		return 1
+	}
+
+	lines, err := verifySubjects(st, files)
+	for _, line := range lines {
+		fmt.Printf("\033[92m✓\033[0m %s\n", line)
+	}
+	if err != nil {
+		fmt.Printf("\033[91m✗\033[0m %v\n", err)
+		return 1
+	}
+
+	p := st.Predicate
+	fmt.Printf("\nValidated %s by bjarne %s on %s\n", p.ValidatedOn.Local().Format("2006-01-02 15:04"), p.Builder.Version, p.Image.URI)
+	for algo, digest := range p.Image.Digest {
+		fmt.Printf("  image digest %s:%s\n", algo, digest)
+	}
+	if len(p.Generator.Models) > 0 {
+		fmt.Printf("  generated by %s %v\n", p.Generator.Provider, p.Generator.Models)
+	}
+	var gates []string
+	for _, g := range p.Gates {
+		gates = append(gates, g.Name+" "+g.Result)
+	}
+	fmt.Printf("  gates: %s\n", strings.Join(gates, ", "))
+	return 0
+}
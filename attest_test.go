@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testManifest() *ValidationManifest {
+	code := "int main() { return 0; }\n"
+	return &ValidationManifest{
+		Version:  manifestVersion,
+		Created:  time.Date(2026, 10, 16, 15, 30, 0, 0, time.UTC),
+		Bjarne:   "1.4.0",
+		Image:    "ghcr.io/3rg0n/bjarne-validator:latest",
+		Digest:   "sha256:" + strings.Repeat("ab", 32),
+		Config:   ManifestConfig{Standard: "c++20"},
+		Files:    []ManifestFile{{Name: "code.cpp", SHA256: sha256Hex(code), Content: code}},
+		Stages:   []ManifestStage{{Stage: "compile", Success: true}, {Stage: "msan", Success: true, Skipped: true}},
+		Provider: "anthropic",
+		Models:   map[string]string{"code": "sonnet"},
+	}
+}
+
+func TestNewAttestation(t *testing.T) {
+	st := NewAttestation(testManifest(), "20261016-153000-9f3a61c0")
+	if st.Type != inTotoStatementType || st.PredicateType != validationPredicateType {
+		t.Errorf("statement types = %q, %q", st.Type, st.PredicateType)
+	}
+	if len(st.Subject) != 1 || st.Subject[0].Name != "code.cpp" || st.Subject[0].Digest["sha256"] == "" {
+		t.Errorf("subject = %+v", st.Subject)
+	}
+	p := st.Predicate
+	if p.Image.URI != "ghcr.io/3rg0n/bjarne-validator:latest" || p.Image.Digest["sha256"] != strings.Repeat("ab", 32) {
+		t.Errorf("image = %+v", p.Image)
+	}
+	if len(p.Gates) != 2 || p.Gates[1] != (AttestationGate{Name: "msan", Result: "SKIP"}) {
+		t.Errorf("gates = %+v", p.Gates)
+	}
+	if p.Generator.Models["code"] != "sonnet" || p.Invocation != "20261016-153000-9f3a61c0" || p.Standard != "c++20" {
+		t.Errorf("predicate = %+v", p)
+	}
+}
+
+func TestAttestationSignature(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "bjarne")
+	if err := GenerateSigningKey(name); err != nil {
+		t.Fatal(err)
+	}
+	if err := GenerateSigningKey(name); err == nil {
+		t.Error("GenerateSigningKey() overwrote an existing key")
+	}
+	if _, err := LoadSigningKey(name + ".pem"); err != nil {
+		t.Fatal(err)
+	}
+	pub, err := LoadVerifyKey(name + ".pub.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manifestPath := filepath.Join(dir, "20261016-153000-9f3a61c0.json")
+	out := attestationPath(manifestPath)
+	if err := WriteAttestation(manifestPath, testManifest(), out, name+".pem", false); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var env DSSEEnvelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.Verify(pub); err != nil {
+		t.Errorf("Verify() = %v", err)
+	}
+	st, err := env.Statement()
+	if err != nil || st.Predicate.Invocation != "20261016-153000-9f3a61c0" {
+		t.Fatalf("Statement() = %+v, %v", st, err)
+	}
+
+	tampered := env
+	st.Predicate.Gates[0].Result = "PASS"
+	st.Subject[0].Digest["sha256"] = sha256Hex("int main() { return 1; }\n")
+	payload, _ := json.Marshal(st)
+	tampered.Payload = base64.StdEncoding.EncodeToString(payload)
+	if err := tampered.Verify(pub); err == nil {
+		t.Error("Verify() accepted an edited statement")
+	}
+
+	if err := GenerateSigningKey(filepath.Join(dir, "other")); err != nil {
+		t.Fatal(err)
+	}
+	other, err := LoadVerifyKey(filepath.Join(dir, "other.pub.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.Verify(other); err == nil {
+		t.Error("Verify() accepted another key")
+	}
+
+	unsigned, err := NewEnvelope(NewAttestation(testManifest(), "x"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := unsigned.Verify(pub); err == nil || !strings.Contains(err.Error(), "not signed") {
+		t.Errorf("Verify(unsigned) = %v", err)
+	}
+	if _, err := LoadSigningKey(name + ".pub.pem"); err == nil {
+		t.Error("LoadSigningKey() accepted a public key")
+	}
+}
+
+func TestVerifySubjects(t *testing.T) {
+	st := NewAttestation(testManifest(), "x")
+	lines, err := verifySubjects(st, map[string]string{"src/main.cpp": "int main() { return 0; }\n"})
+	if err != nil || len(lines) != 1 || lines[0] != "src/main.cpp matches code.cpp" {
+		t.Errorf("verifySubjects(same code) = %q, %v", lines, err)
+	}
+	if _, err := verifySubjects(st, map[string]string{"src/main.cpp": "int main() { return 1; }\n"}); err == nil {
+		t.Error("verifySubjects(edited code) = nil, want an error")
+	}
+}
+
+func TestRunAttestVerifySignature(t *testing.T) {
+	dir := t.TempDir()
+	key := filepath.Join(dir, "bjarne")
+	if err := GenerateSigningKey(key); err != nil {
+		t.Fatal(err)
+	}
+	manifestPath := filepath.Join(dir, "20261016-153000-9f3a61c0.json")
+	signed := filepath.Join(dir, "signed"+attestationSuffix)
+	unsigned := filepath.Join(dir, "unsigned"+attestationSuffix)
+	if err := WriteAttestation(manifestPath, testManifest(), signed, key+".pem", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteAttestation(manifestPath, testManifest(), unsigned, "", false); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"signed with its key", []string{signed, "--key", key + ".pub.pem"}, 0},
+		{"signed without a key", []string{signed}, 1},
+		{"signed without a key, allowed", []string{signed, "--allow-unsigned"}, 0},
+		{"unsigned", []string{unsigned}, 1},
+		{"unsigned, allowed", []string{unsigned, "--allow-unsigned"}, 0},
+		{"unsigned with a key", []string{unsigned, "--key", key + ".pub.pem"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runAttestVerify(tt.args); got != tt.want {
+				t.Errorf("runAttestVerify(%q) = %d, want %d", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunAttestVerifySigstore(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs /bin/sh")
+	}
+	dir := t.TempDir()
+	// Stands in for cosign: accepts only me@example.com from the GitHub issuer
+	stub := "#!/bin/sh\ncase \"$*\" in\n*'--certificate-identity me@example.com --certificate-oidc-issuer https://github.com/login/oauth'*) exit 0 ;;\nesac\necho 'none of the expected identities matched'\nexit 1\n"
+	if err := os.WriteFile(filepath.Join(dir, "cosign"), []byte(stub), 0700); err != nil { //nolint:gosec // the test runs it
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	manifestPath := filepath.Join(dir, "20261016-153000-9f3a61c0.json")
+	bundled := filepath.Join(dir, "bundled"+attestationSuffix)
+	unbundled := filepath.Join(dir, "unbundled"+attestationSuffix)
+	for _, path := range []string{bundled, unbundled} {
+		if err := WriteAttestation(manifestPath, testManifest(), path, "", false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(bundled+sigstoreBundleSuffix, []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	issuer := "https://github.com/login/oauth"
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"signer", []string{bundled, "--sigstore", "me@example.com", "--issuer", issuer}, 0},
+		{"another signer", []string{bundled, "--sigstore", "mallory@example.com", "--issuer", issuer}, 1},
+		{"no bundle", []string{unbundled, "--sigstore", "me@example.com", "--issuer", issuer}, 1},
+		{"bundle not checked", []string{bundled}, 1},
+		{"no issuer", []string{bundled, "--sigstore", "me@example.com"}, 1},
+		{"with a key", []string{bundled, "--sigstore", "me@example.com", "--issuer", issuer, "--key", "attest.pub.pem"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runAttestVerify(tt.args); got != tt.want {
+				t.Errorf("runAttestVerify(%q) = %d, want %d", tt.args, got, tt.want)
+			}
+		})
+	}
+	if got := runAttest([]string{manifestPath, "--key", "attest.pem", "--sigstore"}); got != 1 {
+		t.Errorf("runAttest() with --key and --sigstore = %d, want 1", got)
+	}
+}
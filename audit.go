@@ -0,0 +1,446 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuditEntry records one LLM call; Hash chains each entry to the previous one so edits,
+// insertions and deletions inside a session log are detectable
+type AuditEntry struct {
+	Seq          int       `json:"seq"`
+	Time         time.Time `json:"time"`
+	Session      string    `json:"session"`
+	Provider     string    `json:"provider"`
+	Model        string    `json:"model"`
+	Prompt       string    `json:"prompt"`      // Last user message, as sent (after redaction)
+	RequestHash  string    `json:"requestHash"` // SHA-256 of the system prompt and every message
+	ResponseHash string    `json:"responseHash,omitempty"`
+	InputTokens  int       `json:"inputTokens"`
+	OutputTokens int       `json:"outputTokens"`
+	Error        string    `json:"error,omitempty"`
+	PrevHash     string    `json:"prevHash"`
+	Hash         string    `json:"hash"`
+}
+
+// AuditLog appends entries for one session to ~/.bjarne/audit/<session>.jsonl
+type AuditLog struct {
+	mu       sync.Mutex
+	path     string
+	session  string
+	seq      int
+	prevHash string
+}
+
+// activeAudit wraps providers created by NewProvider (nil = auditing off)
+var activeAudit *AuditLog
+
+// SetAuditLog records LLM calls from providers created afterwards in log
+func SetAuditLog(log *AuditLog) {
+	activeAudit = log
+}
+
+// auditDir returns ~/.bjarne/audit
+func auditDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".bjarne", "audit"), nil
+}
+
+// newSessionID returns a sortable session ID such as 20261016-153012-9f3a61c0
+func newSessionID(now time.Time) string {
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	return now.Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
+
+// OpenAuditLog starts a session log in dir
+func OpenAuditLog(dir, session string) (*AuditLog, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create audit directory: %w", err)
+	}
+	return &AuditLog{path: filepath.Join(dir, session+".jsonl"), session: session}, nil
+}
+
+// Session returns the session ID
+func (l *AuditLog) Session() string {
+	return l.session
+}
+
+// Record appends an entry, filling in its sequence number, session and hash chain
+// The chain only advances once the entry is written, so a failed write leaves no gap
+func (l *AuditLog) Record(e AuditEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e.Seq = l.seq + 1
+	e.Session = l.session
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	e.PrevHash = l.prevHash
+	e.Hash = e.computeHash()
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	l.seq = e.Seq
+	l.prevHash = e.Hash
+	return nil
+}
+
+// computeHash hashes the entry without its own Hash field
+func (e AuditEntry) computeHash() string {
+	e.Hash = ""
+	data, _ := json.Marshal(e)
+	return sha256Hex(string(data))
+}
+
+// sha256Hex returns the hex SHA-256 of s
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// requestHash hashes everything sent in one call
+func requestHash(systemPrompt string, messages []Message) string {
+	h := sha256.New()
+	h.Write([]byte(systemPrompt))
+	for _, m := range messages {
+		h.Write([]byte{0})
+		h.Write([]byte(m.Role))
+		h.Write([]byte{0})
+		h.Write([]byte(m.Content))
+		for _, img := range m.Images {
+			h.Write(img.Data)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ReadAuditLog loads a session log
+func ReadAuditLog(path string) ([]AuditEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var e AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return entries, fmt.Errorf("line %d is not a valid audit entry: %w", line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// VerifyAuditEntries checks sequence numbers and the hash chain
+func VerifyAuditEntries(entries []AuditEntry) error {
+	prev := ""
+	for i, e := range entries {
+		if e.Seq != i+1 {
+			return fmt.Errorf("entry %d has sequence number %d (entries missing or reordered)", i+1, e.Seq)
+		}
+		if e.PrevHash != prev {
+			return fmt.Errorf("entry %d does not follow entry %d (chain broken)", e.Seq, e.Seq-1)
+		}
+		if e.computeHash() != e.Hash {
+			return fmt.Errorf("entry %d was modified (hash mismatch)", e.Seq)
+		}
+		prev = e.Hash
+	}
+	return nil
+}
+
+// PruneAuditLogs deletes session logs beyond maxSessions or older than maxAge (0 = no limit)
+func PruneAuditLogs(dir string, maxSessions int, maxAge time.Duration) error {
+	sessions, err := listAuditSessions(dir)
+	if err != nil {
+		return err
+	}
+	for i, s := range sessions {
+		expired := maxAge > 0 && time.Since(s.modTime) > maxAge
+		excess := maxSessions > 0 && i < len(sessions)-maxSessions
+		if expired || excess {
+			if err := os.Remove(s.path); err != nil {
+				return fmt.Errorf("failed to remove old audit log: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// auditSession is one session log on disk
+type auditSession struct {
+	id      string
+	path    string
+	modTime time.Time
+}
+
+// listAuditSessions returns session logs, oldest first
+func listAuditSessions(dir string) ([]auditSession, error) {
+	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit directory: %w", err)
+	}
+	var sessions []auditSession
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".jsonl") {
+			continue
+		}
+		info, err := f.Info()
+		if err != nil {
+			continue
+		}
+		sessions = append(sessions, auditSession{
+			id:      strings.TrimSuffix(f.Name(), ".jsonl"),
+			path:    filepath.Join(dir, f.Name()),
+			modTime: info.ModTime(),
+		})
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].id < sessions[j].id })
+	return sessions, nil
+}
+
+// findAuditSession resolves a session ID, unique prefix, or "latest"
+func findAuditSession(dir, id string) (auditSession, error) {
+	sessions, err := listAuditSessions(dir)
+	if err != nil {
+		return auditSession{}, err
+	}
+	if len(sessions) == 0 {
+		return auditSession{}, fmt.Errorf("no audit logs in %s", dir)
+	}
+	if id == "latest" {
+		return sessions[len(sessions)-1], nil
+	}
+	var matches []auditSession
+	for _, s := range sessions {
+		if s.id == id {
+			return s, nil
+		}
+		if strings.HasPrefix(s.id, id) {
+			matches = append(matches, s)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return auditSession{}, fmt.Errorf("no audit session %q (try 'bjarne audit list')", id)
+	case 1:
+		return matches[0], nil
+	default:
+		return auditSession{}, fmt.Errorf("session prefix %q is ambiguous (%d matches)", id, len(matches))
+	}
+}
+
+// auditedProvider records every call of the wrapped provider
+type auditedProvider struct {
+	LLMProvider
+	log *AuditLog
+}
+
+// withAudit wraps a provider so its calls are recorded (no-op without a log)
+func withAudit(p LLMProvider, log *AuditLog) LLMProvider {
+	if log == nil {
+		return p
+	}
+	return &auditedProvider{LLMProvider: p, log: log}
+}
+
+// Unwrap returns the wrapped provider
+func (a *auditedProvider) Unwrap() LLMProvider {
+	return a.LLMProvider
+}
+
+// Generate records the call after the wrapped provider returns
+func (a *auditedProvider) Generate(ctx context.Context, model, systemPrompt string, messages []Message, maxTokens int) (*GenerateResult, error) {
+	result, err := a.LLMProvider.Generate(ctx, model, systemPrompt, messages, maxTokens)
+	a.record(model, systemPrompt, messages, result, err)
+	return result, err
+}
+
+// GenerateStreaming records the call after the stream completes
+func (a *auditedProvider) GenerateStreaming(ctx context.Context, model, systemPrompt string, messages []Message, maxTokens int, callback StreamCallback) (*GenerateResult, error) {
+	result, err := a.LLMProvider.GenerateStreaming(ctx, model, systemPrompt, messages, maxTokens, callback)
+	a.record(model, systemPrompt, messages, result, err)
+	return result, err
+}
+
+// record appends an entry; audit failures never interrupt generation
+func (a *auditedProvider) record(model, systemPrompt string, messages []Message, result *GenerateResult, err error) {
+	if model == "" {
+		model = a.DefaultModel()
+	}
+	e := AuditEntry{
+		Provider:    a.Name(),
+		Model:       a.MapModel(model),
+		RequestHash: requestHash(systemPrompt, messages),
+	}
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			e.Prompt = messages[i].Content
+			break
+		}
+	}
+	if result != nil {
+		e.ResponseHash = sha256Hex(result.Text)
+		e.InputTokens = result.InputTokens
+		e.OutputTokens = result.OutputTokens
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	_ = a.log.Record(e)
+}
+
+// startAuditSession opens this run's audit log and prunes old ones per settings
+func startAuditSession(settings AuditSettings, session string) (*AuditLog, error) {
+	if !settings.Enabled {
+		return nil, nil
+	}
+	dir, err := auditDir()
+	if err != nil {
+		return nil, err
+	}
+	if err := PruneAuditLogs(dir, settings.MaxSessions, time.Duration(settings.MaxAgeDays)*24*time.Hour); err != nil {
+		return nil, err
+	}
+	return OpenAuditLog(dir, session)
+}
+
+// runAudit implements `bjarne audit list|show <session>`
+func runAudit(args []string) int {
+	dir, err := auditDir()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	cmd := "list"
+	if len(args) > 0 {
+		cmd = args[0]
+	}
+	switch cmd {
+	case "list":
+		return printAuditSessions(dir)
+	case "show":
+		if len(args) < 2 {
+			fmt.Fprintln(os.Stderr, "Usage: bjarne audit show <session|prefix|latest>")
+			return 1
+		}
+		return printAuditSession(dir, args[1])
+	default:
+		fmt.Fprintln(os.Stderr, "Usage: bjarne audit [list | show <session|prefix|latest>]")
+		return 1
+	}
+}
+
+// printAuditSessions lists sessions with call and token totals
+func printAuditSessions(dir string) int {
+	sessions, err := listAuditSessions(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if len(sessions) == 0 {
+		fmt.Printf("No audit logs in %s\n", dir)
+		return 0
+	}
+	fmt.Printf("%-22s %6s %10s %10s  %s\n", "SESSION", "CALLS", "IN", "OUT", "CHAIN")
+	for _, s := range sessions {
+		entries, err := ReadAuditLog(s.path)
+		status := "ok"
+		if err != nil {
+			status = "unreadable"
+		} else if err := VerifyAuditEntries(entries); err != nil {
+			status = "TAMPERED"
+		}
+		in, out := 0, 0
+		for _, e := range entries {
+			in += e.InputTokens
+			out += e.OutputTokens
+		}
+		fmt.Printf("%-22s %6d %10d %10d  %s\n", s.id, len(entries), in, out, status)
+	}
+	return 0
+}
+
+// printAuditSession shows every call in a session and verifies the chain
+func printAuditSession(dir, id string) int {
+	s, err := findAuditSession(dir, id)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	entries, err := ReadAuditLog(s.path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Session %s (%s)\n\n", s.id, s.path)
+	for _, e := range entries {
+		fmt.Printf("#%d  %s  %s  %s\n", e.Seq, e.Time.Local().Format("2006-01-02 15:04:05"), e.Provider, e.Model)
+		fmt.Printf("    tokens %d in / %d out  request %s  response %s\n", e.InputTokens, e.OutputTokens, shortHash(e.RequestHash), shortHash(e.ResponseHash))
+		if e.Error != "" {
+			fmt.Printf("    error: %s\n", e.Error)
+		}
+		prompt := strings.TrimSpace(e.Prompt)
+		if first, _, multi := strings.Cut(prompt, "\n"); multi {
+			prompt = first + " …"
+		}
+		if len(prompt) > 100 {
+			prompt = prompt[:100] + "…"
+		}
+		fmt.Printf("    prompt: %s\n", prompt)
+	}
+	fmt.Println()
+
+	if err := VerifyAuditEntries(entries); err != nil {
+		fmt.Printf("\033[91mChain verification failed:\033[0m %v\n", err)
+		return 2
+	}
+	fmt.Printf("\033[92mChain verified\033[0m (%d entries)\n", len(entries))
+	return 0
+}
+
+// shortHash abbreviates a hex hash for display
+func shortHash(h string) string {
+	if h == "" {
+		return "-"
+	}
+	if len(h) > 12 {
+		return h[:12]
+	}
+	return h
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubProvider returns a fixed response
+type stubProvider struct {
+	LLMProvider
+	text string
+}
+
+func (s stubProvider) Name() string                 { return "stub" }
+func (s stubProvider) DefaultModel() string         { return "stub-model" }
+func (s stubProvider) MapModel(model string) string { return model }
+func (s stubProvider) Generate(ctx context.Context, model, systemPrompt string, messages []Message, maxTokens int) (*GenerateResult, error) {
+	return &GenerateResult{Text: s.text, InputTokens: 12, OutputTokens: 34}, nil
+}
+
+func TestAuditedProviderRecordsChain(t *testing.T) {
+	dir := t.TempDir()
+	log, err := OpenAuditLog(dir, "20261016-120000-abcd")
+	if err != nil {
+		t.Fatalf("OpenAuditLog() error = %v", err)
+	}
+	p := withAudit(stubProvider{text: "int main() {}"}, log)
+
+	for _, prompt := range []string{"write fizzbuzz", "now add tests"} {
+		msgs := []Message{{Role: "assistant", Content: "earlier"}, {Role: "user", Content: prompt}}
+		if _, err := p.Generate(context.Background(), "", "system", msgs, 100); err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+	}
+
+	entries, err := ReadAuditLog(filepath.Join(dir, "20261016-120000-abcd.jsonl"))
+	if err != nil {
+		t.Fatalf("ReadAuditLog() error = %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	e := entries[1]
+	if e.Seq != 2 || e.Prompt != "now add tests" || e.Model != "stub-model" || e.Provider != "stub" {
+		t.Errorf("entry = %+v", e)
+	}
+	if e.InputTokens != 12 || e.OutputTokens != 34 || e.ResponseHash != sha256Hex("int main() {}") {
+		t.Errorf("entry tokens/hash = %d/%d/%s", e.InputTokens, e.OutputTokens, e.ResponseHash)
+	}
+	if e.PrevHash != entries[0].Hash {
+		t.Errorf("PrevHash = %q, want %q", e.PrevHash, entries[0].Hash)
+	}
+	if err := VerifyAuditEntries(entries); err != nil {
+		t.Errorf("VerifyAuditEntries() error = %v", err)
+	}
+}
+
+func TestVerifyAuditEntriesDetectsTampering(t *testing.T) {
+	dir := t.TempDir()
+	log, _ := OpenAuditLog(dir, "s")
+	for i := 0; i < 3; i++ {
+		if err := log.Record(AuditEntry{Provider: "stub", Model: "m", Prompt: "p", InputTokens: i}); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+	entries, err := ReadAuditLog(filepath.Join(dir, "s.jsonl"))
+	if err != nil {
+		t.Fatalf("ReadAuditLog() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		tamper func([]AuditEntry) []AuditEntry
+		want   string
+	}{
+		{"intact", func(e []AuditEntry) []AuditEntry { return e }, ""},
+		{"edited", func(e []AuditEntry) []AuditEntry { e[1].InputTokens = 999; return e }, "modified"},
+		{"deleted", func(e []AuditEntry) []AuditEntry { return append(e[:1], e[2:]...) }, "sequence"},
+		{"truncated head", func(e []AuditEntry) []AuditEntry { return e[1:] }, "sequence"},
+		{"rehashed edit", func(e []AuditEntry) []AuditEntry {
+			e[1].Prompt = "other"
+			e[1].Hash = e[1].computeHash()
+			return e
+		}, "chain broken"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			copied := append([]AuditEntry(nil), entries...)
+			err := VerifyAuditEntries(tt.tamper(copied))
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("VerifyAuditEntries() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("VerifyAuditEntries() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuditLogFileIsJSONL(t *testing.T) {
+	dir := t.TempDir()
+	log, _ := OpenAuditLog(dir, "s")
+	_ = log.Record(AuditEntry{Prompt: "a"})
+	_ = log.Record(AuditEntry{Prompt: "b\nc"})
+
+	data, err := os.ReadFile(filepath.Join(dir, "s.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+	for _, line := range lines {
+		var e AuditEntry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Errorf("line %q: %v", line, err)
+		}
+	}
+}
+
+func TestAuditLogFailedWriteKeepsChain(t *testing.T) {
+	dir := t.TempDir()
+	log, _ := OpenAuditLog(dir, "s")
+	if err := log.Record(AuditEntry{Prompt: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	// A directory in the log's place makes the next write fail
+	path := filepath.Join(dir, "s.jsonl")
+	data, _ := os.ReadFile(path)
+	_ = os.Remove(path)
+	if err := os.Mkdir(path, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := log.Record(AuditEntry{Prompt: "lost"}); err == nil {
+		t.Fatal("Record() succeeded without a writable log")
+	}
+	_ = os.Remove(path)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := log.Record(AuditEntry{Prompt: "b"}); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := ReadAuditLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyAuditEntries(entries); err != nil || len(entries) != 2 {
+		t.Errorf("VerifyAuditEntries() = %v with %d entries; want an intact chain of 2", err, len(entries))
+	}
+}
+
+func TestPruneAuditLogs(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-100 * 24 * time.Hour)
+	for i, id := range []string{"20260101-000000-aaaa", "20260201-000000-bbbb", "20261001-000000-cccc", "20261002-000000-dddd"} {
+		path := filepath.Join(dir, id+".jsonl")
+		if err := os.WriteFile(path, []byte("{}\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			_ = os.Chtimes(path, old, old)
+		}
+	}
+
+	if err := PruneAuditLogs(dir, 2, 90*24*time.Hour); err != nil {
+		t.Fatalf("PruneAuditLogs() error = %v", err)
+	}
+	sessions, _ := listAuditSessions(dir)
+	var ids []string
+	for _, s := range sessions {
+		ids = append(ids, s.id)
+	}
+	if got := strings.Join(ids, ","); got != "20261001-000000-cccc,20261002-000000-dddd" {
+		t.Errorf("remaining = %s", got)
+	}
+
+	s, err := findAuditSession(dir, "20261001")
+	if err != nil || s.id != "20261001-000000-cccc" {
+		t.Errorf("findAuditSession(prefix) = %v, %v", s.id, err)
+	}
+	if _, err := findAuditSession(dir, "2026100"); err == nil {
+		t.Error("findAuditSession() accepted an ambiguous prefix")
+	}
+	if s, _ := findAuditSession(dir, "latest"); s.id != "20261002-000000-dddd" {
+		t.Errorf("findAuditSession(latest) = %s", s.id)
+	}
+}
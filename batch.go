@@ -0,0 +1,456 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+// batchUsage is printed for bad `bjarne batch` arguments
+const batchUsage = "Usage: bjarne batch <tasks.yaml|tasks.json> [--report <file.json|file.md>] [--only <name,...>]"
+
+// batchTask is one entry of a task file
+type batchTask struct {
+	Name          string   `json:"name"`
+	Prompt        string   `json:"prompt"`
+	Output        string   `json:"output"` // File, or directory (trailing /) for multi-file code
+	Std           string   `json:"std"`    // C++ standard, e.g. c++20
+	Gates         []string `json:"gates"`  // Gates that must pass (empty = all)
+	Model         string   `json:"model"`
+	MaxIterations *int     `json:"maxIterations"`
+}
+
+// batchFile is a task file: tasks plus defaults applied to each of them
+type batchFile struct {
+	Defaults batchTask   `json:"defaults"`
+	Tasks    []batchTask `json:"tasks"`
+}
+
+// batchResult is the outcome of one task in the report
+type batchResult struct {
+	Name         string           `json:"name"`
+	Passed       bool             `json:"passed"`
+	Written      []string         `json:"written,omitempty"`
+	Backups      []string         `json:"backups,omitempty"`
+	Gates        []checkpointGate `json:"gates,omitempty"`
+	Failure      string           `json:"failure,omitempty"` // First failing gate's error, or why the task did not run
+	Iterations   int              `json:"iterations"`
+	Model        string           `json:"model,omitempty"`
+	Confidence   int              `json:"confidence,omitempty"`
+	InputTokens  int              `json:"inputTokens"`
+	OutputTokens int              `json:"outputTokens"`
+	DurationMs   int64            `json:"durationMs"`
+}
+
+// batchReport is the consolidated report of a batch run
+type batchReport struct {
+	Tasks      string        `json:"tasks"`
+	Started    time.Time     `json:"started"`
+	DurationMs int64         `json:"durationMs"`
+	Passed     int           `json:"passed"`
+	Failed     int           `json:"failed"`
+	Results    []batchResult `json:"results"`
+}
+
+// batchOptions are the parsed `bjarne batch` arguments
+type batchOptions struct {
+	File   string
+	Report string
+	Only   []string // Run only the tasks with these names
+}
+
+// parseBatchArgs parses `bjarne batch` arguments
+func parseBatchArgs(args []string) (batchOptions, error) {
+	var opts batchOptions
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "--") {
+			if opts.File != "" {
+				return opts, fmt.Errorf("only one task file can be given")
+			}
+			opts.File = arg
+			continue
+		}
+		if i+1 >= len(args) {
+			return opts, fmt.Errorf("%s needs a value", arg)
+		}
+		value := args[i+1]
+		i++
+		switch arg {
+		case "--report":
+			opts.Report = value
+		case "--only":
+			for _, name := range strings.Split(value, ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					opts.Only = append(opts.Only, name)
+				}
+			}
+		default:
+			return opts, fmt.Errorf("unknown batch option: %s", arg)
+		}
+	}
+	if opts.File == "" {
+		return opts, fmt.Errorf("a task file is required")
+	}
+	return opts, nil
+}
+
+// loadBatchFile reads a task file (JSON, or YAML for any other extension)
+// A file that is just a list of tasks has no defaults
+func loadBatchFile(path string) (*batchFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read task file: %w", err)
+	}
+	if strings.ToLower(filepath.Ext(path)) != ".json" {
+		doc, err := parseYAML(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if data, err = json.Marshal(doc); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	}
+
+	var f batchFile
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "[") {
+		err = json.Unmarshal(data, &f.Tasks)
+	} else {
+		err = json.Unmarshal(data, &f)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &f, nil
+}
+
+// resolveTasks applies the defaults, checks every task, and makes outputs relative to baseDir
+func (f *batchFile) resolveTasks(baseDir string) ([]batchTask, error) {
+	if len(f.Tasks) == 0 {
+		return nil, fmt.Errorf("no tasks")
+	}
+	seen := make(map[string]bool)
+	tasks := make([]batchTask, 0, len(f.Tasks))
+	for i, t := range f.Tasks {
+		if t.Name == "" {
+			t.Name = fmt.Sprintf("task-%d", i+1)
+		}
+		if seen[t.Name] {
+			return nil, fmt.Errorf("task %d: duplicate name %q", i+1, t.Name)
+		}
+		seen[t.Name] = true
+		if strings.TrimSpace(t.Prompt) == "" {
+			return nil, fmt.Errorf("task %q: prompt is required", t.Name)
+		}
+
+		if t.Std == "" {
+			t.Std = f.Defaults.Std
+		}
+		if t.Std != "" {
+			std, err := ParseStandard(t.Std)
+			if err != nil {
+				return nil, fmt.Errorf("task %q: %w", t.Name, err)
+			}
+			t.Std = std
+		}
+		if t.Gates == nil {
+			t.Gates = f.Defaults.Gates
+		}
+		gates, err := ParseGateList(strings.Join(t.Gates, ","))
+		if err != nil {
+			return nil, fmt.Errorf("task %q: %w", t.Name, err)
+		}
+		t.Gates = gates
+		if t.Model == "" {
+			t.Model = f.Defaults.Model
+		}
+		if t.MaxIterations == nil {
+			t.MaxIterations = f.Defaults.MaxIterations
+		}
+		if t.MaxIterations != nil && *t.MaxIterations < 0 {
+			return nil, fmt.Errorf("task %q: maxIterations must not be negative", t.Name)
+		}
+
+		// A defaults output is a directory the tasks' files go in, named after each task
+		switch {
+		case t.Output == "" && f.Defaults.Output != "":
+			t.Output = strings.TrimSuffix(f.Defaults.Output, "/") + "/" + t.Name + ".cpp"
+		case t.Output == "":
+			t.Output = t.Name + ".cpp"
+		}
+		if !filepath.IsAbs(t.Output) {
+			t.Output = filepath.Join(baseDir, filepath.FromSlash(t.Output)) + trailingSlash(t.Output)
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, nil
+}
+
+// trailingSlash returns "/" when path names a directory by ending in one
+func trailingSlash(path string) string {
+	if strings.HasSuffix(path, "/") {
+		return "/"
+	}
+	return ""
+}
+
+// taskPrompt is the prompt sent for a task, naming its C++ standard when it sets one
+func (t batchTask) taskPrompt() string {
+	if t.Std == "" {
+		return t.Prompt
+	}
+	return strings.TrimSpace(t.Prompt) + "\n\nThe code must build with -std=" + t.Std + "."
+}
+
+// batchWrites places generated code at a task's output: a single file at the output path,
+// several files in the output directory (or the output file's directory)
+func batchWrites(output string, files []CodeFile) []saveWrite {
+	if len(files) == 1 && !strings.HasSuffix(output, "/") {
+		return []saveWrite{{Path: output, Content: files[0].Content}}
+	}
+	dir := strings.TrimSuffix(output, "/")
+	if len(files) > 1 && !strings.HasSuffix(output, "/") {
+		dir = filepath.Dir(output)
+	}
+	writes := make([]saveWrite, 0, len(files))
+	for _, f := range files {
+		writes = append(writes, saveWrite{Path: filepath.Join(dir, f.Filename), Content: f.Content})
+	}
+	return writes
+}
+
+// writeBatchOutputs writes a passing task's code, backing up files it replaces
+func writeBatchOutputs(result *batchResult, output string, files []CodeFile, backupMode, stashDir string) error {
+	writes := batchWrites(output, files)
+	findOverwrites(writes)
+	for _, w := range writes {
+		if w.Overwrites {
+			backup, err := backupFile(w, backupMode, stashDir)
+			if err != nil {
+				return err
+			}
+			if backup != "" {
+				result.Backups = append(result.Backups, backup)
+			}
+		}
+		if err := saveToPath(w.Path, w.Content); err != nil {
+			return err
+		}
+		result.Written = append(result.Written, w.Path)
+	}
+	return nil
+}
+
+// batchRunner generates and validates one task; the batch command runs the server pipeline
+type batchRunner func(ctx context.Context, t batchTask) (generateResponse, error)
+
+// runBatchTasks runs each task in turn, writing passing outputs, and builds the report
+// progress is called after each task
+func runBatchTasks(ctx context.Context, tasks []batchTask, run batchRunner, backupMode string, progress func(n int, r batchResult)) *batchReport {
+	report := &batchReport{Started: time.Now()}
+	stashDir, _ := stashDirFor(report.Started)
+	for i, t := range tasks {
+		result := batchResult{Name: t.Name}
+		start := time.Now()
+		if ctx.Err() != nil {
+			result.Failure = "not run: interrupted"
+		} else if resp, err := run(ctx, t); err != nil {
+			result.Failure = err.Error()
+		} else {
+			result.Passed = resp.Passed
+			result.Iterations = resp.Iterations
+			result.Model = resp.Model
+			result.Confidence = resp.Confidence
+			result.InputTokens, result.OutputTokens = resp.InputTokens, resp.OutputTokens
+			for _, g := range resp.Gates {
+				result.Gates = append(result.Gates, checkpointGate{Stage: g.Stage, Passed: g.Passed})
+				if !g.Passed && result.Failure == "" {
+					result.Failure = g.Stage + ": " + truncateError(firstLines(g.Error, 1), 120)
+				}
+			}
+			if resp.Passed {
+				files := []CodeFile{{Filename: saveFileName(resp.Code), Content: resp.Code}}
+				if len(resp.Files) > 0 {
+					files = files[:0]
+					for _, f := range resp.Files {
+						files = append(files, CodeFile{Filename: f.Filename, Content: f.Content})
+					}
+				}
+				if err := writeBatchOutputs(&result, t.Output, files, backupMode, stashDir); err != nil {
+					result.Passed = false
+					result.Failure = err.Error()
+				}
+			}
+		}
+		result.DurationMs = time.Since(start).Milliseconds()
+		if result.Passed {
+			report.Passed++
+		} else {
+			report.Failed++
+		}
+		report.Results = append(report.Results, result)
+		if progress != nil {
+			progress(i+1, result)
+		}
+	}
+	report.DurationMs = time.Since(report.Started).Milliseconds()
+	return report
+}
+
+// batchGateSummary lists a result's gates with their outcome
+func batchGateSummary(r batchResult) string {
+	if len(r.Gates) == 0 {
+		return "-"
+	}
+	c := Checkpoint{Gates: r.Gates}
+	return c.Status()
+}
+
+// Table renders the report as an aligned table
+func (r *batchReport) Table() string {
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TASK\tRESULT\tGATES\tITER\tTIME")
+	for _, res := range r.Results {
+		label := "PASS"
+		if !res.Passed {
+			label = "FAIL"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%.1fs\n", res.Name, label, batchGateSummary(res), res.Iterations, float64(res.DurationMs)/1000)
+	}
+	_ = w.Flush()
+	return sb.String()
+}
+
+// Markdown renders the report for reading
+func (r *batchReport) Markdown() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# bjarne batch: %s\n\n", r.Tasks)
+	fmt.Fprintf(&sb, "Started %s, took %.0fs. %d passed, %d failed.\n\n", r.Started.Format("2006-01-02 15:04:05"), float64(r.DurationMs)/1000, r.Passed, r.Failed)
+	sb.WriteString("| Task | Result | Gates | Iterations | Output |\n|------|--------|-------|------------|--------|\n")
+	for _, res := range r.Results {
+		label, output := "PASS", strings.Join(res.Written, ", ")
+		if !res.Passed {
+			label, output = "FAIL", strings.ReplaceAll(res.Failure, "|", "\\|")
+		}
+		fmt.Fprintf(&sb, "| %s | %s | %s | %d | %s |\n", res.Name, label, batchGateSummary(res), res.Iterations, output)
+	}
+	return sb.String()
+}
+
+// writeBatchReport writes the report as JSON, or as markdown for .md files
+func writeBatchReport(path string, r *batchReport) error {
+	var content string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown":
+		content = r.Markdown()
+	default:
+		data, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode report: %w", err)
+		}
+		content = string(data) + "\n"
+	}
+	if err := saveToPath(path, content); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+// selectBatchTasks keeps the tasks named in only (all of them when only is empty)
+func selectBatchTasks(tasks []batchTask, only []string) ([]batchTask, error) {
+	if len(only) == 0 {
+		return tasks, nil
+	}
+	byName := make(map[string]batchTask, len(tasks))
+	for _, t := range tasks {
+		byName[t.Name] = t
+	}
+	selected := make([]batchTask, 0, len(only))
+	for _, name := range only {
+		t, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("no task named %q", name)
+		}
+		selected = append(selected, t)
+	}
+	return selected, nil
+}
+
+// runBatch implements `bjarne batch`: generate and validate every task of a task file unattended
+func runBatch(args []string) int {
+	opts, err := parseBatchArgs(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n%s\n", err, batchUsage)
+		return 1
+	}
+	f, err := loadBatchFile(opts.File)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	tasks, err := f.resolveTasks(filepath.Dir(opts.File))
+	if err == nil {
+		tasks, err = selectBatchTasks(tasks, opts.Only)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s: %v\n", opts.File, err)
+		return 1
+	}
+
+	s, err := newServer("")
+	if err != nil {
+		fmt.Print(FormatUserError(err))
+		return 1
+	}
+	backupMode, err := parseBackupMode(s.cfg.Settings.Save.Backup)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	run := func(ctx context.Context, t batchTask) (generateResponse, error) {
+		s.container.SetStandard(t.Std)
+		s.container.SetGateSelection(GateSelection{Only: t.Gates})
+		maxIterations := s.cfg.MaxIterations
+		if t.MaxIterations != nil {
+			maxIterations = *t.MaxIterations
+		}
+		return s.runGenerate(ctx, generateRequest{Prompt: t.taskPrompt(), Model: t.Model, MaxIterations: &maxIterations})
+	}
+
+	fmt.Printf("bjarne batch: %d task(s) from %s (%s, %s)\n\n", len(tasks), opts.File, s.provider.Name(), s.container.ImageName())
+	report := runBatchTasks(ctx, tasks, run, backupMode, func(n int, r batchResult) {
+		status := "\033[92mPASS\033[0m " + strings.Join(r.Written, ", ")
+		if !r.Passed {
+			status = "\033[91mFAIL\033[0m " + r.Failure
+		}
+		fmt.Printf("[%d/%d] %s: %s\n", n, len(tasks), r.Name, status)
+	})
+	report.Tasks = opts.File
+	fmt.Printf("\n%s", report.Table())
+
+	if opts.Report != "" {
+		if err := writeBatchReport(opts.Report, report); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Report written to %s\n", opts.Report)
+	}
+	if report.Failed > 0 {
+		fmt.Printf("\033[91m%d of %d task(s) failed\033[0m\n", report.Failed, len(tasks))
+		return 1
+	}
+	fmt.Printf("\033[92mAll %d task(s) passed\033[0m\n", len(tasks))
+	return 0
+}
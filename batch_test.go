@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadBatchFile(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "tasks.yaml")
+	yamlDoc := `defaults:
+  std: "20"
+  gates: [compile, asan]
+  output: out/
+  maxIterations: 1
+tasks:
+  - name: fizzbuzz
+    prompt: FizzBuzz up to n
+  - name: lru
+    prompt: An LRU cache
+    output: cache/lru.cpp
+    std: c++17
+    gates: []
+`
+	if err := os.WriteFile(yamlPath, []byte(yamlDoc), 0600); err != nil {
+		t.Fatal(err)
+	}
+	f, err := loadBatchFile(yamlPath)
+	if err != nil {
+		t.Fatalf("loadBatchFile() error = %v", err)
+	}
+	tasks, err := f.resolveTasks(dir)
+	if err != nil {
+		t.Fatalf("resolveTasks() error = %v", err)
+	}
+	if len(tasks) != 2 {
+		t.Fatalf("got %d tasks, want 2", len(tasks))
+	}
+
+	fizz, lru := tasks[0], tasks[1]
+	if fizz.Std != "c++20" || strings.Join(fizz.Gates, ",") != "compile,asan" || *fizz.MaxIterations != 1 {
+		t.Errorf("defaults not applied: %+v", fizz)
+	}
+	if want := filepath.Join(dir, "out", "fizzbuzz.cpp"); fizz.Output != want {
+		t.Errorf("fizzbuzz output = %q, want %q", fizz.Output, want)
+	}
+	if lru.Std != "c++17" || len(lru.Gates) != 0 || lru.Output != filepath.Join(dir, "cache", "lru.cpp") {
+		t.Errorf("task settings should override defaults: %+v", lru)
+	}
+	if !strings.HasSuffix(fizz.taskPrompt(), "-std=c++20.") {
+		t.Errorf("taskPrompt() = %q, want the standard named", fizz.taskPrompt())
+	}
+
+	// JSON task files may also be a bare list
+	jsonPath := filepath.Join(dir, "tasks.json")
+	if err := os.WriteFile(jsonPath, []byte(`[{"prompt": "a ring buffer"}]`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	f, err = loadBatchFile(jsonPath)
+	if err != nil {
+		t.Fatalf("loadBatchFile(json) error = %v", err)
+	}
+	if tasks, err := f.resolveTasks("."); err != nil || tasks[0].Name != "task-1" || tasks[0].Output != "task-1.cpp" {
+		t.Errorf("resolveTasks(json) = %+v, %v", tasks, err)
+	}
+}
+
+func TestResolveBatchTasksErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file batchFile
+	}{
+		{"no tasks", batchFile{}},
+		{"missing prompt", batchFile{Tasks: []batchTask{{Name: "a"}}}},
+		{"duplicate name", batchFile{Tasks: []batchTask{{Name: "a", Prompt: "x"}, {Name: "a", Prompt: "y"}}}},
+		{"unknown gate", batchFile{Tasks: []batchTask{{Prompt: "x", Gates: []string{"lint"}}}}},
+		{"bad standard", batchFile{Defaults: batchTask{Std: "c++99"}, Tasks: []batchTask{{Prompt: "x"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.file.resolveTasks("."); err == nil {
+				t.Error("resolveTasks() should fail")
+			}
+		})
+	}
+}
+
+func TestRunBatchTasks(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "a.cpp")
+	if err := os.WriteFile(existing, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tasks := []batchTask{
+		{Name: "a", Prompt: "single file", Output: existing},
+		{Name: "b", Prompt: "multi file", Output: filepath.Join(dir, "b") + "/"},
+		{Name: "c", Prompt: "fails"},
+		{Name: "d", Prompt: "errors"},
+	}
+	run := func(ctx context.Context, task batchTask) (generateResponse, error) {
+		switch task.Name {
+		case "a":
+			return generateResponse{Passed: true, Code: "int main() {}\n", Iterations: 1, Gates: []apiGate{{Stage: "compile", Passed: true}}}, nil
+		case "b":
+			return generateResponse{Passed: true, Code: "joined", Iterations: 2, Files: []apiFile{{Filename: "x.h", Content: "#pragma once\n"}, {Filename: "main.cpp", Content: "int main() {}\n"}}}, nil
+		case "c":
+			return generateResponse{Iterations: 3, Gates: []apiGate{{Stage: "compile", Passed: true}, {Stage: "asan", Error: "heap-use-after-free\nmore"}}}, nil
+		}
+		return generateResponse{}, errors.New("provider unavailable")
+	}
+
+	var seen []string
+	report := runBatchTasks(context.Background(), tasks, run, BackupBak, func(n int, r batchResult) { seen = append(seen, r.Name) })
+	if report.Passed != 2 || report.Failed != 2 || len(seen) != 4 {
+		t.Fatalf("report = %+v, progress = %v", report, seen)
+	}
+
+	if data, _ := os.ReadFile(existing); string(data) != "int main() {}\n" {
+		t.Errorf("a.cpp = %q", data)
+	}
+	if data, _ := os.ReadFile(existing + ".bak"); string(data) != "old" {
+		t.Errorf("overwritten output should be backed up, got %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b", "x.h")); err != nil {
+		t.Errorf("multi-file output not written: %v", err)
+	}
+	if got := report.Results[2].Failure; got != "asan: heap-use-after-free" {
+		t.Errorf("failure = %q", got)
+	}
+	if got := report.Results[3].Failure; got != "provider unavailable" {
+		t.Errorf("failure = %q", got)
+	}
+
+	rows := strings.Split(strings.TrimSpace(report.Table()), "\n")
+	for i, want := range []string{"TASK RESULT GATES", "a PASS 1/1 gates passed 1", "b PASS - 2", "c FAIL 1/2 gates passed 3", "d FAIL - 0"} {
+		if got := strings.Join(strings.Fields(rows[i]), " "); !strings.HasPrefix(got, want) {
+			t.Errorf("table row %d = %q, want prefix %q", i, got, want)
+		}
+	}
+	path := filepath.Join(dir, "report.md")
+	if err := writeBatchReport(path, report); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), "| c | FAIL | 1/2 gates passed | 3 | asan: heap-use-after-free |") {
+		t.Errorf("markdown report:\n%s", data)
+	}
+}
@@ -19,20 +19,68 @@ var _ LLMProvider = (*BedrockClient)(nil)
 type BedrockClient struct {
 	client       *bedrockruntime.Client
 	defaultModel string
+	generation   GenerationSettings
 }
 
 // Message represents a conversation message
 type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role    string            `json:"role"`
+	Content string            `json:"content"`
+	Images  []ImageAttachment `json:"-"` // Sent by multimodal providers only
 }
 
 // ClaudeRequest represents the request body for Claude models
 type ClaudeRequest struct {
-	AnthropicVersion string    `json:"anthropic_version"`
-	MaxTokens        int       `json:"max_tokens"`
-	Messages         []Message `json:"messages"`
-	System           string    `json:"system,omitempty"`
+	AnthropicVersion string          `json:"anthropic_version"`
+	MaxTokens        int             `json:"max_tokens"`
+	Messages         []ClaudeMessage `json:"messages"`
+	System           string          `json:"system,omitempty"`
+	Temperature      *float64        `json:"temperature,omitempty"`
+	TopP             *float64        `json:"top_p,omitempty"`
+}
+
+// ClaudeMessage is a message in the Claude Messages API format
+// Content is a plain string, or content blocks when images are attached
+type ClaudeMessage struct {
+	Role    string      `json:"role"`
+	Content interface{} `json:"content"`
+}
+
+// ClaudeContentBlock is a text or image content block
+type ClaudeContentBlock struct {
+	Type   string             `json:"type"`
+	Text   string             `json:"text,omitempty"`
+	Source *ClaudeImageSource `json:"source,omitempty"`
+}
+
+// ClaudeImageSource holds base64 image data for an image block
+type ClaudeImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+}
+
+// toClaudeMessages converts bjarne Messages to Claude format (shared by Bedrock and Anthropic)
+func toClaudeMessages(messages []Message) []ClaudeMessage {
+	result := make([]ClaudeMessage, 0, len(messages))
+	for _, msg := range messages {
+		if len(msg.Images) == 0 {
+			result = append(result, ClaudeMessage{Role: msg.Role, Content: msg.Content})
+			continue
+		}
+
+		// Images first, then the text that refers to them
+		blocks := make([]ClaudeContentBlock, 0, len(msg.Images)+1)
+		for _, img := range msg.Images {
+			blocks = append(blocks, ClaudeContentBlock{
+				Type:   "image",
+				Source: &ClaudeImageSource{Type: "base64", MediaType: img.MediaType, Data: img.Base64()},
+			})
+		}
+		blocks = append(blocks, ClaudeContentBlock{Type: "text", Text: msg.Content})
+		result = append(result, ClaudeMessage{Role: msg.Role, Content: blocks})
+	}
+	return result
 }
 
 // ClaudeResponse represents the response from Claude models
@@ -53,6 +101,8 @@ type GenerateResult struct {
 	Text         string
 	InputTokens  int
 	OutputTokens int
+	Dropped      int  // Oldest messages left out to fit the model's context window
+	Estimated    bool // Token counts were computed locally because the provider did not report them
 }
 
 // StreamCallback is called for each chunk of streamed text
@@ -74,8 +124,13 @@ type StreamEvent struct {
 // NewBedrockClient creates a new Bedrock client with configuration from environment
 func NewBedrockClient(ctx context.Context, defaultModel string) (*BedrockClient, error) {
 	// Load AWS config from environment/credentials
+	httpClient, err := NewHTTPClient(endpointBedrock, 0)
+	if err != nil {
+		return nil, err
+	}
 	cfg, err := config.LoadDefaultConfig(ctx,
 		config.WithRegion(getEnvOrDefault("AWS_REGION", "us-east-1")),
+		config.WithHTTPClient(httpClient),
 	)
 	if err != nil {
 		return nil, ErrAWSConfig(err)
@@ -106,11 +161,14 @@ func (b *BedrockClient) GenerateWithTokens(ctx context.Context, systemPrompt str
 
 // GenerateWithModel sends a prompt to a specific model and returns response with token usage
 func (b *BedrockClient) GenerateWithModel(ctx context.Context, modelID, systemPrompt string, messages []Message, maxTokens int) (*GenerateResult, error) {
+	temperature, topP := b.generation.claudeSampling()
 	request := ClaudeRequest{
 		AnthropicVersion: "bedrock-2023-05-31",
 		MaxTokens:        maxTokens,
-		Messages:         messages,
+		Messages:         toClaudeMessages(messages),
 		System:           systemPrompt,
+		Temperature:      temperature,
+		TopP:             topP,
 	}
 
 	requestBody, err := json.Marshal(request)
@@ -159,11 +217,14 @@ func (b *BedrockClient) GenerateWithModel(ctx context.Context, modelID, systemPr
 
 // GenerateStreaming sends a prompt and streams the response, calling callback for each chunk
 func (b *BedrockClient) GenerateStreaming(ctx context.Context, modelID, systemPrompt string, messages []Message, maxTokens int, callback StreamCallback) (*GenerateResult, error) {
+	temperature, topP := b.generation.claudeSampling()
 	request := ClaudeRequest{
 		AnthropicVersion: "bedrock-2023-05-31",
 		MaxTokens:        maxTokens,
-		Messages:         messages,
+		Messages:         toClaudeMessages(messages),
 		System:           systemPrompt,
+		Temperature:      temperature,
+		TopP:             topP,
 	}
 
 	requestBody, err := json.Marshal(request)
@@ -217,10 +278,12 @@ func (b *BedrockClient) GenerateStreaming(ctx context.Context, modelID, systemPr
 		return nil, fmt.Errorf("stream error: %w", err)
 	}
 
-	return &GenerateResult{
+	result := &GenerateResult{
 		Text:         fullText,
 		OutputTokens: outputTokens,
-	}, nil
+	}
+	fillUsageEstimate(result, systemPrompt, messages)
+	return result, nil
 }
 
 // GetDefaultModel returns the configured default model ID
@@ -255,8 +318,13 @@ func NewBedrockProvider(ctx context.Context, cfg *ProviderConfig) (LLMProvider,
 		region = getEnvOrDefault("AWS_REGION", "us-east-1")
 	}
 
+	httpClient, err := NewHTTPClient(endpointBedrock, 0)
+	if err != nil {
+		return nil, err
+	}
 	awsCfg, err := config.LoadDefaultConfig(ctx,
 		config.WithRegion(region),
+		config.WithHTTPClient(httpClient),
 	)
 	if err != nil {
 		return nil, ErrAWSConfig(err)
@@ -273,9 +341,15 @@ func NewBedrockProvider(ctx context.Context, cfg *ProviderConfig) (LLMProvider,
 	return &BedrockClient{
 		client:       client,
 		defaultModel: defaultModel,
+		generation:   cfg.Generation,
 	}, nil
 }
 
+// SetGeneration sets temperature and top-p for subsequent requests (Claude has no seed)
+func (b *BedrockClient) SetGeneration(gen GenerationSettings) {
+	b.generation = gen
+}
+
 // getEnvOrDefault returns the environment variable value or a default
 func getEnvOrDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
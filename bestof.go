@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxCandidates caps best-of-N to keep container and token usage bounded
+const maxCandidates = 8
+
+// Candidate is one generation in best-of-N mode, validated independently of the others
+type Candidate struct {
+	Index        int
+	Provider     string
+	Model        string
+	Text         string // Raw LLM response
+	Code         string
+	Files        []CodeFile
+	Results      []ValidationResult
+	Formatted    []CodeFile
+	Confidence   int // Review confidence (0 if not reviewed)
+	Summary      string
+	ReviewErr    error
+	InputTokens  int
+	OutputTokens int
+	Duration     time.Duration // Generation through review
+	Err          error         // Generation or validation system error
+}
+
+// GatesPassed counts passed and total validation gates
+func (c *Candidate) GatesPassed() (passed, total int) {
+	for _, r := range c.Results {
+		if r.Success {
+			passed++
+		}
+	}
+	return passed, len(c.Results)
+}
+
+// validatedFiles returns the files the candidate's code was validated as
+func (c *Candidate) validatedFiles() []CodeFile {
+	if len(c.Files) > 1 {
+		return c.Files
+	}
+	return []CodeFile{{Filename: "code.cpp", Content: c.Code}}
+}
+
+// Passed reports whether the candidate passed every gate
+func (c *Candidate) Passed() bool {
+	return c.Err == nil && len(c.Results) > 0 && allPassed(c.Results)
+}
+
+// RunTime is the time spent in run stages (benchmark signal, lower is better)
+func (c *Candidate) RunTime() time.Duration {
+	var d time.Duration
+	for _, r := range c.Results {
+		if r.Stage == "run" || r.Stage == string(ValidatorBenchmark) {
+			d += r.Duration
+		}
+	}
+	return d
+}
+
+// rankCandidates orders candidates best first: all gates passed, then most gates passed,
+// then review confidence, then fastest run time
+func rankCandidates(cands []Candidate) []Candidate {
+	ranked := append([]Candidate(nil), cands...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := &ranked[i], &ranked[j]
+		if (a.Err == nil) != (b.Err == nil) {
+			return a.Err == nil
+		}
+		if a.Passed() != b.Passed() {
+			return a.Passed()
+		}
+		pa, _ := a.GatesPassed()
+		pb, _ := b.GatesPassed()
+		if pa != pb {
+			return pa > pb
+		}
+		if a.Confidence != b.Confidence {
+			return a.Confidence > b.Confidence
+		}
+		return a.RunTime() < b.RunTime()
+	})
+	return ranked
+}
+
+// bestOfCandidates bounds bestOf.candidates to 1..maxCandidates; settings validation reports values outside it
+func bestOfCandidates(n int) int {
+	return min(max(n, 1), maxCandidates)
+}
+
+// candidateModels picks the model for each of n candidates, at most maxCandidates
+// Models from settings are used round-robin; otherwise every candidate uses base
+func candidateModels(configured []string, base string, n int) []string {
+	models := make([]string, bestOfCandidates(n))
+	for i := range models {
+		if len(configured) > 0 {
+			models[i] = configured[i%len(configured)]
+		} else {
+			models[i] = base
+		}
+	}
+	return models
+}
+
+// candidateSpec is the provider and model that generate one candidate
+type candidateSpec struct {
+	provider LLMProvider
+	model    string
+}
+
+// candidateRequest holds everything needed to generate and validate candidates
+type candidateRequest struct {
+	provider       LLMProvider // Used for review (and generation unless the spec overrides it)
+	container      *ContainerRuntime
+	systemPrompt   string
+	conversation   []Message
+	maxTokens      int
+	originalPrompt string
+	review         reviewPanel
+	prompts        *PromptSet
+	examples       *ExampleTests
+	dod            *DefinitionOfDone
+	autoFormat     bool
+}
+
+// generateCandidates generates, validates and reviews one candidate per spec concurrently
+func generateCandidates(ctx context.Context, req candidateRequest, specs []candidateSpec) []Candidate {
+	cands := make([]Candidate, len(specs))
+	var wg sync.WaitGroup
+	for i, spec := range specs {
+		wg.Add(1)
+		go func(i int, spec candidateSpec) {
+			defer wg.Done()
+			start := time.Now()
+			cands[i] = generateCandidate(ctx, req, spec, i+1)
+			cands[i].Duration = time.Since(start)
+		}(i, spec)
+	}
+	wg.Wait()
+	return cands
+}
+
+// generateCandidate runs one candidate through generation, validation and review
+func generateCandidate(ctx context.Context, req candidateRequest, spec candidateSpec, index int) Candidate {
+	provider := spec.provider
+	if provider == nil {
+		provider = req.provider
+	}
+	c := Candidate{Index: index, Provider: provider.Name(), Model: spec.model}
+	model := spec.model
+	if IsCanonicalModel(model) {
+		model = provider.MapModel(model)
+	}
+
+	result, err := provider.Generate(ctx, model, req.systemPrompt, req.conversation, req.maxTokens)
+	if err != nil {
+		c.Err = err
+		return c
+	}
+	c.Text = result.Text
+	c.InputTokens, c.OutputTokens = result.InputTokens, result.OutputTokens
+
+	c.Files = extractMultipleFiles(result.Text)
+	c.Code = extractCode(result.Text)
+	if len(c.Files) == 0 {
+		c.Err = fmt.Errorf("no code block in response")
+		return c
+	}
+
+	if len(c.Files) > 1 {
+		c.Results, err = req.container.ValidateMultiFileCodeWithExamples(ctx, c.Files, req.examples, req.dod)
+	} else {
+		c.Results, err = req.container.ValidateCodeWithExamples(ctx, c.Code, "code.cpp", req.examples, req.dod)
+	}
+	if err != nil {
+		c.Err = err
+		return c
+	}
+	if !c.Passed() {
+		return c
+	}
+
+	if req.autoFormat {
+		if f, fmtErr := req.container.FormatFiles(ctx, c.validatedFiles()); fmtErr == nil {
+			c.Formatted = f
+		}
+	}
+
+	review, err := req.review.Assess(ctx, req.provider, req.prompts, req.container, req.originalPrompt, c.Code, c.validatedFiles())
+	c.InputTokens += review.Usage.InputTokens
+	c.OutputTokens += review.Usage.OutputTokens
+	if err != nil {
+		c.ReviewErr = err
+		return c
+	}
+	c.Confidence, c.Summary = review.Confidence, review.Summary
+	return c
+}
+
+// formatCandidateTable renders a comparison of ranked candidates (winner first)
+func formatCandidateTable(ranked []Candidate) []string {
+	lines := []string{fmt.Sprintf("  %-3s %-22s %-7s %-11s %-9s %s", "#", "Model", "Gates", "Confidence", "Run", "Result")}
+	for i, c := range ranked {
+		gates, confidence, run, result := "-", "-", "-", "failed"
+		if c.Err != nil {
+			result = "error: " + truncateError(c.Err.Error(), 40)
+		} else {
+			passed, total := c.GatesPassed()
+			gates = fmt.Sprintf("%d/%d", passed, total)
+			if c.Passed() {
+				result = "passed"
+				if c.ReviewErr == nil {
+					confidence = fmt.Sprintf("%d%%", c.Confidence)
+				}
+			}
+			if rt := c.RunTime(); rt > 0 {
+				run = rt.Round(time.Millisecond).String()
+			}
+		}
+		if i == 0 && c.Err == nil {
+			result += "  <- winner"
+		}
+		lines = append(lines, fmt.Sprintf("  %-3d %-22s %-7s %-11s %-9s %s", c.Index, shortModelName(c.Model), gates, confidence, run, result))
+	}
+	return lines
+}
+
+// truncateError shortens an error message to limit characters for table display
+func truncateError(msg string, limit int) string {
+	msg = strings.SplitN(msg, "\n", 2)[0]
+	if len(msg) <= limit {
+		return msg
+	}
+	return msg[:limit-3] + "..."
+}
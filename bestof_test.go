@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRankCandidates(t *testing.T) {
+	pass := func(stage string) ValidationResult { return ValidationResult{Stage: stage, Success: true} }
+	fail := func(stage string) ValidationResult { return ValidationResult{Stage: stage} }
+
+	cands := []Candidate{
+		{Index: 1, Err: errors.New("timeout")},
+		{Index: 2, Results: []ValidationResult{pass("compile"), fail("asan")}},
+		{Index: 3, Results: []ValidationResult{pass("compile"), pass("asan")}, Confidence: 80},
+		{Index: 4, Results: []ValidationResult{pass("compile"), pass("asan")}, Confidence: 95},
+		{Index: 5, Results: []ValidationResult{pass("compile"), {Stage: "run", Success: true, Duration: 5 * time.Millisecond}}, Confidence: 95},
+	}
+
+	ranked := rankCandidates(cands)
+	var order []int
+	for _, c := range ranked {
+		order = append(order, c.Index)
+	}
+	// 4 and 5 tie on confidence; 4 has no run time recorded so sorts first
+	want := []int{4, 5, 3, 2, 1}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("rank order = %v, want %v", order, want)
+		}
+	}
+}
+
+func TestCandidateModels(t *testing.T) {
+	got := candidateModels([]string{"sonnet", "opus"}, "haiku", 3)
+	if strings.Join(got, ",") != "sonnet,opus,sonnet" {
+		t.Errorf("round-robin models = %v", got)
+	}
+	got = candidateModels(nil, "haiku", 2)
+	if strings.Join(got, ",") != "haiku,haiku" {
+		t.Errorf("default models = %v", got)
+	}
+	if got := candidateModels(nil, "haiku", 1000); len(got) != maxCandidates {
+		t.Errorf("candidateModels() with 1000 candidates made %d, want %d", len(got), maxCandidates)
+	}
+}
+
+func TestFormatCandidateTable(t *testing.T) {
+	ranked := []Candidate{
+		{Index: 2, Model: "global.anthropic.claude-sonnet-4-5-20250929-v1:0", Results: []ValidationResult{{Stage: "compile", Success: true}}, Confidence: 90},
+		{Index: 1, Model: "haiku", Err: errors.New("no code block in response")},
+	}
+	lines := formatCandidateTable(ranked)
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want header + 2", len(lines))
+	}
+	if !strings.Contains(lines[1], "claude-sonnet-4-5") || !strings.Contains(lines[1], "90%") || !strings.Contains(lines[1], "winner") {
+		t.Errorf("winner row = %q", lines[1])
+	}
+	if !strings.Contains(lines[2], "error: no code block") {
+		t.Errorf("error row = %q", lines[2])
+	}
+}
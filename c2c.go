@@ -0,0 +1,242 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// c2cStage runs the program under perf c2c; it gets CAP_PERFMON so perf can open its events
+const c2cStage = "cache-c2c"
+
+// c2cUnavailableMarker starts the reason perf c2c could not run
+const c2cUnavailableMarker = "bjarne-c2c: unavailable:"
+
+// c2cReportMarker starts perf c2c's report in the stage's output
+const c2cReportMarker = "bjarne-c2c-report:"
+
+// c2cLayoutsMarker starts clang's record layouts in the stage's output
+const c2cLayoutsMarker = "bjarne-c2c-layouts:"
+
+// cacheLineBytes is the cache line size the false-sharing advice assumes
+const cacheLineBytes = 64
+
+// threadPattern matches code that starts threads, without which nothing can contend for a cache line
+var threadPattern = regexp.MustCompile(`\bstd::(?:thread|jthread|async)\b|\bpthread_create\b|#pragma omp parallel`)
+
+var (
+	c2cLinePattern      = regexp.MustCompile(`^\s*\d+(?:\s+\d+)+\s+(0x[0-9a-f]+)\s*$`)
+	c2cSymbolPattern    = regexp.MustCompile(`\[\.\]\s+(.+?)\s{2,}`)
+	c2cSourcePattern    = regexp.MustCompile(`\s(\S+):(\d+)\b`)
+	layoutFieldPattern  = regexp.MustCompile(`^\s*(\d+)(?::[\d-]+)? \|( +)(\S.*?)\s*$`)
+	layoutRecordPattern = regexp.MustCompile(`^(?:struct|class|union) (\S+)`)
+	memberAccessPattern = regexp.MustCompile(`(?:\.|->)\s*(\w+)`)
+)
+
+// C2CAccess is a load or store perf c2c attributes to a contended cache line
+type C2CAccess struct {
+	Offset int // Byte offset in the cache line
+	Symbol string
+	File   string
+	Line   int
+}
+
+// C2CLine is a cache line with HITM (modified-line) hits from more than one core
+type C2CLine struct {
+	Address  string
+	HITM     int
+	Accesses []C2CAccess
+}
+
+// parseC2CReport reads the cache lines and their accesses from perf c2c report --stdio
+// The header of each line in the Pareto table is its index, HITM and store counts and address;
+// the rows below it are the accesses
+func parseC2CReport(report string) []C2CLine {
+	var lines []C2CLine
+	var current *C2CLine
+	for _, row := range strings.Split(report, "\n") {
+		if m := c2cLinePattern.FindStringSubmatch(row); m != nil {
+			fields := strings.Fields(row)
+			hitm := 0
+			for _, f := range fields[1:3] {
+				n, _ := strconv.Atoi(f)
+				hitm += n
+			}
+			lines = append(lines, C2CLine{Address: m[1], HITM: hitm})
+			current = &lines[len(lines)-1]
+			continue
+		}
+		if current == nil || !strings.Contains(row, "[.]") {
+			continue
+		}
+		a := C2CAccess{Offset: -1}
+		for _, f := range strings.Fields(row) {
+			if strings.HasPrefix(f, "0x") {
+				if off, err := strconv.ParseInt(f[2:], 16, 64); err == nil {
+					a.Offset = int(off)
+				}
+				break
+			}
+		}
+		if m := c2cSymbolPattern.FindStringSubmatch(row); m != nil {
+			a.Symbol = m[1]
+		}
+		if m := c2cSourcePattern.FindAllStringSubmatch(row, -1); m != nil {
+			last := m[len(m)-1]
+			a.File = last[1]
+			a.Line, _ = strconv.Atoi(last[2])
+		}
+		current.Accesses = append(current.Accesses, a)
+	}
+	return lines
+}
+
+// RecordField is a top-level member of a record from clang's -fdump-record-layouts
+type RecordField struct {
+	Record string
+	Name   string
+	Offset int
+}
+
+// parseRecordLayouts reads the top-level members of every record clang laid out, leaving out the standard library's
+func parseRecordLayouts(dump string) map[string][]RecordField {
+	records := make(map[string][]RecordField)
+	record, indent, starting := "", 0, false
+	for _, row := range strings.Split(dump, "\n") {
+		if strings.Contains(row, "*** Dumping AST Record Layout") {
+			record, starting = "", true
+			continue
+		}
+		m := layoutFieldPattern.FindStringSubmatch(row)
+		if m == nil {
+			continue
+		}
+		if starting {
+			// The first row names the record; its members are indented two more spaces
+			starting = false
+			if r := layoutRecordPattern.FindStringSubmatch(m[3]); r != nil && !strings.HasPrefix(r[1], "std::") && !strings.HasPrefix(r[1], "__") {
+				record, indent = r[1], len(m[2])
+			}
+			continue
+		}
+		if record == "" || len(m[2]) != indent+2 || strings.HasSuffix(m[3], "(base)") || strings.HasSuffix(m[3], "(primary base)") {
+			continue
+		}
+		fields := strings.Fields(m[3])
+		offset, _ := strconv.Atoi(m[1])
+		records[record] = append(records[record], RecordField{Record: record, Name: fields[len(fields)-1], Offset: offset})
+	}
+	return records
+}
+
+// accessedMembers finds the record members a line of code reads or writes through . or ->
+func accessedMembers(line string, records map[string][]RecordField) []RecordField {
+	var found []RecordField
+	for _, m := range memberAccessPattern.FindAllStringSubmatch(line, -1) {
+		for _, name := range sortedKeys(records) {
+			for _, f := range records[name] {
+				if f.Name == m[1] && !containsField(found, f) {
+					found = append(found, f)
+				}
+			}
+		}
+	}
+	return found
+}
+
+// containsField reports whether fields holds f
+func containsField(fields []RecordField, f RecordField) bool {
+	for _, g := range fields {
+		if g == f {
+			return true
+		}
+	}
+	return false
+}
+
+// c2cFindings turns the contended cache lines in the checked file into diagnostics, naming the struct
+// members each access touches. Accesses at different offsets are false sharing; all at one offset
+// is a single variable contended on purpose or not, reported as a note
+func c2cFindings(lines []C2CLine, code, filename string, records map[string][]RecordField) []string {
+	source := strings.Split(code, "\n")
+	var findings []string
+	for _, cl := range lines {
+		var accesses []C2CAccess
+		offsets := make(map[int]bool)
+		for _, a := range cl.Accesses {
+			if a.Line > 0 && a.Line <= len(source) && strings.HasSuffix(a.File, filename) {
+				accesses = append(accesses, a)
+				offsets[a.Offset] = true
+			}
+		}
+		if cl.HITM == 0 || len(accesses) == 0 {
+			continue
+		}
+		var members, sites []string
+		for _, a := range accesses {
+			for _, f := range accessedMembers(source[a.Line-1], records) {
+				if m := fmt.Sprintf("%s::%s (offset %d)", f.Record, f.Name, f.Offset); !slices.Contains(members, m) {
+					members = append(members, m)
+				}
+			}
+			if site := fmt.Sprintf("%s at line %d", a.Symbol, a.Line); !slices.Contains(sites, site) {
+				sites = append(sites, site)
+			}
+		}
+		what := "data"
+		if len(members) > 0 {
+			what = strings.Join(members, ", ")
+		}
+		level, check, advice := LevelWarning, "false-sharing",
+			fmt.Sprintf("put each on its own cache line with alignas(%d) or std::hardware_destructive_interference_size", cacheLineBytes)
+		if len(offsets) < 2 {
+			level, check, advice = LevelNote, "cache-line-contention", "threads write the same variable; batch the updates per thread or shard it"
+		}
+		findings = append(findings, fmt.Sprintf("/src/%s:%d:1: %s: cache line %s bounced between cores %d times (HITM): %s accessed by %s; %s [%s]",
+			filename, accesses[0].Line, level, cl.Address, cl.HITM, what, strings.Join(sites, ", "), advice, check))
+	}
+	return findings
+}
+
+// c2cCommand builds the program with debug info, dumps its record layouts and runs it under perf c2c
+// It prints c2cUnavailableMarker and exits 0 when perf is missing or cannot sample in this container
+func (c *ContainerRuntime) c2cCommand(filename string) string {
+	build := c.cxx(c.stdFlag() + " -O2 -g -pthread -o /tmp/c2c_test /src/" + filename)
+	layouts := c.cxx(c.stdFlag() + " -fsyntax-only -Xclang -fdump-record-layouts /src/" + filename)
+	run := c.programCommand("", "/tmp/c2c_test")
+	return fmt.Sprintf("command -v perf >/dev/null 2>&1 || { echo '%s perf is not installed in the validator image'; exit 0; }; "+
+		"%s || exit 1; %s > /tmp/c2c_layouts.txt 2>/dev/null; "+
+		"perf c2c record -o /tmp/c2c.data -- sh -c '%s' > /tmp/c2c_run.log 2>&1 || { echo '%s perf c2c record failed'; tail -n 5 /tmp/c2c_run.log; exit 0; }; "+
+		"echo '%s'; perf c2c report -i /tmp/c2c.data --stdio --full-symbols 2>&1; echo '%s'; cat /tmp/c2c_layouts.txt",
+		c2cUnavailableMarker, build, layouts, strings.ReplaceAll(run, "'", `'\''`), c2cUnavailableMarker, c2cReportMarker, c2cLayoutsMarker)
+}
+
+// runC2CCheck runs threaded code under perf c2c and reports the cache lines its threads contend for
+// It returns the findings, the metrics and a note when the check was skipped
+func (c *ContainerRuntime) runC2CCheck(run func(stage string, command ...string) ValidationResult, code, filename string) ([]string, map[string]interface{}, string) {
+	if !threadPattern.MatchString(code) {
+		return nil, nil, "perf c2c: skipped, the code starts no threads"
+	}
+	result := run(c2cStage, "sh", "-c", c.c2cCommand(filename))
+	if !result.Success {
+		return nil, nil, "perf c2c: the program did not build or run:\n" + result.Output + result.Error
+	}
+	if _, reason, ok := strings.Cut(result.Output, c2cUnavailableMarker); ok {
+		return nil, nil, "perf c2c: skipped," + strings.TrimRight(reason, "\n")
+	}
+	_, rest, _ := strings.Cut(result.Output, c2cReportMarker)
+	report, dump, _ := strings.Cut(rest, c2cLayoutsMarker)
+	lines := parseC2CReport(report)
+	findings := c2cFindings(lines, code, filename, parseRecordLayouts(dump))
+	hitm := 0
+	for _, cl := range lines {
+		hitm += cl.HITM
+	}
+	metrics := map[string]interface{}{"c2c_hitm": hitm, "c2c_contended_lines": len(findings)}
+	if len(findings) == 0 {
+		return nil, metrics, fmt.Sprintf("perf c2c: no contended cache lines in %s (%d HITM in total)", filename, hitm)
+	}
+	return findings, metrics, ""
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const c2cCode = `#include <atomic>
+#include <thread>
+
+struct Counters {
+    std::atomic<long> a{0};
+    std::atomic<long> b{0};
+};
+
+Counters counters;
+
+void worker_a(Counters* c) {
+    for (int i = 0; i < 1000000; ++i) c->a.fetch_add(1, std::memory_order_relaxed);
+}
+
+void worker_b(Counters* c) {
+    for (int i = 0; i < 1000000; ++i) c->b.fetch_add(1, std::memory_order_relaxed);
+}
+
+int main() {
+    std::thread t1(worker_a, &counters), t2(worker_b, &counters);
+    t1.join();
+    t2.join();
+}
+`
+
+// c2cReport is perf c2c report --stdio output, trimmed to the Pareto table
+const c2cReport = `=================================================
+      Shared Cache Line Distribution Pareto
+=================================================
+#
+#        ----- HITM -----  -- Store Refs --  ------- CL --------                      ---------- cycles ----------    Total       cpu                                  Shared
+#   Num  RmtHitm  LclHitm   L1 Hit  L1 Miss      Off  Node  PA cnt        Code address  rmt hitm  lcl hitm      load  records       cnt                        Symbol  Object        Source:Line  Node
+#
+  -------------------------------------------------------------------------------
+      0        0     2241    12853        0      0x55d0c4e4b040
+  -------------------------------------------------------------------------------
+           0.00%   51.27%   49.96%    0.00%                 0x0     0       1      0x55d0c4e4a1d4         0       154       121    12281         2  [.] worker_a(Counters*)     c2c_test  code.cpp:12        0
+           0.00%   48.73%   50.04%    0.00%                 0x8     0       1      0x55d0c4e4a214         0       149       117    12222         2  [.] worker_b(Counters*)     c2c_test  code.cpp:16        0
+  -------------------------------------------------------------------------------
+      1        0       12      300        0      0x7f1a2b3c4d80
+  -------------------------------------------------------------------------------
+           0.00%  100.00%  100.00%    0.00%                0x10     0       1      0x7f1a2b3c1000         0        80        60      310         2  [.] __lll_lock_wait     libc.so.6  lowlevellock.c:49        0
+`
+
+const c2cLayouts = `
+*** Dumping AST Record Layout
+         0 | struct std::__atomic_base<long>
+         0 |   __int_type _M_i
+           | [sizeof=8, dsize=8, align=8,
+           |  nvsize=8, nvalign=8]
+
+*** Dumping AST Record Layout
+         0 | struct Counters
+         0 |   std::atomic<long> a
+         0 |     struct std::__atomic_base<long> (base)
+         0 |       __int_type _M_i
+         8 |   std::atomic<long> b
+         8 |     struct std::__atomic_base<long> (base)
+         8 |       __int_type _M_i
+           | [sizeof=16, dsize=16, align=8,
+           |  nvsize=16, nvalign=8]
+`
+
+func TestParseC2CReport(t *testing.T) {
+	lines := parseC2CReport(c2cReport)
+	if len(lines) != 2 || lines[0].Address != "0x55d0c4e4b040" || lines[0].HITM != 2241 || lines[1].HITM != 12 {
+		t.Fatalf("parseC2CReport() = %+v, want two cache lines", lines)
+	}
+	want := []C2CAccess{{0, "worker_a(Counters*)", "code.cpp", 12}, {8, "worker_b(Counters*)", "code.cpp", 16}}
+	if len(lines[0].Accesses) != 2 || lines[0].Accesses[0] != want[0] || lines[0].Accesses[1] != want[1] {
+		t.Errorf("accesses = %+v, want %+v", lines[0].Accesses, want)
+	}
+}
+
+func TestParseRecordLayouts(t *testing.T) {
+	records := parseRecordLayouts(c2cLayouts)
+	want := []RecordField{{"Counters", "a", 0}, {"Counters", "b", 8}}
+	if len(records) != 1 || len(records["Counters"]) != 2 || records["Counters"][0] != want[0] || records["Counters"][1] != want[1] {
+		t.Errorf("parseRecordLayouts() = %+v, want %+v", records, want)
+	}
+}
+
+func TestC2CFindings(t *testing.T) {
+	findings := c2cFindings(parseC2CReport(c2cReport), c2cCode, "code.cpp", parseRecordLayouts(c2cLayouts))
+	if len(findings) != 1 {
+		t.Fatalf("c2cFindings() = %v, want the user's cache line only", findings)
+	}
+	for _, want := range []string{"/src/code.cpp:12:1: warning:", "2241 times", "Counters::a (offset 0), Counters::b (offset 8)",
+		"worker_a(Counters*) at line 12, worker_b(Counters*) at line 16", "alignas(64)", "[false-sharing]"} {
+		if !strings.Contains(findings[0], want) {
+			t.Errorf("c2cFindings() = %q, missing %q", findings[0], want)
+		}
+	}
+
+	single := []C2CLine{{Address: "0x10", HITM: 5, Accesses: []C2CAccess{{0, "worker_a(Counters*)", "code.cpp", 12}, {0, "worker_b(Counters*)", "code.cpp", 12}}}}
+	if got := c2cFindings(single, c2cCode, "code.cpp", nil); len(got) != 1 || !strings.Contains(got[0], "note:") || !strings.Contains(got[0], "[cache-line-contention]") {
+		t.Errorf("c2cFindings(one offset) = %v, want a contention note", got)
+	}
+}
+
+func TestRunC2CCheck(t *testing.T) {
+	c := &ContainerRuntime{}
+	tests := []struct {
+		name     string
+		code     string
+		output   string
+		findings int
+		note     string
+	}{
+		{"no threads", "int main() {}", "", 0, "the code starts no threads"},
+		{"perf missing", c2cCode, c2cUnavailableMarker + " perf is not installed in the validator image\n", 0, "skipped, perf is not installed"},
+		{"contention", c2cCode, c2cReportMarker + "\n" + c2cReport + c2cLayoutsMarker + "\n" + c2cLayouts, 1, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stage string
+			findings, _, note := c.runC2CCheck(func(s string, command ...string) ValidationResult {
+				stage = s
+				return ValidationResult{Stage: s, Success: true, Output: tt.output}
+			}, tt.code, "code.cpp")
+			if len(findings) != tt.findings || !strings.Contains(note, tt.note) {
+				t.Errorf("runC2CCheck() = %v, %q; want %d finding(s) and %q", findings, note, tt.findings, tt.note)
+			}
+			if tt.output != "" && stage != c2cStage {
+				t.Errorf("runC2CCheck() ran stage %q, want %q", stage, c2cStage)
+			}
+		})
+	}
+	if script := c.c2cCommand("code.cpp"); !strings.Contains(script, "perf c2c record -o /tmp/c2c.data") || !strings.Contains(script, "-fdump-record-layouts") {
+		t.Errorf("c2cCommand() = %q", script)
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// checkpointNamePattern restricts checkpoint names to safe file names
+var checkpointNamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// checkpointGate is the outcome of one validation gate when a checkpoint was taken
+type checkpointGate struct {
+	Stage   string `json:"stage"`
+	Passed  bool   `json:"passed"`
+	Skipped bool   `json:"skipped,omitempty"`
+}
+
+// Checkpoint is a named snapshot of the generated code (/checkpoint, /restore, /diff)
+type Checkpoint struct {
+	Name       string            `json:"name"`
+	Created    time.Time         `json:"created"`
+	Prompt     string            `json:"prompt,omitempty"`
+	Files      []CodeFile        `json:"files"`
+	DoD        *DefinitionOfDone `json:"dod,omitempty"`
+	Examples   *ExampleTests     `json:"examples,omitempty"`
+	Validated  bool              `json:"validated"`
+	Gates      []checkpointGate  `json:"gates,omitempty"`
+	Confidence int               `json:"confidence,omitempty"`
+}
+
+// Status summarizes the validation state captured by the checkpoint
+func (c *Checkpoint) Status() string {
+	if len(c.Gates) == 0 {
+		if c.Validated {
+			return "validated"
+		}
+		return "not validated"
+	}
+	passed, run := 0, 0
+	for _, g := range c.Gates {
+		if g.Skipped {
+			continue
+		}
+		run++
+		if g.Passed {
+			passed++
+		}
+	}
+	status := fmt.Sprintf("%d/%d gates passed", passed, run)
+	if c.Validated && c.Confidence > 0 {
+		status += fmt.Sprintf(", %d%% confidence", c.Confidence)
+	}
+	return status
+}
+
+// checkpointGates records the gate outcomes of a validation run
+func checkpointGates(results []ValidationResult) []checkpointGate {
+	gates := make([]checkpointGate, 0, len(results))
+	for _, r := range results {
+		gates = append(gates, checkpointGate{Stage: r.Stage, Passed: r.Success, Skipped: r.Skipped})
+	}
+	return gates
+}
+
+// CheckpointStore keeps a session's checkpoints under ~/.bjarne/history/<session>/checkpoints
+type CheckpointStore struct {
+	dir string
+}
+
+// checkpointDir returns the checkpoint directory for a session
+func checkpointDir(session string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".bjarne", "history", session, "checkpoints"), nil
+}
+
+// validateCheckpointName rejects names that are not safe file names
+func validateCheckpointName(name string) error {
+	if !checkpointNamePattern.MatchString(name) || strings.Trim(name, ".") == "" {
+		return fmt.Errorf("invalid checkpoint name %q (use letters, digits, '.', '_' or '-', up to 64 characters)", name)
+	}
+	return nil
+}
+
+// Save writes a checkpoint, replacing any earlier one with the same name
+func (s CheckpointStore) Save(c *Checkpoint) error {
+	if err := validateCheckpointName(c.Name); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(s.dir, c.Name+".json"), data, 0600); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Load reads the checkpoint with the given name
+func (s CheckpointStore) Load(name string) (*Checkpoint, error) {
+	if err := validateCheckpointName(name); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(s.dir, name+".json"))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no checkpoint named %q in this session", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	var c Checkpoint
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint %q: %w", name, err)
+	}
+	return &c, nil
+}
+
+// List returns the session's checkpoints, oldest first
+// Unreadable checkpoint files are skipped
+func (s CheckpointStore) List() []*Checkpoint {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil
+	}
+	var checkpoints []*Checkpoint
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok || e.IsDir() {
+			continue
+		}
+		if c, err := s.Load(name); err == nil {
+			checkpoints = append(checkpoints, c)
+		}
+	}
+	sort.Slice(checkpoints, func(i, j int) bool { return checkpoints[i].Created.Before(checkpoints[j].Created) })
+	return checkpoints
+}
+
+// checkpointDiff diffs a checkpoint's files against the current files
+// It returns the unified diff and the lines added and removed since the checkpoint
+func checkpointDiff(c *Checkpoint, files []CodeFile) (string, int, int) {
+	old := make(map[string]string, len(c.Files))
+	var names []string
+	for _, f := range c.Files {
+		old[f.Filename] = f.Content
+		names = append(names, f.Filename)
+	}
+	cur := make(map[string]string, len(files))
+	for _, f := range files {
+		cur[f.Filename] = f.Content
+		if _, ok := old[f.Filename]; !ok {
+			names = append(names, f.Filename)
+		}
+	}
+
+	var sb strings.Builder
+	totalAdded, totalRemoved := 0, 0
+	for _, name := range names {
+		added, removed := DiffStats(LineDiff(old[name], cur[name]))
+		totalAdded += added
+		totalRemoved += removed
+		sb.WriteString(UnifiedDiff(c.Name+"/"+name, name, old[name], cur[name], 3))
+	}
+	return sb.String(), totalAdded, totalRemoved
+}
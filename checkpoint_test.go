@@ -0,0 +1,89 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateCheckpointName(t *testing.T) {
+	for _, name := range []string{"baseline", "v1.2", "before_fix-3"} {
+		if err := validateCheckpointName(name); err != nil {
+			t.Errorf("validateCheckpointName(%q) = %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "..", "../escape", "two words", strings.Repeat("x", 65)} {
+		if err := validateCheckpointName(name); err == nil {
+			t.Errorf("validateCheckpointName(%q) should fail", name)
+		}
+	}
+}
+
+func TestCheckpointStore(t *testing.T) {
+	store := CheckpointStore{dir: t.TempDir()}
+	if got := store.List(); len(got) != 0 {
+		t.Fatalf("List() on an empty store = %v", got)
+	}
+
+	now := time.Now()
+	baseline := &Checkpoint{
+		Name:       "baseline",
+		Created:    now,
+		Files:      []CodeFile{{Filename: "code.cpp", Content: "int main() {}\n"}},
+		DoD:        &DefinitionOfDone{ThreadSafe: true},
+		Validated:  true,
+		Gates:      checkpointGates([]ValidationResult{{Stage: "compile", Success: true}, {Stage: "asan", Success: true}, {Stage: "tsan", Success: true, Skipped: true}}),
+		Confidence: 85,
+	}
+	later := &Checkpoint{Name: "later", Created: now.Add(time.Minute), Files: baseline.Files}
+	for _, c := range []*Checkpoint{later, baseline} {
+		if err := store.Save(c); err != nil {
+			t.Fatalf("Save(%s) error = %v", c.Name, err)
+		}
+	}
+
+	got, err := store.Load("baseline")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got.Files[0].Content != "int main() {}\n" || got.DoD == nil || !got.DoD.ThreadSafe || !got.Validated {
+		t.Errorf("Load() = %+v, want the saved checkpoint", got)
+	}
+	if status := got.Status(); status != "2/2 gates passed, 85% confidence" {
+		t.Errorf("Status() = %q", status)
+	}
+
+	if _, err := store.Load("missing"); err == nil || !strings.Contains(err.Error(), "no checkpoint named") {
+		t.Errorf("Load(missing) error = %v", err)
+	}
+
+	list := store.List()
+	if len(list) != 2 || list[0].Name != "baseline" || list[1].Name != "later" {
+		t.Errorf("List() = %v, want oldest first", list)
+	}
+}
+
+func TestCheckpointDiff(t *testing.T) {
+	c := &Checkpoint{Name: "baseline", Files: []CodeFile{
+		{Filename: "queue.h", Content: "#pragma once\nstruct Q {};\n"},
+		{Filename: "old.cpp", Content: "int unused;\n"},
+	}}
+	current := []CodeFile{
+		{Filename: "queue.h", Content: "#pragma once\nstruct Q { int n; };\n"},
+		{Filename: "main.cpp", Content: "int main() {}\n"},
+	}
+
+	diff, added, removed := checkpointDiff(c, current)
+	if added != 2 || removed != 2 {
+		t.Errorf("checkpointDiff() = +%d -%d, want +2 -2", added, removed)
+	}
+	for _, want := range []string{"--- baseline/queue.h\n+++ queue.h", "-int unused;", "+int main() {}"} {
+		if !strings.Contains(diff, want) {
+			t.Errorf("diff missing %q:\n%s", want, diff)
+		}
+	}
+
+	if diff, _, _ := checkpointDiff(c, c.Files); diff != "" {
+		t.Errorf("checkpointDiff() of unchanged files = %q, want empty", diff)
+	}
+}
@@ -0,0 +1,463 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+// ciUsage is printed for bad `bjarne ci` arguments
+const ciUsage = "Usage: bjarne ci [--base <ref>] [--gates <list>] [--skip <list>] [--annotate github|none] [--sarif <file>] [files...]"
+
+// sarifSchema identifies the SARIF version bjarne writes
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+// ciOptions are the parsed `bjarne ci` arguments
+type ciOptions struct {
+	Base     string        // Diff base for changed files
+	Gates    GateSelection // Gates to run
+	Annotate bool          // Emit GitHub workflow commands for findings
+	SARIF    string        // Write findings as SARIF to this path
+	Files    []string      // Explicit files instead of the diff
+}
+
+// ciFileResult is the outcome of validating one changed file
+type ciFileResult struct {
+	Path     string // As given or as reported by git, relative to the working directory
+	Results  []ValidationResult
+	Findings []GateFinding
+	Err      error
+	Duration time.Duration
+}
+
+// Passed reports whether every gate that ran passed
+func (r ciFileResult) Passed() bool {
+	if r.Err != nil {
+		return false
+	}
+	for _, result := range r.Results {
+		if !result.Success {
+			return false
+		}
+	}
+	return true
+}
+
+// FindingPath returns the path of a finding's file relative to the working directory
+func (r ciFileResult) FindingPath(f GateFinding) string {
+	return filepath.ToSlash(r.Paths().Resolve(f.File))
+}
+
+// Paths maps the files validated for r, which sit next to it, to the workspace
+// Piped code has no place in the workspace, so its files keep their names
+func (r ciFileResult) Paths() PathMap {
+	if r.Path == stdinName {
+		return PathMap{}
+	}
+	return PathMap{Dir: filepath.Dir(r.Path)}
+}
+
+// runCI implements `bjarne ci`: validate the C/C++ files a change touches and report for CI
+func runCI(args []string) int {
+	opts, err := parseCIArgs(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n%s\n", err, ciUsage)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	files := opts.Files
+	source := "files given"
+	if len(files) == 0 {
+		if files, err = changedFiles(ctx, opts.Base); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		source = "changed since " + opts.Base
+	}
+	if len(files) == 0 {
+		fmt.Printf("bjarne ci: no C/C++ files %s\n", source)
+		return 0
+	}
+
+	container, err := newValidationRuntime(ctx, LoadConfig())
+	if err != nil {
+		fmt.Print(FormatUserError(err))
+		return 1
+	}
+	container.SetGateSelection(opts.Gates)
+	check := containerCheck(container)
+
+	fmt.Printf("bjarne ci: %d C/C++ file(s) %s (%s, %s)\n\n", len(files), source, opts.Gates, container.ImageName())
+
+	outcomes := validateFileList(ctx, check, files, readDiskFile, opts.Annotate)
+	fmt.Printf("\n%s", ciSummaryTable(outcomes))
+
+	if opts.SARIF != "" {
+		if err := writeSARIF(opts.SARIF, outcomes); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("SARIF written to %s\n", opts.SARIF)
+	}
+	if summaryPath := os.Getenv("GITHUB_STEP_SUMMARY"); summaryPath != "" {
+		if err := appendStepSummary(summaryPath, outcomes); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+
+	failed := 0
+	for _, r := range outcomes {
+		if !r.Passed() {
+			failed++
+		}
+	}
+	if failed > 0 || len(outcomes) < len(files) {
+		fmt.Printf("\033[91m%d of %d file(s) failed validation\033[0m\n", failed, len(files))
+		return 1
+	}
+	fmt.Printf("\033[92mAll %d file(s) passed validation\033[0m\n", len(files))
+	return 0
+}
+
+// parseCIArgs parses `bjarne ci` flags; the diff base defaults to the pull request's base branch
+func parseCIArgs(args []string) (ciOptions, error) {
+	opts := ciOptions{
+		Base:     "HEAD~1",
+		Annotate: os.Getenv("GITHUB_ACTIONS") == "true",
+	}
+	if ref := os.Getenv("GITHUB_BASE_REF"); ref != "" {
+		opts.Base = "origin/" + ref
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "--") {
+			opts.Files = append(opts.Files, arg)
+			continue
+		}
+		if i+1 >= len(args) {
+			return opts, fmt.Errorf("%s needs a value", arg)
+		}
+		value := args[i+1]
+		i++
+
+		var err error
+		switch arg {
+		case "--base":
+			opts.Base = value
+		case "--gates":
+			opts.Gates.Only, err = ParseGateList(value)
+		case "--skip":
+			opts.Gates.Skip, err = ParseGateList(value)
+		case "--annotate":
+			switch value {
+			case "github":
+				opts.Annotate = true
+			case "none":
+				opts.Annotate = false
+			default:
+				err = fmt.Errorf("unknown --annotate %q (want github or none)", value)
+			}
+		case "--sarif":
+			opts.SARIF = value
+		default:
+			err = fmt.Errorf("unknown ci option: %s", arg)
+		}
+		if err != nil {
+			return opts, err
+		}
+	}
+	return opts, nil
+}
+
+// changedFiles lists C/C++ files added, copied, modified or renamed since base
+func changedFiles(ctx context.Context, base string) ([]string, error) {
+	cmd := exec.CommandContext(ctx, "git", "diff", "--name-only", "--diff-filter=ACMR", "--relative", base+"...HEAD")
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("git diff against %s failed: %s", base, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("git diff against %s failed: %w", base, err)
+	}
+	return filterCppFiles(strings.Split(string(out), "\n")), nil
+}
+
+// filterCppFiles keeps the C/C++ sources and headers of a file list
+func filterCppFiles(paths []string) []string {
+	var files []string
+	for _, p := range paths {
+		p = strings.TrimSpace(p)
+		if p != "" && (isSourceFile(p) || isHeaderFile(p)) {
+			files = append(files, p)
+		}
+	}
+	return files
+}
+
+// validateFileList validates files one at a time, printing a line per file and the failures
+// It stops early when ctx is cancelled, so it can return fewer results than files
+func validateFileList(ctx context.Context, check fileCheckFunc, files []string, read func(string) (string, bool), annotate bool) []ciFileResult {
+	var outcomes []ciFileResult
+	for i, path := range files {
+		fmt.Printf("[%d/%d] %s ... ", i+1, len(files), path)
+		r := validateCIFile(ctx, check, path, read)
+		outcomes = append(outcomes, r)
+
+		switch {
+		case r.Err != nil:
+			fmt.Printf("\033[91mERROR\033[0m %v\n", r.Err)
+		case r.Passed():
+			fmt.Printf("\033[92mPASS\033[0m (%.1fs)\n", r.Duration.Seconds())
+		default:
+			fmt.Printf("\033[91mFAIL\033[0m (%.1fs)\n", r.Duration.Seconds())
+			fmt.Print(FormatResults(r.Results, r.Paths()))
+		}
+		if annotate {
+			for _, f := range r.Findings {
+				fmt.Println(githubAnnotation(r.FindingPath(f), f))
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return outcomes
+}
+
+// validateCIFile validates one file with the local headers it includes, read with read
+func validateCIFile(ctx context.Context, check fileCheckFunc, path string, read func(string) (string, bool)) ciFileResult {
+	start := time.Now()
+	r := ciFileResult{Path: path}
+	files := collectLocalFiles(path, read)
+	if len(files) == 0 {
+		r.Err = fmt.Errorf("cannot read %s", path)
+		return r
+	}
+	if strings.TrimSpace(files[0].Content) == "" {
+		r.Err = fmt.Errorf("file is empty")
+		return r
+	}
+	r.Results, r.Err = check(ctx, files, files[0].Filename, nil)
+	r.Findings = gateFindings(files[0].Filename, files, r.Results)
+	r.Duration = time.Since(start)
+	return r
+}
+
+// ciGateSummary describes a file's gates in one cell: what failed, or how many passed
+func ciGateSummary(r ciFileResult) string {
+	if r.Err != nil {
+		return r.Err.Error()
+	}
+	passed, skipped := 0, 0
+	for _, result := range r.Results {
+		switch {
+		case !result.Success:
+			summary := result.Stage
+			if n := len(r.Findings); n > 0 {
+				summary += fmt.Sprintf(" (%d finding(s))", n)
+			}
+			return summary
+		case result.Skipped:
+			skipped++
+		default:
+			passed++
+		}
+	}
+	summary := fmt.Sprintf("%d passed", passed)
+	if skipped > 0 {
+		summary += fmt.Sprintf(", %d skipped", skipped)
+	}
+	return summary
+}
+
+// ciResultLabel is the RESULT column for a file
+func ciResultLabel(r ciFileResult) string {
+	switch {
+	case r.Err != nil:
+		return "ERROR"
+	case r.Passed():
+		return "PASS"
+	default:
+		return "FAIL"
+	}
+}
+
+// ciSummaryTable renders the per-file results as an aligned table
+func ciSummaryTable(outcomes []ciFileResult) string {
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FILE\tRESULT\tGATES\tTIME")
+	for _, r := range outcomes {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%.1fs\n", r.Path, ciResultLabel(r), ciGateSummary(r), r.Duration.Seconds())
+	}
+	_ = w.Flush()
+	return sb.String()
+}
+
+// appendStepSummary adds the results table to the GitHub Actions job summary
+func appendStepSummary(path string, outcomes []ciFileResult) error {
+	var sb strings.Builder
+	sb.WriteString("### bjarne validation\n\n| File | Result | Gates | Time |\n|------|--------|-------|------|\n")
+	for _, r := range outcomes {
+		fmt.Fprintf(&sb, "| `%s` | %s | %s | %.1fs |\n", r.Path, ciResultLabel(r),
+			strings.ReplaceAll(ciGateSummary(r), "|", "\\|"), r.Duration.Seconds())
+	}
+	sb.WriteString("\n")
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open step summary: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	if _, err := f.WriteString(sb.String()); err != nil {
+		return fmt.Errorf("failed to write step summary: %w", err)
+	}
+	return nil
+}
+
+// githubAnnotation formats a finding as a GitHub Actions workflow command
+func githubAnnotation(path string, f GateFinding) string {
+	command := "error"
+	if f.Level == LevelWarning {
+		command = "warning"
+	}
+	props := []string{"file=" + escapeAnnotationProperty(path)}
+	if f.Line > 0 {
+		props = append(props, fmt.Sprintf("line=%d", f.Line))
+		if f.Column > 0 {
+			props = append(props, fmt.Sprintf("col=%d", f.Column))
+		}
+	}
+	title := "bjarne " + f.Stage
+	if f.Check != "" && f.Check != f.Stage {
+		title += " (" + f.Check + ")"
+	}
+	props = append(props, "title="+escapeAnnotationProperty(title))
+	return fmt.Sprintf("::%s %s::%s", command, strings.Join(props, ","), escapeAnnotationData(f.Message))
+}
+
+// escapeAnnotationData escapes a workflow command message
+func escapeAnnotationData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeAnnotationProperty escapes a workflow command property value
+func escapeAnnotationProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+// sarifLog is the subset of SARIF 2.1.0 that code scanning needs
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+		Region struct {
+			StartLine   int `json:"startLine"`
+			StartColumn int `json:"startColumn,omitempty"`
+		} `json:"region"`
+	} `json:"physicalLocation"`
+}
+
+// buildSARIF converts the findings of a run into a SARIF log
+// Rule IDs are the tool's check name when it has one, otherwise the gate
+func buildSARIF(outcomes []ciFileResult) sarifLog {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "bjarne",
+			Version:        Version,
+			InformationURI: "https://github.com/3rg0n/bjarne",
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+
+	rules := make(map[string]bool)
+	for _, r := range outcomes {
+		for _, f := range r.Findings {
+			id := f.Check
+			if id == "" {
+				id = f.Stage
+			}
+			if !rules[id] {
+				rules[id] = true
+				run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: id, ShortDescription: sarifMessage{Text: "bjarne " + f.Stage + " gate: " + id}})
+			}
+
+			level := "error"
+			if f.Level == LevelWarning {
+				level = "warning"
+			}
+			var loc sarifLocation
+			loc.PhysicalLocation.ArtifactLocation.URI = r.FindingPath(f)
+			loc.PhysicalLocation.Region.StartLine = max(f.Line, 1)
+			loc.PhysicalLocation.Region.StartColumn = f.Column
+			run.Results = append(run.Results, sarifResult{RuleID: id, Level: level, Message: sarifMessage{Text: f.Message}, Locations: []sarifLocation{loc}})
+		}
+	}
+	return sarifLog{Version: "2.1.0", Schema: sarifSchema, Runs: []sarifRun{run}}
+}
+
+// writeSARIF writes the findings of a run for upload to code scanning
+func writeSARIF(path string, outcomes []ciFileResult) error {
+	data, err := json.MarshalIndent(buildSARIF(outcomes), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode SARIF: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
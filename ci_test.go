@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseCIArgs(t *testing.T) {
+	t.Setenv("GITHUB_ACTIONS", "true")
+	t.Setenv("GITHUB_BASE_REF", "main")
+
+	opts, err := parseCIArgs(nil)
+	if err != nil || opts.Base != "origin/main" || !opts.Annotate {
+		t.Errorf("defaults on GitHub = %+v, %v", opts, err)
+	}
+
+	opts, err = parseCIArgs([]string{"--base", "v1.2", "--gates", "clang-tidy,compile", "--skip", "msan", "--annotate", "none", "--sarif", "out.sarif", "src/a.cpp"})
+	if err != nil {
+		t.Fatalf("parseCIArgs() error = %v", err)
+	}
+	if opts.Base != "v1.2" || opts.Annotate || opts.SARIF != "out.sarif" || len(opts.Gates.Only) != 2 ||
+		len(opts.Gates.Skip) != 1 || len(opts.Files) != 1 || opts.Files[0] != "src/a.cpp" {
+		t.Errorf("parseCIArgs() = %+v", opts)
+	}
+
+	for _, args := range [][]string{{"--gates", "lint"}, {"--annotate", "gitlab"}, {"--jobs", "4"}, {"--base"}} {
+		if _, err := parseCIArgs(args); err == nil {
+			t.Errorf("parseCIArgs(%v) accepted bad arguments", args)
+		}
+	}
+}
+
+func TestFilterCppFiles(t *testing.T) {
+	got := filterCppFiles([]string{"src/a.cpp", "README.md", "", "include/b.hpp", "go.mod", "lib/c.c"})
+	if strings.Join(got, ",") != "src/a.cpp,include/b.hpp,lib/c.c" {
+		t.Errorf("filterCppFiles() = %v", got)
+	}
+}
+
+func TestGithubAnnotation(t *testing.T) {
+	tests := []struct {
+		name    string
+		finding GateFinding
+		want    string
+	}{
+		{
+			"located warning",
+			GateFinding{File: "a.cpp", Line: 4, Column: 7, Level: LevelWarning, Stage: "clang-tidy", Check: "bugprone-narrowing-conversions", Message: "narrowing 100%"},
+			"::warning file=src/a.cpp,line=4,col=7,title=bjarne clang-tidy (bugprone-narrowing-conversions)::narrowing 100%25",
+		},
+		{
+			"failure without location",
+			GateFinding{File: "a.cpp", Level: LevelError, Stage: "run", Message: "run failed\nexit status 3"},
+			"::error file=src/a.cpp,title=bjarne run::run failed%0Aexit status 3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := githubAnnotation("src/a.cpp", tt.finding); got != tt.want {
+				t.Errorf("githubAnnotation() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCISummary(t *testing.T) {
+	outcomes := []ciFileResult{
+		{Path: "src/main.cpp", Results: []ValidationResult{{Stage: "compile", Success: true}, {Stage: "asan", Success: true, Skipped: true}}},
+		{Path: "src/util.h", Results: []ValidationResult{{Stage: "syntax:util.h", Success: false}},
+			Findings: []GateFinding{{File: "util.h", Line: 3, Level: LevelError, Stage: "syntax", Message: "expected ';'"}}},
+		{Path: "src/gone.cpp", Err: errors.New("cannot read src/gone.cpp")},
+	}
+
+	table := ciSummaryTable(outcomes)
+	for _, want := range []string{"src/main.cpp  PASS    1 passed, 1 skipped", "src/util.h    FAIL    syntax:util.h (1 finding(s))", "src/gone.cpp  ERROR   cannot read src/gone.cpp"} {
+		if !strings.Contains(table, want) {
+			t.Errorf("summary table missing %q:\n%s", want, table)
+		}
+	}
+
+	data, err := json.Marshal(buildSARIF(outcomes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(data, &log); err != nil {
+		t.Fatal(err)
+	}
+	results := log.Runs[0].Results
+	if log.Version != "2.1.0" || len(results) != 1 || results[0].RuleID != "syntax" ||
+		results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI != "src/util.h" ||
+		results[0].Locations[0].PhysicalLocation.Region.StartLine != 3 {
+		t.Errorf("SARIF = %s", data)
+	}
+}
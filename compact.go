@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CompactionSystemPrompt asks the cheap model to summarize earlier turns
+const CompactionSystemPrompt = `You condense C/C++ coding conversations so they can continue without the full history.`
+
+// CompactionPrompt follows the turns being summarized
+const CompactionPrompt = `Summarize the conversation so far so we can continue without it. Include:
+- what the user asked for, with every requirement and constraint
+- design decisions and answers to clarifying questions
+- errors that were fixed and how, and approaches that did not work
+
+Do not include code. Use short bullet points, at most 300 words.`
+
+// CompactedContextTemplate replaces the summarized turns
+// Args: summary, latest code and errors (may be empty)
+const CompactedContextTemplate = `Earlier conversation, summarized to save tokens:
+
+%s%s`
+
+// compactAcknowledgement keeps user and assistant turns alternating after the summary
+const compactAcknowledgement = "Understood. Continuing from this summary."
+
+// splitForCompaction returns the turns to summarize and the latest exchange, kept verbatim
+// The kept turns start at the last user message so roles still alternate
+func splitForCompaction(conversation []Message) (old, recent []Message) {
+	for i := len(conversation) - 1; i >= 0; i-- {
+		if conversation[i].Role == "user" {
+			return conversation[:i], conversation[i:]
+		}
+	}
+	return nil, conversation
+}
+
+// compactionRequest is what the summarizer sees: the old turns without images, then the instruction
+func compactionRequest(old []Message) []Message {
+	messages := make([]Message, 0, len(old)+1)
+	for _, msg := range old {
+		messages = append(messages, Message{Role: msg.Role, Content: msg.Content})
+	}
+	return append(messages, Message{Role: "user", Content: CompactionPrompt})
+}
+
+// compactedConversation rebuilds the conversation from a summary and the kept turns
+// The latest code and errors are appended verbatim unless a kept turn already carries them
+func compactedConversation(summary string, recent []Message, code, errors string) []Message {
+	latest := ""
+	if code != "" && !messagesContain(recent, code) {
+		latest = "\n\nLatest code:\n```cpp\n" + strings.TrimSpace(code) + "\n```"
+		if errors != "" {
+			latest += "\n\nLatest validation errors:\n" + strings.TrimSpace(errors)
+		}
+	}
+
+	conversation := []Message{
+		{Role: "user", Content: fmt.Sprintf(CompactedContextTemplate, strings.TrimSpace(summary), latest)},
+		{Role: "assistant", Content: compactAcknowledgement},
+	}
+	return append(conversation, recent...)
+}
+
+// messagesContain reports whether any message includes text
+func messagesContain(messages []Message, text string) bool {
+	for _, msg := range messages {
+		if strings.Contains(msg.Content, text) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitForCompaction(t *testing.T) {
+	conversation := []Message{
+		{Role: "user", Content: "write a ring buffer"},
+		{Role: "assistant", Content: "```cpp\nv1\n```"},
+		{Role: "user", Content: "fix: overflow"},
+		{Role: "assistant", Content: "```cpp\nv2\n```"},
+	}
+
+	tests := []struct {
+		name       string
+		messages   []Message
+		wantOld    int
+		wantRecent int
+	}{
+		{"keeps the latest exchange", conversation, 2, 2},
+		{"ends with a user turn", conversation[:3], 2, 1},
+		{"single exchange", conversation[:2], 0, 2},
+		{"empty", nil, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old, recent := splitForCompaction(tt.messages)
+			if len(old) != tt.wantOld || len(recent) != tt.wantRecent {
+				t.Errorf("splitForCompaction() = %d old, %d recent; want %d, %d", len(old), len(recent), tt.wantOld, tt.wantRecent)
+			}
+			if len(recent) > 0 && recent[0].Role != "user" {
+				t.Errorf("kept turns start with %s, want user", recent[0].Role)
+			}
+		})
+	}
+}
+
+func TestCompactionRequestDropsImages(t *testing.T) {
+	old := []Message{
+		{Role: "user", Content: "match this diagram", Images: []ImageAttachment{{Data: []byte{1, 2, 3}}}},
+		{Role: "assistant", Content: "done"},
+	}
+	messages := compactionRequest(old)
+	if len(messages) != 3 || messages[2].Content != CompactionPrompt || messages[2].Role != "user" {
+		t.Fatalf("compactionRequest() = %+v", messages)
+	}
+	if len(messages[0].Images) != 0 {
+		t.Error("compactionRequest() kept image attachments")
+	}
+	if len(old[0].Images) != 1 {
+		t.Error("compactionRequest() modified the original conversation")
+	}
+}
+
+func TestCompactedConversation(t *testing.T) {
+	code := "int main() { return 0; }"
+	errs := "main.cpp:1: warning: unused"
+
+	t.Run("latest code kept verbatim", func(t *testing.T) {
+		recent := []Message{{Role: "user", Content: "make it faster"}}
+		got := compactedConversation("- wants a ring buffer", recent, code, errs)
+		if len(got) != 3 || got[0].Role != "user" || got[1].Role != "assistant" || got[2].Content != "make it faster" {
+			t.Fatalf("compactedConversation() = %+v", got)
+		}
+		for _, want := range []string{"- wants a ring buffer", code, errs} {
+			if !strings.Contains(got[0].Content, want) {
+				t.Errorf("summary message missing %q", want)
+			}
+		}
+	})
+
+	t.Run("no duplicate when kept turns carry the code", func(t *testing.T) {
+		recent := []Message{{Role: "user", Content: "fix"}, {Role: "assistant", Content: "```cpp\n" + code + "\n```"}}
+		got := compactedConversation("summary", recent, code, errs)
+		if strings.Contains(got[0].Content, code) {
+			t.Error("summary message repeats code already in the kept turns")
+		}
+	})
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// providerAliases are the provider prefixes accepted in "provider:model" specs
+var providerAliases = map[string]ProviderType{
+	"bedrock":   ProviderBedrock,
+	"aws":       ProviderBedrock,
+	"anthropic": ProviderAnthropic,
+	"claude":    ProviderAnthropic,
+	"openai":    ProviderOpenAI,
+	"gpt":       ProviderOpenAI,
+	"gemini":    ProviderGemini,
+	"google":    ProviderGemini,
+}
+
+// parseModelSpec splits "provider:model" (e.g. "gemini:sonnet")
+// Specs without a known provider prefix are returned as a model for the current provider
+// (Bedrock IDs such as "...-v1:0" contain colons, so only known prefixes are split off)
+func parseModelSpec(spec string) (ProviderType, string, bool) {
+	if i := strings.Index(spec, ":"); i > 0 {
+		if p, ok := providerAliases[strings.ToLower(spec[:i])]; ok && i < len(spec)-1 {
+			return p, spec[i+1:], true
+		}
+	}
+	return "", spec, false
+}
+
+// resolveModelSpec returns the provider and model for a /compare argument
+// Other providers read their key from BJARNE_<PROVIDER>_API_KEY, falling back to BJARNE_API_KEY
+func resolveModelSpec(ctx context.Context, cfg *Config, current LLMProvider, spec string) (candidateSpec, error) {
+	providerType, model, hasProvider := parseModelSpec(spec)
+	if !hasProvider || providerType == cfg.Provider {
+		return candidateSpec{provider: current, model: model}, nil
+	}
+
+	providerCfg := cfg.GetProviderConfig()
+	providerCfg.Provider = providerType
+	if key := os.Getenv("BJARNE_" + strings.ToUpper(string(providerType)) + "_API_KEY"); key != "" {
+		providerCfg.APIKey = key
+	}
+	provider, err := NewProvider(ctx, providerCfg)
+	if err != nil {
+		return candidateSpec{}, fmt.Errorf("%s: %w", spec, err)
+	}
+	return candidateSpec{provider: provider, model: model}, nil
+}
+
+// formatComparison renders two candidates side by side
+func formatComparison(a, b Candidate) []string {
+	column := func(c Candidate) []string {
+		gates, confidence := "-", "-"
+		if c.Err == nil {
+			passed, total := c.GatesPassed()
+			gates = fmt.Sprintf("%d/%d", passed, total)
+			if c.Passed() && c.ReviewErr == nil {
+				confidence = fmt.Sprintf("%d%%", c.Confidence)
+			}
+		}
+		failed := "-"
+		if c.Err != nil {
+			failed = "error: " + truncateError(c.Err.Error(), 30)
+		} else if names := failedStages(c.Results); len(names) > 0 {
+			failed = strings.Join(names, ", ")
+		}
+		return []string{
+			c.Provider,
+			gates,
+			failed,
+			confidence,
+			fmt.Sprintf("%d / %d", c.InputTokens, c.OutputTokens),
+			c.Duration.Round(100 * time.Millisecond).String(),
+			fmt.Sprintf("%d", len(splitLines(c.Code))),
+		}
+	}
+
+	labels := []string{"Provider", "Gates", "Failed", "Confidence", "Tokens in/out", "Duration", "Lines"}
+	colA, colB := column(a), column(b)
+
+	lines := []string{fmt.Sprintf("  %-14s %-32s %s", "", shortModelName(a.Model), shortModelName(b.Model))}
+	for i, label := range labels {
+		lines = append(lines, fmt.Sprintf("  %-14s %-32s %s", label, colA[i], colB[i]))
+	}
+	return lines
+}
+
+// failedStages lists the names of failed validation stages
+func failedStages(results []ValidationResult) []string {
+	var names []string
+	for _, r := range results {
+		if !r.Success {
+			names = append(names, r.Stage)
+		}
+	}
+	return names
+}
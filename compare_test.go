@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseModelSpec(t *testing.T) {
+	tests := []struct {
+		spec         string
+		wantProvider ProviderType
+		wantModel    string
+		wantOK       bool
+	}{
+		{"sonnet", "", "sonnet", false},
+		{"gemini:sonnet", ProviderGemini, "sonnet", true},
+		{"OpenAI:gpt-5-mini-2025-08-07", ProviderOpenAI, "gpt-5-mini-2025-08-07", true},
+		{"global.anthropic.claude-haiku-4-5-20251001-v1:0", "", "global.anthropic.claude-haiku-4-5-20251001-v1:0", false},
+		{"bedrock:global.anthropic.claude-haiku-4-5-20251001-v1:0", ProviderBedrock, "global.anthropic.claude-haiku-4-5-20251001-v1:0", true},
+		{"gemini:", "", "gemini:", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			p, model, ok := parseModelSpec(tt.spec)
+			if p != tt.wantProvider || model != tt.wantModel || ok != tt.wantOK {
+				t.Errorf("parseModelSpec(%q) = %q, %q, %v; want %q, %q, %v", tt.spec, p, model, ok, tt.wantProvider, tt.wantModel, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestFormatComparison(t *testing.T) {
+	a := Candidate{
+		Provider: "AWS Bedrock", Model: "haiku", Code: "int main() {}\n",
+		Results:     []ValidationResult{{Stage: "compile", Success: true}, {Stage: "asan"}},
+		InputTokens: 1200, OutputTokens: 300, Duration: 4 * time.Second,
+	}
+	b := Candidate{Provider: "Google Gemini", Model: "sonnet", Err: errors.New("API error (status 429)")}
+
+	out := strings.Join(formatComparison(a, b), "\n")
+	for _, want := range []string{"haiku", "sonnet", "1/2", "asan", "1200 / 300", "4s", "error: API error (status 429)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("comparison missing %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// slashCommands lists the commands offered by tab completion
+var slashCommands = []string{
+	"/bestof", "/checkpoint", "/clear", "/code", "/compact", "/compare", "/config", "/debug", "/diff", "/edit", "/exit", "/export", "/help",
+	"/highlight", "/history", "/image", "/import", "/init", "/model", "/plan", "/prompts", "/quit", "/recover", "/redo", "/restore", "/review", "/save", "/settings", "/show", "/stdin", "/strategy", "/suppress", "/temp", "/template", "/tests", "/theme", "/tokens", "/undo", "/validate",
+}
+
+// configCategories maps /config category names to validator categories
+var configCategories = map[string]ValidatorCategory{
+	"game":     CategoryGame,
+	"hft":      CategoryHFT,
+	"embedded": CategoryEmbedded,
+	"security": CategorySecurity,
+	"perf":     CategoryPerformance,
+	"gpu":      CategoryGPU,
+	"core":     CategoryCore,
+}
+
+// argCompleters provides argument candidates for commands that take them
+var argCompleters = map[string]func(prefix string) []string{
+	"/config":   completeConfigArg,
+	"/model":    completeModelArg,
+	"/temp":     completeTempArg,
+	"/tests":    completeTestsArg,
+	"/stdin":    completeStdinArg,
+	"/prompts":  completePromptsArg,
+	"/plan":     completePlanArg,
+	"/review":   completeReviewArg,
+	"/history":  completeHistoryArg,
+	"/template": completeTemplateArg,
+	"/theme":    completeThemeArg,
+	"/recover":  completeRecoverArg,
+	"/image":    completePath,
+	"/export":   completePath,
+	"/import":   completePath,
+	"/validate": completePath,
+	"/v":        completePath,
+	"/save":     completePath,
+	"/s":        completePath,
+}
+
+// completeInput completes the last word of a slash command or an @file mention
+// Returns the new input (unchanged if there is nothing to add) and all candidates for the word
+func completeInput(input string) (string, []string) {
+	// @file attachments anywhere in a prompt
+	cut := strings.LastIndexAny(input, " \n\t") + 1
+	if word := input[cut:]; strings.HasPrefix(word, "@") {
+		var candidates []string
+		for _, c := range completePath(word[1:]) {
+			candidates = append(candidates, "@"+c)
+		}
+		return applyCompletion(input, input[:cut], word, candidates), candidates
+	}
+
+	if !strings.HasPrefix(input, "/") || strings.Contains(input, "\n") {
+		return input, nil
+	}
+
+	// Completing the command itself
+	if !strings.Contains(input, " ") {
+		candidates := matchPrefix(slashCommands, strings.ToLower(input))
+		return applyCompletion(input, "", input, candidates), candidates
+	}
+
+	// Completing an argument (the text after the last space)
+	cmd := strings.ToLower(strings.Fields(input)[0])
+	completer, ok := argCompleters[cmd]
+	if !ok {
+		return input, nil
+	}
+	cut = strings.LastIndex(input, " ") + 1
+	word := input[cut:]
+	candidates := completer(word)
+	return applyCompletion(input, input[:cut], word, candidates), candidates
+}
+
+// applyCompletion replaces word with the candidates' longest common prefix
+// A single candidate is completed fully and followed by a space (unless it is a directory)
+func applyCompletion(input, head, word string, candidates []string) string {
+	switch len(candidates) {
+	case 0:
+		return input
+	case 1:
+		c := candidates[0]
+		if strings.HasSuffix(c, "/") || strings.HasSuffix(c, string(filepath.Separator)) {
+			return head + c
+		}
+		return head + c + " "
+	}
+
+	prefix := commonPrefix(candidates)
+	if len(prefix) <= len(word) {
+		return input
+	}
+	return head + prefix
+}
+
+// completeConfigArg offers /config models, category names and validator IDs
+func completeConfigArg(prefix string) []string {
+	options := []string{"models", "image"}
+	for name := range configCategories {
+		options = append(options, name)
+	}
+	for _, v := range AllValidators() {
+		options = append(options, string(v.ID))
+	}
+	return matchPrefix(options, strings.ToLower(prefix))
+}
+
+// completeModelArg offers the canonical model tiers and auto
+func completeModelArg(prefix string) []string {
+	return matchPrefix([]string{ModelHaiku, ModelSonnet, ModelOpus, "auto"}, strings.ToLower(prefix))
+}
+
+// completeTempArg offers /temp subcommands
+func completeTempArg(prefix string) []string {
+	return matchPrefix([]string{"default", "reset", "seed", "top-p"}, strings.ToLower(prefix))
+}
+
+// completeTestsArg offers /tests subcommands
+func completeTestsArg(prefix string) []string {
+	return matchPrefix([]string{"add", "clear", "rm", "set"}, strings.ToLower(prefix))
+}
+
+// completeStdinArg offers /stdin subcommands
+func completeStdinArg(prefix string) []string {
+	return matchPrefix([]string{"args", "clear", "load", "set", "timeout"}, strings.ToLower(prefix))
+}
+
+// completePlanArg offers /plan subcommands
+func completePlanArg(prefix string) []string {
+	return matchPrefix([]string{"add", "deps", "go", "off", "purpose", "rm"}, strings.ToLower(prefix))
+}
+
+// completeReviewArg offers /review subcommands
+func completeReviewArg(prefix string) []string {
+	return matchPrefix([]string{"consensus", "consistency", "functional", "threshold"}, strings.ToLower(prefix))
+}
+
+// completeHistoryArg offers /history actions
+func completeHistoryArg(prefix string) []string {
+	return matchPrefix([]string{"delete", "restore", "show", "validate"}, strings.ToLower(prefix))
+}
+
+// completeTemplateArg offers /template actions
+func completeTemplateArg(prefix string) []string {
+	return matchPrefix([]string{"delete", "list", "save", "show", "use"}, strings.ToLower(prefix))
+}
+
+// completeThemeArg offers the theme presets and preview
+func completeThemeArg(prefix string) []string {
+	return matchPrefix(append(AvailableThemes(), "preview"), strings.ToLower(prefix))
+}
+
+// completeRecoverArg offers /recover subcommands
+func completeRecoverArg(prefix string) []string {
+	return matchPrefix([]string{"discard"}, strings.ToLower(prefix))
+}
+
+// completePromptsArg offers /prompts subcommands
+func completePromptsArg(prefix string) []string {
+	return matchPrefix([]string{"reload"}, strings.ToLower(prefix))
+}
+
+// completePath offers filesystem entries matching prefix (directories end with /)
+func completePath(prefix string) []string {
+	dir, base := filepath.Split(prefix)
+	readDir := dir
+	if readDir == "" {
+		readDir = "."
+	}
+	if strings.HasPrefix(readDir, "~") {
+		if home, err := os.UserHomeDir(); err == nil {
+			readDir = home + readDir[1:]
+		}
+	}
+
+	entries, err := os.ReadDir(readDir)
+	if err != nil {
+		return nil
+	}
+
+	var candidates []string
+	for _, e := range entries {
+		name := e.Name()
+		// Hidden entries only when explicitly asked for
+		if strings.HasPrefix(name, ".") && !strings.HasPrefix(base, ".") {
+			continue
+		}
+		if !strings.HasPrefix(name, base) {
+			continue
+		}
+		if e.IsDir() {
+			name += "/"
+		}
+		candidates = append(candidates, dir+name)
+	}
+	sort.Strings(candidates)
+	return candidates
+}
+
+// matchPrefix returns the sorted options starting with prefix
+func matchPrefix(options []string, prefix string) []string {
+	var matches []string
+	for _, o := range options {
+		if strings.HasPrefix(o, prefix) {
+			matches = append(matches, o)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+// commonPrefix returns the longest prefix shared by all strings
+func commonPrefix(strs []string) string {
+	if len(strs) == 0 {
+		return ""
+	}
+	prefix := strs[0]
+	for _, s := range strs[1:] {
+		for !strings.HasPrefix(s, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
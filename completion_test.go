@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompleteInput(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"/hig", "/highlight "},
+		{"/hi", "/hi"}, // ambiguous: /highlight, /history
+		{"/history r", "/history restore "},
+		{"/co", "/co"}, // ambiguous: /code, /config
+		{"/con", "/config "},
+		{"/config frame", "/config frame-timing "},
+		{"/config mem", "/config mem"}, // ambiguous: mem-prof, memory-budget
+		{"/config memo", "/config memory-budget "},
+		{"/model s", "/model sonnet "},
+		{"/tokens x", "/tokens x"}, // no argument completion
+		{"hello", "hello"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got, _ := completeInput(tt.input); got != tt.want {
+				t.Errorf("completeInput(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompletePath(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "src", "long"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"src/long/path.cpp", "src/long/path.h", "src/.hidden"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	base := filepath.ToSlash(dir) + "/"
+
+	got, _ := completeInput("/validate " + base + "sr")
+	if want := "/validate " + base + "src/"; got != want {
+		t.Errorf("directory completion = %q, want %q", got, want)
+	}
+
+	got, _ = completeInput("/validate " + base + "src/long/pa")
+	if want := "/validate " + base + "src/long/path."; got != want {
+		t.Errorf("common prefix completion = %q, want %q", got, want)
+	}
+
+	if c := completePath(base + "src/"); len(c) != 1 {
+		t.Errorf("hidden files should be skipped, got %v", c)
+	}
+}
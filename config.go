@@ -7,9 +7,12 @@ import (
 
 // Config holds runtime configuration (merged from settings.json + env vars)
 type Config struct {
-	// Settings (loaded from ~/.bjarne/settings.json)
+	// Settings (the effective settings of every layer, see LoadSettingsLayers)
 	Settings *Settings
 
+	// Layers records where each setting came from (nil for DefaultConfig)
+	Layers *SettingsLayers
+
 	// Theme (created from settings)
 	Theme *Theme
 
@@ -26,12 +29,14 @@ type Config struct {
 	Region   string       // AWS region for Bedrock
 
 	// Model configuration
-	ChatModel         string   // Model for chat/non-code responses
-	ReflectionModel   string   // Model for initial prompt analysis
-	GenerateModel     string   // Model for initial code generation
-	OracleModel       string   // Model for deep analysis (COMPLEX tasks)
-	EscalationModels  []string // Models to try on validation failure
-	EscalateOnFailure bool
+	ChatModel          string           // Model for chat/non-code responses
+	ReflectionModel    string           // Model for initial prompt analysis
+	GenerateModel      string           // Model for initial code generation
+	OracleModel        string           // Model for deep analysis (COMPLEX tasks)
+	ComplexityModels   ComplexityModels // Generation model per complexity
+	EscalationModels   []string         // Models to try on validation failure
+	EscalationAttempts []int            // Fix attempts per ladder rung
+	EscalateOnFailure  bool
 }
 
 // DefaultConfig returns the default configuration
@@ -42,36 +47,48 @@ func DefaultConfig() *Config {
 
 // configFromSettings creates a Config from Settings
 func configFromSettings(settings *Settings) *Config {
-	return &Config{
-		Settings:           settings,
-		Theme:              NewTheme(&settings.Theme),
-		MaxIterations:      settings.Validation.MaxIterations,
-		MaxTokens:          settings.Tokens.MaxPerResponse,
-		MaxTotalTokens:     settings.Tokens.MaxPerSession,
-		WarnTokenThreshold: settings.Tokens.MaxPerSession * 80 / 100,
-		ValidatorImage:     settings.Container.Image,
-		Provider:           ProviderBedrock, // Default to Bedrock
-		Region:             "",              // Will use AWS_REGION env var
-		APIKey:             "",              // Will be set from env var
-		ChatModel:          settings.Models.Chat,
-		ReflectionModel:    settings.Models.Reflection,
-		GenerateModel:      settings.Models.Generate,
-		OracleModel:        settings.Models.Oracle,
-		EscalationModels:   settings.Models.Escalation,
-		EscalateOnFailure:  settings.Validation.EscalateOnFailure,
+	cfg := &Config{
+		Settings: settings,
+		Provider: ProviderBedrock, // Default to Bedrock
+		Region:   "",              // Will use AWS_REGION env var
+		APIKey:   "",              // Will be set from env var
 	}
+	cfg.syncSettings()
+	return cfg
+}
+
+// syncSettings recomputes the values derived from Settings (after /settings edits them)
+func (c *Config) syncSettings() {
+	s := c.Settings
+	c.Theme = NewTheme(&s.Theme)
+	c.MaxIterations = s.Validation.MaxIterations
+	c.MaxTokens = s.Tokens.MaxPerResponse
+	c.MaxTotalTokens = s.Tokens.MaxPerSession
+	c.WarnTokenThreshold = s.Tokens.MaxPerSession * 80 / 100
+	c.ValidatorImage = s.Container.Image
+	c.ChatModel = s.Models.Chat
+	c.ReflectionModel = s.Models.Reflection
+	c.GenerateModel = s.Models.Generate
+	c.OracleModel = s.Models.Oracle
+	c.ComplexityModels = s.Models.Complexity
+	c.EscalationModels = s.Models.Escalation
+	c.EscalationAttempts = s.Models.EscalationAttempts
+	c.EscalateOnFailure = s.Validation.EscalateOnFailure
 }
 
-// LoadConfig loads configuration from settings.json, then applies env var overrides
+// LoadConfig loads the layered settings (settings.json, .bjarne.toml, env vars, --set flags),
+// then applies the env vars that are not settings
 func LoadConfig() *Config {
-	// Load settings from file (or defaults if not found)
-	settings, _ := LoadSettings()
+	// Unreadable settings.json falls back to defaults; startup and config validate report it
+	cwd, _ := os.Getwd()
+	layers, _ := LoadSettingsLayers(cwd)
+	settings := layers.Settings
 	cfg := configFromSettings(settings)
+	cfg.Layers = layers
 
-	// Provider configuration
-	// BJARNE_PROVIDER: bedrock (default), anthropic, openai, gemini
-	if val := os.Getenv("BJARNE_PROVIDER"); val != "" {
-		cfg.Provider = ParseProviderType(val)
+	// Provider configuration: settings.provider, which BJARNE_PROVIDER overrides
+	if settings.Provider != "" {
+		cfg.Provider = ParseProviderType(settings.Provider)
 	}
 
 	// BJARNE_API_KEY: Required for non-Bedrock providers
@@ -84,42 +101,8 @@ func LoadConfig() *Config {
 		cfg.Region = val
 	}
 
-	// Environment variable overrides
-	if val := os.Getenv("BJARNE_MAX_ITERATIONS"); val != "" {
-		if n, err := strconv.Atoi(val); err == nil && n > 0 {
-			cfg.MaxIterations = n
-		}
-	}
-
-	if val := os.Getenv("BJARNE_MAX_TOKENS"); val != "" {
-		if n, err := strconv.Atoi(val); err == nil && n > 0 {
-			cfg.MaxTokens = n
-		}
-	}
-
-	if val := os.Getenv("BJARNE_MAX_TOTAL_TOKENS"); val != "" {
-		if n, err := strconv.Atoi(val); err == nil && n >= 0 {
-			cfg.MaxTotalTokens = n // 0 = unlimited
-		}
-	}
-
-	// Model overrides (single model override applies to generate)
-	if val := os.Getenv("BJARNE_MODEL"); val != "" {
-		cfg.GenerateModel = val
-	}
-	if val := os.Getenv("BJARNE_CHAT_MODEL"); val != "" {
-		cfg.ChatModel = val
-	}
-
-	if val := os.Getenv("BJARNE_VALIDATOR_IMAGE"); val != "" {
-		cfg.ValidatorImage = val
-	}
-
-	if val := os.Getenv("BJARNE_THEME"); val != "" {
-		if _, ok := ThemePresets[val]; ok {
-			cfg.Settings.Theme.Name = val
-			cfg.Theme = NewTheme(&cfg.Settings.Theme)
-		}
+	if offlineMode {
+		cfg.applyOffline()
 	}
 
 	// Recalculate warning threshold if max changed
@@ -130,13 +113,26 @@ func LoadConfig() *Config {
 	return cfg
 }
 
+// SaveSettings writes the settings to ~/.bjarne/settings.json
+// Values from .bjarne.toml, the environment or --set are written back as settings.json had
+// them unless they were changed during the session, so overrides do not leak into the file
+func (c *Config) SaveSettings() error {
+	if c.Layers == nil || c.Layers.Settings != c.Settings {
+		return SaveSettings(c.Settings)
+	}
+	return SaveSettings(c.Layers.userView())
+}
+
 // GetProviderConfig returns a ProviderConfig from the Config
 func (c *Config) GetProviderConfig() *ProviderConfig {
 	return &ProviderConfig{
-		Provider: c.Provider,
-		APIKey:   c.APIKey,
-		Region:   c.Region,
-		Models:   c.Settings.Models,
+		Provider:   c.Provider,
+		APIKey:     c.APIKey,
+		Region:     c.Region,
+		Local:      c.Settings.Local,
+		Models:     c.Settings.Models,
+		Generation: c.Settings.Generation,
+		RateLimits: c.Settings.RateLimits,
 	}
 }
 
@@ -148,6 +144,14 @@ type TokenTracker struct {
 	MaxTokens    int
 	WarnAt       int
 	warned       bool
+
+	// EstimatedTokens is the part of TotalTokens counted locally (the provider reported no usage)
+	EstimatedTokens int
+
+	// Compaction thresholds: CompactAt input tokens in one request (0 = off), or WarnAt
+	CompactAt   int
+	LastInput   int
+	compactedAt int // TotalTokens when the conversation was last compacted
 }
 
 // NewTokenTracker creates a new token tracker with the given limits
@@ -158,10 +162,18 @@ func NewTokenTracker(maxTokens, warnAt int) *TokenTracker {
 	}
 }
 
+// sessionTokenTracker creates the session's tracker with the compaction threshold from settings
+func sessionTokenTracker(cfg *Config) *TokenTracker {
+	t := NewTokenTracker(cfg.MaxTotalTokens, cfg.WarnTokenThreshold)
+	t.CompactAt = cfg.Settings.Tokens.CompactAt
+	return t
+}
+
 // Add adds tokens to the tracker and returns (ok, warning message)
 func (t *TokenTracker) Add(input, output int) (bool, string) {
 	t.InputTokens += input
 	t.OutputTokens += output
+	t.LastInput = input
 	t.TotalTokens = t.InputTokens + t.OutputTokens
 
 	// Check if unlimited
@@ -189,11 +201,29 @@ func (t *TokenTracker) GetUsage() (input, output, total int) {
 	return t.InputTokens, t.OutputTokens, t.TotalTokens
 }
 
+// ShouldCompact reports whether the last request was large or the session is nearing its
+// budget without having been compacted since
+func (t *TokenTracker) ShouldCompact() bool {
+	if t.CompactAt > 0 && t.LastInput >= t.CompactAt {
+		return true
+	}
+	return t.WarnAt > 0 && t.TotalTokens >= t.WarnAt && t.compactedAt < t.WarnAt
+}
+
+// Compacted records that the conversation was just summarized
+func (t *TokenTracker) Compacted() {
+	t.compactedAt = t.TotalTokens
+	t.LastInput = 0
+}
+
 // Reset resets the token tracker
 func (t *TokenTracker) Reset() {
 	t.InputTokens = 0
 	t.OutputTokens = 0
 	t.TotalTokens = 0
+	t.LastInput = 0
+	t.compactedAt = 0
+	t.EstimatedTokens = 0
 	t.warned = false
 }
 
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -69,6 +71,27 @@ func TestLoadConfigUnlimited(t *testing.T) {
 	}
 }
 
+func TestLoadConfigProvider(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("BJARNE_PROVIDER", "")
+	if err := os.MkdirAll(filepath.Join(home, ".bjarne"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".bjarne", "settings.json"), []byte(`{"provider": "anthropic"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg := LoadConfig(); cfg.Provider != ProviderAnthropic {
+		t.Errorf("Provider = %q, want anthropic from settings", cfg.Provider)
+	}
+	t.Setenv("BJARNE_PROVIDER", "openai")
+	if cfg := LoadConfig(); cfg.Provider != ProviderOpenAI {
+		t.Errorf("Provider = %q, want openai from BJARNE_PROVIDER", cfg.Provider)
+	}
+}
+
 func TestTokenTracker(t *testing.T) {
 	t.Run("basic tracking", func(t *testing.T) {
 		tracker := NewTokenTracker(1000, 800)
@@ -135,6 +158,34 @@ func TestTokenTracker(t *testing.T) {
 		}
 	})
 
+	t.Run("compaction thresholds", func(t *testing.T) {
+		tracker := NewTokenTracker(1000, 800)
+		tracker.CompactAt = 300
+
+		tracker.Add(100, 50)
+		if tracker.ShouldCompact() {
+			t.Error("Should not compact a small request")
+		}
+		tracker.Add(350, 50) // One large request
+		if !tracker.ShouldCompact() {
+			t.Error("Should compact after a request over CompactAt")
+		}
+		tracker.Compacted()
+		if tracker.ShouldCompact() {
+			t.Error("Should not compact again right after compacting")
+		}
+
+		tracker.Add(200, 100) // 850 total, past WarnAt
+		if !tracker.ShouldCompact() {
+			t.Error("Should compact once the session passes WarnAt")
+		}
+		tracker.Compacted()
+		tracker.Add(50, 10)
+		if tracker.ShouldCompact() {
+			t.Error("Should compact only once past WarnAt")
+		}
+	})
+
 	t.Run("reset", func(t *testing.T) {
 		tracker := NewTokenTracker(1000, 800)
 		tracker.Add(500, 400)
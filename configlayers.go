@@ -0,0 +1,379 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// projectSettingsFile overrides ~/.bjarne/settings.json for one project (in the working directory)
+const projectSettingsFile = ".bjarne.toml"
+
+// Settings layers, lowest precedence first
+const (
+	layerDefault = iota
+	layerUser    // ~/.bjarne/settings.json
+	layerProject // ./.bjarne.toml
+	layerEnv     // BJARNE_* environment variables
+	layerFlag    // --set key=value
+)
+
+// projectDeniedSettings cannot come from a project file: a cloned repository must not be able
+// to redirect requests and credentials, weaken TLS, scanning or auditing, or swap the validator
+// image (a project picks one of the user's image profiles with container.profile instead),
+// write the log (which holds prompts at debug level) outside ~/.bjarne, or pick the signing key
+// for attestations and publish them to sigstore's public transparency log
+var projectDeniedSettings = []string{"provider", "local", "network", "guard", "redaction", "audit", "rateLimits", "container.image", "container.profiles", "logging.file", "attestation"}
+
+// settingsEnvVars are the environment variables that override a setting
+var settingsEnvVars = []struct {
+	Name  string
+	Path  string
+	Parse func(value string, s *Settings) (any, bool)
+}{
+	{"BJARNE_PROVIDER", "provider", envString},
+	{"BJARNE_MODEL", "models.generate", envString},
+	{"BJARNE_CHAT_MODEL", "models.chat", envString},
+	{"BJARNE_MAX_ITERATIONS", "validation.maxIterations", envInt(1)},
+	{"BJARNE_MAX_TOKENS", "tokens.maxPerResponse", envInt(1)},
+	{"BJARNE_MAX_TOTAL_TOKENS", "tokens.maxPerSession", envInt(0)}, // 0 = unlimited
+	{"BJARNE_VALIDATOR_IMAGE", "container.image", envString},
+	{"BJARNE_THEME", "theme.name", envTheme},
+	{"BJARNE_CA_BUNDLE", "network.caBundle", envString},
+	{"BJARNE_LOG_LEVEL", "logging.level", envString},
+}
+
+// envString accepts any value
+func envString(s string, _ *Settings) (any, bool) { return s, true }
+
+// envInt accepts integers of at least lo; other values are ignored, as they always were
+func envInt(lo int) func(string, *Settings) (any, bool) {
+	return func(s string, _ *Settings) (any, bool) {
+		n, err := strconv.Atoi(s)
+		return n, err == nil && n >= lo
+	}
+}
+
+// envTheme accepts the name of a theme preset or of a custom theme in the settings so far
+func envTheme(s string, settings *Settings) (any, bool) {
+	return s, slices.Contains(settings.Theme.Names(), s)
+}
+
+// settingFlags holds the --set key=value flags given on the command line
+var settingFlags []string
+
+// settingOrigin is the layer that last set a value
+type settingOrigin struct {
+	layer  int
+	source string // File path, "env NAME" or "--set"
+}
+
+// SettingsLayers is the effective settings and where each value came from:
+// defaults < ~/.bjarne/settings.json < ./.bjarne.toml < environment < --set flags
+type SettingsLayers struct {
+	Settings *Settings
+	Issues   []SettingsIssue // Project or flag values that were ignored
+
+	user    *Settings // Defaults plus settings.json: what is written back on save
+	loaded  *Settings // Settings as loaded, to tell session edits from overrides
+	origins map[string]settingOrigin
+}
+
+// LoadSettingsLayers merges every settings layer for a session in cwd
+// The error is LoadSettings's: settings.json could not be read or parsed (defaults are used for it)
+func LoadSettingsLayers(cwd string) (*SettingsLayers, error) {
+	l := &SettingsLayers{Settings: DefaultSettings(), origins: make(map[string]settingOrigin)}
+
+	// User settings keep LoadSettings's behavior, errors included
+	var loadErr error
+	if path, err := SettingsPath(); err == nil {
+		data, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			if err := json.Unmarshal(data, l.Settings); err != nil {
+				loadErr = settingsParseError(path, data, err)
+			}
+			var raw map[string]any
+			if json.Unmarshal(data, &raw) == nil {
+				l.mark(raw, reflect.TypeOf(Settings{}), nil, settingOrigin{layerUser, path}, nil)
+			}
+		case !os.IsNotExist(err):
+			loadErr = err
+		}
+	}
+	l.user = cloneSettings(l.Settings)
+
+	if cwd != "" {
+		path := filepath.Join(cwd, projectSettingsFile)
+		if data, err := os.ReadFile(path); err == nil {
+			raw, _, err := parseTOML(string(data))
+			if err != nil {
+				l.Issues = append(l.Issues, SettingsIssue{Message: fmt.Sprintf("%s ignored: %v", path, err)})
+			} else {
+				l.apply(raw, settingOrigin{layerProject, path}, projectDeniedSettings)
+			}
+		}
+	}
+
+	for _, env := range settingsEnvVars {
+		if s := os.Getenv(env.Name); s != "" {
+			if v, ok := env.Parse(s, l.Settings); ok {
+				l.apply(nestSetting(env.Path, v), settingOrigin{layerEnv, "env " + env.Name}, nil)
+			}
+		}
+	}
+
+	for _, flag := range settingFlags {
+		path, value, err := parseSettingFlag(flag)
+		if err != nil {
+			l.Issues = append(l.Issues, SettingsIssue{Message: err.Error()})
+			continue
+		}
+		l.apply(nestSetting(path, value), settingOrigin{layerFlag, "--set"}, nil)
+	}
+
+	l.loaded = cloneSettings(l.Settings)
+	return l, loadErr
+}
+
+// apply overlays raw (a settings.json-shaped tree) onto the settings, leaving out denied keys
+func (l *SettingsLayers) apply(raw map[string]any, origin settingOrigin, denied []string) {
+	l.mark(raw, reflect.TypeOf(Settings{}), nil, origin, denied)
+	if data, err := json.Marshal(raw); err == nil {
+		_ = json.Unmarshal(data, l.Settings) // Mistyped values keep their earlier value; config validate reports them
+	}
+}
+
+// mark records origin for every setting raw sets, removing those under a denied prefix
+func (l *SettingsLayers) mark(raw map[string]any, t reflect.Type, prefix []string, origin settingOrigin, denied []string) {
+	for key, value := range raw {
+		field, ok := settingsField(t, key)
+		if !ok {
+			continue // Unknown keys are reported by config validate
+		}
+		path := append(append([]string{}, prefix...), jsonFieldName(field))
+		name := strings.Join(path, ".")
+		if isDeniedSetting(name, denied) {
+			delete(raw, key)
+			l.Issues = append(l.Issues, SettingsIssue{
+				Path:    name,
+				Message: fmt.Sprintf("ignored in %s; set it in ~/.bjarne/settings.json instead", filepath.Base(origin.source)),
+			})
+			continue
+		}
+		if sub, ok := value.(map[string]any); ok && field.Type.Kind() == reflect.Struct {
+			l.mark(sub, field.Type, path, origin, denied)
+			continue
+		}
+		l.origins[name] = origin
+	}
+}
+
+// isDeniedSetting reports whether name is, or is under, one of denied
+func isDeniedSetting(name string, denied []string) bool {
+	for _, d := range denied {
+		if name == d || strings.HasPrefix(name, d+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// Origin describes where a setting's value came from
+func (l *SettingsLayers) Origin(path string) string {
+	if o, ok := l.origins[path]; ok {
+		return o.source
+	}
+	return "default"
+}
+
+// userView returns the settings to write back to settings.json: values that came from
+// the project file, the environment or --set keep their settings.json value unless they
+// were changed during the session
+func (l *SettingsLayers) userView() *Settings {
+	out := cloneSettings(l.Settings)
+	cur := reflect.ValueOf(out).Elem()
+	loaded := reflect.ValueOf(l.loaded).Elem()
+	user := reflect.ValueOf(l.user).Elem()
+	for name, o := range l.origins {
+		if o.layer <= layerUser {
+			continue
+		}
+		path := strings.Split(name, ".")
+		v := settingValue(cur, path)
+		if reflect.DeepEqual(v.Interface(), settingValue(loaded, path).Interface()) {
+			v.Set(settingValue(user, path))
+		}
+	}
+	return out
+}
+
+// Show writes every effective setting as "path = value", and where it came from when origins is set
+func (l *SettingsLayers) Show(w io.Writer, origins bool) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	walkSettings(reflect.ValueOf(l.Settings).Elem(), nil, func(path []string, v reflect.Value) {
+		name := strings.Join(path, ".")
+		value := formatSettingValue(name, v)
+		if origins {
+			fmt.Fprintf(tw, "%s\t= %s  # %s\n", name, value, l.Origin(name))
+		} else {
+			fmt.Fprintf(tw, "%s\t= %s\n", name, value)
+		}
+	})
+	_ = tw.Flush()
+}
+
+// walkSettings calls fn for each leaf setting (anything but a nested struct) in field order
+func walkSettings(v reflect.Value, prefix []string, fn func(path []string, v reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := jsonFieldName(t.Field(i))
+		if name == "" {
+			continue
+		}
+		path := append(append([]string{}, prefix...), name)
+		if f := v.Field(i); f.Kind() == reflect.Struct {
+			walkSettings(f, path, fn)
+		} else {
+			fn(path, f)
+		}
+	}
+}
+
+// formatSettingValue renders a value as JSON, masking secrets
+func formatSettingValue(name string, v reflect.Value) string {
+	if strings.HasSuffix(strings.ToLower(name), "apikey") && v.Kind() == reflect.String && v.String() != "" {
+		return `"********"`
+	}
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return fmt.Sprint(v.Interface())
+	}
+	return string(data)
+}
+
+// settingValue finds the (settable) field at a leaf path
+func settingValue(v reflect.Value, path []string) reflect.Value {
+	for _, name := range path {
+		field, _ := settingsField(v.Type(), name)
+		v = v.FieldByIndex(field.Index)
+	}
+	return v
+}
+
+// cloneSettings deep-copies settings
+func cloneSettings(s *Settings) *Settings {
+	out := &Settings{}
+	if data, err := json.Marshal(s); err == nil {
+		_ = json.Unmarshal(data, out)
+	}
+	return out
+}
+
+// nestSetting turns "a.b.c" and a value into {"a": {"b": {"c": value}}}
+func nestSetting(path string, value any) map[string]any {
+	parts := strings.Split(path, ".")
+	tree := map[string]any{parts[len(parts)-1]: value}
+	for i := len(parts) - 2; i >= 0; i-- {
+		tree = map[string]any{parts[i]: tree}
+	}
+	return tree
+}
+
+// parseSettingFlag splits a --set key=value flag, checking the key names a setting
+// The value is read as JSON when it parses (numbers, true/false, lists), else as a string
+func parseSettingFlag(flag string) (string, any, error) {
+	path, raw, ok := strings.Cut(flag, "=")
+	if !ok || path == "" {
+		return "", nil, fmt.Errorf("--set %s: expected key=value", flag)
+	}
+	t := reflect.TypeOf(Settings{})
+	parts := strings.Split(path, ".")
+	for i, name := range parts {
+		field, ok := settingsField(t, name)
+		if !ok {
+			return "", nil, fmt.Errorf("--set %s: unknown setting %q%s", flag, strings.Join(parts[:i+1], "."), suggestKey(t, name))
+		}
+		parts[i] = jsonFieldName(field)
+		t = field.Type
+		if t.Kind() != reflect.Struct && i < len(parts)-1 {
+			return "", nil, fmt.Errorf("--set %s: %s is not a group of settings", flag, strings.Join(parts[:i+1], "."))
+		}
+	}
+	if t.Kind() == reflect.Struct {
+		return "", nil, fmt.Errorf("--set %s: %s is a group of settings; set one of its keys", flag, path)
+	}
+	var value any
+	if json.Unmarshal([]byte(raw), &value) != nil {
+		value = raw
+	}
+	return strings.Join(parts, "."), value, nil
+}
+
+// parseSetFlags removes --set key=value (or --set=key=value) flags from args and checks them
+func parseSetFlags(args []string) ([]string, []string, error) {
+	var rest, sets []string
+	for i := 0; i < len(args); i++ {
+		var flag string
+		switch {
+		case args[i] == "--set":
+			if i+1 >= len(args) {
+				return nil, nil, fmt.Errorf("--set needs key=value")
+			}
+			i++
+			flag = args[i]
+		case strings.HasPrefix(args[i], "--set="):
+			flag = strings.TrimPrefix(args[i], "--set=")
+		default:
+			rest = append(rest, args[i])
+			continue
+		}
+		if _, _, err := parseSettingFlag(flag); err != nil {
+			return nil, nil, err
+		}
+		sets = append(sets, flag)
+	}
+	return rest, sets, nil
+}
+
+// checkProjectSettings validates .bjarne.toml content like settings.json, plus the keys a project may not set
+func checkProjectSettings(data []byte) []SettingsIssue {
+	raw, lines, err := parseTOML(string(data))
+	if err != nil {
+		return []SettingsIssue{{Message: err.Error()}}
+	}
+	l := &SettingsLayers{Settings: DefaultSettings(), origins: make(map[string]settingOrigin)}
+	l.mark(raw, reflect.TypeOf(Settings{}), nil, settingOrigin{layerProject, projectSettingsFile}, projectDeniedSettings)
+	jsonData, err := json.Marshal(raw)
+	if err != nil {
+		return []SettingsIssue{{Message: err.Error()}}
+	}
+	issues := append(l.Issues, checkSettingsData(jsonData)...)
+	for i := range issues {
+		issues[i].Line = tomlKeyLine(lines, issues[i].Path)
+	}
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].Line < issues[j].Line })
+	return issues
+}
+
+// tomlKeyLine finds the line that set a key, or the first line under it for a table
+func tomlKeyLine(lines map[string]int, path string) int {
+	if line, ok := lines[path]; ok || path == "" {
+		return line
+	}
+	first := 0
+	for key, line := range lines {
+		if strings.HasPrefix(key, path+".") && (first == 0 || line < first) {
+			first = line
+		}
+	}
+	return first
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setupLayers writes settings.json under a fake home and .bjarne.toml in a project directory
+func setupLayers(t *testing.T, userJSON, projectTOML string) (string, string) {
+	t.Helper()
+	home, project := t.TempDir(), t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	for _, env := range settingsEnvVars {
+		t.Setenv(env.Name, "")
+	}
+	userPath := filepath.Join(home, ".bjarne", "settings.json")
+	if err := os.MkdirAll(filepath.Dir(userPath), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(userPath, []byte(userJSON), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(project, projectSettingsFile), []byte(projectTOML), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return userPath, project
+}
+
+func TestLoadSettingsLayers(t *testing.T) {
+	userPath, project := setupLayers(t,
+		`{"review": {"threshold": 60}, "theme": {"name": "nord"}, "models": {"generate": "sonnet"}}`,
+		"provider = \"local\"\n[review]\nthreshold = 85\n[validation]\nmaxIterations = 5\n")
+	t.Setenv("BJARNE_MODEL", "opus")
+	t.Setenv("BJARNE_THEME", "no-such-theme") // Ignored, as before layering
+	t.Setenv("BJARNE_MAX_TOKENS", "0")        // Ignored: must be positive
+	old := settingFlags
+	settingFlags = []string{"tokens.maxPerSession=0"}
+	defer func() { settingFlags = old }()
+
+	l, err := LoadSettingsLayers(project)
+	if err != nil {
+		t.Fatalf("LoadSettingsLayers: %v", err)
+	}
+	projectPath := filepath.Join(project, projectSettingsFile)
+	tests := []struct {
+		path   string
+		got    any
+		want   any
+		origin string
+	}{
+		{"review.threshold", l.Settings.Review.Threshold, 85, projectPath},
+		{"validation.maxIterations", l.Settings.Validation.MaxIterations, 5, projectPath},
+		{"theme.name", l.Settings.Theme.Name, "nord", userPath},
+		{"models.generate", l.Settings.Models.Generate, "opus", "env BJARNE_MODEL"},
+		{"tokens.maxPerSession", l.Settings.Tokens.MaxPerSession, 0, "--set"},
+		{"tokens.maxPerResponse", l.Settings.Tokens.MaxPerResponse, 8192, "default"},
+		{"provider", l.Settings.Provider, "", "default"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.path, tt.got, tt.want)
+		}
+		if origin := l.Origin(tt.path); origin != tt.origin {
+			t.Errorf("origin of %s = %q, want %q", tt.path, origin, tt.origin)
+		}
+	}
+	if len(l.Issues) != 1 || l.Issues[0].Path != "provider" {
+		t.Errorf("Issues = %v, want the denied provider", l.Issues)
+	}
+
+	var out bytes.Buffer
+	l.Show(&out, true)
+	if !strings.Contains(out.String(), "= 85  # "+projectPath) {
+		t.Errorf("Show missing the project threshold:\n%s", out.String())
+	}
+}
+
+func TestConfigSaveSettingsKeepsOverrides(t *testing.T) {
+	userPath, project := setupLayers(t,
+		`{"review": {"threshold": 60}, "models": {"chat": "haiku"}}`,
+		"[review]\nthreshold = 85\n[validation]\nmaxIterations = 5\n[models]\nchat = \"sonnet\"\n")
+	t.Setenv("BJARNE_THEME", "matrix")
+
+	l, err := LoadSettingsLayers(project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := configFromSettings(l.Settings)
+	cfg.Layers = l
+
+	// Session edits: a user setting, and an overridden one
+	cfg.Settings.Display.Highlight = false
+	cfg.Settings.Models.Chat = "opus"
+	if err := cfg.SaveSettings(); err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := LoadSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Review.Threshold != 60 {
+		t.Errorf("review.threshold = %d, want settings.json's 60", saved.Review.Threshold)
+	}
+	if saved.Validation.MaxIterations != 3 {
+		t.Errorf("validation.maxIterations = %d, want the default 3", saved.Validation.MaxIterations)
+	}
+	if saved.Theme.Name != "default" {
+		t.Errorf("theme.name = %q, want BJARNE_THEME left out", saved.Theme.Name)
+	}
+	if saved.Display.Highlight {
+		t.Error("display.highlight edit was not saved")
+	}
+	if saved.Models.Chat != "opus" {
+		t.Errorf("models.chat = %q, want the session's opus", saved.Models.Chat)
+	}
+	if _, err := os.Stat(userPath); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseSetFlags(t *testing.T) {
+	rest, sets, err := parseSetFlags([]string{"-v", "--set", "review.threshold=80", "--set=models.escalation=[\"opus\"]", "a.cpp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(rest, " ") != "-v a.cpp" || len(sets) != 2 {
+		t.Errorf("rest = %v, sets = %v", rest, sets)
+	}
+
+	tests := []struct {
+		flag    string
+		path    string
+		value   any
+		wantErr string
+	}{
+		{"review.threshold=80", "review.threshold", float64(80), ""},
+		{"Review.Threshold=80", "review.threshold", float64(80), ""},
+		{"models.generate=claude-opus-4", "models.generate", "claude-opus-4", ""},
+		{"format.check=false", "format.check", false, ""},
+		{"review.treshold=80", "", nil, `unknown setting "review.treshold" (did you mean "threshold"?)`},
+		{"review=80", "", nil, "is a group of settings"},
+		{"provider.name=x", "", nil, "is not a group of settings"},
+		{"review.threshold", "", nil, "expected key=value"},
+	}
+	for _, tt := range tests {
+		path, value, err := parseSettingFlag(tt.flag)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseSettingFlag(%q) error = %v, want %q", tt.flag, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || path != tt.path || value != tt.value {
+			t.Errorf("parseSettingFlag(%q) = %q, %v, %v", tt.flag, path, value, err)
+		}
+	}
+	if _, _, err := parseSetFlags([]string{"--set"}); err == nil {
+		t.Error("--set without a value was accepted")
+	}
+}
+
+func TestCheckProjectSettings(t *testing.T) {
+	doc := "[review]\nthreshold = 150\n\n[guard]\nenabled = false\n\n[models]\ngenerate = \"sonet\"\ncolour = 1\n"
+	var got []string
+	for _, issue := range checkProjectSettings([]byte(doc)) {
+		got = append(got, issue.String())
+	}
+	want := []string{
+		"line 2: review.threshold: must be between 1 and 100 (got 150)",
+		"line 5: guard: ignored in .bjarne.toml; set it in ~/.bjarne/settings.json instead",
+		`line 8: models.generate: unknown model "sonet" (did you mean "sonnet"?)`,
+		"line 9: models.colour: unknown key",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("issues:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if issues := checkProjectSettings([]byte("a = ")); len(issues) != 1 || !strings.Contains(issues[0].Message, "line 1") {
+		t.Errorf("syntax error issues = %v", issues)
+	}
+	issues := checkProjectSettings([]byte("[attestation]\nkey = \"/tmp/attest.pem\"\nsigstore = true\n"))
+	if len(issues) != 1 || issues[0].Path != "attestation" {
+		t.Errorf("attestation issues = %v, want the denied attestation", issues)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// maxDivergences bounds the divergences reported by one consistency check
+const maxDivergences = 5
+
+// maxConsistencyTokens bounds the consistency checker's response
+const maxConsistencyTokens = 400
+
+// divergencePrefix starts each divergence line in the checker's response
+const divergencePrefix = "DIVERGENCE:"
+
+// parseConsistencyResponse returns the divergences the checker reported (none = consistent)
+func parseConsistencyResponse(response string) []string {
+	var divergences []string
+	for _, line := range strings.Split(response, "\n") {
+		line = strings.TrimLeft(strings.TrimSpace(line), "-* ")
+		if len(line) < len(divergencePrefix) || !strings.EqualFold(line[:len(divergencePrefix)], divergencePrefix) {
+			continue
+		}
+		if d := strings.TrimSpace(line[len(divergencePrefix):]); d != "" {
+			divergences = append(divergences, d)
+		}
+		if len(divergences) == maxDivergences {
+			break
+		}
+	}
+	return divergences
+}
+
+// checkConsistency asks model whether code keeps the commitments made in analysis
+// It returns the divergences found and the tokens the check used
+func checkConsistency(ctx context.Context, provider LLMProvider, model, request, analysis, code string) ([]string, *GenerateResult, error) {
+	prompt := fmt.Sprintf(ConsistencyCheckPrompt, request, strings.TrimSpace(analysis), code)
+	result, err := provider.Generate(ctx, model, "", []Message{{Role: "user", Content: prompt}}, maxConsistencyTokens)
+	if err != nil {
+		return nil, nil, fmt.Errorf("consistency check failed: %w", err)
+	}
+	return parseConsistencyResponse(result.Text), result, nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseConsistencyResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     []string
+	}{
+		{"consistent", "CONSISTENT", nil},
+		{
+			name:     "divergences",
+			response: "DIVERGENCE: lock-free ring buffer -> std::mutex around a std::deque\n- divergence: O(log n) lookup -> linear scan\nDIVERGENCE:\nSome commentary.",
+			want:     []string{"lock-free ring buffer -> std::mutex around a std::deque", "O(log n) lookup -> linear scan"},
+		},
+		{"capped", strings.Repeat("DIVERGENCE: x\n", maxDivergences+3), []string{"x", "x", "x", "x", "x"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseConsistencyResponse(tt.response)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("parseConsistencyResponse() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckConsistency(t *testing.T) {
+	provider := reviewerStub{
+		responses: map[string]string{"fast": "DIVERGENCE: promised thread-safe push -> push is unsynchronized"},
+		prompts:   make(chan string, 1),
+	}
+	got, usage, err := checkConsistency(context.Background(), provider, "fast", "a queue", "  I'll use a mutex-guarded queue.\n", "struct Q {};")
+	if err != nil || len(got) != 1 || usage == nil || usage.InputTokens != 100 {
+		t.Fatalf("checkConsistency() = %v, %+v, %v", got, usage, err)
+	}
+	prompt := <-provider.prompts
+	if !strings.Contains(prompt, "ANALYSIS GIVEN TO THE USER:\nI'll use a mutex-guarded queue.\n") || !strings.Contains(prompt, "struct Q {};") {
+		t.Errorf("consistency prompt = %q", prompt)
+	}
+
+	if _, _, err := checkConsistency(context.Background(), provider, "down", "a queue", "analysis", "code"); err == nil {
+		t.Error("checkConsistency() with a failing model should fail")
+	}
+}
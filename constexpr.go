@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// constexprFunctionPattern matches a constexpr or consteval function definition, capturing its name and parameters
+var constexprFunctionPattern = regexp.MustCompile(`\b(?:constexpr|consteval)\s+[\w:<>,\s*&]*?\b(\w+)\s*\(([^)]*)\)\s*(?:noexcept\s*)?(?:->\s*[\w:<>,\s*&]+)?\{`)
+
+// constexprChecksMarker starts the static_asserts appended to the checked file
+const constexprChecksMarker = "// bjarne: constexpr checks"
+
+// constexprHint explains a constexpr gate failure in the fix prompt
+const constexprHint = "Compile-time evaluation failed: the constexpr/consteval functions were evaluated in static_asserts. " +
+	"Undefined behavior (overflow, out-of-bounds access, reading an uninitialized value) and constructs a constant expression cannot use " +
+	"(non-constexpr calls, I/O, static locals, reinterpret_cast) make the expression non-constant; the notes show where evaluation stopped."
+
+// constexprFunctions returns the free functions declared constexpr or consteval, and whether each takes no parameters
+// Only definitions at file scope count: members and functions in namespaces cannot be called unqualified
+func constexprFunctions(code string) map[string]bool {
+	found := make(map[string]bool)
+	for _, m := range constexprFunctionPattern.FindAllStringSubmatchIndex(code, -1) {
+		if braceDepth(code[:m[0]]) != 0 {
+			continue
+		}
+		name := code[m[2]:m[3]]
+		if name == "main" || name == "operator" {
+			continue
+		}
+		params := strings.TrimSpace(code[m[4]:m[5]])
+		found[name] = found[name] || params == "" || params == "void"
+	}
+	return found
+}
+
+// braceDepth counts the braces left open in code
+func braceDepth(code string) int {
+	return strings.Count(code, "{") - strings.Count(code, "}")
+}
+
+// constexprChecks returns a static_assert for each example calling a constexpr function, and one
+// forcing compile-time evaluation of each constexpr function without parameters
+func constexprChecks(files []CodeFile, examples *ExampleTests) []string {
+	functions := make(map[string]bool)
+	for _, f := range files {
+		if !isSourceFile(f.Filename) && !isHeaderFile(f.Filename) {
+			continue
+		}
+		for name, noParams := range constexprFunctions(f.Content) {
+			functions[name] = functions[name] || noParams
+		}
+	}
+	if len(functions) == 0 {
+		return nil
+	}
+
+	var checks []string
+	called := make(map[string]bool)
+	if examples != nil {
+		for _, tc := range examples.Tests {
+			name := testFunctionName(tc)
+			if _, ok := functions[name]; !ok {
+				continue
+			}
+			called[name] = true
+			checks = append(checks, fmt.Sprintf("static_assert((%s) == (%s), \"%s\");",
+				tc.FunctionCall, tc.Expected, escapeString(tc.FunctionCall+" -> "+tc.Expected)))
+		}
+	}
+	for _, name := range sortedKeys(functions) {
+		if functions[name] && !called[name] {
+			checks = append(checks, fmt.Sprintf("static_assert((static_cast<void>(%s()), true), \"%s() is a constant expression\");", name, name))
+		}
+	}
+	return checks
+}
+
+// constexprGate compiles the project with static_asserts that evaluate its constexpr functions
+// It reports false when the code has nothing to evaluate at compile time
+func (c *ContainerRuntime) constexprGate(ctx context.Context, files []CodeFile, examples *ExampleTests, progress ProgressCallback) (ValidationResult, bool, error) {
+	return c.runAppendedChecks(ctx, "constexpr", files, constexprChecksMarker, constexprChecks(files, examples), progress)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const constexprCode = `#include <cstdint>
+
+constexpr int64_t fact(int n) {
+    return n <= 1 ? 1 : n * fact(n - 1);
+}
+
+template <typename T>
+constexpr T square(T x) { return x * x; }
+
+consteval int answer() { return 42; }
+
+struct Counter {
+    constexpr int size() { return n; }
+    int n = 0;
+};
+
+int main() {
+    if constexpr (sizeof(int) == 4) {
+        return static_cast<int>(fact(5));
+    }
+    constexpr int limit = square(3);
+    return limit;
+}
+`
+
+func TestConstexprFunctions(t *testing.T) {
+	got := constexprFunctions(constexprCode)
+	want := map[string]bool{"fact": false, "square": false, "answer": true}
+	if len(got) != len(want) {
+		t.Fatalf("constexprFunctions() = %v, want %v", got, want)
+	}
+	for name, noParams := range want {
+		if v, ok := got[name]; !ok || v != noParams {
+			t.Errorf("constexprFunctions()[%q] = %v, %v, want %v", name, v, ok, noParams)
+		}
+	}
+}
+
+func TestConstexprChecks(t *testing.T) {
+	examples := &ExampleTests{Tests: []TestCase{
+		{FunctionCall: "fact(5)", Expected: "120"},
+		{FunctionCall: "square(4)", Expected: "16"},
+		{FunctionCall: "format(1)", Expected: `"1"`},
+	}}
+	files := []CodeFile{{Filename: "code.cpp", Content: constexprCode}}
+	checks := constexprChecks(files, examples)
+	want := []string{
+		`static_assert((fact(5)) == (120), "fact(5) -> 120");`,
+		`static_assert((square(4)) == (16), "square(4) -> 16");`,
+		`static_assert((static_cast<void>(answer()), true), "answer() is a constant expression");`,
+	}
+	if strings.Join(checks, "\n") != strings.Join(want, "\n") {
+		t.Errorf("constexprChecks() = %q, want %q", checks, want)
+	}
+
+	if checks := constexprChecks([]CodeFile{{Filename: "code.cpp", Content: "int f(int x) { return x; }\n"}}, examples); checks != nil {
+		t.Errorf("constexprChecks(no constexpr) = %q, want none", checks)
+	}
+}
+
+func TestAppendChecks(t *testing.T) {
+	files := []CodeFile{
+		{Filename: "math.h", Content: "#pragma once\nconstexpr int twice(int x) { return 2 * x; }\n"},
+		{Filename: "util.cpp", Content: "int helper() { return 1; }\n"},
+		{Filename: "main.cpp", Content: "#include \"math.h\"\nint main() { return twice(1); }\n"},
+	}
+	checked, ok := appendChecks(files, constexprChecksMarker, []string{"static_assert(twice(2) == 4);"})
+	if !ok || checked.Filename != "main.cpp" {
+		t.Fatalf("appendChecks() = %+v, %v, want main.cpp", checked, ok)
+	}
+	if !strings.HasPrefix(checked.Content, files[2].Content) {
+		t.Errorf("appendChecks() moved the user's code: %q", checked.Content)
+	}
+	if !strings.HasSuffix(checked.Content, constexprChecksMarker+"\n#include \"math.h\"\nstatic_assert(twice(2) == 4);\n") {
+		t.Errorf("appendChecks() = %q", checked.Content)
+	}
+	if _, ok := appendChecks(files[:1], constexprChecksMarker, []string{"static_assert(true);"}); ok {
+		t.Error("appendChecks() succeeded without a source file")
+	}
+}
+
+func TestFormatErrorForLLMConstexpr(t *testing.T) {
+	output := "/src/code.cpp:26:15: error: static assertion expression is not an integral constant expression\n" +
+		"/src/code.cpp:4:25: note: value 6227020800 is outside the range of representable values of type 'int'\n" +
+		"/src/code.cpp:26:16: note: in call to 'fact(13)'\n"
+	got := FormatErrorForLLM("constexpr", output, []CodeFile{{Filename: "code.cpp", Content: constexprCode}})
+	for _, want := range []string{"[constexpr] Compile-time evaluation failed", "outside the range of representable values", "Source:", "n * fact(n - 1)"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("FormatErrorForLLM() = %q, missing %q", got, want)
+		}
+	}
+}
@@ -3,21 +3,55 @@ package main
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
 // ContainerRuntime represents a container runtime (podman or docker)
 type ContainerRuntime struct {
-	binary    string // "podman" or "docker"
-	imageName string // e.g., "bjarne-validator:latest" or "ghcr.io/3rg0n/bjarne-validator:latest"
+	binary    string         // "podman" or "docker"
+	imageName string         // e.g., "bjarne-validator:latest" or "ghcr.io/3rg0n/bjarne-validator:latest"
+	format    FormatSettings // clang-format gate and auto-format options
+
+	dependencies DependencySettings    // Third-party library allowlist and package manager
+	deps         *ResolvedDependencies // Libraries the current validation builds against (nil = none)
+	gates        GateSelection         // Stages to run (CLI --gates/--skip; zero value = all)
+	standard     string                // C++ standard, e.g. "c++20" (empty = defaultStandard)
+	tidy         ClangTidySettings     // Which clang-tidy findings fail the gate
+	suppressions []Suppression         // Accepted findings from .bjarne/suppressions.json
+	input        *RunInput             // stdin and arguments for the program stages (nil = none)
+	hang         HangSettings          // Watchdog on the stages that run the program
+	stress       StressSettings        // Repeated TSAN runs of threaded code
+	exceptions   ExceptionSettings     // Runs with injected allocation failures
+	modelCheck   ModelCheckSettings    // GenMC over code using weak atomic orderings
+	valgrind     ValgrindSettings      // Helgrind/DRD gate for raw-pthread code
+	templates    TemplateSettings      // Types the templates gate instantiates templates with
+	astRules     []ASTRule             // clang-query rules from .bjarne/ast-rules
+	artifacts    *artifactStaging      // Binaries kept for export (nil = not kept)
+	devices      []string              // Extra run flags passing host devices (GPUs) to the container
+	qt           *QtBuild              // moc/uic output and Qt flags (nil = not Qt code)
+	sandbox      SandboxSettings       // Network namespace the program stages run in
+	fixtures     *Fixtures             // Files from .bjarne/fixtures the program stages run with (nil = none)
+	coroutines   bool                  // The code uses C++20 coroutines
+	plugins      []ValidatorPlugin     // Domain validators from ~/.bjarne/validators
 }
 
+// defaultStandard is the C++ standard validation builds with unless --lang says otherwise
+const defaultStandard = "c++17"
+
+// supportedStandards are the values accepted by --lang, oldest first
+var supportedStandards = []string{"c++11", "c++14", "c++17", "c++20", "c++23"}
+
 // DetectContainerRuntime finds an available container runtime
 // Preference: podman > docker (per ADR-005)
 // Also checks ~/.bjarne/bin/ for locally installed binaries
@@ -27,6 +61,10 @@ func DetectContainerRuntime() (*ContainerRuntime, error) {
 		return &ContainerRuntime{
 			binary:    path,
 			imageName: getImageName(),
+			format:    DefaultSettings().Format,
+			tidy:      DefaultSettings().ClangTidy,
+			hang:      DefaultSettings().Hang,
+			stress:    DefaultSettings().Stress,
 		}, nil
 	}
 
@@ -35,6 +73,10 @@ func DetectContainerRuntime() (*ContainerRuntime, error) {
 		return &ContainerRuntime{
 			binary:    path,
 			imageName: getImageName(),
+			format:    DefaultSettings().Format,
+			tidy:      DefaultSettings().ClangTidy,
+			hang:      DefaultSettings().Hang,
+			stress:    DefaultSettings().Stress,
 		}, nil
 	}
 
@@ -84,13 +126,23 @@ func (c *ContainerRuntime) GetBinary() string {
 
 // ImageExists checks if the validation container image exists locally
 func (c *ContainerRuntime) ImageExists(ctx context.Context) bool {
-	cmd := exec.CommandContext(ctx, c.binary, "image", "inspect", c.imageName)
+	return c.HasImage(ctx, c.imageName)
+}
+
+// HasImage checks if an image reference is available locally
+func (c *ContainerRuntime) HasImage(ctx context.Context, ref string) bool {
+	cmd := exec.CommandContext(ctx, c.binary, "image", "inspect", ref)
 	return cmd.Run() == nil
 }
 
 // GetLocalImageDigest returns the digest of the local image, or empty string if not found
 func (c *ContainerRuntime) GetLocalImageDigest(ctx context.Context) string {
-	cmd := exec.CommandContext(ctx, c.binary, "image", "inspect", "--format", "{{.Digest}}", c.imageName)
+	return c.LocalDigest(ctx, c.imageName)
+}
+
+// LocalDigest returns the digest of a local image reference, or empty string if not found
+func (c *ContainerRuntime) LocalDigest(ctx context.Context, ref string) string {
+	cmd := exec.CommandContext(ctx, c.binary, "image", "inspect", "--format", "{{.Digest}}", ref)
 	output, err := cmd.Output()
 	if err != nil {
 		return ""
@@ -100,6 +152,9 @@ func (c *ContainerRuntime) GetLocalImageDigest(ctx context.Context) string {
 
 // GetRemoteImageDigest returns the digest of the remote image without pulling it
 func (c *ContainerRuntime) GetRemoteImageDigest(ctx context.Context) string {
+	if offlineMode {
+		return ""
+	}
 	// Use skopeo-style inspection via podman/docker manifest inspect
 	cmd := exec.CommandContext(ctx, c.binary, "manifest", "inspect", c.imageName)
 	output, err := cmd.Output()
@@ -123,9 +178,65 @@ func (c *ContainerRuntime) GetRemoteImageDigest(ctx context.Context) string {
 	return ""
 }
 
+// SetImage switches the validator image (e.g. to an image profile)
+func (c *ContainerRuntime) SetImage(ref string) {
+	c.imageName = ref
+}
+
+// ImageName returns the validator image reference
+func (c *ContainerRuntime) ImageName() string {
+	return c.imageName
+}
+
+// SetStandard selects the C++ standard every gate compiles and analyzes with
+func (c *ContainerRuntime) SetStandard(std string) {
+	c.standard = std
+}
+
+// stdFlag returns the -std= flag for clang++, clang-tidy and include-what-you-use
+func (c *ContainerRuntime) stdFlag() string {
+	if c.standard == "" {
+		return "-std=" + defaultStandard
+	}
+	return "-std=" + c.standard
+}
+
+// cppcheckStandard returns the --std value for cppcheck, which knows neither GNU
+// dialects nor standards newer than C++20
+func (c *ContainerRuntime) cppcheckStandard() string {
+	std := strings.Replace(strings.TrimPrefix(c.stdFlag(), "-std="), "gnu++", "c++", 1)
+	if std > "c++20" {
+		return "c++20"
+	}
+	return std
+}
+
+// ParseStandard normalizes a --lang value ("c++20", "C++20", "cpp20", "gnu++20" or "20")
+func ParseStandard(lang string) (string, error) {
+	std := strings.ToLower(strings.TrimSpace(lang))
+	prefix := "c++"
+	switch {
+	case strings.HasPrefix(std, "gnu++"):
+		prefix, std = "gnu++", strings.TrimPrefix(std, "gnu++")
+	case strings.HasPrefix(std, "c++"):
+		std = strings.TrimPrefix(std, "c++")
+	case strings.HasPrefix(std, "cpp"):
+		std = strings.TrimPrefix(std, "cpp")
+	}
+	for _, s := range supportedStandards {
+		if s == "c++"+std {
+			return prefix + std, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported language standard %q (use one of %s)", lang, strings.Join(supportedStandards, ", "))
+}
+
 // CheckForUpdate checks if a newer container image is available
 // Returns true if an update is available, false otherwise
 func (c *ContainerRuntime) CheckForUpdate(ctx context.Context) bool {
+	if isPinnedRef(c.imageName) {
+		return false // Pinned to a digest - never "outdated"
+	}
 	localDigest := c.GetLocalImageDigest(ctx)
 	if localDigest == "" {
 		return false // No local image, not an "update" scenario
@@ -148,13 +259,27 @@ func (c *ContainerRuntime) PullImage(ctx context.Context) error {
 	return cmd.Run()
 }
 
+// FetchImage pulls an image reference without writing to the terminal (for the TUI)
+func (c *ContainerRuntime) FetchImage(ctx context.Context, ref string) error {
+	containerLog.Info("pulling image", "ref", ref)
+	output, err := exec.CommandContext(ctx, c.binary, "pull", ref).CombinedOutput()
+	if err != nil {
+		containerLog.Warn("image pull failed", "ref", ref, "err", err)
+		return fmt.Errorf("%s pull %s failed: %w\n%s", c.binary, ref, err, lastLines(string(output), 10))
+	}
+	return nil
+}
+
 // ValidationResult holds the result of a validation run
 type ValidationResult struct {
-	Stage    string // "clang-tidy", "compile", "asan", "ubsan", "tsan", "run"
-	Success  bool
-	Output   string
-	Error    string
-	Duration time.Duration
+	Stage      string // "clang-tidy", "compile", "asan", "ubsan", "tsan", "run"
+	Success    bool
+	Output     string
+	Error      string
+	Duration   time.Duration
+	Skipped    bool     // Deselected by the gate selection (Success is true so the pipeline continues)
+	Suppressed int      // Findings ignored because they match .bjarne/suppressions.json
+	Command    []string // What the stage ran in the container, recorded in validation manifests
 }
 
 // ProgressCallback is called during validation to report progress
@@ -171,11 +296,22 @@ func (c *ContainerRuntime) ValidateMultiFileCode(ctx context.Context, files []Co
 }
 
 // ValidateMultiFileCodeWithExamples validates a multi-file project with example tests
-// Note: examples and dod parameters are reserved for future use (will be implemented similar to single-file validation)
-func (c *ContainerRuntime) ValidateMultiFileCodeWithExamples(ctx context.Context, files []CodeFile, examples *ExampleTests, dod *DefinitionOfDone) ([]ValidationResult, error) { //nolint:unparam // examples and dod will be used in future
-	// TODO: Implement example tests and DoD for multi-file projects
-	_ = examples // Reserved for future use
-	_ = dod      // Reserved for future use
+// Examples and DoD benchmarks run after the standard gates, linked against all compilation units
+func (c *ContainerRuntime) ValidateMultiFileCodeWithExamples(ctx context.Context, files []CodeFile, examples *ExampleTests, dod *DefinitionOfDone) ([]ValidationResult, error) {
+	return c.ValidateMultiFileCodeWithProgress(ctx, files, examples, dod, nil)
+}
+
+// ValidateMultiFileCodeWithProgress validates a multi-file project, reporting each gate as it starts and finishes
+func (c *ContainerRuntime) ValidateMultiFileCodeWithProgress(ctx context.Context, files []CodeFile, examples *ExampleTests, dod *DefinitionOfDone, progress ProgressCallback) ([]ValidationResult, error) {
+	if err := c.checkNetns(ctx); err != nil {
+		return nil, err
+	}
+	// Resolve declared third-party libraries; every stage below builds against them
+	c, depResult, err := c.withDependencies(ctx, files)
+	if err != nil || depResult != nil {
+		return resultsOf(depResult), err
+	}
+	c = c.withRunInput(dod)
 
 	// Create temp directory for all files
 	tmpDir, err := os.MkdirTemp("", "bjarne-validate-*")
@@ -196,6 +332,9 @@ func (c *ContainerRuntime) ValidateMultiFileCodeWithExamples(ctx context.Context
 			sourceFiles = append(sourceFiles, "/src/"+f.Filename)
 		}
 	}
+	if err := c.input.write(tmpDir); err != nil {
+		return nil, err
+	}
 
 	if len(sourceFiles) == 0 {
 		return nil, fmt.Errorf("no source files (.cpp/.cc/.c) found")
@@ -206,11 +345,33 @@ func (c *ContainerRuntime) ValidateMultiFileCodeWithExamples(ctx context.Context
 
 	var results []ValidationResult
 
+	// Helper to run a stage with progress
+	runStage := func(stage string, command ...string) ValidationResult {
+		if progress != nil {
+			progress(stage, true, nil)
+		}
+		result := c.runValidationStage(ctx, tmpDir, stage, command...)
+		if progress != nil {
+			progress(stage, false, &result)
+		}
+		return result
+	}
+	runHarness := func(stage string, harness []CodeFile, optFlags string) (ValidationResult, error) {
+		if progress != nil {
+			progress(stage, true, nil)
+		}
+		result, err := c.runProjectHarness(ctx, stage, harness, optFlags)
+		if err == nil && progress != nil {
+			progress(stage, false, &result)
+		}
+		return result, err
+	}
+
 	// Stage 1: clang-tidy on all source files
 	for _, f := range files {
 		if strings.HasSuffix(f.Filename, ".cpp") || strings.HasSuffix(f.Filename, ".cc") || strings.HasSuffix(f.Filename, ".c") {
-			result := c.runValidationStage(ctx, tmpDir, "clang-tidy:"+f.Filename,
-				"clang-tidy", "-quiet", "-header-filter=.*", "/src/"+f.Filename, "--", "-std=c++17", "-Wall", "-Wextra", "-I/src")
+			result := runStage("clang-tidy:"+f.Filename,
+				append([]string{"clang-tidy", "-quiet", "-header-filter=.*", "/src/" + f.Filename, "--", c.stdFlag(), "-Wall", "-Wextra", "-I/src"}, c.compileFlags()...)...)
 			results = append(results, result)
 			if !result.Success {
 				return results, nil
@@ -219,9 +380,9 @@ func (c *ContainerRuntime) ValidateMultiFileCodeWithExamples(ctx context.Context
 	}
 
 	// Stage 2: cppcheck on all files
-	result := c.runValidationStage(ctx, tmpDir, "cppcheck",
+	result := runStage("cppcheck",
 		"sh", "-c",
-		"which cppcheck > /dev/null 2>&1 && cppcheck --enable=all --error-exitcode=1 --suppress=missingIncludeSystem --std=c++17 -I/src /src/*.cpp /src/*.h 2>&1 || (which cppcheck > /dev/null 2>&1 || echo 'cppcheck not installed, skipping')")
+		"which cppcheck > /dev/null 2>&1 && cppcheck --enable=all --error-exitcode=1 --inline-suppr --suppress=missingIncludeSystem --std="+c.cppcheckStandard()+" -I/src /src/*.cpp /src/*.h 2>&1 || (which cppcheck > /dev/null 2>&1 || echo 'cppcheck not installed, skipping')")
 	if !result.Success && !strings.Contains(result.Output, "not installed") {
 		results = append(results, result)
 		return results, nil
@@ -229,31 +390,64 @@ func (c *ContainerRuntime) ValidateMultiFileCodeWithExamples(ctx context.Context
 	if !strings.Contains(result.Output, "not installed") {
 		results = append(results, result)
 	}
+	if result, ok, err := c.astRulesGate(tmpDir, sourceFiles, runStage); err != nil {
+		return results, err
+	} else if ok {
+		results = append(results, result)
+		if !result.Success {
+			return results, nil
+		}
+	}
+
+	// Format check (clang-format) on all files
+	if c.format.Check {
+		var names []string
+		for _, f := range files {
+			names = append(names, f.Filename)
+		}
+		if result, ran := c.runFormatStage(ctx, tmpDir, names, progress); ran {
+			results = append(results, result)
+			if !result.Success {
+				return results, nil
+			}
+		}
+	}
 
 	// Stage 3: Compile all source files together with hardening flags
 	// Security hardening: stack protector, FORTIFY_SOURCE, PIE, RELRO
 	// Note: -U_FORTIFY_SOURCE before -D to avoid macro redefinition error (container may have it set)
-	result = c.runValidationStage(ctx, tmpDir, "compile",
+	result = runStage("compile",
 		"sh", "-c",
-		"clang++ -std=c++17 -Wall -Wextra -Werror -fstack-protector-all -U_FORTIFY_SOURCE -D_FORTIFY_SOURCE=2 -fPIE -pie -Wl,-z,relro -Wl,-z,now -I/src -o /tmp/test "+srcArgs)
+		c.cxx(c.stdFlag()+" -Wall -Wextra -Werror -fstack-protector-all -U_FORTIFY_SOURCE -D_FORTIFY_SOURCE=2 -fPIE -pie -Wl,-z,relro -Wl,-z,now -I/src -o /tmp/test "+srcArgs))
 	results = append(results, result)
 	if !result.Success {
 		return results, nil
 	}
+	if result, ok := c.coroutinesGate(files, progress); ok {
+		results = append(results, result)
+	}
 
 	// Stage 4: ASAN
-	result = c.runValidationStage(ctx, tmpDir, "asan",
+	result = runStage("asan",
 		"sh", "-c",
-		"clang++ -std=c++17 -fsanitize=address -fno-omit-frame-pointer -g -I/src -o /tmp/test "+srcArgs+" && /tmp/test")
+		c.keepBinary("asan", c.cxx(c.stdFlag()+" -fsanitize=address -fno-omit-frame-pointer -g -I/src -o /tmp/test "+srcArgs))+" && "+c.programCommand(c.asanOptions(), "/tmp/test"))
 	results = append(results, result)
 	if !result.Success {
 		return results, nil
 	}
+	if result, ok, err := c.exceptionsGate(tmpDir, "-I/src "+srcArgs, runStage); err != nil {
+		return results, err
+	} else if ok {
+		results = append(results, result)
+		if !result.Success {
+			return results, nil
+		}
+	}
 
 	// Stage 5: UBSAN
-	result = c.runValidationStage(ctx, tmpDir, "ubsan",
+	result = runStage("ubsan",
 		"sh", "-c",
-		"clang++ -std=c++17 -fsanitize=undefined -fno-omit-frame-pointer -g -I/src -o /tmp/test "+srcArgs+" && /tmp/test")
+		c.keepBinary("ubsan", c.cxx(c.stdFlag()+" -fsanitize=undefined -fno-omit-frame-pointer -g -I/src -o /tmp/test "+srcArgs))+" && "+c.programCommand("", "/tmp/test"))
 	results = append(results, result)
 	if !result.Success {
 		return results, nil
@@ -261,12 +455,12 @@ func (c *ContainerRuntime) ValidateMultiFileCodeWithExamples(ctx context.Context
 
 	// Stage 6: MSan (MemorySanitizer) - detects uninitialized memory reads
 	// Note: MSan works best for heap allocations. See single-file validation for details.
-	result = c.runValidationStage(ctx, tmpDir, "msan",
+	result = runStage("msan",
 		"sh", "-c",
-		"clang++ -std=c++17 -fsanitize=memory -fsanitize-memory-track-origins "+
+		c.keepBinary("msan", c.cxx(c.stdFlag()+" -fsanitize=memory -fsanitize-memory-track-origins "+
 			"-fno-omit-frame-pointer -g -O1 "+
-			"-I/src -o /tmp/test "+srcArgs+" 2>&1 && "+
-			"MSAN_OPTIONS=halt_on_error=1 /tmp/test 2>&1")
+			"-I/src -o /tmp/test "+srcArgs)+" 2>&1")+" && "+
+			c.programCommand("MSAN_OPTIONS=halt_on_error=1 ", "/tmp/test")+" 2>&1")
 	results = append(results, result)
 	if !result.Success {
 		return results, nil
@@ -281,24 +475,224 @@ func (c *ContainerRuntime) ValidateMultiFileCodeWithExamples(ctx context.Context
 		}
 	}
 	if usesThreads {
-		result = c.runValidationStage(ctx, tmpDir, "tsan",
+		if result, ok := c.deadlockGate(files, "-I/src "+srcArgs, runStage, progress); ok {
+			results = append(results, result)
+			if !result.Success {
+				return results, nil
+			}
+		}
+		result = runStage("tsan",
 			"sh", "-c",
-			"clang++ -std=c++17 -fsanitize=thread -fno-omit-frame-pointer -g -I/src -o /tmp/test "+srcArgs+" && /tmp/test")
+			c.keepBinary("tsan", c.cxx(c.stdFlag()+" -fsanitize=thread -fno-omit-frame-pointer -g -I/src -o /tmp/test "+srcArgs))+" && "+c.programCommand("", "/tmp/test"))
 		results = append(results, result)
 		if !result.Success {
 			return results, nil
 		}
+		result, ok, err := c.stressGate(tmpDir, "-I/src "+srcArgs, runStage)
+		if err != nil {
+			return results, err
+		}
+		if ok {
+			results = append(results, result)
+			if !result.Success {
+				return results, nil
+			}
+		}
+		usesPthreads := false
+		for _, f := range files {
+			usesPthreads = usesPthreads || codeUsesPthreads(f.Content)
+		}
+		if result, ok := c.valgrindGate(usesPthreads, "-I/src "+srcArgs, runStage); ok {
+			results = append(results, result)
+			if !result.Success {
+				return results, nil
+			}
+		}
+		if result, ok, err := c.modelCheckGate(tmpDir, files, runStage); err != nil {
+			return results, err
+		} else if ok {
+			results = append(results, result)
+			if !result.Success {
+				return results, nil
+			}
+		}
 	}
 
 	// Stage 8: Final run
-	result = c.runValidationStage(ctx, tmpDir, "run",
+	result = runStage("run",
 		"sh", "-c",
-		"clang++ -std=c++17 -O2 -I/src -o /tmp/test "+srcArgs+" && /tmp/test")
+		c.keepBinary("run", c.cxx(c.stdFlag()+" -O2 -g -I/src -o /tmp/test "+srcArgs))+" && "+c.runCommand("/tmp/test"))
 	results = append(results, result)
+	if !result.Success {
+		return results, nil
+	}
+	if result, ok := c.outputGate(dod, result, progress); ok {
+		results = append(results, result)
+		if !result.Success {
+			return results, nil
+		}
+	}
+	if result, ok := c.filesGate(tmpDir, result, progress); ok {
+		results = append(results, result)
+		if !result.Success {
+			return results, nil
+		}
+	}
+
+	if result, ok, err := c.constexprGate(ctx, files, examples, progress); err != nil {
+		return results, err
+	} else if ok {
+		results = append(results, result)
+		if !result.Success {
+			return results, nil
+		}
+	}
+	if result, ok, err := c.templatesGate(ctx, files, progress); err != nil {
+		return results, err
+	} else if ok {
+		results = append(results, result)
+		if !result.Success {
+			return results, nil
+		}
+	}
+
+	// Run example tests if provided
+	if examples != nil && len(examples.Tests) > 0 {
+		harness := GenerateProjectHarness(files, testHarnessPreamble(), testHarnessMain(examples))
+		result, err := runHarness("examples", harness, "-g")
+		if err != nil {
+			return results, err
+		}
+		results = append(results, result)
+		if !result.Success {
+			return results, nil // Fail fast on example tests
+		}
+	}
+
+	// Run benchmark if DoD has performance requirements
+	if dod != nil && dod.MaxTimeMs > 0 {
+		var all strings.Builder
+		for _, f := range files {
+			if isSourceFile(f.Filename) || isHeaderFile(f.Filename) {
+				all.WriteString(f.Content + "\n")
+			}
+		}
+		if funcCall := detectBenchmarkFunction(all.String(), examples); funcCall != "" {
+			result, err := runHarness("benchmark", dod.GenerateProjectBenchmark(files, funcCall), "-O2")
+			if err != nil {
+				return results, err
+			}
+			results = append(results, result)
+		}
+	}
 
 	return results, nil
 }
 
+// runProjectHarness compiles every source file of a harness project together and runs it
+func (c *ContainerRuntime) runProjectHarness(ctx context.Context, stage string, files []CodeFile, optFlags string) (ValidationResult, error) {
+	tmpDir, err := os.MkdirTemp("", "bjarne-"+stage+"-*")
+	if err != nil {
+		return ValidationResult{}, fmt.Errorf("failed to create temp dir for %s: %w", stage, err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	var sources []string
+	for _, f := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, f.Filename), []byte(f.Content), 0600); err != nil {
+			return ValidationResult{}, fmt.Errorf("failed to write %s: %w", f.Filename, err)
+		}
+		if isSourceFile(f.Filename) {
+			sources = append(sources, "/src/"+f.Filename)
+		}
+	}
+
+	return c.runValidationStage(ctx, tmpDir, stage,
+		"sh", "-c",
+		c.cxx(c.stdFlag()+" "+optFlags+" -I/src -o /tmp/"+stage+" "+strings.Join(sources, " "))+" && "+c.netnsCommand("", "/tmp/"+stage)), nil
+}
+
+// runAppendedChecks compiles the project with checks appended to one source file (see appendChecks), without linking
+// Gates that only need the compiler use it; it reports false when there are no checks
+func (c *ContainerRuntime) runAppendedChecks(ctx context.Context, stage string, files []CodeFile, marker string, checks []string, progress ProgressCallback) (ValidationResult, bool, error) {
+	if len(checks) == 0 {
+		return ValidationResult{}, false, nil
+	}
+	checked, ok := appendChecks(files, marker, checks)
+	if !ok {
+		return ValidationResult{}, false, nil
+	}
+
+	tmpDir, err := os.MkdirTemp("", "bjarne-"+stage+"-*")
+	if err != nil {
+		return ValidationResult{}, false, fmt.Errorf("failed to create temp dir for %s: %w", stage, err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	for _, f := range files {
+		if f.Filename == checked.Filename {
+			f = checked
+		}
+		if err := os.WriteFile(filepath.Join(tmpDir, f.Filename), []byte(f.Content), 0600); err != nil {
+			return ValidationResult{}, false, fmt.Errorf("failed to write %s: %w", f.Filename, err)
+		}
+	}
+
+	if progress != nil {
+		progress(stage, true, nil)
+	}
+	result := c.runValidationStage(ctx, tmpDir, stage,
+		"sh", "-c", c.cxx(c.stdFlag()+" -fsyntax-only -I/src /src/"+checked.Filename))
+	if progress != nil {
+		progress(stage, false, &result)
+	}
+	return result, true, nil
+}
+
+// CheckSyntax compiles one file of a partially written project without linking
+// Used while scaffolding, before the files that would make the project link exist
+func (c *ContainerRuntime) CheckSyntax(ctx context.Context, files []CodeFile, target string) (ValidationResult, error) {
+	// Headers are checked as C++ sources; -Wno-pragma-once-outside-header keeps #pragma once quiet
+	return c.runStageOnFiles(ctx, files, "syntax:"+target, func(c *ContainerRuntime) []string {
+		return append([]string{"clang++", c.stdFlag(), "-fsyntax-only", "-Wall", "-Wextra", "-Werror",
+			"-Wno-pragma-once-outside-header", "-x", "c++", "-I/src", "/src/" + target}, c.compileFlags()...)
+	})
+}
+
+// LintFile runs clang-tidy on one file of a project that may not link
+// Used by the language server for headers and sources without main()
+func (c *ContainerRuntime) LintFile(ctx context.Context, files []CodeFile, target string) (ValidationResult, error) {
+	return c.runStageOnFiles(ctx, files, "clang-tidy", func(c *ContainerRuntime) []string {
+		return append([]string{"clang-tidy", "-quiet", "-header-filter=.*", "/src/" + target, "--",
+			c.stdFlag(), "-Wall", "-Wextra", "-Wno-pragma-once-outside-header", "-x", "c++", "-I/src"}, c.compileFlags()...)
+	})
+}
+
+// runStageOnFiles resolves dependencies, writes the files to a temp directory and runs one stage
+// command receives the runtime with dependencies resolved so it can add their flags
+func (c *ContainerRuntime) runStageOnFiles(ctx context.Context, files []CodeFile, stage string, command func(c *ContainerRuntime) []string) (ValidationResult, error) {
+	c, depResult, err := c.withDependencies(ctx, files)
+	if err != nil {
+		return ValidationResult{}, err
+	}
+	if depResult != nil {
+		return *depResult, nil
+	}
+
+	tmpDir, err := os.MkdirTemp("", "bjarne-check-*")
+	if err != nil {
+		return ValidationResult{}, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	for _, f := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, f.Filename), []byte(f.Content), 0600); err != nil {
+			return ValidationResult{}, fmt.Errorf("failed to write %s: %w", f.Filename, err)
+		}
+	}
+
+	return c.runValidationStage(ctx, tmpDir, stage, command(c)...), nil
+}
+
 // ValidateCodeWithExamples runs validation including example-based tests
 func (c *ContainerRuntime) ValidateCodeWithExamples(ctx context.Context, code string, filename string, examples *ExampleTests, dod *DefinitionOfDone) ([]ValidationResult, error) {
 	return c.validateCodeFull(ctx, code, filename, examples, dod, nil)
@@ -311,6 +705,13 @@ func (c *ContainerRuntime) ValidateCodeWithDoD(ctx context.Context, code string,
 
 // validateCodeFull runs the full validation pipeline with examples and DoD
 func (c *ContainerRuntime) validateCodeFull(ctx context.Context, code string, filename string, examples *ExampleTests, dod *DefinitionOfDone, progress ProgressCallback) ([]ValidationResult, error) {
+	// Resolve declared third-party libraries once for the gates and the harnesses
+	c, depResult, err := c.withDependencies(ctx, []CodeFile{{Filename: filename, Content: code}})
+	if err != nil || depResult != nil {
+		return resultsOf(depResult), err
+	}
+	c = c.withRunInput(dod)
+
 	// First, validate the original code through normal pipeline
 	results, err := c.ValidateCodeWithProgress(ctx, code, filename, progress)
 	if err != nil {
@@ -329,6 +730,36 @@ func (c *ContainerRuntime) validateCodeFull(ctx context.Context, code string, fi
 		return results, nil // Fail fast on normal validation
 	}
 
+	// Compare the run stage's output with the expected output
+	for _, run := range results {
+		if run.Stage != "run" {
+			continue
+		}
+		if result, ok := c.outputGate(dod, run, progress); ok {
+			results = append(results, result)
+			if !result.Success {
+				return results, nil
+			}
+		}
+	}
+
+	if result, ok, err := c.constexprGate(ctx, []CodeFile{{Filename: filename, Content: code}}, examples, progress); err != nil {
+		return results, err
+	} else if ok {
+		results = append(results, result)
+		if !result.Success {
+			return results, nil
+		}
+	}
+	if result, ok, err := c.templatesGate(ctx, []CodeFile{{Filename: filename, Content: code}}, progress); err != nil {
+		return results, err
+	} else if ok {
+		results = append(results, result)
+		if !result.Success {
+			return results, nil
+		}
+	}
+
 	// Run example tests if provided
 	if examples != nil && len(examples.Tests) > 0 {
 		harness := GenerateTestHarness(code, examples)
@@ -353,7 +784,7 @@ func (c *ContainerRuntime) validateCodeFull(ctx context.Context, code string, fi
 		}
 		result := c.runValidationStage(ctx, tmpDir, "examples",
 			"sh", "-c",
-			"clang++ -std=c++17 -o /tmp/test_harness /src/"+harnessFilename+" && /tmp/test_harness")
+			c.cxx(c.stdFlag()+" -o /tmp/test_harness /src/"+harnessFilename)+" && "+c.netnsCommand("", "/tmp/test_harness"))
 		if progress != nil {
 			progress("examples", false, &result)
 		}
@@ -391,7 +822,7 @@ func (c *ContainerRuntime) validateCodeFull(ctx context.Context, code string, fi
 			}
 			result := c.runValidationStage(ctx, tmpDir, "benchmark",
 				"sh", "-c",
-				"clang++ -std=c++17 -O2 -o /tmp/benchmark /src/"+benchFilename+" && /tmp/benchmark")
+				c.cxx(c.stdFlag()+" -O2 -o /tmp/benchmark /src/"+benchFilename)+" && "+c.netnsCommand("", "/tmp/benchmark"))
 			if progress != nil {
 				progress("benchmark", false, &result)
 			}
@@ -404,6 +835,14 @@ func (c *ContainerRuntime) validateCodeFull(ctx context.Context, code string, fi
 
 // ValidateCodeWithProgress runs the full validation pipeline with progress callbacks
 func (c *ContainerRuntime) ValidateCodeWithProgress(ctx context.Context, code string, filename string, progress ProgressCallback) ([]ValidationResult, error) {
+	if err := c.checkNetns(ctx); err != nil {
+		return nil, err
+	}
+	c, depResult, err := c.withDependencies(ctx, []CodeFile{{Filename: filename, Content: code}})
+	if err != nil || depResult != nil {
+		return resultsOf(depResult), err
+	}
+
 	// Create temp directory for the code
 	tmpDir, err := os.MkdirTemp("", "bjarne-validate-*")
 	if err != nil {
@@ -416,6 +855,9 @@ func (c *ContainerRuntime) ValidateCodeWithProgress(ctx context.Context, code st
 	if err := os.WriteFile(codePath, []byte(code), 0600); err != nil {
 		return nil, fmt.Errorf("failed to write code file: %w", err)
 	}
+	if err := c.input.write(tmpDir); err != nil {
+		return nil, err
+	}
 
 	var results []ValidationResult
 
@@ -434,7 +876,7 @@ func (c *ContainerRuntime) ValidateCodeWithProgress(ctx context.Context, code st
 	// Stage 1: clang-tidy (static analysis)
 	// -quiet removes system header noise, focusing on user code issues
 	result := runStage("clang-tidy",
-		"clang-tidy", "-quiet", "-header-filter=.*", "/src/"+filename, "--", "-std=c++17", "-Wall", "-Wextra")
+		append([]string{"clang-tidy", "-quiet", "-header-filter=.*", "/src/" + filename, "--", c.stdFlag(), "-Wall", "-Wextra"}, c.compileFlags()...)...)
 	results = append(results, result)
 	if !result.Success {
 		return results, nil // Fail fast
@@ -444,7 +886,7 @@ func (c *ContainerRuntime) ValidateCodeWithProgress(ctx context.Context, code st
 	// Skip if cppcheck not installed
 	result = runStage("cppcheck",
 		"sh", "-c",
-		"which cppcheck > /dev/null 2>&1 && cppcheck --enable=all --error-exitcode=1 --suppress=missingIncludeSystem --std=c++17 /src/"+filename+" || (which cppcheck > /dev/null 2>&1 || echo 'cppcheck not installed, skipping')")
+		"which cppcheck > /dev/null 2>&1 && cppcheck --enable=all --error-exitcode=1 --inline-suppr --suppress=missingIncludeSystem --std="+c.cppcheckStandard()+" /src/"+filename+" || (which cppcheck > /dev/null 2>&1 || echo 'cppcheck not installed, skipping')")
 	// Only fail if cppcheck exists and found issues
 	if !result.Success && !strings.Contains(result.Output, "not installed") {
 		results = append(results, result)
@@ -453,12 +895,20 @@ func (c *ContainerRuntime) ValidateCodeWithProgress(ctx context.Context, code st
 	if !strings.Contains(result.Output, "not installed") {
 		results = append(results, result)
 	}
+	if result, ok, err := c.astRulesGate(tmpDir, []string{"/src/" + filename}, runStage); err != nil {
+		return results, err
+	} else if ok {
+		results = append(results, result)
+		if !result.Success {
+			return results, nil
+		}
+	}
 
 	// Stage 3: IWYU (Include What You Use) - check header hygiene
 	// IWYU always returns non-zero, so we check for actual suggestions in output
 	result = runStage("iwyu",
 		"sh", "-c",
-		"include-what-you-use -std=c++17 /src/"+filename+" 2>&1; exit 0")
+		"include-what-you-use "+c.stdFlag()+" /src/"+filename+" 2>&1; exit 0")
 	// IWYU is advisory - we mark success if it ran, the suggestions are informational
 	result.Success = true
 	results = append(results, result)
@@ -477,32 +927,54 @@ func (c *ContainerRuntime) ValidateCodeWithProgress(ctx context.Context, code st
 		results = append(results, result)
 	}
 
+	// Format check (clang-format) - uses the project's .clang-format if present
+	if c.format.Check {
+		if result, ran := c.runFormatStage(ctx, tmpDir, []string{filename}, progress); ran {
+			results = append(results, result)
+			if !result.Success {
+				return results, nil
+			}
+		}
+	}
+
 	// Stage 5: Compile with strict warnings and hardening flags
 	// Security hardening: stack protector, FORTIFY_SOURCE, PIE, RELRO
 	// Note: -U_FORTIFY_SOURCE before -D to avoid macro redefinition error (container may have it set)
-	result = runStage("compile",
-		"clang++", "-std=c++17", "-Wall", "-Wextra", "-Werror",
+	compileArgs := []string{"clang++", c.stdFlag(), "-Wall", "-Wextra", "-Werror",
 		"-fstack-protector-all", "-U_FORTIFY_SOURCE", "-D_FORTIFY_SOURCE=2",
 		"-fPIE", "-pie", "-Wl,-z,relro", "-Wl,-z,now",
-		"-o", "/tmp/test", "/src/"+filename)
+		"-o", "/tmp/test", "/src/" + filename}
+	compileArgs = append(compileArgs, c.compileFlags()...)
+	result = runStage("compile", append(compileArgs, c.linkFlags()...)...)
 	results = append(results, result)
 	if !result.Success {
 		return results, nil
 	}
+	if result, ok := c.coroutinesGate([]CodeFile{{Filename: filename, Content: code}}, progress); ok {
+		results = append(results, result)
+	}
 
 	// Stage 6: ASAN (AddressSanitizer)
 	result = runStage("asan",
 		"sh", "-c",
-		"clang++ -std=c++17 -fsanitize=address -fno-omit-frame-pointer -g -o /tmp/test /src/"+filename+" && /tmp/test")
+		c.keepBinary("asan", c.cxx(c.stdFlag()+" -fsanitize=address -fno-omit-frame-pointer -g -o /tmp/test /src/"+filename))+" && "+c.programCommand(c.asanOptions(), "/tmp/test"))
 	results = append(results, result)
 	if !result.Success {
 		return results, nil
 	}
+	if result, ok, err := c.exceptionsGate(tmpDir, "/src/"+filename, runStage); err != nil {
+		return results, err
+	} else if ok {
+		results = append(results, result)
+		if !result.Success {
+			return results, nil
+		}
+	}
 
 	// Stage 7: UBSAN (UndefinedBehaviorSanitizer)
 	result = runStage("ubsan",
 		"sh", "-c",
-		"clang++ -std=c++17 -fsanitize=undefined -fno-omit-frame-pointer -g -o /tmp/test /src/"+filename+" && /tmp/test")
+		c.keepBinary("ubsan", c.cxx(c.stdFlag()+" -fsanitize=undefined -fno-omit-frame-pointer -g -o /tmp/test /src/"+filename))+" && "+c.programCommand("", "/tmp/test"))
 	results = append(results, result)
 	if !result.Success {
 		return results, nil
@@ -514,10 +986,10 @@ func (c *ContainerRuntime) ValidateCodeWithProgress(ctx context.Context, code st
 	// issues. This simpler approach catches the most common uninitialized memory bugs.
 	result = runStage("msan",
 		"sh", "-c",
-		"clang++ -std=c++17 -fsanitize=memory -fsanitize-memory-track-origins "+
+		c.keepBinary("msan", c.cxx(c.stdFlag()+" -fsanitize=memory -fsanitize-memory-track-origins "+
 			"-fno-omit-frame-pointer -g -O1 "+
-			"-o /tmp/test /src/"+filename+" 2>&1 && "+
-			"MSAN_OPTIONS=halt_on_error=1 /tmp/test 2>&1")
+			"-o /tmp/test /src/"+filename)+" 2>&1")+" && "+
+			c.programCommand("MSAN_OPTIONS=halt_on_error=1 ", "/tmp/test")+" 2>&1")
 	results = append(results, result)
 	if !result.Success {
 		return results, nil
@@ -525,26 +997,98 @@ func (c *ContainerRuntime) ValidateCodeWithProgress(ctx context.Context, code st
 
 	// Stage 9: Check if code uses threads, run TSAN if so
 	if codeUsesThreads(code) {
+		if result, ok := c.deadlockGate([]CodeFile{{Filename: filename, Content: code}}, "/src/"+filename, runStage, progress); ok {
+			results = append(results, result)
+			if !result.Success {
+				return results, nil
+			}
+		}
 		result = runStage("tsan",
 			"sh", "-c",
-			"clang++ -std=c++17 -fsanitize=thread -fno-omit-frame-pointer -g -o /tmp/test /src/"+filename+" && /tmp/test")
+			c.keepBinary("tsan", c.cxx(c.stdFlag()+" -fsanitize=thread -fno-omit-frame-pointer -g -o /tmp/test /src/"+filename))+" && "+c.programCommand("", "/tmp/test"))
 		results = append(results, result)
 		if !result.Success {
 			return results, nil
 		}
+		result, ok, err := c.stressGate(tmpDir, "/src/"+filename, runStage)
+		if err != nil {
+			return results, err
+		}
+		if ok {
+			results = append(results, result)
+			if !result.Success {
+				return results, nil
+			}
+		}
+		if result, ok := c.valgrindGate(codeUsesPthreads(code), "/src/"+filename, runStage); ok {
+			results = append(results, result)
+			if !result.Success {
+				return results, nil
+			}
+		}
+		if result, ok, err := c.modelCheckGate(tmpDir, []CodeFile{{Filename: filename, Content: code}}, runStage); err != nil {
+			return results, err
+		} else if ok {
+			results = append(results, result)
+			if !result.Success {
+				return results, nil
+			}
+		}
 	}
 
 	// Stage 9: Final run (clean execution)
 	result = runStage("run",
 		"sh", "-c",
-		"clang++ -std=c++17 -O2 -o /tmp/test /src/"+filename+" && /tmp/test")
+		c.keepBinary("run", c.cxx(c.stdFlag()+" -O2 -g -o /tmp/test /src/"+filename))+" && "+c.runCommand("/tmp/test"))
 	results = append(results, result)
+	if !result.Success {
+		return results, nil
+	}
+	if result, ok := c.filesGate(tmpDir, result, progress); ok {
+		results = append(results, result)
+	}
 
 	return results, nil
 }
 
+// withRunInput returns a copy of the runtime whose program stages run with the DoD's stdin and arguments
+func (c *ContainerRuntime) withRunInput(dod *DefinitionOfDone) *ContainerRuntime {
+	if dod == nil || dod.Input.empty() {
+		return c
+	}
+	withInput := *c
+	withInput.input = dod.Input
+	return &withInput
+}
+
+// outputGate checks the run stage's stdout against the DoD's expected output
+// It reports false when there is no expectation to check
+func (c *ContainerRuntime) outputGate(dod *DefinitionOfDone, run ValidationResult, progress ProgressCallback) (ValidationResult, bool) {
+	if dod == nil || dod.ExpectedOutput == nil {
+		return ValidationResult{}, false
+	}
+	if !c.gates.Enabled("output") {
+		return ValidationResult{Stage: "output", Success: true, Skipped: true}, true
+	}
+	if progress != nil {
+		progress("output", true, nil)
+	}
+	result := checkExpectedOutput(dod.ExpectedOutput, run)
+	if progress != nil {
+		progress("output", false, &result)
+	}
+	return result, true
+}
+
 // runValidationStage runs a single validation stage in the container
 func (c *ContainerRuntime) runValidationStage(ctx context.Context, tmpDir, stage string, command ...string) ValidationResult {
+	if !c.gates.Enabled(stage) {
+		return ValidationResult{Stage: stage, Success: true, Skipped: true}
+	}
+	if stage == "msan" && c.qt != nil {
+		// Qt's libraries are not built with MemorySanitizer, so every read of their memory would be reported
+		return ValidationResult{Stage: stage, Success: true, Skipped: true, Output: "skipped: Qt is not built with MemorySanitizer"}
+	}
 	start := time.Now()
 
 	// Convert Windows path to forward slashes for Podman/Docker
@@ -556,28 +1100,49 @@ func (c *ContainerRuntime) runValidationStage(ctx context.Context, tmpDir, stage
 	// Security is maintained via --network none and read-only source mount
 	// seccomp=unconfined is required for TSAN to work (needs ptrace/ASLR control)
 	args := []string{
-		"run", "--rm",
+		"--rm",
 		"--network", "none", // No network access
 		"--security-opt", "seccomp=unconfined", // Required for TSAN
 		"-v", mountPath + ":/src:ro", // Mount code read-only
-		"--timeout", "120", // 2 minute timeout
-		c.imageName,
+		"--timeout", strconv.Itoa(c.stageTimeout(stage)),
+	}
+	if c.deps != nil {
+		args = append(args, "-v", filepath.ToSlash(c.deps.HostDir)+":/deps:ro") // Resolved libraries
+	}
+	if c.artifacts != nil {
+		args = append(args, "-v", filepath.ToSlash(c.artifacts.dir)+":/artifacts") // Binaries kept for export
+	}
+	if c.qt != nil {
+		mount := filepath.ToSlash(c.qt.HostDir) + ":/qt:ro" // Generated moc/uic code
+		if c.qt.generating {
+			mount = filepath.ToSlash(c.qt.HostDir) + ":/qt"
+		}
+		args = append(args, "-v", mount, "-e", "QT_QPA_PLATFORM=offscreen") // No display in the container
+	}
+	if c.hang.Timeout > 0 && c.hang.Backtrace {
+		args = append(args, "--cap-add", "SYS_PTRACE") // Lets gdb attach to a hung program
+	}
+	if stage == c2cStage {
+		args = append(args, "--cap-add", "PERFMON") // Lets perf c2c sample memory accesses
+	}
+	fixtureArgs, err := c.fixtureMount(tmpDir, stage)
+	if err != nil {
+		return ValidationResult{Stage: stage, Error: err.Error()}
 	}
+	args = append(args, fixtureArgs...)
+	args = append(args, c.devices...)
+	args = append(args, c.imageName)
 	args = append(args, command...)
 
-	cmd := exec.CommandContext(ctx, c.binary, args...)
-
 	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	err := cmd.Run()
+	err = c.runContainer(ctx, &stdout, &stderr, args...)
 	duration := time.Since(start)
 
 	result := ValidationResult{
 		Stage:    stage,
 		Duration: duration,
 		Output:   stdout.String(),
+		Command:  command,
 	}
 
 	if err != nil {
@@ -589,8 +1154,89 @@ func (c *ContainerRuntime) runValidationStage(ctx context.Context, tmpDir, stage
 	} else {
 		result.Success = true
 	}
+	// The program needs its own network namespace and the container could not create one
+	if reason := c.netnsFailure(result.Error+"\n"+result.Output, err); reason != "" {
+		containerLog.Warn("network namespace unavailable", "stage", stage)
+		result.Error = reason
+		return result
+	}
+	// The watchdog killed the program; MSan's stage sends stderr to stdout
+	if hang := parseHang(result.Error + "\n" + result.Output); hang != nil && !result.Success {
+		containerLog.Info("program hung", "stage", stage, "timeout", hang.Timeout, "at", hang.Location())
+		result.Error = hang.Describe()
+	} else if crash := parseCrash(result.Error); crash != nil && !result.Success {
+		containerLog.Info("program crashed", "stage", stage, "signal", crash.signalName(), "at", crash.Location())
+		result.Error = crash.Describe()
+	}
 
-	return result
+	switch gateName(stage) {
+	case "clang-tidy":
+		result = c.applyTidyPolicy(tmpDir, result)
+	case "ast-rules":
+		result = c.applyASTRules(result)
+	}
+	return c.applySuppressions(tmpDir, result)
+}
+
+// containerKillTimeout bounds stopping the container of a cancelled stage
+const containerKillTimeout = 10 * time.Second
+
+// runningContainers maps the names of containers started by runContainer and not yet finished
+// to their runtime binary, so an exiting bjarne can stop them
+var runningContainers sync.Map
+
+// runContainer runs `<runtime> run --name <unique name> args...`
+// Cancelling ctx kills the container itself, not only the local client, which would leave the
+// stage running inside the container until its timeout
+func (c *ContainerRuntime) runContainer(ctx context.Context, stdout, stderr io.Writer, args ...string) error {
+	name := newContainerName()
+	cmd := exec.CommandContext(ctx, c.binary, append([]string{"run", "--name", name}, args...)...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.Cancel = func() error {
+		containerLog.Info("cancelled, killing container", "name", name)
+		killContainer(c.binary, name)
+		return cmd.Process.Kill()
+	}
+	cmd.WaitDelay = containerKillTimeout
+
+	runningContainers.Store(name, c.binary)
+	defer runningContainers.Delete(name)
+	containerLog.Debug("run", "name", name, "args", args)
+	start := time.Now()
+	err := cmd.Run()
+	containerLog.Debug("exited", "name", name, "duration", time.Since(start).Round(time.Millisecond), "err", err)
+	return err
+}
+
+// killContainer stops a container by name; it may not have started or may already be gone
+func killContainer(binary, name string) {
+	ctx, cancel := context.WithTimeout(context.Background(), containerKillTimeout)
+	defer cancel()
+	if out, err := exec.CommandContext(ctx, binary, "kill", name).CombinedOutput(); err != nil {
+		containerLog.Debug("kill failed", "name", name, "err", err, "output", strings.TrimSpace(string(out)))
+	}
+}
+
+// stopRunningContainers kills the containers of stages still running, e.g. when quitting mid-validation
+func stopRunningContainers() {
+	var wg sync.WaitGroup
+	runningContainers.Range(func(name, binary any) bool {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			killContainer(binary.(string), name.(string))
+		}()
+		return true
+	})
+	wg.Wait()
+}
+
+// newContainerName returns a unique name such as bjarne-3f9a0c21d4e5, so a stage's container can be killed
+func newContainerName() string {
+	suffix := make([]byte, 6)
+	_, _ = rand.Read(suffix)
+	return "bjarne-" + hex.EncodeToString(suffix)
 }
 
 // detectBenchmarkFunction tries to find a function to benchmark in the code
@@ -663,20 +1309,24 @@ func codeUsesThreads(code string) bool {
 	return false
 }
 
-// FormatResults formats validation results for display
-func FormatResults(results []ValidationResult) string {
+// FormatResults formats validation results for display, with diagnostics pointing into the workspace
+func FormatResults(results []ValidationResult, paths PathMap) string {
 	var sb strings.Builder
 
 	allPassed := true
 	for _, r := range results {
-		if r.Success {
+		if r.Skipped {
+			sb.WriteString(fmt.Sprintf("SKIP %s\n", r.Stage))
+		} else if r.Success && r.Suppressed > 0 {
+			sb.WriteString(fmt.Sprintf("PASS %s (%.2fs, %d suppressed)\n", r.Stage, r.Duration.Seconds(), r.Suppressed))
+		} else if r.Success {
 			sb.WriteString(fmt.Sprintf("PASS %s (%.2fs)\n", r.Stage, r.Duration.Seconds()))
 		} else {
 			allPassed = false
 			sb.WriteString(fmt.Sprintf("FAIL %s (%.2fs)\n", r.Stage, r.Duration.Seconds()))
 			if r.Error != "" {
 				// Parse and format diagnostics based on stage type
-				formatted := formatStageError(r.Stage, r.Error)
+				formatted := formatStageError(r.Stage, r.Error, paths)
 				sb.WriteString(formatted)
 			}
 		}
@@ -690,40 +1340,59 @@ func FormatResults(results []ValidationResult) string {
 }
 
 // formatStageError parses and formats error output based on stage type (for user display)
-func formatStageError(stage, errorOutput string) string {
-	switch stage {
+func formatStageError(stage, errorOutput string, paths PathMap) string {
+	switch gateName(stage) {
 	case "clang-tidy":
 		diags := ParseClangTidyOutput(errorOutput)
 		if len(diags) > 0 {
-			return FormatDiagnostics(diags)
+			formatted := FormatDiagnostics(paths.Rewrite(diags))
+			if i := strings.LastIndex(errorOutput, tidyPolicyPrefix); i >= 0 {
+				formatted += "  " + strings.TrimSpace(errorOutput[i:]) + "\n"
+			}
+			return formatted
 		}
 	case "cppcheck":
 		diags := ParseCppcheckOutput(errorOutput)
 		if len(diags) > 0 {
-			return FormatDiagnostics(diags)
+			return FormatDiagnostics(paths.Rewrite(diags))
+		}
+	case "ast-rules":
+		diags := ParseClangTidyOutput(errorOutput)
+		if len(diags) > 0 {
+			return FormatDiagnostics(paths.Rewrite(diags))
 		}
 	case "complexity":
 		// Lizard output is already human-readable, just indent it
 		// No special parsing needed
-	case "asan":
+	case "asan", "exceptions":
 		diags := ParseSanitizerOutput(errorOutput, "asan")
 		if len(diags) > 0 {
-			return FormatDiagnostics(diags)
+			return FormatDiagnostics(paths.Rewrite(diags))
 		}
 	case "ubsan":
 		diags := ParseSanitizerOutput(errorOutput, "ubsan")
 		if len(diags) > 0 {
-			return FormatDiagnostics(diags)
+			return FormatDiagnostics(paths.Rewrite(diags))
 		}
 	case "msan":
 		diags := ParseSanitizerOutput(errorOutput, "msan")
 		if len(diags) > 0 {
-			return FormatDiagnostics(diags)
+			return FormatDiagnostics(paths.Rewrite(diags))
 		}
-	case "tsan":
+	case "tsan", "stress":
 		diags := ParseSanitizerOutput(errorOutput, "tsan")
 		if len(diags) > 0 {
-			return FormatDiagnostics(diags)
+			return FormatDiagnostics(paths.Rewrite(diags))
+		}
+	case "deadlock":
+		diags := append(ParseClangTidyOutput(errorOutput), ParseSanitizerOutput(errorOutput, "tsan")...)
+		if len(diags) > 0 {
+			return FormatDiagnostics(paths.Rewrite(diags))
+		}
+	case ValgrindToolHelgrind, ValgrindToolDRD:
+		diags := ParseValgrindOutput(errorOutput, gateName(stage))
+		if len(diags) > 0 {
+			return FormatDiagnostics(paths.Rewrite(diags))
 		}
 	}
 
@@ -731,36 +1400,81 @@ func formatStageError(stage, errorOutput string) string {
 	var sb strings.Builder
 	lines := strings.Split(strings.TrimSpace(errorOutput), "\n")
 	for _, line := range lines {
-		sb.WriteString(fmt.Sprintf("  %s\n", line))
+		sb.WriteString(fmt.Sprintf("  %s\n", paths.RewriteText(line)))
 	}
 	return sb.String()
 }
 
 // FormatErrorForLLM formats a validation error in a compact format for LLM processing
-// Returns a clean, minimal representation without ANSI colors
-func FormatErrorForLLM(stage, errorOutput string) string {
+// Returns a clean, minimal representation without ANSI colors, followed by the numbered
+// lines of files that the diagnostics point at
+func FormatErrorForLLM(stage, errorOutput string, files []CodeFile) string {
+	if hang := parseHang(errorOutput); hang != nil {
+		formatted := fmt.Sprintf("[%s] %s", stage, hang.promptText())
+		if snippets := sourceSnippets(stackDiagnostics(hang.Frames, "program hangs here"), files); snippets != "" {
+			formatted += "\nSource:\n" + snippets
+		}
+		return formatted
+	}
+	if crash := parseCrash(errorOutput); crash != nil {
+		formatted := fmt.Sprintf("[%s] %s", stage, crash.promptText())
+		if snippets := sourceSnippets(stackDiagnostics(crash.Frames, "program crashes here"), files); snippets != "" {
+			formatted += "\nSource:\n" + snippets
+		}
+		return formatted
+	}
+
+	prefix := fmt.Sprintf("[%s] ", stage)
+	if f := parseStressFailure(errorOutput); f != nil {
+		prefix += f.promptText() + "\n"
+	}
+	if f := parseExceptionFailure(errorOutput); f != nil {
+		prefix += f.promptText() + "\n"
+	}
+	if f := parseModelCheckFailure(errorOutput); f != nil {
+		prefix += f.promptText() + "\n"
+	}
+
 	var diags []Diagnostic
 
-	switch stage {
+	switch gateName(stage) {
 	case "clang-tidy":
 		diags = ParseClangTidyOutput(errorOutput)
 	case "cppcheck":
 		diags = ParseCppcheckOutput(errorOutput)
-	case "asan":
+	case "asan", "exceptions":
 		diags = ParseSanitizerOutput(errorOutput, "asan")
 	case "ubsan":
 		diags = ParseSanitizerOutput(errorOutput, "ubsan")
 	case "msan":
 		diags = ParseSanitizerOutput(errorOutput, "msan")
-	case "tsan":
+	case "tsan", "stress":
 		diags = ParseSanitizerOutput(errorOutput, "tsan")
+	case "deadlock":
+		diags = append(ParseClangTidyOutput(errorOutput), ParseSanitizerOutput(errorOutput, "tsan")...)
+		prefix += deadlockHint + "\n"
+	case ValgrindToolHelgrind, ValgrindToolDRD:
+		diags = ParseValgrindOutput(errorOutput, gateName(stage))
 	case "compile":
 		// Compiler errors follow similar pattern to clang-tidy
 		diags = ParseClangTidyOutput(errorOutput)
+	case "constexpr":
+		diags = compilerDiagnosticsWithNotes(errorOutput)
+		prefix += constexprHint + "\n"
+	case "templates":
+		diags = compilerDiagnosticsWithNotes(errorOutput)
+		prefix += templatesHint + "\n"
+	case "ast-rules":
+		diags = ParseClangTidyOutput(errorOutput)
+		prefix += astRulesHint + "\n"
 	}
 
 	if len(diags) > 0 {
-		return fmt.Sprintf("[%s] %s", stage, FormatDiagnosticsForLLM(diags))
+		formatted := prefix + FormatDiagnosticsForLLM(diags)
+		if snippets := sourceSnippets(diags, files); snippets != "" {
+			formatted += "Source:\n" + snippets
+		}
+		return formatted
 	}
 
 	// Fallback: use raw output but with stage prefix
@@ -770,5 +1484,5 @@ func FormatErrorForLLM(stage, errorOutput string) string {
 		lines = lines[:50]
 		lines = append(lines, "... (truncated, showing first 50 lines)")
 	}
-	return fmt.Sprintf("[%s] %s", stage, strings.Join(lines, "\n"))
+	return prefix + strings.Join(lines, "\n")
 }
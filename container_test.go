@@ -1,7 +1,14 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestCodeUsesThreads(t *testing.T) {
@@ -74,7 +81,7 @@ func TestFormatResults(t *testing.T) {
 		{Stage: "asan", Success: false, Error: "memory error", Duration: 300000000},
 	}
 
-	output := FormatResults(results)
+	output := FormatResults(results, PathMap{})
 
 	// Check that output contains expected strings
 	if !contains(output, "PASS clang-tidy") {
@@ -100,7 +107,7 @@ func TestFormatResultsAllPassed(t *testing.T) {
 		{Stage: "compile", Success: true, Duration: 200000000},
 	}
 
-	output := FormatResults(results)
+	output := FormatResults(results, PathMap{})
 
 	if !contains(output, "All validation stages passed") {
 		t.Error("FormatResults should say all passed when all succeeded")
@@ -177,3 +184,101 @@ func TestDetectBenchmarkFunction(t *testing.T) {
 		})
 	}
 }
+
+func TestParseStandard(t *testing.T) {
+	tests := []struct {
+		lang    string
+		want    string
+		wantErr bool
+	}{
+		{lang: "c++20", want: "c++20"},
+		{lang: "C++23", want: "c++23"},
+		{lang: "cpp14", want: "c++14"},
+		{lang: "17", want: "c++17"},
+		{lang: "gnu++20", want: "gnu++20"},
+		{lang: "c++98", wantErr: true},
+		{lang: "c11", wantErr: true},
+		{lang: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.lang, func(t *testing.T) {
+			got, err := ParseStandard(tt.lang)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseStandard(%q) error = %v, wantErr %v", tt.lang, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseStandard(%q) = %q, want %q", tt.lang, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStandardFlags(t *testing.T) {
+	tests := []struct {
+		standard     string
+		wantFlag     string
+		wantCppcheck string
+	}{
+		{standard: "", wantFlag: "-std=c++17", wantCppcheck: "c++17"},
+		{standard: "c++14", wantFlag: "-std=c++14", wantCppcheck: "c++14"},
+		{standard: "gnu++20", wantFlag: "-std=gnu++20", wantCppcheck: "c++20"},
+		{standard: "c++23", wantFlag: "-std=c++23", wantCppcheck: "c++20"},
+	}
+
+	for _, tt := range tests {
+		c := &ContainerRuntime{}
+		c.SetStandard(tt.standard)
+		if got := c.stdFlag(); got != tt.wantFlag {
+			t.Errorf("stdFlag() with %q = %q, want %q", tt.standard, got, tt.wantFlag)
+		}
+		if got := c.cppcheckStandard(); got != tt.wantCppcheck {
+			t.Errorf("cppcheckStandard() with %q = %q, want %q", tt.standard, got, tt.wantCppcheck)
+		}
+	}
+}
+
+func TestRunContainerKillsOnCancel(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the container runtime")
+	}
+	dir := t.TempDir()
+	log := filepath.Join(dir, "calls")
+	fake := filepath.Join(dir, "podman")
+	script := "#!/bin/sh\necho \"$@\" >> " + log + "\n[ \"$1\" = kill ] && exit 0\nexec sleep 5\n"
+	if err := os.WriteFile(fake, []byte(script), 0700); err != nil { //nolint:gosec // test runtime
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+	c := &ContainerRuntime{binary: fake}
+	var out bytes.Buffer
+	start := time.Now()
+	if err := c.runContainer(ctx, &out, &out, "--rm", "image", "true"); err == nil {
+		t.Error("runContainer() = nil, want the cancellation")
+	}
+	if took := time.Since(start); took > 4*time.Second {
+		t.Errorf("runContainer() took %s, want it stopped on cancel", took)
+	}
+
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(calls) != 2 {
+		t.Fatalf("calls = %q, want run then kill", calls)
+	}
+	run := strings.Fields(calls[0])
+	if len(run) < 3 || run[0] != "run" || run[1] != "--name" || !strings.HasPrefix(run[2], "bjarne-") {
+		t.Fatalf("run call = %q, want a named container", calls[0])
+	}
+	if calls[1] != "kill "+run[2] {
+		t.Errorf("kill call = %q, want kill %s", calls[1], run[2])
+	}
+	runningContainers.Range(func(name, _ any) bool {
+		t.Errorf("container %v still tracked after it stopped", name)
+		return true
+	})
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// defaultContextWindow is assumed for models not in knownContextWindows
+const defaultContextWindow = 128000
+
+// defaultLocalContextWindow is assumed for local models (servers often run with a small context)
+const defaultLocalContextWindow = 8192
+
+// imageTokenEstimate approximates the input tokens of one attached image
+const imageTokenEstimate = 1600
+
+// knownContextWindows are input windows by model ID substring; the first match wins
+var knownContextWindows = []struct {
+	match  string
+	tokens int
+}{
+	{"claude", 200000},
+	{"gpt-5", 272000},
+	{"gpt-4.1", 1047576},
+	{"gpt-4o", 128000},
+	{"o1", 200000},
+	{"o3", 200000},
+	{"o4", 200000},
+	{"gemini", 1048576},
+}
+
+// contextWindowFor returns a model's input window: a settings override by canonical name or ID,
+// then the built-in table
+func contextWindowFor(overrides map[string]int, canonical, id string) int {
+	if n := overrides[canonical]; n > 0 {
+		return n
+	}
+	if n := overrides[id]; n > 0 {
+		return n
+	}
+	lower := strings.ToLower(id)
+	for _, w := range knownContextWindows {
+		if strings.Contains(lower, w.match) {
+			return w.tokens
+		}
+	}
+	return defaultContextWindow
+}
+
+// estimateTokens approximates the tokens in text with the local tokenizer
+func estimateTokens(text string) int {
+	return CountTokens(text)
+}
+
+// estimateMessageTokens approximates one message including role overhead and images
+func estimateMessageTokens(msg Message) int {
+	return estimateTokens(msg.Content) + 4 + len(msg.Images)*imageTokenEstimate
+}
+
+// estimateRequestTokens approximates the input tokens of a request
+func estimateRequestTokens(systemPrompt string, messages []Message) int {
+	total := estimateTokens(systemPrompt)
+	for _, msg := range messages {
+		total += estimateMessageTokens(msg)
+	}
+	return total
+}
+
+// ContextOverflowError reports a request that cannot fit the model's context window
+type ContextOverflowError struct {
+	Model string
+	Need  int // Estimated input tokens after trimming
+	Limit int // Input tokens available (window minus max output)
+}
+
+func (e *ContextOverflowError) Error() string {
+	return fmt.Sprintf("request needs ~%d input tokens but %s has room for %d; use /compact or /clear, or attach less context",
+		e.Need, e.Model, e.Limit)
+}
+
+// fitContext drops the oldest exchanges after the first message until the request fits limit
+// Returns the messages to send and how many were dropped; the caller's slice is not modified
+func fitContext(systemPrompt string, messages []Message, limit int) ([]Message, int, bool) {
+	need := estimateRequestTokens(systemPrompt, messages)
+	dropped := 0
+	for need > limit && len(messages) > 3 {
+		// Dropping an assistant/user pair keeps the roles alternating
+		need -= estimateMessageTokens(messages[1]) + estimateMessageTokens(messages[2])
+		messages = append([]Message{messages[0]}, messages[3:]...)
+		dropped += 2
+	}
+	return messages, dropped, need <= limit
+}
+
+// contextLimitedProvider trims each request to the model's context window before sending it
+type contextLimitedProvider struct {
+	LLMProvider
+	windows map[string]int // Overrides by canonical name or model ID
+}
+
+// withContextLimits wraps a provider with the windows configured in settings
+func withContextLimits(p LLMProvider, cfg *ProviderConfig) LLMProvider {
+	windows := make(map[string]int, len(cfg.Models.ContextWindows)+1)
+	if cfg.Provider == ProviderLocal {
+		local := cfg.Local.ContextWindow
+		if local <= 0 {
+			local = defaultLocalContextWindow
+		}
+		windows[p.MapModel(p.DefaultModel())] = local
+	}
+	for model, n := range cfg.Models.ContextWindows {
+		windows[model] = n
+	}
+	return &contextLimitedProvider{LLMProvider: p, windows: windows}
+}
+
+// Unwrap returns the wrapped provider
+func (c *contextLimitedProvider) Unwrap() LLMProvider {
+	return c.LLMProvider
+}
+
+// InputLimit returns the input tokens available to a request with maxTokens of output
+func (c *contextLimitedProvider) InputLimit(model string, maxTokens int) int {
+	if model == "" {
+		model = c.DefaultModel()
+	}
+	limit := contextWindowFor(c.windows, model, c.MapModel(model)) - maxTokens
+	if limit < 1024 {
+		limit = 1024
+	}
+	return limit
+}
+
+// fit trims messages to the model's window or reports why it cannot
+func (c *contextLimitedProvider) fit(model, systemPrompt string, messages []Message, maxTokens int) ([]Message, int, error) {
+	limit := c.InputLimit(model, maxTokens)
+	fitted, dropped, ok := fitContext(systemPrompt, messages, limit)
+	if !ok {
+		if model == "" {
+			model = c.DefaultModel()
+		}
+		return nil, dropped, &ContextOverflowError{
+			Model: c.MapModel(model),
+			Need:  estimateRequestTokens(systemPrompt, fitted),
+			Limit: limit,
+		}
+	}
+	return fitted, dropped, nil
+}
+
+// Generate trims the conversation to fit before calling the wrapped provider
+func (c *contextLimitedProvider) Generate(ctx context.Context, model, systemPrompt string, messages []Message, maxTokens int) (*GenerateResult, error) {
+	fitted, dropped, err := c.fit(model, systemPrompt, messages, maxTokens)
+	if err != nil {
+		return nil, err
+	}
+	result, err := c.LLMProvider.Generate(ctx, model, systemPrompt, fitted, maxTokens)
+	if result != nil {
+		result.Dropped = dropped
+	}
+	return result, err
+}
+
+// GenerateStreaming trims the conversation to fit before calling the wrapped provider
+func (c *contextLimitedProvider) GenerateStreaming(ctx context.Context, model, systemPrompt string, messages []Message, maxTokens int, callback StreamCallback) (*GenerateResult, error) {
+	fitted, dropped, err := c.fit(model, systemPrompt, messages, maxTokens)
+	if err != nil {
+		return nil, err
+	}
+	result, err := c.LLMProvider.GenerateStreaming(ctx, model, systemPrompt, fitted, maxTokens, callback)
+	if result != nil {
+		result.Dropped = dropped
+	}
+	return result, err
+}
+
+// providerInputLimit returns the input tokens available for a request, or 0 if unknown
+func providerInputLimit(p LLMProvider, model string, maxTokens int) int {
+	for p != nil {
+		if c, ok := p.(*contextLimitedProvider); ok {
+			return c.InputLimit(model, maxTokens)
+		}
+		u, ok := p.(interface{ Unwrap() LLMProvider })
+		if !ok {
+			return 0
+		}
+		p = u.Unwrap()
+	}
+	return 0
+}
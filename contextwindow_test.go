@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestContextWindowFor(t *testing.T) {
+	overrides := map[string]int{"sonnet": 50000, "my-finetune": 32000}
+
+	tests := []struct {
+		name      string
+		canonical string
+		id        string
+		want      int
+	}{
+		{"canonical override", "sonnet", AnthropicModelMap[ModelSonnet], 50000},
+		{"id override", "my-finetune", "my-finetune", 32000},
+		{"bedrock claude", "haiku", BedrockModelMap[ModelHaiku], 200000},
+		{"gpt-5", "opus", OpenAIModelMap[ModelOpus], 272000},
+		{"gemini", "opus", GeminiModelMap[ModelOpus], 1048576},
+		{"unknown", "mystery-model", "mystery-model", defaultContextWindow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := contextWindowFor(overrides, tt.canonical, tt.id); got != tt.want {
+				t.Errorf("contextWindowFor(%q, %q) = %d, want %d", tt.canonical, tt.id, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFitContext(t *testing.T) {
+	big := strings.Repeat("token ", 1000) // ~1000 tokens
+	conversation := []Message{
+		{Role: "user", Content: "original request " + big},
+		{Role: "assistant", Content: big},
+		{Role: "user", Content: "fix 1 " + big},
+		{Role: "assistant", Content: big},
+		{Role: "user", Content: "fix 2"},
+	}
+
+	t.Run("fits unchanged", func(t *testing.T) {
+		got, dropped, ok := fitContext("system", conversation, 100000)
+		if !ok || dropped != 0 || len(got) != len(conversation) {
+			t.Errorf("fitContext() = %d messages, dropped %d, ok %v", len(got), dropped, ok)
+		}
+	})
+
+	t.Run("drops oldest exchanges after the request", func(t *testing.T) {
+		got, dropped, ok := fitContext("system", conversation, 2500)
+		if !ok || dropped != 2 {
+			t.Fatalf("fitContext() dropped %d, ok %v", dropped, ok)
+		}
+		if !strings.HasPrefix(got[0].Content, "original request") || got[1].Role != "assistant" || got[len(got)-1].Content != "fix 2" {
+			t.Errorf("fitContext() kept %+v", got)
+		}
+		if len(conversation) != 5 || conversation[1].Content != big {
+			t.Error("fitContext() modified the caller's conversation")
+		}
+	})
+
+	t.Run("cannot fit", func(t *testing.T) {
+		if _, _, ok := fitContext("system", conversation, 500); ok {
+			t.Error("fitContext() reported a fit for an oversized request")
+		}
+	})
+}
+
+func TestContextLimitedProvider(t *testing.T) {
+	cfg := &ProviderConfig{Provider: ProviderLocal, Local: LocalSettings{ContextWindow: 3000}}
+	p := withContextLimits(stubProvider{text: "ok"}, cfg).(*contextLimitedProvider)
+
+	if got := p.InputLimit("", 1000); got != 2000 {
+		t.Errorf("InputLimit() = %d, want 2000", got)
+	}
+	if got := providerInputLimit(withAudit(stubProvider{}, nil), "", 1000); got != 0 {
+		t.Errorf("providerInputLimit(unwrapped) = %d, want 0", got)
+	}
+
+	messages := []Message{
+		{Role: "user", Content: "request"},
+		{Role: "assistant", Content: strings.Repeat("token ", 2000)},
+		{Role: "user", Content: "fix"},
+	}
+	result, err := p.Generate(context.Background(), "", "system", append(messages, Message{Role: "assistant", Content: "a"}, Message{Role: "user", Content: "b"}), 1000)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if result.Dropped != 2 {
+		t.Errorf("Dropped = %d, want 2", result.Dropped)
+	}
+
+	_, err = p.Generate(context.Background(), "", "system", []Message{{Role: "user", Content: strings.Repeat("token ", 5000)}}, 1000)
+	var overflow *ContextOverflowError
+	if !errors.As(err, &overflow) || overflow.Limit != 2000 {
+		t.Errorf("Generate() error = %v, want ContextOverflowError with limit 2000", err)
+	}
+}
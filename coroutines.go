@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// coroutineStandard is the first standard with coroutines; clang needs no -fcoroutines flag from it on
+const coroutineStandard = "c++20"
+
+// coroutineASANOptions makes ASAN catch a frame used after its coroutine returned
+const coroutineASANOptions = "ASAN_OPTIONS=detect_stack_use_after_return=1 "
+
+// coroutineKeywordPattern matches the keywords that make a function a coroutine
+var coroutineKeywordPattern = regexp.MustCompile(`\bco_(?:await|yield|return)\b`)
+
+// coroutineSuspendPattern matches the keywords that can suspend a coroutine
+var coroutineSuspendPattern = regexp.MustCompile(`\bco_(?:await|yield)\b`)
+
+// coroutineLambdaPattern matches a lambda with captures up to its body's brace, capturing the capture list
+// The character before '[' rules out subscripts, and [[attributes]] never match
+var coroutineLambdaPattern = regexp.MustCompile(`(?:^|[^\w\])\s])\s*\[([^\[\]]+)\]\s*(?:\([^()]*\))?\s*(?:mutable\s*)?(?:->\s*[^{;]+)?\{`)
+
+// coroutineFunctionPattern matches a function definition up to its body's brace, capturing its name and parameters
+var coroutineFunctionPattern = regexp.MustCompile(`(?m)^[ \t]*(?:[\w:<>,*&]+[ \t]+)+(\w+)[ \t]*\(([^()]*)\)[ \t]*(?:const[ \t]*)?(?:noexcept[ \t]*)?\{`)
+
+// controlKeywords start statements that look like function definitions to coroutineFunctionPattern
+var controlKeywords = []string{"if", "for", "while", "switch", "catch"}
+
+// coroutineLockPattern matches a lock held for the rest of its scope
+var coroutineLockPattern = regexp.MustCompile(`\bstd::(?:lock_guard|unique_lock|scoped_lock)\b`)
+
+// coroutineBorrowedParamPattern matches a parameter that refers to the caller's object instead of copying it
+var coroutineBorrowedParamPattern = regexp.MustCompile(`&|\b(?:std::)?(?:string_view|span)\b`)
+
+// usesCoroutines reports whether any file defines a coroutine
+func usesCoroutines(files []CodeFile) bool {
+	for _, f := range files {
+		if coroutineKeywordPattern.MatchString(f.Content) {
+			return true
+		}
+	}
+	return false
+}
+
+// withCoroutines returns a runtime for coroutine code: built as C++20 at least, with ASAN
+// checking for stack use after return
+func (c *ContainerRuntime) withCoroutines(files []CodeFile) *ContainerRuntime {
+	if c.coroutines || !usesCoroutines(files) {
+		return c
+	}
+	withCoroutines := *c
+	withCoroutines.coroutines = true
+	current := strings.TrimPrefix(c.stdFlag(), "-std=")
+	for _, std := range supportedStandards {
+		if std == current {
+			withCoroutines.standard = coroutineStandard // Older than C++20: coroutines do not compile
+			break
+		}
+		if std == coroutineStandard {
+			break
+		}
+	}
+	return &withCoroutines
+}
+
+// asanOptions returns the environment the ASAN stage runs the program with
+func (c *ContainerRuntime) asanOptions() string {
+	if c.coroutines {
+		return coroutineASANOptions
+	}
+	return ""
+}
+
+// coroutineFindings reports patterns that leave a coroutine frame referring to objects that are
+// gone when it resumes: lambda coroutines with captures, coroutine parameters taken by reference
+// or as views, and locks held across a suspension
+func coroutineFindings(code, filename string) []string {
+	var findings []string
+	report := func(offset int, check, message string) {
+		line := strings.Count(code[:offset], "\n") + 1
+		findings = append(findings, fmt.Sprintf("/src/%s:%d:1: %s: %s [%s]", filename, line, LevelWarning, message, check))
+	}
+
+	for _, m := range coroutineLambdaPattern.FindAllStringSubmatchIndex(code, -1) {
+		body := code[m[1]-1 : blockEnd(code, m[1]-1)]
+		if coroutineKeywordPattern.MatchString(body) {
+			report(m[2], "coroutine-lambda-capture",
+				fmt.Sprintf("lambda coroutine captures [%s]; the captures live in the lambda, not the coroutine frame, and dangle once it suspends", strings.TrimSpace(code[m[2]:m[3]])))
+		}
+	}
+
+	for _, m := range coroutineFunctionPattern.FindAllStringSubmatchIndex(code, -1) {
+		open := m[1] - 1
+		body := code[open:blockEnd(code, open)]
+		if slices.Contains(controlKeywords, code[m[2]:m[3]]) || !coroutineKeywordPattern.MatchString(body) {
+			continue
+		}
+		for _, param := range strings.Split(code[m[4]:m[5]], ",") {
+			if param = strings.TrimSpace(param); coroutineBorrowedParamPattern.MatchString(param) {
+				report(m[4], "coroutine-reference-param",
+					fmt.Sprintf("coroutine parameter '%s' refers to the caller's object, which may be destroyed before the coroutine resumes; take it by value", param))
+			}
+		}
+		if lock := coroutineLockPattern.FindStringIndex(body); lock != nil {
+			if suspend := coroutineSuspendPattern.FindStringIndex(body[lock[1]:]); suspend != nil {
+				report(open+lock[1]+suspend[0], "coroutine-lock-across-suspend",
+					"a lock is held across a suspension; the coroutine may resume on another thread or never, leaving the mutex locked")
+			}
+		}
+	}
+
+	return findings
+}
+
+// blockEnd returns the index just past the brace closing the one at open (or the end of code)
+func blockEnd(code string, open int) int {
+	depth := 0
+	for i := open; i < len(code); i++ {
+		switch code[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(code)
+}
+
+// coroutinesGate checks coroutine code for dangling-frame patterns
+// The findings are advisory: they are reported as warnings and never fail validation
+// It reports false when the code has no coroutines
+func (c *ContainerRuntime) coroutinesGate(files []CodeFile, progress ProgressCallback) (ValidationResult, bool) {
+	if !c.coroutines {
+		return ValidationResult{}, false
+	}
+	if !c.gates.Enabled("coroutines") {
+		return ValidationResult{Stage: "coroutines", Success: true, Skipped: true}, true
+	}
+	if progress != nil {
+		progress("coroutines", true, nil)
+	}
+	var findings []string
+	for _, f := range files {
+		if isSourceFile(f.Filename) || isHeaderFile(f.Filename) {
+			findings = append(findings, coroutineFindings(f.Content, f.Filename)...)
+		}
+	}
+	result := ValidationResult{Stage: "coroutines", Success: true, Output: "No dangling coroutine frame patterns found"}
+	if len(findings) > 0 {
+		result.Output = strings.Join(findings, "\n") + "\n(advisory: reported without failing validation)"
+	}
+	if progress != nil {
+		progress("coroutines", false, &result)
+	}
+	return result, true
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const coroutineTask = `#include <coroutine>
+struct task {
+    struct promise_type {
+        task get_return_object() { return {}; }
+        std::suspend_never initial_suspend() { return {}; }
+        std::suspend_never final_suspend() noexcept { return {}; }
+        void return_void() {}
+        void unhandled_exception() {}
+    };
+};
+`
+
+func TestWithCoroutines(t *testing.T) {
+	plain := []CodeFile{{Filename: "main.cpp", Content: "int main() { return 0; }"}}
+	coro := []CodeFile{{Filename: "main.cpp", Content: coroutineTask + "task run() { co_return; }\nint main() { run(); }"}}
+
+	tests := []struct {
+		name     string
+		standard string
+		files    []CodeFile
+		wantStd  string
+		wantCoro bool
+	}{
+		{"no coroutines", "", plain, "-std=c++17", false},
+		{"default standard raised", "", coro, "-std=c++20", true},
+		{"c++14 raised", "c++14", coro, "-std=c++20", true},
+		{"c++20 kept", "c++20", coro, "-std=c++20", true},
+		{"c++23 kept", "c++23", coro, "-std=c++23", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &ContainerRuntime{standard: tt.standard}
+			got := c.withCoroutines(tt.files)
+			if got.stdFlag() != tt.wantStd || got.coroutines != tt.wantCoro {
+				t.Errorf("withCoroutines() = %s, coroutines %v; want %s, %v", got.stdFlag(), got.coroutines, tt.wantStd, tt.wantCoro)
+			}
+			if c.coroutines {
+				t.Error("withCoroutines() changed the original runtime")
+			}
+			if tt.wantCoro && got.asanOptions() != coroutineASANOptions {
+				t.Errorf("asanOptions() = %q, want %q", got.asanOptions(), coroutineASANOptions)
+			}
+		})
+	}
+}
+
+func TestCoroutineFindings(t *testing.T) {
+	tests := []struct {
+		name  string
+		code  string
+		check string // Empty = no findings
+		line  int
+	}{
+		{
+			name:  "lambda capture",
+			code:  "int main() {\n    int n = 1;\n    auto f = [&n]() -> task { co_return; };\n    f();\n}",
+			check: "coroutine-lambda-capture",
+			line:  3,
+		},
+		{
+			name: "captureless lambda",
+			code: "int main() {\n    auto f = []() -> task { co_return; };\n    f();\n}",
+		},
+		{
+			name:  "reference parameter",
+			code:  "task consume(const std::string& s) {\n    co_await std::suspend_always{};\n    use(s);\n}",
+			check: "coroutine-reference-param",
+			line:  1,
+		},
+		{
+			name:  "string_view parameter",
+			code:  "task consume(int n, std::string_view s) {\n    co_return;\n}",
+			check: "coroutine-reference-param",
+			line:  1,
+		},
+		{
+			name: "value parameter",
+			code: "task consume(std::string s) {\n    co_await std::suspend_always{};\n}",
+		},
+		{
+			name: "reference parameter of a plain function",
+			code: "void consume(const std::string& s) {\n    use(s);\n}",
+		},
+		{
+			name:  "lock across suspend",
+			code:  "task step(int n) {\n    std::lock_guard<std::mutex> lock(m);\n    ++count;\n    co_await std::suspend_always{};\n}",
+			check: "coroutine-lock-across-suspend",
+			line:  4,
+		},
+		{
+			name: "condition with && in a coroutine",
+			code: "task step(int n) {\n    if (n > 0 && n < 10) {\n        co_return;\n    }\n}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := coroutineFindings(tt.code, "main.cpp")
+			if tt.check == "" {
+				if len(findings) != 0 {
+					t.Errorf("coroutineFindings() = %v, want none", findings)
+				}
+				return
+			}
+			if len(findings) != 1 {
+				t.Fatalf("coroutineFindings() = %v, want one %s finding", findings, tt.check)
+			}
+			want := fmt.Sprintf("/src/main.cpp:%d:1: warning: ", tt.line)
+			if !strings.HasPrefix(findings[0], want) || !strings.HasSuffix(findings[0], "["+tt.check+"]") {
+				t.Errorf("coroutineFindings() = %q, want %s... [%s]", findings[0], want, tt.check)
+			}
+		})
+	}
+}
+
+func TestCoroutinesGate(t *testing.T) {
+	files := []CodeFile{{Filename: "main.cpp", Content: coroutineTask + "task run(const int& n) { co_return; }\nint main() { run(1); }"}}
+	if _, ok := (&ContainerRuntime{}).coroutinesGate(files, nil); ok {
+		t.Error("coroutinesGate() ran for a runtime without coroutines")
+	}
+
+	c := (&ContainerRuntime{}).withCoroutines(files)
+	result, ok := c.coroutinesGate(files, nil)
+	if !ok || !result.Success || !strings.Contains(result.Output, "[coroutine-reference-param]") || !strings.Contains(result.Output, "advisory") {
+		t.Errorf("coroutinesGate() = %+v, %v; want an advisory finding that passes", result, ok)
+	}
+
+	c.SetGateSelection(GateSelection{Skip: []string{"coroutines"}})
+	if result, ok := c.coroutinesGate(files, nil); !ok || !result.Skipped {
+		t.Errorf("coroutinesGate(skipped) = %+v, %v; want a skipped result", result, ok)
+	}
+}
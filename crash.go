@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// crashMarker starts the crash report on stderr when a signal kills the program in the run stage
+const crashMarker = "bjarne: crash:"
+
+// crashSignals are the signals reported as crashes, with what they usually mean in C++
+var crashSignals = map[int]struct{ name, meaning string }{
+	4:  {"SIGILL", "illegal instruction, e.g. flowing off the end of a function that returns a value"},
+	6:  {"SIGABRT", "abort, e.g. a failed assert, an uncaught exception or a corrupted heap"},
+	7:  {"SIGBUS", "bus error: a misaligned or out-of-range memory access"},
+	8:  {"SIGFPE", "arithmetic error, e.g. integer division by zero or overflow in division"},
+	11: {"SIGSEGV", "segmentation fault: a read or write through an invalid pointer"},
+}
+
+var crashMarkerPattern = regexp.MustCompile(regexp.QuoteMeta(crashMarker) + ` the program was killed by signal (\d+)`)
+
+// runCommand runs the program in the run stage, which has no sanitizer to explain a crash
+// When a crash signal kills it, gdb prints a backtrace from the core dump, or from a second
+// run under gdb when the host's core_pattern sends cores out of the container
+func (c *ContainerRuntime) runCommand(binary string) string {
+	args, redirect := c.programInput()
+	if redirect == "" {
+		redirect = " < /dev/null"
+	}
+	timeout := c.runTimeout()
+	if timeout <= 0 {
+		timeout = maxHangTimeout
+	}
+	codes := make([]string, 0, len(crashSignals))
+	for sig := range crashSignals {
+		codes = append(codes, strconv.Itoa(128+sig))
+	}
+	sort.Strings(codes)
+
+	fromCore := "gdb -batch -nx -ex bt " + binary + ` "$core"`
+	rerun := fmt.Sprintf("timeout %d gdb -batch -nx -ex 'set disable-randomization off' -ex run -ex bt --args %s%s%s", timeout, binary, args, redirect)
+	return fmt.Sprintf("{ ulimit -c unlimited 2>/dev/null; cd %s && rm -f core core.*; %s; s=$?; "+
+		"case $s in %s) echo \"%s the program was killed by signal $((s-128))\" >&2; "+
+		"if command -v gdb >/dev/null 2>&1; then core=$(ls core core.* 2>/dev/null | head -n 1); "+
+		"if [ -n \"$core\" ]; then %s; else %s; fi 2>/dev/null | grep -E '^#' >&2; fi;; esac; (exit $s); }",
+		c.programDir(), c.programCommand("", binary), strings.Join(codes, "|"), crashMarker, fromCore, rerun)
+}
+
+// Crash is a program killed by a crash signal in the run stage, with where it crashed
+type Crash struct {
+	Signal int
+	Frames []stackFrame // The crashing thread's frames, innermost first; empty without gdb
+	Stderr string       // What the program wrote to stderr before it crashed, e.g. a failed assert
+}
+
+// parseCrash finds the crash report in the run stage's stderr, or returns nil when the program did not crash
+func parseCrash(output string) *Crash {
+	idx := strings.Index(output, crashMarker)
+	if idx < 0 {
+		return nil
+	}
+	crash := &Crash{
+		Frames: parseStackFrames(strings.Split(output[idx:], "\n")[1:]),
+		Stderr: strings.TrimSpace(output[:idx]),
+	}
+	if m := crashMarkerPattern.FindStringSubmatch(output[idx:]); m != nil {
+		crash.Signal, _ = strconv.Atoi(m[1])
+	}
+	return crash
+}
+
+// signalName names the signal, e.g. "SIGSEGV"
+func (c *Crash) signalName() string {
+	if s, ok := crashSignals[c.Signal]; ok {
+		return s.name
+	}
+	return fmt.Sprintf("signal %d", c.Signal)
+}
+
+// Location is the frame the program crashed in
+func (c *Crash) Location() string {
+	return stackLocation(c.Frames)
+}
+
+// Describe reports the crash for the gate result; the program's stderr comes first so it parses again
+func (c *Crash) Describe() string {
+	var sb strings.Builder
+	if c.Stderr != "" {
+		sb.WriteString(c.Stderr + "\n")
+	}
+	fmt.Fprintf(&sb, "%s the program was killed by signal %d (%s)\n", crashMarker, c.Signal, c.signalName())
+	if loc := c.Location(); loc != "" {
+		sb.WriteString("Program crashed at " + loc + "\n")
+		sb.WriteString(formatStackFrames(c.Frames))
+	} else {
+		sb.WriteString("No backtrace: gdb is missing from the validator image, or could not read the crash")
+	}
+	return sb.String()
+}
+
+// promptText describes the crash for the fix prompt
+func (c *Crash) promptText() string {
+	text := "Program crashed with " + c.signalName()
+	if s, ok := crashSignals[c.Signal]; ok {
+		text += " (" + s.meaning + ")"
+	}
+	if loc := c.Location(); loc != "" {
+		text += " at " + loc
+	}
+	text += ". The sanitizer builds did not catch it, so look for behavior that differs at -O2: undefined behavior the optimizer exploits, uninitialized values or out-of-bounds access."
+	if c.Stderr != "" {
+		text += "\nProgram stderr:\n" + indentLines(firstLines(c.Stderr, outputMismatchLines), "  ")
+	}
+	if len(c.Frames) > 0 {
+		text += "\nBacktrace:\n" + formatStackFrames(c.Frames)
+	}
+	return text
+}
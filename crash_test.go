@@ -0,0 +1,99 @@
+package main
+
+import (
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestRunCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs /bin/sh")
+	}
+	c := &ContainerRuntime{hang: HangSettings{Timeout: 5}}
+	tests := []struct {
+		name       string
+		program    string
+		wantCode   int
+		wantSignal int
+	}{
+		{"passes", "true", 0, 0},
+		{"fails", "false", 1, 0},
+		{"segfaults", "sh -c 'echo about to crash >&2; kill -SEGV $$'", 139, 11},
+		{"aborts", "sh -c 'kill -ABRT $$'", 134, 6},
+		{"terminated", "sh -c 'kill -TERM $$'", 143, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command("sh", "-c", c.runCommand(tt.program))
+			cmd.Dir = t.TempDir()
+			var stderr strings.Builder
+			cmd.Stderr = &stderr
+			err := cmd.Run()
+			code := 0
+			if exitErr, ok := err.(*exec.ExitError); ok {
+				code = exitErr.ExitCode()
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d (stderr %q)", code, tt.wantCode, stderr.String())
+			}
+			crash := parseCrash(stderr.String())
+			if tt.wantSignal == 0 {
+				if crash != nil {
+					t.Errorf("parseCrash(%q) = %+v, want no crash", stderr.String(), crash)
+				}
+				return
+			}
+			if crash == nil || crash.Signal != tt.wantSignal {
+				t.Fatalf("parseCrash(%q) = %+v, want signal %d", stderr.String(), crash, tt.wantSignal)
+			}
+			if tt.wantSignal == 11 && !strings.HasPrefix(crash.Stderr, "about to crash") {
+				t.Errorf("crash.Stderr = %q, want the program's stderr", crash.Stderr)
+			}
+		})
+	}
+}
+
+func TestParseCrash(t *testing.T) {
+	stderr := "code: /src/code.cpp:9: int main(): Assertion `p != nullptr' failed.\n" +
+		crashMarker + " the program was killed by signal 6\n" +
+		"#0  0x00007ffff7a4e9fc in __pthread_kill_implementation () from /lib/libc.so.6\n" +
+		"#1  0x00007ffff79fa476 in raise () from /lib/libc.so.6\n" +
+		"#2  0x0000555555555180 in main () at /src/code.cpp:9\n"
+	crash := parseCrash(stderr)
+	if crash == nil || crash.Signal != 6 || len(crash.Frames) != 3 || !strings.Contains(crash.Stderr, "Assertion") {
+		t.Fatalf("parseCrash() = %+v", crash)
+	}
+	if got := crash.Location(); got != "main () at /src/code.cpp:9" {
+		t.Errorf("Location() = %q", got)
+	}
+	desc := crash.Describe()
+	if !strings.Contains(desc, "(SIGABRT)") || !strings.Contains(desc, "Program crashed at main () at /src/code.cpp:9") {
+		t.Errorf("Describe() = %q", desc)
+	}
+	if again := parseCrash(desc); again == nil || again.Signal != 6 || len(again.Frames) != 3 || again.Stderr != crash.Stderr {
+		t.Errorf("parseCrash(Describe()) = %+v, want the same crash", again)
+	}
+
+	noTrace := parseCrash(crashMarker + " the program was killed by signal 11\n")
+	if noTrace == nil || !strings.Contains(noTrace.Describe(), "No backtrace") {
+		t.Errorf("parseCrash(no backtrace) = %+v", noTrace)
+	}
+	if parseCrash("terminate called after throwing an instance of 'std::out_of_range'") != nil {
+		t.Error("parseCrash() reported a crash without the marker")
+	}
+}
+
+func TestFormatErrorForLLMCrash(t *testing.T) {
+	code := "int main() {\n    int* p = nullptr;\n    return *p;\n}\n"
+	crash := &Crash{Signal: 11, Frames: []stackFrame{{Text: "#0 main () at /src/code.cpp:3", File: "/src/code.cpp", Line: 3}}}
+	got := FormatErrorForLLM("run", crash.Describe(), []CodeFile{{Filename: "code.cpp", Content: code}})
+	for _, want := range []string{"[run] Program crashed with SIGSEGV (segmentation fault", "at main () at /src/code.cpp:3", "Backtrace:", "Source:", "return *p;"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("FormatErrorForLLM() = %q, missing %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// deadlockTSANOptions turns on TSAN's lock-order graph and makes it print where both locks of an inversion were taken
+const deadlockTSANOptions = "TSAN_OPTIONS=detect_deadlocks=1:second_deadlock_stack=1 "
+
+// deadlockHint explains a deadlock gate failure in the fix prompt
+const deadlockHint = "Lock-order inversion: two code paths take the same mutexes in opposite orders, so two threads can each hold one " +
+	"and wait forever for the other. Take them together with std::scoped_lock(a, b) (or std::lock), or always in one global order."
+
+// mutexDeclPattern matches a mutex declaration, capturing a '[' or ',' that makes it declare several
+var mutexDeclPattern = regexp.MustCompile(`\b(?:std::(?:recursive_|timed_|recursive_timed_|shared_|shared_timed_)?mutex|pthread_mutex_t)\s+\w+\s*([\[,])?`)
+
+// lockEventPattern matches what takes or releases a lock, and the braces that end a lock guard's scope:
+// a guard declaration (kind, variable, arguments), std::lock (arguments), x.lock() or x.unlock()
+// (object, lock|unlock), pthread_mutex_lock or pthread_mutex_unlock (lock|unlock, mutex), or a brace
+var lockEventPattern = regexp.MustCompile(`\bstd::(lock_guard|unique_lock|scoped_lock)\b(?:\s*<[^<>;]*>)?\s+(\w+)\s*[({]([^(){};]*)[)}]` +
+	`|\bstd::lock\s*\(([^();]*)\)` +
+	`|([\w.>\-\[\]]+?)\s*\.\s*(lock|unlock)\s*\(\s*\)` +
+	`|\bpthread_mutex_(lock|unlock)\s*\(\s*&?\s*([\w.>\-\[\]]+)\s*\)` +
+	`|([{}])`)
+
+// lockFunctionPattern matches a function definition up to its body's brace, capturing its (possibly qualified) name
+var lockFunctionPattern = regexp.MustCompile(`(?m)^[ \t]*(?:[\w:<>,*&]+[ \t]+)+((?:\w+::)*~?\w+)[ \t]*\([^()]*\)[ \t]*(?:const[ \t]*)?(?:noexcept[ \t]*)?(?:override[ \t]*)?\{`)
+
+// usesMultipleLocks reports whether the code declares more than one mutex or takes several at once,
+// which is when locks can be taken in conflicting orders
+func usesMultipleLocks(files []CodeFile) bool {
+	mutexes := 0
+	for _, f := range files {
+		for _, m := range mutexDeclPattern.FindAllStringSubmatch(f.Content, -1) {
+			mutexes++
+			if m[1] != "" {
+				mutexes++
+			}
+		}
+		for _, m := range lockEventPattern.FindAllStringSubmatch(f.Content, -1) {
+			if strings.Contains(m[3]+m[4], ",") && !strings.Contains(m[3], "_lock") {
+				mutexes += 2
+			}
+		}
+	}
+	return mutexes > 1
+}
+
+// LockOrder is one place a mutex is taken while another is held
+type LockOrder struct {
+	Held, Acquired string
+	Function       string
+	File           string
+	Line           int
+}
+
+// heldLock is a mutex held at a point of a function; scoped locks are released at the end of the block at depth
+type heldLock struct {
+	mutex  string
+	scoped bool
+	depth  int
+}
+
+// lockOrders finds, function by function, which mutexes are taken while others are held
+// Mutexes are identified by the expression that names them, so members of different objects
+// reached through the same name are one mutex; TSAN checks the orders of the real objects
+func lockOrders(files []CodeFile) []LockOrder {
+	var orders []LockOrder
+	for _, f := range files {
+		if !isSourceFile(f.Filename) && !isHeaderFile(f.Filename) {
+			continue
+		}
+		end := 0
+		for _, m := range lockFunctionPattern.FindAllStringSubmatchIndex(f.Content, -1) {
+			name := f.Content[m[2]:m[3]]
+			if m[0] < end || slices.Contains(controlKeywords, name) {
+				continue
+			}
+			open := m[1] - 1
+			end = blockEnd(f.Content, open)
+			orders = append(orders, functionLockOrders(f.Content[:end], open, name, f.Filename)...)
+		}
+	}
+	return orders
+}
+
+// functionLockOrders walks the function body starting at open, following lock guards' scopes
+func functionLockOrders(code string, open int, function, filename string) []LockOrder {
+	var orders []LockOrder
+	var held []heldLock
+	guards := make(map[string][]string) // Guard variable to the mutexes it manages
+	depth := 0
+	acquire := func(offset int, mutexes []string, scoped bool) {
+		for _, mutex := range mutexes {
+			for _, h := range held {
+				if h.mutex != mutex && !slices.Contains(mutexes, h.mutex) {
+					orders = append(orders, LockOrder{Held: h.mutex, Acquired: mutex, Function: function,
+						File: filename, Line: strings.Count(code[:offset], "\n") + 1})
+				}
+			}
+		}
+		for _, mutex := range mutexes {
+			held = append(held, heldLock{mutex: mutex, scoped: scoped, depth: depth})
+		}
+	}
+	release := func(mutex string) {
+		for i := len(held) - 1; i >= 0; i-- {
+			if held[i].mutex == mutex {
+				held = append(held[:i], held[i+1:]...)
+				return
+			}
+		}
+	}
+	// resolve maps names that may be guard variables to the mutexes they manage
+	resolve := func(names []string) []string {
+		var mutexes []string
+		for _, name := range names {
+			if g, ok := guards[name]; ok {
+				mutexes = append(mutexes, g...)
+			} else {
+				mutexes = append(mutexes, name)
+			}
+		}
+		return mutexes
+	}
+
+	for _, m := range lockEventPattern.FindAllStringSubmatchIndex(code[open:], -1) {
+		group := func(i int) string {
+			if m[2*i] < 0 {
+				return ""
+			}
+			return code[open+m[2*i] : open+m[2*i+1]]
+		}
+		offset := open + m[0]
+		switch {
+		case group(9) == "{":
+			depth++
+		case group(9) == "}":
+			kept := held[:0]
+			for _, h := range held {
+				if !h.scoped || h.depth < depth {
+					kept = append(kept, h)
+				}
+			}
+			held = kept
+			depth--
+		case group(1) != "":
+			var mutexes []string
+			deferred, adopted := false, false
+			for _, arg := range strings.Split(group(3), ",") {
+				switch arg = strings.TrimSpace(arg); {
+				case strings.Contains(arg, "defer_lock") || strings.Contains(arg, "try_to_lock"):
+					deferred = true
+				case strings.Contains(arg, "adopt_lock"):
+					adopted = true
+				case arg != "":
+					mutexes = append(mutexes, mutexName(arg))
+				}
+			}
+			guards[group(2)] = mutexes
+			switch {
+			case adopted:
+				// The mutexes are held already; the guard now releases them at the end of the block
+				for i := range held {
+					if slices.Contains(mutexes, held[i].mutex) {
+						held[i].scoped, held[i].depth = true, depth
+					}
+				}
+			case !deferred:
+				acquire(offset, mutexes, true)
+			}
+		case group(4) != "":
+			var names []string
+			for _, arg := range strings.Split(group(4), ",") {
+				names = append(names, mutexName(arg))
+			}
+			acquire(offset, resolve(names), false)
+		case group(6) == "lock" || group(7) == "lock":
+			acquire(offset, resolve([]string{mutexName(group(5) + group(8))}), false)
+		case group(6) == "unlock" || group(7) == "unlock":
+			for _, mutex := range resolve([]string{mutexName(group(5) + group(8))}) {
+				release(mutex)
+			}
+		}
+	}
+	return orders
+}
+
+// mutexName normalizes the expression naming a mutex: "&this->m_" and "m_" are the same mutex
+func mutexName(expr string) string {
+	expr = strings.Join(strings.Fields(expr), "")
+	expr = strings.TrimLeft(expr, "&*")
+	return strings.TrimPrefix(expr, "this->")
+}
+
+// lockOrderFindings reports every cycle in the lock-order graph as an error where the first of its
+// orders is taken. Each cycle is reported once, with where each of its other orders is taken
+func lockOrderFindings(files []CodeFile) []string {
+	orders := lockOrders(files)
+	first := make(map[[2]string]LockOrder) // The first place each order is taken
+	next := make(map[string][]string)
+	for _, o := range orders {
+		key := [2]string{o.Held, o.Acquired}
+		if _, ok := first[key]; !ok {
+			first[key] = o
+			next[o.Held] = append(next[o.Held], o.Acquired)
+		}
+	}
+
+	var findings []string
+	reported := make(map[string]bool)
+	for _, o := range orders {
+		if first[[2]string{o.Held, o.Acquired}] != o {
+			continue
+		}
+		path := lockPath(next, o.Acquired, o.Held)
+		if path == nil {
+			continue
+		}
+		cycle := append([]string{o.Held}, path...)
+		members := append([]string(nil), cycle[1:]...)
+		sort.Strings(members)
+		key := strings.Join(members, "\x00")
+		if reported[key] {
+			continue
+		}
+		reported[key] = true
+		var others []string
+		for i := 1; i+1 < len(cycle); i++ {
+			other := first[[2]string{cycle[i], cycle[i+1]}]
+			others = append(others, fmt.Sprintf("%s takes %s while holding %s at %s:%d", other.Function, other.Acquired, other.Held, other.File, other.Line))
+		}
+		findings = append(findings, fmt.Sprintf("/src/%s:%d:1: %s: lock-order inversion (potential deadlock): %s takes %s while holding %s, but %s [lock-order-inversion]",
+			o.File, o.Line, LevelError, o.Function, o.Acquired, o.Held, strings.Join(others, ", and ")))
+	}
+	return findings
+}
+
+// lockPath finds a chain of lock orders from one mutex to another, returning the mutexes after from, or nil
+func lockPath(next map[string][]string, from, to string) []string {
+	prev := map[string]string{from: ""}
+	queue := []string{from}
+	for len(queue) > 0 {
+		mutex := queue[0]
+		queue = queue[1:]
+		if mutex == to {
+			path := []string{}
+			for m := to; m != ""; m = prev[m] {
+				path = append([]string{m}, path...)
+			}
+			return path
+		}
+		for _, n := range next[mutex] {
+			if _, seen := prev[n]; !seen {
+				prev[n] = mutex
+				queue = append(queue, n)
+			}
+		}
+	}
+	return nil
+}
+
+// deadlockGate checks code that uses several mutexes for lock-order inversions, after compiling:
+// statically, from the order each function takes its locks in, then by running it under TSAN's
+// deadlock detector. srcArgs are the compiler's include flags and sources; it reports false when
+// the code does not use several mutexes
+func (c *ContainerRuntime) deadlockGate(files []CodeFile, srcArgs string, runStage func(stage string, command ...string) ValidationResult, progress ProgressCallback) (ValidationResult, bool) {
+	if !usesMultipleLocks(files) {
+		return ValidationResult{}, false
+	}
+	if !c.gates.Enabled("deadlock") {
+		return ValidationResult{Stage: "deadlock", Success: true, Skipped: true}, true
+	}
+	if findings := lockOrderFindings(files); len(findings) > 0 {
+		result := ValidationResult{Stage: "deadlock", Error: strings.Join(findings, "\n")}
+		if progress != nil {
+			progress("deadlock", true, nil)
+			progress("deadlock", false, &result)
+		}
+		return result, true
+	}
+	return runStage("deadlock", "sh", "-c", c.deadlockCommand(srcArgs)), true
+}
+
+// deadlockCommand builds the program under TSAN and runs it with the deadlock detector reporting both stacks
+func (c *ContainerRuntime) deadlockCommand(srcArgs string) string {
+	return c.cxx(c.stdFlag()+" -fsanitize=thread -fno-omit-frame-pointer -g -o /tmp/deadlock "+srcArgs) + " && " +
+		c.programCommand(deadlockTSANOptions, "/tmp/deadlock")
+}
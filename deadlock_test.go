@@ -0,0 +1,198 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const bankSource = `#include <mutex>
+#include <pthread.h>
+
+std::mutex accounts_mutex;
+std::mutex log_mutex;
+
+void transfer() {
+    std::lock_guard<std::mutex> a(accounts_mutex);
+    std::lock_guard<std::mutex> l(log_mutex);
+}
+
+void audit() {
+    std::lock_guard<std::mutex> l(log_mutex);
+    std::lock_guard<std::mutex> a(accounts_mutex);
+}
+`
+
+func TestUsesMultipleLocks(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want bool
+	}{
+		{"two mutexes", bankSource, true},
+		{"one mutex", "std::mutex m;\nvoid f() { std::lock_guard<std::mutex> g(m); }\n", false},
+		{"mutex array", "std::mutex forks[5];\n", true},
+		{"pthread mutexes", "pthread_mutex_t a, b;\n", true},
+		{"scoped_lock of members", "void swap(Box& x, Box& y) { std::scoped_lock lock(x.m, y.m); }\n", true},
+		{"unique_lock with defer_lock", "std::mutex m;\nvoid f() { std::unique_lock<std::mutex> lk(m, std::defer_lock); }\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := usesMultipleLocks([]CodeFile{{Filename: "main.cpp", Content: tt.code}}); got != tt.want {
+				t.Errorf("usesMultipleLocks() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLockOrderFindings(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []CodeFile
+		want  []string // Substrings of the findings, one per finding; nil = none
+	}{
+		{
+			name:  "inversion between guards",
+			files: []CodeFile{{Filename: "bank.cpp", Content: bankSource}},
+			want:  []string{"/src/bank.cpp:9:1: error: lock-order inversion (potential deadlock): transfer takes log_mutex while holding accounts_mutex, but audit takes accounts_mutex while holding log_mutex at bank.cpp:14 [lock-order-inversion]"},
+		},
+		{
+			name: "guard scope ends at its block",
+			files: []CodeFile{{Filename: "main.cpp", Content: `void first() {
+    {
+        std::lock_guard<std::mutex> g(a);
+    }
+    std::lock_guard<std::mutex> h(b);
+}
+void second() {
+    std::lock_guard<std::mutex> h(b);
+    std::lock_guard<std::mutex> g(a);
+}
+`}},
+		},
+		{
+			name: "scoped_lock takes both together",
+			files: []CodeFile{{Filename: "main.cpp", Content: `void first() {
+    std::scoped_lock lock(a, b);
+}
+void second() {
+    std::scoped_lock lock{b, a};
+}
+`}},
+		},
+		{
+			name: "manual lock and unlock across files",
+			files: []CodeFile{
+				{Filename: "worker.h", Content: "inline void left() {\n    pthread_mutex_lock(&fork1);\n    pthread_mutex_lock(&fork2);\n    pthread_mutex_unlock(&fork2);\n    pthread_mutex_unlock(&fork1);\n}\n"},
+				{Filename: "main.cpp", Content: "void Table::right() {\n    this->fork2.lock();\n    fork1.lock();\n    fork1.unlock();\n    fork2.unlock();\n}\n"},
+			},
+			want: []string{"/src/worker.h:3:1: error: lock-order inversion (potential deadlock): left takes fork2 while holding fork1, but Table::right takes fork1 while holding fork2 at main.cpp:3"},
+		},
+		{
+			name: "unlock ends the order",
+			files: []CodeFile{{Filename: "main.cpp", Content: `void first() {
+    a.lock();
+    a.unlock();
+    b.lock();
+    b.unlock();
+}
+void second() {
+    std::unique_lock<std::mutex> lk(b);
+    lk.unlock();
+    std::lock_guard<std::mutex> g(a);
+}
+`}},
+		},
+		{
+			name: "three-mutex cycle reported once",
+			files: []CodeFile{{Filename: "main.cpp", Content: `void ab() {
+    std::lock_guard<std::mutex> x(a);
+    std::lock_guard<std::mutex> y(b);
+}
+void bc() {
+    std::lock_guard<std::mutex> x(b);
+    std::lock_guard<std::mutex> y(c);
+}
+void ca() {
+    std::lock_guard<std::mutex> x(c);
+    std::lock_guard<std::mutex> y(a);
+}
+`}},
+			want: []string{"ab takes b while holding a, but bc takes c while holding b at main.cpp:7, and ca takes a while holding c at main.cpp:11"},
+		},
+		{
+			name: "adopted locks",
+			files: []CodeFile{{Filename: "main.cpp", Content: `void first() {
+    std::lock(a, b);
+    std::lock_guard<std::mutex> x(a, std::adopt_lock);
+    std::lock_guard<std::mutex> y(b, std::adopt_lock);
+}
+void second() {
+    std::unique_lock<std::mutex> lb(b, std::defer_lock);
+    std::unique_lock<std::mutex> la(a, std::defer_lock);
+    std::lock(lb, la);
+}
+`}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := lockOrderFindings(tt.files)
+			if len(got) != len(tt.want) {
+				t.Fatalf("lockOrderFindings() = %q, want %d finding(s)", got, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(got[i], want) {
+					t.Errorf("finding %d = %q, want it to contain %q", i, got[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestDeadlockGate(t *testing.T) {
+	var command []string
+	run := func(stage string, cmd ...string) ValidationResult {
+		command = cmd
+		return ValidationResult{Stage: stage, Success: true}
+	}
+	c := &ContainerRuntime{}
+
+	result, ok := c.deadlockGate([]CodeFile{{Filename: "bank.cpp", Content: bankSource}}, "/src/bank.cpp", run, nil)
+	if !ok || result.Success || !strings.Contains(result.Error, "[lock-order-inversion]") || command != nil {
+		t.Errorf("deadlockGate() = %+v, %v; want the static inversion without running", result, ok)
+	}
+
+	ordered := strings.Replace(bankSource, "std::lock_guard<std::mutex> l(log_mutex);\n    std::lock_guard<std::mutex> a(accounts_mutex);", "std::scoped_lock both(log_mutex, accounts_mutex);", 1)
+	result, ok = c.deadlockGate([]CodeFile{{Filename: "bank.cpp", Content: ordered}}, "/src/bank.cpp", run, nil)
+	if !ok || !result.Success || result.Stage != "deadlock" {
+		t.Fatalf("deadlockGate() = %+v, %v; want the TSAN run", result, ok)
+	}
+	script := strings.Join(command, " ")
+	for _, want := range []string{"-fsanitize=thread", "/src/bank.cpp", "TSAN_OPTIONS=detect_deadlocks=1:second_deadlock_stack=1 /tmp/deadlock"} {
+		if !strings.Contains(script, want) {
+			t.Errorf("deadlock command = %q, missing %q", script, want)
+		}
+	}
+
+	if _, ok := c.deadlockGate([]CodeFile{{Filename: "main.cpp", Content: "std::mutex m;\n"}}, "/src/main.cpp", run, nil); ok {
+		t.Error("deadlockGate() ran on code with one mutex")
+	}
+	c.gates = GateSelection{Skip: []string{"deadlock"}}
+	if result, ok := c.deadlockGate([]CodeFile{{Filename: "bank.cpp", Content: bankSource}}, "/src/bank.cpp", run, nil); !ok || !result.Skipped {
+		t.Errorf("deadlockGate() = %+v, %v; want skipped", result, ok)
+	}
+}
+
+func TestFormatErrorForLLMDeadlock(t *testing.T) {
+	report := "WARNING: ThreadSanitizer: lock-order-inversion (potential deadlock) (pid=12)\n" +
+		"  Cycle in lock order graph: M0 (0x01) => M1 (0x02) => M0\n" +
+		"  Mutex M1 acquired here while holding mutex M0 in thread T1:\n" +
+		"    #0 pthread_mutex_lock <null> (deadlock+0x1)\n" +
+		"    #1 transfer() /src/bank.cpp:9:33 (deadlock+0x2)\n"
+	got := FormatErrorForLLM("deadlock", report, nil)
+	for _, want := range []string{"[deadlock] Lock-order inversion", "scoped_lock", "lock-order-inversion (potential deadlock)"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("FormatErrorForLLM() = %q, missing %q", got, want)
+		}
+	}
+}
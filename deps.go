@@ -0,0 +1,393 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Package managers that can resolve dependencies inside the validation container
+const (
+	DependencyManagerVcpkg = "vcpkg"
+	DependencyManagerConan = "conan"
+)
+
+// dependencyInstallTimeout bounds a vcpkg/Conan install (first builds can be slow)
+const dependencyInstallTimeout = 15 * time.Minute
+
+// knownDependency describes how a library is found and built against
+// Header-only use keeps sanitizer builds fully instrumented (MSan needs that)
+type knownDependency struct {
+	IncludePrefix string   // #include path that implies the library, e.g. "fmt/"
+	Vcpkg         string   // vcpkg port
+	Conan         string   // Conan reference
+	IncludeSubdir string   // Extra include directory under include/ (e.g. eigen3)
+	Defines       []string // Preprocessor defines for header-only use
+	Requires      []string // Other libraries this one needs
+	Libs          []string // Libraries to link (empty = header-only)
+}
+
+// knownDependencies maps canonical library names to their package details
+// Allowed libraries not listed here are installed by name and linked as -l<name>
+var knownDependencies = map[string]knownDependency{
+	"fmt": {
+		IncludePrefix: "fmt/",
+		Vcpkg:         "fmt",
+		Conan:         "fmt/10.2.1",
+		Defines:       []string{"FMT_HEADER_ONLY"},
+	},
+	"spdlog": {
+		IncludePrefix: "spdlog/",
+		Vcpkg:         "spdlog",
+		Conan:         "spdlog/1.13.0",
+		Defines:       []string{"SPDLOG_HEADER_ONLY", "SPDLOG_FMT_EXTERNAL"},
+		Requires:      []string{"fmt"},
+	},
+	"nlohmann-json": {
+		IncludePrefix: "nlohmann/",
+		Vcpkg:         "nlohmann-json",
+		Conan:         "nlohmann_json/3.11.3",
+	},
+	"eigen3": {
+		IncludePrefix: "Eigen/",
+		Vcpkg:         "eigen3",
+		Conan:         "eigen/3.4.0",
+		IncludeSubdir: "eigen3",
+	},
+	"boost": {
+		IncludePrefix: "boost/",
+		Vcpkg:         "boost-headers",
+		Conan:         "boost/1.84.0",
+		Defines:       []string{"BOOST_ALL_NO_LIB"},
+	},
+}
+
+// dependencyAliases maps common spellings to canonical names
+var dependencyAliases = map[string]string{
+	"nlohmann_json": "nlohmann-json",
+	"nlohmann":      "nlohmann-json",
+	"json":          "nlohmann-json",
+	"eigen":         "eigen3",
+	"libfmt":        "fmt",
+}
+
+// dependencyNamePattern restricts names to what package managers accept (also keeps them shell-safe)
+var dependencyNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._+-]*$`)
+
+// requiresPattern matches "// REQUIRES: fmt, spdlog" declarations in generated code
+var requiresPattern = regexp.MustCompile(`(?m)^\s*//\s*REQUIRES:\s*(.+)$`)
+
+// canonicalDependency normalizes a library name
+func canonicalDependency(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if alias, ok := dependencyAliases[name]; ok {
+		return alias
+	}
+	return name
+}
+
+// DetectDependencies returns the third-party libraries the code declares or includes
+// Declarations use "// REQUIRES: name, name"; includes of known libraries count too
+func DetectDependencies(files []CodeFile) []string {
+	seen := make(map[string]bool)
+	var add func(name string)
+	add = func(name string) {
+		name = canonicalDependency(name)
+		if name == "" || seen[name] {
+			return
+		}
+		seen[name] = true
+		for _, dep := range knownDependencies[name].Requires {
+			add(dep)
+		}
+	}
+
+	for _, f := range files {
+		for _, m := range requiresPattern.FindAllStringSubmatch(f.Content, -1) {
+			for _, name := range strings.Split(m[1], ",") {
+				add(name)
+			}
+		}
+		for _, m := range includePattern.FindAllStringSubmatch(f.Content, -1) {
+			for name, dep := range knownDependencies {
+				if strings.HasPrefix(m[1], dep.IncludePrefix) {
+					add(name)
+				}
+			}
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Disallowed returns the names that are not on the allowlist (or not valid package names)
+func (s DependencySettings) Disallowed(names []string) []string {
+	allowed := make(map[string]bool, len(s.Allowed))
+	for _, name := range s.Allowed {
+		allowed[canonicalDependency(name)] = true
+	}
+	var denied []string
+	for _, name := range names {
+		if !allowed[name] || !dependencyNamePattern.MatchString(name) {
+			denied = append(denied, name)
+		}
+	}
+	return denied
+}
+
+// PromptSection tells the model which libraries it may use and how to declare them
+func (s DependencySettings) PromptSection() string {
+	if len(s.Allowed) == 0 {
+		return ""
+	}
+	return "THIRD-PARTY LIBRARIES: Besides the standard library you may use: " + strings.Join(s.Allowed, ", ") + ".\n" +
+		"Declare each one you use on its own line at the top of the file that uses it, e.g. // REQUIRES: fmt\n" +
+		"Do not use any other third-party library."
+}
+
+// ResolvedDependencies are installed libraries and the flags to build against them
+type ResolvedDependencies struct {
+	Names   []string
+	HostDir string // Mounted read-only at /deps in validation stages
+
+	includeDirs []string
+	defines     []string
+	libDirs     []string
+	libs        []string
+}
+
+// CompileFlags returns the include and define flags (for clang-tidy and -fsyntax-only)
+func (r *ResolvedDependencies) CompileFlags() []string {
+	if r == nil {
+		return nil
+	}
+	var flags []string
+	for _, dir := range r.includeDirs {
+		flags = append(flags, "-isystem", dir)
+	}
+	for _, def := range r.defines {
+		flags = append(flags, "-D"+def)
+	}
+	return flags
+}
+
+// LinkFlags returns the library search path and library flags
+func (r *ResolvedDependencies) LinkFlags() []string {
+	if r == nil || len(r.libs) == 0 {
+		return nil
+	}
+	var flags []string
+	for _, dir := range r.libDirs {
+		flags = append(flags, "-L"+dir)
+	}
+	for _, lib := range r.libs {
+		flags = append(flags, "-l"+lib)
+	}
+	return flags
+}
+
+// SetDependencySettings configures the dependency allowlist and package manager
+func (c *ContainerRuntime) SetDependencySettings(settings DependencySettings) {
+	c.dependencies = settings
+}
+
+// compileFlags returns the include and define flags of the resolved libraries and Qt
+func (c *ContainerRuntime) compileFlags() []string {
+	return append(append([]string{}, c.deps.CompileFlags()...), c.qt.CompileFlags()...)
+}
+
+// linkFlags returns the libraries, and generated sources, the program links against
+func (c *ContainerRuntime) linkFlags() []string {
+	return append(append([]string{}, c.deps.LinkFlags()...), c.qt.LinkFlags()...)
+}
+
+// cxx builds a clang++ command line, adding dependency flags after the sources
+func (c *ContainerRuntime) cxx(args string) string {
+	cmd := "clang++ " + args
+	if flags := append(c.compileFlags(), c.linkFlags()...); len(flags) > 0 {
+		cmd += " " + strings.Join(flags, " ")
+	}
+	return cmd
+}
+
+// dependencyCacheDir returns ~/.bjarne/deps, where installed libraries persist between runs
+func dependencyCacheDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".bjarne", "deps"), nil
+}
+
+// withDependencies resolves the libraries the files need (and Qt, see withQt) and returns a runtime whose
+// stages build against them. A failed result is returned (instead of the runtime)
+// when a library is not allowed or cannot be installed, so the fix loop sees why.
+func (c *ContainerRuntime) withDependencies(ctx context.Context, files []CodeFile) (*ContainerRuntime, *ValidationResult, error) {
+	c = c.withCoroutines(files)
+	if c.deps != nil {
+		return c.withQt(ctx, files)
+	}
+	names := DetectDependencies(files)
+	if len(names) == 0 {
+		return c.withQt(ctx, files)
+	}
+
+	if denied := c.dependencies.Disallowed(names); len(denied) > 0 {
+		allowed := "none; only the standard library may be used"
+		if len(c.dependencies.Allowed) > 0 {
+			allowed = strings.Join(c.dependencies.Allowed, ", ")
+		}
+		return nil, &ValidationResult{
+			Stage: "dependencies",
+			Error: fmt.Sprintf("not in the dependency allowlist: %s (allowed: %s). Remove them or use an allowed library.",
+				strings.Join(denied, ", "), allowed),
+		}, nil
+	}
+
+	resolved, result, err := c.installDependencies(ctx, names)
+	if err != nil || result != nil {
+		return nil, result, err
+	}
+
+	withDeps := *c
+	withDeps.deps = resolved
+	return withDeps.withQt(ctx, files)
+}
+
+// installDependencies runs vcpkg or Conan in a networked container against the cache directory
+func (c *ContainerRuntime) installDependencies(ctx context.Context, names []string) (*ResolvedDependencies, *ValidationResult, error) {
+	hostDir, err := dependencyCacheDir()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := os.MkdirAll(hostDir, 0750); err != nil {
+		return nil, nil, fmt.Errorf("failed to create dependency cache: %w", err)
+	}
+
+	resolved := &ResolvedDependencies{Names: names, HostDir: hostDir}
+	var command []string
+	switch c.dependencies.manager() {
+	case DependencyManagerConan:
+		var refs []string
+		for _, name := range names {
+			ref := name + "/[*]"
+			if dep, ok := knownDependencies[name]; ok {
+				ref = dep.Conan
+			}
+			refs = append(refs, "--requires="+ref)
+			pkg, _, _ := strings.Cut(ref, "/")
+			resolved.addPackage(name, "/deps/conan/direct_deploy/"+pkg)
+		}
+		command = []string{"sh", "-c",
+			"export CONAN_HOME=/deps/conan-home && conan profile detect --exist-ok >/dev/null && " +
+				"conan install " + strings.Join(refs, " ") + " --build=missing --deployer=direct_deploy --deployer-folder=/deps/conan"}
+	default:
+		triplet := "x64-linux"
+		if runtime.GOARCH == "arm64" {
+			triplet = "arm64-linux"
+		}
+		var ports []string
+		for _, name := range names {
+			port := name
+			if dep, ok := knownDependencies[name]; ok {
+				port = dep.Vcpkg
+			}
+			ports = append(ports, port)
+			resolved.addPackage(name, "/deps/vcpkg/"+triplet)
+		}
+		command = append([]string{"vcpkg", "install", "--triplet=" + triplet, "--x-install-root=/deps/vcpkg"}, ports...)
+	}
+
+	installCtx, cancel := context.WithTimeout(ctx, dependencyInstallTimeout)
+	defer cancel()
+
+	// Installing needs the network, so it runs separately from the sandboxed gates
+	// Offline, only libraries already in the cache resolve
+	args := []string{"--rm"}
+	if offlineMode {
+		args = append(args, "--network=none")
+	}
+	args = append(append(args, "-v", filepath.ToSlash(hostDir)+":/deps", c.imageName), command...)
+	var output bytes.Buffer
+	start := time.Now()
+	if err := c.runContainer(installCtx, &output, &output, args...); err != nil {
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		hint := ""
+		if offlineMode {
+			hint = " (offline: only libraries already installed in ~/.bjarne/deps can be used)"
+		}
+		return nil, &ValidationResult{
+			Stage:    "dependencies",
+			Error:    fmt.Sprintf("%s install of %s failed%s: %v\n%s", c.dependencies.manager(), strings.Join(names, ", "), hint, err, lastLines(output.String(), 30)),
+			Duration: time.Since(start),
+		}, nil
+	}
+	return resolved, nil, nil
+}
+
+// addPackage records the include/library directories and flags for one installed library
+func (r *ResolvedDependencies) addPackage(name, root string) {
+	dep, known := knownDependencies[name]
+	r.addDir(&r.includeDirs, root+"/include")
+	if dep.IncludeSubdir != "" {
+		r.addDir(&r.includeDirs, root+"/include/"+dep.IncludeSubdir)
+	}
+	r.defines = append(r.defines, dep.Defines...)
+	libs := dep.Libs
+	if !known {
+		libs = []string{name}
+	}
+	if len(libs) > 0 {
+		r.addDir(&r.libDirs, root+"/lib")
+		r.libs = append(r.libs, libs...)
+	}
+}
+
+// addDir appends dir to dirs once
+func (r *ResolvedDependencies) addDir(dirs *[]string, dir string) {
+	for _, d := range *dirs {
+		if d == dir {
+			return
+		}
+	}
+	*dirs = append(*dirs, dir)
+}
+
+// resultsOf wraps an optional failed result for an early return from a pipeline
+func resultsOf(r *ValidationResult) []ValidationResult {
+	if r == nil {
+		return nil
+	}
+	return []ValidationResult{*r}
+}
+
+// manager returns the configured package manager name
+func (s DependencySettings) manager() string {
+	if s.Manager == DependencyManagerConan {
+		return DependencyManagerConan
+	}
+	return DependencyManagerVcpkg
+}
+
+// lastLines returns the final n lines of s (install logs are long)
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDetectDependencies(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []CodeFile
+		want  string
+	}{
+		{"standard library only", []CodeFile{{Filename: "main.cpp", Content: "#include <vector>\nint main() {}"}}, ""},
+		{"declared", []CodeFile{{Filename: "main.cpp", Content: "// REQUIRES: nlohmann_json, Eigen\nint main() {}"}}, "eigen3,nlohmann-json"},
+		{"known include", []CodeFile{{Filename: "main.cpp", Content: "#include <fmt/core.h>\nint main() {}"}}, "fmt"},
+		{
+			"implied and across files",
+			[]CodeFile{
+				{Filename: "log.h", Content: "#pragma once\n#include \"spdlog/spdlog.h\""},
+				{Filename: "main.cpp", Content: "// REQUIRES: zlib\n#include \"log.h\""},
+			},
+			"fmt,spdlog,zlib",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strings.Join(DetectDependencies(tt.files), ","); got != tt.want {
+				t.Errorf("DetectDependencies() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDependencySettingsDisallowed(t *testing.T) {
+	s := DependencySettings{Allowed: []string{"fmt", "nlohmann_json", "bad;rm"}}
+
+	if got := s.Disallowed([]string{"fmt", "nlohmann-json"}); len(got) != 0 {
+		t.Errorf("Disallowed(allowed) = %v, want none", got)
+	}
+	if got := strings.Join(s.Disallowed([]string{"boost", "fmt", "bad;rm"}), ","); got != "boost,bad;rm" {
+		t.Errorf("Disallowed() = %q, want boost and the unsafe name", got)
+	}
+	if got := (DependencySettings{}).Disallowed([]string{"fmt"}); len(got) != 1 {
+		t.Errorf("empty allowlist should deny everything, got %v", got)
+	}
+}
+
+func TestDependencySettingsPromptSection(t *testing.T) {
+	if got := (DependencySettings{}).PromptSection(); got != "" {
+		t.Errorf("PromptSection() with no allowlist = %q, want empty", got)
+	}
+	got := DependencySettings{Allowed: []string{"fmt", "spdlog"}}.PromptSection()
+	if !strings.Contains(got, "fmt, spdlog") || !strings.Contains(got, "// REQUIRES:") {
+		t.Errorf("PromptSection() = %q", got)
+	}
+}
+
+func TestResolvedDependenciesFlags(t *testing.T) {
+	r := &ResolvedDependencies{}
+	r.addPackage("spdlog", "/deps/vcpkg/x64-linux")
+	r.addPackage("fmt", "/deps/vcpkg/x64-linux")
+	r.addPackage("eigen3", "/deps/vcpkg/x64-linux")
+	r.addPackage("zlib", "/deps/vcpkg/x64-linux")
+
+	compile := strings.Join(r.CompileFlags(), " ")
+	for _, want := range []string{
+		"-isystem /deps/vcpkg/x64-linux/include",
+		"-isystem /deps/vcpkg/x64-linux/include/eigen3",
+		"-DSPDLOG_HEADER_ONLY",
+		"-DFMT_HEADER_ONLY",
+	} {
+		if !strings.Contains(compile, want) {
+			t.Errorf("CompileFlags() = %q, missing %q", compile, want)
+		}
+	}
+	if strings.Count(compile, "-isystem /deps/vcpkg/x64-linux/include ") != 1 {
+		t.Errorf("CompileFlags() repeats the include directory: %q", compile)
+	}
+
+	// Only the unknown library is linked; the known ones are header-only
+	if got := strings.Join(r.LinkFlags(), " "); got != "-L/deps/vcpkg/x64-linux/lib -lzlib" {
+		t.Errorf("LinkFlags() = %q", got)
+	}
+
+	c := &ContainerRuntime{deps: r}
+	if got := c.cxx("-O2 -o /tmp/test /src/code.cpp"); !strings.HasPrefix(got, "clang++ -O2 -o /tmp/test /src/code.cpp -isystem") || !strings.HasSuffix(got, "-lzlib") {
+		t.Errorf("cxx() = %q", got)
+	}
+	if got := (&ContainerRuntime{}).cxx("-O2 /src/code.cpp"); got != "clang++ -O2 /src/code.cpp" {
+		t.Errorf("cxx() without dependencies = %q", got)
+	}
+}
+
+func TestWithDependenciesAllowlist(t *testing.T) {
+	c := &ContainerRuntime{dependencies: DependencySettings{Manager: DependencyManagerVcpkg, Allowed: []string{"fmt"}}}
+
+	same, result, err := c.withDependencies(context.Background(), []CodeFile{{Filename: "code.cpp", Content: "#include <vector>"}})
+	if err != nil || result != nil || same != c {
+		t.Errorf("standard-library code should validate unchanged, got %v, %v", result, err)
+	}
+
+	_, result, err = c.withDependencies(context.Background(), []CodeFile{{Filename: "code.cpp", Content: "#include <boost/asio.hpp>"}})
+	if err != nil {
+		t.Fatalf("withDependencies() error = %v", err)
+	}
+	if result == nil || result.Success || result.Stage != "dependencies" || !strings.Contains(result.Error, "boost") {
+		t.Errorf("disallowed library result = %+v", result)
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DiffOp is the kind of change for a diff line
+type DiffOp int
+
+const (
+	DiffEqual DiffOp = iota
+	DiffInsert
+	DiffDelete
+)
+
+// DiffLine is one line of a line-based diff
+type DiffLine struct {
+	Op   DiffOp
+	Text string
+}
+
+// maxDiffCells bounds the LCS table size; larger inputs fall back to a whole-file replace
+const maxDiffCells = 4_000_000
+
+// splitLines splits text into lines, ignoring a single trailing newline
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// LineDiff computes a line-based diff between two texts (longest common subsequence)
+func LineDiff(oldText, newText string) []DiffLine {
+	a, b := splitLines(oldText), splitLines(newText)
+
+	if len(a)*len(b) > maxDiffCells {
+		var out []DiffLine
+		for _, l := range a {
+			out = append(out, DiffLine{DiffDelete, l})
+		}
+		for _, l := range b {
+			out = append(out, DiffLine{DiffInsert, l})
+		}
+		return out
+	}
+
+	// lcs[i][j] = length of LCS of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var out []DiffLine
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, DiffLine{DiffEqual, a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, DiffLine{DiffDelete, a[i]})
+			i++
+		default:
+			out = append(out, DiffLine{DiffInsert, b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		out = append(out, DiffLine{DiffDelete, a[i]})
+	}
+	for ; j < len(b); j++ {
+		out = append(out, DiffLine{DiffInsert, b[j]})
+	}
+	return out
+}
+
+// DiffStats counts inserted and deleted lines
+func DiffStats(diff []DiffLine) (added, removed int) {
+	for _, d := range diff {
+		switch d.Op {
+		case DiffInsert:
+			added++
+		case DiffDelete:
+			removed++
+		}
+	}
+	return added, removed
+}
+
+// UnifiedDiff renders a unified diff with the given number of context lines
+// Returns empty string if the texts are identical
+func UnifiedDiff(oldName, newName, oldText, newText string, context int) string {
+	diff := LineDiff(oldText, newText)
+	if added, removed := DiffStats(diff); added == 0 && removed == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("--- %s\n+++ %s\n", oldName, newName))
+
+	// Group changes into hunks separated by more than 2*context unchanged lines
+	oldLine, newLine := 1, 1
+	for start := 0; start < len(diff); {
+		// Find next change
+		first := start
+		for first < len(diff) && diff[first].Op == DiffEqual {
+			first++
+		}
+		if first == len(diff) {
+			break
+		}
+
+		// Extend the hunk while changes are close together
+		last := first
+		for k := first; k < len(diff); k++ {
+			if diff[k].Op != DiffEqual {
+				last = k
+			} else if k-last > 2*context {
+				break
+			}
+		}
+
+		hunkStart := first - context
+		if hunkStart < start {
+			hunkStart = start
+		}
+		hunkEnd := last + context + 1
+		if hunkEnd > len(diff) {
+			hunkEnd = len(diff)
+		}
+
+		// Advance line counters to the hunk start
+		for k := start; k < hunkStart; k++ {
+			oldLine++
+			newLine++
+		}
+
+		var body strings.Builder
+		oldCount, newCount := 0, 0
+		for k := hunkStart; k < hunkEnd; k++ {
+			switch diff[k].Op {
+			case DiffEqual:
+				body.WriteString(" " + diff[k].Text + "\n")
+				oldCount++
+				newCount++
+			case DiffDelete:
+				body.WriteString("-" + diff[k].Text + "\n")
+				oldCount++
+			case DiffInsert:
+				body.WriteString("+" + diff[k].Text + "\n")
+				newCount++
+			}
+		}
+
+		sb.WriteString(fmt.Sprintf("@@ -%d,%d +%d,%d @@\n", oldLine, oldCount, newLine, newCount))
+		sb.WriteString(body.String())
+
+		oldLine += oldCount
+		newLine += newCount
+		start = hunkEnd
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLineDiff(t *testing.T) {
+	tests := []struct {
+		name        string
+		old, new    string
+		wantAdded   int
+		wantRemoved int
+	}{
+		{"identical", "a\nb\n", "a\nb\n", 0, 0},
+		{"insert", "a\nc\n", "a\nb\nc\n", 1, 0},
+		{"delete", "a\nb\nc\n", "a\nc\n", 0, 1},
+		{"replace", "a\nb\nc\n", "a\nx\nc\n", 1, 1},
+		{"from empty", "", "a\nb\n", 2, 0},
+		{"to empty", "a\nb\n", "", 0, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			added, removed := DiffStats(LineDiff(tt.old, tt.new))
+			if added != tt.wantAdded || removed != tt.wantRemoved {
+				t.Errorf("DiffStats = +%d -%d, want +%d -%d", added, removed, tt.wantAdded, tt.wantRemoved)
+			}
+		})
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	if got := UnifiedDiff("a", "b", "x\ny\n", "x\ny\n", 3); got != "" {
+		t.Errorf("identical texts should give empty diff, got %q", got)
+	}
+
+	oldText := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	newText := "1\nTWO\n3\n4\n5\n6\n7\n8\n9\n10\nELEVEN\n12\n"
+	got := UnifiedDiff("old.cpp", "new.cpp", oldText, newText, 1)
+
+	for _, want := range []string{"--- old.cpp\n+++ new.cpp\n", "@@ -1,3 +1,3 @@\n", "-2\n+TWO\n", "@@ -10,3 +10,3 @@\n", "-11\n+ELEVEN\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("diff missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Count(got, "@@ -") != 2 {
+		t.Errorf("expected 2 hunks for distant changes:\n%s", got)
+	}
+}
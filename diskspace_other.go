@@ -0,0 +1,10 @@
+//go:build !unix && !windows
+
+package main
+
+import "errors"
+
+// diskFree is not available on this platform
+func diskFree(string) (int64, error) {
+	return 0, errors.New("free disk space is not available on this platform")
+}
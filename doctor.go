@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// doctorTimeout bounds the container runtime probes
+const doctorTimeout = 15 * time.Second
+
+// doctorLowDisk is the free space below which the disk check warns
+const doctorLowDisk = 2 << 30
+
+// Doctor check results
+const (
+	doctorOK   = "ok"
+	doctorWarn = "warn"
+	doctorFail = "fail"
+)
+
+// doctorCheck is one line of `bjarne doctor` output
+type doctorCheck struct {
+	Name   string
+	Status string // doctorOK, doctorWarn or doctorFail
+	Detail string
+	Fix    string // What to do about a warning or failure
+}
+
+// runDoctor implements `bjarne doctor`: checks each part of the setup and says how to fix what is broken
+func runDoctor(args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintf(os.Stderr, "Unknown doctor option: %s\nUsage: bjarne doctor\n", args[0])
+		return 1
+	}
+	fmt.Println("bjarne doctor")
+	fmt.Println()
+
+	ctx := context.Background()
+	settings, settingsErr := LoadSettings()
+	checks := checkSettings(settings, settingsErr)
+	checks = append(checks, checkContainer(ctx, settings)...)
+	checks = append(checks, checkProviderSetup(ctx, settings))
+	checks = append(checks, checkONNX())
+	if home, err := os.UserHomeDir(); err == nil {
+		checks = append(checks, checkDisk(filepath.Join(home, ".bjarne")))
+	}
+
+	fmt.Print(formatDoctorReport(checks))
+	if doctorFailed(checks) {
+		return 1
+	}
+	return 0
+}
+
+// doctorFailed reports whether any check failed (warnings do not count)
+func doctorFailed(checks []doctorCheck) bool {
+	for _, c := range checks {
+		if c.Status == doctorFail {
+			return true
+		}
+	}
+	return false
+}
+
+// formatDoctorReport renders the checks, with the fix under each one that needs attention
+func formatDoctorReport(checks []doctorCheck) string {
+	var sb strings.Builder
+	var fails, warns int
+	for _, c := range checks {
+		mark := "\033[92m✓\033[0m"
+		switch c.Status {
+		case doctorWarn:
+			mark = "\033[93m!\033[0m"
+			warns++
+		case doctorFail:
+			mark = "\033[91m✗\033[0m"
+			fails++
+		}
+		fmt.Fprintf(&sb, "  %s %-10s %s\n", mark, c.Name, c.Detail)
+		if c.Fix != "" && c.Status != doctorOK {
+			for _, line := range strings.Split(c.Fix, "\n") {
+				fmt.Fprintf(&sb, "%15s %s\n", "→", strings.TrimSpace(line))
+			}
+		}
+	}
+	sb.WriteString("\n")
+	switch {
+	case fails > 0:
+		fmt.Fprintf(&sb, "\033[91m%d check(s) failed\033[0m, %d warning(s)\n", fails, warns)
+	case warns > 0:
+		fmt.Fprintf(&sb, "All checks passed with %d warning(s)\n", warns)
+	default:
+		sb.WriteString("\033[92mAll checks passed\033[0m\n")
+	}
+	return sb.String()
+}
+
+// checkSettings validates ~/.bjarne/settings.json beyond parsing: values the
+// rest of bjarne would reject when it gets to them
+func checkSettings(settings *Settings, loadErr error) []doctorCheck {
+	path, _ := SettingsPath()
+	if loadErr != nil {
+		return []doctorCheck{{
+			Name:   "settings",
+			Status: doctorFail,
+			Detail: fmt.Sprintf("%s: %v", path, loadErr),
+			Fix:    "Fix the JSON in " + path + ", or move it aside and run 'bjarne init'",
+		}}
+	}
+
+	issues := settings.Validate()
+	if data, err := os.ReadFile(path); err == nil {
+		issues = checkSettingsData(data)
+	}
+	if len(issues) > 0 {
+		problems := make([]string, len(issues))
+		for i, issue := range issues {
+			problems[i] = issue.String()
+		}
+		return []doctorCheck{{
+			Name:   "settings",
+			Status: doctorFail,
+			Detail: strings.Join(problems, "; "),
+			Fix:    "Edit " + path + " to correct the values above ('bjarne config validate' rechecks it)",
+		}}
+	}
+	detail := path
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		detail = "using defaults (no " + path + ")"
+	}
+	return []doctorCheck{{Name: "settings", Status: doctorOK, Detail: detail}}
+}
+
+// checkContainer finds the container runtime and the validator image
+func checkContainer(ctx context.Context, settings *Settings) []doctorCheck {
+	ctx, cancel := context.WithTimeout(ctx, doctorTimeout)
+	defer cancel()
+
+	container, err := DetectContainerRuntime()
+	if err != nil {
+		return []doctorCheck{
+			{Name: "container", Status: doctorFail, Detail: "no podman or docker found", Fix: "Install podman: " + GetPodmanInstallInfo().Manual},
+			{Name: "image", Status: doctorFail, Detail: "not checked (no container runtime)"},
+		}
+	}
+	version, err := exec.CommandContext(ctx, container.binary, "--version").Output()
+	if err != nil {
+		return []doctorCheck{
+			{
+				Name:   "container",
+				Status: doctorFail,
+				Detail: fmt.Sprintf("%s is installed but not working: %v", container.GetBinary(), err),
+				Fix:    fmt.Sprintf("Check that '%s info' succeeds (on macOS and Windows: '%s machine start')", container.GetBinary(), container.GetBinary()),
+			},
+			{Name: "image", Status: doctorFail, Detail: "not checked (container runtime not working)"},
+		}
+	}
+	checks := []doctorCheck{{Name: "container", Status: doctorOK, Detail: strings.TrimSpace(string(version))}}
+
+	if settings == nil {
+		settings = DefaultSettings()
+	}
+	image, err := resolveSessionImage(settings.Container)
+	if err != nil {
+		return append(checks, doctorCheck{Name: "image", Status: doctorFail, Detail: err.Error(), Fix: "Correct the container settings in settings.json"})
+	}
+	if !container.HasImage(ctx, image.Ref) {
+		return append(checks, doctorCheck{
+			Name:   "image",
+			Status: doctorFail,
+			Detail: image.Ref + " is not pulled",
+			Fix:    fmt.Sprintf("Run '%s pull %s', or start bjarne and accept the pull prompt", container.GetBinary(), image.Ref),
+		})
+	}
+	detail := image.Ref
+	if digest := container.LocalDigest(ctx, image.Ref); digest != "" {
+		detail += " (" + digest + ")"
+	}
+	return append(checks, doctorCheck{Name: "image", Status: doctorOK, Detail: detail})
+}
+
+// checkProviderSetup makes the same tiny request `bjarne init` does to prove the credentials work
+func checkProviderSetup(ctx context.Context, settings *Settings) doctorCheck {
+	cfg := LoadConfig()
+	pcfg := cfg.GetProviderConfig()
+	if settings != nil {
+		pcfg.Local = settings.Local
+		SetNetworkSettings(settings.Network)
+	}
+	name, err := checkProvider(ctx, pcfg)
+	if err != nil {
+		return doctorCheck{
+			Name:   "provider",
+			Status: doctorFail,
+			Detail: fmt.Sprintf("%s: %s", cfg.Provider, truncateError(err.Error(), 120)),
+			Fix:    providerFix(cfg.Provider, err),
+		}
+	}
+	return doctorCheck{Name: "provider", Status: doctorOK, Detail: name + " answered"}
+}
+
+// providerFix suggests how to fix a provider failure, preferring the error-specific suggestion
+func providerFix(p ProviderType, err error) string {
+	if suggestion := getSuggestionForError(err.Error()); suggestion != "" {
+		return suggestion
+	}
+	switch p {
+	case ProviderBedrock:
+		return "Configure AWS credentials (aws configure, or AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY) and check AWS_REGION"
+	case ProviderLocal:
+		return "Start your local model server and check local.url in settings.json"
+	}
+	return fmt.Sprintf("Set BJARNE_%s_API_KEY (or BJARNE_API_KEY), or run 'bjarne init'", strings.ToUpper(string(p)))
+}
+
+// checkONNX reports whether semantic indexing can use the ONNX embedder
+func checkONNX() doctorCheck {
+	if !onnxCompiledIn {
+		return doctorCheck{
+			Name:   "onnx",
+			Status: doctorOK,
+			Detail: "not compiled in; semantic search uses the built-in embedder",
+		}
+	}
+	if IsONNXAvailable() {
+		return doctorCheck{Name: "onnx", Status: doctorOK, Detail: "runtime " + onnxVersion + " found"}
+	}
+	return doctorCheck{
+		Name:   "onnx",
+		Status: doctorWarn,
+		Detail: "runtime library not found; semantic search falls back to the built-in embedder",
+		Fix:    "Run /init in bjarne to download ONNX Runtime into ~/.bjarne/lib",
+	}
+}
+
+// checkDisk reports how much bjarne keeps under dir and warns when the disk is nearly full
+func checkDisk(dir string) doctorCheck {
+	used, err := dirSize(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return doctorCheck{Name: "disk", Status: doctorWarn, Detail: fmt.Sprintf("cannot read %s: %v", dir, err)}
+	}
+	detail := fmt.Sprintf("%s used by %s", formatBytes(used), dir)
+
+	// Measure free space on the nearest directory that exists
+	probe := dir
+	for {
+		if _, err := os.Stat(probe); err == nil || filepath.Dir(probe) == probe {
+			break
+		}
+		probe = filepath.Dir(probe)
+	}
+	free, err := diskFree(probe)
+	if err != nil {
+		return doctorCheck{Name: "disk", Status: doctorOK, Detail: detail}
+	}
+	detail += fmt.Sprintf(", %s free", formatBytes(free))
+	if free < doctorLowDisk {
+		return doctorCheck{
+			Name:   "disk",
+			Status: doctorWarn,
+			Detail: detail,
+			Fix:    fmt.Sprintf("Free some space: delete old auto-saved code in %s and old backups in %s, or lower audit.maxAgeDays", filepath.Join(dir, "history"), filepath.Join(dir, "backups")),
+		}
+	}
+	return doctorCheck{Name: "disk", Status: doctorOK, Detail: detail}
+}
+
+// dirSize adds up the sizes of the regular files under dir
+func dirSize(dir string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir {
+				return err
+			}
+			return nil // Skip unreadable entries
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	return total, err
+}
+
+// formatBytes renders a byte count in binary units
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{5 << 20, "5.0 MiB"},
+		{3 << 30, "3.0 GiB"},
+	}
+	for _, tt := range tests {
+		if got := formatBytes(tt.n); got != tt.want {
+			t.Errorf("formatBytes(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestCheckSettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Settings)
+		loadErr error
+		status  string
+		detail  string
+	}{
+		{"defaults", func(*Settings) {}, nil, doctorOK, ""},
+		{"parse error", func(*Settings) {}, errors.New("invalid character"), doctorFail, "invalid character"},
+		{"provider", func(s *Settings) { s.Provider = "openia" }, nil, doctorFail, `unknown provider "openia"`},
+		{"backup", func(s *Settings) { s.Save.Backup = "tape" }, nil, doctorFail, "save.backup"},
+		{"consensus", func(s *Settings) { s.Review.Consensus = "vote" }, nil, doctorFail, "review.consensus"},
+		{"theme", func(s *Settings) { s.Theme.Name = "neon" }, nil, doctorFail, `unknown theme "neon"`},
+		{"hook gates", func(s *Settings) { s.Hook.Gates = []string{"compile", "lint"} }, nil, doctorFail, "hook.gates"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := DefaultSettings()
+			tt.modify(s)
+			checks := checkSettings(s, tt.loadErr)
+			if len(checks) != 1 {
+				t.Fatalf("got %d checks, want 1", len(checks))
+			}
+			c := checks[0]
+			if c.Status != tt.status {
+				t.Errorf("status = %q, want %q (%s)", c.Status, tt.status, c.Detail)
+			}
+			if !strings.Contains(c.Detail, tt.detail) {
+				t.Errorf("detail %q does not mention %q", c.Detail, tt.detail)
+			}
+			if c.Status == doctorFail && c.Fix == "" {
+				t.Error("failed check has no fix")
+			}
+		})
+	}
+}
+
+func TestFormatDoctorReport(t *testing.T) {
+	checks := []doctorCheck{
+		{Name: "settings", Status: doctorOK, Detail: "using defaults", Fix: "never shown"},
+		{Name: "onnx", Status: doctorWarn, Detail: "library not found", Fix: "Run /init"},
+		{Name: "image", Status: doctorFail, Detail: "not pulled", Fix: "Pull it\nor start bjarne"},
+	}
+	out := formatDoctorReport(checks)
+	for _, want := range []string{"settings", "→ Run /init", "→ Pull it", "→ or start bjarne", "1 check(s) failed", "1 warning(s)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "never shown") {
+		t.Errorf("fix shown for a passing check:\n%s", out)
+	}
+	if !doctorFailed(checks) {
+		t.Error("doctorFailed = false with a failed check")
+	}
+	if doctorFailed(checks[:2]) {
+		t.Error("doctorFailed = true with only a warning")
+	}
+	if out := formatDoctorReport(checks[:1]); !strings.Contains(out, "All checks passed") {
+		t.Errorf("passing report = %q", out)
+	}
+}
+
+func TestCheckDisk(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "history"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	for name, size := range map[string]int{"settings.json": 100, filepath.Join("history", "a.cpp"): 2000} {
+		if err := os.WriteFile(filepath.Join(dir, name), make([]byte, size), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	used, err := dirSize(dir)
+	if err != nil {
+		t.Fatalf("dirSize: %v", err)
+	}
+	if used != 2100 {
+		t.Errorf("dirSize = %d, want 2100", used)
+	}
+
+	c := checkDisk(dir)
+	if !strings.HasPrefix(c.Detail, "2.1 KiB used by "+dir) {
+		t.Errorf("detail = %q", c.Detail)
+	}
+
+	// A missing ~/.bjarne is not an error: nothing is used yet
+	c = checkDisk(filepath.Join(dir, "missing"))
+	if !strings.HasPrefix(c.Detail, "0 B used by") {
+		t.Errorf("missing dir detail = %q", c.Detail)
+	}
+}
@@ -26,6 +26,12 @@ type DefinitionOfDone struct {
 	MaxMemoryMB int // Max memory usage in MB
 	BenchmarkN  int // Number of items to benchmark with
 
+	// What the program must print, from the analysis (checked by the output gate)
+	ExpectedOutput *ExpectedOutput
+
+	// stdin and arguments the program runs with in the sanitizer and run stages
+	Input *RunInput
+
 	// What bjarne cannot test (informational only)
 	CannotTest []string
 }
@@ -75,6 +81,9 @@ FORMAT YOUR RESPONSE AS:
 
 Be concise. No fluff. Channel Bjarne's directness.`
 
+// DoDRequestPrompt asks for the acceptance-criteria questions once the analysis has been shown
+const DoDRequestPrompt = "Before I answer your analysis, ask me for the testable acceptance criteria you need."
+
 // ParseDefinitionOfDone extracts DoD from user's response
 func ParseDefinitionOfDone(response string) *DefinitionOfDone {
 	dod := &DefinitionOfDone{}
@@ -114,6 +123,9 @@ func ParseDefinitionOfDone(response string) *DefinitionOfDone {
 	// Parse property tests
 	parseProperties(responseLower, dod)
 
+	// Parse "stdin:" and "args:" lines for programs that read input
+	dod.Input = parseRunInput(response)
+
 	// Parse performance requirements
 	// Pattern: "N items in <X ms" or "< X ms" or "under X ms"
 	perfPattern := regexp.MustCompile(`(\d+)\s*(?:items?|elements?)?\s*(?:in\s*)?[<]?\s*(\d+)\s*ms`)
@@ -218,6 +230,12 @@ func parseProperties(response string, dod *DefinitionOfDone) {
 
 // HasTestableRequirements checks if DoD has anything we can actually test
 func (d *DefinitionOfDone) HasTestableRequirements() bool {
+	return d.hasCriteria() || d.ExpectedOutput != nil || !d.Input.empty()
+}
+
+// hasCriteria reports whether the DoD holds acceptance criteria from the user,
+// rather than only the analysis's expected output or the /stdin input
+func (d *DefinitionOfDone) hasCriteria() bool {
 	return len(d.Examples) > 0 ||
 		len(d.Properties) > 0 ||
 		d.HandleEmpty ||
@@ -247,6 +265,74 @@ func (d *DefinitionOfDone) ToExampleTests() *ExampleTests {
 	}
 }
 
+// MergeExamples adds the DoD's example tests to those parsed from the original prompt
+// Duplicate calls are skipped; the prompt's inferred function name wins
+func (d *DefinitionOfDone) MergeExamples(examples *ExampleTests) *ExampleTests {
+	fromDoD := d.ToExampleTests()
+	if fromDoD == nil {
+		return examples
+	}
+	if examples == nil || len(examples.Tests) == 0 {
+		return fromDoD
+	}
+
+	merged := &ExampleTests{
+		Tests:        append([]TestCase(nil), examples.Tests...),
+		FunctionName: examples.FunctionName,
+	}
+	seen := make(map[string]bool, len(examples.Tests))
+	for _, tc := range examples.Tests {
+		seen[tc.FunctionCall] = true
+	}
+	for _, tc := range fromDoD.Tests {
+		if !seen[tc.FunctionCall] {
+			merged.Tests = append(merged.Tests, tc)
+		}
+	}
+	return merged
+}
+
+// PromptSection describes the requirements for the generation prompt
+// Returns empty string if there is nothing testable
+func (d *DefinitionOfDone) PromptSection() string {
+	if d == nil || !d.HasTestableRequirements() {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("DEFINITION OF DONE (enforced by the validation gates):\n")
+	for _, tc := range d.Examples {
+		sb.WriteString(fmt.Sprintf("- %s must return %s\n", tc.FunctionCall, tc.Expected))
+	}
+	for _, p := range d.Properties {
+		sb.WriteString("- " + p.Description + "\n")
+	}
+	if d.HandleEmpty {
+		sb.WriteString("- Handle empty input\n")
+	}
+	if d.HandleNegative {
+		sb.WriteString("- Handle negative numbers\n")
+	}
+	if d.ThreadSafe {
+		sb.WriteString("- Must be thread-safe (exercise it from multiple threads in main so TSAN can verify)\n")
+	}
+	if d.MaxTimeMs > 0 {
+		sb.WriteString(fmt.Sprintf("- %d calls must complete in under %dms at -O2\n", d.BenchmarkN, d.MaxTimeMs))
+	}
+	if in := d.Input; !in.empty() {
+		sb.WriteString("- main is run with this input; read it from std::cin and argv rather than hard-coding it:\n" +
+			indentLines(strings.Join(in.Describe(), "\n"), "    ") + "\n")
+	}
+	if e := d.ExpectedOutput; e != nil {
+		if e.Regex {
+			sb.WriteString("- main's stdout must match this regular expression in full: " + e.Text + "\n")
+		} else {
+			sb.WriteString("- main must print exactly this to stdout (diagnostics go to stderr):\n" + indentLines(normalizeOutput(e.Text), "    ") + "\n")
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
 // GenerateBenchmarkHarness creates a benchmark test for performance requirements
 func (d *DefinitionOfDone) GenerateBenchmarkHarness(code, funcName string) string {
 	if d.MaxTimeMs == 0 {
@@ -254,10 +340,7 @@ func (d *DefinitionOfDone) GenerateBenchmarkHarness(code, funcName string) strin
 	}
 
 	var sb strings.Builder
-
-	sb.WriteString("#include <iostream>\n")
-	sb.WriteString("#include <chrono>\n")
-	sb.WriteString("#include <vector>\n\n")
+	sb.WriteString(benchmarkPreamble)
 
 	// Include user code (strip main)
 	userCode := stripMainFunction(code)
@@ -265,6 +348,26 @@ func (d *DefinitionOfDone) GenerateBenchmarkHarness(code, funcName string) strin
 	sb.WriteString(userCode)
 	sb.WriteString("\n\n")
 
+	sb.WriteString(d.benchmarkMain(funcName))
+	return sb.String()
+}
+
+// GenerateProjectBenchmark rewrites a multi-file project to benchmark funcName
+// Returns nil if there are no performance requirements
+func (d *DefinitionOfDone) GenerateProjectBenchmark(files []CodeFile, funcName string) []CodeFile {
+	if d.MaxTimeMs == 0 {
+		return nil
+	}
+	return GenerateProjectHarness(files, benchmarkPreamble, d.benchmarkMain(funcName))
+}
+
+// benchmarkPreamble holds the includes needed by the benchmark main
+const benchmarkPreamble = "#include <iostream>\n#include <chrono>\n#include <vector>\n\n"
+
+// benchmarkMain returns a main() that times BenchmarkN calls against MaxTimeMs
+func (d *DefinitionOfDone) benchmarkMain(funcName string) string {
+	var sb strings.Builder
+
 	// Generate benchmark main
 	sb.WriteString("int main() {\n")
 	sb.WriteString("    using namespace std::chrono;\n\n")
@@ -328,6 +431,12 @@ func (d *DefinitionOfDone) FormatDoDSummary() string {
 	if d.MaxTimeMs > 0 {
 		parts = append(parts, fmt.Sprintf("<%dms for %d items", d.MaxTimeMs, d.BenchmarkN))
 	}
+	if d.ExpectedOutput != nil {
+		parts = append(parts, "expected output")
+	}
+	if !d.Input.empty() {
+		parts = append(parts, "stdin/args input")
+	}
 
 	if len(parts) == 0 {
 		return "No testable requirements specified"
@@ -283,3 +283,77 @@ func TestDoDFormatSummaryWithProperties(t *testing.T) {
 		t.Error("Summary should mention idempotent property")
 	}
 }
+
+func TestDoDMergeExamples(t *testing.T) {
+	dod := &DefinitionOfDone{Examples: []TestCase{
+		{FunctionCall: "fact(5)", Expected: "120"},
+		{FunctionCall: "fact(0)", Expected: "1"},
+	}}
+
+	t.Run("no prompt examples", func(t *testing.T) {
+		got := dod.MergeExamples(nil)
+		if got == nil || len(got.Tests) != 2 || got.FunctionName != "fact" {
+			t.Errorf("MergeExamples(nil) = %+v, want DoD examples", got)
+		}
+	})
+
+	t.Run("merge skips duplicates", func(t *testing.T) {
+		prompt := &ExampleTests{FunctionName: "fact", Tests: []TestCase{{FunctionCall: "fact(5)", Expected: "120"}}}
+		got := dod.MergeExamples(prompt)
+		if len(got.Tests) != 2 {
+			t.Errorf("merged %d tests, want 2", len(got.Tests))
+		}
+		if len(prompt.Tests) != 1 {
+			t.Error("MergeExamples modified the prompt's examples")
+		}
+	})
+
+	t.Run("no DoD examples", func(t *testing.T) {
+		prompt := &ExampleTests{FunctionName: "f"}
+		if got := (&DefinitionOfDone{ThreadSafe: true}).MergeExamples(prompt); got != prompt {
+			t.Error("MergeExamples should return the prompt examples unchanged")
+		}
+	})
+}
+
+func TestDoDPromptSection(t *testing.T) {
+	var none *DefinitionOfDone
+	if none.PromptSection() != "" || (&DefinitionOfDone{}).PromptSection() != "" {
+		t.Error("PromptSection() should be empty without testable requirements")
+	}
+
+	dod := &DefinitionOfDone{
+		Examples:   []TestCase{{FunctionCall: "fact(5)", Expected: "120"}},
+		ThreadSafe: true,
+		MaxTimeMs:  100,
+		BenchmarkN: 10000,
+	}
+	section := dod.PromptSection()
+	for _, want := range []string{"DEFINITION OF DONE", "fact(5) must return 120", "thread-safe", "10000 calls must complete in under 100ms"} {
+		if !strings.Contains(section, want) {
+			t.Errorf("PromptSection() missing %q:\n%s", want, section)
+		}
+	}
+}
+
+func TestGenerateProjectBenchmark(t *testing.T) {
+	files := []CodeFile{
+		{Filename: "sum.h", Content: "#pragma once\nint sum();\n"},
+		{Filename: "sum.cpp", Content: "#include \"sum.h\"\nint sum() { return 1; }\nint main() { return sum(); }\n"},
+	}
+
+	if got := (&DefinitionOfDone{}).GenerateProjectBenchmark(files, "sum()"); got != nil {
+		t.Error("no performance requirement should yield no benchmark")
+	}
+
+	dod := &DefinitionOfDone{MaxTimeMs: 50, BenchmarkN: 500}
+	out := dod.GenerateProjectBenchmark(files, "sum()")
+	if len(out) != 2 {
+		t.Fatalf("got %d files, want 2", len(out))
+	}
+	for _, want := range []string{"#include <chrono>", "const int N = 500", "const int MAX_MS = 50", "sum();"} {
+		if !strings.Contains(out[1].Content, want) {
+			t.Errorf("benchmark sum.cpp missing %q", want)
+		}
+	}
+}
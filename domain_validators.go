@@ -3,6 +3,8 @@ package main
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -14,6 +16,7 @@ type DomainValidationResult struct {
 	Success     bool
 	Output      string
 	Metrics     map[string]interface{} // Domain-specific metrics (e.g., latency values, memory usage)
+	Report      string                 // Full report to save under .bjarne/reports, when asked for
 }
 
 // RunDomainValidators executes enabled domain-specific validators
@@ -44,7 +47,11 @@ func (c *ContainerRuntime) RunDomainValidators(ctx context.Context, tmpDir strin
 		results = append(results, result)
 	}
 	if config.IsEnabled(ValidatorCache) {
-		result := c.runCacheValidator(ctx, tmpDir, code, filename)
+		result := c.runCacheValidator(ctx, tmpDir, code, filename, config.GetArg(ValidatorCache))
+		results = append(results, result)
+	}
+	if config.IsEnabled(ValidatorAllocCount) {
+		result := c.runAllocCountValidator(ctx, tmpDir, code, filename, config.GetArg(ValidatorAllocCount))
 		results = append(results, result)
 	}
 
@@ -98,9 +105,60 @@ func (c *ContainerRuntime) RunDomainValidators(ctx context.Context, tmpDir strin
 		results = append(results, result)
 	}
 
+	// GPU / CUDA validators
+	if config.IsEnabled(ValidatorCUDACompile) {
+		result := c.runCUDACompileValidator(ctx, tmpDir, code, filename)
+		results = append(results, result)
+	}
+	if config.IsEnabled(ValidatorComputeSanitizer) {
+		result := c.runComputeSanitizerValidator(ctx, tmpDir, code, filename)
+		results = append(results, result)
+	}
+	if config.IsEnabled(ValidatorCUDAStatic) {
+		result := runCUDAStaticValidator(code, filename)
+		results = append(results, result)
+	}
+
+	// Plugins from ~/.bjarne/validators
+	for _, p := range config.Plugins {
+		if config.IsEnabled(p.ID) {
+			results = append(results, c.runPluginValidator(ctx, tmpDir, filename, p, config.GetArg(p.ID)))
+		}
+	}
+
 	return results
 }
 
+// RunDomainValidatorsOn writes the file with main() (else the first) to a temp directory and runs
+// the enabled domain validators on it
+func (c *ContainerRuntime) RunDomainValidatorsOn(ctx context.Context, files []CodeFile, config *ValidatorConfig) []DomainValidationResult {
+	if len(files) == 0 {
+		return nil
+	}
+	tmpDir, err := os.MkdirTemp("", "bjarne-domain-*")
+	if err != nil {
+		return nil
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	code, filename := files[0].Content, files[0].Filename
+	for _, f := range files {
+		if mainFunctionPattern.MatchString(f.Content) {
+			code, filename = f.Content, f.Filename
+			break
+		}
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, filename), []byte(code), 0600); err != nil {
+		return nil
+	}
+	return c.RunDomainValidators(ctx, tmpDir, code, filename, config)
+}
+
+// domainStageResult reports a domain validator as a validation stage
+func domainStageResult(dr DomainValidationResult) ValidationResult {
+	return ValidationResult{Stage: string(dr.ValidatorID), Success: dr.Success, Output: dr.Output}
+}
+
 // =============================================================================
 // F-010: Game Development Validators
 // =============================================================================
@@ -267,7 +325,8 @@ func (c *ContainerRuntime) runLockFreeValidator(ctx context.Context, tmpDir, cod
 }
 
 // runCacheValidator checks cache-friendly access patterns
-func (c *ContainerRuntime) runCacheValidator(ctx context.Context, tmpDir, code, filename string) DomainValidationResult {
+// With c2c=on, threaded code is also run under perf c2c to measure the false sharing it really has
+func (c *ContainerRuntime) runCacheValidator(ctx context.Context, tmpDir, code, filename, arg string) DomainValidationResult {
 	var warnings []string
 
 	// Static analysis for cache-unfriendly patterns
@@ -296,6 +355,18 @@ func (c *ContainerRuntime) runCacheValidator(ctx context.Context, tmpDir, code,
 		fmt.Sprintf(`clang++ -std=c++17 -O2 -o /tmp/cache_test /src/%s &&
 		echo "Cache analysis complete"`, filename))
 
+	var metrics map[string]interface{}
+	if value, _ := argValue(arg, "c2c"); result.Success && value == "on" {
+		findings, c2cMetrics, note := c.runC2CCheck(func(stage string, command ...string) ValidationResult {
+			return c.runValidationStage(ctx, tmpDir, stage, command...)
+		}, code, filename)
+		warnings = append(warnings, findings...)
+		if note != "" {
+			warnings = append(warnings, note)
+		}
+		metrics = c2cMetrics
+	}
+
 	output := strings.Join(warnings, "\n")
 	if output != "" {
 		output += "\n"
@@ -306,6 +377,7 @@ func (c *ContainerRuntime) runCacheValidator(ctx context.Context, tmpDir, code,
 		ValidatorID: ValidatorCache,
 		Success:     result.Success,
 		Output:      output,
+		Metrics:     metrics,
 	}
 }
 
@@ -402,82 +474,76 @@ func (c *ContainerRuntime) runInterruptValidator(ctx context.Context, tmpDir, co
 	}
 }
 
-// runRealTimeValidator checks real-time constraints (WCET)
-func (c *ContainerRuntime) runRealTimeValidator(ctx context.Context, tmpDir, code, filename, arg string) DomainValidationResult {
+// runRealTimeValidator checks real-time constraints (WCET) on the code's LLVM IR: loops without
+// provable bounds, recursion, and dynamic dispatch or allocation on hot paths
+func (c *ContainerRuntime) runRealTimeValidator(ctx context.Context, tmpDir, code, filename, arg string) DomainValidationResult { //nolint:unparam // code reserved for future use
 	deadlineUs := 1000
-	if arg != "" {
-		if us, err := parseArg(arg, "deadline_us"); err == nil {
-			deadlineUs = us
-		}
-	}
-
-	// Check for unbounded operations
-	var warnings []string
-	unboundedPatterns := []struct {
-		pattern string
-		warning string
-	}{
-		{"std::vector", "INFO: std::vector may have unbounded allocation time"},
-		{"std::string", "INFO: std::string may have unbounded allocation time"},
-		{"while (true)", "WARNING: Unbounded loop detected - ensure exit condition exists"},
-		{"for (;;)", "WARNING: Unbounded loop detected"},
-		{"malloc", "WARNING: Dynamic allocation has unbounded WCET"},
-		{"new ", "WARNING: Dynamic allocation has unbounded WCET"},
-	}
-
-	for _, p := range unboundedPatterns {
-		if strings.Contains(code, p.pattern) {
-			warnings = append(warnings, p.warning)
-		}
+	if us, err := parseArg(arg, "deadline_us"); err == nil {
+		deadlineUs = us
 	}
+	value, _ := argValue(arg, "strict")
+	strict, _ := strconv.ParseBool(value)
 
+	// -O1 puts loops in the canonical form the pass reads without unrolling most of them away
 	result := c.runValidationStage(ctx, tmpDir, "real-time",
 		"sh", "-c",
-		fmt.Sprintf(`clang++ -std=c++17 -O2 -o /tmp/rt_test /src/%s &&
-		echo "Real-time analysis (deadline: %dus):" &&
-		echo "Note: Full WCET analysis requires specialized tools"`, filename, deadlineUs))
+		fmt.Sprintf("%s && echo '%s' && cat /tmp/rt.ll",
+			c.cxx(c.stdFlag()+" -O1 -g -fno-discard-value-names -S -emit-llvm -o /tmp/rt.ll /src/"+filename), wcetIRMarker))
+	if !result.Success {
+		return DomainValidationResult{ValidatorID: ValidatorRealTime, Output: result.Output + result.Error}
+	}
 
-	output := strings.Join(warnings, "\n")
-	if output != "" {
-		output += "\n"
+	_, ir, _ := strings.Cut(result.Output, wcetIRMarker)
+	analysis := analyzeWCET(ir, filename)
+	var lines []string
+	for _, f := range analysis.Findings {
+		lines = append(lines, f.String(filename))
+	}
+	lines = append(lines, analysis.Summary(deadlineUs))
+	if !strict && analysis.warnings() > 0 {
+		lines = append(lines, "(advisory: reported without failing validation; set strict=true to fail on warnings)")
 	}
-	output += result.Output
 
 	return DomainValidationResult{
 		ValidatorID: ValidatorRealTime,
-		Success:     result.Success,
-		Output:      output,
-		Metrics:     map[string]interface{}{"deadline_us": deadlineUs},
+		Success:     !strict || analysis.warnings() == 0,
+		Output:      strings.Join(lines, "\n"),
+		Metrics:     analysis.Metrics(deadlineUs),
 	}
 }
 
-// runROMSizeValidator checks binary size for embedded targets
+// runROMSizeValidator checks binary size for embedded targets and profiles it by symbol:
+// the largest symbols, templates instantiated into bloat, and functions over a size budget
 func (c *ContainerRuntime) runROMSizeValidator(ctx context.Context, tmpDir, code, filename, arg string) DomainValidationResult { //nolint:unparam // code reserved for future use
-	maxKB := 256
-	if arg != "" {
-		if kb, err := parseArg(arg, "max_kb"); err == nil {
-			maxKB = kb
-		}
+	maxKB, maxFnBytes, top := 256, 0, 10
+	if kb, err := parseArg(arg, "max_kb"); err == nil {
+		maxKB = kb
+	}
+	if n, err := parseArg(arg, "max_fn_bytes"); err == nil {
+		maxFnBytes = n
 	}
+	if n, err := parseArg(arg, "top"); err == nil {
+		top = n
+	}
+	value, _ := argValue(arg, "report")
+	report, _ := strconv.ParseBool(value)
 
-	result := c.runValidationStage(ctx, tmpDir, "rom-size",
-		"sh", "-c",
-		fmt.Sprintf(`clang++ -std=c++17 -Os -ffunction-sections -fdata-sections -Wl,--gc-sections -o /tmp/rom_test /src/%s &&
-		SIZE=$(stat -c%%s /tmp/rom_test 2>/dev/null || stat -f%%z /tmp/rom_test)
-		SIZE_KB=$((SIZE / 1024))
-		echo "Binary size: ${SIZE} bytes (${SIZE_KB} KB)"
-		if [ $SIZE_KB -gt %d ]; then
-			echo "ERROR: Binary size ${SIZE_KB}KB exceeds limit %dKB"
-			exit 1
-		fi
-		echo "ROM size check PASSED: ${SIZE_KB}KB <= %dKB"`, filename, maxKB, maxKB, maxKB))
+	result := c.runValidationStage(ctx, tmpDir, "rom-size", "sh", "-c", c.sizeProfileCommand(filename))
+	if !result.Success {
+		return DomainValidationResult{ValidatorID: ValidatorROMSize, Output: result.Output + result.Error}
+	}
 
-	return DomainValidationResult{
+	profile := parseSizeProfile(result.Output)
+	dr := DomainValidationResult{
 		ValidatorID: ValidatorROMSize,
-		Success:     result.Success,
-		Output:      result.Output,
-		Metrics:     map[string]interface{}{"max_kb": maxKB},
+		Success:     profile.Binary/1024 <= maxKB && len(profile.overBudget(maxFnBytes)) == 0,
+		Output:      profile.Summary(maxKB, maxFnBytes, top),
+		Metrics:     profile.Metrics(maxKB, maxFnBytes),
 	}
+	if report {
+		dr.Report = profile.Report()
+	}
+	return dr
 }
 
 // =============================================================================
@@ -625,11 +691,7 @@ func (c *ContainerRuntime) runBenchmarkValidator(ctx context.Context, tmpDir, co
 	// baseline arg could specify a baseline file to compare against
 	_ = arg
 
-	// Check for benchmark::State
-	hasBenchmark := strings.Contains(code, "benchmark::State") ||
-		strings.Contains(code, "BENCHMARK(")
-
-	if !hasBenchmark {
+	if !usesGoogleBenchmark(code) {
 		return DomainValidationResult{
 			ValidatorID: ValidatorBenchmark,
 			Success:     true,
@@ -649,6 +711,11 @@ func (c *ContainerRuntime) runBenchmarkValidator(ctx context.Context, tmpDir, co
 	}
 }
 
+// usesGoogleBenchmark reports whether the code defines Google Benchmark benchmarks
+func usesGoogleBenchmark(code string) bool {
+	return strings.Contains(code, "benchmark::State") || strings.Contains(code, "BENCHMARK(")
+}
+
 // runMemProfileValidator profiles memory usage
 func (c *ContainerRuntime) runMemProfileValidator(ctx context.Context, tmpDir, code, filename string) DomainValidationResult { //nolint:unparam // code reserved for future use
 	result := c.runValidationStage(ctx, tmpDir, "mem-prof",
@@ -715,12 +782,24 @@ func (c *ContainerRuntime) runFlameGraphValidator(ctx context.Context, tmpDir, c
 
 // parseArg extracts an integer value from arg string like "key=value"
 func parseArg(arg, key string) (int, error) {
-	parts := strings.Split(arg, "=")
-	if len(parts) != 2 {
-		return 0, fmt.Errorf("invalid arg format")
+	value, err := argValue(arg, key)
+	if err != nil {
+		return 0, err
 	}
-	if parts[0] != key {
-		return 0, fmt.Errorf("key mismatch")
+	return strconv.Atoi(value)
+}
+
+// argValue finds key's value in a validator argument of space- or comma-separated key=value pairs
+func argValue(arg, key string) (string, error) {
+	fields := strings.FieldsFunc(arg, func(r rune) bool { return r == ' ' || r == ',' })
+	for _, field := range fields {
+		k, v, ok := strings.Cut(field, "=")
+		if !ok {
+			return "", fmt.Errorf("invalid arg format")
+		}
+		if k == key {
+			return v, nil
+		}
 	}
-	return strconv.Atoi(parts[1])
+	return "", fmt.Errorf("key mismatch")
 }
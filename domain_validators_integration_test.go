@@ -155,7 +155,7 @@ int main() {
 		t.Fatalf("Failed to write code: %v", err)
 	}
 
-	result := container.runCacheValidator(ctx, tmpDir, cacheUnfriendlyCode, "cache.cpp")
+	result := container.runCacheValidator(ctx, tmpDir, cacheUnfriendlyCode, "cache.cpp", "")
 
 	t.Logf("Cache validator output:\n%s", result.Output)
 
@@ -146,6 +146,9 @@ func TestParseArg(t *testing.T) {
 		{"wrong_key=100", "max_kb", 0, true},
 		{"invalid", "max_kb", 0, true},
 		{"max_kb=notanumber", "max_kb", 0, true},
+		{"max_kb=128 max_fn_bytes=512", "max_fn_bytes", 512, false},
+		{"max_kb=128,top=5", "top", 5, false},
+		{"max_kb=128 top", "top", 0, true},
 	}
 
 	for _, tt := range tests {
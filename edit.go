@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// editorCommand builds the command that opens paths in the user's editor
+// Uses $VISUAL, then $EDITOR (which may include arguments, e.g. "code --wait"), then a platform default
+func editorCommand(paths []string) *exec.Cmd {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+
+	args := strings.Fields(editor)
+	if len(args) == 0 {
+		if runtime.GOOS == "windows" {
+			args = []string{"notepad"}
+		} else {
+			args = []string{"vi"}
+		}
+	}
+
+	args = append(args, paths...)
+	cmd := exec.Command(args[0], args[1:]...)
+	if plainMode {
+		cmd.Stdout, cmd.Stderr = terminalOutput() // The editor needs the terminal, not plain mode's filter
+	}
+	return cmd
+}
+
+// writeEditFiles writes files to a fresh temp directory for editing
+// Returns the directory and the paths in the same order as files
+func writeEditFiles(files []CodeFile) (string, []string, error) {
+	dir, err := os.MkdirTemp("", "bjarne-edit-*")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+
+	paths := make([]string, 0, len(files))
+	for _, f := range files {
+		path, err := editFilePath(dir, f.Filename)
+		if err == nil {
+			err = os.MkdirAll(filepath.Dir(path), 0700)
+		}
+		if err != nil {
+			_ = os.RemoveAll(dir)
+			return "", nil, err
+		}
+		if err := os.WriteFile(path, []byte(f.Content), 0600); err != nil {
+			_ = os.RemoveAll(dir)
+			return "", nil, fmt.Errorf("failed to write %s: %w", f.Filename, err)
+		}
+		paths = append(paths, path)
+	}
+	return dir, paths, nil
+}
+
+// readEditFiles reads edited files back from dir, keeping the original filenames
+func readEditFiles(dir string, files []CodeFile) ([]CodeFile, error) {
+	edited := make([]CodeFile, 0, len(files))
+	for _, f := range files {
+		path, err := editFilePath(dir, f.Filename)
+		if err != nil {
+			return nil, err
+		}
+		content, err := os.ReadFile(path) //nolint:gosec // editFilePath keeps it inside dir
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", f.Filename, err)
+		}
+		edited = append(edited, CodeFile{Filename: f.Filename, Content: string(content)})
+	}
+	return edited, nil
+}
+
+// editFilePath places a file under dir by its relative path, so src/util.h and include/util.h
+// stay apart; absolute paths and ones leaving dir are rejected
+func editFilePath(dir, filename string) (string, error) {
+	rel := filepath.Clean(filepath.FromSlash(filename))
+	if rel == "." || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("cannot edit %s: not a relative path inside the project", filename)
+	}
+	return filepath.Join(dir, rel), nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEditorCommand(t *testing.T) {
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "code --wait")
+
+	cmd := editorCommand([]string{"/tmp/a.cpp", "/tmp/b.h"})
+	want := []string{"code", "--wait", "/tmp/a.cpp", "/tmp/b.h"}
+	if len(cmd.Args) != len(want) {
+		t.Fatalf("Args = %v, want %v", cmd.Args, want)
+	}
+	for i := range want {
+		if cmd.Args[i] != want[i] {
+			t.Errorf("Args[%d] = %q, want %q", i, cmd.Args[i], want[i])
+		}
+	}
+
+	t.Setenv("VISUAL", "nano")
+	if cmd := editorCommand([]string{"x.cpp"}); cmd.Args[0] != "nano" {
+		t.Errorf("VISUAL should take precedence over EDITOR, got %q", cmd.Args[0])
+	}
+}
+
+func TestEditFilesRoundTrip(t *testing.T) {
+	files := []CodeFile{
+		{Filename: "main.cpp", Content: "int main() { return 0; }\n"},
+		{Filename: "util.h", Content: "#pragma once\n"},
+	}
+
+	dir, paths, err := writeEditFiles(files)
+	if err != nil {
+		t.Fatalf("writeEditFiles: %v", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	if len(paths) != 2 || filepath.Base(paths[1]) != "util.h" {
+		t.Fatalf("unexpected paths: %v", paths)
+	}
+
+	if err := os.WriteFile(paths[0], []byte("int main() { return 1; }\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	edited, err := readEditFiles(dir, files)
+	if err != nil {
+		t.Fatalf("readEditFiles: %v", err)
+	}
+	if edited[0].Content != "int main() { return 1; }\n" || edited[1].Content != files[1].Content {
+		t.Errorf("unexpected edited content: %+v", edited)
+	}
+}
+
+func TestEditFilesSameBaseName(t *testing.T) {
+	files := []CodeFile{
+		{Filename: "src/util.h", Content: "// src\n"},
+		{Filename: "include/util.h", Content: "// include\n"},
+	}
+	dir, _, err := writeEditFiles(files)
+	if err != nil {
+		t.Fatalf("writeEditFiles: %v", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	edited, err := readEditFiles(dir, files)
+	if err != nil {
+		t.Fatalf("readEditFiles: %v", err)
+	}
+	if edited[0].Content != "// src\n" || edited[1].Content != "// include\n" {
+		t.Errorf("edited = %+v; want each file's own content", edited)
+	}
+
+	for _, name := range []string{"../escape.h", "src/../../escape.h", "/etc/passwd", ""} {
+		if dir, _, err := writeEditFiles([]CodeFile{{Filename: name}}); err == nil {
+			_ = os.RemoveAll(dir)
+			t.Errorf("writeEditFiles(%q) succeeded", name)
+		}
+	}
+}
@@ -244,3 +244,6 @@ func getONNXSearchPaths() []string {
 
 	return paths
 }
+
+// onnxCompiledIn reports whether this build includes the ONNX embedder
+const onnxCompiledIn = true
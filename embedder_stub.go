@@ -17,3 +17,6 @@ func newONNXBackend(_ string) (EmbedderBackend, error) {
 func isONNXAvailable() bool {
 	return false
 }
+
+// onnxCompiledIn reports whether this build includes the ONNX embedder
+const onnxCompiledIn = false
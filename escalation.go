@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// defaultRungAttempts is the number of fix attempts per model when escalationAttempts doesn't say
+const defaultRungAttempts = 5
+
+// escalationStep is one rung of the escalation ladder
+type escalationStep struct {
+	Model    string
+	Attempts int
+}
+
+// buildEscalationLadder builds the fix ladder: the base (complexity) model, then each escalation model
+// attempts[0] is for the base model and attempts[i+1] for escalation[i]; missing or invalid entries use defaultRungAttempts
+// Rungs at or below the base model's position in the ladder keep using the base model (no de-escalation)
+func buildEscalationLadder(base string, escalation []string, attempts []int) []escalationStep {
+	count := func(i int) int {
+		if i < len(attempts) && attempts[i] > 0 {
+			return attempts[i]
+		}
+		return defaultRungAttempts
+	}
+
+	baseIdx := -1
+	for i, model := range escalation {
+		if model == base {
+			baseIdx = i
+		}
+	}
+
+	ladder := []escalationStep{{Model: base, Attempts: count(0)}}
+	for i, model := range escalation {
+		if i <= baseIdx {
+			model = base
+		}
+		ladder = append(ladder, escalationStep{Model: model, Attempts: count(i + 1)})
+	}
+	return ladder
+}
+
+// ladderAttempts is the total number of fix attempts across the ladder
+func ladderAttempts(ladder []escalationStep) int {
+	total := 0
+	for _, step := range ladder {
+		total += step.Attempts
+	}
+	return total
+}
+
+// modelForAttempt returns the model for a 1-based fix attempt (0 = initial generation)
+func modelForAttempt(ladder []escalationStep, attempt int) string {
+	if len(ladder) == 0 {
+		return ""
+	}
+	for _, step := range ladder {
+		if attempt <= step.Attempts {
+			return step.Model
+		}
+		attempt -= step.Attempts
+	}
+	return ladder[len(ladder)-1].Model
+}
+
+// parseModelList parses a comma-separated model list ("sonnet, opus")
+func parseModelList(s string) []string {
+	var models []string
+	for _, m := range strings.Split(s, ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			models = append(models, m)
+		}
+	}
+	return models
+}
+
+// parseAttemptList parses comma-separated attempt counts ("5,5,5")
+func parseAttemptList(s string) ([]int, error) {
+	var counts []int
+	for _, field := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid attempt count %q (must be a positive number)", field)
+		}
+		counts = append(counts, n)
+	}
+	return counts, nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"path/filepath"
 	"regexp"
 	"strings"
 )
@@ -92,7 +93,7 @@ func ParseExampleTests(prompt string) *ExampleTests {
 
 			// For I/O patterns, we'll use a placeholder that needs to be filled
 			tests = append(tests, TestCase{
-				FunctionCall: fmt.Sprintf("FUNCTION(%s)", input),
+				FunctionCall: fmt.Sprintf("%s(%s)", placeholderFunction, input),
 				Expected:     output,
 				Line:         i + 1,
 			})
@@ -109,6 +110,84 @@ func ParseExampleTests(prompt string) *ExampleTests {
 	}
 }
 
+// placeholderFunction stands in for the function name in Input/Output examples
+const placeholderFunction = "FUNCTION"
+
+// ParseTestCase parses a single example such as `fact(5) -> 120` (same formats as prompts)
+func ParseTestCase(line string) (TestCase, bool) {
+	parsed := ParseExampleTests(line)
+	if parsed == nil || len(parsed.Tests) != 1 {
+		return TestCase{}, false
+	}
+	tc := parsed.Tests[0]
+	tc.Line = 0 // Not from the prompt
+	return tc, true
+}
+
+// testFunctionName returns the function called by a test case
+func testFunctionName(tc TestCase) string {
+	name, _, _ := strings.Cut(tc.FunctionCall, "(")
+	return strings.TrimSpace(name)
+}
+
+// inferFunctionName sets FunctionName from the first test with a real function name
+func (e *ExampleTests) inferFunctionName() {
+	e.FunctionName = ""
+	for _, tc := range e.Tests {
+		if name := testFunctionName(tc); name != "" && name != placeholderFunction {
+			e.FunctionName = name
+			return
+		}
+	}
+}
+
+// AddTest appends a test case
+func (e *ExampleTests) AddTest(tc TestCase) {
+	e.Tests = append(e.Tests, tc)
+	e.inferFunctionName()
+}
+
+// SetTest replaces test n (1-based)
+func (e *ExampleTests) SetTest(n int, tc TestCase) error {
+	if n < 1 || n > len(e.Tests) {
+		return fmt.Errorf("no test %d (have %d)", n, len(e.Tests))
+	}
+	e.Tests[n-1] = tc
+	e.inferFunctionName()
+	return nil
+}
+
+// RemoveTest deletes test n (1-based)
+func (e *ExampleTests) RemoveTest(n int) error {
+	if n < 1 || n > len(e.Tests) {
+		return fmt.Errorf("no test %d (have %d)", n, len(e.Tests))
+	}
+	e.Tests = append(e.Tests[:n-1], e.Tests[n:]...)
+	e.inferFunctionName()
+	return nil
+}
+
+// FormatTable renders the tests as numbered "call → expected" rows
+// Calls using the Input/Output placeholder are flagged, since they cannot compile as-is
+func (e *ExampleTests) FormatTable() []string {
+	width := 0
+	for _, tc := range e.Tests {
+		if len(tc.FunctionCall) > width {
+			width = len(tc.FunctionCall)
+		}
+	}
+
+	lines := make([]string, 0, len(e.Tests))
+	for i, tc := range e.Tests {
+		line := fmt.Sprintf("%2d  %-*s  →  %s", i+1, width, tc.FunctionCall, tc.Expected)
+		if testFunctionName(tc) == placeholderFunction {
+			line += "   (needs a function name: /tests set " + fmt.Sprint(i+1) + " ...)"
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
 // GenerateTestHarness creates a C++ test harness for the example tests
 // The harness wraps the user's code and validates it against the examples
 func GenerateTestHarness(code string, examples *ExampleTests) string {
@@ -116,6 +195,21 @@ func GenerateTestHarness(code string, examples *ExampleTests) string {
 		return code
 	}
 
+	var sb strings.Builder
+	sb.WriteString(testHarnessPreamble())
+
+	// Include the user's code, but strip their main() if present
+	userCode := stripMainFunction(code)
+	sb.WriteString("// User code (main stripped)\n")
+	sb.WriteString(userCode)
+	sb.WriteString("\n\n")
+
+	sb.WriteString(testHarnessMain(examples))
+	return sb.String()
+}
+
+// testHarnessPreamble returns the includes and EXPECT_EQ framework used by test harnesses
+func testHarnessPreamble() string {
 	var sb strings.Builder
 
 	// Add iostream for test output
@@ -140,13 +234,13 @@ func GenerateTestHarness(code string, examples *ExampleTests) string {
 	sb.WriteString("    } \\\n")
 	sb.WriteString("} while(0)\n\n")
 
-	// Include the user's code, but strip their main() if present
-	userCode := stripMainFunction(code)
-	sb.WriteString("// User code (main stripped)\n")
-	sb.WriteString(userCode)
-	sb.WriteString("\n\n")
+	return sb.String()
+}
+
+// testHarnessMain returns a main() that runs each example and reports the results
+func testHarnessMain(examples *ExampleTests) string {
+	var sb strings.Builder
 
-	// Generate test main
 	sb.WriteString("// Generated test main\n")
 	sb.WriteString("int main() {\n")
 	sb.WriteString("    std::cout << \"Running example tests...\" << std::endl;\n")
@@ -154,7 +248,11 @@ func GenerateTestHarness(code string, examples *ExampleTests) string {
 
 	for i, test := range examples.Tests {
 		testName := fmt.Sprintf("Test %d: %s", i+1, test.FunctionCall)
-		sb.WriteString(fmt.Sprintf("    // Test from line %d\n", test.Line))
+		if test.Line > 0 {
+			sb.WriteString(fmt.Sprintf("    // Test from line %d\n", test.Line))
+		} else {
+			sb.WriteString("    // Test added with /tests\n")
+		}
 		sb.WriteString(fmt.Sprintf("    EXPECT_EQ(%s, %s, \"%s\");\n\n",
 			test.FunctionCall, test.Expected, escapeString(testName)))
 	}
@@ -167,13 +265,98 @@ func GenerateTestHarness(code string, examples *ExampleTests) string {
 	return sb.String()
 }
 
+// harnessFilename is added to projects that have no main() to replace
+const harnessFilename = "bjarne_harness.cpp"
+
+// isSourceFile reports whether a file is compiled (as opposed to a header)
+func isSourceFile(name string) bool {
+	switch filepath.Ext(name) {
+	case ".cpp", ".cc", ".cxx", ".c":
+		return true
+	}
+	return false
+}
+
+// isHeaderFile reports whether a file is a header
+func isHeaderFile(name string) bool {
+	switch filepath.Ext(name) {
+	case ".h", ".hpp", ".hh", ".hxx":
+		return true
+	}
+	return false
+}
+
+// GenerateProjectHarness rewrites a multi-file project so that mainFunc is its entry point
+// The source file defining main() keeps the rest of its code, gains preamble, and includes
+// every project header so the functions under test are declared. Projects without a
+// main() get a separate harness file. All other files are returned unchanged.
+func GenerateProjectHarness(files []CodeFile, preamble, mainFunc string) []CodeFile {
+	var includes strings.Builder
+	for _, f := range files {
+		if isHeaderFile(f.Filename) {
+			includes.WriteString(fmt.Sprintf("#include \"%s\"\n", f.Filename))
+		}
+	}
+	header := preamble + includes.String() + "\n"
+
+	out := make([]CodeFile, 0, len(files)+1)
+	replaced := false
+	for _, f := range files {
+		if !replaced && isSourceFile(f.Filename) && mainFunctionPattern.MatchString(f.Content) {
+			content := header + "// User code (main stripped)\n" + stripMainFunction(f.Content) + "\n\n" + mainFunc
+			out = append(out, CodeFile{Filename: f.Filename, Content: content})
+			replaced = true
+			continue
+		}
+		out = append(out, f)
+	}
+	if !replaced {
+		out = append(out, CodeFile{Filename: harnessFilename, Content: header + mainFunc})
+	}
+	return out
+}
+
+// appendChecks appends code after the user's, following every project header, to the source file
+// defining main() (else the first source file), so the user's lines keep their numbers in diagnostics
+func appendChecks(files []CodeFile, marker string, checks []string) (CodeFile, bool) {
+	target := -1
+	for i, f := range files {
+		if !isSourceFile(f.Filename) {
+			continue
+		}
+		if target < 0 || mainFunctionPattern.MatchString(f.Content) {
+			target = i
+		}
+		if mainFunctionPattern.MatchString(f.Content) {
+			break
+		}
+	}
+	if target < 0 {
+		return CodeFile{}, false
+	}
+
+	var sb strings.Builder
+	sb.WriteString(strings.TrimRight(files[target].Content, "\n") + "\n\n" + marker + "\n")
+	for _, f := range files {
+		if isHeaderFile(f.Filename) {
+			sb.WriteString(fmt.Sprintf("#include \"%s\"\n", f.Filename))
+		}
+	}
+	for _, check := range checks {
+		sb.WriteString(check + "\n")
+	}
+	return CodeFile{Filename: files[target].Filename, Content: sb.String()}, true
+}
+
+// mainFunctionPattern matches the start of a main() definition
+var mainFunctionPattern = regexp.MustCompile(`(?s)\bint\s+main\s*\([^)]*\)\s*\{`)
+
 // stripMainFunction removes main() from user code to allow test harness to provide its own
 func stripMainFunction(code string) string {
 	// Simple approach: look for "int main" and remove the function
 	// This is a heuristic - a proper parser would be better
 
-	mainPattern := regexp.MustCompile(`(?s)\bint\s+main\s*\([^)]*\)\s*\{`)
-	loc := mainPattern.FindStringIndex(code)
+	loc := mainFunctionPattern.FindStringIndex(code)
 	if loc == nil {
 		return code
 	}
@@ -164,3 +164,105 @@ func TestHasExampleTests(t *testing.T) {
 		}
 	}
 }
+
+func TestParseTestCase(t *testing.T) {
+	tests := []struct {
+		line     string
+		wantCall string
+		wantExp  string
+		wantOK   bool
+	}{
+		{"fact(5) -> 120", "fact(5)", "120", true},
+		{`isPalindrome("aba") should return true`, `isPalindrome("aba")`, "true", true},
+		{"Input: 3 Output: 6", "FUNCTION(3)", "6", true},
+		{"make it fast", "", "", false},
+		{"f(1) -> 1\nf(2) -> 2", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			tc, ok := ParseTestCase(tt.line)
+			if ok != tt.wantOK || tc.FunctionCall != tt.wantCall || tc.Expected != tt.wantExp {
+				t.Errorf("ParseTestCase(%q) = %+v, %v; want %q -> %q, %v", tt.line, tc, ok, tt.wantCall, tt.wantExp, tt.wantOK)
+			}
+			if ok && tc.Line != 0 {
+				t.Errorf("Line = %d, want 0 for added tests", tc.Line)
+			}
+		})
+	}
+}
+
+func TestExampleTestsEditing(t *testing.T) {
+	e := ParseExampleTests("Input: 3 Output: 6\nInput: 4 Output: 8")
+	if e.FunctionName != "" {
+		t.Fatalf("FunctionName = %q, want empty for Input/Output examples", e.FunctionName)
+	}
+	table := e.FormatTable()
+	if len(table) != 2 || !strings.Contains(table[0], "needs a function name") {
+		t.Errorf("FormatTable() = %q, want placeholder flagged", table)
+	}
+
+	if err := e.SetTest(1, TestCase{FunctionCall: "twice(3)", Expected: "6"}); err != nil {
+		t.Fatal(err)
+	}
+	if e.FunctionName != "twice" {
+		t.Errorf("FunctionName = %q, want twice after set", e.FunctionName)
+	}
+
+	e.AddTest(TestCase{FunctionCall: "twice(0)", Expected: "0"})
+	if err := e.RemoveTest(2); err != nil {
+		t.Fatal(err)
+	}
+	if len(e.Tests) != 2 || e.Tests[1].FunctionCall != "twice(0)" {
+		t.Errorf("Tests = %+v, want twice(3), twice(0)", e.Tests)
+	}
+
+	if err := e.RemoveTest(5); err == nil {
+		t.Error("RemoveTest(5) should fail")
+	}
+	if err := e.SetTest(0, TestCase{}); err == nil {
+		t.Error("SetTest(0) should fail")
+	}
+
+	harness := GenerateTestHarness("int twice(int x) { return 2 * x; }", e)
+	if !strings.Contains(harness, "// Test added with /tests") {
+		t.Error("harness should mark tests added with /tests")
+	}
+}
+
+func TestGenerateProjectHarness(t *testing.T) {
+	examples := &ExampleTests{FunctionName: "add", Tests: []TestCase{{FunctionCall: "add(2, 3)", Expected: "5", Line: 1}}}
+	header := CodeFile{Filename: "math.h", Content: "#pragma once\nint add(int a, int b);\n"}
+	impl := CodeFile{Filename: "math.cpp", Content: "#include \"math.h\"\nint add(int a, int b) { return a + b; }\n"}
+
+	t.Run("replaces main", func(t *testing.T) {
+		mainFile := CodeFile{Filename: "main.cpp", Content: "#include \"math.h\"\nint helper() { return 1; }\nint main() { return add(1, helper()); }\n"}
+		out := GenerateProjectHarness([]CodeFile{header, impl, mainFile}, testHarnessPreamble(), testHarnessMain(examples))
+
+		if len(out) != 3 {
+			t.Fatalf("got %d files, want 3", len(out))
+		}
+		if out[0] != header || out[1] != impl {
+			t.Error("files without main() should be unchanged")
+		}
+		got := out[2].Content
+		for _, want := range []string{`#include "math.h"`, "int helper()", "EXPECT_EQ(add(2, 3), 5", "#define EXPECT_EQ"} {
+			if !strings.Contains(got, want) {
+				t.Errorf("harness main.cpp missing %q", want)
+			}
+		}
+		if strings.Count(got, "int main()") != 1 {
+			t.Error("original main() should be replaced by the generated one")
+		}
+	})
+
+	t.Run("library without main", func(t *testing.T) {
+		out := GenerateProjectHarness([]CodeFile{header, impl}, testHarnessPreamble(), testHarnessMain(examples))
+		if len(out) != 3 || out[2].Filename != harnessFilename {
+			t.Fatalf("want harness file appended, got %d files", len(out))
+		}
+		if !strings.Contains(out[2].Content, `#include "math.h"`) {
+			t.Error("harness should include the project headers")
+		}
+	})
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+// exceptionsMarker starts the exceptions gate's report of the allocation whose failure broke the program
+const exceptionsMarker = "bjarne: exceptions:"
+
+// exceptionsCountMarker starts the line the counting run prints its number of allocations on
+const exceptionsCountMarker = "bjarne-alloc-count:"
+
+// maxExceptionInjections bounds exceptions.maxInjections
+const maxExceptionInjections = 1000
+
+// exceptionShimFile is the failing allocator's name in the mounted source directory
+const exceptionShimFile = "bjarne_alloc_fail.cpp"
+
+// exceptionShim replaces the global operator new and delete. Allocation number BJARNE_ALLOC_FAIL
+// throws std::bad_alloc (the nothrow forms return nullptr) after printing where it was made;
+// with BJARNE_ALLOC_FAIL=0 nothing fails and the number of allocations is printed at exit
+const exceptionShim = `#include <cstddef>
+#include <cstdio>
+#include <cstdlib>
+#include <new>
+#include <sanitizer/common_interface_defs.h>
+
+namespace {
+unsigned long bjarne_alloc_count = 0;
+long bjarne_alloc_fail = -1;
+
+void* bjarne_alloc(std::size_t size, std::size_t align) {
+    if (bjarne_alloc_fail < 0) {
+        const char* env = std::getenv("BJARNE_ALLOC_FAIL");
+        bjarne_alloc_fail = env ? std::strtol(env, nullptr, 10) : 0;
+    }
+    unsigned long n = __atomic_add_fetch(&bjarne_alloc_count, 1ul, __ATOMIC_RELAXED);
+    if (bjarne_alloc_fail > 0 && n == static_cast<unsigned long>(bjarne_alloc_fail)) {
+        std::fprintf(stderr, "bjarne: allocation %lu throws std::bad_alloc, allocated at:\n", n);
+        __sanitizer_print_stack_trace();
+        throw std::bad_alloc();
+    }
+    void* p = nullptr;
+    if (align > alignof(std::max_align_t)) {
+        if (posix_memalign(&p, align, size ? size : 1) != 0) p = nullptr;
+    } else {
+        p = std::malloc(size ? size : 1);
+    }
+    if (!p) throw std::bad_alloc();
+    return p;
+}
+
+void* bjarne_alloc_nothrow(std::size_t size, std::size_t align) noexcept {
+    try {
+        return bjarne_alloc(size, align);
+    } catch (...) {
+        return nullptr;
+    }
+}
+
+struct bjarne_alloc_report {
+    ~bjarne_alloc_report() {
+        if (bjarne_alloc_fail <= 0) std::fprintf(stderr, "` + exceptionsCountMarker + ` %lu\n", bjarne_alloc_count);
+    }
+} bjarne_alloc_reporter;
+}
+
+void* operator new(std::size_t size) { return bjarne_alloc(size, 0); }
+void* operator new[](std::size_t size) { return bjarne_alloc(size, 0); }
+void* operator new(std::size_t size, const std::nothrow_t&) noexcept { return bjarne_alloc_nothrow(size, 0); }
+void* operator new[](std::size_t size, const std::nothrow_t&) noexcept { return bjarne_alloc_nothrow(size, 0); }
+void operator delete(void* p) noexcept { std::free(p); }
+void operator delete[](void* p) noexcept { std::free(p); }
+void operator delete(void* p, std::size_t) noexcept { std::free(p); }
+void operator delete[](void* p, std::size_t) noexcept { std::free(p); }
+void operator delete(void* p, const std::nothrow_t&) noexcept { std::free(p); }
+void operator delete[](void* p, const std::nothrow_t&) noexcept { std::free(p); }
+#if __cpp_aligned_new
+void* operator new(std::size_t size, std::align_val_t a) { return bjarne_alloc(size, static_cast<std::size_t>(a)); }
+void* operator new[](std::size_t size, std::align_val_t a) { return bjarne_alloc(size, static_cast<std::size_t>(a)); }
+void* operator new(std::size_t size, std::align_val_t a, const std::nothrow_t&) noexcept { return bjarne_alloc_nothrow(size, static_cast<std::size_t>(a)); }
+void* operator new[](std::size_t size, std::align_val_t a, const std::nothrow_t&) noexcept { return bjarne_alloc_nothrow(size, static_cast<std::size_t>(a)); }
+void operator delete(void* p, std::align_val_t) noexcept { std::free(p); }
+void operator delete[](void* p, std::align_val_t) noexcept { std::free(p); }
+void operator delete(void* p, std::size_t, std::align_val_t) noexcept { std::free(p); }
+void operator delete[](void* p, std::size_t, std::align_val_t) noexcept { std::free(p); }
+#endif
+`
+
+// exceptionsBrokenPattern matches what shows a failed allocation broke the program rather than ending it cleanly
+const exceptionsBrokenPattern = `ERROR: (Address|Leak)Sanitizer|Assertion .* failed`
+
+// exceptionsUncaughtPattern matches libc++ and libstdc++ terminating on the injected std::bad_alloc,
+// which ends the program without leaking or corrupting anything
+const exceptionsUncaughtPattern = `uncaught exception of type std::bad_alloc|instance of 'std::bad_alloc'`
+
+var exceptionsFailurePattern = regexp.MustCompile(regexp.QuoteMeta(exceptionsMarker) + ` allocation (\d+) of (\d+) failed \(exit (\d+)\)`)
+
+// SetExceptionSettings configures the exceptions gate
+func (c *ContainerRuntime) SetExceptionSettings(settings ExceptionSettings) {
+	c.exceptions = settings
+}
+
+// exceptionsEnabled reports whether the exceptions gate runs: exceptions.enabled, or asked for with --gates exceptions
+func (c *ContainerRuntime) exceptionsEnabled() bool {
+	return (c.exceptions.Enabled || slices.Contains(c.gates.Only, "exceptions")) && c.gates.Enabled("exceptions")
+}
+
+// exceptionInjections is exceptions.maxInjections, defaulting when unset
+func (c *ContainerRuntime) exceptionInjections() int {
+	if c.exceptions.MaxInjections > 0 {
+		return c.exceptions.MaxInjections
+	}
+	return DefaultSettings().Exceptions.MaxInjections
+}
+
+// exceptionsGate reruns the program with one allocation at a time throwing std::bad_alloc, after ASAN passed
+// srcArgs are the compiler's include flags and sources; it reports false when the gate is off
+func (c *ContainerRuntime) exceptionsGate(tmpDir, srcArgs string, runStage func(stage string, command ...string) ValidationResult) (ValidationResult, bool, error) {
+	if !c.exceptionsEnabled() {
+		return ValidationResult{}, false, nil
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, exceptionShimFile), []byte(exceptionShim), 0600); err != nil {
+		return ValidationResult{}, false, fmt.Errorf("failed to write failing allocator: %w", err)
+	}
+	return runStage("exceptions", "sh", "-c", c.exceptionsCommand(srcArgs)), true, nil
+}
+
+// exceptionsCommand builds the ASAN binary with the failing allocator, counts the program's allocations,
+// then fails each in turn, spreading exceptions.maxInjections runs evenly when there are more allocations.
+// A run may catch the exception or terminate on it; a sanitizer report, failed assertion, other crash
+// or hang stops the loop with exceptionsMarker and the allocation's number
+func (c *ContainerRuntime) exceptionsCommand(srcArgs string) string {
+	build := c.cxx(c.stdFlag() + " -fsanitize=address -fno-omit-frame-pointer -g -o /tmp/exceptions " + srcArgs + " /src/" + exceptionShimFile)
+	count := c.programCommand("BJARNE_ALLOC_FAIL=0 "+c.asanOptions(), "/tmp/exceptions")
+	run := c.programCommand("BJARNE_ALLOC_FAIL=$i "+c.asanOptions(), "/tmp/exceptions")
+	n := c.exceptionInjections()
+	return fmt.Sprintf("%s && { %s; } > /tmp/exceptions.log 2>&1; "+
+		"n=$(sed -n 's/^%s //p' /tmp/exceptions.log | tail -n 1); "+
+		"if [ -z \"$n\" ] || [ \"$n\" -eq 0 ]; then echo 'the program made no counted allocations, skipping'; exit 0; fi; "+
+		"step=$(( (n + %d - 1) / %d )); i=1; tested=0; "+
+		"while [ $i -le $n ]; do { %s; } > /tmp/exceptions.log 2>&1; s=$?; bad=0; "+
+		"grep -qE '%s|%s' /tmp/exceptions.log && bad=1; "+
+		"if [ $s -gt 128 ] && ! grep -qE \"%s\" /tmp/exceptions.log; then bad=1; fi; "+
+		"if [ $bad = 1 ]; then echo \"%s allocation $i of $n failed (exit $s)\" >&2; cat /tmp/exceptions.log >&2; exit 1; fi; "+
+		"tested=$((tested+1)); i=$((i+step)); done; "+
+		"echo \"$tested of $n allocation(s) made to throw std::bad_alloc: no leaks, crashes or failed assertions\"",
+		build, count, exceptionsCountMarker, n, n, run, exceptionsBrokenPattern, hangMarker, exceptionsUncaughtPattern, exceptionsMarker)
+}
+
+// ExceptionFailure is the injected allocation failure that broke the program
+type ExceptionFailure struct {
+	Allocation, Allocations, Exit int
+}
+
+// parseExceptionFailure finds the failing allocation in the exceptions gate's output, or returns nil
+func parseExceptionFailure(output string) *ExceptionFailure {
+	m := exceptionsFailurePattern.FindStringSubmatch(output)
+	if m == nil {
+		return nil
+	}
+	f := &ExceptionFailure{}
+	f.Allocation, _ = strconv.Atoi(m[1])
+	f.Allocations, _ = strconv.Atoi(m[2])
+	f.Exit, _ = strconv.Atoi(m[3])
+	return f
+}
+
+// promptText explains the failure for the fix prompt
+func (f *ExceptionFailure) promptText() string {
+	return fmt.Sprintf("Found by exception-safety testing: allocation %d of %d (counting every operator new) was made to throw std::bad_alloc, "+
+		"and the program then leaked, crashed or broke an invariant (exit %d). The stack of that allocation and the report follow. "+
+		"Make the code exception-safe: own resources with RAII (smart pointers, containers), change state only after everything that can throw has succeeded, "+
+		"and do not leave objects half-updated when an exception propagates.",
+		f.Allocation, f.Allocations, f.Exit)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestExceptionsEnabled(t *testing.T) {
+	tests := []struct {
+		name       string
+		exceptions ExceptionSettings
+		gates      GateSelection
+		want       bool
+	}{
+		{"off by default", ExceptionSettings{}, GateSelection{}, false},
+		{"enabled", ExceptionSettings{Enabled: true}, GateSelection{}, true},
+		{"asked for with --gates", ExceptionSettings{}, GateSelection{Only: []string{"asan", "exceptions"}}, true},
+		{"skipped", ExceptionSettings{Enabled: true}, GateSelection{Skip: []string{"exceptions"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &ContainerRuntime{exceptions: tt.exceptions, gates: tt.gates}
+			if got := c.exceptionsEnabled(); got != tt.want {
+				t.Errorf("exceptionsEnabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExceptionsGate(t *testing.T) {
+	c := &ContainerRuntime{exceptions: ExceptionSettings{Enabled: true, MaxInjections: 50}}
+	tmpDir := t.TempDir()
+	var command []string
+	result, ok, err := c.exceptionsGate(tmpDir, "/src/code.cpp", func(stage string, cmd ...string) ValidationResult {
+		command = cmd
+		return ValidationResult{Stage: stage, Success: true}
+	})
+	if err != nil || !ok || result.Stage != "exceptions" {
+		t.Fatalf("exceptionsGate() = %+v, %v, %v; want the exceptions stage", result, ok, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(tmpDir, exceptionShimFile)); err != nil || !strings.Contains(string(data), "throw std::bad_alloc()") {
+		t.Errorf("failing allocator not written: %v", err)
+	}
+	script := strings.Join(command, " ")
+	for _, want := range []string{"-fsanitize=address", "/src/code.cpp /src/" + exceptionShimFile, "BJARNE_ALLOC_FAIL=0 /tmp/exceptions", "BJARNE_ALLOC_FAIL=$i /tmp/exceptions", "(n + 50 - 1) / 50"} {
+		if !strings.Contains(script, want) {
+			t.Errorf("exceptions command = %q, missing %q", script, want)
+		}
+	}
+	if got, want := c.stageTimeout("exceptions"), 120+51*maxHangTimeout; got != want {
+		t.Errorf("stageTimeout(exceptions) = %d, want %d", got, want)
+	}
+}
+
+func TestExceptionsLoop(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs /bin/sh")
+	}
+	tests := []struct {
+		name      string
+		program   string // Stands in for the built program; BJARNE_ALLOC_FAIL is set
+		wantFail  int    // Allocation reported as failing; 0 = the gate passes
+		wantCount string
+	}{
+		{
+			name: "leak on allocation 3",
+			program: `case $BJARNE_ALLOC_FAIL in
+0) echo "` + exceptionsCountMarker + ` 5" >&2 ;;
+3) echo "ERROR: LeakSanitizer: detected memory leaks" >&2; exit 23 ;;
+esac`,
+			wantFail: 3,
+		},
+		{
+			name: "uncaught bad_alloc is clean",
+			program: `case $BJARNE_ALLOC_FAIL in
+0) echo "` + exceptionsCountMarker + ` 4" >&2 ;;
+*) echo "libc++abi: terminating due to uncaught exception of type std::bad_alloc" >&2; exit 134 ;;
+esac`,
+			wantCount: "4 of 4 allocation(s)",
+		},
+		{
+			name: "crash after a caught failure",
+			program: `case $BJARNE_ALLOC_FAIL in
+0) echo "` + exceptionsCountMarker + ` 2" >&2 ;;
+2) exit 139 ;;
+esac`,
+			wantFail: 2,
+		},
+		{
+			name:      "no allocations",
+			program:   `true`,
+			wantCount: "no counted allocations",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program := filepath.Join(t.TempDir(), "program.sh")
+			if err := os.WriteFile(program, []byte(tt.program+"\n"), 0700); err != nil { //nolint:gosec // the test runs it
+				t.Fatal(err)
+			}
+			c := &ContainerRuntime{}
+			script := strings.Replace(c.exceptionsCommand(""), c.cxx(""), "true ", 1)
+			script = strings.ReplaceAll(script, "/tmp/exceptions;", "sh "+program+";")
+			script = strings.ReplaceAll(script, "/tmp/exceptions.log", filepath.Join(t.TempDir(), "exceptions.log"))
+			out, err := exec.Command("sh", "-c", script).CombinedOutput()
+			f := parseExceptionFailure(string(out))
+			if tt.wantFail == 0 {
+				if err != nil || f != nil || !strings.Contains(string(out), tt.wantCount) {
+					t.Errorf("exceptions loop = %v, %q; want a pass mentioning %q", err, out, tt.wantCount)
+				}
+				return
+			}
+			if err == nil || f == nil || f.Allocation != tt.wantFail {
+				t.Errorf("exceptions loop = %v, %q; want allocation %d reported", err, out, tt.wantFail)
+			}
+		})
+	}
+}
+
+func TestFormatErrorForLLMExceptions(t *testing.T) {
+	report := exceptionsMarker + " allocation 7 of 42 failed (exit 23)\n" +
+		"bjarne: allocation 7 throws std::bad_alloc, allocated at:\n" +
+		"==12==ERROR: LeakSanitizer: detected memory leaks\n" +
+		"Direct leak of 16 byte(s) in 1 object(s) allocated from:\n" +
+		"    #0 0x4f1 in operator new(unsigned long) /src/bjarne_alloc_fail.cpp:70:43\n" +
+		"    #1 0x4f2 in Stack::push(int) /src/code.cpp:12:20\n"
+	got := FormatErrorForLLM("exceptions", report, nil)
+	for _, want := range []string{"[exceptions] Found by exception-safety testing", "allocation 7 of 42", "RAII"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("FormatErrorForLLM() = %q, missing %q", got, want)
+		}
+	}
+	if parseExceptionFailure("ERROR: LeakSanitizer: detected memory leaks") != nil {
+		t.Error("parseExceptionFailure() found a failure without the marker")
+	}
+}
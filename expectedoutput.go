@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ExpectedOutput is what the program must print, recorded by the analysis for tasks such as
+// "print the first 10 primes" and checked by the output gate after the run stage
+type ExpectedOutput struct {
+	Text  string // Exact stdout, compared line by line ignoring trailing whitespace
+	Regex bool   // Text is a regular expression the whole stdout must match
+}
+
+// expectedOutputPattern matches the analysis's [EXPECTED OUTPUT] or [EXPECTED OUTPUT REGEX] block
+var expectedOutputPattern = regexp.MustCompile(`(?s)\[EXPECTED OUTPUT( REGEX)?\][ \t]*\n?(.*?)\n?[ \t]*\[/EXPECTED OUTPUT(?: REGEX)?\]`)
+
+// outputMismatchLines bounds the expected and actual output quoted in a mismatch
+const outputMismatchLines = 20
+
+// parseExpectedOutput extracts the expected output block from the analysis
+// It returns the expectation and the text without the block, or nil and the text unchanged
+// when there is no block; a regex that does not compile is dropped rather than failing every run
+func parseExpectedOutput(text string) (*ExpectedOutput, string) {
+	m := expectedOutputPattern.FindStringSubmatchIndex(text)
+	if m == nil {
+		return nil, text
+	}
+	rest := strings.TrimSpace(text[:m[0]] + text[m[1]:])
+	e := &ExpectedOutput{Text: text[m[4]:m[5]], Regex: m[2] >= 0}
+	if e.Regex {
+		e.Text = strings.TrimSpace(e.Text)
+		if _, err := e.compile(); err != nil {
+			return nil, rest
+		}
+	}
+	if normalizeOutput(e.Text) == "" {
+		return nil, rest
+	}
+	return e, rest
+}
+
+// compile anchors the regex so it must match the whole output
+func (e *ExpectedOutput) compile() (*regexp.Regexp, error) {
+	return regexp.Compile(`\A(?:` + e.Text + `)\z`)
+}
+
+// normalizeOutput drops carriage returns, trailing whitespace on each line and trailing blank lines
+func normalizeOutput(s string) string {
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+}
+
+// Check compares the program's stdout with the expectation and describes any mismatch
+func (e *ExpectedOutput) Check(stdout string) (bool, string) {
+	actual := normalizeOutput(stdout)
+	if e.Regex {
+		re, err := e.compile()
+		if err != nil {
+			return false, fmt.Sprintf("invalid expected output regex: %v", err)
+		}
+		if re.MatchString(actual) {
+			return true, ""
+		}
+		return false, fmt.Sprintf("Program output does not match the expected pattern\nExpected (regex, whole output):\n%s\nActual output:\n%s",
+			indentLines(e.Text, "  "), quoteOutput(actual))
+	}
+
+	expected := normalizeOutput(e.Text)
+	if actual == expected {
+		return true, ""
+	}
+	want, got := strings.Split(expected, "\n"), strings.Split(actual, "\n")
+	line := 0
+	for line < len(want) && line < len(got) && want[line] == got[line] {
+		line++
+	}
+	wantLine, gotLine := "(end of output)", "(end of output)"
+	if line < len(want) {
+		wantLine = fmt.Sprintf("%q", want[line])
+	}
+	if line < len(got) {
+		gotLine = fmt.Sprintf("%q", got[line])
+	}
+	return false, fmt.Sprintf("Program output differs from the expected output at line %d\n  expected: %s\n  actual:   %s\nExpected output:\n%s\nActual output:\n%s",
+		line+1, wantLine, gotLine, indentLines(firstLines(expected, outputMismatchLines), "  "), quoteOutput(actual))
+}
+
+// quoteOutput indents the start of the program's output for an error message
+func quoteOutput(s string) string {
+	if s == "" {
+		return "  (nothing)"
+	}
+	return indentLines(firstLines(s, outputMismatchLines), "  ")
+}
+
+// checkExpectedOutput runs the output gate on the run stage's result
+func checkExpectedOutput(expected *ExpectedOutput, run ValidationResult) ValidationResult {
+	result := ValidationResult{Stage: "output", Success: true, Output: "Program output matches the expected output"}
+	if run.Skipped {
+		result.Skipped = true
+		result.Output = ""
+		return result
+	}
+	if ok, mismatch := expected.Check(run.Output); !ok {
+		result.Success = false
+		result.Output = ""
+		result.Error = mismatch
+	}
+	return result
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseExpectedOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		want     *ExpectedOutput
+		wantRest string
+	}{
+		{"exact", "Sieve up to 30.\n[EXPECTED OUTPUT]\n2 3 5 7\n11\n[/EXPECTED OUTPUT]", &ExpectedOutput{Text: "2 3 5 7\n11"}, "Sieve up to 30."},
+		{"regex", "Sum the squares.\n\n[EXPECTED OUTPUT REGEX]\nSum: \\d+\n[/EXPECTED OUTPUT REGEX]\nCorrect me if I'm wrong.", &ExpectedOutput{Text: `Sum: \d+`, Regex: true}, "Sum the squares.\n\n\nCorrect me if I'm wrong."},
+		{"none", "A ring buffer.", nil, "A ring buffer."},
+		{"bad regex is dropped", "x\n[EXPECTED OUTPUT REGEX]\n(unclosed\n[/EXPECTED OUTPUT REGEX]", nil, "x"},
+		{"empty block is dropped", "x\n[EXPECTED OUTPUT]\n\n[/EXPECTED OUTPUT]", nil, "x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rest := parseExpectedOutput(tt.text)
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("parseExpectedOutput() = %+v, want %+v", got, tt.want)
+			}
+			if rest != tt.wantRest {
+				t.Errorf("rest = %q, want %q", rest, tt.wantRest)
+			}
+		})
+	}
+}
+
+func TestExpectedOutputCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected ExpectedOutput
+		stdout   string
+		wantOK   bool
+		wantErr  string
+	}{
+		{"exact", ExpectedOutput{Text: "2\n3\n5\n"}, "2\n3\n5\n", true, ""},
+		{"trailing whitespace and CRLF", ExpectedOutput{Text: "2\n3"}, "2  \r\n3\r\n\r\n", true, ""},
+		{"wrong line", ExpectedOutput{Text: "2\n3\n5"}, "2\n3\n4\n", false, "at line 3\n  expected: \"5\"\n  actual:   \"4\""},
+		{"short output", ExpectedOutput{Text: "2\n3"}, "2\n", false, "expected: \"3\"\n  actual:   (end of output)"},
+		{"no output", ExpectedOutput{Text: "hi"}, "", false, "Actual output:\n  (nothing)"},
+		{"regex", ExpectedOutput{Text: `Sum: \d+`, Regex: true}, "Sum: 385\n", true, ""},
+		{"regex must match everything", ExpectedOutput{Text: `Sum: \d+`, Regex: true}, "Sum: 385\ndebug\n", false, "does not match the expected pattern"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, mismatch := tt.expected.Check(tt.stdout)
+			if ok != tt.wantOK || !strings.Contains(mismatch, tt.wantErr) {
+				t.Errorf("Check() = %v, %q; want %v containing %q", ok, mismatch, tt.wantOK, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckExpectedOutput(t *testing.T) {
+	expected := &ExpectedOutput{Text: "2 3 5 7"}
+	if r := checkExpectedOutput(expected, ValidationResult{Stage: "run", Success: true, Output: "2 3 5 7\n"}); !r.Success || r.Stage != "output" {
+		t.Errorf("matching output = %+v", r)
+	}
+	if r := checkExpectedOutput(expected, ValidationResult{Stage: "run", Success: true, Output: "2 3 5\n"}); r.Success || r.Error == "" {
+		t.Errorf("mismatch = %+v, want a failure with the difference", r)
+	}
+	if r := checkExpectedOutput(expected, ValidationResult{Stage: "run", Success: true, Skipped: true}); !r.Skipped {
+		t.Errorf("skipped run = %+v, want the output gate skipped", r)
+	}
+
+	dod := &DefinitionOfDone{ExpectedOutput: expected}
+	if !dod.HasTestableRequirements() || !strings.Contains(dod.PromptSection(), "print exactly this to stdout") || dod.FormatDoDSummary() != "expected output" {
+		t.Errorf("DoD with only an expected output: prompt %q, summary %q", dod.PromptSection(), dod.FormatDoDSummary())
+	}
+}
+
+func TestExpectOutput(t *testing.T) {
+	m := Model{config: &Config{}, tokenTracker: NewTokenTracker(0, 0), styles: NewStyles(NewTheme(&ThemeSettings{Name: "default"}))}
+	m.expectOutput(&ExpectedOutput{Text: "2 3 5 7"})
+	if m.dod == nil || m.dod.ExpectedOutput == nil {
+		t.Fatal("expectOutput() did not record the expectation")
+	}
+
+	// A later Definition of Done keeps the analysis's expectation
+	m.applyDefinitionOfDone("fact(5) -> 120")
+	if m.dod.ExpectedOutput == nil || len(m.dod.Examples) != 1 {
+		t.Fatalf("after the DoD: %+v", m.dod)
+	}
+
+	// A follow-up analysis without one clears it, without touching earlier snapshots
+	before := m.dod
+	m.expectOutput(nil)
+	if m.dod.ExpectedOutput != nil || before.ExpectedOutput == nil || len(m.dod.Examples) != 1 {
+		t.Errorf("expectOutput(nil): dod %+v, snapshot %+v", m.dod, before)
+	}
+}
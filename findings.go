@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// fileCheckFunc runs the gates for one file of a source tree (target) and the local files it needs
+// progress is called as each gate finishes so findings can be shown before the slow gates end
+type fileCheckFunc func(ctx context.Context, files []CodeFile, target string, progress ProgressCallback) ([]ValidationResult, error)
+
+// GateFinding is a gate diagnostic placed in one of the validated files
+type GateFinding struct {
+	File    string // Filename within the validated directory
+	Line    int    // 1-based; 0 when the gate failed without a location
+	Column  int    // 1-based; 0 when unknown
+	Level   DiagnosticLevel
+	Stage   string // Gate that reported it
+	Check   string // Tool check name, if any
+	Message string
+}
+
+// localIncludePattern matches #include "name" without directories (the validator mounts one directory)
+var localIncludePattern = regexp.MustCompile(`(?m)^\s*#\s*include\s*"([^"/\\]+)"`)
+
+// stackFramePattern finds file:line locations in sanitizer stack frames
+var stackFramePattern = regexp.MustCompile(` at (\S+):(\d+)`)
+
+// containerCheck validates files of an existing source tree in the container
+// Files with main() get the full pipeline; headers and other translation units cannot link,
+// so they get a syntax check and clang-tidy
+func containerCheck(container *ContainerRuntime) fileCheckFunc {
+	return func(ctx context.Context, files []CodeFile, target string, progress ProgressCallback) ([]ValidationResult, error) {
+		if isSourceFile(target) && mainFunctionPattern.MatchString(files[0].Content) {
+			var results []ValidationResult
+			var err error
+			if len(files) == 1 {
+				results, err = container.ValidateCodeWithProgress(ctx, files[0].Content, target, progress)
+			} else {
+				results, err = container.ValidateMultiFileCode(ctx, files)
+			}
+			if err != nil {
+				return results, err
+			}
+			return container.withPluginValidators(ctx, files, results), nil
+		}
+
+		var results []ValidationResult
+		for _, step := range []func(context.Context, []CodeFile, string) (ValidationResult, error){container.CheckSyntax, container.LintFile} {
+			result, err := step(ctx, files, target)
+			if err != nil {
+				return results, err
+			}
+			results = append(results, result)
+			if progress != nil {
+				progress(result.Stage, false, &result)
+			}
+			if !result.Success {
+				break
+			}
+		}
+		return results, nil
+	}
+}
+
+// collectLocalFiles gathers a file and the local headers it includes, transitively
+// A file with main() also gets the sources next to those headers so the project links
+// The file itself is always first
+func collectLocalFiles(path string, read func(string) (string, bool)) []CodeFile {
+	dir, target := filepath.Dir(path), filepath.Base(path)
+	content, ok := read(path)
+	if !ok {
+		return nil
+	}
+	linked := isSourceFile(target) && mainFunctionPattern.MatchString(content)
+
+	var files []CodeFile
+	seen := make(map[string]bool)
+	queue := []string{target}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		content, ok := read(filepath.Join(dir, name))
+		if !ok {
+			continue // A system or generated header; the compiler reports it if it matters
+		}
+		files = append(files, CodeFile{Filename: name, Content: content})
+
+		for _, m := range localIncludePattern.FindAllStringSubmatch(content, -1) {
+			queue = append(queue, m[1])
+		}
+		if linked && isHeaderFile(name) {
+			stem := strings.TrimSuffix(name, filepath.Ext(name))
+			for _, ext := range []string{".cpp", ".cc", ".cxx", ".c"} {
+				if _, err := os.Stat(filepath.Join(dir, stem+ext)); err == nil {
+					queue = append(queue, stem+ext)
+				}
+			}
+		}
+	}
+	return files
+}
+
+// readDiskFile reads a file for collectLocalFiles
+func readDiskFile(path string) (string, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
+
+// gateFindings places the diagnostics of gate results in the validated files
+// Notes and findings in system headers are dropped; a failed gate with no locatable
+// error is reported at the top of target
+func gateFindings(target string, files []CodeFile, results []ValidationResult) []GateFinding {
+	known := make(map[string]bool, len(files))
+	for _, f := range files {
+		known[f.Filename] = true
+	}
+
+	var findings []GateFinding
+	seen := make(map[string]bool)
+	add := func(f GateFinding) {
+		key := fmt.Sprintf("%s:%d:%d:%s", f.File, f.Line, f.Column, f.Message)
+		if !seen[key] {
+			seen[key] = true
+			findings = append(findings, f)
+		}
+	}
+
+	for _, r := range results {
+		stage := gateName(r.Stage)
+		located := false
+		for _, d := range stageDiagnostics(r) {
+			if d.Level == LevelNote {
+				continue
+			}
+			name, line, col := locateDiagnostic(d, target, known)
+			if name == "" {
+				continue // In a system header
+			}
+			level := d.Level
+			if level != LevelWarning {
+				level = LevelError
+			}
+			message := d.Message
+			if d.File == "" && d.Context != "" {
+				message += "\n" + firstLines(d.Context, 3)
+			}
+			add(GateFinding{File: name, Line: line, Column: col, Level: level, Stage: stage, Check: d.Check, Message: message})
+			located = located || level == LevelError
+		}
+
+		if !r.Success && !located {
+			detail := strings.TrimSpace(r.Error)
+			if detail == "" {
+				detail = strings.TrimSpace(r.Output)
+			}
+			add(GateFinding{File: target, Level: LevelError, Stage: stage,
+				Message: fmt.Sprintf("%s failed\n%s", r.Stage, firstLines(detail, 10))})
+		}
+	}
+	return findings
+}
+
+// stageDiagnostics parses a gate's output with the parser for its tool
+func stageDiagnostics(r ValidationResult) []Diagnostic {
+	text := r.Output + "\n" + r.Error
+	switch stage := gateName(r.Stage); stage {
+	case "clang-tidy", "compile", "syntax", "constexpr", "templates", "ast-rules":
+		return ParseClangTidyOutput(text)
+	case "cppcheck":
+		return ParseCppcheckOutput(text)
+	case "asan", "ubsan", "msan", "tsan":
+		return ParseSanitizerOutput(text, stage)
+	case "stress":
+		return ParseSanitizerOutput(text, "tsan")
+	case "deadlock":
+		return append(ParseClangTidyOutput(text), ParseSanitizerOutput(text, "tsan")...)
+	case ValgrindToolHelgrind, ValgrindToolDRD:
+		return ParseValgrindOutput(text, stage)
+	}
+	return nil
+}
+
+// locateDiagnostic finds which collected file and line a diagnostic belongs to
+// Sanitizer reports carry their location in the stack trace; the first frame in the
+// project wins, and a report with no project frame goes to the top of target
+func locateDiagnostic(d Diagnostic, target string, known map[string]bool) (string, int, int) {
+	if d.File != "" {
+		if name := filepath.Base(filepath.FromSlash(d.File)); known[name] {
+			return name, d.Line, d.Column
+		}
+		return "", 0, 0
+	}
+	for _, m := range stackFramePattern.FindAllStringSubmatch(d.Context, -1) {
+		if name := filepath.Base(filepath.FromSlash(m[1])); known[name] {
+			line, _ := strconv.Atoi(m[2])
+			return name, line, 0
+		}
+	}
+	return target, 0, 0
+}
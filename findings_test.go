@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCollectLocalFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("main.cpp", "#include \"queue.h\"\n#include <vector>\n#include \"sub/other.h\"\nint main() { return 0; }\n")
+	write("queue.h", "#pragma once\n#include \"node.h\"\n")
+	write("node.h", "#pragma once\n")
+	write("queue.cpp", "#include \"queue.h\"\n")
+	write("unrelated.cpp", "int x;\n")
+
+	var names []string
+	for _, f := range collectLocalFiles(filepath.Join(dir, "main.cpp"), readDiskFile) {
+		names = append(names, f.Filename)
+	}
+	if got := strings.Join(names, ","); got != "main.cpp,queue.h,node.h,queue.cpp" {
+		t.Errorf("files for main.cpp = %s", got)
+	}
+
+	// Without main() nothing needs to link, so sibling sources stay out
+	names = nil
+	for _, f := range collectLocalFiles(filepath.Join(dir, "queue.h"), readDiskFile) {
+		names = append(names, f.Filename)
+	}
+	if got := strings.Join(names, ","); got != "queue.h,node.h" {
+		t.Errorf("files for queue.h = %s", got)
+	}
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxFixPatterns caps the knowledge base; the least recently useful patterns are dropped first
+const maxFixPatterns = 500
+
+// maxFixHints bounds the known fixes added to one fix prompt
+const maxFixHints = 3
+
+// maxFixGuidance bounds the stored guidance for one pattern, in bytes
+const maxFixGuidance = 400
+
+// maxFixDiffLines bounds the changed lines kept as guidance when a fix came without an explanation
+const maxFixDiffLines = 8
+
+// Patterns that make diagnostic messages comparable across programs
+var (
+	quotedNamePattern = regexp.MustCompile(`'[^']*'|"[^"]*"`)
+	hexNumberPattern  = regexp.MustCompile(`\b0x[0-9a-fA-F]+\b`)
+	numberPattern     = regexp.MustCompile(`\d+`)
+	codeBlockPattern  = regexp.MustCompile("(?s)```.*?```")
+)
+
+// FixPattern is a kind of validation failure and the guidance that fixed it before
+type FixPattern struct {
+	Gate      string    `json:"gate"`
+	Check     string    `json:"check,omitempty"`
+	Message   string    `json:"message"` // Normalized message; with Gate and Check, the signature
+	Guidance  string    `json:"guidance"`
+	Successes int       `json:"successes"`
+	LastFixed time.Time `json:"lastFixed"`
+}
+
+// FixKnowledge remembers which fixes made which failures go away, across sessions
+// Safe for concurrent use (the HTTP server shares one)
+type FixKnowledge struct {
+	mu       sync.Mutex
+	path     string
+	Patterns map[string]*FixPattern `json:"patterns"`
+}
+
+// pendingFix is a fix response whose validation is still running, to learn from if it works
+type pendingFix struct {
+	failures map[string]FixPattern // Kinds of failure the fix was asked to clear
+	response string
+	before   string // Code before the fix
+}
+
+// fixKnowledgePath returns ~/.bjarne/fix_knowledge.json
+func fixKnowledgePath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".bjarne", "fix_knowledge.json"), nil
+}
+
+// loadFixKnowledge reads the knowledge base from path (empty path = in-memory only)
+// A missing or unreadable file yields an empty knowledge base
+func loadFixKnowledge(path string) *FixKnowledge {
+	k := &FixKnowledge{path: path, Patterns: make(map[string]*FixPattern)}
+	if path == "" {
+		return k
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return k
+	}
+	if err := json.Unmarshal(data, k); err != nil || k.Patterns == nil {
+		k.Patterns = make(map[string]*FixPattern)
+	}
+	return k
+}
+
+// projectFixKnowledge loads the user's knowledge base, or returns nil when learning is off
+func projectFixKnowledge(settings ValidationSettings) *FixKnowledge {
+	if !settings.LearnFixes {
+		return nil
+	}
+	path, _ := fixKnowledgePath()
+	return loadFixKnowledge(path)
+}
+
+// normalizeDiagnosticMessage strips the program-specific parts of a message (names, numbers)
+func normalizeDiagnosticMessage(msg string) string {
+	msg, _, _ = strings.Cut(msg, "\n")
+	msg = quotedNamePattern.ReplaceAllString(msg, "'_'")
+	msg = hexNumberPattern.ReplaceAllString(msg, "N")
+	msg = numberPattern.ReplaceAllString(msg, "N")
+	return strings.Join(strings.Fields(msg), " ")
+}
+
+// failureSignatures returns the kinds of failure in a validation, keyed by signature
+func failureSignatures(results []ValidationResult) map[string]FixPattern {
+	sigs := make(map[string]FixPattern)
+	for _, r := range results {
+		if r.Success || r.Skipped {
+			continue
+		}
+		gate := gateName(r.Stage)
+		for _, d := range stageDiagnostics(r) {
+			if d.Level == LevelNote {
+				continue
+			}
+			p := FixPattern{
+				Gate:    gate,
+				Check:   strings.TrimSuffix(d.Check, warningsAsErrorsSuffix),
+				Message: normalizeDiagnosticMessage(d.Message),
+			}
+			if p.Message == "" {
+				continue
+			}
+			sigs[p.signature()] = p
+		}
+	}
+	return sigs
+}
+
+// signature identifies the kind of failure a pattern describes
+func (p FixPattern) signature() string {
+	return p.Gate + "|" + p.Check + "|" + p.Message
+}
+
+// fixGuidance summarizes how a fix worked: the explanation around the code in the response,
+// or the lines it changed when there was none
+func fixGuidance(response, before, after string) string {
+	prose := strings.Join(strings.Fields(codeBlockPattern.ReplaceAllString(response, " ")), " ")
+	if prose != "" {
+		return truncateGuidance(prose)
+	}
+
+	var changed []string
+	for _, l := range LineDiff(before, after) {
+		text := strings.TrimSpace(l.Text)
+		if text == "" || l.Op == DiffEqual {
+			continue
+		}
+		prefix := "+ "
+		if l.Op == DiffDelete {
+			prefix = "- "
+		}
+		changed = append(changed, prefix+text)
+		if len(changed) == maxFixDiffLines {
+			break
+		}
+	}
+	if len(changed) == 0 {
+		return ""
+	}
+	return truncateGuidance("Changed lines:\n" + strings.Join(changed, "\n"))
+}
+
+// truncateGuidance caps guidance at maxFixGuidance bytes without splitting a UTF-8 character
+func truncateGuidance(s string) string {
+	if len(s) <= maxFixGuidance {
+		return s
+	}
+	cut := maxFixGuidance
+	for cut > 0 && s[cut]&0xC0 == 0x80 {
+		cut--
+	}
+	return s[:cut] + "..."
+}
+
+// Hints returns the guidance known to fix failures in sigs, as a section for a fix prompt
+// (empty when nothing is known)
+func (k *FixKnowledge) Hints(sigs map[string]FixPattern) string {
+	if k == nil || len(sigs) == 0 {
+		return ""
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	var known []*FixPattern
+	for sig := range sigs {
+		if p, ok := k.Patterns[sig]; ok && p.Guidance != "" {
+			known = append(known, p)
+		}
+	}
+	if len(known) == 0 {
+		return ""
+	}
+	sort.Slice(known, func(i, j int) bool {
+		if known[i].Successes != known[j].Successes {
+			return known[i].Successes > known[j].Successes
+		}
+		return known[i].signature() < known[j].signature()
+	})
+	if len(known) > maxFixHints {
+		known = known[:maxFixHints]
+	}
+
+	var sb strings.Builder
+	sb.WriteString("\nFIXES THAT WORKED BEFORE FOR THESE ERRORS:\n")
+	for _, p := range known {
+		label := p.Gate
+		if p.Check != "" && p.Check != p.Gate {
+			label += " " + p.Check
+		}
+		sb.WriteString(fmt.Sprintf("- [%s] %s (fixed %d time(s)):\n", label, p.Message, p.Successes))
+		for _, line := range strings.Split(p.Guidance, "\n") {
+			sb.WriteString("  " + line + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// learn records a fix once its code, now code, has been validated with results
+func (k *FixKnowledge) learn(fix *pendingFix, results []ValidationResult, code string) (int, error) {
+	if k == nil || fix == nil {
+		return 0, nil
+	}
+	ran := make(map[string]bool)
+	for _, r := range results {
+		if !r.Skipped {
+			ran[gateName(r.Stage)] = true
+		}
+	}
+	return k.Learn(fix.failures, failureSignatures(results), ran, fixGuidance(fix.response, fix.before, code))
+}
+
+// Learn records guidance for every failure in before that no longer occurs in after,
+// then saves the knowledge base. A failure only counts as cleared when its gate ran again
+// (validation stops at the first failing stage). It returns how many patterns were learned
+func (k *FixKnowledge) Learn(before, after map[string]FixPattern, ran map[string]bool, guidance string) (int, error) {
+	if k == nil || guidance == "" {
+		return 0, nil
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	learned := 0
+	now := time.Now().UTC()
+	for sig, p := range before {
+		if _, still := after[sig]; still || !ran[p.Gate] {
+			continue
+		}
+		if known, ok := k.Patterns[sig]; ok {
+			p.Successes = known.Successes
+		}
+		p.Guidance = guidance
+		p.Successes++
+		p.LastFixed = now
+		k.Patterns[sig] = &p
+		learned++
+	}
+	if learned == 0 {
+		return 0, nil
+	}
+	k.prune()
+	return learned, k.save()
+}
+
+// prune drops the patterns fixed least often, oldest first, beyond maxFixPatterns
+func (k *FixKnowledge) prune() {
+	if len(k.Patterns) <= maxFixPatterns {
+		return
+	}
+	sigs := make([]string, 0, len(k.Patterns))
+	for sig := range k.Patterns {
+		sigs = append(sigs, sig)
+	}
+	sort.Slice(sigs, func(i, j int) bool {
+		a, b := k.Patterns[sigs[i]], k.Patterns[sigs[j]]
+		if a.Successes != b.Successes {
+			return a.Successes < b.Successes
+		}
+		return a.LastFixed.Before(b.LastFixed)
+	})
+	for _, sig := range sigs[:len(sigs)-maxFixPatterns] {
+		delete(k.Patterns, sig)
+	}
+}
+
+// save writes the knowledge base (no-op for in-memory ones); the caller holds mu
+func (k *FixKnowledge) save() error {
+	if k.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(k.path), 0700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(k.path), err)
+	}
+	data, err := json.MarshalIndent(k, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode fix knowledge: %w", err)
+	}
+	if err := os.WriteFile(k.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write fix knowledge: %w", err)
+	}
+	return nil
+}
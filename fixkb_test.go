@@ -0,0 +1,112 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeDiagnosticMessage(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want string
+	}{
+		{"variable 'count' is not initialized", "variable '_' is not initialized"},
+		{"narrowing conversion from 'long' to 'int'", "narrowing conversion from '_' to '_'"},
+		{"READ of size 4 at 0x602000000014 thread T0", "READ of size N at N thread TN"},
+		{"  use of undeclared identifier \"foo\"\n  more detail", "use of undeclared identifier '_'"},
+	}
+	for _, tt := range tests {
+		if got := normalizeDiagnosticMessage(tt.msg); got != tt.want {
+			t.Errorf("normalizeDiagnosticMessage(%q) = %q, want %q", tt.msg, got, tt.want)
+		}
+	}
+}
+
+func TestFailureSignatures(t *testing.T) {
+	results := []ValidationResult{
+		{Stage: "clang-tidy", Success: true, Output: "/src/code.cpp:3:5: warning: use auto [modernize-use-auto]"},
+		{Stage: "compile", Success: false, Error: "/src/code.cpp:7:9: error: use of undeclared identifier 'x'\n/src/code.cpp:9:9: error: use of undeclared identifier 'y'"},
+		{Stage: "asan", Skipped: true},
+	}
+	sigs := failureSignatures(results)
+	if len(sigs) != 1 {
+		t.Fatalf("failureSignatures() = %v, want one signature for both undeclared identifiers", sigs)
+	}
+	for _, p := range sigs {
+		if p.Gate != "compile" || p.Message != "use of undeclared identifier '_'" {
+			t.Errorf("signature = %+v", p)
+		}
+	}
+}
+
+func TestFixGuidance(t *testing.T) {
+	response := "The loop read past the end; iterate to size() - 1.\n```cpp\nint main() {}\n```"
+	if got := fixGuidance(response, "", ""); got != "The loop read past the end; iterate to size() - 1." {
+		t.Errorf("fixGuidance() with an explanation = %q", got)
+	}
+
+	got := fixGuidance("```cpp\nint x = 0;\n```", "int x;\nreturn x;\n", "int x = 0;\nreturn x;\n")
+	if got != "Changed lines:\n- int x;\n+ int x = 0;" {
+		t.Errorf("fixGuidance() without an explanation = %q", got)
+	}
+
+	if got := fixGuidance(strings.Repeat("é", maxFixGuidance), "", ""); len(got) > maxFixGuidance+3 || !strings.HasSuffix(got, "...") {
+		t.Errorf("fixGuidance() did not truncate: %d bytes", len(got))
+	}
+}
+
+func TestFixKnowledgeLearnAndHints(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fix_knowledge.json")
+	k := loadFixKnowledge(path)
+
+	uninit := FixPattern{Gate: "msan", Check: "msan", Message: "use-of-uninitialized-value"}
+	race := FixPattern{Gate: "tsan", Check: "tsan", Message: "data race"}
+	before := map[string]FixPattern{uninit.signature(): uninit, race.signature(): race}
+	after := map[string]FixPattern{race.signature(): race}
+
+	if n, err := k.Learn(before, after, map[string]bool{"msan": true}, ""); n != 0 || err != nil {
+		t.Errorf("Learn() without guidance = %d, %v; want nothing learned", n, err)
+	}
+	if n, err := k.Learn(before, nil, map[string]bool{"msan": true}, "Initialize members in the constructor."); n != 1 || err != nil {
+		t.Fatalf("Learn() = %d, %v; want only the failure whose gate ran", n, err)
+	}
+	if n, _ := k.Learn(before, after, map[string]bool{"msan": true, "tsan": true}, "Value-initialize with {}."); n != 1 {
+		t.Errorf("Learn() = %d, want the cleared failure only", n)
+	}
+
+	reloaded := loadFixKnowledge(path)
+	hints := reloaded.Hints(map[string]FixPattern{uninit.signature(): uninit})
+	if !strings.Contains(hints, "[msan] use-of-uninitialized-value (fixed 2 time(s))") || !strings.Contains(hints, "Value-initialize with {}.") {
+		t.Errorf("Hints() after reload = %q", hints)
+	}
+	if hints := reloaded.Hints(map[string]FixPattern{race.signature(): race}); hints != "" {
+		t.Errorf("Hints() for an unknown failure = %q, want none", hints)
+	}
+
+	var off *FixKnowledge
+	if off.Hints(before) != "" {
+		t.Error("Hints() on a disabled knowledge base returned hints")
+	}
+	if n, err := off.learn(&pendingFix{failures: before}, nil, ""); n != 0 || err != nil {
+		t.Errorf("learn() on a disabled knowledge base = %d, %v", n, err)
+	}
+}
+
+func TestFixKnowledgePrune(t *testing.T) {
+	k := loadFixKnowledge("")
+	for i := 0; i < maxFixPatterns+5; i++ {
+		p := FixPattern{Gate: "compile", Message: strings.Repeat("x", i+1)}
+		k.Patterns[p.signature()] = &p
+	}
+	kept := FixPattern{Gate: "compile", Message: "x"}
+	k.Patterns[kept.signature()].Successes = 3
+
+	k.prune()
+	if len(k.Patterns) != maxFixPatterns {
+		t.Errorf("prune() kept %d patterns, want %d", len(k.Patterns), maxFixPatterns)
+	}
+	if _, ok := k.Patterns[kept.signature()]; !ok {
+		t.Error("prune() dropped the most successful pattern")
+	}
+}
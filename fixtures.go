@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// projectFixturesDir holds the files programs read and write, relative to the project root:
+// input/ is copied into the program's working directory and expected/ is what it must leave there
+var projectFixturesDir = filepath.Join(".bjarne", "fixtures")
+
+// fixtureWorkDir is the program's writable working directory in the container
+const fixtureWorkDir = "/work"
+
+// fixtureScratchDir holds each program stage's working directory in the validation directory
+const fixtureScratchDir = "bjarne_work"
+
+// maxFixtureBytes bounds the fixtures copied into every program stage
+const maxFixtureBytes = 16 << 20
+
+// fixtureStages are the stages that run the program, and so get a working directory
+var fixtureStages = []string{"asan", "exceptions", "ubsan", "msan", "deadlock", "tsan", "stress", ValgrindToolHelgrind, ValgrindToolDRD, "run", c2cStage}
+
+// Fixtures are the project's file fixtures from .bjarne/fixtures
+type Fixtures struct {
+	Dir      string   `json:"dir"`                // Host directory holding input/ and expected/
+	Inputs   []string `json:"inputs,omitempty"`   // Files under input/, slash-separated and sorted
+	Expected []string `json:"expected,omitempty"` // Files under expected/ the run stage must produce
+	Digest   string   `json:"digest"`             // Hash of every fixture's path and content
+}
+
+// LoadFixtures reads the project's fixtures (nil if the directory does not exist or is empty)
+func LoadFixtures(root string) (*Fixtures, error) {
+	dir := filepath.Join(root, projectFixturesDir)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil, nil
+	}
+	f := &Fixtures{Dir: dir}
+	var digest strings.Builder
+	total := int64(0)
+	for _, sub := range []string{"input", "expected"} {
+		base := filepath.Join(dir, sub)
+		err := filepath.WalkDir(base, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) && path == base {
+					return filepath.SkipDir
+				}
+				return err
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if total += info.Size(); total > maxFixtureBytes {
+				return fmt.Errorf("fixtures are larger than %d MB", maxFixtureBytes>>20)
+			}
+			data, err := os.ReadFile(path) //nolint:gosec // path is under the project's fixtures
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(base, path)
+			if err != nil {
+				return err
+			}
+			rel = filepath.ToSlash(rel)
+			if sub == "input" {
+				f.Inputs = append(f.Inputs, rel)
+			} else {
+				f.Expected = append(f.Expected, rel)
+			}
+			digest.WriteString(sub + "/" + rel + "\x00" + sha256Hex(string(data)) + "\x00")
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", filepath.Join(projectFixturesDir, sub), err)
+		}
+	}
+	if len(f.Inputs) == 0 && len(f.Expected) == 0 {
+		return nil, nil
+	}
+	sort.Strings(f.Inputs)
+	sort.Strings(f.Expected)
+	f.Digest = sha256Hex(digest.String())
+	return f, nil
+}
+
+// Status describes the fixtures for the splash
+func (f *Fixtures) Status() string {
+	return fmt.Sprintf("%d input / %d expected fixture(s)", len(f.Inputs), len(f.Expected))
+}
+
+// PromptSection tells the model which files the program starts with and must produce
+func (f *Fixtures) PromptSection() string {
+	if f == nil {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("FILE FIXTURES: main runs in a writable working directory; open files by relative path.\n")
+	if len(f.Inputs) > 0 {
+		sb.WriteString("- It starts with these input files: " + strings.Join(f.Inputs, ", ") + "\n")
+	}
+	if len(f.Expected) > 0 {
+		sb.WriteString("- When it exits these files must exist there with the expected content: " + strings.Join(f.Expected, ", ") + "\n")
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// SetFixtures sets the project's file fixtures
+func (c *ContainerRuntime) SetFixtures(f *Fixtures) {
+	c.fixtures = f
+}
+
+// Fixtures returns the project's file fixtures (nil = none)
+func (c *ContainerRuntime) Fixtures() *Fixtures {
+	if c == nil {
+		return nil
+	}
+	return c.fixtures
+}
+
+// loadProjectFixtures gives the container the working directory's file fixtures
+func loadProjectFixtures(container *ContainerRuntime) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+	fixtures, err := LoadFixtures(cwd)
+	if err != nil {
+		return err
+	}
+	container.SetFixtures(fixtures)
+	return nil
+}
+
+// programDir is the directory the program runs in: /work with fixtures, otherwise /tmp
+func (c *ContainerRuntime) programDir() string {
+	if c.fixtures != nil {
+		return fixtureWorkDir
+	}
+	return "/tmp"
+}
+
+// fixtureMount prepares a stage's working directory from the input fixtures and returns its mount flags
+// Every program stage starts from a fresh copy, so files one stage writes are not seen by the next
+func (c *ContainerRuntime) fixtureMount(tmpDir, stage string) ([]string, error) {
+	if c.fixtures == nil || !slices.Contains(fixtureStages, stage) {
+		return nil, nil
+	}
+	work := filepath.Join(tmpDir, fixtureScratchDir, stage)
+	if err := os.RemoveAll(work); err != nil {
+		return nil, fmt.Errorf("failed to clear working directory: %w", err)
+	}
+	if err := os.MkdirAll(work, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create working directory: %w", err)
+	}
+	if len(c.fixtures.Inputs) > 0 {
+		if err := copyTree(filepath.Join(c.fixtures.Dir, "input"), work); err != nil {
+			return nil, fmt.Errorf("failed to copy input fixtures: %w", err)
+		}
+	}
+	return []string{"-v", filepath.ToSlash(work) + ":" + fixtureWorkDir}, nil
+}
+
+// filesGate checks the files the run stage left in its working directory against the expected fixtures
+// It reports false when there are none to check
+func (c *ContainerRuntime) filesGate(tmpDir string, run ValidationResult, progress ProgressCallback) (ValidationResult, bool) {
+	if c.fixtures == nil || len(c.fixtures.Expected) == 0 {
+		return ValidationResult{}, false
+	}
+	if !c.gates.Enabled("files") || run.Skipped {
+		return ValidationResult{Stage: "files", Success: true, Skipped: true}, true
+	}
+	if progress != nil {
+		progress("files", true, nil)
+	}
+	result := checkFixtures(c.fixtures, filepath.Join(tmpDir, fixtureScratchDir, "run"))
+	if progress != nil {
+		progress("files", false, &result)
+	}
+	return result, true
+}
+
+// checkFixtures compares each expected fixture with the file of the same name in work
+func checkFixtures(f *Fixtures, work string) ValidationResult {
+	var problems []string
+	for _, name := range f.Expected {
+		want, err := os.ReadFile(filepath.Join(f.Dir, "expected", filepath.FromSlash(name)))
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		got, err := os.ReadFile(filepath.Join(work, filepath.FromSlash(name)))
+		if err != nil {
+			problems = append(problems, name+" was not created")
+			continue
+		}
+		if mismatch := fileMismatch(want, got); mismatch != "" {
+			problems = append(problems, name+" "+mismatch)
+		}
+	}
+	if len(problems) > 0 {
+		return ValidationResult{Stage: "files", Error: fmt.Sprintf("Files the program wrote differ from %s:\n%s",
+			filepath.ToSlash(filepath.Join(projectFixturesDir, "expected")), strings.Join(problems, "\n"))}
+	}
+	return ValidationResult{Stage: "files", Success: true, Output: fmt.Sprintf("%d file(s) match the expected fixtures", len(f.Expected))}
+}
+
+// fileMismatch describes how a written file differs from the expected one, or returns "" when they match
+// Text is compared like program output, ignoring trailing whitespace and line endings; anything else byte for byte
+func fileMismatch(want, got []byte) string {
+	if !isTextFixture(want) || !isTextFixture(got) {
+		if bytes.Equal(want, got) {
+			return ""
+		}
+		at := 0
+		for at < len(want) && at < len(got) && want[at] == got[at] {
+			at++
+		}
+		return fmt.Sprintf("differs at byte %d (expected %d bytes, wrote %d)", at, len(want), len(got))
+	}
+	expected, actual := normalizeOutput(string(want)), normalizeOutput(string(got))
+	if expected == actual {
+		return ""
+	}
+	wantLines, gotLines := strings.Split(expected, "\n"), strings.Split(actual, "\n")
+	line := 0
+	for line < len(wantLines) && line < len(gotLines) && wantLines[line] == gotLines[line] {
+		line++
+	}
+	wantLine, gotLine := "(end of file)", "(end of file)"
+	if line < len(wantLines) {
+		wantLine = fmt.Sprintf("%q", wantLines[line])
+	}
+	if line < len(gotLines) {
+		gotLine = fmt.Sprintf("%q", gotLines[line])
+	}
+	return fmt.Sprintf("differs at line %d\n  expected: %s\n  actual:   %s", line+1, wantLine, gotLine)
+}
+
+// isTextFixture reports whether a file is UTF-8 text without NUL bytes
+func isTextFixture(data []byte) bool {
+	return utf8.Valid(data) && bytes.IndexByte(data, 0) < 0
+}
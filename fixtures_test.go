@@ -0,0 +1,179 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeFixtures creates .bjarne/fixtures under root from name -> content, names starting with input/ or expected/
+func writeFixtures(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, projectFixturesDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoadFixtures(t *testing.T) {
+	root := t.TempDir()
+	if f, err := LoadFixtures(root); err != nil || f != nil {
+		t.Fatalf("LoadFixtures(no dir) = %v, %v; want nil, nil", f, err)
+	}
+
+	writeFixtures(t, root, map[string]string{
+		"input/data.csv":      "a,1\nb,2\n",
+		"input/nested/x.txt":  "x\n",
+		"expected/report.txt": "total 3\n",
+	})
+	f, err := LoadFixtures(root)
+	if err != nil {
+		t.Fatalf("LoadFixtures() error = %v", err)
+	}
+	if want := []string{"data.csv", "nested/x.txt"}; !reflect.DeepEqual(f.Inputs, want) {
+		t.Errorf("Inputs = %v, want %v", f.Inputs, want)
+	}
+	if want := []string{"report.txt"}; !reflect.DeepEqual(f.Expected, want) {
+		t.Errorf("Expected = %v, want %v", f.Expected, want)
+	}
+	if f.Digest == "" {
+		t.Error("Digest is empty")
+	}
+
+	writeFixtures(t, root, map[string]string{"expected/report.txt": "total 4\n"})
+	changed, err := LoadFixtures(root)
+	if err != nil {
+		t.Fatalf("LoadFixtures() error = %v", err)
+	}
+	if changed.Digest == f.Digest {
+		t.Error("Digest did not change with a fixture's content")
+	}
+}
+
+func TestFixturesPromptSection(t *testing.T) {
+	var none *Fixtures
+	if got := none.PromptSection(); got != "" {
+		t.Errorf("PromptSection(nil) = %q, want empty", got)
+	}
+	got := (&Fixtures{Inputs: []string{"data.csv"}, Expected: []string{"report.txt"}}).PromptSection()
+	for _, want := range []string{"relative path", "input files: data.csv", "expected content: report.txt"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("PromptSection() = %q, missing %q", got, want)
+		}
+	}
+}
+
+func TestFileMismatch(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+		got  string
+		diff string // Substring of the mismatch; empty = match
+	}{
+		{"identical", "total 3\n", "total 3\n", ""},
+		{"line endings and trailing space", "a\nb\n", "a  \r\nb\r\n\r\n", ""},
+		{"different line", "a\nb\n", "a\nc\n", `differs at line 2`},
+		{"missing line", "a\nb\n", "a\n", `actual:   (end of file)`},
+		{"binary", "\x00\x01\x02", "\x00\x01\x03", "differs at byte 2 (expected 3 bytes, wrote 3)"},
+		{"binary equal", "\x00\xff", "\x00\xff", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fileMismatch([]byte(tt.want), []byte(tt.got))
+			if tt.diff == "" && got != "" {
+				t.Errorf("fileMismatch() = %q, want a match", got)
+			}
+			if tt.diff != "" && !strings.Contains(got, tt.diff) {
+				t.Errorf("fileMismatch() = %q, want it to contain %q", got, tt.diff)
+			}
+		})
+	}
+}
+
+func TestCheckFixtures(t *testing.T) {
+	root := t.TempDir()
+	writeFixtures(t, root, map[string]string{
+		"expected/report.txt":  "total 3\n",
+		"expected/out/log.txt": "done\n",
+	})
+	f, err := LoadFixtures(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	work := t.TempDir()
+	if err := os.WriteFile(filepath.Join(work, "report.txt"), []byte("total 3\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	result := checkFixtures(f, work)
+	if result.Success || !strings.Contains(result.Error, "out/log.txt was not created") || strings.Contains(result.Error, "report.txt") {
+		t.Errorf("checkFixtures(missing) = %+v, want only out/log.txt reported", result)
+	}
+
+	if err := os.MkdirAll(filepath.Join(work, "out"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(work, "out", "log.txt"), []byte("done\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if result := checkFixtures(f, work); !result.Success || result.Stage != "files" {
+		t.Errorf("checkFixtures(all written) = %+v, want success", result)
+	}
+}
+
+func TestFixtureMount(t *testing.T) {
+	root := t.TempDir()
+	writeFixtures(t, root, map[string]string{"input/data.csv": "a,1\n"})
+	f, err := LoadFixtures(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &ContainerRuntime{}
+	tmpDir := t.TempDir()
+	if args, err := c.fixtureMount(tmpDir, "asan"); err != nil || args != nil {
+		t.Errorf("fixtureMount(no fixtures) = %v, %v; want no mount", args, err)
+	}
+	c.SetFixtures(f)
+	if args, err := c.fixtureMount(tmpDir, "clang-tidy"); err != nil || args != nil {
+		t.Errorf("fixtureMount(clang-tidy) = %v, %v; want no mount", args, err)
+	}
+
+	work := filepath.Join(tmpDir, fixtureScratchDir, "run")
+	if err := os.MkdirAll(work, 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(work, "stale.txt"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	args, err := c.fixtureMount(tmpDir, "run")
+	if err != nil {
+		t.Fatalf("fixtureMount(run) error = %v", err)
+	}
+	if want := []string{"-v", filepath.ToSlash(work) + ":/work"}; !reflect.DeepEqual(args, want) {
+		t.Errorf("fixtureMount(run) = %v, want %v", args, want)
+	}
+	if data, err := os.ReadFile(filepath.Join(work, "data.csv")); err != nil || string(data) != "a,1\n" {
+		t.Errorf("data.csv = %q, %v; want the input fixture", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(work, "stale.txt")); !os.IsNotExist(err) {
+		t.Error("fixtureMount() kept a file from an earlier run")
+	}
+}
+
+func TestProgramCommandFixtures(t *testing.T) {
+	c := &ContainerRuntime{fixtures: &Fixtures{Inputs: []string{"data.csv"}}}
+	if got := c.programCommand("", "/tmp/test"); !strings.HasPrefix(got, "cd /work && /tmp/test") {
+		t.Errorf("programCommand() = %q, want the program run in /work", got)
+	}
+	if got := c.runCommand("/tmp/test"); !strings.Contains(got, "cd /work && rm -f core core.*") {
+		t.Errorf("runCommand() = %q, want core files looked for in /work", got)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// clangFormatConfigNames are the files clang-format itself looks for
+var clangFormatConfigNames = []string{".clang-format", "_clang-format"}
+
+// findClangFormatConfig walks up from dir looking for a project .clang-format
+// Returns the path of the first match, or empty string if none is found
+func findClangFormatConfig(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+
+	for {
+		for _, name := range clangFormatConfigNames {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// SetFormatSettings configures the clang-format gate and auto-formatting
+func (c *ContainerRuntime) SetFormatSettings(settings FormatSettings) {
+	c.format = settings
+}
+
+// prepareFormatStyle copies the project's .clang-format into tmpDir (if any)
+// and returns the --style argument to pass to clang-format
+func (c *ContainerRuntime) prepareFormatStyle(tmpDir string) string {
+	cwd, err := os.Getwd()
+	if err == nil {
+		if path := findClangFormatConfig(cwd); path != "" {
+			data, err := os.ReadFile(path) //nolint:gosec // path is discovered from the workspace
+			if err == nil && os.WriteFile(filepath.Join(tmpDir, ".clang-format"), data, 0600) == nil {
+				return "--style=file"
+			}
+		}
+	}
+
+	style := c.format.Style
+	if style == "" {
+		style = defaultFormatStyle
+	}
+	return "--style=" + style
+}
+
+// runFormatStage checks formatting with clang-format --dry-run
+// When auto-apply is enabled the gate is advisory, since the final code is reformatted anyway
+func (c *ContainerRuntime) runFormatStage(ctx context.Context, tmpDir string, filenames []string, progress ProgressCallback) (ValidationResult, bool) {
+	styleArg := c.prepareFormatStyle(tmpDir)
+
+	var paths []string
+	for _, f := range filenames {
+		paths = append(paths, "/src/"+f)
+	}
+
+	if progress != nil {
+		progress("format", true, nil)
+	}
+	result := c.runValidationStage(ctx, tmpDir, "format", "sh", "-c", formatCheckCommand(styleArg, paths))
+
+	// Skip silently if clang-format isn't in the image
+	ran := !strings.Contains(result.Output, "not installed")
+	if ran && c.format.AutoApply {
+		result.Success = true
+	}
+	if progress != nil {
+		progress("format", false, &result)
+	}
+	return result, ran
+}
+
+// formatCheckCommand checks the files with clang-format --dry-run --Werror, which fails on any
+// formatting violation; it only skips when clang-format is not installed
+func formatCheckCommand(styleArg string, paths []string) string {
+	return "if ! command -v clang-format > /dev/null 2>&1; then echo 'clang-format not installed, skipping'; exit 0; fi; " +
+		"exec clang-format --dry-run --Werror " + shellQuote(styleArg) + " " + strings.Join(paths, " ")
+}
+
+// FormatFiles runs clang-format over the given files and returns the reformatted copies
+// Files that fail to format are returned unchanged
+func (c *ContainerRuntime) FormatFiles(ctx context.Context, files []CodeFile) ([]CodeFile, error) {
+	tmpDir, err := os.MkdirTemp("", "bjarne-format-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	for _, f := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, f.Filename), []byte(f.Content), 0600); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", f.Filename, err)
+		}
+	}
+
+	styleArg := c.prepareFormatStyle(tmpDir)
+
+	formatted := make([]CodeFile, len(files))
+	for i, f := range files {
+		formatted[i] = f
+		result := c.runValidationStage(ctx, tmpDir, "format", "clang-format", styleArg, "/src/"+f.Filename)
+		if result.Success && strings.TrimSpace(result.Output) != "" {
+			formatted[i].Content = result.Output
+		}
+	}
+
+	return formatted, nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestFindClangFormatConfig(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "src", "lib")
+	if err := os.MkdirAll(nested, 0750); err != nil {
+		t.Fatal(err)
+	}
+
+	// No config anywhere under the temp root
+	if got := findClangFormatConfig(nested); got != "" && strings.HasPrefix(got, root) {
+		t.Errorf("findClangFormatConfig() = %q, want no match under %s", got, root)
+	}
+
+	configPath := filepath.Join(root, ".clang-format")
+	if err := os.WriteFile(configPath, []byte("BasedOnStyle: Google\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		dir  string
+		want string
+	}{
+		{"config in same directory", root, configPath},
+		{"config in parent directory", nested, configPath},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := findClangFormatConfig(tt.dir); got != tt.want {
+				t.Errorf("findClangFormatConfig(%q) = %q, want %q", tt.dir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrepareFormatStyle(t *testing.T) {
+	tests := []struct {
+		name  string
+		style string
+		want  string
+	}{
+		{"configured style", "Google", "--style=Google"},
+		{"empty style falls back to default", "", "--style=" + defaultFormatStyle},
+	}
+
+	// Run from a directory without a .clang-format so the configured style is used
+	workDir := t.TempDir()
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(workDir); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Chdir(oldWd) }()
+
+	if findClangFormatConfig(workDir) != "" {
+		t.Skip("a .clang-format exists above the temp directory")
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &ContainerRuntime{format: FormatSettings{Style: tt.style}}
+			if got := c.prepareFormatStyle(t.TempDir()); got != tt.want {
+				t.Errorf("prepareFormatStyle() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatCheckCommand(t *testing.T) {
+	got := formatCheckCommand("--style={BasedOnStyle: LLVM, IndentWidth: 4}", []string{"/src/main.cpp", "/src/util.h"})
+	want := "exec clang-format --dry-run --Werror '--style={BasedOnStyle: LLVM, IndentWidth: 4}' /src/main.cpp /src/util.h"
+	if !strings.HasSuffix(got, want) {
+		t.Errorf("formatCheckCommand() = %q, want it to end with %q", got, want)
+	}
+	if runtime.GOOS == "windows" {
+		t.Skip("needs /bin/sh")
+	}
+
+	// A stand-in clang-format that, like the real one with --Werror, fails on a badly formatted file
+	bin := t.TempDir()
+	stub := "#!/bin/sh\nfor a; do case $a in *bad.cpp) echo \"$a:1:4: error: code should be clang-formatted\" >&2; exit 1 ;; esac; done\n"
+	if err := os.WriteFile(filepath.Join(bin, "clang-format"), []byte(stub), 0700); err != nil { //nolint:gosec // the test runs it
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+		want    string
+	}{
+		{"well formatted", bin + ":" + os.Getenv("PATH"), false, ""},
+		{"badly formatted", bin + ":" + os.Getenv("PATH"), true, "should be clang-formatted"},
+		{"not installed", t.TempDir(), false, "not installed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := "/src/good.cpp"
+			if tt.wantErr {
+				file = "/src/bad.cpp"
+			}
+			cmd := exec.Command("/bin/sh", "-c", formatCheckCommand("--style=file", []string{file}))
+			cmd.Env = append(os.Environ(), "PATH="+tt.path)
+			out, err := cmd.CombinedOutput()
+			if (err != nil) != tt.wantErr || !strings.Contains(string(out), tt.want) {
+				t.Errorf("format check = %v, %q; want error %v and %q", err, out, tt.wantErr, tt.want)
+			}
+		})
+	}
+}
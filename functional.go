@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// functionalStage names the container stage that runs the reviewer's checks
+const functionalStage = "functional"
+
+// maxFunctionalChecks is how many checks the reviewer is asked to write
+const maxFunctionalChecks = 3
+
+// maxChecksTokens bounds the reviewer's checks response
+const maxChecksTokens = 1024
+
+// functionalStartMarker is printed before the first check runs, so a harness that ran can
+// be told apart from one that did not compile
+const functionalStartMarker = "Running functional checks..."
+
+// functionalResultsPattern matches the harness's final tally
+var functionalResultsPattern = regexp.MustCompile(`(?m)^Results: (\d+) passed, (\d+) failed$`)
+
+// checkCallPattern matches uses of the CHECK macro in the reviewer's checks
+var checkCallPattern = regexp.MustCompile(`\bCHECK\s*\(`)
+
+// functionalPreamble is the CHECK macro the reviewer's checks are written against
+// Failures print the condition, so the fix prompt shows what was expected
+const functionalPreamble = `#include <exception>
+#include <iostream>
+
+// Functional checks framework
+static int _check_passed = 0;
+static int _check_failed = 0;
+
+#define CHECK(cond, what) do { \
+    if (cond) { \
+        std::cout << "PASS: " << (what) << std::endl; \
+        _check_passed++; \
+    } else { \
+        std::cout << "FAIL: " << (what) << " [" << #cond << "]" << std::endl; \
+        _check_failed++; \
+    } \
+} while(0)
+
+`
+
+// functionalMain wraps the reviewer's checks in a main() that tallies them
+// An escaping exception counts as a failed check
+func functionalMain(checks string) string {
+	var sb strings.Builder
+	sb.WriteString("// Generated functional checks main\n")
+	sb.WriteString("int main() {\n")
+	sb.WriteString("    std::cout << \"" + functionalStartMarker + "\" << std::endl;\n")
+	sb.WriteString("    try {\n")
+	for _, line := range strings.Split(strings.TrimRight(checks, "\n"), "\n") {
+		sb.WriteString("        " + line + "\n")
+	}
+	sb.WriteString("    } catch (const std::exception& e) {\n")
+	sb.WriteString("        std::cout << \"FAIL: exception thrown: \" << e.what() << std::endl;\n")
+	sb.WriteString("        _check_failed++;\n")
+	sb.WriteString("    } catch (...) {\n")
+	sb.WriteString("        std::cout << \"FAIL: unknown exception thrown\" << std::endl;\n")
+	sb.WriteString("        _check_failed++;\n")
+	sb.WriteString("    }\n")
+	sb.WriteString("    std::cout << \"Results: \" << _check_passed << \" passed, \" << _check_failed << \" failed\" << std::endl;\n")
+	sb.WriteString("    return _check_failed > 0 ? 1 : 0;\n")
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// FunctionalChecks is the outcome of running the reviewer's checks against the code
+type FunctionalChecks struct {
+	Code     string   // The checks as the reviewer wrote them
+	Total    int      // Checks run (or written, if the harness crashed)
+	Passed   int      // Checks that held
+	Failures []string // FAIL lines, with the failed condition
+	Ran      bool     // The checks compiled and started; false = inconclusive
+	Error    string   // Why the checks were inconclusive, or how the harness crashed
+}
+
+// parseFunctionalChecks extracts the reviewer's checks from its response
+func parseFunctionalChecks(response string) (string, error) {
+	checks := strings.TrimSpace(extractCode(response))
+	if checks == "" {
+		checks = strings.TrimSpace(response)
+	}
+	if mainFunctionPattern.MatchString(checks) {
+		return "", fmt.Errorf("checks define their own main()")
+	}
+	if !checkCallPattern.MatchString(checks) {
+		return "", fmt.Errorf("no CHECK statements in the reviewer's response")
+	}
+	return checks, nil
+}
+
+// parseFunctionalOutput reads the checks' outcome from the harness run
+func parseFunctionalOutput(checks string, r ValidationResult) *FunctionalChecks {
+	fc := &FunctionalChecks{Code: checks}
+	if !strings.Contains(r.Output, functionalStartMarker) {
+		fc.Error = "checks did not compile"
+		return fc
+	}
+	fc.Ran = true
+	for _, line := range strings.Split(r.Output, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "PASS: "):
+			fc.Passed++
+		case strings.HasPrefix(line, "FAIL: "):
+			fc.Failures = append(fc.Failures, strings.TrimPrefix(line, "FAIL: "))
+		}
+	}
+	fc.Total = fc.Passed + len(fc.Failures)
+	if !functionalResultsPattern.MatchString(r.Output) {
+		// The harness died mid-way (crash, abort, timeout): the check that was running failed
+		fc.Error = "checks crashed"
+		if msg := strings.TrimSpace(r.Error); msg != "" {
+			fc.Error += ": " + firstLine(msg)
+		}
+		fc.Total = max(fc.Total+1, len(checkCallPattern.FindAllString(checks, -1)))
+	}
+	return fc
+}
+
+// firstLine returns the first line of s
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
+
+// AllPassed reports whether the checks ran and every one held
+func (fc *FunctionalChecks) AllPassed() bool {
+	return fc.Ran && fc.Total > 0 && fc.Passed == fc.Total
+}
+
+// Adjust factors the checks into a reviewer's confidence: passing checks move it halfway
+// toward 100, and failing ones cap it at the share of checks that held
+// Inconclusive checks leave it unchanged
+func (fc *FunctionalChecks) Adjust(confidence int) int {
+	if !fc.Ran || fc.Total == 0 {
+		return confidence
+	}
+	if fc.AllPassed() {
+		return (confidence + 101) / 2
+	}
+	return min(confidence, 100*fc.Passed/fc.Total)
+}
+
+// Summary describes the outcome in one line, naming the first failure
+func (fc *FunctionalChecks) Summary() string {
+	if !fc.Ran {
+		return "Functional checks inconclusive: " + fc.Error
+	}
+	s := fmt.Sprintf("Functional checks: %d/%d passed", fc.Passed, fc.Total)
+	if len(fc.Failures) > 0 {
+		s += "; FAIL: " + fc.Failures[0]
+	}
+	if fc.Error != "" {
+		s += "; " + fc.Error
+	}
+	return s
+}
+
+// RunFunctionalChecks compiles checks as the main() of files and runs them
+func (c *ContainerRuntime) RunFunctionalChecks(ctx context.Context, files []CodeFile, checks string) (*FunctionalChecks, error) {
+	c, depResult, err := c.withDependencies(ctx, files)
+	if err != nil {
+		return nil, err
+	}
+	if depResult != nil {
+		return &FunctionalChecks{Code: checks, Error: "dependencies could not be resolved"}, nil
+	}
+	harness := GenerateProjectHarness(files, functionalPreamble, functionalMain(checks))
+	result, err := c.runProjectHarness(ctx, functionalStage, harness, "-g")
+	if err != nil {
+		return nil, err
+	}
+	return parseFunctionalOutput(checks, result), nil
+}
+
+// functionalChecks has the first reviewer write checks for request and runs them against files
+// The reviewer's token usage is added to usage even when the checks cannot be run
+func (p reviewPanel) functionalChecks(ctx context.Context, provider LLMProvider, container *ContainerRuntime, request string, files []CodeFile, usage *GenerateResult) (*FunctionalChecks, error) {
+	prompt := fmt.Sprintf(FunctionalChecksPrompt, maxFunctionalChecks, request, joinCodeFiles(files))
+	result, err := provider.Generate(ctx, p.models[0], "", []Message{{Role: "user", Content: prompt}}, maxChecksTokens)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write checks: %w", err)
+	}
+	usage.InputTokens += result.InputTokens
+	usage.OutputTokens += result.OutputTokens
+
+	checks, err := parseFunctionalChecks(result.Text)
+	if err != nil {
+		return nil, err
+	}
+	return container.RunFunctionalChecks(ctx, files, checks)
+}
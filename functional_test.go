@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseFunctionalChecks(t *testing.T) {
+	got, err := parseFunctionalChecks("Checks:\n```cpp\nCHECK(fact(5) == 120, \"5! is 120\");\nCHECK(fact(0) == 1, \"0! is 1\");\n```")
+	if err != nil || !strings.HasPrefix(got, "CHECK(fact(5)") || strings.Contains(got, "```") {
+		t.Errorf("parseFunctionalChecks() = %q, %v", got, err)
+	}
+
+	for _, response := range []string{
+		"```cpp\nassert(fact(5) == 120);\n```",
+		"```cpp\nint main() { CHECK(true, \"x\"); }\n```",
+	} {
+		if _, err := parseFunctionalChecks(response); err == nil {
+			t.Errorf("parseFunctionalChecks(%q) should fail", response)
+		}
+	}
+}
+
+func TestFunctionalHarness(t *testing.T) {
+	files := []CodeFile{{Filename: "code.cpp", Content: "int fact(int n) { return n <= 1 ? 1 : n * fact(n - 1); }\nint main() { return fact(3) == 6 ? 0 : 1; }\n"}}
+	harness := GenerateProjectHarness(files, functionalPreamble, functionalMain("CHECK(fact(5) == 120, \"5! is 120\");"))
+	if len(harness) != 1 {
+		t.Fatalf("harness has %d files, want the code with its main replaced", len(harness))
+	}
+	code := harness[0].Content
+	for _, want := range []string{"#define CHECK(cond, what)", "int fact(int n)", "        CHECK(fact(5) == 120", functionalStartMarker, "catch (...)"} {
+		if !strings.Contains(code, want) {
+			t.Errorf("harness missing %q:\n%s", want, code)
+		}
+	}
+	if strings.Contains(code, "fact(3) == 6") {
+		t.Error("harness kept the code's own main()")
+	}
+}
+
+func TestParseFunctionalOutput(t *testing.T) {
+	checks := "CHECK(a(), \"a\");\nCHECK(b(), \"b\");\nCHECK(c(), \"c\");"
+	tests := []struct {
+		name    string
+		result  ValidationResult
+		ran     bool
+		passed  int
+		total   int
+		adjust  int // Adjusted confidence for a reviewer's 80
+		summary string
+	}{
+		{
+			name:    "all pass",
+			result:  ValidationResult{Success: true, Output: functionalStartMarker + "\nPASS: a\nPASS: b\nPASS: c\nResults: 3 passed, 0 failed\n"},
+			ran:     true,
+			passed:  3,
+			total:   3,
+			adjust:  90,
+			summary: "Functional checks: 3/3 passed",
+		},
+		{
+			name:    "one fails",
+			result:  ValidationResult{Output: functionalStartMarker + "\nPASS: a\nFAIL: b [b()]\nPASS: c\nResults: 2 passed, 1 failed\n"},
+			ran:     true,
+			passed:  2,
+			total:   3,
+			adjust:  66,
+			summary: "Functional checks: 2/3 passed; FAIL: b [b()]",
+		},
+		{
+			name:    "crash",
+			result:  ValidationResult{Output: functionalStartMarker + "\nPASS: a\n", Error: "Segmentation fault (core dumped)\nmore"},
+			ran:     true,
+			passed:  1,
+			total:   3,
+			adjust:  33,
+			summary: "Functional checks: 1/3 passed; checks crashed: Segmentation fault (core dumped)",
+		},
+		{
+			name:    "did not compile",
+			result:  ValidationResult{Error: "/src/code.cpp:9:5: error: use of undeclared identifier 'a'"},
+			adjust:  80,
+			summary: "Functional checks inconclusive: checks did not compile",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc := parseFunctionalOutput(checks, tt.result)
+			if fc.Ran != tt.ran || fc.Passed != tt.passed || fc.Total != tt.total {
+				t.Errorf("parseFunctionalOutput() = ran %v, %d/%d; want ran %v, %d/%d", fc.Ran, fc.Passed, fc.Total, tt.ran, tt.passed, tt.total)
+			}
+			if got := fc.Adjust(80); got != tt.adjust {
+				t.Errorf("Adjust(80) = %d, want %d", got, tt.adjust)
+			}
+			if got := fc.Summary(); got != tt.summary {
+				t.Errorf("Summary() = %q, want %q", got, tt.summary)
+			}
+		})
+	}
+}
+
+func TestAssessWithoutChecks(t *testing.T) {
+	provider := reviewerStub{responses: map[string]string{"fast": "CONFIDENCE: 75\nSUMMARY: Probably fine."}}
+	prompts, _ := LoadPromptSet("")
+	files := []CodeFile{{Filename: "code.cpp", Content: "int main() {}"}}
+
+	// No container, or direct validation: the reviewer only reads the code
+	p := reviewPanel{models: []string{"fast"}, functional: true}
+	for _, request := range []string{"sum a list", directValidationPrefix + "main.cpp)"} {
+		v, err := p.Assess(context.Background(), provider, prompts, nil, request, "int main() {}", files)
+		if err != nil || v.Confidence != 75 || v.Checks != nil {
+			t.Errorf("Assess(%q) = %+v, %v; want the reading-based verdict", request, v, err)
+		}
+	}
+}
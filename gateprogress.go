@@ -0,0 +1,74 @@
+package main
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// gateStatus is one validation gate as the TUI shows it
+type gateStatus struct {
+	Stage   string
+	Started time.Time
+	Result  *ValidationResult // nil while the gate runs
+}
+
+// elapsed is how long the gate took, or has been running
+func (g gateStatus) elapsed(now time.Time) time.Duration {
+	if g.Result != nil {
+		return g.Result.Duration
+	}
+	return now.Sub(g.Started)
+}
+
+// gateProgress tracks the gates of one validation run as they start and finish
+type gateProgress struct {
+	gates []gateStatus
+}
+
+// gateProgressMsg reports a gate starting or finishing
+// events is the validation's stream, which is read until validationDoneMsg closes it
+type gateProgressMsg struct {
+	progress *gateProgress
+	stage    string
+	running  bool
+	result   *ValidationResult
+	events   <-chan tea.Msg
+}
+
+// update records a gate event at now
+func (p *gateProgress) update(msg gateProgressMsg, now time.Time) {
+	if msg.running {
+		p.gates = append(p.gates, gateStatus{Stage: msg.stage, Started: now})
+		return
+	}
+	for i := len(p.gates) - 1; i >= 0; i-- {
+		if p.gates[i].Stage == msg.stage && p.gates[i].Result == nil {
+			p.gates[i].Result = msg.result
+			return
+		}
+	}
+	// A finish without a start: the gate reported only its result
+	var took time.Duration
+	if msg.result != nil {
+		took = msg.result.Duration
+	}
+	p.gates = append(p.gates, gateStatus{Stage: msg.stage, Started: now.Add(-took), Result: msg.result})
+}
+
+// running returns the gate that is still running, or nil
+func (p *gateProgress) running() *gateStatus {
+	for i := len(p.gates) - 1; i >= 0; i-- {
+		if p.gates[i].Result == nil {
+			return &p.gates[i]
+		}
+	}
+	return nil
+}
+
+// waitForValidation reads the next message from a validation's stream
+func waitForValidation(events <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		return <-events // nil once the stream is closed
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestGateProgressUpdate(t *testing.T) {
+	start := time.Unix(1000, 0)
+	p := &gateProgress{}
+	p.update(gateProgressMsg{stage: "clang-tidy", running: true}, start)
+	p.update(gateProgressMsg{stage: "clang-tidy", result: &ValidationResult{Stage: "clang-tidy", Success: true, Duration: 2 * time.Second}}, start.Add(2*time.Second))
+	p.update(gateProgressMsg{stage: "asan", running: true}, start.Add(3*time.Second))
+
+	if len(p.gates) != 2 {
+		t.Fatalf("gates = %+v, want clang-tidy and asan", p.gates)
+	}
+	if g := p.running(); g == nil || g.Stage != "asan" {
+		t.Fatalf("running = %+v, want asan", g)
+	}
+	now := start.Add(10 * time.Second)
+	if got := p.gates[0].elapsed(now); got != 2*time.Second {
+		t.Errorf("finished gate elapsed = %s, want its duration", got)
+	}
+	if got := p.gates[1].elapsed(now); got != 7*time.Second {
+		t.Errorf("running gate elapsed = %s, want the live time", got)
+	}
+
+	p.update(gateProgressMsg{stage: "asan", result: &ValidationResult{Stage: "asan", Duration: 8 * time.Second}}, now.Add(time.Second))
+	if g := p.running(); g != nil {
+		t.Errorf("running = %+v after every gate finished", g)
+	}
+
+	// A result without a start event is still listed
+	p.update(gateProgressMsg{stage: "examples", result: &ValidationResult{Stage: "examples", Success: true, Duration: time.Second}}, now)
+	if len(p.gates) != 3 || p.gates[2].Stage != "examples" || p.gates[2].elapsed(now) != time.Second {
+		t.Errorf("gates = %+v, want examples appended", p.gates)
+	}
+}
+
+func TestWaitForValidation(t *testing.T) {
+	events := make(chan tea.Msg, 1)
+	events <- validationDoneMsg{}
+	close(events)
+	if _, ok := waitForValidation(events)().(validationDoneMsg); !ok {
+		t.Error("want the queued validationDoneMsg")
+	}
+	if msg := waitForValidation(events)(); msg != nil {
+		t.Errorf("closed stream returned %v, want nil", msg)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// selectableGates are the validation stages that --gates and --skip accept
+// Other stages (dependency resolution, syntax checks) always run
+var selectableGates = []string{
+	"clang-tidy", "cppcheck", "ast-rules", "iwyu", "complexity", "format", "compile", "coroutines",
+	"asan", "exceptions", "ubsan", "msan", "deadlock", "tsan", "stress", "helgrind", "drd", "model-check", "run", "output", "files", "constexpr", "templates", "examples", "benchmark",
+}
+
+// GateSelection restricts which validation gates run (the zero value runs all of them)
+type GateSelection struct {
+	Only []string `json:"only,omitempty"` // Run only these gates (empty = all)
+	Skip []string `json:"skip,omitempty"` // Never run these gates
+}
+
+// ParseGateList splits a comma-separated gate list and rejects unknown names
+func ParseGateList(value string) ([]string, error) {
+	var gates []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !isSelectableGate(name) {
+			return nil, fmt.Errorf("unknown gate %q (available: %s)", name, strings.Join(selectableGates, ", "))
+		}
+		gates = append(gates, name)
+	}
+	return gates, nil
+}
+
+// Enabled reports whether a stage runs under this selection
+func (g GateSelection) Enabled(stage string) bool {
+	name := gateName(stage)
+	if !isSelectableGate(name) {
+		return true
+	}
+	for _, skip := range g.Skip {
+		if skip == name {
+			return false
+		}
+	}
+	if len(g.Only) == 0 {
+		return true
+	}
+	for _, only := range g.Only {
+		if only == name {
+			return true
+		}
+	}
+	return false
+}
+
+// anyGateEnabled reports whether a selection leaves at least one selectable gate to run
+func anyGateEnabled(g GateSelection) bool {
+	for _, name := range selectableGates {
+		if g.Enabled(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// String describes the selection for display
+func (g GateSelection) String() string {
+	var parts []string
+	if len(g.Only) > 0 {
+		parts = append(parts, "only "+strings.Join(g.Only, ","))
+	}
+	if len(g.Skip) > 0 {
+		parts = append(parts, "skipping "+strings.Join(g.Skip, ","))
+	}
+	if len(parts) == 0 {
+		return "all gates"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// SetGateSelection limits the stages later validations run
+func (c *ContainerRuntime) SetGateSelection(gates GateSelection) {
+	c.gates = gates
+}
+
+// gateName strips the per-file suffix from a stage name ("clang-tidy:main.cpp" -> "clang-tidy")
+func gateName(stage string) string {
+	name, _, _ := strings.Cut(stage, ":")
+	return name
+}
+
+// isSelectableGate reports whether name is one of selectableGates
+func isSelectableGate(name string) bool {
+	for _, gate := range selectableGates {
+		if gate == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package main
+
+import "testing"
+
+func TestParseGateList(t *testing.T) {
+	got, err := ParseGateList(" clang-tidy, ASAN,,compile ")
+	if err != nil || len(got) != 3 || got[0] != "clang-tidy" || got[1] != "asan" || got[2] != "compile" {
+		t.Errorf("ParseGateList() = %v, %v", got, err)
+	}
+	if _, err := ParseGateList("clang-tidy,valgrind"); err == nil {
+		t.Error("ParseGateList() accepted an unknown gate")
+	}
+}
+
+func TestGateSelectionEnabled(t *testing.T) {
+	tests := []struct {
+		name  string
+		sel   GateSelection
+		stage string
+		want  bool
+	}{
+		{"zero value runs everything", GateSelection{}, "msan", true},
+		{"only listed", GateSelection{Only: []string{"clang-tidy", "compile"}}, "compile", true},
+		{"only excludes others", GateSelection{Only: []string{"clang-tidy", "compile"}}, "asan", false},
+		{"per-file stage names", GateSelection{Only: []string{"clang-tidy"}}, "clang-tidy:main.cpp", true},
+		{"skip", GateSelection{Skip: []string{"msan", "tsan"}}, "tsan", false},
+		{"skip wins over only", GateSelection{Only: []string{"asan"}, Skip: []string{"asan"}}, "asan", false},
+		{"unselectable stages always run", GateSelection{Only: []string{"asan"}}, "syntax:util.h", true},
+		{"dependency resolution always runs", GateSelection{Only: []string{"asan"}}, "dependencies", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.sel.Enabled(tt.stage); got != tt.want {
+				t.Errorf("Enabled(%q) = %v, want %v", tt.stage, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnyGateEnabled(t *testing.T) {
+	if !anyGateEnabled(GateSelection{}) {
+		t.Error("zero value should run gates")
+	}
+	if !anyGateEnabled(GateSelection{Only: []string{"asan", "msan"}, Skip: []string{"msan"}}) {
+		t.Error("asan should still run")
+	}
+	if anyGateEnabled(GateSelection{Only: []string{"asan"}, Skip: []string{"asan"}}) {
+		t.Error("skipping the only selected gate should leave nothing to run")
+	}
+}
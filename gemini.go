@@ -20,6 +20,7 @@ type GeminiClient struct {
 	apiKey       string
 	defaultModel string
 	httpClient   *http.Client
+	generation   GenerationSettings
 }
 
 // GeminiRequest represents a request to the Gemini API
@@ -36,9 +37,16 @@ type GeminiContent struct {
 	Parts []GeminiPart `json:"parts"`
 }
 
-// GeminiPart represents a part of content (text, etc.)
+// GeminiPart represents a part of content (text or inline image data)
 type GeminiPart struct {
-	Text string `json:"text"`
+	Text       string            `json:"text,omitempty"`
+	InlineData *GeminiInlineData `json:"inlineData,omitempty"`
+}
+
+// GeminiInlineData holds base64 media sent inline with a request
+type GeminiInlineData struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
 }
 
 // GeminiSystemInstruct represents system instruction
@@ -48,10 +56,15 @@ type GeminiSystemInstruct struct {
 
 // GeminiGenerationConfig contains generation parameters
 type GeminiGenerationConfig struct {
-	Temperature     float64 `json:"temperature,omitempty"`
-	MaxOutputTokens int     `json:"maxOutputTokens,omitempty"`
+	Temperature     *float64 `json:"temperature,omitempty"`
+	TopP            *float64 `json:"topP,omitempty"`
+	Seed            *int64   `json:"seed,omitempty"`
+	MaxOutputTokens int      `json:"maxOutputTokens,omitempty"`
 }
 
+// geminiDefaultTemperature is sent when no temperature is configured (required for reasoning in Gemini 3)
+const geminiDefaultTemperature = 1.0
+
 // GeminiThinkingConfig configures thinking/reasoning for Gemini 3 Pro
 type GeminiThinkingConfig struct {
 	ThinkingBudget int `json:"thinkingBudget,omitempty"` // -1 for dynamic, or specific token count
@@ -88,13 +101,38 @@ func NewGeminiProvider(cfg *ProviderConfig) (LLMProvider, error) {
 		defaultModel = GeminiModelMap[ModelSonnet]
 	}
 
+	httpClient, err := NewHTTPClient(endpointGemini, 0)
+	if err != nil {
+		return nil, err
+	}
+
 	return &GeminiClient{
 		apiKey:       cfg.APIKey,
 		defaultModel: defaultModel,
-		httpClient:   &http.Client{},
+		httpClient:   httpClient,
+		generation:   cfg.Generation,
 	}, nil
 }
 
+// SetGeneration sets temperature, top-p and seed for subsequent requests
+func (c *GeminiClient) SetGeneration(gen GenerationSettings) {
+	c.generation = gen
+}
+
+// generationConfig builds the generation parameters for a request
+func (c *GeminiClient) generationConfig(maxTokens int) *GeminiGenerationConfig {
+	temperature := geminiDefaultTemperature
+	if c.generation.Temperature != nil {
+		temperature = *c.generation.Temperature
+	}
+	return &GeminiGenerationConfig{
+		Temperature:     &temperature,
+		TopP:            c.generation.TopP,
+		Seed:            c.generation.Seed,
+		MaxOutputTokens: maxTokens,
+	}
+}
+
 // Name returns the provider name
 func (c *GeminiClient) Name() string {
 	return "Google Gemini"
@@ -121,11 +159,17 @@ func convertMessagesToGemini(messages []Message) []GeminiContent {
 			role = "model"
 		}
 
+		parts := make([]GeminiPart, 0, len(msg.Images)+1)
+		for _, img := range msg.Images {
+			parts = append(parts, GeminiPart{
+				InlineData: &GeminiInlineData{MimeType: img.MediaType, Data: img.Base64()},
+			})
+		}
+		parts = append(parts, GeminiPart{Text: msg.Content})
+
 		result = append(result, GeminiContent{
-			Role: role,
-			Parts: []GeminiPart{
-				{Text: msg.Content},
-			},
+			Role:  role,
+			Parts: parts,
 		})
 	}
 
@@ -164,12 +208,9 @@ func (c *GeminiClient) Generate(ctx context.Context, model, systemPrompt string,
 	url := fmt.Sprintf(geminiAPIURLTemplate, model) + "?key=" + c.apiKey
 
 	req := GeminiRequest{
-		Contents: convertMessagesToGemini(messages),
-		GenerationConfig: &GeminiGenerationConfig{
-			Temperature:     1.0, // Required for reasoning in Gemini 3
-			MaxOutputTokens: maxTokens,
-		},
-		ThinkingConfig: getThinkingConfig(model, isComplex),
+		Contents:         convertMessagesToGemini(messages),
+		GenerationConfig: c.generationConfig(maxTokens),
+		ThinkingConfig:   getThinkingConfig(model, isComplex),
 	}
 
 	// Add system instruction if provided
@@ -246,12 +287,9 @@ func (c *GeminiClient) GenerateStreaming(ctx context.Context, model, systemPromp
 	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:streamGenerateContent", model) + "?key=" + c.apiKey
 
 	req := GeminiRequest{
-		Contents: convertMessagesToGemini(messages),
-		GenerationConfig: &GeminiGenerationConfig{
-			Temperature:     1.0,
-			MaxOutputTokens: maxTokens,
-		},
-		ThinkingConfig: getThinkingConfig(model, isComplex),
+		Contents:         convertMessagesToGemini(messages),
+		GenerationConfig: c.generationConfig(maxTokens),
+		ThinkingConfig:   getThinkingConfig(model, isComplex),
 	}
 
 	if systemPrompt != "" {
@@ -285,6 +323,7 @@ func (c *GeminiClient) GenerateStreaming(ctx context.Context, model, systemPromp
 
 	// Process streaming response (Gemini returns newline-delimited JSON)
 	var fullText string
+	var inputTokens, outputTokens int
 	decoder := json.NewDecoder(resp.Body)
 
 	for {
@@ -296,6 +335,12 @@ func (c *GeminiClient) GenerateStreaming(ctx context.Context, model, systemPromp
 			continue
 		}
 
+		// Usage metadata, when sent, is cumulative; the last chunk has the totals
+		if chunk.UsageMetadata.PromptTokenCount > 0 {
+			inputTokens = chunk.UsageMetadata.PromptTokenCount
+			outputTokens = chunk.UsageMetadata.CandidatesTokenCount
+		}
+
 		if len(chunk.Candidates) > 0 {
 			for _, part := range chunk.Candidates[0].Content.Parts {
 				if part.Text != "" {
@@ -308,7 +353,11 @@ func (c *GeminiClient) GenerateStreaming(ctx context.Context, model, systemPromp
 		}
 	}
 
-	return &GenerateResult{
-		Text: fullText,
-	}, nil
+	result := &GenerateResult{
+		Text:         fullText,
+		InputTokens:  inputTokens,
+		OutputTokens: outputTokens,
+	}
+	fillUsageEstimate(result, systemPrompt, messages)
+	return result, nil
 }
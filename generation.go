@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Accepted ranges for sampling parameters (widest across providers)
+const (
+	maxTemperature = 2.0
+	maxTopP        = 1.0
+)
+
+// maxClaudeTemperature is the highest temperature the Anthropic and Bedrock Claude APIs accept
+const maxClaudeTemperature = 1.0
+
+// isDefaultArg reports whether an argument clears a parameter back to the provider default
+func isDefaultArg(arg string) bool {
+	switch strings.ToLower(arg) {
+	case "default", "off", "none", "auto":
+		return true
+	}
+	return false
+}
+
+// parseUnitFloat parses a float in [0, limit], or nil for "default"
+func parseUnitFloat(name, arg string, limit float64) (*float64, error) {
+	if isDefaultArg(arg) {
+		return nil, nil
+	}
+	v, err := strconv.ParseFloat(arg, 64)
+	if err != nil || v < 0 || v > limit {
+		return nil, fmt.Errorf("%s must be a number between 0 and %g", name, limit)
+	}
+	return &v, nil
+}
+
+// applyGenerationArgs updates generation settings from /temp arguments:
+//
+//	<value|default>         set temperature
+//	top-p <value|default>   set nucleus sampling cutoff
+//	seed <n|off>            set sampling seed
+//	reset                   clear everything
+//
+// maxTemp is the highest temperature the active provider accepts
+func applyGenerationArgs(gen *GenerationSettings, args []string, maxTemp float64) error {
+	if len(args) == 0 {
+		return nil
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "reset":
+		*gen = GenerationSettings{}
+		return nil
+	case "top-p", "topp", "top_p":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: /temp top-p <0-1|default>")
+		}
+		v, err := parseUnitFloat("top-p", args[1], maxTopP)
+		if err != nil {
+			return err
+		}
+		gen.TopP = v
+		return nil
+	case "seed":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: /temp seed <n|off>")
+		}
+		if isDefaultArg(args[1]) {
+			gen.Seed = nil
+			return nil
+		}
+		n, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("seed must be an integer")
+		}
+		gen.Seed = &n
+		return nil
+	}
+
+	v, err := parseUnitFloat("temperature", args[0], maxTemp)
+	if err != nil {
+		return err
+	}
+	gen.Temperature = v
+	return nil
+}
+
+// claudeSampling returns the temperature and top_p to send to Claude, which rejects requests
+// setting both: top_p is sent only when it is set and the temperature is not
+func (gen GenerationSettings) claudeSampling() (temperature, topP *float64) {
+	if gen.Temperature != nil {
+		return gen.Temperature, nil
+	}
+	return nil, gen.TopP
+}
+
+// formatGeneration renders generation settings as "temperature 0.2 · top-p default · seed off"
+func formatGeneration(gen GenerationSettings) string {
+	temp, topP, seed := "default", "default", "off"
+	if gen.Temperature != nil {
+		temp = strconv.FormatFloat(*gen.Temperature, 'g', -1, 64)
+	}
+	if gen.TopP != nil {
+		topP = strconv.FormatFloat(*gen.TopP, 'g', -1, 64)
+	}
+	if gen.Seed != nil {
+		seed = strconv.FormatInt(*gen.Seed, 10)
+	}
+	return fmt.Sprintf("temperature %s · top-p %s · seed %s", temp, topP, seed)
+}
+
+// providerMaxTemperature returns the highest temperature the provider accepts
+func providerMaxTemperature(p LLMProvider) float64 {
+	switch p := p.(type) {
+	case *AnthropicClient, *BedrockClient:
+		return maxClaudeTemperature
+	case interface{ Unwrap() LLMProvider }:
+		return providerMaxTemperature(p.Unwrap())
+	}
+	return maxTemperature
+}
+
+// providerSupportsSeed reports whether the provider honours a sampling seed
+func providerSupportsSeed(p LLMProvider) bool {
+	switch p := p.(type) {
+	case *OpenAIClient, *GeminiClient:
+		return true
+	case interface{ Unwrap() LLMProvider }:
+		return providerSupportsSeed(p.Unwrap())
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestApplyGenerationArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{"no args", nil, "temperature 0.7 · top-p default · seed 42", false},
+		{"temperature", []string{"0.2"}, "temperature 0.2 · top-p default · seed 42", false},
+		{"temperature default", []string{"default"}, "temperature default · top-p default · seed 42", false},
+		{"top-p", []string{"top-p", "0.9"}, "temperature 0.7 · top-p 0.9 · seed 42", false},
+		{"seed", []string{"seed", "7"}, "temperature 0.7 · top-p default · seed 7", false},
+		{"seed off", []string{"seed", "off"}, "temperature 0.7 · top-p default · seed off", false},
+		{"reset", []string{"reset"}, "temperature default · top-p default · seed off", false},
+		{"temperature out of range", []string{"2.5"}, "", true},
+		{"temperature over the Claude limit", []string{"1.5"}, "", true},
+		{"temperature at the Claude limit", []string{"1"}, "temperature 1 · top-p default · seed 42", false},
+		{"top-p out of range", []string{"top-p", "1.5"}, "", true},
+		{"not a number", []string{"warm"}, "", true},
+		{"seed not an integer", []string{"seed", "1.5"}, "", true},
+		{"missing value", []string{"seed"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			temp, seed := 0.7, int64(42)
+			gen := GenerationSettings{Temperature: &temp, Seed: &seed}
+
+			err := applyGenerationArgs(&gen, tt.args, maxClaudeTemperature)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyGenerationArgs(%v) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := formatGeneration(gen); got != tt.want {
+				t.Errorf("formatGeneration() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGeminiGenerationConfig(t *testing.T) {
+	c := &GeminiClient{}
+	cfg := c.generationConfig(1024)
+	if cfg.Temperature == nil || *cfg.Temperature != geminiDefaultTemperature {
+		t.Errorf("default temperature = %v, want %v", cfg.Temperature, geminiDefaultTemperature)
+	}
+
+	temp, seed := 0.0, int64(3)
+	c.SetGeneration(GenerationSettings{Temperature: &temp, Seed: &seed})
+	body, err := json.Marshal(c.generationConfig(1024))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// An explicit zero temperature must still be sent
+	for _, want := range []string{`"temperature":0`, `"seed":3`, `"maxOutputTokens":1024`} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("generationConfig JSON %s missing %s", body, want)
+		}
+	}
+}
+
+func TestOpenAIApplyGeneration(t *testing.T) {
+	temp, topP, seed := 0.3, 0.8, int64(9)
+	c := &OpenAIClient{generation: GenerationSettings{Temperature: &temp, TopP: &topP, Seed: &seed}}
+
+	req := OpenAIRequest{Model: "gpt-4o"}
+	c.applyGeneration(&req)
+	if req.Temperature == nil || req.TopP == nil || req.Seed == nil {
+		t.Errorf("gpt-4o request = %+v, want temperature, top-p and seed set", req)
+	}
+
+	// Reasoning models reject non-default sampling but accept a seed
+	req = OpenAIRequest{Model: "gpt-5.1-2025-11-13"}
+	c.applyGeneration(&req)
+	if req.Temperature != nil || req.TopP != nil {
+		t.Errorf("reasoning model request sets temperature/top-p: %+v", req)
+	}
+	if req.Seed == nil || *req.Seed != seed {
+		t.Errorf("reasoning model seed = %v, want %d", req.Seed, seed)
+	}
+}
+
+func TestClaudeSampling(t *testing.T) {
+	temp, topP := 0.0, 0.9
+	tests := []struct {
+		name     string
+		gen      GenerationSettings
+		wantTemp *float64
+		wantTopP *float64
+	}{
+		{"neither", GenerationSettings{}, nil, nil},
+		{"temperature", GenerationSettings{Temperature: &temp}, &temp, nil},
+		{"top-p", GenerationSettings{TopP: &topP}, nil, &topP},
+		{"both", GenerationSettings{Temperature: &temp, TopP: &topP}, &temp, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotTemp, gotTopP := tt.gen.claudeSampling()
+			if gotTemp != tt.wantTemp || gotTopP != tt.wantTopP {
+				t.Errorf("claudeSampling() = %v, %v; want %v, %v", gotTemp, gotTopP, tt.wantTemp, tt.wantTopP)
+			}
+		})
+	}
+}
+
+func TestProviderMaxTemperature(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider LLMProvider
+		want     float64
+	}{
+		{"anthropic", &AnthropicClient{}, 1},
+		{"wrapped bedrock", withContextLimits(&BedrockClient{}, &ProviderConfig{}), 1},
+		{"wrapped openai", withContextLimits(&OpenAIClient{}, &ProviderConfig{}), 2},
+		{"gemini", &GeminiClient{}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := providerMaxTemperature(tt.provider); got != tt.want {
+				t.Errorf("providerMaxTemperature() = %g, want %g", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProviderSupportsSeed(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider LLMProvider
+		want     bool
+	}{
+		{"openai", &OpenAIClient{}, true},
+		{"gemini", &GeminiClient{}, true},
+		{"anthropic", &AnthropicClient{}, false},
+		{"wrapped openai", withContextLimits(&OpenAIClient{}, &ProviderConfig{}), true},
+		{"wrapped gemini", withContextLimits(&GeminiClient{}, &ProviderConfig{}), true},
+		{"wrapped bedrock", withContextLimits(&BedrockClient{}, &ProviderConfig{}), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := providerSupportsSeed(tt.provider); got != tt.want {
+				t.Errorf("providerSupportsSeed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// cudaImageHint tells the user where the CUDA toolchain is
+const cudaImageHint = "use the gpu validator image: /config image gpu"
+
+// cudaCodePattern detects CUDA source: kernels, launches or runtime API calls
+var cudaCodePattern = regexp.MustCompile(`__global__|__device__|<<<|\bcuda[A-Z]\w*\s*\(`)
+
+// cudaAllocPattern matches a device or pinned allocation, capturing the API and the pointer it allocates
+var cudaAllocPattern = regexp.MustCompile(`\b(cudaMalloc(?:Managed|Async|Pitch|Host)?)\s*\(\s*(?:\([^)]*\)\s*)?&\s*(\w+)`)
+
+// cudaLaunchPattern matches a kernel launch
+var cudaLaunchPattern = regexp.MustCompile(`\w+\s*<<<[^>]*>>>\s*\(`)
+
+// cudaLaunchCheckPattern matches the calls that pick up a launch's error
+var cudaLaunchCheckPattern = regexp.MustCompile(`\bcuda(?:GetLastError|PeekAtLastError)\s*\(`)
+
+// cudaDiscardedCallPattern matches a statement that calls an error-returning API and drops the result
+var cudaDiscardedCallPattern = regexp.MustCompile(`^\s*(cuda(?:Malloc\w*|Memcpy\w*|Memset\w*|DeviceSynchronize|StreamSynchronize))\s*\(`)
+
+// cudaLaunchCheckLines is how far after a launch its error must be checked
+const cudaLaunchCheckLines = 3
+
+// isCUDACode reports whether the code uses CUDA
+func isCUDACode(code string) bool {
+	return cudaCodePattern.MatchString(code)
+}
+
+// cudaStaticFindings reports allocations never freed, launches whose errors are never
+// checked and API calls whose error result is dropped, as located findings
+func cudaStaticFindings(code, filename string) []string {
+	lines := strings.Split(code, "\n")
+	var findings []string
+	report := func(line int, check, message string) {
+		findings = append(findings, fmt.Sprintf("/src/%s:%d:1: %s: %s [%s]", filename, line+1, LevelError, message, check))
+	}
+
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "//") {
+			continue
+		}
+		for _, m := range cudaAllocPattern.FindAllStringSubmatch(line, -1) {
+			free := "cudaFree"
+			if m[1] == "cudaMallocHost" {
+				free = "cudaFreeHost"
+			}
+			freed := regexp.MustCompile(`\b` + free + `\s*\(\s*(?:\([^)]*\)\s*)?` + regexp.QuoteMeta(m[2]) + `\b`)
+			if !freed.MatchString(code) {
+				report(i, "cuda-missing-free", fmt.Sprintf("%s of %s is never released with %s", m[1], m[2], free))
+			}
+		}
+		if cudaLaunchPattern.MatchString(line) && !launchChecked(lines, i) {
+			report(i, "cuda-unchecked-launch", "kernel launch errors are not checked; call cudaGetLastError() after the launch")
+		}
+		if m := cudaDiscardedCallPattern.FindStringSubmatch(line); m != nil {
+			report(i, "cuda-unchecked-call", fmt.Sprintf("the cudaError_t returned by %s is ignored", m[1]))
+		}
+	}
+	return findings
+}
+
+// launchChecked reports whether cudaGetLastError or cudaPeekAtLastError follows the launch on line i
+func launchChecked(lines []string, i int) bool {
+	for j := i; j < len(lines) && j <= i+cudaLaunchCheckLines; j++ {
+		if cudaLaunchCheckPattern.MatchString(lines[j]) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostHasGPU reports whether the host has an NVIDIA GPU the container can be given
+func hostHasGPU(ctx context.Context) bool {
+	if _, err := exec.LookPath("nvidia-smi"); err != nil {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "nvidia-smi", "-L").Output()
+	return err == nil && strings.Contains(string(out), "GPU")
+}
+
+// gpuDeviceArgs passes the host's GPUs into the container: CDI for podman, --gpus for docker
+func (c *ContainerRuntime) gpuDeviceArgs() []string {
+	if strings.Contains(filepath.Base(c.binary), "docker") {
+		return []string{"--gpus", "all"}
+	}
+	return []string{"--device", "nvidia.com/gpu=all"}
+}
+
+// runCUDACompileValidator compiles the code as CUDA with nvcc
+func (c *ContainerRuntime) runCUDACompileValidator(ctx context.Context, tmpDir, code, filename string) DomainValidationResult {
+	if !isCUDACode(code) {
+		return DomainValidationResult{ValidatorID: ValidatorCUDACompile, Success: true, Output: "No CUDA code detected, skipping"}
+	}
+	result := c.runValidationStage(ctx, tmpDir, "cuda-compile",
+		"sh", "-c",
+		fmt.Sprintf(`command -v nvcc >/dev/null 2>&1 || { echo 'nvcc not installed, skipping (%s)'; exit 0; }
+		nvcc -x cu %s -o /tmp/cuda_test /src/%s 2>&1`, cudaImageHint, c.stdFlag(), filename))
+
+	return DomainValidationResult{
+		ValidatorID: ValidatorCUDACompile,
+		Success:     result.Success,
+		Output:      strings.TrimSpace(result.Output + "\n" + result.Error),
+	}
+}
+
+// runComputeSanitizerValidator runs the program under compute-sanitizer's memcheck and racecheck tools
+// It needs a GPU, so it is skipped on hosts without one
+func (c *ContainerRuntime) runComputeSanitizerValidator(ctx context.Context, tmpDir, code, filename string) DomainValidationResult {
+	if !isCUDACode(code) {
+		return DomainValidationResult{ValidatorID: ValidatorComputeSanitizer, Success: true, Output: "No CUDA code detected, skipping"}
+	}
+	if !hostHasGPU(ctx) {
+		return DomainValidationResult{ValidatorID: ValidatorComputeSanitizer, Success: true, Output: "No GPU available, skipping compute-sanitizer"}
+	}
+	withGPU := *c
+	withGPU.devices = c.gpuDeviceArgs()
+	result := withGPU.runValidationStage(ctx, tmpDir, "compute-sanitizer",
+		"sh", "-c",
+		fmt.Sprintf(`command -v nvcc >/dev/null 2>&1 && command -v compute-sanitizer >/dev/null 2>&1 || { echo 'CUDA toolkit not installed, skipping (%s)'; exit 0; }
+		nvcc -x cu %s -G -lineinfo -o /tmp/cuda_test /src/%s 2>&1 &&
+		echo '=== memcheck ===' && compute-sanitizer --tool memcheck --leak-check full --error-exitcode 1 /tmp/cuda_test 2>&1 &&
+		echo '=== racecheck ===' && compute-sanitizer --tool racecheck --error-exitcode 1 /tmp/cuda_test 2>&1`, cudaImageHint, c.stdFlag(), filename))
+
+	return DomainValidationResult{
+		ValidatorID: ValidatorComputeSanitizer,
+		Success:     result.Success,
+		Output:      strings.TrimSpace(result.Output + "\n" + result.Error),
+	}
+}
+
+// runCUDAStaticValidator checks the source for common CUDA mistakes without compiling it
+func runCUDAStaticValidator(code, filename string) DomainValidationResult {
+	if !isCUDACode(code) {
+		return DomainValidationResult{ValidatorID: ValidatorCUDAStatic, Success: true, Output: "No CUDA code detected, skipping"}
+	}
+	findings := cudaStaticFindings(code, filename)
+	if len(findings) == 0 {
+		return DomainValidationResult{ValidatorID: ValidatorCUDAStatic, Success: true, Output: "No CUDA pitfalls found"}
+	}
+	return DomainValidationResult{
+		ValidatorID: ValidatorCUDAStatic,
+		Output:      strings.Join(findings, "\n"),
+		Metrics:     map[string]interface{}{"findings": len(findings)},
+	}
+}
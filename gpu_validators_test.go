@@ -0,0 +1,92 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const vectorAddKernel = `__global__ void add(const float* a, float* b, int n) {
+    int i = blockIdx.x * blockDim.x + threadIdx.x;
+    if (i < n) b[i] += a[i];
+}
+`
+
+func TestCUDAStaticFindings(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want []string // Checks reported, in order
+	}{
+		{
+			name: "clean",
+			code: vectorAddKernel + `int main() {
+    float *a, *b;
+    if (cudaMalloc(&a, 64) != cudaSuccess) return 1;
+    if (cudaMalloc((void**)&b, 64) != cudaSuccess) return 1;
+    add<<<1, 16>>>(a, b, 16);
+    if (cudaGetLastError() != cudaSuccess) return 1;
+    cudaFree(a);
+    cudaFree((void*)b);
+}
+`,
+		},
+		{
+			name: "leak",
+			code: vectorAddKernel + `int main() {
+    float *a;
+    if (cudaMallocHost(&a, 64) != cudaSuccess) return 1;
+    cudaFree(a);
+}
+`,
+			want: []string{"cuda-missing-free"},
+		},
+		{
+			name: "unchecked launch and call",
+			code: vectorAddKernel + `int main() {
+    float *a;
+    if (cudaMalloc(&a, 64) != cudaSuccess) return 1;
+    add<<<1, 16>>>(a, a, 16);
+    cudaDeviceSynchronize();
+    // cudaMemcpy(a, a, 0, cudaMemcpyDeviceToDevice);
+    cudaFree(a);
+}
+`,
+			want: []string{"cuda-unchecked-launch", "cuda-unchecked-call"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cudaStaticFindings(tt.code, "main.cu")
+			if len(got) != len(tt.want) {
+				t.Fatalf("cudaStaticFindings() = %q, want checks %v", got, tt.want)
+			}
+			for i, check := range tt.want {
+				if !strings.HasSuffix(got[i], "["+check+"]") || !strings.HasPrefix(got[i], "/src/main.cu:") {
+					t.Errorf("finding %d = %q, want %s", i, got[i], check)
+				}
+			}
+		})
+	}
+}
+
+func TestRunCUDAStaticValidator(t *testing.T) {
+	if r := runCUDAStaticValidator("int main() { return 0; }", "main.cpp"); !r.Success || !strings.Contains(r.Output, "No CUDA code") {
+		t.Errorf("runCUDAStaticValidator(plain C++) = %+v, want a skip", r)
+	}
+	leak := vectorAddKernel + "int main() { float* a; if (cudaMalloc(&a, 4)) return 1; }\n"
+	r := runCUDAStaticValidator(leak, "main.cu")
+	if r.Success || !strings.Contains(r.Output, "/src/main.cu:5:1: error: cudaMalloc of a is never released with cudaFree [cuda-missing-free]") {
+		t.Errorf("runCUDAStaticValidator(leak) = %+v", r)
+	}
+}
+
+func TestGPUDeviceArgs(t *testing.T) {
+	for binary, want := range map[string]string{
+		"/usr/bin/podman": "--device nvidia.com/gpu=all",
+		"/usr/bin/docker": "--gpus all",
+	} {
+		if got := strings.Join((&ContainerRuntime{binary: binary}).gpuDeviceArgs(), " "); got != want {
+			t.Errorf("gpuDeviceArgs(%s) = %q, want %q", binary, got, want)
+		}
+	}
+}
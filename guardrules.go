@@ -0,0 +1,64 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// guardRule is one built-in pattern reported under an LLM Guard scanner name
+type guardRule struct {
+	scanner string
+	risk    string
+	pattern *regexp.Regexp
+	group   int // Submatch holding the secret itself, for redaction (0 = whole match)
+}
+
+// secretRules detect credentials in prompts and generated code
+var secretRules = []guardRule{
+	{"Secrets", "AWS access key", regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`), 0},
+	{"Secrets", "private key", regexp.MustCompile(`-----BEGIN (RSA |EC |DSA |OPENSSH |ENCRYPTED )?PRIVATE KEY-----[\s\S]*?(-----END [A-Z ]*PRIVATE KEY-----|$)`), 0},
+	{"Secrets", "GitHub token", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`), 0},
+	{"Secrets", "Anthropic API key", regexp.MustCompile(`\bsk-ant-[A-Za-z0-9_-]{20,}`), 0},
+	{"Secrets", "OpenAI API key", regexp.MustCompile(`\bsk-(proj-)?[A-Za-z0-9]{32,}\b`), 0},
+	{"Secrets", "Google API key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`), 0},
+	{"Secrets", "Slack token", regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`), 0},
+	{"Secrets", "hard-coded credential", regexp.MustCompile(`(?i)\b(password|passwd|secret|api[_-]?key|access[_-]?token)\s*[:=]\s*["']([^"'\s]{8,})["']`), 2},
+}
+
+// injectionRules detect common prompt-injection phrasing (prompts only)
+var injectionRules = []guardRule{
+	{"PromptInjection", "instruction override", regexp.MustCompile(`(?i)\b(ignore|disregard|forget)\s+(all\s+)?(of\s+)?(the\s+|your\s+)?(previous|prior|above|earlier)\s+(instructions|prompts?|rules)`), 0},
+	{"PromptInjection", "system prompt extraction", regexp.MustCompile(`(?i)\b(reveal|print|show|repeat|output)\s+(me\s+)?(your|the)\s+(system\s+prompt|hidden\s+instructions)`), 0},
+	{"PromptInjection", "role hijack", regexp.MustCompile(`(?i)\byou\s+are\s+now\s+(in\s+)?(DAN|developer\s+mode|jailbroken)`), 0},
+}
+
+// scanLocalPrompt checks a prompt against the built-in secret and injection rules
+func scanLocalPrompt(prompt string) *LLMGuardScanResponse {
+	return scanWithRules(prompt, secretRules, injectionRules)
+}
+
+// scanLocalOutput checks generated code against the built-in secret rules
+func scanLocalOutput(output string) *LLMGuardScanResponse {
+	return scanWithRules(output, secretRules)
+}
+
+// scanWithRules reports one result per scanner, listing every matched risk
+func scanWithRules(text string, ruleSets ...[]guardRule) *LLMGuardScanResponse {
+	resp := &LLMGuardScanResponse{IsValid: true, Results: make(map[string]Result)}
+	risks := make(map[string][]string)
+	for _, rules := range ruleSets {
+		for _, rule := range rules {
+			if _, ok := resp.Results[rule.scanner]; !ok {
+				resp.Results[rule.scanner] = Result{IsValid: true}
+			}
+			if rule.pattern.MatchString(text) {
+				risks[rule.scanner] = append(risks[rule.scanner], rule.risk)
+			}
+		}
+	}
+	for scanner, found := range risks {
+		resp.Results[scanner] = Result{Score: 1, IsValid: false, Risk: strings.Join(found, ", ")}
+		resp.IsValid = false
+	}
+	return resp
+}
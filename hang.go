@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxHangTimeout keeps the watchdog below the container's 120s timeout, so a hang is reported
+// as one instead of the container being stopped with no diagnostics
+const maxHangTimeout = 110
+
+// hangMarker starts the watchdog's report on stderr when it kills a program
+const hangMarker = "bjarne: hang:"
+
+// hangBacktraceCommand prints every thread's stack of the process $p to stderr with gdb, or eu-stack without it
+const hangBacktraceCommand = "if command -v gdb >/dev/null 2>&1; then gdb -p $p -batch -nx -ex 'thread apply all bt' 2>/dev/null | grep -E '^(Thread|#)' >&2; " +
+	"elif command -v eu-stack >/dev/null 2>&1; then eu-stack -s -p $p 2>/dev/null >&2; fi"
+
+var hangMarkerPattern = regexp.MustCompile(regexp.QuoteMeta(hangMarker) + ` the program did not finish within (\d+)s`)
+
+// SetHangSettings configures the watchdog on the stages that run the program
+func (c *ContainerRuntime) SetHangSettings(settings HangSettings) {
+	c.hang = settings
+}
+
+// programCommand returns the shell command that runs the built program in a stage, after env
+// assignments such as MSAN_OPTIONS=... It passes the /stdin input, and a watchdog kills the
+// program once it runs past the hang timeout, first printing hangMarker and the threads' stacks
+func (c *ContainerRuntime) programCommand(env, binary string) string {
+	args, redirect := c.programInput()
+	run := c.netnsCommand(env, binary+args)
+	if c.fixtures != nil {
+		run = "cd " + fixtureWorkDir + " && " + run
+	}
+	timeout := c.runTimeout()
+	if timeout <= 0 {
+		return run + redirect
+	}
+	if redirect == "" {
+		redirect = " < /dev/null"
+	}
+
+	backtrace := ""
+	if c.hang.Backtrace {
+		backtrace = hangBacktraceCommand + "; "
+	}
+	return fmt.Sprintf("{ %s%s & p=$!; "+
+		"( trap 'kill $t 2>/dev/null; exit 0' TERM; sleep %d & t=$!; wait $t; "+
+		"kill -0 $p 2>/dev/null || exit 0; echo '%s the program did not finish within %ds' >&2; %skill -9 $p ) & w=$!; "+
+		"wait $p; s=$?; kill $w 2>/dev/null; (exit $s); }",
+		run, redirect, timeout, hangMarker, timeout, backtrace)
+}
+
+// programInput returns the program's quoted arguments and stdin redirection from /stdin, each with a leading space
+func (c *ContainerRuntime) programInput() (string, string) {
+	in := c.input
+	if in.empty() {
+		return "", ""
+	}
+	var args strings.Builder
+	for _, a := range in.Args {
+		args.WriteString(" " + shellQuote(a))
+	}
+	redirect := ""
+	if in.Stdin != "" {
+		redirect = " < /src/" + runInputFile
+	}
+	return args.String(), redirect
+}
+
+// runTimeout is the seconds the program may run in a stage: /stdin's timeout, else hang.timeout (0 = no limit)
+func (c *ContainerRuntime) runTimeout() int {
+	if c.input != nil && c.input.Timeout > 0 {
+		return c.input.Timeout
+	}
+	return c.hang.Timeout
+}
+
+// Hang is a program the watchdog killed, with the frames its threads were stuck in
+type Hang struct {
+	Timeout int          // Seconds the program ran before it was killed
+	Frames  []stackFrame // Every thread's frames, innermost first; empty when no debugger could attach
+}
+
+// parseHang finds the watchdog's report in a stage's output, or returns nil when the program did not hang
+func parseHang(output string) *Hang {
+	idx := strings.Index(output, hangMarker)
+	if idx < 0 {
+		return nil
+	}
+	h := &Hang{Frames: parseStackFrames(strings.Split(output[idx:], "\n")[1:])}
+	if m := hangMarkerPattern.FindStringSubmatch(output[idx:]); m != nil {
+		h.Timeout, _ = strconv.Atoi(m[1])
+	}
+	return h
+}
+
+// Location is where the program appears to be stuck
+func (h *Hang) Location() string {
+	return stackLocation(h.Frames)
+}
+
+// Describe reports the hang for the gate result; it starts with the marker so it parses again
+func (h *Hang) Describe() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s the program did not finish within %ds and was killed\n", hangMarker, h.Timeout)
+	if loc := h.Location(); loc != "" {
+		sb.WriteString("Program appears to hang at " + loc + "\n")
+		sb.WriteString(formatStackFrames(h.Frames) + "\n")
+	} else {
+		sb.WriteString("No backtrace: hang.backtrace is off, or gdb could not attach\n")
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// promptText describes the hang for the fix prompt
+func (h *Hang) promptText() string {
+	text := fmt.Sprintf("Program appears to hang: it did not finish within %ds and was killed.", h.Timeout)
+	if loc := h.Location(); loc != "" {
+		text += " It was stuck at " + loc + "."
+	}
+	text += " Look for a loop whose exit condition never becomes true, a read from stdin that waits for input it never gets, or threads waiting on each other."
+	if len(h.Frames) > 0 {
+		text += "\nBacktrace:\n" + formatStackFrames(h.Frames)
+	}
+	return text
+}
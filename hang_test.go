@@ -0,0 +1,114 @@
+package main
+
+import (
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgramCommand(t *testing.T) {
+	c := &ContainerRuntime{}
+	if got := c.programCommand("MSAN_OPTIONS=halt_on_error=1 ", "/tmp/test"); got != "MSAN_OPTIONS=halt_on_error=1 /tmp/test" {
+		t.Errorf("programCommand() without a watchdog = %q", got)
+	}
+
+	c.input = &RunInput{Stdin: "1 2", Args: []string{"-n", "it's"}, Timeout: 3}
+	c.hang = HangSettings{Timeout: 30, Backtrace: true}
+	got := c.programCommand("", "/tmp/test")
+	for _, want := range []string{"/tmp/test -n 'it'\\''s' < /src/" + runInputFile + " &", "sleep 3 &", "within 3s", "gdb -p $p", "kill -9 $p"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("programCommand() = %q, missing %q", got, want)
+		}
+	}
+
+	c.input = nil
+	c.hang.Backtrace = false
+	if got := c.programCommand("", "/tmp/test"); !strings.Contains(got, "/tmp/test < /dev/null &") || strings.Contains(got, "gdb") {
+		t.Errorf("programCommand() without backtraces = %q", got)
+	}
+}
+
+func TestProgramCommandWatchdog(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs /bin/sh")
+	}
+	c := &ContainerRuntime{hang: HangSettings{Timeout: 1}}
+	tests := []struct {
+		name     string
+		program  string
+		wantCode int
+		wantHang bool
+	}{
+		{"passes", "true", 0, false},
+		{"fails", "false", 1, false},
+		{"hangs", "sleep 30", 137, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			out, err := exec.Command("sh", "-c", c.programCommand("", tt.program)).CombinedOutput()
+			code := 0
+			if exitErr, ok := err.(*exec.ExitError); ok {
+				code = exitErr.ExitCode()
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d (output %q)", code, tt.wantCode, out)
+			}
+			if hang := parseHang(string(out)); (hang != nil) != tt.wantHang || (hang != nil && hang.Timeout != 1) {
+				t.Errorf("parseHang(%q) = %+v, want hang %v", out, hang, tt.wantHang)
+			}
+			if elapsed := time.Since(start); elapsed > 10*time.Second {
+				t.Errorf("took %v; the watchdog did not kill the program", elapsed)
+			}
+		})
+	}
+}
+
+func TestParseHang(t *testing.T) {
+	gdb := "partial output\n" + hangMarker + " the program did not finish within 30s\n" +
+		"Thread 1 (Thread 0x7f00 (LWP 12) \"test\"):\n" +
+		"#0  0x0000555555555189 in spin (n=3) at /src/code.cpp:7\n" +
+		"#1  0x00005555555551a0 in main () at /src/code.cpp:12\n"
+	h := parseHang(gdb)
+	if h == nil || h.Timeout != 30 || len(h.Frames) != 2 {
+		t.Fatalf("parseHang(gdb) = %+v", h)
+	}
+	if got := h.Location(); got != "spin (n=3) at /src/code.cpp:7" {
+		t.Errorf("Location() = %q", got)
+	}
+	if diags := stackDiagnostics(h.Frames, "program hangs here"); len(diags) != 2 || diags[0].Line != 7 {
+		t.Errorf("diagnostics() = %+v", diags)
+	}
+	if again := parseHang(h.Describe()); again == nil || len(again.Frames) != 2 || again.Location() != h.Location() {
+		t.Errorf("parseHang(Describe()) = %+v, want the same hang", again)
+	}
+
+	euStack := hangMarker + " the program did not finish within 5s\nTID 12:\n#0  0x00007f0000001000 __libc_read\n    ../sysdeps/read.c:26\n#1  0x000055555555513d main\n    /src/main.cpp:9:5\n"
+	h = parseHang(euStack)
+	if h == nil || len(h.Frames) != 2 || h.Location() != "main at /src/main.cpp:9" || h.Frames[1].File != "/src/main.cpp" || h.Frames[1].Line != 9 {
+		t.Errorf("parseHang(eu-stack) = %+v", h)
+	}
+
+	h = parseHang(hangMarker + " the program did not finish within 5s\n")
+	if h == nil || h.Location() != "" || !strings.Contains(h.Describe(), "No backtrace") {
+		t.Errorf("parseHang(no backtrace) = %+v", h)
+	}
+	if parseHang("ERROR: AddressSanitizer: heap-use-after-free") != nil {
+		t.Error("parseHang() reported a hang for a sanitizer error")
+	}
+}
+
+func TestFormatErrorForLLMHang(t *testing.T) {
+	code := "int spin(int n) {\n    int i = 0;\n    while (n > 0) {\n        i++;\n    }\n    return i;\n}\n"
+	h := &Hang{Timeout: 30, Frames: []stackFrame{{Text: "#0 spin (n=3) at /src/code.cpp:4", File: "/src/code.cpp", Line: 4}}}
+	got := FormatErrorForLLM("run", h.Describe(), []CodeFile{{Filename: "code.cpp", Content: code}})
+	for _, want := range []string{"[run] Program appears to hang", "stuck at spin (n=3) at /src/code.cpp:4", "Source:", "i++;"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("FormatErrorForLLM() = %q, missing %q", got, want)
+		}
+	}
+}
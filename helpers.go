@@ -5,8 +5,10 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
+	"unicode"
 )
 
 // handleFirstRunPull handles the first-run container pull experience
@@ -88,6 +90,25 @@ func extractCode(response string) string {
 		return files[0].Content
 	}
 	// For backwards compatibility, return all content if multiple files
+	return joinCodeFiles(files)
+}
+
+// textAfterFields returns s after its first n whitespace-separated fields, as typed: the
+// whitespace before the rest is dropped but its line breaks and spacing are kept
+func textAfterFields(s string, n int) string {
+	for range n {
+		s = strings.TrimLeftFunc(s, unicode.IsSpace)
+		end := strings.IndexFunc(s, unicode.IsSpace)
+		if end < 0 {
+			return ""
+		}
+		s = s[end:]
+	}
+	return strings.TrimLeftFunc(s, unicode.IsSpace)
+}
+
+// joinCodeFiles combines files into one blob, each preceded by a // FILE: marker
+func joinCodeFiles(files []CodeFile) string {
 	var sb strings.Builder
 	for i, f := range files {
 		if i > 0 {
@@ -99,6 +120,59 @@ func extractCode(response string) string {
 	return sb.String()
 }
 
+// formatFilesForPrompt renders each file in its own cpp block with a // FILE: marker
+func formatFilesForPrompt(files []CodeFile) string {
+	var sb strings.Builder
+	for i, f := range files {
+		if i > 0 {
+			sb.WriteString("\n\n")
+		}
+		sb.WriteString("```cpp\n// FILE: " + f.Filename + "\n" + f.Content + "\n```")
+	}
+	return sb.String()
+}
+
+// filesInErrors returns the project files mentioned in validation errors, in project order
+func filesInErrors(files []CodeFile, errs string) []string {
+	var named []string
+	for _, f := range files {
+		if strings.Contains(errs, f.Filename) {
+			named = append(named, f.Filename)
+		}
+	}
+	return named
+}
+
+// applyFilePatches replaces project files with patched versions of the same name
+// Patches with new names are added, except an unnamed block (defaulted to code.cpp),
+// which cannot be matched to a file. Returns the merged files and the names that changed.
+func applyFilePatches(files, patches []CodeFile) ([]CodeFile, []string) {
+	merged := append([]CodeFile(nil), files...)
+	index := make(map[string]int, len(files))
+	for i, f := range files {
+		index[f.Filename] = i
+	}
+
+	var changed []string
+	for _, p := range patches {
+		i, ok := index[p.Filename]
+		switch {
+		case ok:
+			if merged[i].Content == p.Content {
+				continue
+			}
+			merged[i].Content = p.Content
+		case p.Filename == "code.cpp":
+			continue
+		default:
+			index[p.Filename] = len(merged)
+			merged = append(merged, p)
+		}
+		changed = append(changed, p.Filename)
+	}
+	return merged, changed
+}
+
 // extractMultipleFiles extracts multiple code files from an LLM response
 // Returns a slice of CodeFile, each with filename and content
 // If no // FILE: markers are found, returns single file with default name
@@ -250,6 +324,14 @@ func saveToFile(filename, code string) error {
 	return os.WriteFile(filename, []byte(code), 0600)
 }
 
+// saveToPath saves code to a file, creating its directory first
+func saveToPath(path, code string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return err
+	}
+	return saveToFile(path, code)
+}
+
 // stripMarkdown removes common markdown formatting from text for terminal display
 func stripMarkdown(text string) string {
 	// Remove code blocks entirely (```...```) - handles various formats:
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -304,3 +305,76 @@ func TestContainsRefusal(t *testing.T) {
 		})
 	}
 }
+
+func TestApplyFilePatches(t *testing.T) {
+	files := []CodeFile{
+		{Filename: "counter.h", Content: "class Counter;"},
+		{Filename: "counter.cpp", Content: "old impl"},
+		{Filename: "main.cpp", Content: "int main() {}"},
+	}
+
+	tests := []struct {
+		name        string
+		patches     []CodeFile
+		wantChanged []string
+		wantCount   int
+	}{
+		{"patch one file", []CodeFile{{Filename: "counter.cpp", Content: "new impl"}}, []string{"counter.cpp"}, 3},
+		{"unchanged content", []CodeFile{{Filename: "main.cpp", Content: "int main() {}"}}, nil, 3},
+		{"new file", []CodeFile{{Filename: "util.h", Content: "int util();"}}, []string{"util.h"}, 4},
+		{"unnamed block ignored", []CodeFile{{Filename: "code.cpp", Content: "int x;"}}, nil, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, changed := applyFilePatches(files, tt.patches)
+			if len(merged) != tt.wantCount {
+				t.Errorf("got %d files, want %d", len(merged), tt.wantCount)
+			}
+			if strings.Join(changed, ",") != strings.Join(tt.wantChanged, ",") {
+				t.Errorf("changed = %v, want %v", changed, tt.wantChanged)
+			}
+		})
+	}
+
+	if files[1].Content != "old impl" {
+		t.Error("applyFilePatches modified its input")
+	}
+}
+
+func TestFilesInErrors(t *testing.T) {
+	files := []CodeFile{{Filename: "counter.h"}, {Filename: "counter.cpp"}, {Filename: "main.cpp"}}
+	errs := "compile: /src/counter.cpp:12:5: error: use of undeclared identifier 'n'"
+	if got := filesInErrors(files, errs); len(got) != 1 || got[0] != "counter.cpp" {
+		t.Errorf("filesInErrors() = %v, want [counter.cpp]", got)
+	}
+}
+
+func TestJoinCodeFilesRoundTrip(t *testing.T) {
+	files := []CodeFile{{Filename: "a.h", Content: "int a();"}, {Filename: "a.cpp", Content: "int a() { return 1; }"}}
+	got := extractMultipleFiles(formatFilesForPrompt(files))
+	if len(got) != 2 || got[0] != files[0] || got[1] != files[1] {
+		t.Errorf("extractMultipleFiles(formatFilesForPrompt()) = %+v, want %+v", got, files)
+	}
+	if !strings.HasPrefix(joinCodeFiles(files), "// FILE: a.h\nint a();") {
+		t.Errorf("joinCodeFiles() = %q", joinCodeFiles(files))
+	}
+}
+
+func TestTextAfterFields(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"/compare haiku opus write a  queue\n\n- lock-free\n- bounded", 3, "write a  queue\n\n- lock-free\n- bounded"},
+		{"/compare  haiku\topus\n  indented", 3, "indented"},
+		{"/compare haiku opus", 3, ""},
+		{"/compare haiku", 3, ""},
+	}
+	for _, tt := range tests {
+		if got := textAfterFields(tt.s, tt.n); got != tt.want {
+			t.Errorf("textAfterFields(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// chromaStyles maps bjarne theme presets to the closest chroma style
+var chromaStyles = map[string]string{
+	"default":   "monokai",
+	"matrix":    "vim",
+	"solarized": "solarized-dark",
+	"gruvbox":   "gruvbox",
+	"dracula":   "dracula",
+	"nord":      "nord",
+}
+
+// colorDisabled reports whether the terminal asked for no color (https://no-color.org), or plain mode is on
+func colorDisabled() bool {
+	return os.Getenv("NO_COLOR") != "" || plainMode
+}
+
+// highlightCode colorizes source for the terminal using the theme's chroma style
+// lang may be a language name ("cpp", "diff") or a filename to match on
+// theme is a preset name or a chroma style name (from a custom theme)
+// Returns the input unchanged if highlighting fails
+func highlightCode(code, lang, theme string) string {
+	lexer := lexers.Get(lang)
+	if lexer == nil {
+		lexer = lexers.Match(lang)
+	}
+	if lexer == nil {
+		lexer = lexers.Get("cpp")
+	}
+	lexer = chroma.Coalesce(lexer)
+
+	styleName, ok := chromaStyles[theme]
+	if !ok {
+		styleName = chromaStyles["default"]
+		if _, ok := styles.Registry[theme]; ok {
+			styleName = theme
+		}
+	}
+	style := styles.Get(styleName)
+
+	formatter := formatters.Get("terminal256")
+	if truecolorSupported() {
+		formatter = formatters.Get("terminal16m")
+	}
+
+	iterator, err := lexer.Tokenise(nil, code)
+	if err != nil {
+		return code
+	}
+
+	var sb strings.Builder
+	if err := formatter.Format(&sb, style, iterator); err != nil {
+		return code
+	}
+	return sb.String()
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHighlightCode(t *testing.T) {
+	code := "#include <iostream>\nint main() {\n    return 0;\n}\n"
+
+	tests := []struct {
+		name  string
+		lang  string
+		theme string
+	}{
+		{"cpp by name", "cpp", "default"},
+		{"match by filename", "widget.hpp", "dracula"},
+		{"unknown theme falls back", "cpp", "no-such-theme"},
+		{"unknown language falls back to cpp", "???", "nord"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := highlightCode(code, tt.lang, tt.theme)
+			if !strings.Contains(got, "\x1b[") {
+				t.Error("expected ANSI color codes in highlighted output")
+			}
+			if stripANSI(got) != code {
+				t.Errorf("highlighting changed the text:\n%q", stripANSI(got))
+			}
+		})
+	}
+}
+
+func TestColorDisabled(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	if colorDisabled() {
+		t.Error("colorDisabled() = true with NO_COLOR unset")
+	}
+	t.Setenv("NO_COLOR", "1")
+	if !colorDisabled() {
+		t.Error("colorDisabled() = false with NO_COLOR=1")
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// historyManifestName is the sidecar file describing the auto-saved outputs
+const historyManifestName = "manifest.json"
+
+// historyProjectSuffix marks a multi-file auto-save directory
+const historyProjectSuffix = "_project"
+
+// historyLockTimeout bounds the wait for another session updating the manifest
+const historyLockTimeout = 5 * time.Second
+
+// HistoryEntry describes one auto-saved output in ~/.bjarne/history
+type HistoryEntry struct {
+	ID         string           `json:"id"` // File or directory name in the history directory
+	Created    time.Time        `json:"created"`
+	Prompt     string           `json:"prompt,omitempty"`
+	Files      []string         `json:"files,omitempty"` // Original file names (single-file code has one)
+	Model      string           `json:"model,omitempty"`
+	Gates      []checkpointGate `json:"gates,omitempty"`
+	Confidence int              `json:"confidence,omitempty"`
+	Session    string           `json:"session,omitempty"`
+}
+
+// GateSummary describes the gates the entry passed and its review confidence ("" when unknown)
+func (e *HistoryEntry) GateSummary() string {
+	if len(e.Gates) == 0 {
+		return ""
+	}
+	c := Checkpoint{Gates: e.Gates, Validated: true, Confidence: e.Confidence} // Only validated code is auto-saved
+	return c.Status()
+}
+
+// HistoryManifest holds the metadata of the auto-saved outputs (/history)
+type HistoryManifest struct {
+	dir     string
+	Entries []HistoryEntry `json:"entries"`
+}
+
+// historyDir returns ~/.bjarne/history
+func historyDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".bjarne", "history"), nil
+}
+
+// loadHistoryManifest reads the manifest in dir
+// A missing or unreadable manifest yields an empty one
+func loadHistoryManifest(dir string) *HistoryManifest {
+	h := &HistoryManifest{dir: dir}
+	data, err := os.ReadFile(filepath.Join(dir, historyManifestName))
+	if err != nil {
+		return h
+	}
+	if err := json.Unmarshal(data, h); err != nil {
+		h.Entries = nil
+	}
+	return h
+}
+
+// historyName names an auto-save made at now, e.g. 2026-10-16_150405-9f3a61c0.cpp
+// The session's random suffix keeps two sessions saving in the same second apart
+func historyName(now time.Time, session string, project bool) string {
+	name := now.Format("2006-01-02_150405")
+	if i := strings.LastIndex(session, "-"); i >= 0 && i < len(session)-1 {
+		name += "-" + session[i+1:]
+	}
+	if project {
+		return name + historyProjectSuffix
+	}
+	return name + ".cpp"
+}
+
+// update applies change to the entries on disk and writes them back
+// Other sessions may have added entries since h was loaded, so the manifest is
+// re-read under a lock rather than overwritten with h's copy
+func (h *HistoryManifest) update(change func([]HistoryEntry) []HistoryEntry) error {
+	if err := os.MkdirAll(h.dir, 0750); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	lock, err := lockWait(filepath.Join(h.dir, historyManifestName+".lock"), historyLockTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to lock history manifest: %w", err)
+	}
+	defer func() { _ = lock.Unlock() }()
+
+	h.Entries = change(loadHistoryManifest(h.dir).Entries)
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode history manifest: %w", err)
+	}
+	if err := writeFileAtomic(filepath.Join(h.dir, historyManifestName), data, 0600); err != nil {
+		return fmt.Errorf("failed to write history manifest: %w", err)
+	}
+	return nil
+}
+
+// Add records an auto-saved output
+func (h *HistoryManifest) Add(e HistoryEntry) error {
+	return h.update(func(entries []HistoryEntry) []HistoryEntry {
+		return append(entries, e)
+	})
+}
+
+// List returns the auto-saved outputs, newest first
+// Outputs saved before the manifest existed are listed without metadata
+func (h *HistoryManifest) List() []HistoryEntry {
+	known := make(map[string]HistoryEntry, len(h.Entries))
+	for _, e := range h.Entries {
+		known[e.ID] = e
+	}
+
+	dirEntries, err := os.ReadDir(h.dir)
+	if err != nil {
+		return nil
+	}
+	var entries []HistoryEntry
+	for _, d := range dirEntries {
+		name := d.Name()
+		autoSaved := (d.IsDir() && strings.HasSuffix(name, historyProjectSuffix)) || (!d.IsDir() && filepath.Ext(name) == ".cpp")
+		if !autoSaved {
+			continue // Manifest, session checkpoints, ...
+		}
+		if e, ok := known[name]; ok {
+			entries = append(entries, e)
+			continue
+		}
+		e := HistoryEntry{ID: name}
+		if info, err := d.Info(); err == nil {
+			e.Created = info.ModTime()
+		}
+		entries = append(entries, e)
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Created.After(entries[j].Created) })
+	return entries
+}
+
+// Find resolves a /history reference: a number from the listing or an entry ID
+func (h *HistoryManifest) Find(ref string) (HistoryEntry, error) {
+	entries := h.List()
+	if n, err := strconv.Atoi(ref); err == nil {
+		if n < 1 || n > len(entries) {
+			return HistoryEntry{}, fmt.Errorf("no history entry %d (there are %d)", n, len(entries))
+		}
+		return entries[n-1], nil
+	}
+	for _, e := range entries {
+		if e.ID == ref {
+			return e, nil
+		}
+	}
+	return HistoryEntry{}, fmt.Errorf("no history entry %q", ref)
+}
+
+// Files reads the code of an entry
+func (h *HistoryManifest) Files(e HistoryEntry) ([]CodeFile, error) {
+	path := filepath.Join(h.dir, e.ID)
+	if !strings.HasSuffix(e.ID, historyProjectSuffix) {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", e.ID, err)
+		}
+		name := "code.cpp"
+		if len(e.Files) == 1 {
+			name = e.Files[0]
+		}
+		return []CodeFile{{Filename: name, Content: string(content)}}, nil
+	}
+
+	names := e.Files
+	if len(names) == 0 {
+		dirEntries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", e.ID, err)
+		}
+		for _, d := range dirEntries {
+			if !d.IsDir() {
+				names = append(names, d.Name())
+			}
+		}
+	}
+	files := make([]CodeFile, 0, len(names))
+	for _, name := range names {
+		content, err := os.ReadFile(filepath.Join(path, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", filepath.Join(e.ID, name), err)
+		}
+		files = append(files, CodeFile{Filename: name, Content: string(content)})
+	}
+	return files, nil
+}
+
+// Delete removes an entry's files and its manifest record
+func (h *HistoryManifest) Delete(e HistoryEntry) error {
+	if err := os.RemoveAll(filepath.Join(h.dir, e.ID)); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", e.ID, err)
+	}
+	return h.update(func(entries []HistoryEntry) []HistoryEntry {
+		kept := entries[:0]
+		for _, known := range entries {
+			if known.ID != e.ID {
+				kept = append(kept, known)
+			}
+		}
+		return kept
+	})
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistoryManifest(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("2026-10-16_090000.cpp", "int legacy;\n") // Saved before the manifest existed
+	write("2026-10-16_100000.cpp", "int single;\n")
+	write("2026-10-16_110000_project/queue.h", "struct Q {};\n")
+	write("2026-10-16_110000_project/main.cpp", "int main() {}\n")
+	write("20261016-100000-abcd/checkpoints/baseline.json", "{}") // Session checkpoints are not auto-saves
+	old := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(dir, "2026-10-16_090000.cpp"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	h := loadHistoryManifest(dir)
+	now := time.Now()
+	gates := checkpointGates([]ValidationResult{{Stage: "compile", Success: true}, {Stage: "asan", Success: true}})
+	if err := h.Add(HistoryEntry{ID: "2026-10-16_100000.cpp", Created: now, Prompt: "a counter", Files: []string{"counter.cpp"}, Model: "sonnet", Gates: gates, Confidence: 90}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := h.Add(HistoryEntry{ID: "2026-10-16_110000_project", Created: now.Add(time.Minute), Prompt: "a queue", Files: []string{"queue.h", "main.cpp"}}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	// Reload to check the manifest round-trips
+	h = loadHistoryManifest(dir)
+	entries := h.List()
+	var ids []string
+	for _, e := range entries {
+		ids = append(ids, e.ID)
+	}
+	if got := strings.Join(ids, " "); got != "2026-10-16_110000_project 2026-10-16_100000.cpp 2026-10-16_090000.cpp" {
+		t.Fatalf("List() = %s, want newest first without the checkpoint directory", got)
+	}
+	if got := entries[1].GateSummary(); got != "2/2 gates passed, 90% confidence" {
+		t.Errorf("GateSummary() = %q", got)
+	}
+
+	e, err := h.Find("2")
+	if err != nil || e.Prompt != "a counter" {
+		t.Fatalf("Find(2) = %+v, %v", e, err)
+	}
+	files, err := h.Files(e)
+	if err != nil || len(files) != 1 || files[0].Filename != "counter.cpp" || files[0].Content != "int single;\n" {
+		t.Errorf("Files(single) = %+v, %v", files, err)
+	}
+
+	e, _ = h.Find("2026-10-16_110000_project")
+	files, err = h.Files(e)
+	if err != nil || len(files) != 2 || files[0].Filename != "queue.h" {
+		t.Errorf("Files(project) = %+v, %v; want the manifest's file order", files, err)
+	}
+
+	e, _ = h.Find("3")
+	if files, err := h.Files(e); err != nil || files[0].Filename != "code.cpp" {
+		t.Errorf("Files(legacy) = %+v, %v", files, err)
+	}
+
+	for _, ref := range []string{"0", "4", "missing"} {
+		if _, err := h.Find(ref); err == nil {
+			t.Errorf("Find(%q) should fail", ref)
+		}
+	}
+
+	e, _ = h.Find("2")
+	if err := h.Delete(e); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, e.ID)); !os.IsNotExist(err) {
+		t.Errorf("Delete() left %s on disk", e.ID)
+	}
+	if got := loadHistoryManifest(dir); len(got.Entries) != 1 || len(got.List()) != 2 {
+		t.Errorf("after Delete() manifest has %d entries, listing %d", len(got.Entries), len(got.List()))
+	}
+}
+
+func TestHistoryConcurrentSessions(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 10, 16, 15, 4, 5, 0, time.Local)
+	a := historyName(now, "20261016-150000-9f3a61c0", false)
+	b := historyName(now, "20261016-150001-0c12ab34", true)
+	if a != "2026-10-16_150405-9f3a61c0.cpp" || b != "2026-10-16_150405-0c12ab34_project" {
+		t.Errorf("historyName() = %s, %s", a, b)
+	}
+	if got := historyName(now, "", false); got != "2026-10-16_150405.cpp" {
+		t.Errorf("historyName() without a session = %s", got)
+	}
+
+	// Two sessions loaded the manifest before either saved: neither entry is lost
+	first, second := loadHistoryManifest(dir), loadHistoryManifest(dir)
+	if err := first.Add(HistoryEntry{ID: a, Created: now}); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Add(HistoryEntry{ID: b, Created: now}); err != nil {
+		t.Fatal(err)
+	}
+	if got := loadHistoryManifest(dir).Entries; len(got) != 2 {
+		t.Fatalf("manifest holds %d entries, want both sessions' saves", len(got))
+	}
+	if err := first.Delete(HistoryEntry{ID: a}); err != nil {
+		t.Fatal(err)
+	}
+	if got := loadHistoryManifest(dir).Entries; len(got) != 1 || got[0].ID != b {
+		t.Errorf("after Delete() manifest = %+v, want only %s", got, b)
+	}
+}
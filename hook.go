@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// hookMarker identifies a pre-commit hook written by bjarne
+const hookMarker = "# Installed by bjarne hook install"
+
+// hookSkipEnv lets a single commit bypass the hook
+const hookSkipEnv = "BJARNE_SKIP_HOOK"
+
+// hookUsage is printed for bad `bjarne hook` arguments
+const hookUsage = `Usage:
+  bjarne hook install [--gates <list>] [--force]
+  bjarne hook uninstall
+  bjarne hook run [--gates <list>]`
+
+// runHook implements `bjarne hook`
+func runHook(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, hookUsage)
+		return 1
+	}
+
+	var gates []string
+	force := false
+	for i := 1; i < len(args); i++ {
+		switch args[i] {
+		case "--gates":
+			if i+1 >= len(args) {
+				fmt.Fprintln(os.Stderr, hookUsage)
+				return 1
+			}
+			var err error
+			if gates, err = ParseGateList(args[i+1]); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+			i++
+		case "--force":
+			force = true
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown hook option: %s\n%s\n", args[i], hookUsage)
+			return 1
+		}
+	}
+
+	var err error
+	switch args[0] {
+	case "install":
+		err = installHook(gates, force)
+	case "uninstall":
+		err = uninstallHook()
+	case "run":
+		return runPreCommit(gates)
+	default:
+		fmt.Fprintln(os.Stderr, hookUsage)
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// preCommitHookPath returns where git looks for the pre-commit hook (honours core.hooksPath)
+func preCommitHookPath() (string, error) {
+	out, err := exec.Command("git", "rev-parse", "--git-path", "hooks/pre-commit").Output()
+	if err != nil {
+		return "", fmt.Errorf("not in a git repository: %w", err)
+	}
+	path := strings.TrimSpace(string(out))
+	if !filepath.IsAbs(path) {
+		if path, err = filepath.Abs(path); err != nil {
+			return "", fmt.Errorf("failed to resolve hook path: %w", err)
+		}
+	}
+	return path, nil
+}
+
+// hookScript is the pre-commit hook that calls back into bjarne
+func hookScript(bin string, gates []string) string {
+	run := shellQuote(bin) + " hook run"
+	if len(gates) > 0 {
+		run += " --gates " + strings.Join(gates, ",")
+	}
+	return fmt.Sprintf(`#!/bin/sh
+%s; remove with: bjarne hook uninstall
+# Skip once with %s=1 git commit (or git commit --no-verify)
+[ -n "$%s" ] && exit 0
+exec %s
+`, hookMarker, hookSkipEnv, hookSkipEnv, run)
+}
+
+// installHook writes the pre-commit hook; an existing hook from another tool is kept unless force
+// With force it is moved aside to pre-commit.bak
+func installHook(gates []string, force bool) error {
+	path, err := preCommitHookPath()
+	if err != nil {
+		return err
+	}
+
+	existing, err := os.ReadFile(path)
+	switch {
+	case err == nil && !strings.Contains(string(existing), hookMarker):
+		if !force {
+			return fmt.Errorf("%s already exists and was not written by bjarne (use --force to replace it; the old hook is kept as pre-commit.bak)", path)
+		}
+		if err := os.Rename(path, path+".bak"); err != nil {
+			return fmt.Errorf("failed to back up existing hook: %w", err)
+		}
+	case err != nil && !os.IsNotExist(err):
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	// Prefer the bjarne on PATH so the hook survives upgrades; fall back to this binary
+	bin := "bjarne"
+	if _, err := exec.LookPath(bin); err != nil {
+		if bin, err = os.Executable(); err != nil {
+			return fmt.Errorf("cannot locate the bjarne binary: %w", err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, []byte(hookScript(bin, gates)), 0700); err != nil { //nolint:gosec // hooks must be executable
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	desc := "the gates in settings hook.gates"
+	if len(gates) > 0 {
+		desc = strings.Join(gates, ", ")
+	}
+	fmt.Printf("Installed pre-commit hook at %s\n", path)
+	fmt.Printf("Staged C/C++ files are checked with %s. Skip once with %s=1.\n", desc, hookSkipEnv)
+	return nil
+}
+
+// uninstallHook removes the pre-commit hook if bjarne wrote it
+func uninstallHook() error {
+	path, err := preCommitHookPath()
+	if err != nil {
+		return err
+	}
+	existing, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		fmt.Println("No pre-commit hook installed")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if !strings.Contains(string(existing), hookMarker) {
+		return fmt.Errorf("%s was not written by bjarne; leaving it alone", path)
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	fmt.Printf("Removed pre-commit hook %s\n", path)
+	return nil
+}
+
+// runPreCommit validates the staged C/C++ files with the hook's gate subset
+// Files are read from the index, so unstaged edits neither pass nor fail the commit
+func runPreCommit(gates []string) int {
+	if os.Getenv(hookSkipEnv) != "" {
+		return 0
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	files, err := stagedFiles(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "bjarne: %v\n", err)
+		return 1
+	}
+	if len(files) == 0 {
+		return 0
+	}
+
+	cfg := LoadConfig()
+	if len(gates) == 0 {
+		if gates, err = ParseGateList(strings.Join(cfg.Settings.Hook.Gates, ",")); err != nil {
+			fmt.Fprintf(os.Stderr, "bjarne: settings hook.gates: %v\n", err)
+			return 1
+		}
+	}
+	container, err := newValidationRuntime(ctx, cfg)
+	if err != nil {
+		fmt.Fprint(os.Stderr, FormatUserError(err))
+		fmt.Fprintf(os.Stderr, "Commit without validation: %s=1 git commit\n", hookSkipEnv)
+		return 1
+	}
+	selection := GateSelection{Only: gates}
+	container.SetGateSelection(selection)
+
+	fmt.Printf("bjarne: checking %d staged file(s) (%s)\n", len(files), selection)
+	outcomes := validateFileList(ctx, containerCheck(container), files, readStagedFile, false)
+
+	for _, r := range outcomes {
+		if !r.Passed() {
+			fmt.Printf("\n%s\nCommit blocked. Fix the findings, or skip once with %s=1 git commit\n", ciSummaryTable(outcomes), hookSkipEnv)
+			return 1
+		}
+	}
+	if len(outcomes) < len(files) {
+		return 1 // Interrupted
+	}
+	return 0
+}
+
+// stagedFiles lists the C/C++ files added, copied, modified or renamed in the index
+func stagedFiles(ctx context.Context) ([]string, error) {
+	out, err := exec.CommandContext(ctx, "git", "diff", "--cached", "--name-only", "--diff-filter=ACMR", "--relative").Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("git diff --cached failed: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("git diff --cached failed: %w", err)
+	}
+	return filterCppFiles(strings.Split(string(out), "\n")), nil
+}
+
+// readStagedFile reads a file's staged contents, falling back to disk for untracked files
+func readStagedFile(path string) (string, bool) {
+	rel := filepath.ToSlash(path)
+	if !filepath.IsAbs(path) {
+		rel = "./" + rel // Relative to the working directory, not the repository root
+	}
+	out, err := exec.Command("git", "show", ":"+rel).Output()
+	if err != nil {
+		return readDiskFile(path)
+	}
+	return string(out), true
+}
+
+// shellQuote quotes a word for /bin/sh when it needs it
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r == '/' || r == '.' || r == '-' || r == '_' || r == ':' || r == '\\' ||
+			(r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9'))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestShellQuote(t *testing.T) {
+	tests := map[string]string{
+		"bjarne":                        "bjarne",
+		"/usr/local/bin/bjarne":         "/usr/local/bin/bjarne",
+		"/Users/me/My Tools/bjarne":     "'/Users/me/My Tools/bjarne'",
+		"/opt/it's/bjarne":              `'/opt/it'\''s/bjarne'`,
+		"C:\\Program Files\\bjarne.exe": "'C:\\Program Files\\bjarne.exe'",
+		"":                              "''",
+	}
+	for in, want := range tests {
+		if got := shellQuote(in); got != want {
+			t.Errorf("shellQuote(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestInstallHook(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", dir).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	t.Chdir(dir)
+	hook := filepath.Join(dir, ".git", "hooks", "pre-commit")
+
+	if err := installHook([]string{"clang-tidy"}, false); err != nil {
+		t.Fatalf("installHook() error = %v", err)
+	}
+	script, err := os.ReadFile(hook)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(script), hookMarker) || !strings.Contains(string(script), "hook run --gates clang-tidy") ||
+		!strings.Contains(string(script), `[ -n "$BJARNE_SKIP_HOOK" ] && exit 0`) {
+		t.Errorf("hook script:\n%s", script)
+	}
+
+	// Reinstalling over our own hook is fine
+	if err := installHook(nil, false); err != nil {
+		t.Errorf("reinstall error = %v", err)
+	}
+
+	// Someone else's hook is only replaced with --force, and kept as a backup
+	if err := os.WriteFile(hook, []byte("#!/bin/sh\nnpm test\n"), 0700); err != nil { //nolint:gosec // test hook
+		t.Fatal(err)
+	}
+	if err := installHook(nil, false); err == nil {
+		t.Error("installHook() replaced a foreign hook without --force")
+	}
+	if err := uninstallHook(); err == nil {
+		t.Error("uninstallHook() removed a foreign hook")
+	}
+	if err := installHook(nil, true); err != nil {
+		t.Fatalf("installHook(force) error = %v", err)
+	}
+	if backup, err := os.ReadFile(hook + ".bak"); err != nil || !strings.Contains(string(backup), "npm test") {
+		t.Errorf("backup = %q, %v", backup, err)
+	}
+
+	if err := uninstallHook(); err != nil {
+		t.Fatalf("uninstallHook() error = %v", err)
+	}
+	if _, err := os.Stat(hook); !os.IsNotExist(err) {
+		t.Errorf("hook still present after uninstall: %v", err)
+	}
+}
+
+func TestReadStagedFile(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", dir).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	t.Chdir(dir)
+
+	if err := os.WriteFile("main.cpp", []byte("staged\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("git", "add", "main.cpp").CombinedOutput(); err != nil {
+		t.Fatalf("git add: %v\n%s", err, out)
+	}
+	if err := os.WriteFile("main.cpp", []byte("unstaged edit\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("untracked.h", []byte("on disk\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, ok := readStagedFile("main.cpp"); !ok || got != "staged\n" {
+		t.Errorf("readStagedFile(main.cpp) = %q, %v; want the index contents", got, ok)
+	}
+	if got, ok := readStagedFile("untracked.h"); !ok || got != "on disk\n" {
+		t.Errorf("readStagedFile(untracked.h) = %q, %v; want the disk contents", got, ok)
+	}
+	if _, ok := readStagedFile("missing.h"); ok {
+		t.Error("readStagedFile(missing.h) reported a file")
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Endpoint names for per-endpoint TLS settings (network.tls in settings.json)
+const (
+	endpointAnthropic = "anthropic"
+	endpointOpenAI    = "openai"
+	endpointGemini    = "gemini"
+	endpointLocal     = "local"
+	endpointBedrock   = "bedrock"
+	endpointLLMGuard  = "llmguard"
+	endpointDownloads = "downloads" // ONNX runtime, embedding model, podman installer
+	endpointUpdates   = "updates"   // GitHub release check
+)
+
+// networkSettings applies to every HTTP client created with NewHTTPClient (set at startup)
+var networkSettings NetworkSettings
+
+// SetNetworkSettings configures the CA bundle and TLS settings for outbound HTTP
+func SetNetworkSettings(s NetworkSettings) {
+	networkSettings = s
+}
+
+// NewHTTPClient returns a client for an endpoint that honors HTTP_PROXY/HTTPS_PROXY/NO_PROXY,
+// the configured CA bundle, and the endpoint's TLS settings (timeout 0 = none, for streaming)
+func NewHTTPClient(endpoint string, timeout time.Duration) (*http.Client, error) {
+	tlsConfig, err := networkSettings.tlsConfig(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport, Timeout: timeout}, nil
+}
+
+// tlsConfig builds the TLS configuration for an endpoint
+// The endpoint's caBundle replaces the global one; both add to the system roots
+func (s NetworkSettings) tlsConfig(endpoint string) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	endpointTLS := s.TLS[endpoint]
+
+	bundle := s.CABundle
+	if endpointTLS.CABundle != "" {
+		bundle = endpointTLS.CABundle
+	}
+	if bundle != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := os.ReadFile(bundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificates found in CA bundle %s", bundle)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if endpointTLS.ClientCert != "" || endpointTLS.ClientKey != "" {
+		cert, err := tls.LoadX509KeyPair(endpointTLS.ClientCert, endpointTLS.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s client certificate: %w", endpoint, err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if endpointTLS.InsecureSkipVerify {
+		cfg.InsecureSkipVerify = true //nolint:gosec // explicit opt-in for TLS-intercepting proxies
+	}
+	return cfg, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewHTTPClientCABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	bundle := filepath.Join(dir, "ca.pem")
+	pemData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(bundle, pemData, 0600); err != nil {
+		t.Fatal(err)
+	}
+	notPEM := filepath.Join(dir, "empty.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		settings NetworkSettings
+		endpoint string
+		wantErr  bool // Client construction fails
+		wantOK   bool // Request to the test server succeeds
+	}{
+		{"system roots only", NetworkSettings{}, endpointOpenAI, false, false},
+		{"global bundle", NetworkSettings{CABundle: bundle}, endpointOpenAI, false, true},
+		{"endpoint bundle", NetworkSettings{TLS: map[string]TLSSettings{endpointGemini: {CABundle: bundle}}}, endpointGemini, false, true},
+		{"endpoint bundle elsewhere", NetworkSettings{TLS: map[string]TLSSettings{endpointGemini: {CABundle: bundle}}}, endpointOpenAI, false, false},
+		{"insecure endpoint", NetworkSettings{TLS: map[string]TLSSettings{endpointLocal: {InsecureSkipVerify: true}}}, endpointLocal, false, true},
+		{"missing bundle", NetworkSettings{CABundle: filepath.Join(dir, "missing.pem")}, endpointOpenAI, true, false},
+		{"bundle without certificates", NetworkSettings{CABundle: notPEM}, endpointOpenAI, true, false},
+		{"missing client certificate", NetworkSettings{TLS: map[string]TLSSettings{endpointAnthropic: {ClientCert: "nope.pem", ClientKey: "nope.key"}}}, endpointAnthropic, true, false},
+	}
+
+	defer SetNetworkSettings(NetworkSettings{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetNetworkSettings(tt.settings)
+			client, err := NewHTTPClient(tt.endpoint, 0)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewHTTPClient() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			resp, err := client.Get(server.URL)
+			if err == nil {
+				_ = resp.Body.Close()
+			}
+			if (err == nil) != tt.wantOK {
+				t.Errorf("GET error = %v, want success %v", err, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxImageBytes is the largest image accepted (Claude's per-image limit)
+const maxImageBytes = 5 * 1024 * 1024
+
+// supportedImageTypes are the media types accepted by the multimodal providers
+var supportedImageTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// ImageAttachment is an image sent alongside a user message
+type ImageAttachment struct {
+	Name      string
+	MediaType string
+	Data      []byte
+}
+
+// Base64 returns the image data base64-encoded for API requests
+func (img ImageAttachment) Base64() string {
+	return base64.StdEncoding.EncodeToString(img.Data)
+}
+
+// LoadImage reads an image file, checking its size and type
+func LoadImage(path string) (ImageAttachment, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ImageAttachment{}, fmt.Errorf("failed to read image: %w", err)
+	}
+	if info.Size() > maxImageBytes {
+		return ImageAttachment{}, fmt.Errorf("image too large (%d KB, max %d KB)", info.Size()/1024, maxImageBytes/1024)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ImageAttachment{}, fmt.Errorf("failed to read image: %w", err)
+	}
+
+	mediaType := http.DetectContentType(data)
+	if !supportedImageTypes[mediaType] {
+		return ImageAttachment{}, fmt.Errorf("unsupported image type %s (use PNG, JPEG, GIF or WebP)", mediaType)
+	}
+
+	return ImageAttachment{Name: filepath.Base(path), MediaType: mediaType, Data: data}, nil
+}
+
+// droppedImagePath recognizes input that is just an image path, as pasted by drag-and-drop
+// Terminals quote the path or escape spaces with backslashes
+func droppedImagePath(input string) (string, bool) {
+	path := strings.TrimSpace(input)
+	if len(path) >= 2 && (path[0] == '\'' || path[0] == '"') && path[len(path)-1] == path[0] {
+		path = path[1 : len(path)-1]
+	} else {
+		path = strings.ReplaceAll(path, `\ `, " ")
+	}
+	path = strings.TrimPrefix(path, "file://")
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png", ".jpg", ".jpeg", ".gif", ".webp":
+	default:
+		return "", false
+	}
+	if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+		return "", false
+	}
+	return path, true
+}
+
+// providerSupportsImages reports whether image attachments are sent by the provider
+func providerSupportsImages(p LLMProvider) bool {
+	switch p := p.(type) {
+	case *BedrockClient, *AnthropicClient, *GeminiClient:
+		return true
+	case interface{ Unwrap() LLMProvider }:
+		return providerSupportsImages(p.Unwrap())
+	}
+	return false
+}
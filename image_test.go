@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// pngHeader is enough of a PNG for content sniffing
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestLoadImage(t *testing.T) {
+	dir := t.TempDir()
+	pngPath := filepath.Join(dir, "diagram.png")
+	if err := os.WriteFile(pngPath, pngHeader, 0600); err != nil {
+		t.Fatal(err)
+	}
+	txtPath := filepath.Join(dir, "notes.png")
+	if err := os.WriteFile(txtPath, []byte("not an image"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := LoadImage(pngPath)
+	if err != nil {
+		t.Fatalf("LoadImage: %v", err)
+	}
+	if img.MediaType != "image/png" || img.Name != "diagram.png" {
+		t.Errorf("got %s %s, want diagram.png image/png", img.Name, img.MediaType)
+	}
+
+	if _, err := LoadImage(txtPath); err == nil {
+		t.Error("expected error for non-image content")
+	}
+}
+
+func TestDroppedImagePath(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "white board.png")
+	if err := os.WriteFile(path, pngHeader, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input string
+		ok    bool
+	}{
+		{"'" + path + "'", true},
+		{strings.ReplaceAll(path, " ", `\ `), true},
+		{filepath.Join(dir, "missing.png"), false},
+		{"draw a diagram.png", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := droppedImagePath(tt.input)
+		if ok != tt.ok {
+			t.Errorf("droppedImagePath(%q) ok = %v, want %v", tt.input, ok, tt.ok)
+		}
+		if ok && got != path {
+			t.Errorf("droppedImagePath(%q) = %q, want %q", tt.input, got, path)
+		}
+	}
+}
+
+func TestProviderImageContent(t *testing.T) {
+	img := ImageAttachment{Name: "a.png", MediaType: "image/png", Data: pngHeader}
+	messages := []Message{
+		{Role: "user", Content: "implement this", Images: []ImageAttachment{img}},
+		{Role: "assistant", Content: "ok"},
+	}
+
+	claude, err := json.Marshal(toClaudeMessages(messages))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"type":"image"`, `"media_type":"image/png"`, `"data":"` + img.Base64() + `"`, `{"role":"assistant","content":"ok"}`} {
+		if !strings.Contains(string(claude), want) {
+			t.Errorf("Claude request missing %s:\n%s", want, claude)
+		}
+	}
+
+	gemini := convertMessagesToGemini(messages)
+	if len(gemini[0].Parts) != 2 || gemini[0].Parts[0].InlineData == nil || gemini[0].Parts[1].Text != "implement this" {
+		t.Errorf("unexpected Gemini parts: %+v", gemini[0].Parts)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// imageUpdateCheckTimeout bounds the startup registry check for a newer validator image
+const imageUpdateCheckTimeout = 10 * time.Second
+
+// ImageRecord tracks the digests of one validator image repository
+type ImageRecord struct {
+	Working  string    `json:"working,omitempty"`  // Last digest that passed validation
+	Previous string    `json:"previous,omitempty"` // Working digest before the most recent update
+	Updated  time.Time `json:"updated,omitempty"`  // When the image was last updated from bjarne
+}
+
+// ImageHistory remembers working digests so an update can be rolled back (/image rollback)
+type ImageHistory struct {
+	path   string
+	Images map[string]*ImageRecord `json:"images"`
+}
+
+// imageHistoryPath returns ~/.bjarne/image_history.json
+func imageHistoryPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".bjarne", "image_history.json"), nil
+}
+
+// loadImageHistory reads the history from path (empty path = in-memory only)
+// A missing or unreadable file yields an empty history
+func loadImageHistory(path string) *ImageHistory {
+	h := &ImageHistory{path: path, Images: make(map[string]*ImageRecord)}
+	if path == "" {
+		return h
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return h
+	}
+	if err := json.Unmarshal(data, h); err != nil || h.Images == nil {
+		h.Images = make(map[string]*ImageRecord)
+	}
+	return h
+}
+
+// imageRepo strips the digest from an image reference ("repo:tag@sha256:..." -> "repo:tag")
+func imageRepo(ref string) string {
+	repo, _, _ := strings.Cut(ref, "@")
+	return repo
+}
+
+// Record returns the record for an image, creating it if needed
+func (h *ImageHistory) Record(ref string) *ImageRecord {
+	repo := imageRepo(ref)
+	rec, ok := h.Images[repo]
+	if !ok {
+		rec = &ImageRecord{}
+		h.Images[repo] = rec
+	}
+	return rec
+}
+
+// MarkWorking records a digest that passed validation
+func (h *ImageHistory) MarkWorking(ref, digest string) error {
+	if digest == "" {
+		return nil
+	}
+	rec := h.Record(ref)
+	if rec.Working == digest {
+		return nil
+	}
+	rec.Working = digest
+	return h.save()
+}
+
+// BeforeUpdate remembers the digest to return to if the update regresses
+// The last working digest is preferred; otherwise the digest being replaced
+func (h *ImageHistory) BeforeUpdate(ref, current string) error {
+	rec := h.Record(ref)
+	switch {
+	case rec.Working != "":
+		rec.Previous = rec.Working
+	case current != "":
+		rec.Previous = current
+	}
+	rec.Updated = time.Now()
+	return h.save()
+}
+
+// RollbackTarget returns the digest /image rollback should switch to
+func (h *ImageHistory) RollbackTarget(ref, current string) (string, error) {
+	rec, ok := h.Images[imageRepo(ref)]
+	if !ok || rec.Previous == "" {
+		return "", fmt.Errorf("no previous digest recorded for %s (nothing was updated from bjarne)", imageRepo(ref))
+	}
+	if rec.Previous == current {
+		return "", fmt.Errorf("%s is already at %s", imageRepo(ref), shortDigest(current))
+	}
+	return rec.Previous, nil
+}
+
+// save writes the history (no-op for in-memory histories)
+func (h *ImageHistory) save() error {
+	if h.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(h.path), err)
+	}
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode image history: %w", err)
+	}
+	if err := os.WriteFile(h.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write image history: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImageHistoryRollback(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "image_history.json")
+	ref := "ghcr.io/3rg0n/bjarne-validator:latest"
+	oldDigest := "sha256:" + strings.Repeat("a", 64)
+	newDigest := "sha256:" + strings.Repeat("b", 64)
+
+	h := loadImageHistory(path)
+	if _, err := h.RollbackTarget(ref, oldDigest); err == nil {
+		t.Fatal("RollbackTarget() without an update should fail")
+	}
+
+	if err := h.MarkWorking(ref, oldDigest); err != nil {
+		t.Fatalf("MarkWorking() error = %v", err)
+	}
+	if err := h.BeforeUpdate(ref, "sha256:"+strings.Repeat("c", 64)); err != nil {
+		t.Fatalf("BeforeUpdate() error = %v", err)
+	}
+
+	// Reload to check persistence; a pinned ref shares the tag's record
+	h = loadImageHistory(path)
+	target, err := h.RollbackTarget(ref+"@"+newDigest, newDigest)
+	if err != nil {
+		t.Fatalf("RollbackTarget() error = %v", err)
+	}
+	if target != oldDigest {
+		t.Errorf("RollbackTarget() = %s, want last working digest %s", target, oldDigest)
+	}
+	if _, err := h.RollbackTarget(ref, oldDigest); err == nil {
+		t.Error("RollbackTarget() when already on the previous digest should fail")
+	}
+}
+
+func TestImageHistoryBeforeUpdateWithoutWorkingDigest(t *testing.T) {
+	h := loadImageHistory("")
+	current := "sha256:" + strings.Repeat("d", 64)
+	if err := h.BeforeUpdate("img:tag", current); err != nil {
+		t.Fatalf("BeforeUpdate() error = %v", err)
+	}
+	if got := h.Record("img:tag").Previous; got != current {
+		t.Errorf("Previous = %q, want replaced digest %q", got, current)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// projectImageFile selects an image profile for one project (workspace-relative)
+var projectImageFile = filepath.Join(".bjarne", "image")
+
+// Published validator image variants (toolchains too large for the default image)
+const (
+	embeddedValidatorImage = "ghcr.io/3rg0n/bjarne-validator-embedded:latest"
+	gpuValidatorImage      = "ghcr.io/3rg0n/bjarne-validator-gpu:latest"
+	qtValidatorImage       = "ghcr.io/3rg0n/bjarne-validator-qt:latest"
+)
+
+// defaultImageProfile is used when neither the project nor the settings select one
+const defaultImageProfile = "default"
+
+// digestPattern matches a pinnable image digest
+var digestPattern = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+// defaultImageProfiles returns the built-in profiles
+func defaultImageProfiles() map[string]ImageProfile {
+	return map[string]ImageProfile{
+		defaultImageProfile: {Image: defaultValidatorImage},
+		"embedded":          {Image: embeddedValidatorImage},
+		"gpu":               {Image: gpuValidatorImage},
+		"qt":                {Image: qtValidatorImage},
+	}
+}
+
+// Ref returns the image reference to run, including the digest when pinned
+func (p ImageProfile) Ref() string {
+	if p.Digest == "" {
+		return p.Image
+	}
+	return p.Image + "@" + p.Digest
+}
+
+// ValidateDigest checks that a digest can be used to pin an image
+func ValidateDigest(digest string) error {
+	if !digestPattern.MatchString(digest) {
+		return fmt.Errorf("invalid digest %q (want sha256:<64 hex chars>)", digest)
+	}
+	return nil
+}
+
+// isPinnedRef reports whether an image reference names a digest
+func isPinnedRef(ref string) bool {
+	return strings.Contains(ref, "@sha256:")
+}
+
+// ProfileNames returns the configured profile names, sorted
+func (s ContainerSettings) ProfileNames() []string {
+	names := make([]string, 0, len(s.Profiles))
+	for name := range s.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ImageSelection is the validator image chosen for a session and why
+type ImageSelection struct {
+	Ref     string // Image reference passed to podman/docker
+	Profile string // Profile name (empty when the image came from the environment or settings.image)
+	Source  string // What selected it, for display
+}
+
+// ResolveImage picks the validator image: BJARNE_VALIDATOR_IMAGE, then the project's
+// .bjarne/image profile, then container.profile, then container.image
+func ResolveImage(settings ContainerSettings, projectProfile string) (ImageSelection, error) {
+	if img := os.Getenv("BJARNE_VALIDATOR_IMAGE"); img != "" {
+		return ImageSelection{Ref: img, Source: "BJARNE_VALIDATOR_IMAGE"}, nil
+	}
+
+	name, source := projectProfile, projectImageFile
+	if name == "" {
+		name, source = settings.Profile, "settings"
+	}
+	if name == "" {
+		image := settings.Image
+		if image == "" {
+			image = defaultValidatorImage
+		}
+		return ImageSelection{Ref: image, Source: "settings"}, nil
+	}
+
+	profile, ok := settings.Profiles[name]
+	if !ok || profile.Image == "" {
+		return ImageSelection{}, fmt.Errorf("unknown image profile %q in %s (available: %s)",
+			name, source, strings.Join(settings.ProfileNames(), ", "))
+	}
+	if profile.Digest != "" {
+		if err := ValidateDigest(profile.Digest); err != nil {
+			return ImageSelection{}, fmt.Errorf("image profile %s: %w", name, err)
+		}
+	}
+	return ImageSelection{Ref: profile.Ref(), Profile: name, Source: source}, nil
+}
+
+// LoadProjectImageProfile reads the profile named in .bjarne/image (empty if none)
+func LoadProjectImageProfile(root string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(root, projectImageFile))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", projectImageFile, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// SaveProjectImageProfile records the project's profile in .bjarne/image
+func SaveProjectImageProfile(root, name string) error {
+	path := filepath.Join(root, projectImageFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, []byte(name+"\n"), 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", projectImageFile, err)
+	}
+	return nil
+}
+
+// resolveSessionImage selects the image for the working directory's project
+func resolveSessionImage(settings ContainerSettings) (ImageSelection, error) {
+	cwd, _ := os.Getwd()
+	projectProfile, err := LoadProjectImageProfile(cwd)
+	if err != nil {
+		return ImageSelection{}, err
+	}
+	return ResolveImage(settings, projectProfile)
+}
+
+// shortDigest abbreviates a digest for display
+func shortDigest(digest string) string {
+	if len(digest) > len("sha256:")+12 {
+		return digest[:len("sha256:")+12]
+	}
+	return digest
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolveImage(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("ab", 32)
+	settings := ContainerSettings{
+		Image: "custom:latest",
+		Profiles: map[string]ImageProfile{
+			"default":  {Image: defaultValidatorImage},
+			"embedded": {Image: embeddedValidatorImage, Digest: digest},
+			"broken":   {Image: "x:latest", Digest: "sha256:short"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		env      string
+		profile  string
+		project  string
+		wantRef  string
+		wantFrom string
+		wantErr  bool
+	}{
+		{"settings image", "", "", "", "custom:latest", "settings", false},
+		{"settings profile", "", "default", "", defaultValidatorImage, "settings", false},
+		{"project overrides settings", "", "default", "embedded", embeddedValidatorImage + "@" + digest, projectImageFile, false},
+		{"environment overrides all", "env:image", "default", "embedded", "env:image", "BJARNE_VALIDATOR_IMAGE", false},
+		{"unknown profile", "", "", "cuda", "", "", true},
+		{"bad digest", "", "broken", "", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("BJARNE_VALIDATOR_IMAGE", tt.env)
+			s := settings
+			s.Profile = tt.profile
+			got, err := ResolveImage(s, tt.project)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveImage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Ref != tt.wantRef || got.Source != tt.wantFrom {
+				t.Errorf("ResolveImage() = %q from %q, want %q from %q", got.Ref, got.Source, tt.wantRef, tt.wantFrom)
+			}
+		})
+	}
+}
+
+func TestImageProfilePinning(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("0f", 32)
+	if err := ValidateDigest(digest); err != nil {
+		t.Errorf("ValidateDigest(%q) = %v", digest, err)
+	}
+	for _, bad := range []string{"", "latest", "sha256:XYZ", "sha512:" + strings.Repeat("0f", 32)} {
+		if ValidateDigest(bad) == nil {
+			t.Errorf("ValidateDigest(%q) = nil, want error", bad)
+		}
+	}
+
+	p := ImageProfile{Image: qtValidatorImage}
+	if isPinnedRef(p.Ref()) {
+		t.Errorf("unpinned profile reported as pinned: %s", p.Ref())
+	}
+	p.Digest = digest
+	if !isPinnedRef(p.Ref()) {
+		t.Errorf("pinned profile not reported as pinned: %s", p.Ref())
+	}
+}
+
+func TestProjectImageProfileRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+
+	if name, err := LoadProjectImageProfile(dir); err != nil || name != "" {
+		t.Fatalf("LoadProjectImageProfile() on empty dir = %q, %v", name, err)
+	}
+	if err := SaveProjectImageProfile(dir, "gpu"); err != nil {
+		t.Fatalf("SaveProjectImageProfile() error = %v", err)
+	}
+	if name, err := LoadProjectImageProfile(dir); err != nil || name != "gpu" {
+		t.Errorf("LoadProjectImageProfile() = %q, %v, want gpu", name, err)
+	}
+}
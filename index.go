@@ -121,6 +121,12 @@ var (
 
 	// Match struct declarations
 	structPattern = regexp.MustCompile(`(?m)^[\t ]*(?:template\s*<[^>]*>\s*)?struct\s+(\w+)(?:\s*:\s*[^{]+)?\s*\{`)
+
+	// Match // line comments
+	lineCommentPattern = regexp.MustCompile(`//[^\n]*`)
+
+	// Match a data member declaration (type name [= init]), capturing the member name
+	memberPattern = regexp.MustCompile(`^[\w:<>,\s*&]+?[\s*&]+(\w+)\s*(?:\[[^\]]*\])?\s*(?:=.*)?$`)
 )
 
 // IndexWorkspace scans and indexes the current directory
@@ -193,6 +199,49 @@ func IndexWorkspace(rootPath string, progressFn func(string)) (*WorkspaceIndex,
 	return index, nil
 }
 
+// UpdateFiles re-parses individual files (e.g. code just written by /save) into the index
+// Paths outside the indexed root and non-C/C++ files are skipped; returns the indexed paths
+func (idx *WorkspaceIndex) UpdateFiles(paths []string) []string {
+	if idx.Files == nil {
+		idx.Files = make(map[string]*FileIndex)
+	}
+	var updated []string
+	for _, path := range paths {
+		absPath, err := filepath.Abs(path)
+		if err != nil || !sourceExtensions[strings.ToLower(filepath.Ext(absPath))] {
+			continue
+		}
+		relPath, err := filepath.Rel(idx.RootPath, absPath)
+		if err != nil || strings.HasPrefix(relPath, "..") {
+			continue
+		}
+		fileIndex, err := parseSourceFile(absPath)
+		if err != nil {
+			continue
+		}
+		fileIndex.Path = relPath
+		idx.Files[relPath] = fileIndex
+		updated = append(updated, relPath)
+	}
+	if len(updated) > 0 {
+		idx.UpdatedAt = time.Now()
+		idx.recomputeSummary()
+	}
+	return updated
+}
+
+// recomputeSummary recounts the summary from the indexed files
+func (idx *WorkspaceIndex) recomputeSummary() {
+	idx.Summary = IndexSummary{}
+	for _, f := range idx.Files {
+		idx.Summary.TotalFiles++
+		idx.Summary.TotalFunctions += len(f.Functions)
+		idx.Summary.TotalClasses += len(f.Classes)
+		idx.Summary.TotalStructs += len(f.Structs)
+		idx.Summary.TotalLines += f.Lines
+	}
+}
+
 // parseSourceFile extracts information from a C/C++ source file
 func parseSourceFile(path string) (*FileIndex, error) {
 	content, err := os.ReadFile(path)
@@ -209,13 +258,17 @@ func parseSourceFile(path string) (*FileIndex, error) {
 	hash := sha256.Sum256(content)
 	hashStr := hex.EncodeToString(hash[:8]) // First 8 bytes is enough
 
-	text := string(content)
-	lines := strings.Count(text, "\n") + 1
+	fileIndex := parseSourceContent(string(content))
+	fileIndex.Hash = hashStr
+	fileIndex.ModTime = info.ModTime()
 
+	return fileIndex, nil
+}
+
+// parseSourceContent extracts includes, functions, classes and structs from C/C++ source text
+func parseSourceContent(text string) *FileIndex {
 	fileIndex := &FileIndex{
-		Hash:    hashStr,
-		ModTime: info.ModTime(),
-		Lines:   lines,
+		Lines: strings.Count(text, "\n") + 1,
 	}
 
 	// Extract includes
@@ -256,8 +309,9 @@ func parseSourceFile(path string) (*FileIndex, error) {
 			className := text[match[2]:match[3]]
 			line := strings.Count(text[:match[0]], "\n") + 1
 			fileIndex.Classes = append(fileIndex.Classes, ClassInfo{
-				Name: className,
-				Line: line,
+				Name:    className,
+				Line:    line,
+				Members: extractMembers(classBody(text, match[1]-1)),
 			})
 		}
 	}
@@ -269,13 +323,67 @@ func parseSourceFile(path string) (*FileIndex, error) {
 			structName := text[match[2]:match[3]]
 			line := strings.Count(text[:match[0]], "\n") + 1
 			fileIndex.Structs = append(fileIndex.Structs, StructInfo{
-				Name: structName,
-				Line: line,
+				Name:    structName,
+				Line:    line,
+				Members: extractMembers(classBody(text, match[1]-1)),
 			})
 		}
 	}
 
-	return fileIndex, nil
+	return fileIndex
+}
+
+// classBody returns the text between the brace at openBrace and its matching close brace,
+// keeping only the top-level lines (nested bodies are dropped)
+func classBody(text string, openBrace int) string {
+	var sb strings.Builder
+	depth := 0
+	for i := openBrace; i < len(text); i++ {
+		switch text[i] {
+		case '{':
+			depth++
+			if depth > 1 {
+				continue
+			}
+		case '}':
+			depth--
+			if depth == 0 {
+				return sb.String()
+			}
+			if depth == 1 {
+				sb.WriteByte(';') // End of an inline method body acts as a statement break
+			}
+			continue
+		}
+		if depth == 1 && i > openBrace {
+			sb.WriteByte(text[i])
+		}
+	}
+	return sb.String()
+}
+
+// extractMembers finds data member names in a class/struct body
+func extractMembers(body string) []string {
+	var members []string
+	body = lineCommentPattern.ReplaceAllString(body, "")
+	for _, stmt := range strings.Split(body, ";") {
+		stmt = strings.TrimSpace(stmt)
+		// Drop access specifiers that precede the declaration
+		for _, spec := range []string{"public:", "private:", "protected:"} {
+			if idx := strings.LastIndex(stmt, spec); idx >= 0 {
+				stmt = strings.TrimSpace(stmt[idx+len(spec):])
+			}
+		}
+		// Skip methods, type aliases and friends
+		if stmt == "" || strings.Contains(stmt, "(") || strings.HasPrefix(stmt, "using ") ||
+			strings.HasPrefix(stmt, "typedef ") || strings.HasPrefix(stmt, "friend ") {
+			continue
+		}
+		if match := memberPattern.FindStringSubmatch(stmt); match != nil && !isKeyword(match[1]) {
+			members = append(members, match[1])
+		}
+	}
+	return members
 }
 
 // isKeyword checks if a string is a C++ keyword (to avoid false positive function matches)
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWorkspaceIndexUpdateFiles(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, content string) string {
+		t.Helper()
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	write("src/old.cpp", "int old_fn(int x) {\n    return x;\n}\n")
+
+	idx, err := IndexWorkspace(root, nil)
+	if err != nil {
+		t.Fatalf("IndexWorkspace() error = %v", err)
+	}
+
+	saved := []string{
+		write("src/ring_buffer.cpp", "class RingBuffer {\n    int head_;\n};\nint push(int v) {\n    return v;\n}\n"),
+		write("notes.txt", "not code"),
+		filepath.Join(t.TempDir(), "outside.cpp"),
+	}
+	updated := idx.UpdateFiles(saved)
+	if len(updated) != 1 || updated[0] != filepath.Join("src", "ring_buffer.cpp") {
+		t.Fatalf("UpdateFiles() = %v, want only the saved source file", updated)
+	}
+	if idx.Summary.TotalFiles != 2 || idx.Summary.TotalClasses != 1 {
+		t.Errorf("summary = %+v, want both files and the new class", idx.Summary)
+	}
+
+	// Updating a file again replaces its entry instead of adding one
+	write("src/ring_buffer.cpp", "int push(int v) {\n    return v;\n}\n")
+	idx.UpdateFiles(saved[:1])
+	if idx.Summary.TotalFiles != 2 || idx.Summary.TotalClasses != 0 {
+		t.Errorf("summary after update = %+v", idx.Summary)
+	}
+}
+
+func TestVectorIndexSharing(t *testing.T) {
+	dir := t.TempDir()
+	cfg := VectorIndexConfig{DBPath: filepath.Join(dir, "index.db"), ModelDir: filepath.Join(dir, "models"), EmbeddingDim: EmbeddingDim}
+	writer, err := NewVectorIndex(cfg)
+	if err != nil {
+		t.Skipf("vector index unavailable: %v", err)
+	}
+	defer func() { _ = writer.Close() }()
+
+	cfg.ReadOnly = true
+	reader, err := NewVectorIndex(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = reader.Close() }()
+	ctx := context.Background()
+	if _, _, _, err := reader.GetStats(ctx); err != nil {
+		t.Errorf("read-only GetStats() error = %v", err)
+	}
+	if _, err := reader.IndexFiles(ctx, dir, nil); !errors.Is(err, errIndexReadOnly) {
+		t.Errorf("read-only IndexFiles() error = %v, want errIndexReadOnly", err)
+	}
+
+	// Another process holding the writer lock: a full index fails at once
+	lock, err := tryLock(cfg.DBPath + ".lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = lock.Unlock() }()
+	if err := writer.IndexWorkspaceWithEmbeddings(ctx, dir, nil); !errors.Is(err, errLocked) {
+		t.Errorf("IndexWorkspaceWithEmbeddings() error = %v, want errLocked", err)
+	}
+}
+
+func TestVectorIndexIndexFiles(t *testing.T) {
+	dir := t.TempDir()
+	vi, err := NewVectorIndex(VectorIndexConfig{DBPath: filepath.Join(dir, "index.db"), ModelDir: filepath.Join(dir, "models"), EmbeddingDim: EmbeddingDim})
+	if err != nil {
+		t.Skipf("vector index unavailable: %v", err)
+	}
+	defer func() { _ = vi.Close() }()
+
+	root := t.TempDir()
+	path := filepath.Join(root, "queue.cpp")
+	if err := os.WriteFile(path, []byte("int pop_front(int* q) {\n    return q[0];\n}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	chunks, err := vi.IndexFiles(ctx, root, []string{path, filepath.Join(root, "README.md")})
+	if err != nil || chunks == 0 {
+		t.Fatalf("IndexFiles() = %d, %v; want the file's chunks", chunks, err)
+	}
+	if files, stored, _, _ := vi.GetStats(ctx); files != 1 || stored != chunks {
+		t.Errorf("GetStats() = %d files, %d chunks; want 1 file, %d chunks", files, stored, chunks)
+	}
+
+	// An unchanged file is not indexed again
+	if again, err := vi.IndexFiles(ctx, root, []string{path}); again != 0 || err != nil {
+		t.Errorf("IndexFiles() of an unchanged file = %d, %v", again, err)
+	}
+}
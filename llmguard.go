@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -13,8 +15,11 @@ import (
 // See: https://github.com/protectai/llm-guard
 type LLMGuardClient struct {
 	baseURL    string
+	apiKey     string
 	httpClient *http.Client
 	enabled    bool
+	clientErr  error // Proxy/TLS setup failure, reported on each scan
+	settings   GuardSettings
 }
 
 // LLMGuardScanRequest is the request format for /scan/prompt and /scan/output
@@ -29,6 +34,7 @@ type LLMGuardScanResponse struct {
 	SanitizedPrompt string            `json:"sanitized_prompt,omitempty"`
 	SanitizedOutput string            `json:"sanitized_output,omitempty"`
 	Results         map[string]Result `json:"results"`
+	Fallback        bool              `json:"-"` // Built-in rules were used because the service was unreachable
 }
 
 // Result represents a single scanner result
@@ -38,28 +44,56 @@ type Result struct {
 	Risk    string  `json:"risk,omitempty"`
 }
 
-// NewLLMGuardClient creates a new llm-guard client
-// Reads LLMGUARD_URL from environment (default: disabled)
-func NewLLMGuardClient() *LLMGuardClient {
-	url := os.Getenv("LLMGUARD_URL")
-	if url == "" {
-		return &LLMGuardClient{enabled: false}
+// NewLLMGuardClient creates a scanner from settings
+// LLMGUARD_URL and LLMGUARD_TOKEN override the endpoint and API key; offline, only built-in rules run
+func NewLLMGuardClient(settings GuardSettings) *LLMGuardClient {
+	if url := os.Getenv("LLMGUARD_URL"); url != "" {
+		settings.Endpoint = url
+		settings.Enabled = true
+	}
+	if token := os.Getenv("LLMGUARD_TOKEN"); token != "" {
+		settings.APIKey = token
+	}
+	if offlineMode {
+		settings.Endpoint = ""
 	}
 
-	return &LLMGuardClient{
-		baseURL: url,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-		enabled: true,
+	c := &LLMGuardClient{
+		baseURL:  strings.TrimRight(settings.Endpoint, "/"),
+		apiKey:   settings.APIKey,
+		enabled:  settings.Enabled && (settings.Endpoint != "" || settings.LocalRules),
+		settings: settings,
+	}
+	if c.baseURL != "" {
+		c.httpClient, c.clientErr = NewHTTPClient(endpointLLMGuard, 30*time.Second)
 	}
+	return c
 }
 
-// IsEnabled returns whether llm-guard is configured
+// IsEnabled returns whether scanning is configured (service or built-in rules)
 func (c *LLMGuardClient) IsEnabled() bool {
 	return c.enabled
 }
 
+// FailClosed reports whether requests are blocked when the scan cannot run
+func (c *LLMGuardClient) FailClosed() bool {
+	return c.settings.FailurePolicy == GuardFailClosed
+}
+
+// Mode describes where scans run, for status output
+func (c *LLMGuardClient) Mode() string {
+	switch {
+	case !c.enabled:
+		return "off"
+	case c.baseURL == "":
+		return "built-in rules"
+	case c.settings.LocalRules:
+		return "LLM Guard (built-in rules as fallback)"
+	default:
+		return "LLM Guard"
+	}
+}
+
 // ScanPrompt scans user input for prompt injection, secrets, and toxicity
 // Returns sanitized prompt and any security issues found
 func (c *LLMGuardClient) ScanPrompt(prompt string) (*LLMGuardScanResponse, error) {
@@ -68,7 +102,11 @@ func (c *LLMGuardClient) ScanPrompt(prompt string) (*LLMGuardScanResponse, error
 	}
 
 	req := LLMGuardScanRequest{Prompt: prompt}
-	return c.doScan("/scan/prompt", req)
+	resp, err := c.scan("/scan/prompt", req, func() *LLMGuardScanResponse { return scanLocalPrompt(prompt) })
+	if resp != nil && resp.SanitizedPrompt == "" {
+		resp.SanitizedPrompt = prompt
+	}
+	return resp, err
 }
 
 // ScanOutput scans LLM-generated code for embedded secrets and security issues
@@ -78,11 +116,72 @@ func (c *LLMGuardClient) ScanOutput(output string) (*LLMGuardScanResponse, error
 	}
 
 	req := LLMGuardScanRequest{Output: output}
-	return c.doScan("/scan/output", req)
+	resp, err := c.scan("/scan/output", req, func() *LLMGuardScanResponse { return scanLocalOutput(output) })
+	if resp != nil && resp.SanitizedOutput == "" {
+		resp.SanitizedOutput = output
+	}
+	return resp, err
+}
+
+// scan runs the service (or the built-in rules) and applies the scanner filter and threshold
+// When the service fails, the built-in rules stand in if enabled; otherwise the error is returned
+// and the caller applies the failure policy
+func (c *LLMGuardClient) scan(endpoint string, req LLMGuardScanRequest, local func() *LLMGuardScanResponse) (*LLMGuardScanResponse, error) {
+	if c.baseURL == "" {
+		return c.evaluate(local()), nil
+	}
+	resp, err := c.doScan(endpoint, req)
+	if err != nil {
+		if !c.settings.LocalRules {
+			return nil, err
+		}
+		resp = local()
+		resp.Fallback = true
+	}
+	return c.evaluate(resp), nil
+}
+
+// evaluate marks results as findings per the enabled scanners and threshold, and recomputes IsValid
+func (c *LLMGuardClient) evaluate(resp *LLMGuardScanResponse) *LLMGuardScanResponse {
+	if len(c.settings.Scanners) == 0 && c.settings.Threshold <= 0 {
+		return resp
+	}
+	resp.IsValid = true
+	for name, result := range resp.Results {
+		finding := !result.IsValid
+		if c.settings.Threshold > 0 {
+			finding = result.Score >= c.settings.Threshold
+		}
+		if finding && !c.scannerEnabled(name) {
+			finding = false
+		}
+		result.IsValid = !finding
+		resp.Results[name] = result
+		if finding {
+			resp.IsValid = false
+		}
+	}
+	return resp
+}
+
+// scannerEnabled reports whether a scanner's results count (all count when none are listed)
+func (c *LLMGuardClient) scannerEnabled(name string) bool {
+	if len(c.settings.Scanners) == 0 {
+		return true
+	}
+	for _, s := range c.settings.Scanners {
+		if strings.EqualFold(s, name) {
+			return true
+		}
+	}
+	return false
 }
 
 // doScan performs the actual HTTP request to llm-guard API
 func (c *LLMGuardClient) doScan(endpoint string, req LLMGuardScanRequest) (*LLMGuardScanResponse, error) {
+	if c.clientErr != nil {
+		return nil, fmt.Errorf("llm-guard client: %w", c.clientErr)
+	}
 	body, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("marshal request: %w", err)
@@ -95,8 +194,8 @@ func (c *LLMGuardClient) doScan(endpoint string, req LLMGuardScanRequest) (*LLMG
 	httpReq.Header.Set("Content-Type", "application/json")
 
 	// Optional auth token
-	if token := os.Getenv("LLMGUARD_TOKEN"); token != "" {
-		httpReq.Header.Set("Authorization", "Bearer "+token)
+	if c.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
 	}
 
 	resp, err := c.httpClient.Do(httpReq)
@@ -138,7 +237,12 @@ func (c *LLMGuardClient) FormatSecurityIssues(resp *LLMGuardScanResponse) string
 		return ""
 	}
 
-	return "Security scan detected issues:\n" + joinLines(issues)
+	sort.Strings(issues)
+	header := "Security scan detected issues:"
+	if resp.Fallback {
+		header = "Security scan (built-in rules; LLM Guard unreachable) detected issues:"
+	}
+	return header + "\n" + joinLines(issues)
 }
 
 // joinLines joins strings with newlines
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errLocked reports a lock held by another bjarne process
+var errLocked = errors.New("locked by another bjarne session")
+
+// lockPollInterval is how often lockWait retries a held lock
+const lockPollInterval = 25 * time.Millisecond
+
+// fileLock is an advisory lock on a file under ~/.bjarne, held until Unlock
+// Locks are released by the OS when the process exits, so a crash never leaves one stuck
+type fileLock struct {
+	f *os.File
+}
+
+// tryLock takes the lock on path without waiting, returning errLocked when another process holds it
+func tryLock(path string) (*fileLock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600) //nolint:gosec // path is under ~/.bjarne
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	if err := lockHandle(f); err != nil {
+		_ = f.Close()
+		if errors.Is(err, errLocked) {
+			return nil, errLocked
+		}
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+	return &fileLock{f: f}, nil
+}
+
+// lockWait takes the lock on path, waiting up to timeout for another process to release it
+func lockWait(path string, timeout time.Duration) (*fileLock, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		l, err := tryLock(path)
+		if !errors.Is(err, errLocked) || time.Now().After(deadline) {
+			return l, err
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// Unlock releases the lock
+func (l *fileLock) Unlock() error {
+	if l == nil {
+		return nil
+	}
+	err := unlockHandle(l.f)
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// writeFileAtomic replaces path with data, so a concurrent reader sees the old or the new file, never half of one
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), perm)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// sessionsDir returns ~/.bjarne/sessions, which holds a lock file per running session
+func sessionsDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".bjarne", "sessions"), nil
+}
+
+// sessionLock marks a running session, so no other process takes its ID
+type sessionLock struct {
+	*fileLock
+	ID   string
+	path string
+}
+
+// startSession picks a session ID no other session in dir uses and locks it for this process
+// The lock file records the process ID for anyone looking at the directory. It is locked under a
+// temporary name and linked into place, so otherSessions never finds it unlocked and removes it
+func startSession(dir string, now time.Time) (*sessionLock, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create sessions directory: %w", err)
+	}
+	f, err := os.CreateTemp(dir, ".session-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session lock: %w", err)
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+	if err := lockHandle(f); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to lock session: %w", err)
+	}
+	_, _ = f.WriteString(strconv.Itoa(os.Getpid()) + "\n")
+	for range 8 {
+		id := newSessionID(now)
+		path := filepath.Join(dir, id+".lock")
+		err := os.Link(f.Name(), path)
+		if errors.Is(err, os.ErrExist) {
+			continue // Taken by a session started in the same second
+		}
+		if err != nil {
+			_ = (&fileLock{f: f}).Unlock()
+			return nil, fmt.Errorf("failed to create session lock: %w", err)
+		}
+		return &sessionLock{fileLock: &fileLock{f: f}, ID: id, path: path}, nil
+	}
+	_ = (&fileLock{f: f}).Unlock()
+	return nil, fmt.Errorf("failed to pick an unused session ID in %s", dir)
+}
+
+// Release ends the session and removes its lock file
+func (s *sessionLock) Release() error {
+	if s == nil {
+		return nil
+	}
+	err := s.Unlock()
+	if rerr := os.Remove(s.path); err == nil && !os.IsNotExist(rerr) {
+		err = rerr
+	}
+	return err
+}
+
+// otherSessions returns the IDs of the sessions in dir still held by a running process
+// Lock files left behind by a crashed session are removed
+func otherSessions(dir string, self string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var running []string
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".session-") {
+			removeStaleSessionTemp(filepath.Join(dir, e.Name()))
+			continue
+		}
+		id, ok := strings.CutSuffix(e.Name(), ".lock")
+		if !ok || e.IsDir() || id == self {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		l, err := tryLock(path)
+		if errors.Is(err, errLocked) {
+			running = append(running, id)
+			continue
+		}
+		if err == nil {
+			_ = l.Unlock()
+			_ = os.Remove(path) // Stale: its process is gone
+		}
+	}
+	return running
+}
+
+// sessionTempGrace is how old startSession's temporary file must be before otherSessions removes it;
+// until startSession locks it, an unlocked one may still be in use
+const sessionTempGrace = time.Minute
+
+// removeStaleSessionTemp removes a temporary file that startSession could not remove, such as
+// one left by a crash, once it is old and unlocked
+func removeStaleSessionTemp(path string) {
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) < sessionTempGrace {
+		return
+	}
+	if l, err := tryLock(path); err == nil {
+		_ = l.Unlock()
+		_ = os.Remove(path)
+	}
+}
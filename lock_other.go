@@ -0,0 +1,15 @@
+//go:build !unix && !windows
+
+package main
+
+import "os"
+
+// lockHandle is a no-op on this platform: sessions are not protected from each other
+func lockHandle(*os.File) error {
+	return nil
+}
+
+// unlockHandle is a no-op on this platform
+func unlockHandle(*os.File) error {
+	return nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "settings.json.lock")
+	first, err := tryLock(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tryLock(path); !errors.Is(err, errLocked) {
+		t.Fatalf("second tryLock() error = %v, want errLocked", err)
+	}
+	if _, err := lockWait(path, 50*time.Millisecond); !errors.Is(err, errLocked) {
+		t.Fatalf("lockWait() error = %v, want errLocked after the timeout", err)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = first.Unlock()
+	}()
+	second, err := lockWait(path, 5*time.Second)
+	if err != nil {
+		t.Fatalf("lockWait() error = %v, want the lock once released", err)
+	}
+	_ = second.Unlock()
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "settings.json")
+	for _, content := range []string{"{}", `{"theme":"dark"}`} {
+		if err := writeFileAtomic(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := os.ReadFile(path); string(got) != content {
+			t.Errorf("content = %q, want %q", got, content)
+		}
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("directory holds %d files, want no temporary files left", len(entries))
+	}
+}
+
+func TestSessions(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	a, err := startSession(dir, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := startSession(dir, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.ID == b.ID {
+		t.Fatalf("two sessions started in the same second share ID %s", a.ID)
+	}
+
+	// A crashed session leaves an unlocked lock file behind
+	stale := filepath.Join(dir, "20261016-090000-deadbeef.lock")
+	if err := os.WriteFile(stale, []byte("1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got := otherSessions(dir, a.ID); len(got) != 1 || got[0] != b.ID {
+		t.Errorf("otherSessions() = %v, want only %s", got, b.ID)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("otherSessions() kept the stale lock file")
+	}
+
+	// A temporary file is removed once it is old, not while startSession may still be locking it
+	fresh := filepath.Join(dir, ".session-fresh")
+	old := filepath.Join(dir, ".session-old")
+	for _, path := range []string{fresh, old} {
+		if err := os.WriteFile(path, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	past := now.Add(-2 * sessionTempGrace)
+	if err := os.Chtimes(old, past, past); err != nil {
+		t.Fatal(err)
+	}
+	_ = otherSessions(dir, a.ID)
+	if _, err := os.Stat(fresh); err != nil {
+		t.Errorf("otherSessions() removed a fresh temporary file: %v", err)
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Error("otherSessions() kept an old temporary file")
+	}
+
+	if err := b.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if got := otherSessions(dir, a.ID); len(got) != 0 {
+		t.Errorf("otherSessions() = %v after release, want none", got)
+	}
+	_ = a.Release()
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Log levels for logging.level, logging.subsystems and --log-level
+const (
+	LogLevelDebug = "debug"
+	LogLevelInfo  = "info"
+	LogLevelWarn  = "warn"
+	LogLevelError = "error"
+	LogLevelOff   = "off"
+)
+
+// Log formats for logging.format
+const (
+	LogFormatText = "text" // key=value lines
+	LogFormatJSON = "json" // One object per line
+)
+
+// logLevels lists the level names, most verbose first
+var logLevels = []string{LogLevelDebug, LogLevelInfo, LogLevelWarn, LogLevelError, LogLevelOff}
+
+// levelOff is above every level slog uses, so nothing passes it
+const levelOff = slog.Level(100)
+
+// logSubsystems are the parts of bjarne with a logger of their own
+var logSubsystems = []string{"provider", "container", "index", "tui"}
+
+// Per-subsystem loggers: records carry subsystem=<name> and obey that subsystem's level
+var (
+	providerLog  = newSubsystemLogger("provider")
+	containerLog = newSubsystemLogger("container")
+	indexLog     = newSubsystemLogger("index")
+	tuiLog       = newSubsystemLogger("tui")
+)
+
+// parseLogLevel converts a level name; "" is the default, warn
+func parseLogLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case LogLevelDebug:
+		return slog.LevelDebug, nil
+	case LogLevelInfo:
+		return slog.LevelInfo, nil
+	case "", LogLevelWarn:
+		return slog.LevelWarn, nil
+	case LogLevelError:
+		return slog.LevelError, nil
+	case LogLevelOff:
+		return levelOff, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q (use %s)", s, strings.Join(logLevels, ", "))
+}
+
+// parseLogLevelFlag removes --log-level <level> (or --log-level=<level>) from args
+// The level is returned as a --set flag, so it overrides logging.level like any other setting
+func parseLogLevelFlag(args []string) ([]string, []string, error) {
+	var rest, sets []string
+	for i := 0; i < len(args); i++ {
+		var level string
+		switch {
+		case args[i] == "--log-level":
+			if i+1 >= len(args) {
+				return nil, nil, fmt.Errorf("--log-level needs a level (%s)", strings.Join(logLevels, ", "))
+			}
+			i++
+			level = args[i]
+		case strings.HasPrefix(args[i], "--log-level="):
+			level = strings.TrimPrefix(args[i], "--log-level=")
+		default:
+			rest = append(rest, args[i])
+			continue
+		}
+		if _, err := parseLogLevel(level); err != nil {
+			return nil, nil, err
+		}
+		sets = append(sets, "logging.level="+strings.ToLower(level))
+	}
+	return rest, sets, nil
+}
+
+// logState is the configured log: where records go and which levels pass
+type logState struct {
+	handler slog.Handler
+	out     *rotatingWriter
+	level   slog.Level
+	levels  map[string]slog.Level // logging.subsystems overrides
+}
+
+// activeLog is the log the subsystem loggers write to (nil before configureLogging)
+var activeLog atomic.Pointer[logState]
+
+// debugLogging is /debug: every subsystem logs at debug level for the rest of the session
+var debugLogging atomic.Bool
+
+// minLevel is the least severe level subsystem logs
+func (s *logState) minLevel(subsystem string) slog.Level {
+	if debugLogging.Load() {
+		return slog.LevelDebug
+	}
+	if l, ok := s.levels[subsystem]; ok {
+		return l
+	}
+	return s.level
+}
+
+// defaultLogPath returns ~/.bjarne/logs/bjarne.log
+func defaultLogPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".bjarne", "logs", "bjarne.log"), nil
+}
+
+// configureLogging points the subsystem loggers at the log described by settings
+// The file is created on the first record, so a quiet session leaves nothing behind
+func configureLogging(settings LoggingSettings) error {
+	level, err := parseLogLevel(settings.Level)
+	if err != nil {
+		return err
+	}
+	levels := make(map[string]slog.Level, len(settings.Subsystems))
+	for name, s := range settings.Subsystems {
+		l, err := parseLogLevel(s)
+		if err != nil {
+			return fmt.Errorf("logging.subsystems.%s: %w", name, err)
+		}
+		levels[name] = l
+	}
+	path := settings.File
+	if path == "" {
+		if path, err = defaultLogPath(); err != nil {
+			return err
+		}
+	}
+
+	out := &rotatingWriter{path: path, maxBytes: int64(settings.MaxSizeMB) << 20, maxFiles: settings.MaxFiles}
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug} // The subsystem handlers filter by level
+	var handler slog.Handler
+	switch settings.Format {
+	case "", LogFormatText:
+		handler = slog.NewTextHandler(out, opts)
+	case LogFormatJSON:
+		handler = slog.NewJSONHandler(out, opts)
+	default:
+		return fmt.Errorf("unknown log format %q (use text or json)", settings.Format)
+	}
+	if old := activeLog.Swap(&logState{handler: handler, out: out, level: level, levels: levels}); old != nil {
+		_ = old.out.Close()
+	}
+	return nil
+}
+
+// startLogging configures logging from the settings (after --set and --log-level)
+// Invalid logging settings are reported and fall back to the defaults
+func startLogging() {
+	cwd, _ := os.Getwd()
+	layers, _ := LoadSettingsLayers(cwd)
+	if err := configureLogging(layers.Settings.Logging); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v; using the default log settings\n", err)
+		_ = configureLogging(DefaultSettings().Logging)
+	}
+}
+
+// stopLogging flushes and closes the log file
+func stopLogging() {
+	if s := activeLog.Swap(nil); s != nil {
+		_ = s.out.Close()
+	}
+}
+
+// setDebugLogging turns /debug on or off and returns the log file's path
+func setDebugLogging(on bool) string {
+	debugLogging.Store(on)
+	if s := activeLog.Load(); s != nil {
+		return s.out.path
+	}
+	return ""
+}
+
+// newSubsystemLogger returns the logger for one subsystem
+func newSubsystemLogger(name string) *slog.Logger {
+	return slog.New(&subsystemHandler{name: name})
+}
+
+// subsystemHandler writes a subsystem's records to the active log
+// It looks the log up on every record, so package-level loggers follow configureLogging
+type subsystemHandler struct {
+	name string
+	wrap []func(slog.Handler) slog.Handler // WithAttrs and WithGroup calls, replayed on the log's handler
+}
+
+// Enabled reports whether the subsystem logs at level
+func (h *subsystemHandler) Enabled(_ context.Context, level slog.Level) bool {
+	s := activeLog.Load()
+	return s != nil && level >= s.minLevel(h.name)
+}
+
+// Handle writes the record with the subsystem's attributes
+func (h *subsystemHandler) Handle(ctx context.Context, r slog.Record) error {
+	s := activeLog.Load()
+	if s == nil {
+		return nil
+	}
+	out := s.handler.WithAttrs([]slog.Attr{slog.String("subsystem", h.name)})
+	for _, w := range h.wrap {
+		out = w(out)
+	}
+	return out.Handle(ctx, r)
+}
+
+// WithAttrs returns a handler that adds attrs to every record
+func (h *subsystemHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(out slog.Handler) slog.Handler { return out.WithAttrs(attrs) })
+}
+
+// WithGroup returns a handler that nests later attributes under name
+func (h *subsystemHandler) WithGroup(name string) slog.Handler {
+	return h.with(func(out slog.Handler) slog.Handler { return out.WithGroup(name) })
+}
+
+func (h *subsystemHandler) with(w func(slog.Handler) slog.Handler) *subsystemHandler {
+	wrap := make([]func(slog.Handler) slog.Handler, len(h.wrap), len(h.wrap)+1)
+	copy(wrap, h.wrap)
+	return &subsystemHandler{name: h.name, wrap: append(wrap, w)}
+}
+
+// rotatingWriter appends to a log file, rotating it to path.1, path.2, ... once it grows past maxBytes
+type rotatingWriter struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64 // 0 never rotates
+	maxFiles int   // Rotated files kept
+	f        *os.File
+	size     int64
+}
+
+// Write appends p, opening the file on first use
+func (w *rotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+	if w.maxBytes > 0 && w.size > 0 && w.size+int64(len(p)) > w.maxBytes {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.f.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *rotatingWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.path), 0700); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+	f, err := os.OpenFile(w.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	w.f, w.size = f, 0
+	if info, err := f.Stat(); err == nil {
+		w.size = info.Size()
+	}
+	return nil
+}
+
+// rotate shifts path.N to path.N+1 (dropping the oldest) and starts a new file
+func (w *rotatingWriter) rotate() error {
+	_ = w.f.Close()
+	w.f = nil
+	if w.maxFiles <= 0 {
+		_ = os.Remove(w.path)
+	} else {
+		_ = os.Remove(fmt.Sprintf("%s.%d", w.path, w.maxFiles))
+		for i := w.maxFiles - 1; i >= 1; i-- {
+			_ = os.Rename(fmt.Sprintf("%s.%d", w.path, i), fmt.Sprintf("%s.%d", w.path, i+1))
+		}
+		_ = os.Rename(w.path, w.path+".1")
+	}
+	return w.open()
+}
+
+// Close closes the file; a later Write reopens it
+func (w *rotatingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return nil
+	}
+	err := w.f.Close()
+	w.f = nil
+	return err
+}
+
+// loggedProvider logs each generation request and its outcome
+type loggedProvider struct {
+	LLMProvider
+}
+
+// withLogging wraps a provider so its calls are logged (subsystem provider)
+func withLogging(p LLMProvider) LLMProvider {
+	return &loggedProvider{LLMProvider: p}
+}
+
+// Unwrap returns the wrapped provider
+func (l *loggedProvider) Unwrap() LLMProvider {
+	return l.LLMProvider
+}
+
+// Generate logs the call after the wrapped provider returns
+func (l *loggedProvider) Generate(ctx context.Context, model, systemPrompt string, messages []Message, maxTokens int) (*GenerateResult, error) {
+	start := time.Now()
+	result, err := l.LLMProvider.Generate(ctx, model, systemPrompt, messages, maxTokens)
+	l.record(ctx, model, messages, false, start, result, err)
+	return result, err
+}
+
+// GenerateStreaming logs the call after the stream completes
+func (l *loggedProvider) GenerateStreaming(ctx context.Context, model, systemPrompt string, messages []Message, maxTokens int, callback StreamCallback) (*GenerateResult, error) {
+	start := time.Now()
+	result, err := l.LLMProvider.GenerateStreaming(ctx, model, systemPrompt, messages, maxTokens, callback)
+	l.record(ctx, model, messages, true, start, result, err)
+	return result, err
+}
+
+// record logs a finished call: failures at warn, successes at debug
+func (l *loggedProvider) record(ctx context.Context, model string, messages []Message, stream bool, start time.Time, result *GenerateResult, err error) {
+	if model == "" {
+		model = l.DefaultModel()
+	}
+	attrs := []any{
+		"provider", l.Name(),
+		"model", l.MapModel(model),
+		"messages", len(messages),
+		"stream", stream,
+		"duration", time.Since(start).Round(time.Millisecond),
+	}
+	if err != nil {
+		providerLog.WarnContext(ctx, "generation failed", append(attrs, "err", err)...)
+		return
+	}
+	if result != nil {
+		attrs = append(attrs, "inputTokens", result.InputTokens, "outputTokens", result.OutputTokens)
+	}
+	providerLog.DebugContext(ctx, "generation done", attrs...)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseLogLevelFlag(t *testing.T) {
+	tests := []struct {
+		args     []string
+		wantRest []string
+		wantSets []string
+		wantErr  bool
+	}{
+		{[]string{"--log-level", "debug", "--validate", "a.cpp"}, []string{"--validate", "a.cpp"}, []string{"logging.level=debug"}, false},
+		{[]string{"--log-level=WARN"}, nil, []string{"logging.level=warn"}, false},
+		{[]string{"--plain"}, []string{"--plain"}, nil, false},
+		{[]string{"--log-level", "verbose"}, nil, nil, true},
+		{[]string{"--log-level"}, nil, nil, true},
+	}
+	for _, tt := range tests {
+		rest, sets, err := parseLogLevelFlag(tt.args)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseLogLevelFlag(%v) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			continue
+		}
+		if strings.Join(rest, " ") != strings.Join(tt.wantRest, " ") || strings.Join(sets, " ") != strings.Join(tt.wantSets, " ") {
+			t.Errorf("parseLogLevelFlag(%v) = %v, %v; want %v, %v", tt.args, rest, sets, tt.wantRest, tt.wantSets)
+		}
+	}
+}
+
+func TestSubsystemLogging(t *testing.T) {
+	t.Cleanup(stopLogging)
+	path := filepath.Join(t.TempDir(), "logs", "bjarne.log")
+	settings := LoggingSettings{Level: LogLevelWarn, Format: LogFormatJSON, File: path, Subsystems: map[string]string{"container": LogLevelDebug}}
+	if err := configureLogging(settings); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("the log file was created before anything was logged")
+	}
+
+	containerLog.Debug("run", "name", "bjarne-1")
+	providerLog.Debug("generation done") // Below the provider's level
+	providerLog.With("model", "sonnet").Warn("generation failed", "err", errors.New("rate limited"))
+	indexLog.Info("indexing workspace") // Below the default level
+	if got := readLogRecords(t, path); len(got) != 2 ||
+		got[0]["subsystem"] != "container" || got[0]["name"] != "bjarne-1" ||
+		got[1]["subsystem"] != "provider" || got[1]["model"] != "sonnet" || got[1]["err"] != "rate limited" {
+		t.Errorf("records = %v, want the container debug and provider warning", got)
+	}
+
+	// /debug logs every subsystem at debug level until turned off
+	if got := setDebugLogging(true); got != path {
+		t.Errorf("setDebugLogging() = %q, want %q", got, path)
+	}
+	indexLog.Debug("opened")
+	setDebugLogging(false)
+	indexLog.Debug("opened")
+	if got := readLogRecords(t, path); len(got) != 3 || got[2]["subsystem"] != "index" {
+		t.Errorf("records = %v, want one index record from /debug", got)
+	}
+
+	settings.Format = "xml"
+	if err := configureLogging(settings); err == nil {
+		t.Error("configureLogging() accepted an unknown format")
+	}
+	stopLogging()
+	tuiLog.Error("dropped") // Nothing is configured
+}
+
+// readLogRecords parses a JSON log file
+func readLogRecords(t *testing.T, path string) []map[string]any {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var r map[string]any
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("invalid record %q: %v", line, err)
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+func TestRotatingWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bjarne.log")
+	w := &rotatingWriter{path: path, maxBytes: 10, maxFiles: 2}
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"bjarne.log": "fourth\n", "bjarne.log.1": "third\n", "bjarne.log.2": "second\n"} {
+		if got, _ := os.ReadFile(filepath.Join(filepath.Dir(path), name)); string(got) != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Error("kept more than maxFiles rotated files")
+	}
+}
+
+func TestValidateLogging(t *testing.T) {
+	s := DefaultSettings()
+	s.Logging.Level = "verbose"
+	s.Logging.MaxFiles = -1
+	s.Logging.Subsystems = map[string]string{"network": "debug", "index": "loud"}
+	var paths []string
+	for _, issue := range s.Validate() {
+		paths = append(paths, issue.Path)
+	}
+	want := "logging.level logging.maxFiles logging.subsystems.index logging.subsystems.network"
+	if got := strings.Join(paths, " "); got != want {
+		t.Errorf("issues = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,468 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// LSP diagnostic severities and message types
+const (
+	lspSeverityError   = 1
+	lspSeverityWarning = 2
+	lspMessageError    = 1
+)
+
+// lspNotification is a JSON-RPC notification sent to the editor
+type lspNotification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+// lspPosition is a zero-based line and character
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// lspRange is a span of a document
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+// lspDiagnostic is one finding published to the editor
+type lspDiagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"`
+	Code     string   `json:"code,omitempty"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+// lspRun is the in-flight validation of one document
+type lspRun struct {
+	cancel context.CancelFunc
+}
+
+// lspServer publishes bjarne's gate results as diagnostics when C/C++ buffers are saved
+type lspServer struct {
+	check fileCheckFunc
+
+	mu        sync.Mutex
+	docs      map[string]string   // Open buffers by path
+	uris      map[string]string   // URI the editor used for each open path
+	runs      map[string]*lspRun  // Validation in progress by path
+	checked   map[string]string   // Hash of the files last validated by path (skips unchanged saves)
+	published map[string][]string // Paths that each document's last run published diagnostics for
+	shutdown  bool
+
+	wg  sync.WaitGroup
+	wmu sync.Mutex // Serializes writes
+	out io.Writer
+}
+
+// newLSPServer creates a language server that validates with check
+func newLSPServer(check fileCheckFunc, out io.Writer) *lspServer {
+	return &lspServer{
+		check:     check,
+		docs:      make(map[string]string),
+		uris:      make(map[string]string),
+		runs:      make(map[string]*lspRun),
+		checked:   make(map[string]string),
+		published: make(map[string][]string),
+		out:       out,
+	}
+}
+
+// runLSP implements `bjarne lsp`: a language server on stdin/stdout (diagnostics go to stderr)
+func runLSP(args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintln(os.Stderr, "Usage: bjarne lsp")
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	container, err := newValidationRuntime(ctx, LoadConfig())
+	if err != nil {
+		fmt.Fprint(os.Stderr, FormatUserError(err))
+		return 1
+	}
+
+	s := newLSPServer(containerCheck(container), os.Stdout)
+	if err := s.serve(ctx, os.Stdin); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if !s.shutdown {
+		return 1 // The protocol asks for a non-zero exit when the editor skipped shutdown
+	}
+	return 0
+}
+
+// serve reads Content-Length framed JSON-RPC messages until exit or end of input
+func (s *lspServer) serve(ctx context.Context, in io.Reader) error {
+	defer s.wg.Wait()
+	defer s.cancelAll()
+
+	r := bufio.NewReader(in)
+	for {
+		body, err := readLSPMessage(r)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var req rpcRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			s.write(rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{rpcParseError, "parse error: " + err.Error()}})
+			continue
+		}
+		if req.Method == "exit" {
+			return nil
+		}
+		s.handle(ctx, req)
+	}
+}
+
+// handle dispatches one request or notification
+func (s *lspServer) handle(ctx context.Context, req rpcRequest) {
+	var params struct {
+		TextDocument struct {
+			URI  string `json:"uri"`
+			Text string `json:"text"`
+		} `json:"textDocument"`
+		ContentChanges []struct {
+			Text string `json:"text"`
+		} `json:"contentChanges"`
+		Text *string `json:"text"`
+	}
+	_ = json.Unmarshal(req.Params, &params)
+	uri := params.TextDocument.URI
+
+	switch req.Method {
+	case "initialize":
+		s.reply(req.ID, map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync": map[string]any{
+					"openClose": true,
+					"change":    1, // Full: the gates need whole files anyway
+					"save":      map[string]any{"includeText": true},
+				},
+			},
+			"serverInfo": map[string]any{"name": "bjarne", "version": Version},
+		})
+	case "shutdown":
+		s.mu.Lock()
+		s.shutdown = true
+		s.mu.Unlock()
+		s.cancelAll()
+		s.reply(req.ID, json.RawMessage("null"))
+	case "textDocument/didOpen":
+		s.setDocument(uri, params.TextDocument.Text)
+	case "textDocument/didChange":
+		if n := len(params.ContentChanges); n > 0 {
+			s.setDocument(uri, params.ContentChanges[n-1].Text)
+		}
+	case "textDocument/didSave":
+		if params.Text != nil {
+			s.setDocument(uri, *params.Text)
+		}
+		s.validate(ctx, uri)
+	case "textDocument/didClose":
+		s.closeDocument(uri)
+	default:
+		if len(req.ID) > 0 {
+			s.write(rpcResponse{JSONRPC: "2.0", ID: req.ID, Error: &rpcError{rpcMethodNotFound, "method not found: " + req.Method}})
+		}
+	}
+}
+
+// setDocument records the editor's contents for a C/C++ buffer
+func (s *lspServer) setDocument(uri, text string) {
+	path, err := uriToPath(uri)
+	if err != nil || !(isSourceFile(path) || isHeaderFile(path)) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.docs[path] = text
+	s.uris[path] = uri
+}
+
+// closeDocument forgets a buffer and clears the diagnostics its runs published
+func (s *lspServer) closeDocument(uri string) {
+	path, err := uriToPath(uri)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	if run := s.runs[path]; run != nil {
+		run.cancel()
+		delete(s.runs, path)
+	}
+	var stale []string
+	for _, p := range s.published[path] {
+		if u, ok := s.uris[p]; ok {
+			stale = append(stale, u)
+		} else {
+			stale = append(stale, pathToURI(p))
+		}
+	}
+	delete(s.published, path)
+	delete(s.checked, path)
+	delete(s.docs, path)
+	delete(s.uris, path)
+	s.mu.Unlock()
+
+	for _, u := range stale {
+		s.publish(u, []lspDiagnostic{})
+	}
+}
+
+// validate runs the gates for a saved document, replacing any run still in progress
+func (s *lspServer) validate(ctx context.Context, uri string) {
+	path, err := uriToPath(uri)
+	if err != nil || !(isSourceFile(path) || isHeaderFile(path)) {
+		return
+	}
+	files := collectLocalFiles(path, s.readFile)
+	if len(files) == 0 {
+		return
+	}
+	var sb strings.Builder
+	for _, f := range files {
+		sb.WriteString(f.Filename + "\x00" + f.Content + "\x00")
+	}
+	hash := sha256Hex(sb.String())
+
+	s.mu.Lock()
+	if s.checked[path] == hash {
+		s.mu.Unlock()
+		return // Nothing changed since the last run; its diagnostics still stand
+	}
+	if run := s.runs[path]; run != nil {
+		run.cancel()
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	run := &lspRun{cancel: cancel}
+	s.runs[path] = run
+	s.mu.Unlock()
+
+	dir, target := filepath.Dir(path), filepath.Base(path)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer cancel()
+
+		var done []ValidationResult
+		progress := func(stage string, running bool, result *ValidationResult) {
+			if running || result == nil {
+				return
+			}
+			done = append(done, *result)
+			s.publishRun(path, run, lspDiagnostics(dir, target, files, done), "")
+		}
+		results, err := s.check(runCtx, files, target, progress)
+		if runCtx.Err() != nil {
+			return // Superseded by a newer save, closed, or shutting down
+		}
+		if err != nil {
+			s.notify("window/logMessage", map[string]any{"type": lspMessageError, "message": "bjarne: " + err.Error()})
+			s.mu.Lock()
+			if s.runs[path] == run {
+				delete(s.runs, path)
+			}
+			s.mu.Unlock()
+			return
+		}
+
+		s.publishRun(path, run, lspDiagnostics(dir, target, files, results), hash)
+	}()
+}
+
+// publishRun sends a run's diagnostics unless a newer run replaced it
+// Files the previous run reported on and this one does not are cleared
+// hash is set for the final results and marks those contents as validated
+func (s *lspServer) publishRun(path string, run *lspRun, diags map[string][]lspDiagnostic, hash string) {
+	s.mu.Lock()
+	if s.runs[path] != run {
+		s.mu.Unlock()
+		return
+	}
+	if hash != "" {
+		delete(s.runs, path)
+		s.checked[path] = hash
+	}
+	var stale []string
+	for _, p := range s.published[path] {
+		if _, ok := diags[p]; !ok {
+			stale = append(stale, p)
+		}
+	}
+	paths := make([]string, 0, len(diags))
+	for p := range diags {
+		paths = append(paths, p)
+	}
+	s.published[path] = paths
+	s.mu.Unlock()
+
+	for _, p := range stale {
+		s.publish(s.uriFor(p), []lspDiagnostic{})
+	}
+	for _, p := range paths {
+		s.publish(s.uriFor(p), diags[p])
+	}
+}
+
+// publish replaces the diagnostics of one document
+func (s *lspServer) publish(uri string, diags []lspDiagnostic) {
+	s.notify("textDocument/publishDiagnostics", map[string]any{"uri": uri, "diagnostics": diags})
+}
+
+// cancelAll stops every validation in progress
+func (s *lspServer) cancelAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for path, run := range s.runs {
+		run.cancel()
+		delete(s.runs, path)
+	}
+}
+
+// readFile returns the editor's buffer for a path, or the file on disk
+func (s *lspServer) readFile(path string) (string, bool) {
+	s.mu.Lock()
+	text, ok := s.docs[path]
+	s.mu.Unlock()
+	if ok {
+		return text, true
+	}
+	return readDiskFile(path)
+}
+
+// uriFor returns the URI the editor knows a path by
+func (s *lspServer) uriFor(path string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if uri, ok := s.uris[path]; ok {
+		return uri
+	}
+	return pathToURI(path)
+}
+
+// reply answers a request
+func (s *lspServer) reply(id json.RawMessage, result any) {
+	s.write(rpcResponse{JSONRPC: "2.0", ID: id, Result: result})
+}
+
+// notify sends a notification
+func (s *lspServer) notify(method string, params any) {
+	s.write(lspNotification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// write sends one framed message
+func (s *lspServer) write(msg any) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	_, _ = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(data), data)
+}
+
+// readLSPMessage reads one Content-Length framed message body
+func readLSPMessage(r *bufio.Reader) ([]byte, error) {
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			if errors.Is(err, io.EOF) && (line != "" || length >= 0) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			if length, err = strconv.Atoi(strings.TrimSpace(value)); err != nil {
+				return nil, fmt.Errorf("invalid Content-Length %q", strings.TrimSpace(value))
+			}
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("message has no Content-Length header")
+	}
+	if length > maxServeRequestBytes {
+		return nil, fmt.Errorf("message of %d bytes exceeds the %d byte limit", length, maxServeRequestBytes)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, fmt.Errorf("failed to read message body: %w", err)
+	}
+	return body, nil
+}
+
+// lspDiagnostics converts gate results into diagnostics keyed by file path
+// The saved file always has an entry so its old diagnostics clear when the gates pass
+func lspDiagnostics(dir, target string, files []CodeFile, results []ValidationResult) map[string][]lspDiagnostic {
+	out := map[string][]lspDiagnostic{filepath.Join(dir, target): {}}
+	for _, f := range gateFindings(target, files, results) {
+		severity := lspSeverityError
+		if f.Level == LevelWarning {
+			severity = lspSeverityWarning
+		}
+		pos := lspPosition{Line: max(f.Line-1, 0), Character: max(f.Column-1, 0)}
+		path := filepath.Join(dir, f.File)
+		out[path] = append(out[path], lspDiagnostic{Range: lspRange{pos, pos}, Severity: severity,
+			Code: f.Check, Source: "bjarne/" + f.Stage, Message: f.Message})
+	}
+	return out
+}
+
+// uriToPath converts a file:// URI to a local path
+func uriToPath(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", fmt.Errorf("invalid document URI %q: %w", uri, err)
+	}
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("unsupported document URI %q (only file:// is validated)", uri)
+	}
+	p := u.Path
+	if runtime.GOOS == "windows" {
+		p = strings.TrimPrefix(p, "/") // file:///C:/src/main.cpp
+	}
+	return filepath.Clean(filepath.FromSlash(p)), nil
+}
+
+// pathToURI converts a local path to a file:// URI
+func pathToURI(path string) string {
+	p := filepath.ToSlash(path)
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	return (&url.URL{Scheme: "file", Path: p}).String()
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadLSPMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"crlf headers", "Content-Length: 2\r\nContent-Type: application/vscode-jsonrpc\r\n\r\n{}", "{}", false},
+		{"lf headers", "content-length: 4\n\nnull", "null", false},
+		{"missing length", "Content-Type: x\r\n\r\n{}", "", true},
+		{"bad length", "Content-Length: two\r\n\r\n{}", "", true},
+		{"short body", "Content-Length: 10\r\n\r\n{}", "", true},
+		{"truncated headers", "Content-Length: 2\r\n", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readLSPMessage(bufio.NewReader(strings.NewReader(tt.input)))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readLSPMessage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("readLSPMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLSPDiagnostics(t *testing.T) {
+	dir := filepath.FromSlash("/work")
+	files := []CodeFile{{Filename: "main.cpp"}, {Filename: "util.h"}}
+	results := []ValidationResult{
+		{Stage: "clang-tidy", Success: true, Output: "/src/util.h:4:10: warning: narrowing conversion [bugprone-narrowing-conversions]\n" +
+			"/usr/include/c++/vector:12:1: warning: system header noise [misc]\n" +
+			"/src/util.h:4:10: note: expanded from here\n"},
+		{Stage: "asan", Error: "==1==ERROR: AddressSanitizer: heap-buffer-overflow on address 0x1\n" +
+			"    #0 0x4f in std::vector<int>::at /usr/include/c++/vector:99\n" +
+			"    #1 0x50 in main /src/main.cpp:17\n"},
+		{Stage: "complexity", Error: "function too complex\nsecond line"},
+	}
+
+	got := lspDiagnostics(dir, "main.cpp", files, results)
+
+	util := got[filepath.Join(dir, "util.h")]
+	if len(util) != 1 || util[0].Range.Start != (lspPosition{3, 9}) || util[0].Severity != lspSeverityWarning ||
+		util[0].Code != "bugprone-narrowing-conversions" || util[0].Source != "bjarne/clang-tidy" {
+		t.Errorf("util.h diagnostics = %+v", util)
+	}
+
+	main := got[filepath.Join(dir, "main.cpp")]
+	if len(main) != 2 {
+		t.Fatalf("main.cpp diagnostics = %+v, want the sanitizer report and the complexity failure", main)
+	}
+	if main[0].Range.Start.Line != 16 || !strings.HasPrefix(main[0].Message, "heap-buffer-overflow") || main[0].Severity != lspSeverityError {
+		t.Errorf("asan diagnostic = %+v, want line 16 (0-based) from the first project frame", main[0])
+	}
+	if main[1].Range.Start.Line != 0 || !strings.HasPrefix(main[1].Message, "complexity failed\nfunction too complex") {
+		t.Errorf("complexity diagnostic = %+v", main[1])
+	}
+	if len(got) != 2 {
+		t.Errorf("diagnostics published for %d files, want 2 (system headers are skipped)", len(got))
+	}
+
+	// A clean run still publishes an empty list for the saved file to clear old findings
+	clean := lspDiagnostics(dir, "main.cpp", files, []ValidationResult{{Stage: "compile", Success: true}})
+	if d, ok := clean[filepath.Join(dir, "main.cpp")]; !ok || len(d) != 0 {
+		t.Errorf("clean run diagnostics = %+v", clean)
+	}
+}
+
+func TestLSPServe(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.cpp")
+	uri := pathToURI(path)
+
+	checks := make(chan string, 4)
+	check := func(ctx context.Context, files []CodeFile, target string, progress ProgressCallback) ([]ValidationResult, error) {
+		checks <- files[0].Content
+		return []ValidationResult{{Stage: "compile", Error: "/src/main.cpp:2:3: error: use of undeclared identifier 'y'"}}, nil
+	}
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	s := newLSPServer(check, outW)
+	done := make(chan error, 1)
+	go func() {
+		done <- s.serve(context.Background(), inR)
+		_ = outW.Close()
+	}()
+
+	send := func(id int, method string, params any) {
+		msg := map[string]any{"jsonrpc": "2.0", "method": method, "params": params}
+		if id > 0 {
+			msg["id"] = id
+		}
+		data, _ := json.Marshal(msg)
+		if _, err := fmt.Fprintf(inW, "Content-Length: %d\r\n\r\n%s", len(data), data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out := bufio.NewReader(outR)
+	receive := func() map[string]any {
+		body, err := readLSPMessage(out)
+		if err != nil {
+			t.Fatalf("reading server message: %v", err)
+		}
+		var msg map[string]any
+		if err := json.Unmarshal(body, &msg); err != nil {
+			t.Fatal(err)
+		}
+		return msg
+	}
+
+	send(1, "initialize", map[string]any{})
+	if caps := receive()["result"].(map[string]any)["capabilities"]; caps == nil {
+		t.Fatal("initialize returned no capabilities")
+	}
+	send(0, "initialized", map[string]any{})
+
+	code := "int main() {\n  y = 1;\n}\n"
+	doc := map[string]any{"uri": uri}
+	send(0, "textDocument/didOpen", map[string]any{"textDocument": map[string]any{"uri": uri, "languageId": "cpp", "version": 1, "text": code}})
+	send(0, "textDocument/didSave", map[string]any{"textDocument": doc})
+
+	msg := receive()
+	params := msg["params"].(map[string]any)
+	diags := params["diagnostics"].([]any)
+	if msg["method"] != "textDocument/publishDiagnostics" || params["uri"] != uri || len(diags) != 1 {
+		t.Fatalf("published %v", msg)
+	}
+	if got := <-checks; got != code {
+		t.Errorf("validated %q, want the open buffer", got)
+	}
+
+	// Saving unchanged contents does not rerun the gates
+	send(0, "textDocument/didSave", map[string]any{"textDocument": doc})
+	send(2, "textDocument/hover", map[string]any{})
+	if resp := receive(); resp["error"] == nil {
+		t.Errorf("hover response = %v, want method not found", resp)
+	}
+	select {
+	case <-checks:
+		t.Error("unchanged save ran the gates again")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	send(0, "textDocument/didClose", map[string]any{"textDocument": doc})
+	if msg := receive(); len(msg["params"].(map[string]any)["diagnostics"].([]any)) != 0 {
+		t.Errorf("close should clear diagnostics, got %v", msg)
+	}
+
+	send(3, "shutdown", nil)
+	if resp := receive(); resp["id"] != float64(3) || resp["error"] != nil {
+		t.Errorf("shutdown response = %v", resp)
+	}
+	send(0, "exit", nil)
+	if err := <-done; err != nil {
+		t.Errorf("serve() error = %v", err)
+	}
+	if !s.shutdown {
+		t.Error("shutdown was not recorded")
+	}
+}
@@ -1,10 +1,8 @@
 package main
 
 import (
-	"context"
 	"fmt"
 	"os"
-	"path/filepath"
 )
 
 // Version information (set via ldflags during build)
@@ -15,106 +13,79 @@ var (
 )
 
 func main() {
+	args, offline := parseOfflineFlag(os.Args[1:])
+	offlineMode = offline
+	args, plain := parsePlainFlag(args)
+	plainMode = plain
+	if plainMode {
+		applyPlainMode()
+	}
+	args, sets, err := parseSetFlags(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+	args, levels, err := parseLogLevelFlag(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+	settingFlags = append(sets, levels...)
+	startLogging()
+
 	// Handle --version and --help flags
-	if len(os.Args) > 1 {
-		switch os.Args[1] {
+	if len(args) > 0 {
+		switch args[0] {
 		case "--version", "-V":
 			fmt.Printf("bjarne %s (%s, built %s)\n", Version, Commit, Date)
 			fmt.Println("AI-assisted C/C++ code generation with mandatory validation")
-			os.Exit(0)
+			exit(0)
 		case "--help", "-h":
 			printHelp()
-			os.Exit(0)
+			exit(0)
+		case "audit":
+			exit(runAudit(args[1:]))
+		case "serve":
+			exit(runServe(args[1:]))
+		case "mcp":
+			exit(runMCP(args[1:]))
+		case "lsp":
+			exit(runLSP(args[1:]))
+		case "ci":
+			exit(runCI(args[1:]))
+		case "reproduce":
+			exit(runReproduce(args[1:]))
+		case "attest":
+			exit(runAttest(args[1:]))
+		case "hook":
+			exit(runHook(args[1:]))
+		case "batch":
+			exit(runBatch(args[1:]))
+		case "init":
+			exit(runInit(args[1:]))
+		case "doctor":
+			exit(runDoctor(args[1:]))
+		case "config":
+			exit(runConfig(args[1:]))
+		case "--watch", "-w":
+			exit(runWatch(args[1:]))
 		case "--validate", "-v":
 			// Validate-only mode
-			if len(os.Args) < 3 {
-				fmt.Fprintln(os.Stderr, "Usage: bjarne --validate <file1.cpp> [file2.cpp ...]")
-				os.Exit(1)
+			opts, err := parseValidateArgs(args[1:])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n%s\n", err, validateUsage)
+				exit(1)
 			}
-			os.Exit(runValidateOnly(os.Args[2:]))
+			exit(runValidateOnly(opts))
 		}
 	}
 
 	// Start the TUI
 	if err := StartTUI(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
-	}
-}
-
-// runValidateOnly validates files without entering the REPL
-func runValidateOnly(files []string) int {
-	ctx := context.Background()
-
-	// Initialize container runtime
-	container, err := DetectContainerRuntime()
-	if err != nil {
-		fmt.Print(FormatUserError(err))
-		return 1
-	}
-	fmt.Printf("Using container runtime: %s\n", container.GetBinary())
-
-	// Check if validation image exists
-	if !container.ImageExists(ctx) {
-		fmt.Printf("\033[91mError:\033[0m Validation container not found.\n")
-		fmt.Printf("       Run 'bjarne' interactively to pull the container first.\n")
-		return 1
-	}
-
-	allPassed := true
-
-	for _, filename := range files {
-		// Read the file
-		content, err := os.ReadFile(filename)
-		if err != nil {
-			fmt.Printf("\033[91mERROR %s:\033[0m %v\n", filename, err)
-			allPassed = false
-			continue
-		}
-
-		code := string(content)
-		if code == "" {
-			fmt.Printf("\033[91mERROR %s:\033[0m File is empty\n", filename)
-			allPassed = false
-			continue
-		}
-
-		fmt.Printf("\n\033[93mValidating %s...\033[0m\n", filename)
-
-		// Get base filename for container
-		baseName := filepath.Base(filename)
-
-		// Run validation pipeline
-		results, err := container.ValidateCode(ctx, code, baseName)
-		if err != nil {
-			fmt.Printf("\033[91mERROR %s:\033[0m %v\n", filename, err)
-			allPassed = false
-			continue
-		}
-
-		fmt.Print(FormatResults(results))
-
-		// Check if all passed
-		filePassed := true
-		for _, r := range results {
-			if !r.Success {
-				filePassed = false
-				allPassed = false
-				break
-			}
-		}
-
-		if filePassed {
-			fmt.Printf("\033[92m%s passed all validation!\033[0m\n", filename)
-		}
-	}
-
-	if allPassed {
-		fmt.Printf("\n\033[92mAll files passed validation!\033[0m\n")
-		return 0
+		exit(1)
 	}
-	fmt.Printf("\n\033[91mSome files failed validation.\033[0m\n")
-	return 1
+	exit(0)
 }
 
 func printHelp() {
@@ -122,12 +93,31 @@ func printHelp() {
 
 Usage:
   bjarne [flags]
-  bjarne --validate <file1.cpp> [file2.cpp ...]
+  bjarne init
+  bjarne doctor
+  bjarne config validate [settings.json | .bjarne.toml]
+  bjarne config show [--origin]
+  bjarne --validate [options] <file.cpp | dir | glob | -> ...
+  bjarne --watch [dir] [--notify]
+  bjarne audit [list | show <session|latest>]
+  bjarne serve [--addr host:port] [--token <token>]
+  bjarne mcp
+  bjarne lsp
+  bjarne ci [--base <ref>] [--gates <list>] [--skip <list>] [--sarif <file>] [files...]
+  bjarne reproduce <manifest.json>
+  bjarne attest <manifest.json> [--key <private.pem> | --sigstore] | keygen <name> | verify <attestation>
+  bjarne hook install [--gates <list>] [--force] | uninstall
+  bjarne batch <tasks.yaml|tasks.json> [--report <file.json|file.md>] [--only <names>]
 
 Flags:
   -h, --help           Show this help message
   -V, --version        Show version information
   -v, --validate       Validate files without entering REPL
+  -w, --watch          Re-validate C/C++ files in a directory as they change
+      --offline        No network: local provider only, no update checks or downloads
+      --plain          No color, box drawing or spinners; announce progress as sentences (screen readers, logs)
+      --set key=value  Override a setting for this run, e.g. --set review.threshold=80
+      --log-level lvl  Log level for this run: debug|info|warn|error|off (log: ~/.bjarne/logs/bjarne.log)
 
 Interactive Commands (in REPL):
   /help                Show available commands
@@ -137,7 +127,10 @@ Interactive Commands (in REPL):
   /quit                Exit bjarne
 
 Environment Variables:
-  BJARNE_PROVIDER         LLM provider: bedrock|anthropic|openai|gemini (default: bedrock)
+  BJARNE_PROVIDER         LLM provider: bedrock|anthropic|openai|gemini|local (default: bedrock)
+  BJARNE_OFFLINE          Set to 1 for offline mode (same as --offline)
+  BJARNE_NO_COLOR         Set to 1 for plain output (same as --plain)
+  BJARNE_LOG_LEVEL        Log level (same as --log-level)
   BJARNE_API_KEY          API key for Anthropic/OpenAI/Gemini providers
   AWS_ACCESS_KEY_ID       AWS credentials for Bedrock
   AWS_SECRET_ACCESS_KEY   AWS credentials for Bedrock
@@ -149,6 +142,7 @@ Environment Variables:
   BJARNE_MAX_TOTAL_TOKENS Session token budget (default: 150000, 0=unlimited)
   LLMGUARD_URL            LLM Guard API URL for security scanning (optional)
   LLMGUARD_TOKEN          LLM Guard API token for authentication (optional)
+  BJARNE_CA_BUNDLE        Extra root CAs (PEM) for outbound HTTPS (proxies: HTTPS_PROXY)
 
 Examples:
   # Interactive mode
@@ -161,6 +155,10 @@ Examples:
   # Validate-only mode
   $ bjarne --validate mycode.cpp
   $ bjarne -v file1.cpp file2.cpp file3.cpp
+  $ bjarne -v --jobs 8 src/*.cpp
+  $ bjarne -v src/ --recursive --exclude 'third_party/**'
+  $ bjarne -v --gates clang-tidy,compile mycode.cpp
+  $ cat snippet.cpp | bjarne --validate --lang c++20 -
 
 For more information: https://github.com/3rg0n/bjarne`)
 }
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// manifestVersion is the format of validation manifests; reproduce rejects newer ones
+const manifestVersion = 1
+
+// projectManifestsDir holds a manifest for each validation that passed, relative to the project root
+var projectManifestsDir = filepath.Join(".bjarne", "manifests")
+
+// reproduceUsage is printed for bad `bjarne reproduce` arguments
+const reproduceUsage = "Usage: bjarne reproduce <manifest.json>"
+
+// ValidationManifest records a validation that passed in enough detail to replay it on another machine
+type ValidationManifest struct {
+	Version  int            `json:"version"`
+	Created  time.Time      `json:"created"`
+	Bjarne   string         `json:"bjarne"`             // bjarne version that ran the validation
+	Image    string         `json:"image"`              // Validator image reference
+	Digest   string         `json:"digest,omitempty"`   // Local digest of the image
+	Compiler string         `json:"compiler,omitempty"` // First line of clang++ --version in the image
+	Config   ManifestConfig `json:"config"`
+	Files    []ManifestFile `json:"files"`
+
+	Examples *ExampleTests     `json:"examples,omitempty"`
+	DoD      *DefinitionOfDone `json:"dod,omitempty"`
+	Stages   []ManifestStage   `json:"stages"`
+
+	Provider    string            `json:"provider,omitempty"`
+	Models      map[string]string `json:"models,omitempty"`      // Role ("code", "reflection") -> model ID
+	PromptsHash string            `json:"promptsHash,omitempty"` // SHA-256 of the system prompt and conversation behind the code
+}
+
+// ManifestConfig is the runtime configuration that decides which gates run and how
+type ManifestConfig struct {
+	Standard     string             `json:"standard"`
+	Gates        GateSelection      `json:"gates"`
+	Format       FormatSettings     `json:"format"`
+	ClangTidy    ClangTidySettings  `json:"clangTidy"`
+	Dependencies DependencySettings `json:"dependencies"`
+	Hang         HangSettings       `json:"hang"`
+	Stress       StressSettings     `json:"stress"`
+	Exceptions   ExceptionSettings  `json:"exceptions"`
+	ModelCheck   ModelCheckSettings `json:"modelCheck"`
+	Valgrind     ValgrindSettings   `json:"valgrind"`
+	Templates    TemplateSettings   `json:"templates"`
+	Sandbox      SandboxSettings    `json:"sandbox"`
+	Suppressions []Suppression      `json:"suppressions,omitempty"`
+	ASTRules     []ASTRule          `json:"astRules,omitempty"`
+	Fixtures     *Fixtures          `json:"fixtures,omitempty"`
+}
+
+// ManifestFile is a validated file and its SHA-256
+type ManifestFile struct {
+	Name    string `json:"name"`
+	SHA256  string `json:"sha256"`
+	Content string `json:"content"`
+}
+
+// ManifestStage is one gate's result and the command it ran in the container
+type ManifestStage struct {
+	Stage    string        `json:"stage"`
+	Command  []string      `json:"command,omitempty"`
+	Success  bool          `json:"success"`
+	Skipped  bool          `json:"skipped,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// CompilerVersion returns the first line of clang++ --version in the validator image, or "" if it cannot run
+func (c *ContainerRuntime) CompilerVersion(ctx context.Context) string {
+	var stdout bytes.Buffer
+	if err := c.runContainer(ctx, &stdout, io.Discard, "--rm", "--network", "none", c.imageName, "clang++", "--version"); err != nil {
+		return ""
+	}
+	line, _, _ := strings.Cut(stdout.String(), "\n")
+	return strings.TrimSpace(line)
+}
+
+// manifestConfig captures the runtime's configuration for a manifest
+func (c *ContainerRuntime) manifestConfig() ManifestConfig {
+	return ManifestConfig{
+		Standard:     strings.TrimPrefix(c.stdFlag(), "-std="),
+		Gates:        c.gates,
+		Format:       c.format,
+		ClangTidy:    c.tidy,
+		Dependencies: c.dependencies,
+		Hang:         c.hang,
+		Stress:       c.stress,
+		Exceptions:   c.exceptions,
+		ModelCheck:   c.modelCheck,
+		Valgrind:     c.valgrind,
+		Templates:    c.templates,
+		Sandbox:      c.sandbox,
+		Suppressions: c.suppressions,
+		ASTRules:     c.astRules,
+		Fixtures:     c.fixtures,
+	}
+}
+
+// applyManifestConfig configures the runtime as it was when the manifest was recorded
+func (c *ContainerRuntime) applyManifestConfig(cfg ManifestConfig) {
+	c.SetStandard(cfg.Standard)
+	c.SetGateSelection(cfg.Gates)
+	c.SetFormatSettings(cfg.Format)
+	c.SetClangTidySettings(cfg.ClangTidy)
+	c.SetDependencySettings(cfg.Dependencies)
+	c.SetHangSettings(cfg.Hang)
+	c.SetStressSettings(cfg.Stress)
+	c.SetExceptionSettings(cfg.Exceptions)
+	c.SetModelCheckSettings(cfg.ModelCheck)
+	c.SetValgrindSettings(cfg.Valgrind)
+	c.SetTemplateSettings(cfg.Templates)
+	c.SetSandboxSettings(cfg.Sandbox)
+	c.SetSuppressions(cfg.Suppressions)
+	c.SetASTRules(cfg.ASTRules)
+	c.SetFixtures(cfg.Fixtures)
+}
+
+// NewValidationManifest records a validation of files: the image, configuration and each gate's command and result
+// The caller adds the provider, models and prompts hash it knows about
+func (c *ContainerRuntime) NewValidationManifest(ctx context.Context, files []CodeFile, examples *ExampleTests, dod *DefinitionOfDone, results []ValidationResult) *ValidationManifest {
+	m := &ValidationManifest{
+		Version:  manifestVersion,
+		Created:  time.Now().UTC(),
+		Bjarne:   Version,
+		Image:    c.imageName,
+		Digest:   c.GetLocalImageDigest(ctx),
+		Compiler: c.CompilerVersion(ctx),
+		Config:   c.manifestConfig(),
+		Examples: examples,
+		DoD:      dod,
+	}
+	for _, f := range files {
+		m.Files = append(m.Files, ManifestFile{Name: f.Filename, SHA256: sha256Hex(f.Content), Content: f.Content})
+	}
+	for _, r := range results {
+		m.Stages = append(m.Stages, ManifestStage{Stage: r.Stage, Command: withoutArtifactCopy(r.Command), Success: r.Success, Skipped: r.Skipped, Duration: r.Duration})
+	}
+	return m
+}
+
+// WriteValidationManifest saves m in root's .bjarne/manifests and returns its path
+func WriteValidationManifest(root string, m *ValidationManifest) (string, error) {
+	dir := filepath.Join(root, projectManifestsDir)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", projectManifestsDir, err)
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	path := filepath.Join(dir, newSessionID(m.Created.Local())+".json")
+	if err := writeFileAtomic(path, append(data, '\n'), 0600); err != nil {
+		return "", fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return path, nil
+}
+
+// LoadValidationManifest reads a manifest and checks its files against their hashes
+func LoadValidationManifest(path string) (*ValidationManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	var m ValidationManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+	if m.Version < 1 || m.Version > manifestVersion {
+		return nil, fmt.Errorf("manifest %s has version %d; this bjarne reads version %d", path, m.Version, manifestVersion)
+	}
+	if len(m.Files) == 0 {
+		return nil, fmt.Errorf("manifest %s has no files", path)
+	}
+	for _, f := range m.Files {
+		if sha256Hex(f.Content) != f.SHA256 {
+			return nil, fmt.Errorf("%s in manifest %s does not match its sha256", f.Name, path)
+		}
+	}
+	return &m, nil
+}
+
+// codeFiles returns the manifest's files for validation
+func (m *ValidationManifest) codeFiles() []CodeFile {
+	files := make([]CodeFile, len(m.Files))
+	for i, f := range m.Files {
+		files[i] = CodeFile{Filename: f.Name, Content: f.Content}
+	}
+	return files
+}
+
+// reproduceImage returns the reference of the manifest's image at its recorded digest, pulling it if needed
+func (c *ContainerRuntime) reproduceImage(ctx context.Context, m *ValidationManifest) (string, error) {
+	if m.Digest == "" || isPinnedRef(m.Image) || c.LocalDigest(ctx, m.Image) == m.Digest {
+		return m.Image, nil
+	}
+	if err := ValidateDigest(m.Digest); err != nil {
+		return "", err
+	}
+	pinned := imageRepo(m.Image) + "@" + m.Digest
+	if c.HasImage(ctx, pinned) {
+		return pinned, nil
+	}
+	if err := c.FetchImage(ctx, pinned); err != nil {
+		return "", fmt.Errorf("the validation ran on %s, which is not available: %w", pinned, err)
+	}
+	return pinned, nil
+}
+
+// stageStatus is a result's PASS, FAIL or SKIP
+func stageStatus(success, skipped bool) string {
+	switch {
+	case skipped:
+		return "SKIP"
+	case success:
+		return "PASS"
+	default:
+		return "FAIL"
+	}
+}
+
+// compareManifestStages lines up the replayed results with the recorded ones
+// It reports whether every gate ran the same command with the same outcome
+func compareManifestStages(recorded []ManifestStage, results []ValidationResult) ([]string, bool) {
+	replayed := make(map[string]ValidationResult, len(results))
+	for _, r := range results {
+		replayed[r.Stage] = r
+	}
+	same := len(recorded) == len(results)
+	var lines []string
+	for _, s := range recorded {
+		was := stageStatus(s.Success, s.Skipped)
+		r, ok := replayed[s.Stage]
+		if !ok {
+			same = false
+			lines = append(lines, fmt.Sprintf("%-20s %s -> did not run", s.Stage, was))
+			continue
+		}
+		now := stageStatus(r.Success, r.Skipped)
+		line := fmt.Sprintf("%-20s %s -> %s", s.Stage, was, now)
+		if was != now {
+			same = false
+		}
+		if strings.Join(s.Command, "\x00") != strings.Join(r.Command, "\x00") {
+			same = false
+			line += " (different command)"
+		}
+		lines = append(lines, line)
+	}
+	recordedStages := make(map[string]bool, len(recorded))
+	for _, s := range recorded {
+		recordedStages[s.Stage] = true
+	}
+	for _, r := range results {
+		if !recordedStages[r.Stage] {
+			same = false
+			lines = append(lines, fmt.Sprintf("%-20s new -> %s", r.Stage, stageStatus(r.Success, r.Skipped)))
+		}
+	}
+	return lines, same
+}
+
+// runReproduce implements `bjarne reproduce <manifest>`: it replays a recorded validation and
+// compares each gate with the recording
+func runReproduce(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, reproduceUsage)
+		return 1
+	}
+	m, err := LoadValidationManifest(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	container, err := DetectContainerRuntime()
+	if err != nil {
+		fmt.Print(FormatUserError(err))
+		return 1
+	}
+	SetNetworkSettings(LoadConfig().Settings.Network)
+	container.applyManifestConfig(m.Config)
+	image, err := container.reproduceImage(ctx, m)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	container.SetImage(image)
+
+	fmt.Printf("Reproducing the validation of %s from %s\n", m.Created.Local().Format("2006-01-02 15:04"), args[0])
+	fmt.Printf("  image    %s\n", image)
+	if m.Compiler != "" {
+		fmt.Printf("  compiler %s\n", m.Compiler)
+		if now := container.CompilerVersion(ctx); now != "" && now != m.Compiler {
+			fmt.Printf("\033[93mWarning:\033[0m the image now has %s\n", now)
+		}
+	}
+	if m.Bjarne != Version {
+		fmt.Printf("\033[93mWarning:\033[0m recorded with bjarne %s, replaying with %s\n", m.Bjarne, Version)
+	}
+
+	files := m.codeFiles()
+	var results []ValidationResult
+	if len(files) > 1 {
+		results, err = container.ValidateMultiFileCodeWithProgress(ctx, files, m.Examples, m.DoD, nil)
+	} else {
+		results, err = container.ValidateCodeWithDoD(ctx, files[0].Content, files[0].Filename, m.Examples, m.DoD, nil)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	lines, same := compareManifestStages(m.Stages, results)
+	fmt.Printf("\n%s\n\n", strings.Join(lines, "\n"))
+	if !same {
+		fmt.Printf("\033[91mNot reproduced:\033[0m the gates above ran differently\n")
+		return 1
+	}
+	fmt.Printf("\033[92mReproduced:\033[0m every gate ran the same command with the same result\n")
+	return 0
+}
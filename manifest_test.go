@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidationManifestRoundTrip(t *testing.T) {
+	c := &ContainerRuntime{imageName: "bjarne-validator:test", standard: "c++20"}
+	c.SetGateSelection(GateSelection{Skip: []string{"msan"}})
+	c.SetASTRules([]ASTRule{{Name: "no-goto", Message: "Avoid goto", Script: "match gotoStmt()"}})
+	files := []CodeFile{{Filename: "code.cpp", Content: "int main() { return 0; }\n"}}
+	results := []ValidationResult{
+		{Stage: "compile", Success: true, Command: []string{"clang++", "-std=c++20", "-o", "/tmp/test", "/src/code.cpp"}},
+		{Stage: "msan", Success: true, Skipped: true},
+		{Stage: "run", Success: true, Command: []string{"sh", "-c", "clang++ -O2 -o /tmp/test /src/code.cpp && cp /tmp/test /artifacts/program && /tmp/test"}},
+	}
+
+	m := c.NewValidationManifest(context.Background(), files, nil, &DefinitionOfDone{MaxTimeMs: 50}, results)
+	m.Models = map[string]string{"code": "sonnet"}
+	path, err := WriteValidationManifest(t.TempDir(), m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadValidationManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Config.Standard != "c++20" || !reflect.DeepEqual(loaded.Config.Gates, c.gates) || len(loaded.Config.ASTRules) != 1 {
+		t.Errorf("loaded config = %+v", loaded.Config)
+	}
+	if loaded.DoD == nil || loaded.DoD.MaxTimeMs != 50 || loaded.Models["code"] != "sonnet" {
+		t.Errorf("loaded manifest = %+v", loaded)
+	}
+	if got := loaded.Stages[2].Command[2]; strings.Contains(got, "/artifacts") {
+		t.Errorf("recorded run command = %q, want the artifact copy left out", got)
+	}
+	if !reflect.DeepEqual(loaded.codeFiles(), files) {
+		t.Errorf("codeFiles() = %+v, want %+v", loaded.codeFiles(), files)
+	}
+
+	replay := &ContainerRuntime{}
+	replay.applyManifestConfig(loaded.Config)
+	if !reflect.DeepEqual(replay.manifestConfig(), loaded.Config) {
+		t.Errorf("applyManifestConfig() = %+v, want %+v", replay.manifestConfig(), loaded.Config)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := strings.Replace(string(data), "return 0;", "return 1;", 1)
+	if err := os.WriteFile(path, []byte(tampered), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadValidationManifest(path); err == nil || !strings.Contains(err.Error(), "does not match its sha256") {
+		t.Errorf("LoadValidationManifest(edited code) error = %v", err)
+	}
+	if err := os.WriteFile(path, []byte(`{"version": 2, "files": [{"name": "a.cpp"}]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadValidationManifest(path); err == nil || !strings.Contains(err.Error(), "version 2") {
+		t.Errorf("LoadValidationManifest(newer version) error = %v", err)
+	}
+}
+
+func TestCompareManifestStages(t *testing.T) {
+	recorded := []ManifestStage{
+		{Stage: "compile", Command: []string{"clang++", "-O0"}, Success: true},
+		{Stage: "msan", Success: true, Skipped: true},
+		{Stage: "run", Command: []string{"sh", "-c", "run"}, Success: true},
+	}
+	same := []ValidationResult{
+		{Stage: "compile", Command: []string{"clang++", "-O0"}, Success: true},
+		{Stage: "msan", Success: true, Skipped: true},
+		{Stage: "run", Command: []string{"sh", "-c", "run"}, Success: true},
+	}
+	if lines, ok := compareManifestStages(recorded, same); !ok || len(lines) != 3 {
+		t.Errorf("compareManifestStages(same) = %q, %v", lines, ok)
+	}
+
+	tests := []struct {
+		name    string
+		results []ValidationResult
+		want    string
+	}{
+		{"failed", []ValidationResult{same[0], same[1], {Stage: "run", Command: []string{"sh", "-c", "run"}}}, "run                  PASS -> FAIL"},
+		{"flags", []ValidationResult{{Stage: "compile", Command: []string{"clang++", "-O2"}, Success: true}, same[1], same[2]}, "(different command)"},
+		{"missing", same[:2], "run                  PASS -> did not run"},
+		{"new gate", append(append([]ValidationResult(nil), same...), ValidationResult{Stage: "tsan", Success: true}), "tsan                 new -> PASS"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines, ok := compareManifestStages(recorded, tt.results)
+			if ok {
+				t.Fatalf("compareManifestStages() = same, want a difference")
+			}
+			if got := strings.Join(lines, "\n"); !strings.Contains(got, tt.want) {
+				t.Errorf("compareManifestStages() = %q, missing %q", got, tt.want)
+			}
+		})
+	}
+}
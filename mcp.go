@@ -0,0 +1,543 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// mcpProtocolVersion is the newest Model Context Protocol revision this server speaks
+const mcpProtocolVersion = "2025-06-18"
+
+// JSON-RPC error codes used by the MCP server
+const (
+	rpcParseError     = -32700
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+)
+
+// rpcRequest is a JSON-RPC 2.0 request or notification (no ID)
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// rpcResponse is a JSON-RPC 2.0 response
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcError is a JSON-RPC 2.0 error object
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// mcpTool describes one tool in tools/list
+type mcpTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+}
+
+// mcpContent is one content block of a tool result
+type mcpContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// mcpToolResult is the result of tools/call
+type mcpToolResult struct {
+	Content           []mcpContent `json:"content"`
+	StructuredContent any          `json:"structuredContent,omitempty"`
+	IsError           bool         `json:"isError,omitempty"`
+}
+
+// workspaceHit is one search_workspace result
+type workspaceHit struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Kind    string `json:"kind"`
+	Name    string `json:"name"`
+	Snippet string `json:"snippet,omitempty"`
+}
+
+// mcpServer answers MCP requests over stdio with the same engine as bjarne serve
+type mcpServer struct {
+	engine    *server
+	workspace *WorkspaceIndex
+	vectors   *VectorIndex
+
+	mu  sync.Mutex // Serializes writes; tool calls run concurrently
+	out io.Writer
+
+	callsMu sync.Mutex
+	calls   map[string]context.CancelFunc // Requests in progress by ID, for notifications/cancelled
+}
+
+// errMCPMessageTooLong reports a message over maxServeRequestBytes; the rest of its line is skipped
+var errMCPMessageTooLong = fmt.Errorf("message longer than %d bytes", maxServeRequestBytes)
+
+// mcpTools are the tools bjarne offers to other agents
+var mcpTools = []mcpTool{
+	{
+		Name:        "validate_cpp",
+		Description: "Compile C/C++ code and run it through bjarne's validation gates (clang-tidy, ASan, UBSan, TSan and more) in an isolated container. Pass code with an optional filename, or files for a multi-file project.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"code":     map[string]any{"type": "string", "description": "Source of a single file"},
+				"filename": map[string]any{"type": "string", "description": "Filename for code (default code.cpp)"},
+				"files": map[string]any{
+					"type":        "array",
+					"description": "Files of a multi-file project",
+					"items": map[string]any{
+						"type":       "object",
+						"properties": map[string]any{"filename": map[string]any{"type": "string"}, "content": map[string]any{"type": "string"}},
+						"required":   []string{"filename", "content"},
+					},
+				},
+			},
+		},
+	},
+	{
+		Name:        "generate_validated_cpp",
+		Description: "Generate C/C++ code for a request and fix it until it passes bjarne's validation gates (or the attempts run out). Returns the code and the gate results.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"prompt":        map[string]any{"type": "string", "description": "What to build"},
+				"model":         map[string]any{"type": "string", "description": "haiku, sonnet, opus, a model ID, or provider:model"},
+				"maxIterations": map[string]any{"type": "integer", "minimum": 0, "description": "Fix attempts after the first generation, up to the configured limit"},
+			},
+			"required": []string{"prompt"},
+		},
+	},
+	{
+		Name:        "search_workspace",
+		Description: "Search the indexed C/C++ workspace (bjarne /init) for functions, classes and structs related to a query.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"query": map[string]any{"type": "string"},
+				"limit": map[string]any{"type": "integer", "minimum": 1, "maximum": 50, "description": "Default 10"},
+			},
+			"required": []string{"query"},
+		},
+	},
+}
+
+// runMCP implements `bjarne mcp`: an MCP server on stdin/stdout (diagnostics go to stderr)
+func runMCP(args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintln(os.Stderr, "Usage: bjarne mcp")
+		return 1
+	}
+
+	engine, err := newServer("")
+	if err != nil {
+		fmt.Fprint(os.Stderr, FormatUserError(err))
+		return 1
+	}
+	s := &mcpServer{engine: engine, out: os.Stdout}
+
+	cwd, _ := os.Getwd()
+	if idx, err := LoadIndex(cwd); err == nil {
+		s.workspace = idx
+	}
+	vecCfg := DefaultVectorIndexConfig()
+	vecCfg.ReadOnly = true // Searches only; editors may run several servers next to the TUI
+	if vi, err := NewVectorIndex(vecCfg); err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if _, _, embeddings, _ := vi.GetStats(ctx); embeddings > 0 && vi.EnsureModel(ctx, nil) == nil {
+			s.vectors = vi
+		} else {
+			_ = vi.Close()
+		}
+		cancel()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := s.serve(ctx, os.Stdin); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// serve reads newline-delimited JSON-RPC messages until in closes
+func (s *mcpServer) serve(ctx context.Context, in io.Reader) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	r := bufio.NewReaderSize(in, 64*1024)
+	for {
+		data, err := readMCPMessage(r, maxServeRequestBytes)
+		if errors.Is(err, errMCPMessageTooLong) {
+			s.write(rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{rpcParseError, "parse error: " + err.Error()}})
+			continue
+		}
+		if line := strings.TrimSpace(string(data)); line != "" {
+			s.dispatch(ctx, &wg, line)
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// readMCPMessage reads one line; a line over limit is read to its end and reported with errMCPMessageTooLong
+func readMCPMessage(r *bufio.Reader, limit int) ([]byte, error) {
+	var line []byte
+	tooLong := false
+	for {
+		chunk, err := r.ReadSlice('\n')
+		tooLong = tooLong || len(line)+len(chunk) > limit+1 // The newline does not count
+		if !tooLong {
+			line = append(line, chunk...)
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if tooLong && (err == nil || errors.Is(err, io.EOF)) {
+			return nil, errMCPMessageTooLong
+		}
+		return line, err
+	}
+}
+
+// dispatch handles one message: requests run concurrently, since tool calls can take minutes
+// and pings and listings should be answered meanwhile
+func (s *mcpServer) dispatch(ctx context.Context, wg *sync.WaitGroup, line string) {
+	var req rpcRequest
+	if err := json.Unmarshal([]byte(line), &req); err != nil {
+		s.write(rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{rpcParseError, "parse error: " + err.Error()}})
+		return
+	}
+	if len(req.ID) == 0 {
+		// Other notifications (initialized) need nothing
+		if req.Method == "notifications/cancelled" {
+			var params struct {
+				RequestID json.RawMessage `json:"requestId"`
+			}
+			if json.Unmarshal(req.Params, &params) == nil {
+				s.cancelCall(params.RequestID)
+			}
+		}
+		return
+	}
+
+	callCtx, cancel := context.WithCancel(ctx)
+	s.trackCall(req.ID, cancel)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer s.untrackCall(req.ID)
+		result, rpcErr := s.handle(callCtx, req)
+		if callCtx.Err() != nil && ctx.Err() == nil {
+			return // Cancelled by the client, which expects no response
+		}
+		s.write(rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: result, Error: rpcErr})
+	}()
+}
+
+// rpcIDKey normalizes a request ID, a JSON number or string, for the calls map
+func rpcIDKey(id json.RawMessage) string {
+	var buf bytes.Buffer
+	if json.Compact(&buf, id) != nil {
+		return string(id)
+	}
+	return buf.String()
+}
+
+// trackCall records the cancel function of a request in progress
+func (s *mcpServer) trackCall(id json.RawMessage, cancel context.CancelFunc) {
+	s.callsMu.Lock()
+	defer s.callsMu.Unlock()
+	if s.calls == nil {
+		s.calls = make(map[string]context.CancelFunc)
+	}
+	s.calls[rpcIDKey(id)] = cancel
+}
+
+// untrackCall forgets a finished request and releases its context
+func (s *mcpServer) untrackCall(id json.RawMessage) {
+	s.callsMu.Lock()
+	defer s.callsMu.Unlock()
+	if cancel, ok := s.calls[rpcIDKey(id)]; ok {
+		cancel()
+		delete(s.calls, rpcIDKey(id))
+	}
+}
+
+// cancelCall stops a request in progress; an unknown or finished one is ignored
+func (s *mcpServer) cancelCall(id json.RawMessage) {
+	s.callsMu.Lock()
+	defer s.callsMu.Unlock()
+	if cancel, ok := s.calls[rpcIDKey(id)]; ok {
+		cancel()
+	}
+}
+
+// write sends one message
+func (s *mcpServer) write(resp rpcResponse) {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		data, _ = json.Marshal(rpcResponse{JSONRPC: "2.0", ID: resp.ID, Error: &rpcError{rpcParseError, err.Error()}})
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, _ = s.out.Write(append(data, '\n'))
+}
+
+// handle dispatches one request
+func (s *mcpServer) handle(ctx context.Context, req rpcRequest) (any, *rpcError) {
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		_ = json.Unmarshal(req.Params, &params)
+		version := params.ProtocolVersion
+		if version == "" || version > mcpProtocolVersion {
+			version = mcpProtocolVersion
+		}
+		return map[string]any{
+			"protocolVersion": version,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]any{"name": "bjarne", "version": Version},
+		}, nil
+	case "ping":
+		return map[string]any{}, nil
+	case "tools/list":
+		return map[string]any{"tools": mcpTools}, nil
+	case "tools/call":
+		var params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &rpcError{rpcInvalidParams, "invalid params: " + err.Error()}
+		}
+		if len(params.Arguments) == 0 {
+			params.Arguments = json.RawMessage("{}")
+		}
+		return s.callTool(ctx, params.Name, params.Arguments)
+	default:
+		return nil, &rpcError{rpcMethodNotFound, "method not found: " + req.Method}
+	}
+}
+
+// callTool runs a tool; failures of the tool itself are results with isError, not RPC errors
+func (s *mcpServer) callTool(ctx context.Context, name string, args json.RawMessage) (any, *rpcError) {
+	switch name {
+	case "validate_cpp":
+		var req validateRequest
+		if err := json.Unmarshal(args, &req); err != nil {
+			return nil, &rpcError{rpcInvalidParams, "invalid arguments: " + err.Error()}
+		}
+		resp, err := s.engine.validate(ctx, req)
+		if err != nil {
+			return toolError(err), nil
+		}
+		return mcpToolResult{Content: []mcpContent{{"text", formatGatesText(resp.Passed, resp.Gates)}}, StructuredContent: resp}, nil
+
+	case "generate_validated_cpp":
+		var req generateRequest
+		if err := json.Unmarshal(args, &req); err != nil {
+			return nil, &rpcError{rpcInvalidParams, "invalid arguments: " + err.Error()}
+		}
+		resp, err := s.engine.runGenerate(ctx, req)
+		if err != nil {
+			return toolError(err), nil
+		}
+		return mcpToolResult{Content: []mcpContent{{"text", formatGeneratedText(resp)}}, StructuredContent: resp}, nil
+
+	case "search_workspace":
+		var req struct {
+			Query string `json:"query"`
+			Limit int    `json:"limit"`
+		}
+		if err := json.Unmarshal(args, &req); err != nil {
+			return nil, &rpcError{rpcInvalidParams, "invalid arguments: " + err.Error()}
+		}
+		if req.Limit <= 0 || req.Limit > 50 {
+			req.Limit = 10
+		}
+		hits, err := s.searchWorkspace(ctx, req.Query, req.Limit)
+		if err != nil {
+			return toolError(err), nil
+		}
+		return mcpToolResult{Content: []mcpContent{{"text", formatHitsText(hits)}}, StructuredContent: map[string]any{"results": hits}}, nil
+
+	default:
+		return nil, &rpcError{rpcInvalidParams, "unknown tool: " + name}
+	}
+}
+
+// toolError reports a failed tool call to the calling agent
+func toolError(err error) mcpToolResult {
+	return mcpToolResult{Content: []mcpContent{{"text", "Error: " + err.Error()}}, IsError: true}
+}
+
+// searchWorkspace uses the vector index when embeddings exist, otherwise the symbol index
+func (s *mcpServer) searchWorkspace(ctx context.Context, query string, limit int) ([]workspaceHit, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, errors.New("query is required")
+	}
+
+	if s.vectors != nil {
+		chunks, err := s.vectors.SearchSimilar(ctx, query, limit)
+		if err == nil {
+			hits := make([]workspaceHit, 0, len(chunks))
+			for _, c := range chunks {
+				path, _ := s.vectors.GetFilePath(ctx, c.FileID)
+				hits = append(hits, workspaceHit{File: path, Line: c.StartLine, Kind: string(c.Type), Name: c.Name, Snippet: firstLines(c.Content, 20)})
+			}
+			return hits, nil
+		}
+	}
+
+	if s.workspace == nil {
+		return nil, errors.New("workspace is not indexed; run /init in bjarne from the project root")
+	}
+	return searchSymbols(s.workspace, query, limit), nil
+}
+
+// searchSymbols ranks indexed functions, classes and structs by query keywords in their names
+func searchSymbols(idx *WorkspaceIndex, query string, limit int) []workspaceHit {
+	keywords := extractKeywords(query)
+	type scored struct {
+		hit   workspaceHit
+		score int
+	}
+	var matches []scored
+	consider := func(hit workspaceHit, text string) {
+		text = strings.ToLower(text)
+		score := 0
+		for _, kw := range keywords {
+			if strings.Contains(text, kw) {
+				score++
+			}
+		}
+		if score > 0 {
+			matches = append(matches, scored{hit, score})
+		}
+	}
+
+	for path, file := range idx.Files {
+		for _, c := range file.Classes {
+			consider(workspaceHit{File: path, Line: c.Line, Kind: "class", Name: c.Name}, c.Name+" "+strings.Join(c.Methods, " "))
+		}
+		for _, st := range file.Structs {
+			consider(workspaceHit{File: path, Line: st.Line, Kind: "struct", Name: st.Name}, st.Name+" "+strings.Join(st.Members, " "))
+		}
+		for _, f := range file.Functions {
+			consider(workspaceHit{File: path, Line: f.Line, Kind: "function", Name: f.Name, Snippet: f.Signature}, f.Signature)
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		if matches[i].hit.File != matches[j].hit.File {
+			return matches[i].hit.File < matches[j].hit.File
+		}
+		return matches[i].hit.Line < matches[j].hit.Line
+	})
+	hits := make([]workspaceHit, 0, min(limit, len(matches)))
+	for i := 0; i < len(matches) && i < limit; i++ {
+		hits = append(hits, matches[i].hit)
+	}
+	return hits
+}
+
+// formatGatesText summarizes gate results for an agent
+func formatGatesText(passed bool, gates []apiGate) string {
+	var sb strings.Builder
+	if passed {
+		fmt.Fprintf(&sb, "PASSED all %d validation gates.\n", len(gates))
+	} else {
+		sb.WriteString("FAILED validation.\n")
+	}
+	for _, g := range gates {
+		status := "pass"
+		if !g.Passed {
+			status = "FAIL"
+		}
+		fmt.Fprintf(&sb, "- %s: %s (%dms)\n", g.Stage, status, g.DurationMs)
+		if !g.Passed && g.Error != "" {
+			sb.WriteString(indentLines(strings.TrimSpace(g.Error), "    ") + "\n")
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// formatGeneratedText presents generated code and its validation for an agent
+func formatGeneratedText(resp generateResponse) string {
+	var sb strings.Builder
+	sb.WriteString(formatGatesText(resp.Passed, resp.Gates))
+	fmt.Fprintf(&sb, "\nModel %s, %d iteration(s).", resp.Model, resp.Iterations)
+	if resp.Summary != "" {
+		fmt.Fprintf(&sb, " Review (%d%%): %s", resp.Confidence, resp.Summary)
+	}
+	sb.WriteString("\n")
+	if len(resp.Files) > 0 {
+		for _, f := range resp.Files {
+			fmt.Fprintf(&sb, "\n// FILE: %s\n```cpp\n%s\n```\n", f.Filename, strings.TrimRight(f.Content, "\n"))
+		}
+	} else {
+		fmt.Fprintf(&sb, "\n```cpp\n%s\n```\n", strings.TrimRight(resp.Code, "\n"))
+	}
+	return sb.String()
+}
+
+// formatHitsText lists search results for an agent
+func formatHitsText(hits []workspaceHit) string {
+	if len(hits) == 0 {
+		return "No matches."
+	}
+	var sb strings.Builder
+	for _, h := range hits {
+		fmt.Fprintf(&sb, "%s:%d %s %s\n", h.File, h.Line, h.Kind, h.Name)
+		if h.Snippet != "" {
+			sb.WriteString(indentLines(h.Snippet, "    ") + "\n")
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// indentLines prefixes every line of text
+func indentLines(text, prefix string) string {
+	return prefix + strings.ReplaceAll(text, "\n", "\n"+prefix)
+}
+
+// firstLines returns up to n lines from the start of s
+func firstLines(s string, n int) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) > n {
+		lines = append(lines[:n], "...")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMCPServe(t *testing.T) {
+	idx := &WorkspaceIndex{Files: map[string]*FileIndex{
+		"src/buffer.h": {
+			Classes:   []ClassInfo{{Name: "RingBuffer", Line: 12, Methods: []string{"push", "pop"}}},
+			Functions: []FuncInfo{{Name: "resizeBuffer", Signature: "void resizeBuffer(RingBuffer& b, size_t n)", Line: 40}},
+		},
+		"src/net.cpp": {Functions: []FuncInfo{{Name: "connect", Signature: "int connect(const char* host)", Line: 5}}},
+	}}
+	var out bytes.Buffer
+	s := &mcpServer{engine: &server{cfg: DefaultConfig()}, workspace: idx, out: &out}
+
+	in := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"search_workspace","arguments":{"query":"ring buffer resize"}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"validate_cpp","arguments":{}}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"resources/list"}`,
+		`{"jsonrpc":"2.0","id":6,"method":"tools/call","params":{"name":"rm_rf"}}`,
+		`not json`,
+	}, "\n")
+	if err := s.serve(context.Background(), strings.NewReader(in)); err != nil {
+		t.Fatalf("serve() error = %v", err)
+	}
+
+	responses := make(map[string]map[string]any)
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var resp map[string]any
+		if err := json.Unmarshal([]byte(line), &resp); err != nil {
+			t.Fatalf("response %q is not JSON: %v", line, err)
+		}
+		id, _ := json.Marshal(resp["id"])
+		responses[string(id)] = resp
+	}
+	if len(responses) != 7 {
+		t.Fatalf("got %d responses, want 7 (the notification gets none):\n%s", len(responses), out.String())
+	}
+
+	result := func(id string) map[string]any {
+		r, _ := responses[id]["result"].(map[string]any)
+		return r
+	}
+	errorCode := func(id string) float64 {
+		e, _ := responses[id]["error"].(map[string]any)
+		code, _ := e["code"].(float64)
+		return code
+	}
+
+	if v := result("1")["protocolVersion"]; v != "2025-03-26" {
+		t.Errorf("initialize protocolVersion = %v, want the client's version", v)
+	}
+	if tools, _ := result("2")["tools"].([]any); len(tools) != 3 {
+		t.Errorf("tools/list returned %d tools, want 3", len(tools))
+	}
+
+	search, _ := json.Marshal(result("3"))
+	if !strings.Contains(string(search), "src/buffer.h:12 class RingBuffer") || strings.Contains(string(search), "connect") {
+		t.Errorf("search_workspace result = %s", search)
+	}
+	if result("4")["isError"] != true {
+		t.Errorf("validate_cpp without code should be a tool error: %v", responses["4"])
+	}
+	if errorCode("5") != rpcMethodNotFound {
+		t.Errorf("unknown method error = %v", responses["5"])
+	}
+	if errorCode("6") != rpcInvalidParams {
+		t.Errorf("unknown tool error = %v", responses["6"])
+	}
+	if errorCode("null") != rpcParseError {
+		t.Errorf("parse error = %v", responses["null"])
+	}
+}
+
+func TestSearchSymbolsRanking(t *testing.T) {
+	idx := &WorkspaceIndex{Files: map[string]*FileIndex{
+		"a.h": {Functions: []FuncInfo{
+			{Name: "parseConfig", Signature: "Config parseConfig(const std::string& path)", Line: 3},
+			{Name: "parseConfigFile", Signature: "Config parseConfigFile(FILE* file, const std::string& path)", Line: 9},
+		}},
+	}}
+	hits := searchSymbols(idx, "parse config file", 1)
+	if len(hits) != 1 || hits[0].Name != "parseConfigFile" {
+		t.Errorf("searchSymbols() = %+v, want parseConfigFile first", hits)
+	}
+}
+
+func TestReadMCPMessage(t *testing.T) {
+	r := bufio.NewReaderSize(strings.NewReader("{\"id\":1}\n"+strings.Repeat("x", 100)+"\n{\"id\":2}\n"+strings.Repeat("y", 100)), 16)
+	want := []struct {
+		line string
+		err  error
+	}{
+		{"{\"id\":1}\n", nil},
+		{"", errMCPMessageTooLong},
+		{"{\"id\":2}\n", nil},
+		{"", errMCPMessageTooLong},
+		{"", io.EOF},
+	}
+	for i, w := range want {
+		line, err := readMCPMessage(r, 64)
+		if string(line) != w.line || !errors.Is(err, w.err) {
+			t.Errorf("message %d = %q, %v; want %q, %v", i, line, err, w.line, w.err)
+		}
+	}
+}
+
+func TestMCPCancel(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs /bin/sh")
+	}
+	// Stands in for the container runtime: a validation stage that never finishes on its own
+	bin := filepath.Join(t.TempDir(), "podman")
+	if err := os.WriteFile(bin, []byte("#!/bin/sh\n[ \"$1\" = run ] && exec sleep 30\nexit 0\n"), 0700); err != nil { //nolint:gosec // the test runs it
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	s := &mcpServer{engine: &server{cfg: DefaultConfig(), container: &ContainerRuntime{binary: bin, imageName: "validator"}}, out: &out}
+
+	in, w := io.Pipe()
+	done := make(chan error)
+	go func() { done <- s.serve(context.Background(), in) }()
+	_, _ = io.WriteString(w, `{"jsonrpc":"2.0","id":"slow","method":"tools/call","params":{"name":"validate_cpp","arguments":{"code":"int main() {}"}}}`+"\n")
+	time.Sleep(200 * time.Millisecond)
+	_, _ = io.WriteString(w, `{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":"slow","reason":"user"}}`+"\n")
+	_, _ = io.WriteString(w, `{"jsonrpc":"2.0","id":2,"method":"ping"}`+"\n")
+	_ = w.Close()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("serve() error = %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("the cancelled tool call kept running")
+	}
+	if strings.Contains(out.String(), `"slow"`) || !strings.Contains(out.String(), `"id":2`) {
+		t.Errorf("responses = %s; want the ping answered and the cancelled call not", out.String())
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// modelCheckMarker starts the model-check gate's report of the error GenMC found
+const modelCheckMarker = "bjarne: model-check:"
+
+// maxModelCheckUnroll bounds modelCheck.unroll
+const maxModelCheckUnroll = 50
+
+// modelCheckHarnessFile is the generated litmus harness's name in the mounted source directory
+const modelCheckHarnessFile = "bjarne_litmus.cpp"
+
+// modelCheckTimeout is how long GenMC may explore before the check is reported as inconclusive
+const modelCheckTimeout = 600
+
+// maxLitmusThreads bounds the threads of the harness: every one multiplies the interleavings
+const maxLitmusThreads = 4
+
+// weakOrderPattern matches a memory order weaker than seq_cst, where interleavings that TSAN and
+// a real run will rarely show become possible
+var weakOrderPattern = regexp.MustCompile(`\bmemory_order(?:_|::)(?:relaxed|consume|acquire|release|acq_rel)\b`)
+
+// litmusFunctionPattern matches a void function without parameters, capturing its name
+var litmusFunctionPattern = regexp.MustCompile(`(?m)^[ \t]*(?:(?:static|inline)[ \t]+)*void[ \t]+(\w+)[ \t]*\([ \t]*(?:void)?[ \t]*\)[ \t]*(?:noexcept[ \t]*)?\{`)
+
+// litmusLaunchPattern matches a thread started on a function without arguments, capturing the function
+var litmusLaunchPattern = regexp.MustCompile(`\bstd::j?thread\b(?:[ \t]+\w+)?[ \t]*[({][ \t]*&?(\w+)[ \t]*[)}]|\bemplace_back\([ \t]*&?(\w+)[ \t]*\)`)
+
+var modelCheckFailurePattern = regexp.MustCompile(regexp.QuoteMeta(modelCheckMarker) + ` (.+?) with threads (.+)$`)
+
+// SetModelCheckSettings configures the model-check gate
+func (c *ContainerRuntime) SetModelCheckSettings(settings ModelCheckSettings) {
+	c.modelCheck = settings
+}
+
+// modelCheckEnabled reports whether the model-check gate runs: modelCheck.enabled, or asked for with --gates model-check
+func (c *ContainerRuntime) modelCheckEnabled() bool {
+	return (c.modelCheck.Enabled || slices.Contains(c.gates.Only, "model-check")) && c.gates.Enabled("model-check")
+}
+
+// modelCheckUnroll is modelCheck.unroll, defaulting when unset
+func (c *ContainerRuntime) modelCheckUnroll() int {
+	if c.modelCheck.Unroll > 0 {
+		return c.modelCheck.Unroll
+	}
+	return DefaultSettings().ModelCheck.Unroll
+}
+
+// usesWeakAtomics reports whether code uses atomics with an ordering weaker than seq_cst
+func usesWeakAtomics(code string) bool {
+	return weakOrderPattern.MatchString(code)
+}
+
+// litmusThreads picks the functions the harness runs concurrently: the parameterless functions the
+// code starts threads on, or else every parameterless function that uses a weak ordering.
+// A single function runs in two threads, racing with itself
+func litmusThreads(files []CodeFile) []string {
+	bodies := make(map[string]string)
+	var names []string
+	for _, f := range files {
+		for _, m := range litmusFunctionPattern.FindAllStringSubmatchIndex(f.Content, -1) {
+			name := f.Content[m[2]:m[3]]
+			if name == "main" || bodies[name] != "" {
+				continue
+			}
+			bodies[name] = f.Content[m[1]-1 : blockEnd(f.Content, m[1]-1)]
+			names = append(names, name)
+		}
+	}
+
+	var threads []string
+	for _, f := range files {
+		for _, m := range litmusLaunchPattern.FindAllStringSubmatch(f.Content, -1) {
+			name := m[1] + m[2]
+			if bodies[name] != "" && len(threads) < maxLitmusThreads {
+				threads = append(threads, name)
+			}
+		}
+	}
+	if len(threads) == 0 {
+		for _, name := range names {
+			if usesWeakAtomics(bodies[name]) && len(threads) < maxLitmusThreads {
+				threads = append(threads, name)
+			}
+		}
+	}
+	if len(threads) == 1 {
+		threads = append(threads, threads[0])
+	}
+	return threads
+}
+
+// litmusHarness includes the sources with their main() renamed and runs each thread function in its
+// own pthread, which GenMC models; the program's own main and its std::threads are not run
+func litmusHarness(sources, threads []string) string {
+	var sb strings.Builder
+	sb.WriteString("// Generated litmus harness for the model-check gate\n#define main bjarne_program_main\n")
+	for _, src := range sources {
+		fmt.Fprintf(&sb, "#include %q\n", src)
+	}
+	sb.WriteString("#undef main\n#include <pthread.h>\n\n")
+	for i, name := range threads {
+		fmt.Fprintf(&sb, "static void* bjarne_litmus_%d(void*) {\n    %s();\n    return nullptr;\n}\n\n", i, name)
+	}
+	fmt.Fprintf(&sb, "int main() {\n    pthread_t threads[%d];\n", len(threads))
+	for i := range threads {
+		fmt.Fprintf(&sb, "    pthread_create(&threads[%d], nullptr, bjarne_litmus_%d, nullptr);\n", i, i)
+	}
+	fmt.Fprintf(&sb, "    for (pthread_t t : threads) {\n        pthread_join(t, nullptr);\n    }\n    return 0;\n}\n")
+	return sb.String()
+}
+
+// modelCheckGate explores every interleaving the C++ memory model allows of the code's thread
+// functions with GenMC, for code using weak atomic orderings; it reports false when the gate is off
+// or the code has no weak orderings
+func (c *ContainerRuntime) modelCheckGate(tmpDir string, files []CodeFile, runStage func(stage string, command ...string) ValidationResult) (ValidationResult, bool, error) {
+	weak := false
+	var sources []string
+	for _, f := range files {
+		weak = weak || usesWeakAtomics(f.Content)
+		if isSourceFile(f.Filename) {
+			sources = append(sources, f.Filename)
+		}
+	}
+	if !c.modelCheckEnabled() || !weak {
+		return ValidationResult{}, false, nil
+	}
+	threads := litmusThreads(files)
+	if len(threads) == 0 {
+		return ValidationResult{Stage: "model-check", Success: true, Skipped: true,
+			Output: "skipped: no parameterless thread functions to build a litmus harness from"}, true, nil
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, modelCheckHarnessFile), []byte(litmusHarness(sources, threads)), 0600); err != nil {
+		return ValidationResult{}, false, fmt.Errorf("failed to write litmus harness: %w", err)
+	}
+	result := runStage("model-check", "sh", "-c", c.modelCheckCommand(threads))
+	if strings.Contains(result.Output, "genmc not installed") {
+		result.Skipped = true
+	}
+	return result, true, nil
+}
+
+// modelCheckCommand runs GenMC over the harness under RC11, with loops unrolled modelCheck.unroll times
+// An error stops the gate with modelCheckMarker, the error and GenMC's counterexample; running out of
+// time is reported as inconclusive without failing
+func (c *ContainerRuntime) modelCheckCommand(threads []string) string {
+	genmc := fmt.Sprintf("timeout %d genmc -rc11 -unroll=%d -- %s -I/src /src/%s",
+		modelCheckTimeout, c.modelCheckUnroll(), c.stdFlag(), modelCheckHarnessFile)
+	return fmt.Sprintf("command -v genmc >/dev/null 2>&1 || { echo 'genmc not installed, skipping'; exit 0; }; "+
+		"%s > /tmp/genmc.log 2>&1; s=$?; "+
+		"if [ $s = 124 ]; then echo 'inconclusive: GenMC did not finish exploring within %ds; lower modelCheck.unroll'; exit 0; fi; "+
+		"if [ $s != 0 ]; then e=$(sed -n 's/^Error detected: //p' /tmp/genmc.log | head -n 1); "+
+		"echo \"%s ${e:-GenMC failed (exit $s)} with threads %s\" >&2; cat /tmp/genmc.log >&2; exit 1; fi; cat /tmp/genmc.log",
+		genmc, modelCheckTimeout, modelCheckMarker, strings.Join(threads, ", "))
+}
+
+// ModelCheckFailure is the error GenMC found and the thread functions it ran
+type ModelCheckFailure struct {
+	Error   string
+	Threads string
+}
+
+// parseModelCheckFailure finds the model-check gate's error in its output, or returns nil
+func parseModelCheckFailure(output string) *ModelCheckFailure {
+	for _, line := range strings.Split(output, "\n") {
+		if m := modelCheckFailurePattern.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+			return &ModelCheckFailure{Error: strings.TrimSuffix(m[1], "!"), Threads: m[2]}
+		}
+	}
+	return nil
+}
+
+// promptText explains the failure for the fix prompt
+func (f *ModelCheckFailure) promptText() string {
+	return fmt.Sprintf("Found by model checking: GenMC ran %s concurrently and explored every interleaving and reordering the C++ memory model (RC11) allows. "+
+		"One of them ends in: %s. The execution graph that follows shows the reads and writes involved; real hardware may take it only rarely, "+
+		"so tests and TSAN can miss it. Strengthen the memory orderings the graph shows (release on the publishing store, acquire on the load that reads it), "+
+		"or synchronize the non-atomic data the race is on.",
+		f.Threads, f.Error)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const litmusSource = `#include <atomic>
+#include <cassert>
+#include <thread>
+
+int data = 0;
+std::atomic<bool> ready{false};
+
+void producer() {
+    data = 42;
+    ready.store(true, std::memory_order_relaxed);
+}
+
+void consumer() {
+    while (!ready.load(std::memory_order_relaxed)) {
+    }
+    assert(data == 42);
+}
+
+int main() {
+    std::thread a(producer);
+    std::thread b{&consumer};
+    a.join();
+    b.join();
+}
+`
+
+func TestModelCheckEnabled(t *testing.T) {
+	tests := []struct {
+		name       string
+		modelCheck ModelCheckSettings
+		gates      GateSelection
+		want       bool
+	}{
+		{"off by default", ModelCheckSettings{}, GateSelection{}, false},
+		{"enabled", ModelCheckSettings{Enabled: true}, GateSelection{}, true},
+		{"asked for with --gates", ModelCheckSettings{}, GateSelection{Only: []string{"tsan", "model-check"}}, true},
+		{"skipped", ModelCheckSettings{Enabled: true}, GateSelection{Skip: []string{"model-check"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &ContainerRuntime{modelCheck: tt.modelCheck, gates: tt.gates}
+			if got := c.modelCheckEnabled(); got != tt.want {
+				t.Errorf("modelCheckEnabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLitmusThreads(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []CodeFile
+		want  []string
+	}{
+		{
+			name:  "thread launches",
+			files: []CodeFile{{Filename: "main.cpp", Content: litmusSource}},
+			want:  []string{"producer", "consumer"},
+		},
+		{
+			name: "emplace_back across files",
+			files: []CodeFile{
+				{Filename: "queue.cpp", Content: "static void push() {\n    head.fetch_add(1, std::memory_order_release);\n}\n"},
+				{Filename: "main.cpp", Content: "int main() {\n    std::vector<std::thread> ts;\n    ts.emplace_back(push);\n}\n"},
+			},
+			want: []string{"push", "push"},
+		},
+		{
+			name: "weak atomics without launches",
+			files: []CodeFile{{Filename: "main.cpp", Content: "void reader(void) {\n    x.load(std::memory_order_acquire);\n}\n" +
+				"void helper() {\n    y = 1;\n}\n" +
+				"void writer() noexcept {\n    x.store(1, std::memory_order::release);\n}\n"}},
+			want: []string{"reader", "writer"},
+		},
+		{
+			name:  "lambdas only",
+			files: []CodeFile{{Filename: "main.cpp", Content: "int main() {\n    std::thread t([] { x.store(1, std::memory_order_relaxed); });\n}\n"}},
+			want:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := litmusThreads(tt.files); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("litmusThreads() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLitmusHarness(t *testing.T) {
+	got := litmusHarness([]string{"queue.cpp", "main.cpp"}, []string{"producer", "consumer"})
+	for _, want := range []string{
+		"#define main bjarne_program_main\n#include \"queue.cpp\"\n#include \"main.cpp\"\n#undef main",
+		"static void* bjarne_litmus_1(void*) {\n    consumer();",
+		"pthread_t threads[2];",
+		"pthread_create(&threads[0], nullptr, bjarne_litmus_0, nullptr);",
+		"pthread_join(t, nullptr);",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("litmusHarness() = %q, missing %q", got, want)
+		}
+	}
+}
+
+func TestModelCheckGate(t *testing.T) {
+	c := &ContainerRuntime{modelCheck: ModelCheckSettings{Enabled: true, Unroll: 5}}
+	tmpDir := t.TempDir()
+	var command []string
+	run := func(stage string, cmd ...string) ValidationResult {
+		command = cmd
+		return ValidationResult{Stage: stage, Success: true}
+	}
+	files := []CodeFile{{Filename: "main.cpp", Content: litmusSource}, {Filename: "queue.h", Content: "#pragma once\n"}}
+	result, ok, err := c.modelCheckGate(tmpDir, files, run)
+	if err != nil || !ok || result.Stage != "model-check" {
+		t.Fatalf("modelCheckGate() = %+v, %v, %v; want the model-check stage", result, ok, err)
+	}
+	data, err := os.ReadFile(filepath.Join(tmpDir, modelCheckHarnessFile))
+	if err != nil || !strings.Contains(string(data), "#include \"main.cpp\"\n#undef main") || strings.Contains(string(data), "queue.h") {
+		t.Errorf("litmus harness = %q, %v; want main.cpp included", data, err)
+	}
+	script := strings.Join(command, " ")
+	for _, want := range []string{"genmc -rc11 -unroll=5 --", "-I/src /src/" + modelCheckHarnessFile, "with threads producer, consumer", "genmc not installed"} {
+		if !strings.Contains(script, want) {
+			t.Errorf("model-check command = %q, missing %q", script, want)
+		}
+	}
+	if got, want := c.stageTimeout("model-check"), 120+modelCheckTimeout; got != want {
+		t.Errorf("stageTimeout(model-check) = %d, want %d", got, want)
+	}
+
+	if _, ok, _ := c.modelCheckGate(tmpDir, []CodeFile{{Filename: "main.cpp", Content: "std::atomic<int> x; void f() { x++; }"}}, run); ok {
+		t.Error("modelCheckGate() ran on seq_cst atomics")
+	}
+	result, ok, _ = c.modelCheckGate(tmpDir, []CodeFile{{Filename: "main.cpp", Content: "int main() { x.load(std::memory_order_relaxed); }"}}, run)
+	if !ok || !result.Skipped {
+		t.Errorf("modelCheckGate() = %+v, %v; want skipped without thread functions", result, ok)
+	}
+	c.modelCheck.Enabled = false
+	if _, ok, _ := c.modelCheckGate(tmpDir, files, run); ok {
+		t.Error("modelCheckGate() ran while off")
+	}
+}
+
+func TestFormatErrorForLLMModelCheck(t *testing.T) {
+	report := modelCheckMarker + " Safety violation! with threads producer, consumer\n" +
+		"Error detected: Safety violation!\n" +
+		"Event (2, 3) in graph:\n" +
+		"<-1, 0> main:\n"
+	got := FormatErrorForLLM("model-check", report, nil)
+	for _, want := range []string{"[model-check] Found by model checking", "producer, consumer", "ends in: Safety violation.", "acquire"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("FormatErrorForLLM() = %q, missing %q", got, want)
+		}
+	}
+	if parseModelCheckFailure("Error detected: Safety violation!") != nil {
+		t.Error("parseModelCheckFailure() found a failure without the marker")
+	}
+}
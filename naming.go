@@ -0,0 +1,596 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// NamingStyle identifies an identifier casing convention
+type NamingStyle string
+
+const (
+	StyleUnknown NamingStyle = ""
+	StyleSnake   NamingStyle = "snake_case"
+	StyleCamel   NamingStyle = "camelCase"
+	StylePascal  NamingStyle = "PascalCase"
+)
+
+// Naming enforcement modes (settings: naming.mode)
+const (
+	NamingModeOff  = "off"
+	NamingModeWarn = "warn"
+	NamingModeFix  = "fix"
+)
+
+// Minimum samples and share needed before a convention counts as dominant
+const (
+	namingMinSamples = 3
+	namingMinShare   = 0.6
+)
+
+// NamingConventions describes the dominant identifier style of a codebase
+type NamingConventions struct {
+	Functions    NamingStyle // Free functions and methods
+	Types        NamingStyle // Classes and structs
+	Members      NamingStyle // Data members (after stripping prefix/suffix)
+	MemberPrefix string      // e.g. "m_" or "_"
+	MemberSuffix string      // e.g. "_"
+}
+
+// NamingViolation is a generated identifier that doesn't match the project style
+type NamingViolation struct {
+	Kind      string // "function", "type", "member"
+	Name      string
+	Suggested string
+}
+
+// memberAffixes are the member prefixes/suffixes we recognise, most specific first
+var memberAffixes = []struct {
+	prefix, suffix string
+}{
+	{"m_", ""},
+	{"_", ""},
+	{"", "_"},
+}
+
+// classifyName returns the casing style of an identifier
+// Single lowercase words are ambiguous (valid snake_case and camelCase) and return StyleUnknown
+func classifyName(name string) NamingStyle {
+	name = strings.Trim(name, "_")
+	if name == "" {
+		return StyleUnknown
+	}
+
+	hasUpper, hasLower := false, false
+	for _, r := range name {
+		if unicode.IsUpper(r) {
+			hasUpper = true
+		} else if unicode.IsLower(r) {
+			hasLower = true
+		}
+	}
+	first := rune(name[0])
+
+	switch {
+	case !hasLower:
+		return StyleUnknown // ALL_CAPS constants/macros
+	case strings.Contains(name, "_") && !hasUpper:
+		return StyleSnake
+	case strings.Contains(name, "_"):
+		return StyleUnknown // Mixed styles like Foo_Bar
+	case unicode.IsUpper(first):
+		return StylePascal
+	case hasUpper:
+		return StyleCamel
+	}
+	return StyleUnknown
+}
+
+// matchesStyle reports whether name is compatible with style
+func matchesStyle(name string, style NamingStyle) bool {
+	if style == StyleUnknown {
+		return true
+	}
+	got := classifyName(name)
+	if got == StyleUnknown {
+		// Single lowercase words fit both snake_case and camelCase
+		trimmed := strings.Trim(name, "_")
+		isLowerWord := trimmed != "" && strings.ToLower(trimmed) == trimmed && !strings.Contains(trimmed, "_")
+		return style != StylePascal && isLowerWord || strings.ToUpper(trimmed) == trimmed
+	}
+	return got == style
+}
+
+// splitWords breaks an identifier into lowercase words
+func splitWords(name string) []string {
+	var words []string
+	var current []rune
+	runes := []rune(strings.Trim(name, "_"))
+
+	flush := func() {
+		if len(current) > 0 {
+			words = append(words, strings.ToLower(string(current)))
+			current = nil
+		}
+	}
+
+	for i, r := range runes {
+		switch {
+		case r == '_':
+			flush()
+			continue
+		case unicode.IsUpper(r) && i > 0:
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			// Break on fooBar and on the last capital of an acronym (HTTPServer -> http, server)
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				flush()
+			}
+		}
+		current = append(current, r)
+	}
+	flush()
+	return words
+}
+
+// convertName rewrites an identifier into the given style
+func convertName(name string, style NamingStyle) string {
+	words := splitWords(name)
+	if len(words) == 0 {
+		return name
+	}
+
+	switch style {
+	case StyleSnake:
+		return strings.Join(words, "_")
+	case StyleCamel, StylePascal:
+		var sb strings.Builder
+		for i, w := range words {
+			if i == 0 && style == StyleCamel {
+				sb.WriteString(w)
+				continue
+			}
+			sb.WriteString(strings.ToUpper(w[:1]) + w[1:])
+		}
+		return sb.String()
+	}
+	return name
+}
+
+// stripMemberAffix removes a recognised member prefix/suffix, returning the bare name and affix
+func stripMemberAffix(name string) (string, string, string) {
+	for _, a := range memberAffixes {
+		if a.prefix != "" && strings.HasPrefix(name, a.prefix) && len(name) > len(a.prefix) {
+			return strings.TrimPrefix(name, a.prefix), a.prefix, ""
+		}
+		if a.suffix != "" && strings.HasSuffix(name, a.suffix) && len(name) > len(a.suffix) {
+			return strings.TrimSuffix(name, a.suffix), "", a.suffix
+		}
+	}
+	// mFoo style (Hungarian-ish m prefix)
+	if len(name) > 1 && name[0] == 'm' && unicode.IsUpper(rune(name[1])) {
+		return name[1:], "m", ""
+	}
+	return name, "", ""
+}
+
+// dominantStyle returns the most common style if it clears the sample and share thresholds
+func dominantStyle(names []string) NamingStyle {
+	counts := make(map[NamingStyle]int)
+	total := 0
+	for _, n := range names {
+		if s := classifyName(n); s != StyleUnknown {
+			counts[s]++
+			total++
+		}
+	}
+	if total < namingMinSamples {
+		return StyleUnknown
+	}
+
+	best, bestCount := StyleUnknown, 0
+	for _, s := range []NamingStyle{StyleSnake, StyleCamel, StylePascal} {
+		if counts[s] > bestCount {
+			best, bestCount = s, counts[s]
+		}
+	}
+	if float64(bestCount)/float64(total) < namingMinShare {
+		return StyleUnknown
+	}
+	return best
+}
+
+// DetectNamingConventions derives the dominant naming conventions from the index
+func (idx *WorkspaceIndex) DetectNamingConventions() *NamingConventions {
+	if idx == nil || len(idx.Files) == 0 {
+		return nil
+	}
+
+	var funcs, types, members []string
+	for _, file := range idx.Files {
+		for _, f := range file.Functions {
+			if f.Name != "main" {
+				funcs = append(funcs, f.Name)
+			}
+		}
+		for _, c := range file.Classes {
+			types = append(types, c.Name)
+			members = append(members, c.Members...)
+		}
+		for _, s := range file.Structs {
+			types = append(types, s.Name)
+			members = append(members, s.Members...)
+		}
+	}
+
+	conv := &NamingConventions{
+		Functions: dominantStyle(funcs),
+		Types:     dominantStyle(types),
+	}
+
+	// Member affix: count each affix and keep it if it's dominant
+	affixCounts := make(map[[2]string]int)
+	var bare []string
+	for _, m := range members {
+		name, prefix, suffix := stripMemberAffix(m)
+		affixCounts[[2]string{prefix, suffix}]++
+		bare = append(bare, name)
+	}
+	if len(members) >= namingMinSamples {
+		for affix, count := range affixCounts {
+			if (affix[0] != "" || affix[1] != "") && float64(count)/float64(len(members)) >= namingMinShare {
+				conv.MemberPrefix, conv.MemberSuffix = affix[0], affix[1]
+			}
+		}
+	}
+	conv.Members = dominantStyle(bare)
+
+	if conv.IsEmpty() {
+		return nil
+	}
+	return conv
+}
+
+// IsEmpty reports whether no convention could be detected
+func (nc *NamingConventions) IsEmpty() bool {
+	return nc == nil || (nc.Functions == StyleUnknown && nc.Types == StyleUnknown &&
+		nc.Members == StyleUnknown && nc.MemberPrefix == "" && nc.MemberSuffix == "")
+}
+
+// PromptHint describes the conventions for the generation system prompt
+func (nc *NamingConventions) PromptHint() string {
+	if nc.IsEmpty() {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## Project Naming Conventions\n")
+	sb.WriteString("Generated identifiers MUST follow the existing codebase style:\n")
+	if nc.Functions != StyleUnknown {
+		sb.WriteString(fmt.Sprintf("- Functions and methods: %s\n", nc.Functions))
+	}
+	if nc.Types != StyleUnknown {
+		sb.WriteString(fmt.Sprintf("- Classes and structs: %s\n", nc.Types))
+	}
+	if nc.Members != StyleUnknown || nc.MemberPrefix != "" || nc.MemberSuffix != "" {
+		example := nc.memberName("value_count")
+		sb.WriteString(fmt.Sprintf("- Data members: %s (e.g. %s)\n", nc.describeMembers(), example))
+	}
+	return sb.String()
+}
+
+// Summary returns a one-line description of the detected conventions
+func (nc *NamingConventions) Summary() string {
+	var parts []string
+	if nc.Functions != StyleUnknown {
+		parts = append(parts, "functions "+string(nc.Functions))
+	}
+	if nc.Types != StyleUnknown {
+		parts = append(parts, "types "+string(nc.Types))
+	}
+	if nc.Members != StyleUnknown || nc.MemberPrefix != "" || nc.MemberSuffix != "" {
+		parts = append(parts, "members "+nc.describeMembers())
+	}
+	return strings.Join(parts, "; ")
+}
+
+// describeMembers summarises the member convention
+func (nc *NamingConventions) describeMembers() string {
+	desc := string(nc.Members)
+	if desc == "" {
+		desc = "any case"
+	}
+	if nc.MemberPrefix != "" {
+		desc += fmt.Sprintf(", prefixed with %q", nc.MemberPrefix)
+	}
+	if nc.MemberSuffix != "" {
+		desc += fmt.Sprintf(", suffixed with %q", nc.MemberSuffix)
+	}
+	return desc
+}
+
+// memberName applies the member convention to a bare name
+func (nc *NamingConventions) memberName(bare string) string {
+	if nc.Members != StyleUnknown {
+		bare = convertName(bare, nc.Members)
+	}
+	if nc.MemberPrefix == "m" {
+		return "m" + convertName(bare, StylePascal)
+	}
+	return nc.MemberPrefix + bare + nc.MemberSuffix
+}
+
+// CheckNamingConventions finds identifiers defined in code that deviate from the conventions
+func CheckNamingConventions(code string, nc *NamingConventions) []NamingViolation {
+	if nc.IsEmpty() {
+		return nil
+	}
+
+	parsed := parseSourceContent(code)
+	typeNames := make(map[string]bool)
+	for _, c := range parsed.Classes {
+		typeNames[c.Name] = true
+	}
+	for _, s := range parsed.Structs {
+		typeNames[s.Name] = true
+	}
+
+	var violations []NamingViolation
+	seen := make(map[string]bool)
+	add := func(kind, name, suggested string) {
+		if seen[name] || suggested == name || suggested == "" {
+			return
+		}
+		seen[name] = true
+		violations = append(violations, NamingViolation{Kind: kind, Name: name, Suggested: suggested})
+	}
+
+	// Only functions the code defines are its own; anything else is declared or called from a library
+	for _, name := range definedFunctions(code) {
+		// Skip main, constructors and operators
+		if name == "main" || typeNames[name] || name == "operator" {
+			continue
+		}
+		if !matchesStyle(name, nc.Functions) {
+			add("function", name, convertName(name, nc.Functions))
+		}
+	}
+
+	var members []string
+	for _, c := range parsed.Classes {
+		if !matchesStyle(c.Name, nc.Types) {
+			add("type", c.Name, convertName(c.Name, nc.Types))
+		}
+		members = append(members, c.Members...)
+	}
+	for _, s := range parsed.Structs {
+		if !matchesStyle(s.Name, nc.Types) {
+			add("type", s.Name, convertName(s.Name, nc.Types))
+		}
+		members = append(members, s.Members...)
+	}
+
+	for _, m := range members {
+		if strings.ToUpper(m) == m {
+			continue // Constants
+		}
+		bare, _, _ := stripMemberAffix(m)
+		want := nc.memberName(bare)
+		if nc.MemberPrefix == "" && nc.MemberSuffix == "" {
+			// No affix convention - only check the casing
+			if matchesStyle(m, nc.Members) {
+				continue
+			}
+			want = convertName(m, nc.Members)
+		}
+		if m != want {
+			add("member", m, want)
+		}
+	}
+
+	return violations
+}
+
+// functionDefinitionPattern matches a function definition up to its body's brace, in or out of its
+// class, capturing the unqualified name
+var functionDefinitionPattern = regexp.MustCompile(`(?m)^[\t ]*[\w:*&<>,\t ]*?\b(?:\w+::)*(\w+)[\t ]*\([^;{}]*\)\s*` +
+	`(?:const\s*)?(?:noexcept\s*)?(?:override\s*)?(?:final\s*)?(?:->[^{};]+)?\{`)
+
+// definedFunctions returns the names of the functions the code defines a body for, in order; a
+// name the code only declares or calls belongs to a library and must keep its name
+func definedFunctions(code string) []string {
+	var names []string
+	for _, m := range functionDefinitionPattern.FindAllStringSubmatch(code, -1) {
+		if !isKeyword(m[1]) && !slices.Contains(names, m[1]) {
+			names = append(names, m[1])
+		}
+	}
+	return names
+}
+
+// ApplyNamingFixes renames the violating identifiers consistently in every file of the project,
+// leaving comments and string and character literals alone. Renames that would collide with an
+// existing identifier, or of names the code also uses for something it did not declare (a std::
+// function, a standard library member), are skipped
+func ApplyNamingFixes(files []CodeFile, violations []NamingViolation) ([]CodeFile, []NamingViolation) {
+	fixed := append([]CodeFile(nil), files...)
+	scopes := declaredScopes(fixed)
+	var applied []NamingViolation
+	for _, v := range violations {
+		if usesIdentifier(fixed, v.Suggested) || usesForeignName(fixed, v.Name, scopes) {
+			continue
+		}
+		for i := range fixed {
+			fixed[i].Content = renameIdentifier(fixed[i].Content, v.Name, v.Suggested)
+		}
+		applied = append(applied, v)
+	}
+	return fixed, applied
+}
+
+// namespacePattern matches a named namespace, capturing its name
+var namespacePattern = regexp.MustCompile(`\bnamespace\s+(\w+)`)
+
+// stdMemberNames are standard library members the code may call on library objects with the
+// same name as one of its own; renaming would also rename those calls
+var stdMemberNames = map[string]bool{
+	"push_back": true, "emplace_back": true, "pop_back": true, "push_front": true, "emplace_front": true,
+	"pop_front": true, "emplace": true, "insert": true, "erase": true, "find": true, "count": true,
+	"contains": true, "clear": true, "size": true, "empty": true, "reserve": true, "resize": true,
+	"capacity": true, "shrink_to_fit": true, "begin": true, "end": true, "cbegin": true, "cend": true,
+	"rbegin": true, "rend": true, "front": true, "back": true, "data": true, "at": true, "swap": true,
+	"c_str": true, "substr": true, "append": true, "length": true, "starts_with": true, "ends_with": true,
+	"first": true, "second": true, "get": true, "reset": true, "release": true, "use_count": true,
+	"value": true, "value_or": true, "has_value": true, "load": true, "store": true, "exchange": true,
+	"fetch_add": true, "fetch_sub": true, "compare_exchange_weak": true, "compare_exchange_strong": true,
+	"lock": true, "unlock": true, "try_lock": true, "wait": true, "wait_for": true, "notify_one": true,
+	"notify_all": true, "join": true, "joinable": true, "detach": true, "get_id": true, "what": true,
+	"top": true, "push": true, "pop": true, "lower_bound": true, "upper_bound": true, "equal_range": true,
+	"try_emplace": true, "insert_or_assign": true, "get_future": true, "set_value": true,
+}
+
+// declaredScopes returns the classes, structs and namespaces declared by the code, the only
+// qualifiers its own identifiers can be reached through
+func declaredScopes(files []CodeFile) map[string]bool {
+	scopes := make(map[string]bool)
+	for _, f := range files {
+		parsed := parseSourceContent(f.Content)
+		for _, c := range parsed.Classes {
+			scopes[c.Name] = true
+		}
+		for _, s := range parsed.Structs {
+			scopes[s.Name] = true
+		}
+		for _, m := range namespacePattern.FindAllStringSubmatch(f.Content, -1) {
+			scopes[m[1]] = true
+		}
+	}
+	return scopes
+}
+
+// usesIdentifier reports whether name appears as an identifier in any file, outside comments and literals
+func usesIdentifier(files []CodeFile, name string) bool {
+	found := false
+	for _, f := range files {
+		forEachIdentifier(f.Content, func(start, end int) {
+			found = found || f.Content[start:end] == name
+		})
+	}
+	return found
+}
+
+// usesForeignName reports whether the code also uses name for something it did not declare:
+// qualified by a namespace or class of its own (such as std::), or as a standard library member
+func usesForeignName(files []CodeFile, name string, scopes map[string]bool) bool {
+	foreign := false
+	for _, f := range files {
+		code := f.Content
+		forEachIdentifier(code, func(start, end int) {
+			if foreign || code[start:end] != name {
+				return
+			}
+			before := strings.TrimRight(code[:start], " \t\n")
+			switch {
+			case strings.HasSuffix(before, "::"):
+				qualifier := strings.TrimRight(before[:len(before)-2], " \t\n")
+				i := len(qualifier)
+				for i > 0 && isIdentByte(qualifier[i-1]) {
+					i--
+				}
+				foreign = i < len(qualifier) && !scopes[qualifier[i:]]
+			case strings.HasSuffix(before, ".") || strings.HasSuffix(before, "->"):
+				foreign = stdMemberNames[name]
+			}
+		})
+	}
+	return foreign
+}
+
+// renameIdentifier replaces every identifier name in code with replacement, outside comments and literals
+func renameIdentifier(code, name, replacement string) string {
+	var sb strings.Builder
+	last := 0
+	forEachIdentifier(code, func(start, end int) {
+		if code[start:end] == name {
+			sb.WriteString(code[last:start])
+			sb.WriteString(replacement)
+			last = end
+		}
+	})
+	sb.WriteString(code[last:])
+	return sb.String()
+}
+
+// literalPrefixes are the encoding and raw prefixes a string or character literal can start with
+var literalPrefixes = map[string]bool{"L": true, "u": true, "U": true, "u8": true, "R": true, "LR": true, "uR": true, "UR": true, "u8R": true}
+
+// forEachIdentifier calls fn with the span of every identifier in code that is outside comments,
+// string and character literals (raw strings included) and number literals
+func forEachIdentifier(code string, fn func(start, end int)) {
+	for i := 0; i < len(code); {
+		switch c := code[i]; {
+		case strings.HasPrefix(code[i:], "//"):
+			end := strings.IndexByte(code[i:], '\n')
+			if end < 0 {
+				return
+			}
+			i += end
+		case strings.HasPrefix(code[i:], "/*"):
+			end := strings.Index(code[i+2:], "*/")
+			if end < 0 {
+				return
+			}
+			i += end + 4
+		case c == '"' || c == '\'':
+			i = literalEnd(code, i)
+		case c >= '0' && c <= '9':
+			for i < len(code) && (isIdentByte(code[i]) || code[i] == '.' || code[i] == '\'') {
+				i++
+			}
+		case isIdentByte(c):
+			start := i
+			for i < len(code) && isIdentByte(code[i]) {
+				i++
+			}
+			if i < len(code) && (code[i] == '"' || code[i] == '\'') && literalPrefixes[code[start:i]] {
+				if strings.HasSuffix(code[start:i], "R") && code[i] == '"' {
+					i = rawLiteralEnd(code, i)
+				} else {
+					i = literalEnd(code, i)
+				}
+				continue
+			}
+			fn(start, i)
+		default:
+			i++
+		}
+	}
+}
+
+// literalEnd returns the offset just past the string or character literal opening at i
+func literalEnd(code string, i int) int {
+	quote := code[i]
+	for i++; i < len(code) && code[i] != quote && code[i] != '\n'; i++ {
+		if code[i] == '\\' {
+			i++
+		}
+	}
+	return min(i+1, len(code))
+}
+
+// rawLiteralEnd returns the offset just past the raw string literal R"delim(...)delim" whose quote is at i
+func rawLiteralEnd(code string, i int) int {
+	open := strings.IndexByte(code[i:], '(')
+	if open < 0 {
+		return len(code)
+	}
+	closing := ")" + code[i+1:i+open] + "\""
+	end := strings.Index(code[i+open:], closing)
+	if end < 0 {
+		return len(code)
+	}
+	return i + open + end + len(closing)
+}
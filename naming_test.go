@@ -0,0 +1,226 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClassifyName(t *testing.T) {
+	tests := []struct {
+		name string
+		want NamingStyle
+	}{
+		{"parse_input", StyleSnake},
+		{"parseInput", StyleCamel},
+		{"ParseInput", StylePascal},
+		{"parse", StyleUnknown},
+		{"MAX_SIZE", StyleUnknown},
+		{"Parse_Input", StyleUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyName(tt.name); got != tt.want {
+				t.Errorf("classifyName(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertName(t *testing.T) {
+	tests := []struct {
+		name  string
+		style NamingStyle
+		want  string
+	}{
+		{"parseInput", StyleSnake, "parse_input"},
+		{"parse_input", StyleCamel, "parseInput"},
+		{"parse_input", StylePascal, "ParseInput"},
+		{"HTTPServer", StyleSnake, "http_server"},
+		{"value2Count", StyleSnake, "value2_count"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+"->"+string(tt.style), func(t *testing.T) {
+			if got := convertName(tt.name, tt.style); got != tt.want {
+				t.Errorf("convertName(%q, %q) = %q, want %q", tt.name, tt.style, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectNamingConventions(t *testing.T) {
+	idx := &WorkspaceIndex{
+		Files: map[string]*FileIndex{
+			"a.cpp": {
+				Functions: []FuncInfo{{Name: "read_file"}, {Name: "parse_header"}, {Name: "write_output"}, {Name: "main"}},
+				Classes: []ClassInfo{
+					{Name: "FileReader", Members: []string{"m_path", "m_buffer_size"}},
+					{Name: "HeaderParser", Members: []string{"m_offset"}},
+				},
+				Structs: []StructInfo{{Name: "Options"}},
+			},
+		},
+	}
+
+	conv := idx.DetectNamingConventions()
+	if conv == nil {
+		t.Fatal("DetectNamingConventions() returned nil")
+	}
+	if conv.Functions != StyleSnake {
+		t.Errorf("Functions = %q, want %q", conv.Functions, StyleSnake)
+	}
+	if conv.Types != StylePascal {
+		t.Errorf("Types = %q, want %q", conv.Types, StylePascal)
+	}
+	if conv.MemberPrefix != "m_" {
+		t.Errorf("MemberPrefix = %q, want m_", conv.MemberPrefix)
+	}
+
+	var empty *WorkspaceIndex
+	if empty.DetectNamingConventions() != nil {
+		t.Error("nil index should have no conventions")
+	}
+}
+
+func TestCheckNamingConventions(t *testing.T) {
+	conv := &NamingConventions{
+		Functions:    StyleSnake,
+		Types:        StylePascal,
+		MemberPrefix: "m_",
+	}
+
+	code := `#include <string>
+
+class word_counter {
+public:
+    int countWords(const std::string& text) { return 0; }
+private:
+    int total;
+    int m_seen = 0;
+};
+
+int main() {
+    word_counter wc;
+    return wc.countWords("a b");
+}
+`
+
+	violations := CheckNamingConventions(code, conv)
+	want := map[string]string{
+		"word_counter": "WordCounter",
+		"countWords":   "count_words",
+		"total":        "m_total",
+	}
+	if len(violations) != len(want) {
+		t.Fatalf("got %d violations (%v), want %d", len(violations), violations, len(want))
+	}
+	for _, v := range violations {
+		if want[v.Name] != v.Suggested {
+			t.Errorf("violation %s -> %s, want -> %s", v.Name, v.Suggested, want[v.Name])
+		}
+	}
+
+	fixed, applied := ApplyNamingFixes([]CodeFile{{Filename: "main.cpp", Content: code}}, violations)
+	if len(applied) != len(violations) {
+		t.Errorf("applied %d fixes, want %d", len(applied), len(violations))
+	}
+	if strings.Contains(fixed[0].Content, "countWords") || !strings.Contains(fixed[0].Content, "wc.count_words(") {
+		t.Errorf("fixed code not renamed consistently:\n%s", fixed[0].Content)
+	}
+}
+
+func TestCheckNamingConventionsSkipsUndefinedFunctions(t *testing.T) {
+	conv := &NamingConventions{Functions: StyleCamel}
+	code := `int legacy_api(int x);
+
+int Parser::parse_line(const std::string& line) const {
+    return legacy_api(1);
+}
+
+std::future<int> run() {
+    std::cout << to_string(3);
+    co_return compute_total(3);
+}
+`
+	violations := CheckNamingConventions(code, conv)
+	if len(violations) != 1 || violations[0].Name != "parse_line" || violations[0].Suggested != "parseLine" {
+		t.Errorf("CheckNamingConventions() = %v, want only parse_line, the function the code defines", violations)
+	}
+}
+
+func TestApplyNamingFixes(t *testing.T) {
+	tests := []struct {
+		name       string
+		files      []CodeFile
+		violations []NamingViolation
+		want       []string // Contents after the fixes
+		applied    int
+	}{
+		{
+			name: "rename across files",
+			files: []CodeFile{
+				{Filename: "counter.h", Content: "#pragma once\nint countWords(const char* text);\n"},
+				{Filename: "counter.cpp", Content: "#include \"counter.h\"\nint countWords(const char* text) { return 0; }\n"},
+				{Filename: "main.cpp", Content: "#include \"counter.h\"\nint main() { return countWords(\"a\"); }\n"},
+			},
+			violations: []NamingViolation{{Kind: "function", Name: "countWords", Suggested: "count_words"}},
+			want: []string{
+				"#pragma once\nint count_words(const char* text);\n",
+				"#include \"counter.h\"\nint count_words(const char* text) { return 0; }\n",
+				"#include \"counter.h\"\nint main() { return count_words(\"a\"); }\n",
+			},
+			applied: 1,
+		},
+		{
+			name: "comments and literals are kept",
+			files: []CodeFile{{Filename: "main.cpp", Content: "// total is the sum\nint total = 0; /* total */\n" +
+				"const char* s = \"total\"; char c = 't'; auto r = R\"x(total \")x\"; int t2 = total + 1'000;\n"}},
+			violations: []NamingViolation{{Kind: "member", Name: "total", Suggested: "m_total"}},
+			want: []string{"// total is the sum\nint m_total = 0; /* total */\n" +
+				"const char* s = \"total\"; char c = 't'; auto r = R\"x(total \")x\"; int t2 = m_total + 1'000;\n"},
+			applied: 1,
+		},
+		{
+			name:       "collision with an existing identifier",
+			files:      []CodeFile{{Filename: "main.cpp", Content: "int getValue() { return 1; }\nint get_value() { return 2; }\n"}},
+			violations: []NamingViolation{{Kind: "function", Name: "getValue", Suggested: "get_value"}},
+			want:       []string{"int getValue() { return 1; }\nint get_value() { return 2; }\n"},
+		},
+		{
+			name: "name also used from the standard library",
+			files: []CodeFile{{Filename: "main.cpp", Content: "namespace app { int to_string(int x) { return x; } }\n" +
+				"int Stack::push_back(int x) { items.push_back(x); return app::to_string(x) + std::to_string(x).size(); }\n"}},
+			violations: []NamingViolation{
+				{Kind: "function", Name: "to_string", Suggested: "toString"},
+				{Kind: "function", Name: "push_back", Suggested: "pushBack"},
+			},
+			want: []string{"namespace app { int to_string(int x) { return x; } }\n" +
+				"int Stack::push_back(int x) { items.push_back(x); return app::to_string(x) + std::to_string(x).size(); }\n"},
+		},
+		{
+			name:       "qualified by a declared class",
+			files:      []CodeFile{{Filename: "main.cpp", Content: "class Parser {\n    int parseLine();\n};\nint Parser::parseLine() { return 0; }\n"}},
+			violations: []NamingViolation{{Kind: "function", Name: "parseLine", Suggested: "parse_line"}},
+			want:       []string{"class Parser {\n    int parse_line();\n};\nint Parser::parse_line() { return 0; }\n"},
+			applied:    1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixed, applied := ApplyNamingFixes(tt.files, tt.violations)
+			if len(applied) != tt.applied {
+				t.Errorf("applied %v, want %d fix(es)", applied, tt.applied)
+			}
+			for i, want := range tt.want {
+				if fixed[i].Content != want {
+					t.Errorf("%s = %q, want %q", fixed[i].Filename, fixed[i].Content, want)
+				}
+			}
+		})
+	}
+	files := []CodeFile{{Filename: "main.cpp", Content: "int fooBar() { return 0; }\n"}}
+	if ApplyNamingFixes(files, []NamingViolation{{Name: "fooBar", Suggested: "foo_bar"}}); files[0].Content != "int fooBar() { return 0; }\n" {
+		t.Error("ApplyNamingFixes() changed its input files")
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Validation network modes (settings: sandbox.network)
+const (
+	SandboxNetworkNone  = "none"
+	SandboxNetworkNetns = "netns"
+)
+
+// sandboxNetworks are the modes sandbox.network accepts, the default first
+var sandboxNetworks = []string{SandboxNetworkNone, SandboxNetworkNetns}
+
+// netnsMarker starts the line a program stage prints instead of running the program when
+// sandbox.network is netns and no network namespace can be created
+const netnsMarker = "bjarne: netns:"
+
+// netnsUnavailableExit is the exit status of a program stage that could not create its namespace
+const netnsUnavailableExit = 125
+
+// loopbackUp brings up the loopback interface of a fresh network namespace (it starts down)
+const loopbackUp = "ip link set lo up 2>/dev/null || ifconfig lo up 2>/dev/null"
+
+// SetSandboxSettings configures the network the program stages run with
+func (c *ContainerRuntime) SetSandboxSettings(settings SandboxSettings) {
+	c.sandbox = settings
+}
+
+// netnsCommand runs a program in its own network namespace with only a loopback interface when
+// sandbox.network is netns. The container's --network none already has a loopback interface, so a
+// client and server can talk over 127.0.0.1 in either mode; a namespace per run also gives each run
+// its own ports, so the loops that run the program many times (stress, exceptions) never find a port
+// still held by an earlier run. Nothing can leave the container either way. checkNetns makes sure the
+// image can create namespaces before validation starts; should one still fail, the program is not
+// run and the stage fails with netnsMarker and netnsUnavailableExit
+func (c *ContainerRuntime) netnsCommand(env, run string) string {
+	if c.sandbox.Network != SandboxNetworkNetns {
+		return env + run
+	}
+	script := shellQuote(loopbackUp + "; " + env + "exec " + run)
+	return fmt.Sprintf("{ if unshare -rn true 2>/dev/null; then unshare -rn sh -c %s; "+
+		"else echo '%s unshare -rn failed: this image or kernel does not allow unprivileged network namespaces' >&2; (exit %d); fi; }",
+		script, netnsMarker, netnsUnavailableExit)
+}
+
+// checkNetns fails validation up front when sandbox.network is netns and the validator image cannot
+// create a network namespace, instead of every stage that runs the program failing on its own
+func (c *ContainerRuntime) checkNetns(ctx context.Context) error {
+	if c.sandbox.Network != SandboxNetworkNetns {
+		return nil
+	}
+	var stdout, stderr bytes.Buffer
+	err := c.runContainer(ctx, &stdout, &stderr, "--rm", "--network", "none", "--security-opt", "seccomp=unconfined",
+		c.imageName, "unshare", "-rn", "true")
+	if err != nil {
+		reason := strings.TrimSpace(stderr.String())
+		if reason == "" {
+			reason = err.Error()
+		}
+		return fmt.Errorf("sandbox.network is netns but the validator image cannot create a network namespace (unshare -rn: %s); "+
+			"set sandbox.network to none to run programs in the container's loopback-only network", reason)
+	}
+	return nil
+}
+
+// netnsFailure returns why a stage could not create the program's network namespace, or "" when it
+// failed for another reason. It only trusts the marker in netns mode, with the exit status netnsCommand
+// uses, and at the start of a line, since the rest of the output is the program's
+func (c *ContainerRuntime) netnsFailure(output string, err error) string {
+	var exit *exec.ExitError
+	if c.sandbox.Network != SandboxNetworkNetns || !errors.As(err, &exit) || exit.ExitCode() != netnsUnavailableExit {
+		return ""
+	}
+	for _, line := range strings.Split(output, "\n") {
+		if reason, ok := strings.CutPrefix(line, netnsMarker+" "); ok {
+			return "sandbox.network is netns but " + reason + "; set sandbox.network to none to run the program in the container's loopback-only network"
+		}
+	}
+	return ""
+}
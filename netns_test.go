@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestNetnsCommand(t *testing.T) {
+	c := &ContainerRuntime{}
+	if got := c.netnsCommand("MSAN_OPTIONS=halt_on_error=1 ", "/tmp/test"); got != "MSAN_OPTIONS=halt_on_error=1 /tmp/test" {
+		t.Errorf("netnsCommand(none) = %q, want the program unchanged", got)
+	}
+
+	c.SetSandboxSettings(SandboxSettings{Network: SandboxNetworkNetns})
+	got := c.netnsCommand("MSAN_OPTIONS=halt_on_error=1 ", "/tmp/test 'a b'")
+	for _, want := range []string{
+		"if unshare -rn true 2>/dev/null; then unshare -rn sh -c '",
+		loopbackUp + "; MSAN_OPTIONS=halt_on_error=1 exec /tmp/test '\\''a b'\\''",
+		"else echo '" + netnsMarker + " unshare -rn failed",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("netnsCommand(netns) = %q, missing %q", got, want)
+		}
+	}
+}
+
+func TestNetnsFailure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs /bin/sh")
+	}
+	// Stands in for an unshare that is not allowed to create the namespace
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "unshare"), []byte("#!/bin/sh\nexit 1\n"), 0700); err != nil { //nolint:gosec // the test runs it
+		t.Fatal(err)
+	}
+	c := &ContainerRuntime{sandbox: SandboxSettings{Network: SandboxNetworkNetns}}
+	cmd := exec.Command("/bin/sh", "-c", c.netnsCommand("", "echo ran"))
+	cmd.Env = append(os.Environ(), "PATH="+bin+":"+os.Getenv("PATH"))
+	out, err := cmd.CombinedOutput()
+	if strings.Contains(string(out), "ran") {
+		t.Fatalf("netnsCommand() ran the program without a namespace: %q", out)
+	}
+	if reason := c.netnsFailure("==1== some output\n"+string(out), err); !strings.Contains(reason, "unshare -rn failed") || !strings.Contains(reason, "set sandbox.network to none") {
+		t.Errorf("netnsFailure() = %q", reason)
+	}
+
+	// The program printing the marker is its own failure, whatever it exits with
+	printed := exec.Command("/bin/sh", "-c", "echo '"+netnsMarker+" unshare -rn failed'; echo 'x: "+netnsMarker+" y'; exit 1")
+	printedOut, printedErr := printed.CombinedOutput()
+	if reason := c.netnsFailure(string(printedOut), printedErr); reason != "" {
+		t.Errorf("netnsFailure() = %q for a program that exited 1", reason)
+	}
+	quoted := exec.Command("/bin/sh", "-c", "echo 'x: "+netnsMarker+" y'; exit 125")
+	quotedOut, quotedErr := quoted.CombinedOutput()
+	if reason := c.netnsFailure(string(quotedOut), quotedErr); reason != "" {
+		t.Errorf("netnsFailure() = %q for the marker inside a line", reason)
+	}
+	if reason := (&ContainerRuntime{}).netnsFailure(string(out), err); reason != "" {
+		t.Errorf("netnsFailure() = %q without netns mode", reason)
+	}
+}
+
+func TestCheckNetns(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs /bin/sh")
+	}
+	// Stands in for the container runtime: unshare fails in the image
+	bin := filepath.Join(t.TempDir(), "podman")
+	if err := os.WriteFile(bin, []byte("#!/bin/sh\necho 'unshare: unshare failed: Operation not permitted' >&2\nexit 1\n"), 0700); err != nil { //nolint:gosec // the test runs it
+		t.Fatal(err)
+	}
+	c := &ContainerRuntime{binary: bin, imageName: "validator"}
+	if err := c.checkNetns(context.Background()); err != nil {
+		t.Errorf("checkNetns() = %v without netns mode", err)
+	}
+	c.SetSandboxSettings(SandboxSettings{Network: SandboxNetworkNetns})
+	err := c.checkNetns(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "Operation not permitted") || !strings.Contains(err.Error(), "sandbox.network to none") {
+		t.Errorf("checkNetns() = %v, want the unshare error", err)
+	}
+	if _, err := c.ValidateCode(context.Background(), "int main() {}", "main.cpp"); err == nil {
+		t.Error("ValidateCode() ran without a network namespace")
+	}
+}
+
+func TestProgramCommandNetns(t *testing.T) {
+	c := &ContainerRuntime{hang: HangSettings{Timeout: 5}}
+	c.SetSandboxSettings(SandboxSettings{Network: SandboxNetworkNetns})
+	got := c.programCommand("", "/tmp/test")
+	if !strings.HasPrefix(got, "{ { if unshare -rn") || !strings.Contains(got, "fi; } < /dev/null & p=$!") {
+		t.Errorf("programCommand() = %q, want the namespaced program under the watchdog", got)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+)
+
+// Events that can notify, as named in notifications settings
+const (
+	NotifyValidationDone      = "validationDone"
+	NotifyBudgetWarning       = "budgetWarning"
+	NotifyEscalationExhausted = "escalationExhausted"
+)
+
+// announce returns how an event is announced once its task has run for elapsed
+// Validation events for tasks quicker than MinSeconds stay silent: you were still watching
+func (n NotificationSettings) announce(event string, elapsed time.Duration) NotifyEvent {
+	switch event {
+	case NotifyBudgetWarning:
+		return n.BudgetWarning
+	case NotifyValidationDone, NotifyEscalationExhausted:
+		if elapsed < time.Duration(n.MinSeconds)*time.Second {
+			return NotifyEvent{}
+		}
+		if event == NotifyValidationDone {
+			return n.ValidationDone
+		}
+		return n.EscalationExhausted
+	}
+	return NotifyEvent{}
+}
+
+// ringBell rings the terminal bell
+func ringBell() {
+	_, _ = fmt.Fprint(os.Stdout, "\a")
+}
+
+// Environment variables that carry notification text to the platform helpers,
+// so titles and messages never need shell or AppleScript quoting
+const (
+	notifyTitleEnv = "BJARNE_NOTIFY_TITLE"
+	notifyBodyEnv  = "BJARNE_NOTIFY_BODY"
+)
+
+// windowsNotifyScript shows a balloon notification from the tray
+const windowsNotifyScript = `Add-Type -AssemblyName System.Windows.Forms
+$n = New-Object System.Windows.Forms.NotifyIcon
+$n.Icon = [System.Drawing.SystemIcons]::Information
+$n.Visible = $true
+$n.ShowBalloonTip(5000, $env:BJARNE_NOTIFY_TITLE, $env:BJARNE_NOTIFY_BODY, 'Info')
+Start-Sleep -Seconds 6
+$n.Dispose()`
+
+// desktopNotify shows a native desktop notification without waiting for it to close
+// Uses osascript on macOS, PowerShell on Windows and notify-send elsewhere
+func desktopNotify(title, message string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("osascript", "-e",
+			`display notification (system attribute "`+notifyBodyEnv+`") with title (system attribute "`+notifyTitleEnv+`")`)
+	case "windows":
+		cmd = exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", windowsNotifyScript)
+	default:
+		if _, err := exec.LookPath("notify-send"); err != nil {
+			return fmt.Errorf("notify-send not found (install libnotify)")
+		}
+		cmd = exec.Command("notify-send", "--app-name=bjarne", title, message)
+	}
+	cmd.Env = append(os.Environ(), notifyTitleEnv+"="+title, notifyBodyEnv+"="+message)
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to show notification: %w", err)
+	}
+	go func() { _ = cmd.Wait() }()
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNotificationAnnounce(t *testing.T) {
+	n := NotificationSettings{
+		ValidationDone:      NotifyEvent{Desktop: true},
+		BudgetWarning:       NotifyEvent{Bell: true},
+		EscalationExhausted: NotifyEvent{Desktop: true, Bell: true},
+		MinSeconds:          30,
+	}
+	tests := []struct {
+		name    string
+		event   string
+		elapsed time.Duration
+		want    NotifyEvent
+	}{
+		{"long validation", NotifyValidationDone, time.Minute, NotifyEvent{Desktop: true}},
+		{"quick validation", NotifyValidationDone, 10 * time.Second, NotifyEvent{}},
+		{"exhausted", NotifyEscalationExhausted, 30 * time.Second, NotifyEvent{Desktop: true, Bell: true}},
+		{"quick exhaustion", NotifyEscalationExhausted, time.Second, NotifyEvent{}},
+		{"budget ignores the task time", NotifyBudgetWarning, 0, NotifyEvent{Bell: true}},
+		{"unknown event", "other", time.Hour, NotifyEvent{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := n.announce(tt.event, tt.elapsed); got != tt.want {
+				t.Errorf("announce(%s, %s) = %+v, want %+v", tt.event, tt.elapsed, got, tt.want)
+			}
+		})
+	}
+
+	n.MinSeconds = 0
+	if got := n.announce(NotifyValidationDone, 0); !got.Desktop {
+		t.Error("minSeconds 0 should always notify")
+	}
+}
+
+func TestValidateNotifications(t *testing.T) {
+	s := DefaultSettings()
+	s.Notifications.MinSeconds = -1
+	issues := s.Validate()
+	if len(issues) != 1 || issues[0].Path != "notifications.minSeconds" {
+		t.Errorf("issues = %v, want one for notifications.minSeconds", issues)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+// offlineMode is set by --offline (or BJARNE_OFFLINE=1): nothing but the local provider
+// and the local container runtime is contacted
+var offlineMode bool
+
+// errOffline is returned by features that need the network in offline mode
+var errOffline = errors.New("unavailable in offline mode (--offline)")
+
+// Defaults for the local OpenAI-compatible server used in offline mode (Ollama's port)
+const (
+	defaultLocalBaseURL = "http://localhost:11434/v1"
+	defaultLocalModel   = "qwen2.5-coder:14b"
+)
+
+// parseOfflineFlag removes --offline from args and reports whether offline mode is on
+// BJARNE_OFFLINE=1 enables it as well
+func parseOfflineFlag(args []string) ([]string, bool) {
+	offline := os.Getenv("BJARNE_OFFLINE") == "1" || strings.EqualFold(os.Getenv("BJARNE_OFFLINE"), "true")
+	rest := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == "--offline" {
+			offline = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return rest, offline
+}
+
+// applyOffline routes generation to the configured local provider
+func (c *Config) applyOffline() {
+	c.Provider = ProviderLocal
+}
+
+// offlineDegraded lists what offline mode turns off, for the splash status line
+func offlineDegraded() []string {
+	return []string{"update checks", "image digests", "model downloads", "LLM Guard service", "library installs (cache only)"}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseOfflineFlag(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		env         string
+		wantArgs    string
+		wantOffline bool
+	}{
+		{"absent", []string{"--validate", "a.cpp"}, "", "--validate a.cpp", false},
+		{"first", []string{"--offline", "--validate", "a.cpp"}, "", "--validate a.cpp", true},
+		{"after command", []string{"--validate", "a.cpp", "--offline"}, "", "--validate a.cpp", true},
+		{"environment", nil, "1", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("BJARNE_OFFLINE", tt.env)
+			args, offline := parseOfflineFlag(tt.args)
+			if got := strings.Join(args, " "); got != tt.wantArgs || offline != tt.wantOffline {
+				t.Errorf("parseOfflineFlag() = %q, %v, want %q, %v", got, offline, tt.wantArgs, tt.wantOffline)
+			}
+		})
+	}
+}
+
+func TestLocalProvider(t *testing.T) {
+	var gotModel, gotAuth, gotPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req OpenAIRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		gotModel, gotAuth, gotPath = req.Model, r.Header.Get("Authorization"), r.URL.Path
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`))
+	}))
+	defer server.Close()
+
+	provider, err := NewProvider(context.Background(), &ProviderConfig{
+		Provider: ParseProviderType("ollama"),
+		Local:    LocalSettings{BaseURL: server.URL + "/v1/", Model: "codellama"},
+	})
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+
+	// Every tier and model ID goes to the one local model
+	for _, model := range []string{ModelOpus, "global.anthropic.claude-haiku-4-5-20251001-v1:0"} {
+		if _, err := provider.Generate(context.Background(), model, "", []Message{{Role: "user", Content: "hi"}}, 100); err != nil {
+			t.Fatalf("Generate(%s) error = %v", model, err)
+		}
+		if gotModel != "codellama" {
+			t.Errorf("Generate(%s) sent model %q, want codellama", model, gotModel)
+		}
+	}
+	if gotPath != "/v1/chat/completions" {
+		t.Errorf("request path = %q, want /v1/chat/completions", gotPath)
+	}
+	if gotAuth != "" {
+		t.Errorf("Authorization = %q, want none without an API key", gotAuth)
+	}
+}
+
+func TestOfflineDisablesNetworkFeatures(t *testing.T) {
+	offlineMode = true
+	defer func() { offlineMode = false }()
+	t.Setenv("LLMGUARD_URL", "http://guard.example")
+
+	if NewLLMGuardClient(GuardSettings{Enabled: true}).IsEnabled() {
+		t.Error("LLM Guard enabled in offline mode")
+	}
+	if err := downloadFile(context.Background(), "http://example.invalid/model", t.TempDir()+"/model", nil); !errors.Is(err, errOffline) {
+		t.Errorf("downloadFile() error = %v, want errOffline", err)
+	}
+	if cfg := LoadConfig(); cfg.Provider != ProviderLocal {
+		t.Errorf("LoadConfig() provider = %s, want local", cfg.Provider)
+	}
+}
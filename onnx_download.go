@@ -67,6 +67,9 @@ func EnsureONNXRuntime(progressFn func(string)) error {
 	}
 
 	// Download
+	if offlineMode {
+		return errOffline
+	}
 	url, archiveType := getONNXDownloadURL()
 	if progressFn != nil {
 		progressFn(fmt.Sprintf("Downloading ONNX Runtime v%s...", onnxVersion))
@@ -81,7 +84,12 @@ func EnsureONNXRuntime(progressFn func(string)) error {
 	defer func() { _ = os.Remove(tmpPath) }()
 
 	// Download
-	resp, err := http.Get(url) //nolint:gosec // URL is hardcoded
+	client, err := NewHTTPClient(endpointDownloads, 0)
+	if err != nil {
+		_ = tmpFile.Close()
+		return err
+	}
+	resp, err := client.Get(url) //nolint:gosec // URL is hardcoded
 	if err != nil {
 		_ = tmpFile.Close()
 		return fmt.Errorf("download failed: %w", err)
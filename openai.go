@@ -20,6 +20,9 @@ type OpenAIClient struct {
 	apiKey       string
 	defaultModel string
 	httpClient   *http.Client
+	generation   GenerationSettings
+	apiURL       string // Chat completions endpoint
+	local        bool   // Local server: every model name maps to defaultModel
 }
 
 // OpenAIRequest represents a request to the OpenAI Chat Completions API
@@ -28,7 +31,9 @@ type OpenAIRequest struct {
 	Messages            []OpenAIMessage `json:"messages"`
 	MaxTokens           int             `json:"max_tokens,omitempty"`            // For older models
 	MaxCompletionTokens int             `json:"max_completion_tokens,omitempty"` // For GPT-5.1+, o1, o3
-	Temperature         float64         `json:"temperature,omitempty"`
+	Temperature         *float64        `json:"temperature,omitempty"`
+	TopP                *float64        `json:"top_p,omitempty"`
+	Seed                *int64          `json:"seed,omitempty"`
 	Stream              bool            `json:"stream,omitempty"`
 	ReasoningEffort     string          `json:"reasoning_effort,omitempty"` // For GPT-5.1: "medium", "high", "xhigh"
 }
@@ -71,23 +76,68 @@ func NewOpenAIProvider(cfg *ProviderConfig) (LLMProvider, error) {
 		defaultModel = OpenAIModelMap[ModelSonnet]
 	}
 
+	httpClient, err := NewHTTPClient(endpointOpenAI, 0)
+	if err != nil {
+		return nil, err
+	}
+
 	return &OpenAIClient{
 		apiKey:       cfg.APIKey,
 		defaultModel: defaultModel,
-		httpClient:   &http.Client{},
+		httpClient:   httpClient,
+		apiURL:       openaiAPIURL,
+		generation:   cfg.Generation,
 	}, nil
 }
 
+// SetGeneration sets temperature, top-p and seed for subsequent requests
+func (c *OpenAIClient) SetGeneration(gen GenerationSettings) {
+	c.generation = gen
+}
+
 // Name returns the provider name
 func (c *OpenAIClient) Name() string {
+	if c.local {
+		return "Local (" + c.defaultModel + ")"
+	}
 	return "OpenAI"
 }
 
 // MapModel maps a canonical model name to OpenAI model ID
 func (c *OpenAIClient) MapModel(canonical string) string {
+	if c.local {
+		return c.defaultModel
+	}
 	return MapModelGeneric(ProviderOpenAI, canonical)
 }
 
+// NewLocalProvider creates an OpenAIClient for a local OpenAI-compatible server
+// A local server usually hosts one model, so every tier and model ID uses it
+func NewLocalProvider(cfg *ProviderConfig) (LLMProvider, error) {
+	baseURL := strings.TrimRight(cfg.Local.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = defaultLocalBaseURL
+	}
+	model := cfg.Local.Model
+	if model == "" {
+		model = defaultLocalModel
+	}
+
+	httpClient, err := NewHTTPClient(endpointLocal, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OpenAIClient{
+		apiKey:       cfg.APIKey,
+		defaultModel: model,
+		httpClient:   httpClient,
+		generation:   cfg.Generation,
+		apiURL:       baseURL + "/chat/completions",
+		local:        true,
+	}, nil
+}
+
 // DefaultModel returns the default model
 func (c *OpenAIClient) DefaultModel() string {
 	return c.defaultModel
@@ -105,9 +155,9 @@ func convertMessagesToOpenAI(systemPrompt string, messages []Message) []OpenAIMe
 		})
 	}
 
-	// Convert user/assistant messages
+	// Convert user/assistant messages (images are not sent)
 	for _, msg := range messages {
-		result = append(result, OpenAIMessage(msg))
+		result = append(result, OpenAIMessage{Role: msg.Role, Content: msg.Content})
 	}
 
 	return result
@@ -128,10 +178,21 @@ func usesMaxCompletionTokens(model string) bool {
 	return strings.HasPrefix(model, "gpt-5") || strings.HasPrefix(model, "o1") || strings.HasPrefix(model, "o3")
 }
 
+// applyGeneration copies sampling parameters into a request
+// Reasoning models only accept the default temperature and top-p, so those are left unset
+func (c *OpenAIClient) applyGeneration(req *OpenAIRequest) {
+	req.Seed = c.generation.Seed
+	if usesMaxCompletionTokens(req.Model) {
+		return
+	}
+	req.Temperature = c.generation.Temperature
+	req.TopP = c.generation.TopP
+}
+
 // Generate sends a request to the OpenAI API
 func (c *OpenAIClient) Generate(ctx context.Context, model, systemPrompt string, messages []Message, maxTokens int) (*GenerateResult, error) {
 	// Map canonical model names to OpenAI IDs
-	if IsCanonicalModel(model) {
+	if IsCanonicalModel(model) || c.local {
 		model = c.MapModel(model)
 	}
 
@@ -147,19 +208,22 @@ func (c *OpenAIClient) Generate(ctx context.Context, model, systemPrompt string,
 	} else {
 		req.MaxTokens = maxTokens
 	}
+	c.applyGeneration(&req)
 
 	body, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", openaiAPIURL, bytes.NewReader(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.apiURL, bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	if c.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
 
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
@@ -190,17 +254,20 @@ func (c *OpenAIClient) Generate(ctx context.Context, model, systemPrompt string,
 		return nil, fmt.Errorf("model returned empty content (finish_reason: %s)", apiResp.Choices[0].FinishReason)
 	}
 
-	return &GenerateResult{
+	result := &GenerateResult{
 		Text:         text,
 		InputTokens:  apiResp.Usage.PromptTokens,
 		OutputTokens: apiResp.Usage.CompletionTokens,
-	}, nil
+	}
+	// Some local servers omit usage
+	fillUsageEstimate(result, systemPrompt, messages)
+	return result, nil
 }
 
 // GenerateStreaming sends a streaming request to the OpenAI API
 func (c *OpenAIClient) GenerateStreaming(ctx context.Context, model, systemPrompt string, messages []Message, maxTokens int, callback StreamCallback) (*GenerateResult, error) {
 	// Map canonical model names to OpenAI IDs
-	if IsCanonicalModel(model) {
+	if IsCanonicalModel(model) || c.local {
 		model = c.MapModel(model)
 	}
 
@@ -217,19 +284,22 @@ func (c *OpenAIClient) GenerateStreaming(ctx context.Context, model, systemPromp
 	} else {
 		req.MaxTokens = maxTokens
 	}
+	c.applyGeneration(&req)
 
 	body, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", openaiAPIURL, bytes.NewReader(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.apiURL, bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	if c.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
 
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
@@ -277,8 +347,8 @@ func (c *OpenAIClient) GenerateStreaming(ctx context.Context, model, systemPromp
 		}
 	}
 
-	return &GenerateResult{
-		Text: fullText,
-		// Token counts not available in streaming responses
-	}, nil
+	// Token counts are not available in streaming responses
+	result := &GenerateResult{Text: fullText}
+	fillUsageEstimate(result, systemPrompt, messages)
+	return result, nil
 }
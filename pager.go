@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Display modes (settings: display.mode)
+const (
+	DisplayScrollback = "scrollback" // Print output straight to the terminal (default)
+	DisplayAltScreen  = "altscreen"  // Full-screen TUI with a scrollable output pane
+)
+
+// maxPagerLines caps the output buffer kept in alt-screen mode
+const maxPagerLines = 10000
+
+// pagerReservedLines is the space below the pane (pager status + 3-line input)
+const pagerReservedLines = 5
+
+// ansiPattern matches ANSI escape sequences (stripped before searching)
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+
+// stripANSI removes ANSI escape sequences from s
+func stripANSI(s string) string {
+	return ansiPattern.ReplaceAllString(s, "")
+}
+
+// outputPager is a scrollable output pane used in alt-screen mode
+// PgUp/PgDn scroll; while scrolled back, / starts a search and n/N jump between matches
+type outputPager struct {
+	viewport viewport.Model
+	lines    []string
+	follow   bool // Stick to the bottom as new output arrives
+
+	searching bool   // Typing a search query
+	query     string // Current (or in-progress) search query
+	matches   []int  // Line numbers matching query
+	matchIdx  int    // Current match
+}
+
+// newOutputPager creates an empty pager
+func newOutputPager(width, height int) *outputPager {
+	return &outputPager{
+		viewport: viewport.New(width, height),
+		follow:   true,
+	}
+}
+
+// Append adds output (which may span several lines) to the pager
+func (p *outputPager) Append(text string) {
+	p.lines = append(p.lines, strings.Split(text, "\n")...)
+	if len(p.lines) > maxPagerLines {
+		p.lines = p.lines[len(p.lines)-maxPagerLines:]
+	}
+	p.refresh()
+}
+
+// SetSize resizes the viewport
+func (p *outputPager) SetSize(width, height int) {
+	if height < 1 {
+		height = 1
+	}
+	p.viewport.Width = width
+	p.viewport.Height = height
+	p.refresh()
+}
+
+// refresh re-renders the content, keeping the bottom in view when following
+func (p *outputPager) refresh() {
+	p.viewport.SetContent(strings.Join(p.lines, "\n"))
+	if p.follow {
+		p.viewport.GotoBottom()
+	}
+}
+
+// HandleKey processes pager keys, returning true if the key was consumed
+func (p *outputPager) HandleKey(msg tea.KeyMsg) bool {
+	if p.searching {
+		switch msg.Type {
+		case tea.KeyEnter:
+			p.searching = false
+			p.search()
+		case tea.KeyEsc:
+			p.searching = false
+			p.query = ""
+		case tea.KeyBackspace:
+			if len(p.query) > 0 {
+				r := []rune(p.query)
+				p.query = string(r[:len(r)-1])
+			}
+		case tea.KeyRunes, tea.KeySpace:
+			p.query += string(msg.Runes)
+		}
+		return true
+	}
+
+	switch msg.Type {
+	case tea.KeyPgUp:
+		p.follow = false
+		p.viewport.PageUp()
+		return true
+	case tea.KeyPgDown:
+		p.viewport.PageDown()
+		if p.viewport.AtBottom() {
+			p.follow = true
+		}
+		return true
+	}
+
+	// Remaining keys only apply while browsing scrollback
+	if p.follow {
+		return false
+	}
+
+	switch msg.Type {
+	case tea.KeyEsc:
+		p.follow = true
+		p.matches = nil
+		p.viewport.GotoBottom()
+		return true
+	case tea.KeyUp:
+		p.viewport.ScrollUp(1)
+		return true
+	case tea.KeyDown:
+		p.viewport.ScrollDown(1)
+		return true
+	case tea.KeyRunes:
+		switch string(msg.Runes) {
+		case "/":
+			p.searching = true
+			p.query = ""
+			return true
+		case "n":
+			p.jump(1)
+			return true
+		case "N":
+			p.jump(-1)
+			return true
+		}
+	}
+	return false
+}
+
+// search finds all lines containing the query (case-insensitive) and jumps to the last one
+func (p *outputPager) search() {
+	p.matches = nil
+	if p.query == "" {
+		return
+	}
+	q := strings.ToLower(p.query)
+	for i, line := range p.lines {
+		if strings.Contains(strings.ToLower(stripANSI(line)), q) {
+			p.matches = append(p.matches, i)
+		}
+	}
+	if len(p.matches) == 0 {
+		return
+	}
+	// Most recent output is usually what the user wants
+	p.matchIdx = len(p.matches) - 1
+	p.follow = false
+	p.viewport.SetYOffset(p.matches[p.matchIdx])
+}
+
+// jump moves to the next (dir=1) or previous (dir=-1) search match
+func (p *outputPager) jump(dir int) {
+	if len(p.matches) == 0 {
+		return
+	}
+	p.matchIdx = (p.matchIdx + dir + len(p.matches)) % len(p.matches)
+	p.viewport.SetYOffset(p.matches[p.matchIdx])
+}
+
+// StatusLine describes the pager state for the footer (empty when following live output)
+func (p *outputPager) StatusLine() string {
+	switch {
+	case p.searching:
+		return "/" + p.query
+	case p.follow:
+		return ""
+	case len(p.matches) > 0:
+		return fmt.Sprintf("%q match %d/%d · n/N next/prev · esc to return", p.query, p.matchIdx+1, len(p.matches))
+	case p.query != "":
+		return fmt.Sprintf("%q not found · esc to return", p.query)
+	}
+	return fmt.Sprintf("%3.0f%% · / search · esc to return", p.viewport.ScrollPercent()*100)
+}
+
+// View renders the output pane
+func (p *outputPager) View() string {
+	return p.viewport.View()
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestOutputPagerSearch(t *testing.T) {
+	p := newOutputPager(80, 5)
+	for i := 0; i < 50; i++ {
+		p.Append("line")
+	}
+	p.Append("\x1b[92mPASS asan\x1b[0m\nline")
+	p.Append("FAIL ubsan")
+
+	// Not browsing yet - "/" belongs to the input box
+	if p.HandleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")}) {
+		t.Fatal("/ should not be consumed while following live output")
+	}
+
+	p.HandleKey(tea.KeyMsg{Type: tea.KeyPgUp})
+	if p.follow {
+		t.Fatal("PgUp should stop following output")
+	}
+
+	p.HandleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	for _, r := range "pass" {
+		p.HandleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	p.HandleKey(tea.KeyMsg{Type: tea.KeyEnter})
+
+	if len(p.matches) != 1 || p.matches[0] != 50 {
+		t.Errorf("matches = %v, want [50] (ANSI codes ignored)", p.matches)
+	}
+
+	p.HandleKey(tea.KeyMsg{Type: tea.KeyEsc})
+	if !p.follow || !p.viewport.AtBottom() {
+		t.Error("Esc should return to live output")
+	}
+}
+
+func TestOutputPagerBufferLimit(t *testing.T) {
+	p := newOutputPager(80, 5)
+	for i := 0; i < maxPagerLines+10; i++ {
+		p.Append("x")
+	}
+	if len(p.lines) != maxPagerLines {
+		t.Errorf("len(lines) = %d, want %d", len(p.lines), maxPagerLines)
+	}
+}
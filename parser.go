@@ -201,6 +201,19 @@ func ParseSanitizerOutput(output string, sanitizerType string) []Diagnostic {
 	return diagnostics
 }
 
+// compilerDiagnosticsWithNotes parses compiler output for gates whose errors are explained by notes
+// The notes become errors: for a failed constant evaluation or template instantiation they hold
+// the reason and the lines in the user's code, which then get source snippets
+func compilerDiagnosticsWithNotes(output string) []Diagnostic {
+	diags := ParseClangTidyOutput(output)
+	for i := range diags {
+		if diags[i].Level == LevelNote {
+			diags[i].Level = LevelError
+		}
+	}
+	return diags
+}
+
 // FormatDiagnosticsForLLM formats diagnostics in a compact format for LLM processing
 // No colors, minimal tokens, maximum clarity
 func FormatDiagnosticsForLLM(diagnostics []Diagnostic) string {
@@ -289,15 +302,7 @@ func FormatDiagnostics(diagnostics []Diagnostic) string {
 
 		if d.File != "" {
 			sb.WriteString("  at ")
-			sb.WriteString(d.File)
-			if d.Line > 0 {
-				sb.WriteString(":")
-				sb.WriteString(intToStr(d.Line))
-				if d.Column > 0 {
-					sb.WriteString(":")
-					sb.WriteString(intToStr(d.Column))
-				}
-			}
+			sb.WriteString(fileLocation(d.File, d.Line, d.Column))
 			sb.WriteString("\n")
 		}
 
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// containerSourceDir is where the validator mounts the code inside the container
+const containerSourceDir = "/src/"
+
+// containerPathPattern matches a mounted file's path in tool output (not /usr/src/...)
+var containerPathPattern = regexp.MustCompile(`(?m)(?:^|[\s(\['"])` + containerSourceDir + `[\w.+-]+`)
+
+// PathMap maps the file names code is validated under back to where the files live in the
+// workspace, so diagnostics point at files the user can open instead of /src/code.cpp
+type PathMap struct {
+	Dir   string            // Directory of files not listed in Files ("" leaves them unmapped)
+	Files map[string]string // Name in the container -> workspace path
+}
+
+// Resolve returns the workspace path for a file named in a diagnostic
+// Files that are not mapped keep their name without the container directory
+func (p PathMap) Resolve(file string) string {
+	if file == "" {
+		return ""
+	}
+	name := strings.TrimPrefix(filepath.ToSlash(file), containerSourceDir)
+	if strings.Contains(name, "/") {
+		return file // A system header or a path outside the mounted code
+	}
+	if path, ok := p.Files[name]; ok {
+		return path
+	}
+	if p.Dir != "" {
+		return filepath.Join(p.Dir, name)
+	}
+	return name
+}
+
+// Rewrite returns diagnostics with their files, and the files in their stack traces, resolved
+func (p PathMap) Rewrite(diags []Diagnostic) []Diagnostic {
+	out := make([]Diagnostic, len(diags))
+	for i, d := range diags {
+		d.File = p.Resolve(d.File)
+		d.Context = p.RewriteText(d.Context)
+		out[i] = d
+	}
+	return out
+}
+
+// RewriteText replaces the container paths of the mounted files in raw tool output
+func (p PathMap) RewriteText(text string) string {
+	if !strings.Contains(text, containerSourceDir) {
+		return text
+	}
+	return containerPathPattern.ReplaceAllStringFunc(text, func(match string) string {
+		i := strings.Index(match, containerSourceDir)
+		return match[:i] + p.Resolve(match[i:])
+	})
+}
+
+// hyperlinksEnabled reports whether terminal output may carry OSC 8 hyperlinks
+// BJARNE_HYPERLINKS=1 or 0 forces them on or off; otherwise they need a terminal
+// on stdout that is not "dumb" and NO_COLOR unset
+func hyperlinksEnabled() bool {
+	switch os.Getenv("BJARNE_HYPERLINKS") {
+	case "1", "true":
+		return true
+	case "0", "false":
+		return false
+	}
+	if colorDisabled() || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// fileURL returns the file:// URL of a path that exists on disk
+func fileURL(path string) (string, bool) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+	if _, err := os.Stat(abs); err != nil {
+		return "", false
+	}
+	slashed := filepath.ToSlash(abs)
+	if !strings.HasPrefix(slashed, "/") {
+		slashed = "/" + slashed // C:/dir on Windows
+	}
+	return (&url.URL{Scheme: "file", Path: slashed}).String(), true
+}
+
+// fileLocation renders path:line:col (line and column when known), as a clickable
+// OSC 8 hyperlink to the file when the terminal supports it and the file exists
+func fileLocation(path string, line, col int) string {
+	loc := path
+	if line > 0 {
+		loc += fmt.Sprintf(":%d", line)
+		if col > 0 {
+			loc += fmt.Sprintf(":%d", col)
+		}
+	}
+	if !hyperlinksEnabled() {
+		return loc
+	}
+	target, ok := fileURL(path)
+	if !ok {
+		return loc
+	}
+	return osc8Link(target, loc)
+}
+
+// osc8Link wraps text in an OSC 8 terminal hyperlink to target
+func osc8Link(target, text string) string {
+	return "\033]8;;" + target + "\033\\" + text + "\033]8;;\033\\"
+}
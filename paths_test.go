@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPathMapResolve(t *testing.T) {
+	tests := []struct {
+		name  string
+		paths PathMap
+		file  string
+		want  string
+	}{
+		{"unmapped container file", PathMap{}, "/src/code.cpp", "code.cpp"},
+		{"saved file", PathMap{Files: map[string]string{"code.cpp": "lru.cpp"}}, "/src/code.cpp", "lru.cpp"},
+		{"directory", PathMap{Dir: filepath.Join("src", "cache")}, "/src/lru.h", filepath.Join("src", "cache", "lru.h")},
+		{"listed file wins over directory", PathMap{Dir: "out", Files: map[string]string{"a.cpp": "a.cc"}}, "/src/a.cpp", "a.cc"},
+		{"bare name", PathMap{Dir: "out"}, "main.cpp", filepath.Join("out", "main.cpp")},
+		{"system header", PathMap{Dir: "out"}, "/usr/include/c++/13/vector", "/usr/include/c++/13/vector"},
+		{"no file", PathMap{Dir: "out"}, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.paths.Resolve(tt.file); got != tt.want {
+				t.Errorf("Resolve(%q) = %q, want %q", tt.file, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPathMapRewrite(t *testing.T) {
+	paths := PathMap{Files: map[string]string{"code.cpp": "lru.cpp"}}
+	diags := paths.Rewrite([]Diagnostic{{
+		File:    "/src/code.cpp",
+		Line:    3,
+		Context: "    #0 0x4f2 in main /src/code.cpp:7:3\n    #1 0x7f in __libc_start_main /usr/src/glibc/csu/libc-start.c:308",
+	}})
+	if diags[0].File != "lru.cpp" {
+		t.Errorf("File = %q, want lru.cpp", diags[0].File)
+	}
+	if !strings.Contains(diags[0].Context, "in main lru.cpp:7:3") || !strings.Contains(diags[0].Context, "/usr/src/glibc") {
+		t.Errorf("Context = %q, want the project frame mapped and the libc frame untouched", diags[0].Context)
+	}
+
+	got := paths.RewriteText("/src/code.cpp:4:1: error: expected ';'")
+	if got != "lru.cpp:4:1: error: expected ';'" {
+		t.Errorf("RewriteText() = %q", got)
+	}
+}
+
+func TestFileLocation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "lru.cpp")
+	if err := os.WriteFile(path, []byte("int main() {}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("BJARNE_HYPERLINKS", "0")
+	if got := fileLocation(path, 12, 5); got != path+":12:5" {
+		t.Errorf("fileLocation() without hyperlinks = %q", got)
+	}
+
+	t.Setenv("BJARNE_HYPERLINKS", "1")
+	got := fileLocation(path, 12, 0)
+	if !strings.HasPrefix(got, "\033]8;;file://") || !strings.Contains(got, "\033\\"+path+":12\033]8;;\033\\") {
+		t.Errorf("fileLocation() = %q, want an OSC 8 link around %s:12", got, path)
+	}
+	if got := fileLocation(filepath.Join(dir, "missing.cpp"), 1, 0); strings.Contains(got, "\033]8") {
+		t.Errorf("fileLocation() linked a missing file: %q", got)
+	}
+}
+
+func TestFindingPath(t *testing.T) {
+	f := GateFinding{File: "lru.h", Line: 3}
+	if got := (ciFileResult{Path: "src/cache/lru.cpp"}).FindingPath(f); got != "src/cache/lru.h" {
+		t.Errorf("FindingPath() = %q, want src/cache/lru.h", got)
+	}
+	if got := (ciFileResult{Path: stdinName}).FindingPath(f); got != "lru.h" {
+		t.Errorf("FindingPath() for piped code = %q, want lru.h", got)
+	}
+}
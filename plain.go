@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// plainMode is accessibility mode (--plain or BJARNE_NO_COLOR=1): no color, box drawing or
+// spinners, and state changes and progress are announced as sentences for screen readers and logs
+var plainMode bool
+
+// plainProgressInterval is how often plain mode reports that a long step is still running
+const plainProgressInterval = 10 * time.Second
+
+// parsePlainFlag removes --plain from args and reports whether plain mode is on
+// BJARNE_NO_COLOR=1 enables it as well
+func parsePlainFlag(args []string) ([]string, bool) {
+	plain := os.Getenv("BJARNE_NO_COLOR") == "1" || strings.EqualFold(os.Getenv("BJARNE_NO_COLOR"), "true")
+	rest := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == "--plain" {
+			plain = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return rest, plain
+}
+
+// applyPlainMode swaps box drawing for plain text and filters color from all output
+func applyPlainMode() {
+	boxTopLeft, boxTopRight, boxBottomLeft, boxBottomRight = "", "", "", ""
+	boxHorizontal, boxVertical = "", ""
+	treeVert, treeBranch, treeEnd = " ", "-", "-"
+	startPlainOutput()
+}
+
+// plainStripPattern matches what plain mode removes: color/style (SGR) sequences and OSC 8
+// hyperlinks (the link text stays). Cursor movement is kept so the TUI can still redraw its input line
+var plainStripPattern = regexp.MustCompile(`\x1b\[[0-9;:]*m|\x1b\]8;[^\x07\x1b]*(?:\x07|\x1b\\)`)
+
+// plainWriter strips color from everything written through it
+// An escape sequence split across writes is held back until it is complete
+type plainWriter struct {
+	w       io.Writer
+	pending []byte
+}
+
+func (p *plainWriter) Write(b []byte) (int, error) {
+	data := append(p.pending, b...)
+	p.pending = nil
+	if i := incompleteEscape(data); i >= 0 {
+		p.pending = append([]byte{}, data[i:]...)
+		data = data[:i]
+	}
+	if _, err := p.w.Write(plainStripPattern.ReplaceAll(data, nil)); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Flush writes whatever was held back
+func (p *plainWriter) Flush() error {
+	_, err := p.w.Write(p.pending)
+	p.pending = nil
+	return err
+}
+
+// incompleteEscape returns where an unfinished escape sequence starts at the end of data, or -1
+func incompleteEscape(data []byte) int {
+	// An OSC sequence runs to BEL or ESC \, and may itself contain the ESC of its terminator
+	if i := bytes.LastIndex(data, []byte("\x1b]")); i >= 0 {
+		rest := data[i+2:]
+		if bytes.IndexByte(rest, '\a') < 0 && !bytes.Contains(rest, []byte("\x1b\\")) {
+			return i
+		}
+	}
+	i := bytes.LastIndexByte(data, 0x1b)
+	switch {
+	case i < 0:
+		return -1
+	case i == len(data)-1:
+		return i
+	case data[i+1] != '[':
+		return -1 // Two-byte sequence (or a terminator), already complete
+	}
+	for _, c := range data[i+2:] {
+		if c >= 0x40 && c <= 0x7e {
+			return -1 // CSI final byte
+		}
+	}
+	return i
+}
+
+// plainOutput is a stream that plain mode filters, and its real file
+type plainOutput struct {
+	pipe *os.File
+	real *os.File
+	done chan struct{}
+}
+
+var plainOutputs []plainOutput
+
+// startPlainOutput sends stdout and stderr through plainWriter, so color written anywhere
+// (including fmt.Print calls with their own escape codes) never reaches the terminal or a log
+func startPlainOutput() {
+	for _, file := range []**os.File{&os.Stdout, &os.Stderr} {
+		r, w, err := os.Pipe()
+		if err != nil {
+			continue // Unfiltered output is still usable
+		}
+		out := plainOutput{pipe: w, real: *file, done: make(chan struct{})}
+		*file = w
+		go func() {
+			pw := &plainWriter{w: out.real}
+			_, _ = io.Copy(pw, r)
+			_ = pw.Flush()
+			close(out.done)
+		}()
+		plainOutputs = append(plainOutputs, out)
+	}
+}
+
+// stopPlainOutput drains the filtered streams and restores the real ones, before exiting
+func stopPlainOutput() {
+	for _, out := range plainOutputs {
+		_ = out.pipe.Close()
+		<-out.done
+		if os.Stdout == out.pipe {
+			os.Stdout = out.real
+		}
+		if os.Stderr == out.pipe {
+			os.Stderr = out.real
+		}
+	}
+	plainOutputs = nil
+}
+
+// terminalOutput returns the terminal's stdout and stderr, for programs (the editor) that
+// need a real terminal rather than plain mode's filter
+func terminalOutput() (stdout, stderr *os.File) {
+	stdout, stderr = os.Stdout, os.Stderr
+	for _, out := range plainOutputs {
+		if out.pipe == stdout {
+			stdout = out.real
+		}
+		if out.pipe == stderr {
+			stderr = out.real
+		}
+	}
+	return stdout, stderr
+}
+
+// exit ends the program with code, first stopping containers still running a stage and
+// flushing plain mode's output
+func exit(code int) {
+	stopRunningContainers()
+	stopLogging()
+	stopPlainOutput()
+	os.Exit(code)
+}
+
+// stateSentences announce each TUI state in plain mode
+var stateSentences = map[State]string{
+	StateInput:         "Ready for input.",
+	StateClassifying:   "Classifying the request.",
+	StateThinking:      "Analyzing the request.",
+	StateDefiningDone:  "Drafting the definition of done.",
+	StateAcknowledging: "Reading your answer.",
+	StatePlanning:      "Planning the files.",
+	StateGenerating:    "Generating code.",
+	StateValidating:    "Validating the code.",
+	StateFixing:        "Fixing the failures.",
+	StateReviewing:     "Reviewing the code.",
+	StateRevealing:     "Showing the validated code.",
+	StateApproving:     "Validation passed. Press a to approve, r to regenerate, e to edit the prompt, or Escape to discard.",
+	StatePullingImage:  "Updating the validator image.",
+	StateCompacting:    "Compacting the conversation.",
+	StateConfirmSave:   "Some files already exist. Press y to overwrite them or n to cancel.",
+	StateFillTemplate:  "Filling in the template. Type each value and press Enter, or Escape to cancel.",
+	StateSettings:      "Settings form. Up and Down choose a setting, Enter edits it, Escape saves and closes.",
+}
+
+// reportsProgress reports whether plain mode announces that a state is still running
+func reportsProgress(state State) bool {
+	switch state {
+	case StateClassifying, StateThinking, StateDefiningDone, StateAcknowledging, StatePlanning,
+		StateGenerating, StateValidating, StateFixing, StateReviewing, StatePullingImage, StateCompacting:
+		return true
+	}
+	return false
+}
+
+// progressSentence reports that a state is still running after elapsed, e.g. "Still generating code (20 seconds)."
+func progressSentence(state State, elapsed time.Duration) string {
+	doing := strings.TrimSuffix(stateSentences[state], ".")
+	if doing == "" {
+		doing = "Working"
+	}
+	return fmt.Sprintf("Still %s%s (%d seconds).", strings.ToLower(doing[:1]), doing[1:], int(elapsed.Seconds()))
+}
+
+// gateSentence announces a validation gate starting (nil result) or finishing
+func gateSentence(stage string, result *ValidationResult) string {
+	switch {
+	case result == nil:
+		return fmt.Sprintf("Running the %s gate.", stage)
+	case result.Success:
+		return fmt.Sprintf("The %s gate passed in %.1f seconds.", stage, result.Duration.Seconds())
+	default:
+		return fmt.Sprintf("The %s gate failed in %.1f seconds.", stage, result.Duration.Seconds())
+	}
+}
+
+// rule returns a horizontal separator line, which plain mode leaves out
+func rule() string {
+	if plainMode {
+		return ""
+	}
+	return strings.Repeat("=", 80)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePlainFlag(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       string
+		args      []string
+		wantRest  string
+		wantPlain bool
+	}{
+		{"off", "", []string{"-v", "a.cpp"}, "-v a.cpp", false},
+		{"flag", "", []string{"--plain", "doctor"}, "doctor", true},
+		{"env", "1", []string{"doctor"}, "doctor", true},
+		{"env true", "TRUE", nil, "", true},
+		{"env other", "0", nil, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("BJARNE_NO_COLOR", tt.env)
+			rest, plain := parsePlainFlag(tt.args)
+			if strings.Join(rest, " ") != tt.wantRest || plain != tt.wantPlain {
+				t.Errorf("parsePlainFlag(%v) = %v, %v", tt.args, rest, plain)
+			}
+		})
+	}
+}
+
+func TestPlainWriter(t *testing.T) {
+	link := "\x1b]8;;file:///src/a.cpp\x1b\\a.cpp:3\x1b]8;;\x1b\\"
+	tests := []struct {
+		name   string
+		writes []string
+		want   string
+	}{
+		{"colors", []string{"\x1b[92m✓\x1b[0m ok \x1b[38;2;1;2;3mtrue\x1b[0m"}, "✓ ok true"},
+		{"hyperlink keeps its text", []string{link}, "a.cpp:3"},
+		{"cursor movement is kept", []string{"\x1b[2K\x1b[1Aline"}, "\x1b[2K\x1b[1Aline"},
+		{"split color", []string{"a\x1b[", "9", "1mb\x1b", "[0m"}, "ab"},
+		{"split hyperlink", []string{link[:10], link[10:20], link[20:]}, "a.cpp:3"},
+		{"held back until flushed", []string{"a\x1b[3"}, "a\x1b[3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			w := &plainWriter{w: &out}
+			for _, s := range tt.writes {
+				if n, err := w.Write([]byte(s)); err != nil || n != len(s) {
+					t.Fatalf("Write(%q) = %d, %v", s, n, err)
+				}
+			}
+			if err := w.Flush(); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("output = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestProgressSentence(t *testing.T) {
+	if got := progressSentence(StateGenerating, 20*time.Second); got != "Still generating code (20 seconds)." {
+		t.Errorf("progressSentence = %q", got)
+	}
+	if got := progressSentence(State(-1), 10*time.Second); got != "Still working (10 seconds)." {
+		t.Errorf("progressSentence for an unknown state = %q", got)
+	}
+	for state := range stateSentences {
+		if reportsProgress(state) && !strings.HasSuffix(stateSentences[state], ".") {
+			t.Errorf("state %d sentence %q is not a sentence", state, stateSentences[state])
+		}
+	}
+	if reportsProgress(StateInput) || reportsProgress(StateApproving) || !reportsProgress(StateValidating) {
+		t.Error("only busy states should report progress")
+	}
+}
+
+func TestGateSentence(t *testing.T) {
+	tests := []struct {
+		result *ValidationResult
+		want   string
+	}{
+		{nil, "Running the asan gate."},
+		{&ValidationResult{Success: true, Duration: 1500 * time.Millisecond}, "The asan gate passed in 1.5 seconds."},
+		{&ValidationResult{Duration: 12 * time.Second}, "The asan gate failed in 12.0 seconds."},
+	}
+	for _, tt := range tests {
+		if got := gateSentence("asan", tt.result); got != tt.want {
+			t.Errorf("gateSentence = %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// PlanManifestPrompt asks for a file manifest instead of code
+const PlanManifestPrompt = `Before writing any code, plan the project as a set of files.
+
+List every file in this exact format, one block per file, headers before the files that use them:
+
+FILE: <filename>
+PURPOSE: <one line describing what the file is responsible for>
+DEPENDS: <comma-separated filenames from this list it includes, or none>
+
+Rules:
+- Use flat filenames (no directories) with .h/.hpp for headers and .cpp for sources
+- Exactly one file defines main()
+- Keep the project small: only the files the request needs
+- Output ONLY the blocks, no code and no commentary`
+
+// ScaffoldFilePromptTemplate generates one planned file with the files written so far
+// Args: manifest, filename, purpose, files written so far
+const ScaffoldFilePromptTemplate = `We are building the project one file at a time from this plan:
+
+%s
+
+Write %s now. Its responsibility: %s
+
+Files written so far (their interfaces are fixed; use them exactly as declared):
+%s
+
+Return ONLY %[2]s, complete, in a single cpp block whose first line is
+// FILE: %[2]s`
+
+// ScaffoldRetryPromptTemplate asks for a planned file again after its syntax check failed
+// Args: filename, errors
+const ScaffoldRetryPromptTemplate = `%s did not compile:
+
+%s
+
+Fix it and return ONLY that file in a single cpp block whose first line is
+// FILE: %[1]s`
+
+// maxScaffoldRetries bounds rewrites of a file that fails its syntax check during scaffolding
+const maxScaffoldRetries = 2
+
+// PlannedFile is one entry in a project manifest
+type PlannedFile struct {
+	Path      string
+	Purpose   string
+	DependsOn []string
+}
+
+// ProjectPlan is the file manifest drafted before generating a COMPLEX project
+type ProjectPlan struct {
+	Files []PlannedFile
+}
+
+// ParseProjectPlan extracts FILE/PURPOSE/DEPENDS blocks from a manifest response
+// Returns nil when the response contains no files
+func ParseProjectPlan(text string) *ProjectPlan {
+	plan := &ProjectPlan{}
+	var current *PlannedFile
+
+	for _, line := range strings.Split(text, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(strings.Trim(line, "*-` \t")), ":")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), "`*")
+
+		switch strings.ToUpper(strings.Trim(key, "*` ")) {
+		case "FILE":
+			name := cleanPlanPath(value)
+			if name == "" || plan.index(name) >= 0 {
+				current = nil
+				continue
+			}
+			plan.Files = append(plan.Files, PlannedFile{Path: name})
+			current = &plan.Files[len(plan.Files)-1]
+		case "PURPOSE":
+			if current != nil {
+				current.Purpose = value
+			}
+		case "DEPENDS", "DEPENDS ON":
+			if current != nil {
+				current.DependsOn = parsePlanDeps(value)
+			}
+		}
+	}
+
+	if len(plan.Files) == 0 {
+		return nil
+	}
+	return plan
+}
+
+// cleanPlanPath reduces a planned filename to a flat name (the validator mounts one directory)
+func cleanPlanPath(name string) string {
+	name = strings.Trim(strings.TrimSpace(name), "`\"'")
+	if name == "" {
+		return ""
+	}
+	return path.Base(strings.ReplaceAll(name, "\\", "/"))
+}
+
+// parsePlanDeps splits a DEPENDS value; "none" or "-" means no dependencies
+func parsePlanDeps(value string) []string {
+	var deps []string
+	for _, dep := range strings.Split(value, ",") {
+		dep = cleanPlanPath(dep)
+		switch strings.ToLower(dep) {
+		case "", "none", "-", "n/a", ".":
+			continue
+		}
+		deps = append(deps, dep)
+	}
+	return deps
+}
+
+// index returns the position of a file in the plan, or -1
+func (p *ProjectPlan) index(name string) int {
+	for i, f := range p.Files {
+		if f.Path == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Order returns the files with each one after the files it depends on
+// Dependencies outside the plan are ignored; cycles keep manifest order
+func (p *ProjectPlan) Order() []PlannedFile {
+	placed := make(map[string]bool, len(p.Files))
+	visiting := make(map[string]bool)
+	ordered := make([]PlannedFile, 0, len(p.Files))
+
+	var visit func(i int)
+	visit = func(i int) {
+		f := p.Files[i]
+		if placed[f.Path] || visiting[f.Path] {
+			return
+		}
+		visiting[f.Path] = true
+		for _, dep := range f.DependsOn {
+			if j := p.index(dep); j >= 0 {
+				visit(j)
+			}
+		}
+		visiting[f.Path] = false
+		placed[f.Path] = true
+		ordered = append(ordered, f)
+	}
+
+	for i := range p.Files {
+		visit(i)
+	}
+	return ordered
+}
+
+// Add appends a file to the plan
+func (p *ProjectPlan) Add(name, purpose string) error {
+	name = cleanPlanPath(name)
+	if name == "" {
+		return fmt.Errorf("usage: /plan add <file> [purpose]")
+	}
+	if p.index(name) >= 0 {
+		return fmt.Errorf("%s is already in the plan", name)
+	}
+	p.Files = append(p.Files, PlannedFile{Path: name, Purpose: purpose})
+	return nil
+}
+
+// Remove deletes file n (1-based) and any dependencies on it
+func (p *ProjectPlan) Remove(n int) error {
+	if n < 1 || n > len(p.Files) {
+		return fmt.Errorf("no file #%d (plan has %d)", n, len(p.Files))
+	}
+	removed := p.Files[n-1].Path
+	p.Files = append(p.Files[:n-1], p.Files[n:]...)
+	for i := range p.Files {
+		var deps []string
+		for _, dep := range p.Files[i].DependsOn {
+			if dep != removed {
+				deps = append(deps, dep)
+			}
+		}
+		p.Files[i].DependsOn = deps
+	}
+	return nil
+}
+
+// SetDeps replaces the dependencies of file n (1-based)
+func (p *ProjectPlan) SetDeps(n int, value string) error {
+	if n < 1 || n > len(p.Files) {
+		return fmt.Errorf("no file #%d (plan has %d)", n, len(p.Files))
+	}
+	deps := parsePlanDeps(value)
+	for _, dep := range deps {
+		if dep == p.Files[n-1].Path {
+			return fmt.Errorf("%s cannot depend on itself", dep)
+		}
+	}
+	p.Files[n-1].DependsOn = deps
+	return nil
+}
+
+// SetPurpose replaces the responsibility of file n (1-based)
+func (p *ProjectPlan) SetPurpose(n int, purpose string) error {
+	if n < 1 || n > len(p.Files) {
+		return fmt.Errorf("no file #%d (plan has %d)", n, len(p.Files))
+	}
+	p.Files[n-1].Purpose = purpose
+	return nil
+}
+
+// Manifest renders the plan in the format the LLM was asked to produce
+func (p *ProjectPlan) Manifest() string {
+	var sb strings.Builder
+	for i, f := range p.Files {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		deps := "none"
+		if len(f.DependsOn) > 0 {
+			deps = strings.Join(f.DependsOn, ", ")
+		}
+		fmt.Fprintf(&sb, "FILE: %s\nPURPOSE: %s\nDEPENDS: %s\n", f.Path, f.Purpose, deps)
+	}
+	return sb.String()
+}
+
+// FormatTable renders the plan as numbered lines for display
+func (p *ProjectPlan) FormatTable() []string {
+	width := 0
+	for _, f := range p.Files {
+		if len(f.Path) > width {
+			width = len(f.Path)
+		}
+	}
+
+	var lines []string
+	for i, f := range p.Files {
+		line := fmt.Sprintf("%2d. %-*s  %s", i+1, width, f.Path, f.Purpose)
+		if len(f.DependsOn) > 0 {
+			line += " (uses " + strings.Join(f.DependsOn, ", ") + ")"
+		}
+		lines = append(lines, strings.TrimRight(line, " "))
+	}
+	return lines
+}
+
+// FilePrompt builds the generation prompt for one planned file given the files written so far
+func (p *ProjectPlan) FilePrompt(f PlannedFile, written []CodeFile) string {
+	soFar := "(none yet)"
+	if len(written) > 0 {
+		soFar = formatFilesForPrompt(written)
+	}
+	purpose := f.Purpose
+	if purpose == "" {
+		purpose = "as described in the plan"
+	}
+	return fmt.Sprintf(ScaffoldFilePromptTemplate, p.Manifest(), f.Path, purpose, soFar)
+}
+
+// scaffoldedFile picks the planned file out of a generation response
+// Falls back to the only code block when it is unnamed or named differently
+func scaffoldedFile(response, name string) (CodeFile, bool) {
+	files := extractMultipleFiles(response)
+	for _, f := range files {
+		if f.Filename == name {
+			return f, true
+		}
+	}
+	if len(files) == 1 {
+		return CodeFile{Filename: name, Content: files[0].Content}, true
+	}
+	return CodeFile{}, false
+}
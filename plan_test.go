@@ -0,0 +1,164 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseProjectPlan(t *testing.T) {
+	response := `Here is the plan:
+
+**FILE:** src/tokenizer.h
+**PURPOSE:** Token types and the Tokenizer interface
+**DEPENDS:** none
+
+FILE: tokenizer.cpp
+PURPOSE: Splits input into tokens
+DEPENDS: tokenizer.h
+
+FILE: main.cpp
+PURPOSE: Reads expressions and prints results
+DEPENDS: tokenizer.h, parser.h
+
+FILE: tokenizer.cpp
+PURPOSE: duplicate entry`
+
+	plan := ParseProjectPlan(response)
+	if plan == nil {
+		t.Fatal("ParseProjectPlan() = nil")
+	}
+	if len(plan.Files) != 3 {
+		t.Fatalf("got %d files, want 3: %+v", len(plan.Files), plan.Files)
+	}
+	if plan.Files[0].Path != "tokenizer.h" || len(plan.Files[0].DependsOn) != 0 {
+		t.Errorf("file 1 = %+v, want flat tokenizer.h with no deps", plan.Files[0])
+	}
+	if got := strings.Join(plan.Files[2].DependsOn, ","); got != "tokenizer.h,parser.h" {
+		t.Errorf("main.cpp deps = %q", got)
+	}
+	if plan.Files[1].Purpose != "Splits input into tokens" {
+		t.Errorf("duplicate FILE overwrote purpose: %q", plan.Files[1].Purpose)
+	}
+
+	if ParseProjectPlan("Sure, here's the code:\n```cpp\nint main() {}\n```") != nil {
+		t.Error("ParseProjectPlan() should be nil without FILE entries")
+	}
+}
+
+func TestProjectPlanOrder(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []PlannedFile
+		want  string
+	}{
+		{
+			"dependencies first",
+			[]PlannedFile{
+				{Path: "main.cpp", DependsOn: []string{"stack.h", "parser.h"}},
+				{Path: "parser.h", DependsOn: []string{"stack.h"}},
+				{Path: "stack.h"},
+			},
+			"stack.h,parser.h,main.cpp",
+		},
+		{
+			"unknown dependency ignored",
+			[]PlannedFile{{Path: "main.cpp", DependsOn: []string{"vector"}}, {Path: "util.h"}},
+			"main.cpp,util.h",
+		},
+		{
+			"cycle keeps every file once",
+			[]PlannedFile{{Path: "a.h", DependsOn: []string{"b.h"}}, {Path: "b.h", DependsOn: []string{"a.h"}}},
+			"b.h,a.h",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := &ProjectPlan{Files: tt.files}
+			var names []string
+			for _, f := range plan.Order() {
+				names = append(names, f.Path)
+			}
+			if got := strings.Join(names, ","); got != tt.want {
+				t.Errorf("Order() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProjectPlanEdits(t *testing.T) {
+	plan := &ProjectPlan{Files: []PlannedFile{
+		{Path: "queue.h", Purpose: "Queue declaration"},
+		{Path: "main.cpp", Purpose: "Demo", DependsOn: []string{"queue.h"}},
+	}}
+
+	if err := plan.Add("queue.cpp", "Queue implementation"); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := plan.Add("queue.h", ""); err == nil {
+		t.Error("Add() should reject a duplicate file")
+	}
+	if err := plan.SetDeps(3, "queue.h"); err != nil {
+		t.Fatalf("SetDeps() error = %v", err)
+	}
+	if err := plan.SetDeps(3, "queue.cpp"); err == nil {
+		t.Error("SetDeps() should reject a self-dependency")
+	}
+	if err := plan.Remove(1); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	for _, f := range plan.Files {
+		if len(f.DependsOn) != 0 {
+			t.Errorf("%s still depends on removed file: %v", f.Path, f.DependsOn)
+		}
+	}
+	if err := plan.Remove(5); err == nil {
+		t.Error("Remove() should reject an out-of-range index")
+	}
+
+	table := plan.FormatTable()
+	if len(table) != 2 || !strings.HasPrefix(table[1], " 2. queue.cpp") {
+		t.Errorf("FormatTable() = %q", table)
+	}
+}
+
+func TestProjectPlanFilePrompt(t *testing.T) {
+	plan := &ProjectPlan{Files: []PlannedFile{
+		{Path: "stack.h", Purpose: "Fixed-capacity stack"},
+		{Path: "main.cpp", Purpose: "Exercises the stack", DependsOn: []string{"stack.h"}},
+	}}
+	written := []CodeFile{{Filename: "stack.h", Content: "template <typename T> class Stack;"}}
+
+	prompt := plan.FilePrompt(plan.Files[1], written)
+	for _, want := range []string{"DEPENDS: stack.h", "Write main.cpp now", "// FILE: stack.h\ntemplate", "// FILE: main.cpp"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("FilePrompt() missing %q", want)
+		}
+	}
+}
+
+func TestScaffoldedFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		ok       bool
+		content  string
+	}{
+		{"named block", "```cpp\n// FILE: stack.h\n#pragma once\n```", true, "#pragma once"},
+		{"unnamed block", "```cpp\n#pragma once\n```", true, "#pragma once"},
+		{"other files only", "```cpp\n// FILE: a.h\nint a;\n```\n```cpp\n// FILE: b.h\nint b;\n```", false, ""},
+		{"no code", "I need more detail.", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, ok := scaffoldedFile(tt.response, "stack.h")
+			if ok != tt.ok {
+				t.Fatalf("scaffoldedFile() ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && (f.Filename != "stack.h" || f.Content != tt.content) {
+				t.Errorf("scaffoldedFile() = %+v", f)
+			}
+		})
+	}
+}
@@ -158,6 +158,9 @@ func (e *PodmanNotFoundError) Error() string {
 
 // downloadPodmanInstaller downloads the Podman installer to a temporary location
 func downloadPodmanInstaller(ctx context.Context, url string, progressFn func(string)) error {
+	if offlineMode {
+		return errOffline
+	}
 	if progressFn != nil {
 		progressFn(fmt.Sprintf("Downloading from %s...", url))
 	}
@@ -177,7 +180,11 @@ func downloadPodmanInstaller(ctx context.Context, url string, progressFn func(st
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	client, err := NewHTTPClient(endpointDownloads, 0)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("download failed: %w", err)
 	}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// projectRulesFiles are checked in order at the workspace root
+var projectRulesFiles = []string{"BJARNE.md", filepath.Join(".bjarne", "rules.md")}
+
+// maxProjectRulesChars bounds how much of the rules file is sent with each prompt
+const maxProjectRulesChars = 8000
+
+// ProjectRules is project-specific guidance (conventions, banned libraries,
+// architectural constraints) injected into the reflection and generation prompts
+type ProjectRules struct {
+	Path      string // Path relative to the workspace root
+	Content   string
+	Truncated bool
+}
+
+// LoadProjectRules reads the first rules file found in root
+// Returns nil (and no error) when the project has none
+func LoadProjectRules(root string) (*ProjectRules, error) {
+	for _, name := range projectRulesFiles {
+		data, err := os.ReadFile(filepath.Join(root, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+
+		content := strings.TrimSpace(string(data))
+		if content == "" {
+			return nil, nil
+		}
+		rules := &ProjectRules{Path: filepath.ToSlash(name), Content: content}
+		if len(content) > maxProjectRulesChars {
+			cut := strings.LastIndex(content[:maxProjectRulesChars], "\n")
+			if cut <= 0 {
+				cut = maxProjectRulesChars
+			}
+			rules.Content = content[:cut]
+			rules.Truncated = true
+		}
+		return rules, nil
+	}
+	return nil, nil
+}
+
+// PromptSection formats the rules for appending to a system prompt
+func (r *ProjectRules) PromptSection() string {
+	if r == nil {
+		return ""
+	}
+	section := fmt.Sprintf("PROJECT RULES (from %s) - these take precedence over general guidance:\n%s", r.Path, r.Content)
+	if r.Truncated {
+		section += "\n[rules truncated]"
+	}
+	return section
+}
+
+// Status describes the loaded rules for the splash and /prompts
+func (r *ProjectRules) Status() string {
+	if r == nil {
+		return ""
+	}
+	status := fmt.Sprintf("%s (%d lines)", r.Path, strings.Count(r.Content, "\n")+1)
+	if r.Truncated {
+		status += " [truncated]"
+	}
+	return status
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadProjectRules(t *testing.T) {
+	long := strings.Repeat("- prefer std::span over pointer/length pairs\n", maxProjectRulesChars/40)
+
+	tests := []struct {
+		name          string
+		files         map[string]string
+		wantPath      string
+		wantTruncated bool
+	}{
+		{"none", nil, "", false},
+		{"BJARNE.md", map[string]string{"BJARNE.md": "No exceptions."}, "BJARNE.md", false},
+		{"rules.md", map[string]string{".bjarne/rules.md": "No RTTI."}, ".bjarne/rules.md", false},
+		{"BJARNE.md wins", map[string]string{"BJARNE.md": "a", ".bjarne/rules.md": "b"}, "BJARNE.md", false},
+		{"empty", map[string]string{"BJARNE.md": "  \n"}, "", false},
+		{"truncated", map[string]string{"BJARNE.md": long}, "BJARNE.md", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			for name, content := range tt.files {
+				path := filepath.Join(root, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			rules, err := LoadProjectRules(root)
+			if err != nil {
+				t.Fatalf("LoadProjectRules() error = %v", err)
+			}
+			if tt.wantPath == "" {
+				if rules != nil {
+					t.Errorf("LoadProjectRules() = %+v, want nil", rules)
+				}
+				return
+			}
+			if rules == nil || rules.Path != tt.wantPath || rules.Truncated != tt.wantTruncated {
+				t.Fatalf("LoadProjectRules() = %+v, want path %q truncated %v", rules, tt.wantPath, tt.wantTruncated)
+			}
+			if len(rules.Content) > maxProjectRulesChars {
+				t.Errorf("content length %d exceeds limit %d", len(rules.Content), maxProjectRulesChars)
+			}
+			if !strings.Contains(rules.PromptSection(), "PROJECT RULES (from "+tt.wantPath+")") {
+				t.Errorf("PromptSection() = %q", rules.PromptSection())
+			}
+		})
+	}
+}
+
+func TestProjectRulesNil(t *testing.T) {
+	var rules *ProjectRules
+	if rules.PromptSection() != "" || rules.Status() != "" {
+		t.Error("nil ProjectRules should render empty")
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxPromptHistory caps the number of prompts kept in ~/.bjarne/prompt_history
+const maxPromptHistory = 1000
+
+// promptHistory provides Up/Down recall of previous prompts, persisted across sessions
+// Entries are stored one JSON string per line so multi-line prompts survive
+type promptHistory struct {
+	path    string
+	entries []string
+	pos     int    // Index into entries while browsing (len(entries) = not browsing)
+	draft   string // Unsent input saved when browsing starts
+}
+
+// promptHistoryPath returns ~/.bjarne/prompt_history
+func promptHistoryPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".bjarne", "prompt_history"), nil
+}
+
+// loadPromptHistory reads history from path (empty path = in-memory only)
+// A missing or unreadable file yields an empty history
+func loadPromptHistory(path string) *promptHistory {
+	h := &promptHistory{path: path}
+	if path == "" {
+		return h
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return h
+	}
+	defer func() { _ = f.Close() }()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry string
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || entry == "" {
+			continue
+		}
+		h.entries = append(h.entries, entry)
+	}
+	if len(h.entries) > maxPromptHistory {
+		h.entries = h.entries[len(h.entries)-maxPromptHistory:]
+	}
+	h.pos = len(h.entries)
+	return h
+}
+
+// Add records a submitted prompt and persists it (consecutive duplicates are skipped)
+func (h *promptHistory) Add(entry string) {
+	defer h.Reset()
+
+	entry = strings.TrimSpace(entry)
+	if entry == "" || (len(h.entries) > 0 && h.entries[len(h.entries)-1] == entry) {
+		return
+	}
+
+	h.entries = append(h.entries, entry)
+	if len(h.entries) > maxPromptHistory {
+		h.entries = h.entries[len(h.entries)-maxPromptHistory:]
+		_ = h.rewrite()
+		return
+	}
+	_ = h.appendEntry(entry)
+}
+
+// Prev moves to the previous (older) entry; current is saved as the draft when browsing starts
+func (h *promptHistory) Prev(current string) (string, bool) {
+	if h.pos == 0 || len(h.entries) == 0 {
+		return "", false
+	}
+	if h.pos == len(h.entries) {
+		h.draft = current
+	}
+	h.pos--
+	return h.entries[h.pos], true
+}
+
+// Next moves to the next (newer) entry, returning the draft after the newest one
+func (h *promptHistory) Next() (string, bool) {
+	if h.pos >= len(h.entries) {
+		return "", false
+	}
+	h.pos++
+	if h.pos == len(h.entries) {
+		return h.draft, true
+	}
+	return h.entries[h.pos], true
+}
+
+// Reset stops browsing
+func (h *promptHistory) Reset() {
+	h.pos = len(h.entries)
+	h.draft = ""
+}
+
+// appendEntry appends one entry to the history file
+func (h *promptHistory) appendEntry(entry string) error {
+	if h.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0750); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// rewrite replaces the history file with the current (trimmed) entries
+func (h *promptHistory) rewrite() error {
+	if h.path == "" {
+		return nil
+	}
+	var sb strings.Builder
+	for _, e := range h.entries {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		sb.Write(data)
+		sb.WriteByte('\n')
+	}
+	return os.WriteFile(h.path, []byte(sb.String()), 0600)
+}
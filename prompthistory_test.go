@@ -0,0 +1,52 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestPromptHistoryNavigation(t *testing.T) {
+	h := loadPromptHistory("")
+	h.Add("first")
+	h.Add("second")
+	h.Add("second") // consecutive duplicate skipped
+
+	if len(h.entries) != 2 {
+		t.Fatalf("entries = %v, want 2", h.entries)
+	}
+
+	if got, ok := h.Prev("draft"); !ok || got != "second" {
+		t.Errorf("Prev = %q, %v; want second", got, ok)
+	}
+	if got, ok := h.Prev(""); !ok || got != "first" {
+		t.Errorf("Prev = %q, %v; want first", got, ok)
+	}
+	if _, ok := h.Prev(""); ok {
+		t.Error("Prev past the oldest entry should fail")
+	}
+	if got, _ := h.Next(); got != "second" {
+		t.Errorf("Next = %q, want second", got)
+	}
+	if got, ok := h.Next(); !ok || got != "draft" {
+		t.Errorf("Next past newest = %q, %v; want draft restored", got, ok)
+	}
+	if _, ok := h.Next(); ok {
+		t.Error("Next when not browsing should fail")
+	}
+}
+
+func TestPromptHistoryPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".bjarne", "prompt_history")
+
+	h := loadPromptHistory(path)
+	h.Add("write a ring buffer")
+	h.Add("make it lock-free\nand header-only")
+
+	reloaded := loadPromptHistory(path)
+	if len(reloaded.entries) != 2 {
+		t.Fatalf("reloaded %d entries, want 2", len(reloaded.entries))
+	}
+	if reloaded.entries[1] != "make it lock-free\nand header-only" {
+		t.Errorf("multi-line entry not preserved: %q", reloaded.entries[1])
+	}
+}
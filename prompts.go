@@ -105,6 +105,17 @@ For complex tasks:
 - Ask specific clarifying questions if needed
 - End with: "Any corrections before I proceed?"
 
+When the request fully determines what the program prints (e.g. "print the first 10 primes"),
+end with that exact stdout between tags, so the run can be checked:
+[EXPECTED OUTPUT]
+2 3 5 7 11 13 17 19 23 29
+[/EXPECTED OUTPUT]
+If only its shape is known, give a regular expression the whole output must match instead:
+[EXPECTED OUTPUT REGEX]
+Sum: \d+
+[/EXPECTED OUTPUT REGEX]
+Leave it out when the output depends on input, time or randomness, or the request does not say.
+
 REMEMBER: Analysis only. NO CODE. Not even pseudo-code or examples.`
 
 // AcknowledgeSystemPrompt is used after user responds to clarifying questions
@@ -251,7 +262,31 @@ CURRENT CODE:
 ERRORS:
 %s
 
-Common fixes by sanitizer:
+The numbered Source lines after an error are the code it refers to; lines marked ">" are where it points. Fix the problem there, not in unrelated code.
+
+` + iterationFixGuidance + `Provide corrected code in a cpp block.`
+
+// MultiFileIterationPromptTemplate is sent when validation of a multi-file project fails
+// %s = project files (one block per file), %s = files named in the errors, %s = errors
+const MultiFileIterationPromptTemplate = `Validation failed. Fix the project.
+
+PROJECT FILES:
+%s
+
+FILES NAMED IN THE ERRORS: %s
+
+ERRORS:
+%s
+
+The numbered Source lines after an error are the code it refers to; lines marked ">" are where it points. Fix the problem there, not in unrelated code.
+
+` + iterationFixGuidance + `Return ONLY the files you change, each complete, in its own cpp block whose first line is
+// FILE: <filename>
+Files you do not return are kept as they are. Keep declarations in headers consistent with their definitions.`
+
+// iterationFixGuidance is the shared sanitizer and security advice for fix prompts
+// Contains %%s, so it must be used in a template passed through fmt.Sprintf
+const iterationFixGuidance = `Common fixes by sanitizer:
 - MSan (uninitialized memory): Initialize ALL variables at declaration. Use = 0, = {}, or = nullptr.
 - ASAN (memory errors): Check array bounds, avoid use-after-free, use smart pointers.
 - UBSAN (undefined behavior): Avoid signed overflow, null deref, invalid shifts.
@@ -277,13 +312,81 @@ Requirements:
 - Functions: CCN <= 15, length <= 100 lines
 - Maintain intended functionality (safely)
 
-Provide corrected code in a cpp block.`
+`
 
 // GenerateNowPrompt is sent after user confirms
 const GenerateNowPrompt = `User confirmed. Generate the code now.
 
 Wrap code in a single cpp block. Make it complete and compilable.`
 
+// RegeneratePrompt is sent when the user rejects validated code at the approval step
+const RegeneratePrompt = `The user rejected the previous code. Generate a fresh implementation of the same request,
+taking a different approach where reasonable.
+
+Wrap code in a single cpp block. Make it complete and compilable.`
+
+// RegenerateFromScratchPromptTemplate starts a request over after repeated failed fixes (regenerate strategy)
+// %s = the original request, %d = failed validations so far, %s = the failures they ran into
+const RegenerateFromScratchPromptTemplate = `%s
+
+Earlier attempts at this request failed validation %d times, and patching them did not help. Start over.
+
+The attempts kept running into:
+%s
+Re-plan first: in a few lines, describe a design that avoids these problems by construction.
+Then write the complete code from scratch. Do not reuse or patch the earlier code.
+
+Wrap code in a single cpp block (for several files, one block per file whose first line is // FILE: <filename>). Make it complete and compilable.`
+
+// FunctionalChecksPrompt asks the reviewer for assertion checks derived from the request (functional review)
+// %d = maximum checks, %s = original request, %s = the code under test
+const FunctionalChecksPrompt = `Write at most %d quick checks that verify this code does what the request asks.
+
+ORIGINAL REQUEST:
+%s
+
+CODE UNDER TEST:
+` + "```cpp" + `
+%s
+` + "```" + `
+
+Your checks become the body of a new main() that replaces the code's own main().
+All of the code's functions, classes and includes are available. Use the macro:
+    CHECK(condition, "what this verifies");
+
+Rules:
+- Derive every check from the REQUEST, not from what the code happens to do
+- Test the main behaviour and one edge case the request implies
+- Keep any setup to a few lines, with no input, files, network or sleeps
+- Do not define main(), a test framework, or new versions of the code's functions
+
+Output a single cpp block containing only the statements.`
+
+// ConsistencyCheckPrompt compares the analysis given before generation with the code that was built
+// %s = original request, %s = the analysis, %s = generated code
+const ConsistencyCheckPrompt = `Before writing this code, the assistant analyzed the request and told the user how it would be built.
+Check whether the code keeps those commitments.
+
+ORIGINAL REQUEST:
+%s
+
+ANALYSIS GIVEN TO THE USER:
+%s
+
+GENERATED CODE:
+` + "```cpp" + `
+%s
+` + "```" + `
+
+Only consider concrete commitments in the analysis: data structures, algorithms, complexity,
+thread-safety and synchronization, error handling, ownership, and stated assumptions or limits.
+Ignore wording, style, and anything the analysis left open or the user changed later.
+
+OUTPUT FORMAT:
+If the code keeps every commitment, output exactly: CONSISTENT
+Otherwise output one line per divergence, at most 5:
+DIVERGENCE: <what the analysis promised> -> <what the code does instead>`
+
 // OracleSystemPrompt is for deep architectural analysis of COMPLEX tasks (Opus)
 const OracleSystemPrompt = BjarnePersona + `
 
@@ -332,7 +435,7 @@ End with questions if requirements are ambiguous, otherwise "Ready to implement.
 REMEMBER: Analysis only. NO CODE. Not even pseudo-code or skeleton code.`
 
 // CodeReviewPrompt is used for the final LLM review gate after sanitizers pass
-// %s = original request, %s = generated code
+// %s = original request, %s = generated code, %s = numbered review criteria (the rubric)
 const CodeReviewPrompt = `You are a pragmatic code reviewer. This code has ALREADY PASSED all sanitizer checks:
 - ASAN (memory errors) - PASSED
 - UBSAN (undefined behavior) - PASSED
@@ -351,9 +454,7 @@ GENERATED CODE:
 ` + "```" + `
 
 REVIEW CRITERIA (focus on real-world impact):
-1. Does the code fulfill the stated requirements?
-2. Are there logic errors that would produce wrong output?
-3. Are there obvious bugs the sanitizers couldn't catch (infinite loops, wrong algorithm)?
+%s
 
 DO NOT penalize for:
 - Theoretical edge cases unlikely in practice
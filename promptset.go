@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Overridable prompt names; each is loaded from <name>.md or <name>.txt in the prompts directory
+const (
+	PromptPersona    = "persona"
+	PromptGeneration = "generation"
+	PromptReview     = "review"
+	PromptIteration  = "iteration"
+)
+
+// promptNames lists the overridable prompts in display order
+var promptNames = []string{PromptPersona, PromptGeneration, PromptReview, PromptIteration}
+
+// promptExtensions are tried in order when looking for an override file
+var promptExtensions = []string{".md", ".txt"}
+
+// PromptSet holds the prompts for a session with any user overrides applied
+//
+// Override files support these variables:
+//
+//	{{default}}  the built-in prompt (to extend rather than replace it)
+//	{{request}}  the original request (review)
+//	{{rubric}}   the numbered review criteria (review)
+//	{{code}}     the current code (review, iteration)
+//	{{errors}}   the validation errors (iteration)
+//	{{files}}    the files named in the errors (iteration, multi-file projects)
+type PromptSet struct {
+	dir       string
+	overrides map[string]string // name -> override text
+	sources   map[string]string // name -> file the override came from
+}
+
+// promptsDir returns ~/.bjarne/prompts
+func promptsDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".bjarne", "prompts"), nil
+}
+
+// loadUserPromptSet reads the overrides in ~/.bjarne/prompts, returning the built-in prompts
+// with the error when there is no home directory
+func loadUserPromptSet() (*PromptSet, []error) {
+	dir, err := promptsDir()
+	if err != nil {
+		p, _ := LoadPromptSet("")
+		return p, []error{err}
+	}
+	return LoadPromptSet(dir)
+}
+
+// LoadPromptSet reads overrides from dir (empty dir or missing files = built-in prompts)
+// Unreadable files are reported and skipped
+func LoadPromptSet(dir string) (*PromptSet, []error) {
+	p := &PromptSet{
+		dir:       dir,
+		overrides: make(map[string]string),
+		sources:   make(map[string]string),
+	}
+	if dir == "" {
+		return p, nil
+	}
+
+	var errs []error
+	for _, name := range promptNames {
+		for _, ext := range promptExtensions {
+			path := filepath.Join(dir, name+ext)
+			data, err := os.ReadFile(path)
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to read prompt %s: %w", path, err))
+				break
+			}
+			if text := strings.TrimSpace(string(data)); text != "" {
+				p.overrides[name] = text
+				p.sources[name] = path
+			}
+			break
+		}
+	}
+	return p, errs
+}
+
+// Reload re-reads overrides from the same directory
+func (p *PromptSet) Reload() []error {
+	fresh, errs := LoadPromptSet(p.dir)
+	*p = *fresh
+	return errs
+}
+
+// Sources returns "name: path" for each overridden prompt, sorted by name
+func (p *PromptSet) Sources() []string {
+	var out []string
+	for name, path := range p.sources {
+		out = append(out, name+": "+path)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Dir returns the directory overrides are loaded from
+func (p *PromptSet) Dir() string {
+	return p.dir
+}
+
+// expandPromptVars replaces {{name}} placeholders; unknown placeholders are left as-is
+func expandPromptVars(text string, vars map[string]string) string {
+	pairs := make([]string, 0, 2*len(vars))
+	for name, value := range vars {
+		pairs = append(pairs, "{{"+name+"}}", value)
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// resolve returns the override for name with vars expanded, or the built-in text
+// A nil PromptSet always yields the built-in prompts
+func (p *PromptSet) resolve(name, builtin string, vars map[string]string) string {
+	if p == nil {
+		return builtin
+	}
+	text, ok := p.overrides[name]
+	if !ok {
+		return builtin
+	}
+	all := map[string]string{"default": builtin}
+	for k, v := range vars {
+		all[k] = v
+	}
+	return expandPromptVars(text, all)
+}
+
+// Persona returns the persona that opens the chat, reflection and oracle prompts
+func (p *PromptSet) Persona() string {
+	return p.resolve(PromptPersona, BjarnePersona, nil)
+}
+
+// WithPersona swaps the built-in persona at the start of a system prompt for the override
+func (p *PromptSet) WithPersona(systemPrompt string) string {
+	if p == nil {
+		return systemPrompt
+	}
+	if _, ok := p.overrides[PromptPersona]; !ok {
+		return systemPrompt
+	}
+	return p.Persona() + strings.TrimPrefix(systemPrompt, BjarnePersona)
+}
+
+// Generation returns the code generation system prompt
+func (p *PromptSet) Generation() string {
+	return p.resolve(PromptGeneration, GenerationSystemPrompt, nil)
+}
+
+// Review returns the review prompt for the request and code, judged by rubric
+// (empty = the built-in criteria)
+func (p *PromptSet) Review(request, code string, rubric []string) string {
+	criteria := formatRubric(rubric)
+	return p.resolve(PromptReview, fmt.Sprintf(CodeReviewPrompt, request, code, criteria),
+		map[string]string{"request": request, "code": code, "rubric": criteria})
+}
+
+// Iteration returns the fix prompt for the code and its validation errors
+func (p *PromptSet) Iteration(code, errs string) string {
+	return p.resolve(PromptIteration, fmt.Sprintf(IterationPromptTemplate, code, errs),
+		map[string]string{"code": code, "errors": errs})
+}
+
+// MultiFileIteration returns the fix prompt for a multi-file project
+// An iteration override receives all files (with // FILE: markers) as {{code}}
+func (p *PromptSet) MultiFileIteration(files []CodeFile, errs string) string {
+	code := formatFilesForPrompt(files)
+	failing := strings.Join(filesInErrors(files, errs), ", ")
+	if failing == "" {
+		failing = "(none identified)"
+	}
+	return p.resolve(PromptIteration, fmt.Sprintf(MultiFileIterationPromptTemplate, code, failing, errs),
+		map[string]string{"code": code, "errors": errs, "files": failing})
+}
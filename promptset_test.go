@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPromptSetBuiltins(t *testing.T) {
+	var nilSet *PromptSet
+	empty, errs := LoadPromptSet(t.TempDir())
+	if len(errs) > 0 {
+		t.Fatalf("LoadPromptSet(empty dir) errors: %v", errs)
+	}
+
+	for name, p := range map[string]*PromptSet{"nil": nilSet, "empty dir": empty} {
+		t.Run(name, func(t *testing.T) {
+			if got := p.Generation(); got != GenerationSystemPrompt {
+				t.Error("Generation() should be the built-in prompt")
+			}
+			if got := p.WithPersona(QuestionSystemPrompt); got != QuestionSystemPrompt {
+				t.Error("WithPersona() should leave the prompt unchanged")
+			}
+			if got, want := p.Iteration("int x;", "msan"), fmt.Sprintf(IterationPromptTemplate, "int x;", "msan"); got != want {
+				t.Error("Iteration() should be the built-in template")
+			}
+		})
+	}
+}
+
+func TestPromptSetOverrides(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"persona.md":    "You are a terse embedded-systems reviewer.",
+		"generation.md": "{{default}}\n\nNever use exceptions.",
+		"iteration.txt": "Fix this:\n{{code}}\nErrors:\n{{errors}}\n{{unknown}}",
+		"review.md":     "   \n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p, errs := LoadPromptSet(dir)
+	if len(errs) > 0 {
+		t.Fatalf("LoadPromptSet errors: %v", errs)
+	}
+
+	if got := p.Generation(); !strings.HasPrefix(got, GenerationSystemPrompt) || !strings.HasSuffix(got, "Never use exceptions.") {
+		t.Errorf("Generation() did not extend the default: %q", got[len(got)-40:])
+	}
+
+	persona := p.WithPersona(QuestionSystemPrompt)
+	if !strings.HasPrefix(persona, "You are a terse embedded-systems reviewer.") || strings.Contains(persona, "Bjarne Stroustrup") {
+		t.Errorf("WithPersona() did not replace the persona: %q", persona[:80])
+	}
+	if !strings.HasSuffix(persona, strings.TrimPrefix(QuestionSystemPrompt, BjarnePersona)) {
+		t.Error("WithPersona() dropped the task instructions")
+	}
+
+	if got, want := p.Iteration("int x;", "use of uninitialized value"), "Fix this:\nint x;\nErrors:\nuse of uninitialized value\n{{unknown}}"; got != want {
+		t.Errorf("Iteration() = %q, want %q", got, want)
+	}
+
+	// Blank override files are ignored
+	if got, want := p.Review("req", "code", nil), fmt.Sprintf(CodeReviewPrompt, "req", "code", formatRubric(nil)); got != want {
+		t.Error("Review() should fall back to the built-in prompt for a blank file")
+	}
+
+	if got := p.Sources(); len(got) != 3 || !strings.HasPrefix(got[0], "generation: ") {
+		t.Errorf("Sources() = %v, want 3 sorted entries", got)
+	}
+
+	// Reload picks up removed files
+	if err := os.Remove(filepath.Join(dir, "persona.md")); err != nil {
+		t.Fatal(err)
+	}
+	if errs := p.Reload(); len(errs) > 0 {
+		t.Fatalf("Reload errors: %v", errs)
+	}
+	if got := p.WithPersona(QuestionSystemPrompt); got != QuestionSystemPrompt {
+		t.Error("persona override still applied after Reload()")
+	}
+}
+
+func TestPromptSetMultiFileIteration(t *testing.T) {
+	var p *PromptSet
+	files := []CodeFile{{Filename: "counter.h", Content: "class Counter;"}, {Filename: "counter.cpp", Content: "void f() {}"}}
+	got := p.MultiFileIteration(files, "counter.cpp:3:1: error: expected ';'")
+	for _, want := range []string{"// FILE: counter.h", "// FILE: counter.cpp", "FILES NAMED IN THE ERRORS: counter.cpp"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("MultiFileIteration() missing %q", want)
+		}
+	}
+}
+
+func TestLoadUserPromptSetErrors(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	// A directory where the generation prompt should be cannot be read
+	if err := os.MkdirAll(filepath.Join(home, ".bjarne", "prompts", PromptGeneration+".md"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	p, errs := loadUserPromptSet()
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), PromptGeneration+".md") {
+		t.Errorf("loadUserPromptSet() errors = %v, want the unreadable generation prompt", errs)
+	}
+	if p.Generation() != GenerationSystemPrompt {
+		t.Error("the unreadable override should leave the built-in generation prompt")
+	}
+}
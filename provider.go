@@ -14,6 +14,7 @@ const (
 	ProviderAnthropic ProviderType = "anthropic"
 	ProviderOpenAI    ProviderType = "openai"
 	ProviderGemini    ProviderType = "gemini"
+	ProviderLocal     ProviderType = "local" // OpenAI-compatible local server (Ollama, llama.cpp)
 )
 
 // LLMProvider is the abstract interface for LLM providers
@@ -32,30 +33,47 @@ type LLMProvider interface {
 
 	// DefaultModel returns the provider's default model
 	DefaultModel() string
+
+	// SetGeneration replaces the sampling parameters used for subsequent requests
+	SetGeneration(gen GenerationSettings)
 }
 
 // ProviderConfig holds configuration for initializing providers
 type ProviderConfig struct {
-	Provider ProviderType
-	APIKey   string // For non-Bedrock providers
-	Region   string // For Bedrock
-	Models   ModelSettings
+	Provider   ProviderType
+	APIKey     string        // For non-Bedrock providers
+	Region     string        // For Bedrock
+	Local      LocalSettings // For the local provider
+	Models     ModelSettings
+	Generation GenerationSettings           // Sampling parameters (temperature, top-p, seed)
+	RateLimits map[string]RateLimitSettings // Per-provider budgets, keyed by provider type
 }
 
 // NewProvider creates an LLM provider based on configuration
+// Requests are trimmed to the model's context window, recorded in the session audit log
+// when one is active, queued to stay within the provider's rate limits, and logged
 func NewProvider(ctx context.Context, cfg *ProviderConfig) (LLMProvider, error) {
+	var provider LLMProvider
+	var err error
 	switch cfg.Provider {
 	case ProviderBedrock:
-		return NewBedrockProvider(ctx, cfg)
+		provider, err = NewBedrockProvider(ctx, cfg)
 	case ProviderAnthropic:
-		return NewAnthropicProvider(cfg)
+		provider, err = NewAnthropicProvider(cfg)
 	case ProviderOpenAI:
-		return NewOpenAIProvider(cfg)
+		provider, err = NewOpenAIProvider(cfg)
 	case ProviderGemini:
-		return NewGeminiProvider(cfg)
+		provider, err = NewGeminiProvider(cfg)
+	case ProviderLocal:
+		provider, err = NewLocalProvider(cfg)
 	default:
 		return nil, fmt.Errorf("unknown provider: %s", cfg.Provider)
 	}
+	if err != nil {
+		return nil, err
+	}
+	limiter := rateLimiterFor(cfg.Provider, cfg.RateLimits[string(cfg.Provider)])
+	return withContextLimits(withAudit(withLogging(withRateLimit(provider, limiter)), activeAudit), cfg), nil
 }
 
 // ParseProviderType converts a string to ProviderType
@@ -69,6 +87,8 @@ func ParseProviderType(s string) ProviderType {
 		return ProviderOpenAI
 	case "gemini", "google":
 		return ProviderGemini
+	case "local", "ollama":
+		return ProviderLocal
 	default:
 		return ProviderBedrock // Default to Bedrock
 	}
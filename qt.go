@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// qtImageHint tells the user where Qt is
+const qtImageHint = "use the qt validator image: /config image qt"
+
+// qtFlagsMarker starts the line the qt stage prints pkg-config's flags on
+const qtFlagsMarker = "bjarne-qt-flags:"
+
+// qtIncludePattern matches a Qt class or module header, e.g. <QApplication> or <QtWidgets/QLabel>
+var qtIncludePattern = regexp.MustCompile(`(?m)^\s*#\s*include\s*<(?:Qt\w+/)?Q[A-Z]\w*(?:\.h)?>`)
+
+// qtMetaObjectPattern matches the macros moc must generate code for
+var qtMetaObjectPattern = regexp.MustCompile(`\bQ_(?:OBJECT|GADGET|NAMESPACE)\b`)
+
+// QtBuild is the moc/uic output and the flags a Qt program builds with
+type QtBuild struct {
+	HostDir string // Mounted read-only at /qt in validation stages; holds moc_*.cpp, *.moc and ui_*.h
+
+	generating   bool     // /qt is writable: the qt stage is writing it
+	compileFlags []string // -isystem/-D from pkg-config, -I/qt and -fPIC
+	linkFlags    []string // Generated moc sources and -l flags from pkg-config
+}
+
+// CompileFlags returns the include and define flags (for clang-tidy and -fsyntax-only)
+func (q *QtBuild) CompileFlags() []string {
+	if q == nil {
+		return nil
+	}
+	return q.compileFlags
+}
+
+// LinkFlags returns the generated sources and the Qt libraries
+func (q *QtBuild) LinkFlags() []string {
+	if q == nil {
+		return nil
+	}
+	return q.linkFlags
+}
+
+// usesQt reports whether any file includes Qt headers or declares a Qt meta-object
+func usesQt(files []CodeFile) bool {
+	for _, f := range files {
+		if qtIncludePattern.MatchString(f.Content) || qtMetaObjectPattern.MatchString(f.Content) {
+			return true
+		}
+	}
+	return false
+}
+
+// qtMocJobs lists the moc runs the files need as source -> generated file. A header's output is
+// compiled on its own (moc_widget.cpp); a source file's output must be included by that file (main.moc)
+func qtMocJobs(files []CodeFile) (map[string]string, error) {
+	jobs := make(map[string]string)
+	for _, f := range files {
+		if !qtMetaObjectPattern.MatchString(f.Content) {
+			continue
+		}
+		base := strings.TrimSuffix(filepath.Base(f.Filename), filepath.Ext(f.Filename))
+		if isHeaderFile(f.Filename) {
+			jobs[f.Filename] = "moc_" + base + ".cpp"
+			continue
+		}
+		moc := base + ".moc"
+		if !regexp.MustCompile(`#\s*include\s*["<]` + regexp.QuoteMeta(moc) + `[">]`).MatchString(f.Content) {
+			return nil, fmt.Errorf("%s declares a Q_OBJECT class but does not #include \"%s\"; add it at the end of the file or move the class to a header", f.Filename, moc)
+		}
+		jobs[f.Filename] = moc
+	}
+	return jobs, nil
+}
+
+// qtCommand finds Qt 6 (or Qt 5) in the image, runs moc and uic into /qt and prints the build flags
+func qtCommand(mocJobs map[string]string, forms []string) string {
+	var sb strings.Builder
+	sb.WriteString("PC=Qt6Widgets; pkg-config --exists $PC 2>/dev/null || PC=Qt5Widgets; ")
+	fmt.Fprintf(&sb, "pkg-config --exists $PC 2>/dev/null || { echo 'Qt is not installed in the validator image (%s)'; exit 1; }; ", qtImageHint)
+	sb.WriteString(`BIN=$(pkg-config --variable=libexecdir Qt6Core 2>/dev/null); [ -x "$BIN/moc" ] || BIN=$(pkg-config --variable=host_bins Qt5Core 2>/dev/null); ` +
+		`[ -x "$BIN/moc" ] || BIN=$(dirname "$(command -v moc)"); `)
+	sources := make([]string, 0, len(mocJobs))
+	for source := range mocJobs {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	for _, source := range sources {
+		fmt.Fprintf(&sb, `"$BIN/moc" -I/src /src/%s -o /qt/%s && `, source, mocJobs[source])
+	}
+	for _, form := range forms {
+		fmt.Fprintf(&sb, `"$BIN/uic" /src/%s -o /qt/ui_%s.h && `, form, strings.TrimSuffix(filepath.Base(form), ".ui"))
+	}
+	fmt.Fprintf(&sb, `echo "%s $(pkg-config --cflags --libs $PC)"`, qtFlagsMarker)
+	return sb.String()
+}
+
+// parseQtFlags splits pkg-config's flags into compile and link flags; Qt's include
+// directories become -isystem so -Werror does not fail on warnings in Qt's headers
+func parseQtFlags(output string) (compile, link []string, ok bool) {
+	for _, line := range strings.Split(output, "\n") {
+		flags, found := strings.CutPrefix(strings.TrimSpace(line), qtFlagsMarker)
+		if !found {
+			continue
+		}
+		compile = []string{"-I/qt", "-fPIC"} // Qt requires position-independent code
+		for _, flag := range strings.Fields(flags) {
+			switch {
+			case strings.HasPrefix(flag, "-I"):
+				compile = append(compile, "-isystem", strings.TrimPrefix(flag, "-I"))
+			case strings.HasPrefix(flag, "-D"), strings.HasPrefix(flag, "-f"):
+				compile = append(compile, flag)
+			case strings.HasPrefix(flag, "-L"), strings.HasPrefix(flag, "-l"), strings.HasPrefix(flag, "-Wl,"):
+				link = append(link, flag)
+			}
+		}
+		return compile, link, true
+	}
+	return nil, nil, false
+}
+
+// qtBuildDir returns the directory for one set of files' generated code; identical files share it
+func qtBuildDir(files []CodeFile) string {
+	var sb strings.Builder
+	for _, f := range files {
+		sb.WriteString(f.Filename + "\x00" + f.Content + "\x00")
+	}
+	return filepath.Join(os.TempDir(), "bjarne-qt-"+shortHash(sha256Hex(sb.String())))
+}
+
+// withQt runs moc and uic on Qt code and returns a runtime whose stages build and link against Qt
+// and run the program on the offscreen platform. A failed result is returned (instead of the runtime)
+// when the image has no Qt or moc rejects the code
+func (c *ContainerRuntime) withQt(ctx context.Context, files []CodeFile) (*ContainerRuntime, *ValidationResult, error) {
+	if c.qt != nil || !usesQt(files) {
+		return c, nil, nil
+	}
+	mocJobs, err := qtMocJobs(files)
+	if err != nil {
+		return nil, &ValidationResult{Stage: "qt", Error: err.Error()}, nil
+	}
+	var forms []string
+	for _, f := range files {
+		if filepath.Ext(f.Filename) == ".ui" {
+			forms = append(forms, f.Filename)
+		}
+	}
+
+	tmpDir, err := os.MkdirTemp("", "bjarne-qt-src-*")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	for _, f := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, f.Filename), []byte(f.Content), 0600); err != nil {
+			return nil, nil, fmt.Errorf("failed to write %s: %w", f.Filename, err)
+		}
+	}
+	build := &QtBuild{HostDir: qtBuildDir(files), generating: true}
+	if err := os.RemoveAll(build.HostDir); err != nil {
+		return nil, nil, fmt.Errorf("failed to clear Qt build dir: %w", err)
+	}
+	if err := os.MkdirAll(build.HostDir, 0750); err != nil {
+		return nil, nil, fmt.Errorf("failed to create Qt build dir: %w", err)
+	}
+
+	generate := *c
+	generate.qt = build
+	result := generate.runValidationStage(ctx, tmpDir, "qt", "sh", "-c", qtCommand(mocJobs, forms))
+	if !result.Success {
+		result.Error = strings.TrimSpace(result.Output + "\n" + result.Error) // moc reports on stderr, a missing Qt on stdout
+		return nil, &result, nil
+	}
+	compile, link, ok := parseQtFlags(result.Output)
+	if !ok {
+		result.Success = false
+		result.Error = "pkg-config did not report Qt's build flags"
+		return nil, &result, nil
+	}
+	var sources []string // A header's moc output is its own translation unit
+	for _, generated := range mocJobs {
+		if strings.HasPrefix(generated, "moc_") {
+			sources = append(sources, "/qt/"+generated)
+		}
+	}
+	sort.Strings(sources)
+	link = append(sources, link...)
+
+	withQt := *c
+	withQt.qt = &QtBuild{HostDir: build.HostDir, compileFlags: compile, linkFlags: link}
+	return &withQt, nil, nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+const qtWidgetHeader = `#pragma once
+#include <QWidget>
+
+class Counter : public QWidget {
+    Q_OBJECT
+public:
+    explicit Counter(QWidget* parent = nullptr);
+};
+`
+
+func TestUsesQt(t *testing.T) {
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{"#include <QApplication>\nint main() {}", true},
+		{"#include <QtWidgets/QLabel>\n", true},
+		{"struct S { Q_GADGET };", true},
+		{"#include <vector>\n#include <queue>\n", false},
+		{"// QApplication is not used\n#include <cstdio>\n", false},
+	}
+	for _, tt := range tests {
+		if got := usesQt([]CodeFile{{Filename: "main.cpp", Content: tt.code}}); got != tt.want {
+			t.Errorf("usesQt(%q) = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestQtMocJobs(t *testing.T) {
+	jobs, err := qtMocJobs([]CodeFile{
+		{Filename: "counter.h", Content: qtWidgetHeader},
+		{Filename: "main.cpp", Content: "#include <QApplication>\nclass Local : public QObject { Q_OBJECT };\n#include \"main.moc\"\n"},
+		{Filename: "util.cpp", Content: "#include \"counter.h\"\n"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 2 || jobs["counter.h"] != "moc_counter.cpp" || jobs["main.cpp"] != "main.moc" {
+		t.Errorf("qtMocJobs() = %v", jobs)
+	}
+
+	_, err = qtMocJobs([]CodeFile{{Filename: "main.cpp", Content: "class Local : public QObject { Q_OBJECT };\n"}})
+	if err == nil || !strings.Contains(err.Error(), `#include "main.moc"`) {
+		t.Errorf("qtMocJobs(missing .moc include) error = %v", err)
+	}
+}
+
+func TestQtCommand(t *testing.T) {
+	got := qtCommand(map[string]string{"counter.h": "moc_counter.cpp"}, []string{"dialog.ui"})
+	for _, want := range []string{
+		`"$BIN/moc" -I/src /src/counter.h -o /qt/moc_counter.cpp`,
+		`"$BIN/uic" /src/dialog.ui -o /qt/ui_dialog.h`,
+		qtFlagsMarker + " $(pkg-config --cflags --libs $PC)",
+		qtImageHint,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("qtCommand() = %q, missing %q", got, want)
+		}
+	}
+}
+
+func TestParseQtFlags(t *testing.T) {
+	output := "something\n" + qtFlagsMarker + " -I/usr/include/qt6/QtWidgets -DQT_WIDGETS_LIB -fPIC -L/usr/lib -lQt6Widgets -lQt6Core\n"
+	compile, link, ok := parseQtFlags(output)
+	if !ok {
+		t.Fatal("parseQtFlags() found no flags")
+	}
+	if got := strings.Join(compile, " "); got != "-I/qt -fPIC -isystem /usr/include/qt6/QtWidgets -DQT_WIDGETS_LIB -fPIC" {
+		t.Errorf("compile flags = %q", got)
+	}
+	if got := strings.Join(link, " "); got != "-L/usr/lib -lQt6Widgets -lQt6Core" {
+		t.Errorf("link flags = %q", got)
+	}
+	if _, _, ok := parseQtFlags("Qt is not installed"); ok {
+		t.Error("parseQtFlags() without the marker should report no flags")
+	}
+}
+
+func TestWithQt(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the container runtime")
+	}
+	dir := t.TempDir()
+	log := filepath.Join(dir, "calls")
+	fake := filepath.Join(dir, "podman")
+	script := "#!/bin/sh\necho \"$@\" >> " + log + "\necho '" + qtFlagsMarker + " -I/usr/include/qt6 -lQt6Widgets'\n"
+	if err := os.WriteFile(fake, []byte(script), 0700); err != nil { //nolint:gosec // test runtime
+		t.Fatal(err)
+	}
+
+	c := &ContainerRuntime{binary: fake, imageName: "validator"}
+	plain := []CodeFile{{Filename: "main.cpp", Content: "#include <vector>\nint main() {}\n"}}
+	if same, result, err := c.withQt(context.Background(), plain); same != c || result != nil || err != nil {
+		t.Fatalf("withQt(no Qt) = %v, %v, %v; want the runtime unchanged", same, result, err)
+	}
+
+	files := []CodeFile{
+		{Filename: "counter.h", Content: qtWidgetHeader},
+		{Filename: "main.cpp", Content: "#include <QApplication>\n#include \"counter.h\"\nint main() {}\n"},
+	}
+	withQt, result, err := c.withQt(context.Background(), files)
+	if err != nil || result != nil {
+		t.Fatalf("withQt() = %+v, %v", result, err)
+	}
+	defer func() { _ = os.RemoveAll(withQt.qt.HostDir) }()
+	if got := strings.Join(withQt.linkFlags(), " "); got != "/qt/moc_counter.cpp -lQt6Widgets" {
+		t.Errorf("linkFlags() = %q, want the moc output and Qt", got)
+	}
+	if !strings.Contains(withQt.cxx("-o /tmp/test /src/main.cpp"), "-isystem /usr/include/qt6") {
+		t.Errorf("cxx() = %q, want Qt's headers", withQt.cxx(""))
+	}
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if call := string(data); !strings.Contains(call, withQt.qt.HostDir+":/qt ") || !strings.Contains(call, "QT_QPA_PLATFORM=offscreen") {
+		t.Errorf("qt stage call = %q, want /qt mounted writable and the offscreen platform", call)
+	}
+
+	if r := withQt.runValidationStage(context.Background(), dir, "msan", "true"); !r.Skipped {
+		t.Errorf("msan stage for Qt code = %+v, want it skipped", r)
+	}
+}
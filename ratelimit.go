@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// rateWindow is the span RPM and TPM budgets are measured over
+const rateWindow = time.Minute
+
+// RateLimiter queues requests to one provider so they stay within its per-minute budgets
+type RateLimiter struct {
+	mu      sync.Mutex
+	rpm     int
+	tpm     int
+	sent    []*rateEvent // Requests in the current window, oldest first
+	waiting atomic.Int32
+	now     func() time.Time
+}
+
+// rateEvent is one request counted against the budgets
+type rateEvent struct {
+	at     time.Time
+	tokens int
+}
+
+// NewRateLimiter creates a limiter; zero budgets are unlimited
+func NewRateLimiter(s RateLimitSettings) *RateLimiter {
+	return &RateLimiter{rpm: s.RequestsPerMinute, tpm: s.TokensPerMinute, now: time.Now}
+}
+
+// rateLimiters are shared per provider so concurrent tasks and /compare draw on one budget
+var (
+	rateLimitersMu sync.Mutex
+	rateLimiters   = make(map[ProviderType]*RateLimiter)
+)
+
+// rateLimiterFor returns the provider's shared limiter, or nil when it has no budgets
+func rateLimiterFor(provider ProviderType, s RateLimitSettings) *RateLimiter {
+	if s.RequestsPerMinute <= 0 && s.TokensPerMinute <= 0 {
+		return nil
+	}
+	rateLimitersMu.Lock()
+	defer rateLimitersMu.Unlock()
+	if l, ok := rateLimiters[provider]; ok {
+		return l
+	}
+	l := NewRateLimiter(s)
+	rateLimiters[provider] = l
+	return l
+}
+
+// queuedRequests counts requests waiting on any provider's budget
+func queuedRequests() int {
+	rateLimitersMu.Lock()
+	defer rateLimitersMu.Unlock()
+	n := 0
+	for _, l := range rateLimiters {
+		n += l.Queued()
+	}
+	return n
+}
+
+// Queued returns how many requests are waiting for budget
+func (l *RateLimiter) Queued() int {
+	return int(l.waiting.Load())
+}
+
+// Wait blocks until a request of about tokens fits both budgets, then counts it
+func (l *RateLimiter) Wait(ctx context.Context, tokens int) (*rateEvent, error) {
+	for {
+		event, delay := l.reserve(tokens)
+		if event != nil {
+			return event, nil
+		}
+
+		l.waiting.Add(1)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			l.waiting.Add(-1)
+			return nil, ctx.Err()
+		case <-timer.C:
+			l.waiting.Add(-1)
+		}
+	}
+}
+
+// Settle replaces a request's estimate with the tokens actually used
+func (l *RateLimiter) Settle(event *rateEvent, tokens int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	event.tokens = tokens
+}
+
+// reserve counts the request if it fits now, or returns how long until it might
+func (l *RateLimiter) reserve(tokens int) (*rateEvent, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	for len(l.sent) > 0 && now.Sub(l.sent[0].at) >= rateWindow {
+		l.sent = l.sent[1:]
+	}
+
+	var wait time.Duration
+	if l.rpm > 0 && len(l.sent) >= l.rpm {
+		wait = l.sent[len(l.sent)-l.rpm].at.Add(rateWindow).Sub(now)
+	}
+	if l.tpm > 0 {
+		used := 0
+		for _, e := range l.sent {
+			used += e.tokens
+		}
+		// Wait for enough earlier requests to age out; a request larger than the whole
+		// budget goes alone rather than never
+		for _, e := range l.sent {
+			if used+tokens <= l.tpm {
+				break
+			}
+			used -= e.tokens
+			if d := e.at.Add(rateWindow).Sub(now); d > wait {
+				wait = d
+			}
+		}
+	}
+	if wait > 0 {
+		return nil, wait
+	}
+
+	event := &rateEvent{at: now, tokens: tokens}
+	l.sent = append(l.sent, event)
+	return event, 0
+}
+
+// rateLimitedProvider waits for budget before each request
+type rateLimitedProvider struct {
+	LLMProvider
+	limiter *RateLimiter
+}
+
+// withRateLimit wraps a provider with a limiter (no-op without one)
+func withRateLimit(p LLMProvider, limiter *RateLimiter) LLMProvider {
+	if limiter == nil {
+		return p
+	}
+	return &rateLimitedProvider{LLMProvider: p, limiter: limiter}
+}
+
+// Unwrap returns the wrapped provider
+func (r *rateLimitedProvider) Unwrap() LLMProvider {
+	return r.LLMProvider
+}
+
+// Generate waits for budget, then calls the wrapped provider
+func (r *rateLimitedProvider) Generate(ctx context.Context, model, systemPrompt string, messages []Message, maxTokens int) (*GenerateResult, error) {
+	event, err := r.limiter.Wait(ctx, estimateRequestTokens(systemPrompt, messages))
+	if err != nil {
+		return nil, err
+	}
+	result, err := r.LLMProvider.Generate(ctx, model, systemPrompt, messages, maxTokens)
+	r.settle(event, result)
+	return result, err
+}
+
+// GenerateStreaming waits for budget, then calls the wrapped provider
+func (r *rateLimitedProvider) GenerateStreaming(ctx context.Context, model, systemPrompt string, messages []Message, maxTokens int, callback StreamCallback) (*GenerateResult, error) {
+	event, err := r.limiter.Wait(ctx, estimateRequestTokens(systemPrompt, messages))
+	if err != nil {
+		return nil, err
+	}
+	result, err := r.LLMProvider.GenerateStreaming(ctx, model, systemPrompt, messages, maxTokens, callback)
+	r.settle(event, result)
+	return result, err
+}
+
+// settle counts the reported usage (input and output) against the token budget
+func (r *rateLimitedProvider) settle(event *rateEvent, result *GenerateResult) {
+	if result != nil && result.InputTokens+result.OutputTokens > 0 {
+		r.limiter.Settle(event, result.InputTokens+result.OutputTokens)
+	}
+}
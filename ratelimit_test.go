@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterReserve(t *testing.T) {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		settings RateLimitSettings
+		sent     []int // Tokens of requests sent at 0s, 10s, 20s, ...
+		tokens   int
+		wantWait time.Duration
+	}{
+		{"unlimited budgets", RateLimitSettings{}, []int{1000, 1000}, 1000, 0},
+		{"under rpm", RateLimitSettings{RequestsPerMinute: 3}, []int{1, 1}, 1, 0},
+		{"at rpm waits for the oldest", RateLimitSettings{RequestsPerMinute: 2}, []int{1, 1}, 1, 40 * time.Second},
+		{"under tpm", RateLimitSettings{TokensPerMinute: 1000}, []int{300, 300}, 300, 0},
+		{"over tpm waits for enough to age out", RateLimitSettings{TokensPerMinute: 1000}, []int{400, 400, 100}, 500, 30 * time.Second},
+		{"larger than the budget goes alone", RateLimitSettings{TokensPerMinute: 1000}, []int{100}, 5000, 50 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewRateLimiter(tt.settings)
+			for i, tokens := range tt.sent {
+				l.now = func() time.Time { return start.Add(time.Duration(i) * 10 * time.Second) }
+				if event, wait := l.reserve(tokens); event == nil {
+					t.Fatalf("setup request %d waited %v", i, wait)
+				}
+			}
+			l.now = func() time.Time { return start.Add(time.Duration(len(tt.sent)) * 10 * time.Second) }
+			// The clock now reads 10s after the last setup request
+			event, wait := l.reserve(tt.tokens)
+			if tt.wantWait == 0 {
+				if event == nil {
+					t.Errorf("reserve() waited %v, want immediate", wait)
+				}
+				return
+			}
+			if event != nil || wait != tt.wantWait {
+				t.Errorf("reserve() = %v, wait %v; want wait %v", event, wait, tt.wantWait)
+			}
+		})
+	}
+}
+
+func TestRateLimiterWaitCancel(t *testing.T) {
+	l := NewRateLimiter(RateLimitSettings{RequestsPerMinute: 1})
+	if _, err := l.Wait(context.Background(), 1); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := l.Wait(ctx, 1)
+		done <- err
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for l.Queued() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if l.Queued() != 1 {
+		t.Fatalf("Queued() = %d, want 1", l.Queued())
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait() error = %v, want context.Canceled", err)
+	}
+	if l.Queued() != 0 {
+		t.Errorf("Queued() = %d after cancel, want 0", l.Queued())
+	}
+}
+
+func TestRateLimiterSettle(t *testing.T) {
+	l := NewRateLimiter(RateLimitSettings{TokensPerMinute: 1000})
+	event, _ := l.reserve(100)
+	l.Settle(event, 950) // The response was much larger than the estimate
+	if event, _ := l.reserve(100); event != nil {
+		t.Error("reserve() ignored the settled usage")
+	}
+}
+
+func TestRateLimiterFor(t *testing.T) {
+	if rateLimiterFor(ProviderGemini, RateLimitSettings{}) != nil {
+		t.Error("rateLimiterFor() created a limiter without budgets")
+	}
+	a := rateLimiterFor(ProviderGemini, RateLimitSettings{RequestsPerMinute: 10})
+	b := rateLimiterFor(ProviderGemini, RateLimitSettings{RequestsPerMinute: 10})
+	if a == nil || a != b {
+		t.Error("rateLimiterFor() should share one limiter per provider")
+	}
+}
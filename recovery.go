@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// recoveryState is a session that bjarne quit mid-task or with unsaved work
+// It is written on quit and offered back with /recover on the next launch
+type recoveryState struct {
+	Transcript
+	Dir     string `json:"dir,omitempty"`     // Working directory of the session
+	Task    string `json:"task,omitempty"`    // What was running at quit, e.g. "Validating the code."
+	Unsaved bool   `json:"unsaved,omitempty"` // The validated code had not been saved
+
+	// Pending fix context: the failed gates and the errors the next fix would have been given
+	FailedResults []ValidationResult `json:"failedResults,omitempty"`
+	FixContext    string             `json:"fixContext,omitempty"`
+}
+
+// recoveryPath returns ~/.bjarne/recovery.json
+func recoveryPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".bjarne", "recovery.json"), nil
+}
+
+// saveRecovery writes the session to path, replacing an earlier one
+func saveRecovery(path string, r *recoveryState) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode session: %w", err)
+	}
+	if err := writeFileAtomic(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	return nil
+}
+
+// loadRecovery reads the session saved at path, or returns nil when there is none
+func loadRecovery(path string) (*recoveryState, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read saved session: %w", err)
+	}
+	var r recoveryState
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse saved session %s: %w", path, err)
+	}
+	return &r, nil
+}
+
+// Summary describes the saved session for the offer to restore it
+func (r *recoveryState) Summary() string {
+	parts := []string{fmt.Sprintf("%d message(s)", len(r.Conversation))}
+	if len(r.Code) > 0 {
+		code := fmt.Sprintf("%d file(s)", len(r.Code))
+		if r.Unsaved {
+			code += ", unsaved"
+		}
+		parts = append(parts, code)
+	}
+	if len(r.FailedResults) > 0 {
+		parts = append(parts, "failed gates to fix")
+	}
+	if r.Task != "" {
+		parts = append(parts, "stopped while "+strings.ToLower(r.Task[:1])+strings.TrimSuffix(r.Task[1:], "."))
+	}
+	summary := strings.Join(parts, ", ")
+	if !r.Exported.IsZero() {
+		summary += "; saved " + r.Exported.Format("2006-01-02 15:04")
+	}
+	if r.Dir != "" {
+		summary += " in " + r.Dir
+	}
+	return summary
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecoveryStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recovery.json")
+	if r, err := loadRecovery(path); r != nil || err != nil {
+		t.Fatalf("loadRecovery(missing) = %v, %v, want nothing", r, err)
+	}
+
+	saved := &recoveryState{
+		Transcript: Transcript{
+			Exported:     time.Date(2026, 10, 16, 15, 4, 0, 0, time.Local),
+			Conversation: []Message{{Role: "user", Content: "ring buffer"}},
+			Code:         []CodeFile{{Filename: "code.cpp", Content: "int main() {}"}},
+		},
+		Dir:           "/work",
+		Task:          "Validating the code.",
+		Unsaved:       true,
+		FailedResults: []ValidationResult{{Stage: "asan", Error: "heap-use-after-free"}},
+		FixContext:    "asan: heap-use-after-free",
+	}
+	if err := saveRecovery(path, saved); err != nil {
+		t.Fatal(err)
+	}
+	r, err := loadRecovery(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Conversation) != 1 || len(r.Code) != 1 || r.FailedResults[0].Stage != "asan" || r.FixContext != saved.FixContext {
+		t.Errorf("loadRecovery() = %+v", r)
+	}
+	want := "1 message(s), 1 file(s), unsaved, failed gates to fix, stopped while validating the code; saved 2026-10-16 15:04 in /work"
+	if got := r.Summary(); got != want {
+		t.Errorf("Summary() = %q, want %q", got, want)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadRecovery(path); err == nil {
+		t.Error("loadRecovery() accepted a corrupt file")
+	}
+}
+
+func TestShutdownAndRecover(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", os.Getenv("HOME"))
+	newModel := func() Model {
+		return Model{config: &Config{}, tokenTracker: NewTokenTracker(0, 0), styles: NewStyles(NewTheme(&ThemeSettings{Name: "default"}))}
+	}
+
+	// Nothing in flight: nothing is kept
+	m := newModel()
+	m.conversation = []Message{{Role: "user", Content: "hi"}, {Role: "assistant", Content: "hello"}}
+	m.shutdown()
+	path, err := recoveryPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("shutdown() saved a session with nothing in flight")
+	}
+
+	// Quitting while a fix is pending keeps the fix context
+	cancelled := false
+	m.state = StateFixing
+	m.cancelFn = func() { cancelled = true }
+	m.currentCode = "int main() { int* p = new int; delete p; return *p; }"
+	m.failedResults = []ValidationResult{{Stage: "asan", Error: "heap-use-after-free"}}
+	m.lastValidationErrs = "asan: heap-use-after-free"
+	m.shutdown()
+	if !cancelled {
+		t.Error("shutdown() did not cancel the running task")
+	}
+
+	m = newModel()
+	m.recoverSession(false)
+	if m.currentCode == "" || len(m.conversation) != 2 || len(m.failedResults) != 1 || !strings.Contains(m.lastValidationErrs, "heap-use-after-free") {
+		t.Errorf("recoverSession() restored code %q, %d messages, failures %v, errors %q", m.currentCode, len(m.conversation), m.failedResults, m.lastValidationErrs)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("recoverSession() kept the saved session after restoring it")
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Entropy detection: long tokens mixing cases and digits with near-random character spread
+const (
+	minEntropyTokenLen = 24
+	minTokenEntropy    = 3.8 // bits per character
+	maxWordFraction    = 0.4 // Share of characters in lowercase runs of 3+ (identifiers are made of words)
+)
+
+// entropyTokenPattern finds candidate key-like tokens
+var entropyTokenPattern = regexp.MustCompile(`[A-Za-z0-9+/=_-]{24,}`)
+
+// RedactedSecret is one value masked before text leaves the machine
+type RedactedSecret struct {
+	Kind  string
+	Value string
+}
+
+// Redactor masks credentials in prompts, workspace context and attached files
+type Redactor struct {
+	settings RedactionSettings
+}
+
+// NewRedactor creates a redactor from settings
+func NewRedactor(settings RedactionSettings) *Redactor {
+	return &Redactor{settings: settings}
+}
+
+// Enabled reports whether redaction runs
+func (r *Redactor) Enabled() bool {
+	return r != nil && r.settings.Enabled
+}
+
+// Redact replaces secrets with [REDACTED:<kind>] and returns what was masked
+func (r *Redactor) Redact(text string) (string, []RedactedSecret) {
+	if !r.Enabled() {
+		return text, nil
+	}
+
+	var found []RedactedSecret
+	for _, rule := range secretRules {
+		text = replaceSubmatch(rule.pattern, text, rule.group, func(secret string) string {
+			if r.allowed(secret) {
+				return secret
+			}
+			found = append(found, RedactedSecret{Kind: rule.risk, Value: secret})
+			return redactionMarker(rule.risk)
+		})
+	}
+
+	if r.settings.Entropy {
+		text = entropyTokenPattern.ReplaceAllStringFunc(text, func(token string) string {
+			if !looksRandom(token) || r.allowed(token) {
+				return token
+			}
+			found = append(found, RedactedSecret{Kind: "high-entropy string", Value: token})
+			return redactionMarker("high-entropy string")
+		})
+	}
+	return text, found
+}
+
+// allowed reports whether a value is on the allowlist (never masked)
+func (r *Redactor) allowed(value string) bool {
+	for _, a := range r.settings.Allow {
+		if a != "" && strings.Contains(value, a) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactionMarker is the placeholder left in place of a secret
+func redactionMarker(kind string) string {
+	return "[REDACTED:" + strings.ReplaceAll(kind, " ", "-") + "]"
+}
+
+// replaceSubmatch replaces one submatch of each match (0 = whole match) using fn
+func replaceSubmatch(re *regexp.Regexp, text string, group int, fn func(string) string) string {
+	var sb strings.Builder
+	last := 0
+	for _, loc := range re.FindAllStringSubmatchIndex(text, -1) {
+		start, end := loc[2*group], loc[2*group+1]
+		if start < 0 {
+			continue
+		}
+		sb.WriteString(text[last:start])
+		sb.WriteString(fn(text[start:end]))
+		last = end
+	}
+	if last == 0 {
+		return text
+	}
+	sb.WriteString(text[last:])
+	return sb.String()
+}
+
+// looksRandom reports whether a token has the character mix and entropy of a generated key
+func looksRandom(token string) bool {
+	if len(token) < minEntropyTokenLen || strings.HasPrefix(token, "REDACTED") {
+		return false
+	}
+	var upper, lower, digit bool
+	for _, c := range token {
+		switch {
+		case c >= 'A' && c <= 'Z':
+			upper = true
+		case c >= 'a' && c <= 'z':
+			lower = true
+		case c >= '0' && c <= '9':
+			digit = true
+		}
+	}
+	return upper && lower && digit && shannonEntropy(token) >= minTokenEntropy && wordFraction(token) < maxWordFraction
+}
+
+// wordFraction returns the share of characters in runs of three or more lowercase letters
+func wordFraction(s string) float64 {
+	run, inWords := 0, 0
+	for i := 0; i <= len(s); i++ {
+		if i < len(s) && s[i] >= 'a' && s[i] <= 'z' {
+			run++
+			continue
+		}
+		if run >= 3 {
+			inWords += run
+		}
+		run = 0
+	}
+	return float64(inWords) / float64(len(s))
+}
+
+// shannonEntropy returns the bits of entropy per character of s
+func shannonEntropy(s string) float64 {
+	counts := make(map[rune]int)
+	n := 0
+	for _, c := range s {
+		counts[c]++
+		n++
+	}
+	entropy := 0.0
+	for _, count := range counts {
+		p := float64(count) / float64(n)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// summarizeRedactions describes masked secrets by kind, e.g. "AWS access key, high-entropy string ×2"
+func summarizeRedactions(found []RedactedSecret) string {
+	counts := make(map[string]int)
+	for _, f := range found {
+		counts[f.Kind]++
+	}
+	kinds := make([]string, 0, len(counts))
+	for kind, n := range counts {
+		if n > 1 {
+			kind = fmt.Sprintf("%s ×%d", kind, n)
+		}
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return strings.Join(kinds, ", ")
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Consensus modes combine two reviewers' confidences
+const (
+	ConsensusOff     = ""        // One reviewer
+	ConsensusAverage = "average" // The mean of both confidences
+	ConsensusMin     = "min"     // The lower confidence: either reviewer can hold code back
+)
+
+// consensusModes lists the modes accepted by settings and /review
+var consensusModes = []string{ConsensusAverage, ConsensusMin}
+
+// defaultReviewThreshold is the confidence below which a review asks for a fix
+const defaultReviewThreshold = 70
+
+// maxReviewTokens bounds a reviewer's response (a confidence and a one-line summary)
+const maxReviewTokens = 200
+
+// defaultReviewRubric is the built-in review criteria
+var defaultReviewRubric = []string{
+	"Does the code fulfill the stated requirements?",
+	"Are there logic errors that would produce wrong output?",
+	"Are there obvious bugs the sanitizers couldn't catch (infinite loops, wrong algorithm)?",
+}
+
+// formatRubric numbers the review criteria (empty = the built-in ones)
+func formatRubric(rubric []string) string {
+	var criteria []string
+	for _, c := range rubric {
+		if c = strings.TrimSpace(c); c != "" {
+			criteria = append(criteria, c)
+		}
+	}
+	if len(criteria) == 0 {
+		criteria = defaultReviewRubric
+	}
+	lines := make([]string, len(criteria))
+	for i, c := range criteria {
+		lines[i] = fmt.Sprintf("%d. %s", i+1, c)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseConsensus validates a consensus mode ("" and "off" = a single reviewer)
+func parseConsensus(mode string) (string, error) {
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	if mode == "" || mode == "off" {
+		return ConsensusOff, nil
+	}
+	if !slices.Contains(consensusModes, mode) {
+		return "", fmt.Errorf("unknown consensus mode %q (use %s or off)", mode, strings.Join(consensusModes, ", "))
+	}
+	return mode, nil
+}
+
+// parseReviewThreshold validates a review threshold (1-100)
+func parseReviewThreshold(s string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(s), "%"))
+	if err != nil || n < 1 || n > 100 {
+		return 0, fmt.Errorf("invalid threshold %q (must be 1-100)", s)
+	}
+	return n, nil
+}
+
+// reviewScore is one reviewer's verdict
+type reviewScore struct {
+	Model      string
+	Confidence int
+	Summary    string
+}
+
+// reviewVerdict is the review gate's combined verdict
+type reviewVerdict struct {
+	Confidence int
+	Summary    string            // The summary of the least confident reviewer
+	Scores     []reviewScore     // Each reviewer that answered, in panel order
+	Checks     *FunctionalChecks // The reviewer's checks, in functional mode
+	Usage      GenerateResult
+}
+
+// reviewPanel is who reviews code that passed the gates and how their scores combine
+type reviewPanel struct {
+	models     []string // Reviewer model IDs: one, or two in consensus mode
+	consensus  string
+	rubric     []string
+	functional bool // The first reviewer also writes checks that are run against the code
+}
+
+// newReviewPanel builds the panel from settings; canonical model names are mapped by provider
+// reflection is the reviewer when settings name none, and an unknown consensus mode means off
+func newReviewPanel(settings ReviewSettings, reflection string, provider LLMProvider) reviewPanel {
+	resolve := func(model string) string {
+		if IsCanonicalModel(model) && provider != nil {
+			return provider.MapModel(model)
+		}
+		return model
+	}
+	first := settings.Model
+	if first == "" {
+		first = reflection
+	}
+	p := reviewPanel{models: []string{resolve(first)}, rubric: settings.Rubric, functional: settings.Functional}
+	if consensus, _ := parseConsensus(settings.Consensus); consensus != ConsensusOff {
+		second := settings.ConsensusModel
+		if second == "" {
+			second = ModelSonnet
+		}
+		p.models = append(p.models, resolve(second))
+		p.consensus = consensus
+	}
+	return p
+}
+
+// Review asks each reviewer for a verdict on code concurrently and combines them
+// A reviewer that fails is left out; the error is returned only when none answered
+func (p reviewPanel) Review(ctx context.Context, provider LLMProvider, prompts *PromptSet, request, code string) (reviewVerdict, error) {
+	prompt := prompts.Review(request, code, p.rubric)
+	scores := make([]*reviewScore, len(p.models))
+	results := make([]*GenerateResult, len(p.models))
+	errs := make([]error, len(p.models))
+
+	var wg sync.WaitGroup
+	for i, model := range p.models {
+		wg.Add(1)
+		go func(i int, model string) {
+			defer wg.Done()
+			result, err := provider.Generate(ctx, model, "", []Message{{Role: "user", Content: prompt}}, maxReviewTokens)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			confidence, summary := parseReviewResponse(strings.TrimSpace(result.Text))
+			scores[i] = &reviewScore{Model: model, Confidence: confidence, Summary: summary}
+			results[i] = result
+		}(i, model)
+	}
+	wg.Wait()
+
+	var v reviewVerdict
+	for i, s := range scores {
+		if s == nil {
+			continue
+		}
+		v.Scores = append(v.Scores, *s)
+		v.Usage.InputTokens += results[i].InputTokens
+		v.Usage.OutputTokens += results[i].OutputTokens
+	}
+	if len(v.Scores) == 0 {
+		return v, errs[0]
+	}
+	v.Confidence, v.Summary = combineScores(v.Scores, p.consensus)
+	return v, nil
+}
+
+// Assess reviews code and, in functional mode, runs the first reviewer's checks against
+// files at the same time and factors their outcome into the confidence
+// Checks that cannot be written or run are reported but leave the verdict as it is
+func (p reviewPanel) Assess(ctx context.Context, provider LLMProvider, prompts *PromptSet, container *ContainerRuntime, request, code string, files []CodeFile) (reviewVerdict, error) {
+	// Code validated with /validate has no request to derive checks from
+	if !p.functional || container == nil || len(files) == 0 || strings.HasPrefix(request, directValidationPrefix) {
+		return p.Review(ctx, provider, prompts, request, code)
+	}
+
+	var checks *FunctionalChecks
+	var checksErr error
+	var checksUsage GenerateResult
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		checks, checksErr = p.functionalChecks(ctx, provider, container, request, files, &checksUsage)
+	}()
+	v, err := p.Review(ctx, provider, prompts, request, code)
+	<-done
+
+	v.Usage.InputTokens += checksUsage.InputTokens
+	v.Usage.OutputTokens += checksUsage.OutputTokens
+	if err != nil {
+		return v, err
+	}
+	if checksErr != nil {
+		checks = &FunctionalChecks{Error: checksErr.Error()}
+	}
+	v.Checks = checks
+	v.Confidence = checks.Adjust(v.Confidence)
+	if checks.Ran && !checks.AllPassed() {
+		v.Summary = checks.Summary() + ". " + v.Summary
+	}
+	return v, nil
+}
+
+// combineScores applies a consensus mode to the reviewers' scores
+// The summary is the least confident reviewer's, since it names the concerns
+func combineScores(scores []reviewScore, consensus string) (int, string) {
+	lowest, total := scores[0], 0
+	for _, s := range scores {
+		total += s.Confidence
+		if s.Confidence < lowest.Confidence {
+			lowest = s
+		}
+	}
+	if consensus == ConsensusAverage {
+		return (total + len(scores)/2) / len(scores), lowest.Summary
+	}
+	return lowest.Confidence, lowest.Summary
+}
+
+// Breakdown describes how a consensus verdict was reached (empty for a single reviewer)
+func (v reviewVerdict) Breakdown(consensus string) string {
+	if consensus == ConsensusOff {
+		return ""
+	}
+	parts := make([]string, len(v.Scores))
+	for i, s := range v.Scores {
+		parts[i] = fmt.Sprintf("%s %d%%", shortModelName(s.Model), s.Confidence)
+	}
+	if len(v.Scores) < 2 {
+		return fmt.Sprintf("%s only; the other reviewer failed", strings.Join(parts, ""))
+	}
+	return fmt.Sprintf("%s of %s", consensus, strings.Join(parts, ", "))
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// reviewerStub answers review prompts with a fixed response per model
+type reviewerStub struct {
+	LLMProvider
+	responses map[string]string
+	prompts   chan string
+}
+
+func (s reviewerStub) MapModel(model string) string { return "mapped-" + model }
+func (s reviewerStub) Generate(ctx context.Context, model, systemPrompt string, messages []Message, maxTokens int) (*GenerateResult, error) {
+	if s.prompts != nil {
+		s.prompts <- messages[0].Content
+	}
+	text, ok := s.responses[model]
+	if !ok {
+		return nil, errors.New("model unavailable")
+	}
+	return &GenerateResult{Text: text, InputTokens: 100, OutputTokens: 10}, nil
+}
+
+func TestFormatRubric(t *testing.T) {
+	if got := formatRubric(nil); !strings.HasPrefix(got, "1. Does the code fulfill the stated requirements?\n2. ") {
+		t.Errorf("formatRubric(nil) = %q, want the built-in criteria", got)
+	}
+	if got := formatRubric([]string{"Is every lock released?", " ", "Is the API exception-safe?"}); got != "1. Is every lock released?\n2. Is the API exception-safe?" {
+		t.Errorf("formatRubric() = %q", got)
+	}
+}
+
+func TestParseReviewOptions(t *testing.T) {
+	for mode, want := range map[string]string{"": ConsensusOff, "off": ConsensusOff, "MIN": ConsensusMin, "average": ConsensusAverage} {
+		if got, err := parseConsensus(mode); got != want || err != nil {
+			t.Errorf("parseConsensus(%q) = %q, %v; want %q", mode, got, err, want)
+		}
+	}
+	if _, err := parseConsensus("max"); err == nil {
+		t.Error("parseConsensus(\"max\") should fail")
+	}
+
+	for s, want := range map[string]int{"80": 80, "85%": 85, "100": 100} {
+		if got, err := parseReviewThreshold(s); got != want || err != nil {
+			t.Errorf("parseReviewThreshold(%q) = %d, %v; want %d", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"0", "101", "high"} {
+		if _, err := parseReviewThreshold(s); err == nil {
+			t.Errorf("parseReviewThreshold(%q) should fail", s)
+		}
+	}
+}
+
+func TestNewReviewPanel(t *testing.T) {
+	provider := reviewerStub{}
+	p := newReviewPanel(ReviewSettings{Consensus: "unknown"}, "reflection-id", provider)
+	if len(p.models) != 1 || p.models[0] != "reflection-id" {
+		t.Errorf("default panel models = %v, want the reflection model", p.models)
+	}
+
+	p = newReviewPanel(ReviewSettings{Model: "haiku", Consensus: "min", ConsensusModel: "opus"}, "reflection-id", provider)
+	if len(p.models) != 2 || p.models[0] != "mapped-haiku" || p.models[1] != "mapped-opus" || p.consensus != ConsensusMin {
+		t.Errorf("consensus panel = %+v", p)
+	}
+}
+
+func TestReviewPanelConsensus(t *testing.T) {
+	provider := reviewerStub{responses: map[string]string{
+		"fast":    "CONFIDENCE: 90\nSUMMARY: Looks correct.",
+		"careful": "CONFIDENCE: 61\nSUMMARY: Off-by-one in the last bucket.",
+	}}
+	prompts, _ := LoadPromptSet("")
+
+	tests := []struct {
+		consensus string
+		want      int
+		breakdown string
+	}{
+		{ConsensusMin, 61, "min of fast 90%, careful 61%"},
+		{ConsensusAverage, 76, "average of fast 90%, careful 61%"},
+	}
+	for _, tt := range tests {
+		p := reviewPanel{models: []string{"fast", "careful"}, consensus: tt.consensus}
+		v, err := p.Review(context.Background(), provider, prompts, "histogram", "int main() {}")
+		if err != nil {
+			t.Fatalf("Review(%s) error = %v", tt.consensus, err)
+		}
+		if v.Confidence != tt.want || v.Summary != "Off-by-one in the last bucket." {
+			t.Errorf("Review(%s) = %d %q, want %d and the least confident summary", tt.consensus, v.Confidence, v.Summary, tt.want)
+		}
+		if got := v.Breakdown(tt.consensus); got != tt.breakdown {
+			t.Errorf("Breakdown(%s) = %q, want %q", tt.consensus, got, tt.breakdown)
+		}
+		if v.Usage.InputTokens != 200 || v.Usage.OutputTokens != 20 {
+			t.Errorf("Review(%s) usage = %+v, want both reviewers counted", tt.consensus, v.Usage)
+		}
+	}
+}
+
+func TestReviewPanelFailures(t *testing.T) {
+	provider := reviewerStub{responses: map[string]string{"fast": "CONFIDENCE: 88\nSUMMARY: Fine."}}
+	prompts, _ := LoadPromptSet("")
+
+	p := reviewPanel{models: []string{"fast", "down"}, consensus: ConsensusMin}
+	v, err := p.Review(context.Background(), provider, prompts, "req", "code")
+	if err != nil || v.Confidence != 88 {
+		t.Errorf("Review() with one failed reviewer = %d, %v; want the other's verdict", v.Confidence, err)
+	}
+	if got := v.Breakdown(ConsensusMin); !strings.Contains(got, "the other reviewer failed") {
+		t.Errorf("Breakdown() = %q", got)
+	}
+
+	p = reviewPanel{models: []string{"down"}}
+	if _, err := p.Review(context.Background(), provider, prompts, "req", "code"); err == nil {
+		t.Error("Review() with no reviewer answering should fail")
+	}
+}
+
+func TestReviewPanelRubric(t *testing.T) {
+	provider := reviewerStub{responses: map[string]string{"fast": "CONFIDENCE: 95"}, prompts: make(chan string, 1)}
+	prompts, _ := LoadPromptSet("")
+
+	p := reviewPanel{models: []string{"fast"}, rubric: []string{"Are all allocations bounded?"}}
+	if _, err := p.Review(context.Background(), provider, prompts, "req", "code"); err != nil {
+		t.Fatalf("Review() error = %v", err)
+	}
+	prompt := <-provider.prompts
+	if !strings.Contains(prompt, "1. Are all allocations bounded?") || strings.Contains(prompt, defaultReviewRubric[0]) {
+		t.Errorf("review prompt does not use the custom rubric:\n%s", prompt)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// RunInput is the stdin and command-line arguments the program is run with in the
+// sanitizer and run stages, for programs that read input instead of hard-coding it
+type RunInput struct {
+	Stdin   string   // Piped to the program; empty runs it with /dev/null
+	Args    []string // argv[1:]
+	Timeout int      // Seconds each run may take before it counts as hung; 0 is hang.timeout
+}
+
+// maxRunInputBytes bounds a stdin fixture file
+const maxRunInputBytes = 1 << 20
+
+// runInputFile is the stdin fixture's name in the mounted source directory
+const runInputFile = "bjarne_stdin.txt"
+
+// runInputPatterns match "stdin:" and "args:" lines in the Definition of Done answers
+var (
+	stdinLinePattern = regexp.MustCompile(`(?mi)^[ \t]*(?:[-*][ \t]*)?stdin[ \t]*:[ \t]*(.+?)[ \t]*$`)
+	argsLinePattern  = regexp.MustCompile(`(?mi)^[ \t]*(?:[-*][ \t]*)?(?:args|argv|arguments)[ \t]*:[ \t]*(.+?)[ \t]*$`)
+)
+
+// parseRunInput reads "stdin: <text>" and "args: <words>" lines, or returns nil when there are none
+// \n in the stdin text starts a new line, so several lines fit on one
+func parseRunInput(text string) *RunInput {
+	in := &RunInput{}
+	if m := stdinLinePattern.FindStringSubmatch(text); m != nil {
+		in.Stdin = unescapeStdin(strings.Trim(m[1], "`"))
+	}
+	if m := argsLinePattern.FindStringSubmatch(text); m != nil {
+		args, err := splitArgs(strings.Trim(m[1], "`"))
+		if err == nil {
+			in.Args = args
+		}
+	}
+	if in.empty() {
+		return nil
+	}
+	return in
+}
+
+// unescapeStdin turns \n and \t typed on one line into newlines and tabs
+func unescapeStdin(s string) string {
+	return strings.NewReplacer(`\n`, "\n", `\t`, "\t").Replace(s)
+}
+
+// splitArgs splits a command line into words, honoring single and double quotes
+func splitArgs(s string) ([]string, error) {
+	var args []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t':
+			if inWord {
+				args = append(args, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote in arguments", quote)
+	}
+	if inWord {
+		args = append(args, word.String())
+	}
+	return args, nil
+}
+
+// loadRunInputFile reads a stdin fixture file
+func loadRunInputFile(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read stdin file: %w", err)
+	}
+	if info.Size() > maxRunInputBytes {
+		return "", fmt.Errorf("stdin file %s is larger than %d KB", path, maxRunInputBytes>>10)
+	}
+	data, err := os.ReadFile(path) //nolint:gosec // path is given by the user
+	if err != nil {
+		return "", fmt.Errorf("failed to read stdin file: %w", err)
+	}
+	return string(data), nil
+}
+
+func (in *RunInput) empty() bool {
+	return in == nil || (in.Stdin == "" && len(in.Args) == 0)
+}
+
+// write saves the stdin fixture next to the sources, ending it with a newline for line-based readers
+func (in *RunInput) write(dir string) error {
+	if in.empty() || in.Stdin == "" {
+		return nil
+	}
+	stdin := in.Stdin
+	if !strings.HasSuffix(stdin, "\n") {
+		stdin += "\n"
+	}
+	if err := os.WriteFile(filepath.Join(dir, runInputFile), []byte(stdin), 0600); err != nil {
+		return fmt.Errorf("failed to write stdin: %w", err)
+	}
+	return nil
+}
+
+// Describe lists the input for /stdin and the generation prompt
+func (in *RunInput) Describe() []string {
+	if in.empty() {
+		return nil
+	}
+	var lines []string
+	if len(in.Args) > 0 {
+		quoted := make([]string, len(in.Args))
+		for i, a := range in.Args {
+			quoted[i] = shellQuote(a)
+		}
+		lines = append(lines, "args: "+strings.Join(quoted, " "))
+	}
+	if in.Stdin != "" {
+		lines = append(lines, fmt.Sprintf("stdin (%d line(s)):", strings.Count(strings.TrimSuffix(in.Stdin, "\n"), "\n")+1))
+		lines = append(lines, strings.Split(indentLines(firstLines(strings.TrimSuffix(in.Stdin, "\n"), outputMismatchLines), "  "), "\n")...)
+	}
+	if in.Timeout > 0 {
+		lines = append(lines, fmt.Sprintf("timeout: %ds per run", in.Timeout))
+	}
+	return lines
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseRunInput(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want *RunInput
+	}{
+		{"none", "Handle empty input: yes", nil},
+		{"stdin", "stdin: 3 4\\n5 6", &RunInput{Stdin: "3 4\n5 6"}},
+		{"args", "- args: --count 3 \"hello world\"", &RunInput{Args: []string{"--count", "3", "hello world"}}},
+		{"both", "Sort the numbers.\nSTDIN: `5 3 1`\nargv: -r", &RunInput{Stdin: "5 3 1", Args: []string{"-r"}}},
+		{"unterminated quote", "args: 'oops", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRunInput(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseRunInput(%q) = %+v, want %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+
+	dod := ParseDefinitionOfDone("fact(5) -> 120\nstdin: 5")
+	if dod.Input == nil || dod.Input.Stdin != "5" || !strings.Contains(dod.FormatDoDSummary(), "stdin/args input") {
+		t.Errorf("ParseDefinitionOfDone() input = %+v, summary %q", dod.Input, dod.FormatDoDSummary())
+	}
+}
+
+func TestRunInputWrite(t *testing.T) {
+	in := &RunInput{Stdin: "1 2", Args: []string{"-n"}}
+	dir := t.TempDir()
+	if err := in.write(dir); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, runInputFile))
+	if err != nil || string(data) != "1 2\n" {
+		t.Errorf("write() = %q, %v, want the stdin ending in a newline", data, err)
+	}
+}
+
+func TestEditRunInput(t *testing.T) {
+	m := Model{config: &Config{}, tokenTracker: NewTokenTracker(0, 0), styles: NewStyles(NewTheme(&ThemeSettings{Name: "default"}))}
+	m.dod = &DefinitionOfDone{HandleEmpty: true}
+	before := m.dod
+
+	m.editRunInput(`set 3\n1 2 3`)
+	m.editRunInput(`args --sum "a b"`)
+	m.editRunInput("timeout 5")
+	want := &RunInput{Stdin: "3\n1 2 3", Args: []string{"--sum", "a b"}, Timeout: 5}
+	if !reflect.DeepEqual(m.dod.Input, want) || !m.dod.HandleEmpty {
+		t.Errorf("editRunInput() dod = %+v, input %+v, want %+v", m.dod, m.dod.Input, want)
+	}
+	if before.Input != nil {
+		t.Error("editRunInput() changed the DoD held by an earlier snapshot")
+	}
+
+	path := filepath.Join(t.TempDir(), "in.txt")
+	if err := os.WriteFile(path, []byte("from file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	m.editRunInput("load " + path)
+	if m.dod.Input.Stdin != "from file\n" {
+		t.Errorf("editRunInput(load) stdin = %q", m.dod.Input.Stdin)
+	}
+
+	m.editRunInput("clear")
+	if m.dod.Input != nil {
+		t.Errorf("editRunInput(clear) input = %+v, want nil", m.dod.Input)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Backup modes for files /save overwrites (settings: save.backup)
+const (
+	BackupBak   = "bak"   // Copy the old file to <file>.bak next to it
+	BackupStash = "stash" // Copy it under ~/.bjarne/backups/<timestamp>/, keeping every version
+	BackupOff   = "off"
+)
+
+// saveWrite is one file a /save writes
+type saveWrite struct {
+	Path       string
+	Content    string
+	Old        string // Existing content of the file being replaced
+	Overwrites bool   // The target exists with different content
+}
+
+// pendingSave is a /save, waiting for confirmation when it would overwrite files
+type pendingSave struct {
+	writes    []saveWrite
+	savedPath string  // Recorded as the session's save location once written
+	paths     PathMap // Where diagnostics should point afterwards
+	note      string  // Shown after a single-file save instead of its size
+}
+
+// overwrites counts the writes that replace existing files
+func (s *pendingSave) overwrites() int {
+	n := 0
+	for _, w := range s.writes {
+		if w.Overwrites {
+			n++
+		}
+	}
+	return n
+}
+
+// parseBackupMode validates a save.backup setting ("" = bak)
+func parseBackupMode(mode string) (string, error) {
+	switch strings.ToLower(mode) {
+	case "", BackupBak:
+		return BackupBak, nil
+	case BackupStash:
+		return BackupStash, nil
+	case BackupOff:
+		return BackupOff, nil
+	}
+	return "", fmt.Errorf("unknown backup mode %q (use bak, stash or off)", mode)
+}
+
+// findOverwrites fills in Old for writes whose target exists with different content
+// and returns how many there are; unchanged files are not overwrites
+func findOverwrites(writes []saveWrite) int {
+	n := 0
+	for i, w := range writes {
+		data, err := os.ReadFile(w.Path)
+		if err != nil || string(data) == w.Content {
+			continue
+		}
+		writes[i].Old = string(data)
+		writes[i].Overwrites = true
+		n++
+	}
+	return n
+}
+
+// overwritePreview diffs the files a save would overwrite against what they hold now
+func overwritePreview(writes []saveWrite) string {
+	var sb strings.Builder
+	for _, w := range writes {
+		if w.Overwrites {
+			sb.WriteString(UnifiedDiff("existing/"+filepath.ToSlash(w.Path), filepath.ToSlash(w.Path), w.Old, w.Content, 3))
+		}
+	}
+	return sb.String()
+}
+
+// backupsDir returns ~/.bjarne/backups
+func backupsDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".bjarne", "backups"), nil
+}
+
+// backupFile keeps the content a save is about to overwrite and returns where it went
+// stashDir is the directory for this save's stash backups (BackupStash only)
+func backupFile(w saveWrite, mode, stashDir string) (string, error) {
+	var dest string
+	switch mode {
+	case BackupOff:
+		return "", nil
+	case BackupStash:
+		abs, err := filepath.Abs(w.Path)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve %s: %w", w.Path, err)
+		}
+		rel := strings.TrimLeft(strings.TrimPrefix(abs, filepath.VolumeName(abs)), `/\`)
+		dest = filepath.Join(stashDir, rel)
+		if err := os.MkdirAll(filepath.Dir(dest), 0700); err != nil {
+			return "", fmt.Errorf("failed to create backup directory: %w", err)
+		}
+	default:
+		dest = w.Path + ".bak"
+	}
+	if err := os.WriteFile(dest, []byte(w.Old), 0600); err != nil {
+		return "", fmt.Errorf("failed to back up %s: %w", w.Path, err)
+	}
+	return dest, nil
+}
+
+// stashDirFor names the stash directory for a save made at now
+func stashDirFor(now time.Time) (string, error) {
+	dir, err := backupsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, now.Format("2006-01-02_150405")), nil
+}
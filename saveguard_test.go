@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseBackupMode(t *testing.T) {
+	for mode, want := range map[string]string{"": BackupBak, "bak": BackupBak, "STASH": BackupStash, "off": BackupOff} {
+		if got, err := parseBackupMode(mode); got != want || err != nil {
+			t.Errorf("parseBackupMode(%q) = %q, %v; want %q", mode, got, err, want)
+		}
+	}
+	if _, err := parseBackupMode("git"); err == nil {
+		t.Error("parseBackupMode(\"git\") should fail")
+	}
+}
+
+func TestFindOverwrites(t *testing.T) {
+	dir := t.TempDir()
+	changed := filepath.Join(dir, "queue.cpp")
+	same := filepath.Join(dir, "queue.h")
+	for path, content := range map[string]string{changed: "int old;\n", same: "struct Q {};\n"} {
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	save := &pendingSave{writes: []saveWrite{
+		{Path: changed, Content: "int updated;\n"},
+		{Path: same, Content: "struct Q {};\n"},
+		{Path: filepath.Join(dir, "main.cpp"), Content: "int main() {}\n"},
+	}}
+	if n := findOverwrites(save.writes); n != 1 || save.overwrites() != 1 {
+		t.Fatalf("findOverwrites() = %d, want only the changed file", n)
+	}
+	if w := save.writes[0]; !w.Overwrites || w.Old != "int old;\n" {
+		t.Errorf("changed write = %+v", w)
+	}
+
+	preview := overwritePreview(save.writes)
+	for _, want := range []string{"-int old;", "+int updated;"} {
+		if !strings.Contains(preview, want) {
+			t.Errorf("preview missing %q:\n%s", want, preview)
+		}
+	}
+	if strings.Contains(preview, "main.cpp") || strings.Contains(preview, "queue.h") {
+		t.Errorf("preview includes files that are not overwritten:\n%s", preview)
+	}
+}
+
+func TestBackupFile(t *testing.T) {
+	dir := t.TempDir()
+	w := saveWrite{Path: filepath.Join(dir, "queue.cpp"), Content: "new", Old: "old", Overwrites: true}
+
+	got, err := backupFile(w, BackupBak, "")
+	if err != nil || got != w.Path+".bak" {
+		t.Fatalf("backupFile(bak) = %q, %v", got, err)
+	}
+	if data, _ := os.ReadFile(got); string(data) != "old" {
+		t.Errorf(".bak holds %q, want the old content", data)
+	}
+
+	stash := filepath.Join(dir, "stash")
+	got, err = backupFile(w, BackupStash, stash)
+	if err != nil || !strings.HasPrefix(got, stash) || !strings.HasSuffix(got, filepath.Join(filepath.Base(dir), "queue.cpp")) {
+		t.Fatalf("backupFile(stash) = %q, %v", got, err)
+	}
+	if data, _ := os.ReadFile(got); string(data) != "old" {
+		t.Errorf("stash backup holds %q, want the old content", data)
+	}
+
+	if got, err := backupFile(w, BackupOff, ""); got != "" || err != nil {
+		t.Errorf("backupFile(off) = %q, %v; want no backup", got, err)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// File roles that /save places with separate templates
+const (
+	saveRoleSource = "source"
+	saveRoleHeader = "header"
+	saveRoleTest   = "test"
+)
+
+// saveTypePattern finds the first class or struct definition, which names single-file saves
+var saveTypePattern = regexp.MustCompile(`\b(?:class|struct)\s+([A-Za-z_]\w*)\s*(?:final\s*)?[:{]`)
+
+// saveFileRole classifies a file as a header, a test or a source file
+func saveFileRole(name string) string {
+	if isHeaderFile(name) {
+		return saveRoleHeader
+	}
+	base := strings.ToLower(strings.TrimSuffix(filepath.Base(name), filepath.Ext(name)))
+	if base == "test" || base == "tests" || strings.HasPrefix(base, "test_") ||
+		strings.HasSuffix(base, "_test") || strings.HasSuffix(base, "_tests") {
+		return saveRoleTest
+	}
+	return saveRoleSource
+}
+
+// saveFileName names single-file code for /save without a target: after its first type, else main.cpp
+func saveFileName(code string) string {
+	if m := saveTypePattern.FindStringSubmatch(code); m != nil {
+		return convertName(m[1], StyleSnake) + ".cpp"
+	}
+	return "main.cpp"
+}
+
+// templateFor returns the template for a role ("" = none configured)
+func (s SaveLocationSettings) templateFor(role string) string {
+	switch role {
+	case saveRoleHeader:
+		return s.Header
+	case saveRoleTest:
+		return s.Test
+	}
+	return s.Source
+}
+
+// detectSaveLayout returns templates for the standard directories (src, include, tests) in root
+func detectSaveLayout(root string) SaveLocationSettings {
+	isDir := func(name string) bool {
+		info, err := os.Stat(filepath.Join(root, name))
+		return err == nil && info.IsDir()
+	}
+	var layout SaveLocationSettings
+	if isDir("src") {
+		layout.Source = "src/{file}"
+	}
+	if isDir("include") {
+		layout.Header = "include/{file}"
+	}
+	for _, dir := range []string{"tests", "test"} {
+		if isDir(dir) {
+			layout.Test = dir + "/{file}"
+			break
+		}
+	}
+	return layout
+}
+
+// expandSaveTemplate fills in a save template for a file name
+// A template ending in / is a directory; {file}, {name} and {ext} are the file name,
+// the name without its extension, and the extension without its dot
+func expandSaveTemplate(tmpl, filename string) string {
+	if strings.HasSuffix(tmpl, "/") {
+		tmpl += "{file}"
+	}
+	ext := filepath.Ext(filename)
+	return strings.NewReplacer(
+		"{file}", filename,
+		"{name}", strings.TrimSuffix(filename, ext),
+		"{ext}", strings.TrimPrefix(ext, "."),
+	).Replace(tmpl)
+}
+
+// planSavePaths decides where /save without a target writes each file, relative to root
+// Configured templates come first, then the detected layout, for the file's own role and then
+// for sources (headers and tests stay next to the sources when they have no place of their own)
+func planSavePaths(files []CodeFile, settings SaveLocationSettings, root string) map[string]string {
+	var layout SaveLocationSettings
+	if settings.DetectLayout {
+		layout = detectSaveLayout(root)
+	}
+	paths := make(map[string]string, len(files))
+	for _, f := range files {
+		role := saveFileRole(f.Filename)
+		tmpl := "{file}"
+		for _, candidate := range []string{settings.templateFor(role), layout.templateFor(role), settings.Source, layout.Source} {
+			if candidate != "" {
+				tmpl = candidate
+				break
+			}
+		}
+		paths[f.Filename] = filepath.Join(root, filepath.FromSlash(expandSaveTemplate(tmpl, f.Filename)))
+	}
+	return paths
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveFileRole(t *testing.T) {
+	for name, want := range map[string]string{
+		"queue.hpp":       saveRoleHeader,
+		"queue.h":         saveRoleHeader,
+		"queue.cpp":       saveRoleSource,
+		"main.cpp":        saveRoleSource,
+		"queue_test.cpp":  saveRoleTest,
+		"test_queue.cpp":  saveRoleTest,
+		"tests.cpp":       saveRoleTest,
+		"contest.cpp":     saveRoleSource,
+		"attestation.cpp": saveRoleSource,
+	} {
+		if got := saveFileRole(name); got != want {
+			t.Errorf("saveFileRole(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestSaveFileName(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"template <typename T>\nclass RingBuffer final {\n};", "ring_buffer.cpp"},
+		{"struct LRUCache : Base {};", "lru_cache.cpp"},
+		{"// class Foo is not defined here\nint main() {}", "main.cpp"},
+	}
+	for _, tt := range tests {
+		if got := saveFileName(tt.code); got != tt.want {
+			t.Errorf("saveFileName(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestExpandSaveTemplate(t *testing.T) {
+	tests := []struct {
+		tmpl string
+		want string
+	}{
+		{"src/{file}", "src/queue.cpp"},
+		{"src/", "src/queue.cpp"},
+		{"lib/{name}/{name}.{ext}", "lib/queue/queue.cpp"},
+	}
+	for _, tt := range tests {
+		if got := expandSaveTemplate(tt.tmpl, "queue.cpp"); got != tt.want {
+			t.Errorf("expandSaveTemplate(%q) = %q, want %q", tt.tmpl, got, tt.want)
+		}
+	}
+}
+
+func TestPlanSavePaths(t *testing.T) {
+	files := []CodeFile{{Filename: "queue.h"}, {Filename: "queue.cpp"}, {Filename: "queue_test.cpp"}}
+	mkdirs := func(t *testing.T, dirs ...string) string {
+		t.Helper()
+		root := t.TempDir()
+		for _, d := range dirs {
+			if err := os.MkdirAll(filepath.Join(root, d), 0750); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return root
+	}
+
+	tests := []struct {
+		name     string
+		dirs     []string
+		settings SaveLocationSettings
+		want     map[string]string // File name -> path relative to root
+	}{
+		{
+			name:     "no layout",
+			settings: SaveLocationSettings{DetectLayout: true},
+			want:     map[string]string{"queue.h": "queue.h", "queue.cpp": "queue.cpp", "queue_test.cpp": "queue_test.cpp"},
+		},
+		{
+			name:     "src include tests",
+			dirs:     []string{"src", "include", "tests"},
+			settings: SaveLocationSettings{DetectLayout: true},
+			want:     map[string]string{"queue.h": "include/queue.h", "queue.cpp": "src/queue.cpp", "queue_test.cpp": "tests/queue_test.cpp"},
+		},
+		{
+			name:     "src only keeps headers and tests with sources",
+			dirs:     []string{"src"},
+			settings: SaveLocationSettings{DetectLayout: true},
+			want:     map[string]string{"queue.h": "src/queue.h", "queue.cpp": "src/queue.cpp", "queue_test.cpp": "src/queue_test.cpp"},
+		},
+		{
+			name:     "layout detection off",
+			dirs:     []string{"src", "include"},
+			settings: SaveLocationSettings{},
+			want:     map[string]string{"queue.h": "queue.h", "queue.cpp": "queue.cpp", "queue_test.cpp": "queue_test.cpp"},
+		},
+		{
+			name:     "templates over layout",
+			dirs:     []string{"src", "include"},
+			settings: SaveLocationSettings{Source: "lib/{file}", Test: "check/", DetectLayout: true},
+			want:     map[string]string{"queue.h": "include/queue.h", "queue.cpp": "lib/queue.cpp", "queue_test.cpp": "check/queue_test.cpp"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := mkdirs(t, tt.dirs...)
+			got := planSavePaths(files, tt.settings, root)
+			for name, want := range tt.want {
+				if got[name] != filepath.Join(root, filepath.FromSlash(want)) {
+					t.Errorf("%s -> %s, want %s", name, got[name], want)
+				}
+			}
+		})
+	}
+}
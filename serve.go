@@ -0,0 +1,543 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// defaultServeAddr listens on loopback only; other interfaces must be chosen with --addr
+const defaultServeAddr = "127.0.0.1:8484"
+
+// maxServeRequestBytes bounds request bodies (prompts and source files)
+const maxServeRequestBytes = 4 << 20
+
+// server exposes generation and validation over HTTP for editors, bots and CI
+type server struct {
+	cfg       *Config
+	provider  LLMProvider
+	container *ContainerRuntime
+	prompts   *PromptSet
+	rules     *ProjectRules
+	fixes     *FixKnowledge
+	review    reviewPanel
+	redactor  *Redactor
+	guard     *LLMGuardClient
+	token     string
+}
+
+// apiFile is a source file in requests and responses
+type apiFile struct {
+	Filename string `json:"filename"`
+	Content  string `json:"content"`
+}
+
+// apiGate is one validation gate result
+type apiGate struct {
+	Stage      string `json:"stage"`
+	Passed     bool   `json:"passed"`
+	Output     string `json:"output,omitempty"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// generateRequest is the body of POST /generate
+type generateRequest struct {
+	Prompt        string `json:"prompt"`
+	Model         string `json:"model,omitempty"`         // Canonical name, model ID, or provider:model
+	MaxIterations *int   `json:"maxIterations,omitempty"` // Fix attempts after the first generation
+}
+
+// generateResponse is the result of POST /generate
+type generateResponse struct {
+	Passed       bool      `json:"passed"`
+	Code         string    `json:"code"`
+	Files        []apiFile `json:"files,omitempty"`
+	Gates        []apiGate `json:"gates"`
+	Iterations   int       `json:"iterations"`
+	Provider     string    `json:"provider"`
+	Model        string    `json:"model"`
+	Confidence   int       `json:"confidence,omitempty"`
+	Summary      string    `json:"summary,omitempty"`
+	Redacted     []string  `json:"redacted,omitempty"` // Kinds of secrets masked in the prompt
+	InputTokens  int       `json:"inputTokens"`
+	OutputTokens int       `json:"outputTokens"`
+}
+
+// validateRequest is the body of POST /validate: either code and filename, or files
+type validateRequest struct {
+	Code     string    `json:"code,omitempty"`
+	Filename string    `json:"filename,omitempty"`
+	Files    []apiFile `json:"files,omitempty"`
+}
+
+// validateResponse is the result of POST /validate
+type validateResponse struct {
+	Passed bool      `json:"passed"`
+	Gates  []apiGate `json:"gates"`
+}
+
+// apiError is the body of every error response
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// runServe implements `bjarne serve [--addr host:port] [--token T]`
+func runServe(args []string) int {
+	addr := defaultServeAddr
+	token := os.Getenv("BJARNE_SERVE_TOKEN")
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--addr", "--token":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Usage: bjarne serve [--addr host:port] [--token <token>]\n")
+				return 1
+			}
+			if args[i] == "--addr" {
+				addr = args[i+1]
+			} else {
+				token = args[i+1]
+			}
+			i++
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown serve option: %s\n", args[i])
+			return 1
+		}
+	}
+
+	generatedToken := token == ""
+	if generatedToken {
+		var err error
+		if token, err = newServeToken(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+
+	s, err := newServer(token)
+	if err != nil {
+		fmt.Print(FormatUserError(err))
+		return 1
+	}
+
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           s.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = httpServer.Shutdown(shutdown)
+	}()
+
+	fmt.Printf("bjarne serving on http://%s (%s, %s)\n", addr, s.provider.Name(), s.container.ImageName())
+	if generatedToken {
+		fmt.Printf("API token: %s\n", s.token)
+	}
+	fmt.Println("Send it as 'Authorization: Bearer <token>'. Ctrl+C stops the server.")
+
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// newServer sets up the provider, container and prompts the same way the TUI does
+// token is empty when the caller does not authenticate requests (MCP over stdio)
+func newServer(token string) (*server, error) {
+	ctx := context.Background()
+	cfg := LoadConfig()
+
+	container, err := newValidationRuntime(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	audit, err := startAuditSession(cfg.Settings.Audit, newSessionID(time.Now()))
+	if err != nil {
+		return nil, err
+	}
+	SetAuditLog(audit)
+
+	provider, err := NewProvider(ctx, cfg.GetProviderConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	prompts, promptErrs := loadUserPromptSet()
+	for _, err := range promptErrs {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	cwd, _ := os.Getwd()
+	rules, err := LoadProjectRules(cwd)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := parseConsensus(cfg.Settings.Review.Consensus); err != nil {
+		return nil, fmt.Errorf("review settings: %w", err)
+	}
+
+	return &server{
+		cfg:       cfg,
+		provider:  provider,
+		container: container,
+		prompts:   prompts,
+		rules:     rules,
+		fixes:     projectFixKnowledge(cfg.Settings.Validation),
+		review:    newReviewPanel(cfg.Settings.Review, cfg.ReflectionModel, provider),
+		redactor:  NewRedactor(cfg.Settings.Redaction),
+		guard:     NewLLMGuardClient(cfg.Settings.Guard),
+		token:     token,
+	}, nil
+}
+
+// applySettings configures the validation gates from the settings
+func (c *ContainerRuntime) applySettings(s *Settings) {
+	c.SetFormatSettings(s.Format)
+	c.SetClangTidySettings(s.ClangTidy)
+	c.SetDependencySettings(s.Dependencies)
+	c.SetHangSettings(s.Hang)
+	c.SetStressSettings(s.Stress)
+	c.SetExceptionSettings(s.Exceptions)
+	c.SetModelCheckSettings(s.ModelCheck)
+	c.SetValgrindSettings(s.Valgrind)
+	c.SetTemplateSettings(s.Templates)
+	c.SetSandboxSettings(s.Sandbox)
+}
+
+// newValidationRuntime prepares the container runtime with the session's settings and image
+// The image must already be present; headless commands do not pull it
+func newValidationRuntime(ctx context.Context, cfg *Config) (*ContainerRuntime, error) {
+	container, err := DetectContainerRuntime()
+	if err != nil {
+		return nil, err
+	}
+	container.applySettings(cfg.Settings)
+	if err := loadProjectSuppressions(container); err != nil {
+		return nil, err
+	}
+	if err := loadProjectASTRules(container); err != nil {
+		return nil, err
+	}
+	if err := loadProjectFixtures(container); err != nil {
+		return nil, err
+	}
+	plugins, err := loadValidatorPlugins()
+	if err != nil {
+		return nil, err
+	}
+	container.SetValidatorPlugins(plugins)
+	SetNetworkSettings(cfg.Settings.Network)
+	image, err := resolveSessionImage(cfg.Settings.Container)
+	if err != nil {
+		return nil, err
+	}
+	container.SetImage(image.Ref)
+	if !container.ImageExists(ctx) {
+		return nil, fmt.Errorf("validation container %s not found; run 'bjarne' interactively to pull it", image.Ref)
+	}
+	return container, nil
+}
+
+// newServeToken generates a random API token for this run
+func newServeToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate API token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// routes registers the API endpoints behind token auth
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", s.handleHealth)
+	mux.HandleFunc("POST /generate", s.handleGenerate)
+	mux.HandleFunc("POST /validate", s.handleValidate)
+	return s.authorize(mux)
+}
+
+// authorize rejects requests without the bearer token
+func (s *server) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeJSON(w, http.StatusUnauthorized, apiError{Error: "missing or invalid API token"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleHealth reports what the server generates and validates with
+func (s *server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"status":   "ok",
+		"version":  Version,
+		"provider": s.provider.Name(),
+		"image":    s.container.ImageName(),
+	})
+}
+
+// requestError is a problem with a request, reported with an HTTP status
+type requestError struct {
+	status  int
+	message string
+}
+
+func (e *requestError) Error() string {
+	return e.message
+}
+
+// writeError writes err with its request status, or fallback for other errors
+func writeError(w http.ResponseWriter, err error, fallback int) {
+	status := fallback
+	var reqErr *requestError
+	if errors.As(err, &reqErr) {
+		status = reqErr.status
+	}
+	writeJSON(w, status, apiError{Error: err.Error()})
+}
+
+// handleGenerate generates code for a prompt and fixes it until the gates pass or attempts run out
+func (s *server) handleGenerate(w http.ResponseWriter, r *http.Request) {
+	var req generateRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	resp, err := s.runGenerate(r.Context(), req)
+	if err != nil {
+		writeError(w, err, http.StatusBadGateway)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// runGenerate checks, redacts and scans the prompt, then generates and validates
+func (s *server) runGenerate(ctx context.Context, req generateRequest) (generateResponse, error) {
+	if strings.TrimSpace(req.Prompt) == "" {
+		return generateResponse{}, &requestError{http.StatusBadRequest, "prompt is required"}
+	}
+	// Clients can ask for fewer fix rounds than the server allows, never more
+	maxIterations := s.cfg.MaxIterations
+	if req.MaxIterations != nil {
+		if *req.MaxIterations < 0 || *req.MaxIterations > s.cfg.MaxIterations {
+			return generateResponse{}, &requestError{http.StatusBadRequest, fmt.Sprintf("maxIterations must be between 0 and %d", s.cfg.MaxIterations)}
+		}
+		maxIterations = *req.MaxIterations
+	}
+
+	prompt, redacted := s.redactor.Redact(req.Prompt)
+	if s.guard.IsEnabled() {
+		scan, err := s.guard.ScanPrompt(prompt)
+		if err != nil && s.guard.FailClosed() {
+			return generateResponse{}, &requestError{http.StatusServiceUnavailable, "security scan unavailable: " + err.Error()}
+		}
+		if err == nil && !scan.IsValid {
+			return generateResponse{}, &requestError{http.StatusUnprocessableEntity, s.guard.FormatSecurityIssues(scan)}
+		}
+	}
+
+	model := req.Model
+	if model == "" {
+		model = s.cfg.GenerateModel
+	}
+	spec, err := resolveModelSpec(ctx, s.cfg, s.provider, model)
+	if err != nil {
+		return generateResponse{}, &requestError{http.StatusBadRequest, err.Error()}
+	}
+
+	out := s.generate(ctx, prompt, spec, maxIterations)
+	if out.err != nil {
+		return generateResponse{}, out.err
+	}
+	for _, secret := range redacted {
+		out.Redacted = append(out.Redacted, secret.Kind)
+	}
+	return out.generateResponse, nil
+}
+
+// generateOutcome is a generateResponse or the error that prevented one
+type generateOutcome struct {
+	generateResponse
+	err error
+}
+
+// generate runs the candidate pipeline, feeding failures back as fix requests
+func (s *server) generate(ctx context.Context, prompt string, spec candidateSpec, maxIterations int) generateOutcome {
+	systemPrompt := s.prompts.Generation()
+	if section := s.rules.PromptSection(); section != "" {
+		systemPrompt += "\n\n" + section
+	}
+	req := candidateRequest{
+		provider:       s.provider,
+		container:      s.container,
+		systemPrompt:   systemPrompt,
+		conversation:   []Message{{Role: "user", Content: prompt}},
+		maxTokens:      s.cfg.MaxTokens,
+		originalPrompt: prompt,
+		review:         s.review,
+		prompts:        s.prompts,
+		examples:       ParseExampleTests(prompt),
+	}
+
+	var out generateOutcome
+	var fix *pendingFix
+	for iteration := 1; ; iteration++ {
+		c := generateCandidate(ctx, req, spec, iteration)
+		out.Iterations = iteration
+		out.Provider, out.Model = c.Provider, c.Model
+		out.InputTokens += c.InputTokens
+		out.OutputTokens += c.OutputTokens
+		if c.Err != nil {
+			out.err = c.Err
+			if c.Code != "" {
+				out.err = fmt.Errorf("validation failed to run: %w", c.Err)
+			}
+			return out
+		}
+
+		if fix != nil {
+			fix.response = c.Text
+			if _, err := s.fixes.learn(fix, c.Results, c.Code); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+		}
+
+		out.Passed = c.Passed()
+		out.Code = c.Code
+		out.Files = apiFiles(c.Files)
+		out.Gates = apiGates(c.Results)
+		out.Confidence, out.Summary = c.Confidence, c.Summary
+		if out.Passed || iteration > maxIterations {
+			return out
+		}
+
+		var failed []string
+		for _, r := range c.Results {
+			if !r.Success && r.Error != "" {
+				failed = append(failed, FormatErrorForLLM(r.Stage, r.Error, c.validatedFiles()))
+			}
+		}
+		sigs := failureSignatures(c.Results)
+		errs := strings.Join(failed, "\n") + s.fixes.Hints(sigs)
+		fixPrompt := s.prompts.Iteration(c.Code, errs)
+		if len(c.Files) > 1 {
+			fixPrompt = s.prompts.MultiFileIteration(c.Files, errs)
+		}
+		fix = &pendingFix{failures: sigs, before: c.Code}
+		req.conversation = append(req.conversation,
+			Message{Role: "assistant", Content: c.Text},
+			Message{Role: "user", Content: fixPrompt})
+	}
+}
+
+// handleValidate runs code through the gates without generating anything
+func (s *server) handleValidate(w http.ResponseWriter, r *http.Request) {
+	var req validateRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	resp, err := s.validate(r.Context(), req)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// validate runs the gates on the code or files in a request
+func (s *server) validate(ctx context.Context, req validateRequest) (validateResponse, error) {
+	var files []CodeFile
+	var results []ValidationResult
+	var err error
+	switch {
+	case len(req.Files) > 0:
+		files = make([]CodeFile, 0, len(req.Files))
+		for _, f := range req.Files {
+			name := filepath.Base(f.Filename)
+			if name == "." || name == string(filepath.Separator) || f.Content == "" {
+				return validateResponse{}, &requestError{http.StatusBadRequest, "every file needs a filename and content"}
+			}
+			files = append(files, CodeFile{Filename: name, Content: f.Content})
+		}
+		results, err = s.container.ValidateMultiFileCodeWithExamples(ctx, files, nil, nil)
+	case strings.TrimSpace(req.Code) != "":
+		filename := filepath.Base(req.Filename)
+		if req.Filename == "" {
+			filename = "code.cpp"
+		}
+		files = []CodeFile{{Filename: filename, Content: req.Code}}
+		results, err = s.container.ValidateCode(ctx, req.Code, filename)
+	default:
+		return validateResponse{}, &requestError{http.StatusBadRequest, "code or files is required"}
+	}
+	if err != nil {
+		return validateResponse{}, fmt.Errorf("validation failed to run: %w", err)
+	}
+	results = s.container.withPluginValidators(ctx, files, results)
+	return validateResponse{Passed: len(results) > 0 && allPassed(results), Gates: apiGates(results)}, nil
+}
+
+// decodeJSON reads a bounded JSON body, writing a 400 on failure
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxServeRequestBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeJSON(w, http.StatusBadRequest, apiError{Error: "invalid request body: " + err.Error()})
+		return false
+	}
+	return true
+}
+
+// writeJSON writes v with a status code
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// apiGates converts validation results for the API
+func apiGates(results []ValidationResult) []apiGate {
+	gates := make([]apiGate, 0, len(results))
+	for _, r := range results {
+		gates = append(gates, apiGate{
+			Stage:      r.Stage,
+			Passed:     r.Success,
+			Output:     r.Output,
+			Error:      r.Error,
+			DurationMs: r.Duration.Milliseconds(),
+		})
+	}
+	return gates
+}
+
+// apiFiles converts generated files for the API (nil for single-file results)
+func apiFiles(files []CodeFile) []apiFile {
+	if len(files) <= 1 {
+		return nil
+	}
+	out := make([]apiFile, 0, len(files))
+	for _, f := range files {
+		out = append(out, apiFile{Filename: f.Filename, Content: f.Content})
+	}
+	return out
+}
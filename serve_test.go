@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServeAuthAndRequestValidation(t *testing.T) {
+	s := &server{cfg: DefaultConfig(), token: "secret-token"}
+	handler := s.routes()
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		auth       string
+		body       string
+		wantStatus int
+		wantError  string
+	}{
+		{"no token", "POST", "/validate", "", `{"code":"int main(){}"}`, http.StatusUnauthorized, "API token"},
+		{"wrong token", "POST", "/validate", "Bearer nope", `{"code":"int main(){}"}`, http.StatusUnauthorized, "API token"},
+		{"wrong scheme", "POST", "/validate", "Basic secret-token", `{}`, http.StatusUnauthorized, "API token"},
+		{"bad json", "POST", "/validate", "Bearer secret-token", `{"code":`, http.StatusBadRequest, "invalid request body"},
+		{"unknown field", "POST", "/validate", "Bearer secret-token", `{"source":"x"}`, http.StatusBadRequest, "unknown field"},
+		{"nothing to validate", "POST", "/validate", "Bearer secret-token", `{"filename":"a.cpp"}`, http.StatusBadRequest, "code or files"},
+		{"file without name", "POST", "/validate", "Bearer secret-token", `{"files":[{"content":"int x;"}]}`, http.StatusBadRequest, "filename"},
+		{"empty prompt", "POST", "/generate", "Bearer secret-token", `{"prompt":"  "}`, http.StatusBadRequest, "prompt is required"},
+		{"negative iterations", "POST", "/generate", "Bearer secret-token", `{"prompt":"x","maxIterations":-1}`, http.StatusBadRequest, "maxIterations"},
+		{"iterations over the limit", "POST", "/generate", "Bearer secret-token", `{"prompt":"x","maxIterations":1000}`, http.StatusBadRequest, "maxIterations must be between 0 and"},
+		{"wrong method", "GET", "/generate", "Bearer secret-token", ``, http.StatusMethodNotAllowed, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantError == "" {
+				return
+			}
+			var body apiError
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("error body is not JSON: %v", err)
+			}
+			if !strings.Contains(body.Error, tt.wantError) {
+				t.Errorf("error = %q, want it to mention %q", body.Error, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestAPIGatesAndFiles(t *testing.T) {
+	gates := apiGates([]ValidationResult{{Stage: "compile", Success: true}, {Stage: "asan", Error: "heap-use-after-free"}})
+	if len(gates) != 2 || !gates[0].Passed || gates[1].Passed || gates[1].Error != "heap-use-after-free" {
+		t.Errorf("apiGates() = %+v", gates)
+	}
+	if files := apiFiles([]CodeFile{{Filename: "main.cpp", Content: "x"}}); files != nil {
+		t.Errorf("apiFiles(single) = %+v, want nil", files)
+	}
+	if files := apiFiles([]CodeFile{{Filename: "a.h"}, {Filename: "a.cpp"}}); len(files) != 2 || files[1].Filename != "a.cpp" {
+		t.Errorf("apiFiles(multi) = %+v", files)
+	}
+}
@@ -2,17 +2,51 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 )
 
 // Settings represents user-configurable settings stored in ~/.bjarne/settings.json
 type Settings struct {
-	Models     ModelSettings      `json:"models"`
-	Validation ValidationSettings `json:"validation"`
-	Tokens     TokenSettings      `json:"tokens"`
-	Container  ContainerSettings  `json:"container"`
-	Theme      ThemeSettings      `json:"theme"`
+	// Provider is the LLM provider (bedrock, anthropic, openai, gemini, local); BJARNE_PROVIDER overrides it
+	Provider      string               `json:"provider,omitempty"`
+	Models        ModelSettings        `json:"models"`
+	Validation    ValidationSettings   `json:"validation"`
+	Review        ReviewSettings       `json:"review"`
+	Approval      ApprovalSettings     `json:"approval"`
+	Save          SaveLocationSettings `json:"save"`
+	BestOf        BestOfSettings       `json:"bestOf"`
+	Generation    GenerationSettings   `json:"generation"`
+	Tokens        TokenSettings        `json:"tokens"`
+	Container     ContainerSettings    `json:"container"`
+	Format        FormatSettings       `json:"format"`
+	ClangTidy     ClangTidySettings    `json:"clangTidy"`
+	Hang          HangSettings         `json:"hang"`
+	Stress        StressSettings       `json:"stress"`
+	Exceptions    ExceptionSettings    `json:"exceptions"`
+	ModelCheck    ModelCheckSettings   `json:"modelCheck"`
+	Valgrind      ValgrindSettings     `json:"valgrind"`
+	Templates     TemplateSettings     `json:"templates"`
+	Sandbox       SandboxSettings      `json:"sandbox"`
+	Artifacts     ArtifactSettings     `json:"artifacts"`
+	Manifests     ManifestSettings     `json:"manifests"`
+	Attestation   AttestationSettings  `json:"attestation"`
+	Dependencies  DependencySettings   `json:"dependencies"`
+	Naming        NamingSettings       `json:"naming"`
+	Display       DisplaySettings      `json:"display"`
+	Theme         ThemeSettings        `json:"theme"`
+	Notifications NotificationSettings `json:"notifications"`
+	Logging       LoggingSettings      `json:"logging"`
+	Local         LocalSettings        `json:"local"`
+	Network       NetworkSettings      `json:"network"`
+	Guard         GuardSettings        `json:"guard"`
+	Redaction     RedactionSettings    `json:"redaction"`
+	Audit         AuditSettings        `json:"audit"`
+	Hook          HookSettings         `json:"hook"`
+	// RateLimits holds per-provider budgets keyed by provider (anthropic, bedrock, openai, gemini, local)
+	RateLimits map[string]RateLimitSettings `json:"rateLimits,omitempty"`
 }
 
 // ModelSettings configures which models to use for different tasks
@@ -25,8 +59,22 @@ type ModelSettings struct {
 	Generate string `json:"generate"`
 	// Oracle is used for deep architectural analysis (COMPLEX tasks)
 	Oracle string `json:"oracle"`
+	// Complexity maps task complexity to the model that generates (and first fixes) code
+	Complexity ComplexityModels `json:"complexity"`
 	// Escalation is a list of models to try when validation fails (in order)
 	Escalation []string `json:"escalation"`
+	// EscalationAttempts is fix attempts per rung: the complexity model first, then each escalation model
+	EscalationAttempts []int `json:"escalationAttempts"`
+	// ContextWindows overrides input windows in tokens, keyed by canonical name or model ID
+	ContextWindows map[string]int `json:"contextWindows,omitempty"`
+}
+
+// ComplexityModels maps classified complexity to a model (canonical name or full ID)
+type ComplexityModels struct {
+	Easy   string `json:"easy"`
+	Medium string `json:"medium"`
+	// Complex defaults to the oracle model when empty
+	Complex string `json:"complex"`
 }
 
 // ValidationSettings configures the validation behavior
@@ -35,6 +83,61 @@ type ValidationSettings struct {
 	MaxIterations int `json:"maxIterations"`
 	// EscalateOnFailure enables model escalation when validation fails
 	EscalateOnFailure bool `json:"escalateOnFailure"`
+	// LearnFixes remembers which fixes cleared which failures (~/.bjarne/fix_knowledge.json)
+	// and offers them to later fix prompts
+	LearnFixes bool `json:"learnFixes"`
+	// Strategy is how failures are retried: "fix" patches the code every time, "regenerate"
+	// starts over from scratch after RegenerateAfter failed fixes in a row
+	Strategy string `json:"strategy"`
+	// RegenerateAfter is the failed-fix threshold for the regenerate strategy
+	RegenerateAfter int `json:"regenerateAfter"`
+}
+
+// ReviewSettings configures the LLM review gate that runs after the sanitizer gates pass
+type ReviewSettings struct {
+	// Model is the reviewer (canonical name or model ID; empty = models.reflection)
+	Model string `json:"model"`
+	// Threshold is the confidence (0-100) below which a review asks for a fix
+	Threshold int `json:"threshold"`
+	// Rubric replaces the built-in review criteria, one criterion per entry
+	Rubric []string `json:"rubric,omitempty"`
+	// Consensus adds a second reviewer: "average" or "min" combines the two confidences ("" = off)
+	Consensus string `json:"consensus"`
+	// ConsensusModel is the second reviewer in consensus mode
+	ConsensusModel string `json:"consensusModel"`
+	// Functional has the reviewer write a few assertion checks from the request, which are
+	// compiled and run against the code and factored into the confidence
+	Functional bool `json:"functional"`
+	// Consistency compares the code with the analysis given before it was generated
+	// (data structures, complexity, thread safety) and warns when they diverge
+	Consistency bool `json:"consistency"`
+}
+
+// ApprovalSettings configures the confirmation step after validation passes
+type ApprovalSettings struct {
+	// Enabled asks Approve / Regenerate / Edit-prompt before revealing and saving code
+	Enabled bool `json:"enabled"`
+	// AutoAcceptEasy skips the approval step for EASY tasks
+	AutoAcceptEasy bool `json:"autoAcceptEasy"`
+}
+
+// BestOfSettings configures best-of-N generation
+type BestOfSettings struct {
+	// Candidates is how many solutions to generate and validate in parallel (1 = off)
+	Candidates int `json:"candidates"`
+	// Models are used round-robin for candidates (empty = the complexity-based model)
+	Models []string `json:"models"`
+}
+
+// GenerationSettings configures sampling parameters sent with each request
+// Unset values leave the provider's default in place
+type GenerationSettings struct {
+	// Temperature controls randomness (Claude 0-1, OpenAI and Gemini 0-2)
+	Temperature *float64 `json:"temperature,omitempty"`
+	// TopP is the nucleus sampling cutoff (0-1)
+	TopP *float64 `json:"topP,omitempty"`
+	// Seed makes sampling repeatable where supported (OpenAI, Gemini)
+	Seed *int64 `json:"seed,omitempty"`
 }
 
 // TokenSettings configures token budgets
@@ -43,18 +146,327 @@ type TokenSettings struct {
 	MaxPerResponse int `json:"maxPerResponse"`
 	// MaxPerSession is the maximum total tokens per session (0 = unlimited)
 	MaxPerSession int `json:"maxPerSession"`
+	// AutoCompact summarizes older turns before a fix when a threshold is reached
+	AutoCompact bool `json:"autoCompact"`
+	// CompactAt is the input tokens of one request that trigger compaction (0 = only near maxPerSession)
+	CompactAt int `json:"compactAt"`
 }
 
 // ContainerSettings configures the validation container
 type ContainerSettings struct {
-	// Image is the container image to use for validation
+	// Image is the container image to use for validation when no profile is selected
+	Image string `json:"image"`
+	// Profile selects a named image from Profiles (a project's .bjarne/image takes precedence)
+	Profile string `json:"profile"`
+	// Profiles maps names (default, embedded, gpu, qt) to validator images
+	Profiles map[string]ImageProfile `json:"profiles"`
+	// CheckUpdates looks for a newer image at startup and offers /image update
+	CheckUpdates bool `json:"checkUpdates"`
+}
+
+// ImageProfile is a named validator image, optionally pinned to a digest
+type ImageProfile struct {
+	// Image is the image reference (registry/name:tag)
 	Image string `json:"image"`
+	// Digest pins the image (sha256:...); pinned images are not checked for updates
+	Digest string `json:"digest,omitempty"`
+}
+
+// FormatSettings configures the clang-format gate
+type FormatSettings struct {
+	// Check runs clang-format in check mode as a validation gate
+	Check bool `json:"check"`
+	// Style is the clang-format style used when the workspace has no .clang-format
+	Style string `json:"style"`
+	// AutoApply reformats the final code before reveal/save (makes the gate advisory)
+	AutoApply bool `json:"autoApply"`
+}
+
+// ClangTidySettings decides which clang-tidy findings fail the gate; compiler errors always do
+// Patterns are globs over check names, e.g. "bugprone-*"
+type ClangTidySettings struct {
+	// Blocking checks fail the gate on any finding
+	Blocking []string `json:"blocking"`
+	// Advisory checks are reported but never fail the gate, even when a .clang-tidy
+	// WarningsAsErrors entry promotes them
+	Advisory []string `json:"advisory"`
+	// MaxWarnings fails the gate when more findings match neither list (-1 = unlimited)
+	MaxWarnings int `json:"maxWarnings"`
+}
+
+// HangSettings configures the watchdog that kills a program running past a timeout in the
+// sanitizer and run stages, so an infinite loop fails with where it was stuck
+type HangSettings struct {
+	// Timeout is how long, in seconds, the program may run in one stage (0 = no watchdog)
+	Timeout int `json:"timeout"`
+	// Backtrace attaches gdb (or eu-stack) to record every thread's stack before the kill
+	Backtrace bool `json:"backtrace"`
+}
+
+// StressSettings configures the stress gate, which reruns threaded code under TSAN with
+// random delays to catch races that depend on thread timing
+type StressSettings struct {
+	// Enabled runs the gate after TSAN passes on code that uses threads (--gates stress also runs it)
+	Enabled bool `json:"enabled"`
+	// Iterations is how many times the program runs; iteration i uses delay seed i
+	Iterations int `json:"iterations"`
+	// Chaos alternates the TSAN runs with rr's chaos mode when rr works in the container
+	Chaos bool `json:"chaos"`
+}
+
+// ExceptionSettings configures the exceptions gate, which reruns the program with one allocation
+// at a time throwing std::bad_alloc to catch leaks and broken invariants on exception paths
+type ExceptionSettings struct {
+	// Enabled runs the gate after ASAN passes (--gates exceptions also runs it)
+	Enabled bool `json:"enabled"`
+	// MaxInjections is how many runs fail an allocation; programs with more allocations are sampled evenly
+	MaxInjections int `json:"maxInjections"`
+}
+
+// ModelCheckSettings configures the model-check gate, which explores every interleaving of code
+// using weak atomic orderings with GenMC
+type ModelCheckSettings struct {
+	// Enabled runs the gate after TSAN passes on code with relaxed, acquire or release atomics (--gates model-check also runs it)
+	Enabled bool `json:"enabled"`
+	// Unroll is how many iterations of each loop GenMC explores
+	Unroll int `json:"unroll"`
+}
+
+// ValgrindSettings configures the Valgrind gate that complements TSAN on code using raw pthreads
+type ValgrindSettings struct {
+	// Tool is "off", "helgrind" or "drd" (--gates helgrind or --gates drd also runs that tool)
+	Tool string `json:"tool"`
+}
+
+// TemplateSettings configures the gate that instantiates generated templates with other types
+// than the ones main uses, to catch missing constraints and assumptions about the type
+type TemplateSettings struct {
+	// Enabled runs the gate on code that defines templates (--gates templates also runs it)
+	Enabled bool `json:"enabled"`
+	// Types are C++ types to instantiate with; "move-only" is a type that cannot be copied
+	Types []string `json:"types"`
+}
+
+// SandboxSettings configures the network of the stages that run the program
+type SandboxSettings struct {
+	// Network is "none" (the container's loopback only) or "netns" (each run gets its own
+	// loopback-only namespace, for client/server code); the container never has egress
+	Network string `json:"network"`
+}
+
+// ArtifactSettings configures copying the binaries of validated code into .bjarne/artifacts
+type ArtifactSettings struct {
+	// Export copies the optimized binary out of the container once every gate passes
+	Export bool `json:"export"`
+	// Sanitizers also exports the ASan, UBSan, MSan and TSan builds
+	Sanitizers bool `json:"sanitizers"`
+}
+
+// ManifestSettings configures the manifests `bjarne reproduce` replays
+type ManifestSettings struct {
+	// Enabled writes .bjarne/manifests/<id>.json after each validation that passes
+	Enabled bool `json:"enabled"`
+}
+
+// AttestationSettings configures the in-toto attestation written with each validation manifest
+type AttestationSettings struct {
+	// Enabled writes .bjarne/manifests/<id>.intoto.json next to each manifest
+	Enabled bool `json:"enabled"`
+	// Key is an Ed25519 private key (PEM) to sign with, from bjarne attest keygen (empty = unsigned)
+	Key string `json:"key"`
+	// Sigstore also signs the attestation with cosign's keyless flow
+	Sigstore bool `json:"sigstore"`
+}
+
+// Default clang-format style when no project .clang-format exists
+const defaultFormatStyle = "LLVM"
+
+// DependencySettings configures third-party libraries generated code may use
+type DependencySettings struct {
+	// Manager installs libraries inside the validation container: "vcpkg" or "conan"
+	Manager string `json:"manager"`
+	// Allowed lists the libraries code may declare (empty = standard library only)
+	Allowed []string `json:"allowed"`
+}
+
+// NamingSettings configures naming-convention enforcement (conventions come from /init)
+type NamingSettings struct {
+	// Mode is "off", "warn" (report deviations) or "fix" (rename generated identifiers)
+	Mode string `json:"mode"`
+}
+
+// LocalSettings configures the local provider (BJARNE_PROVIDER=local, and --offline)
+type LocalSettings struct {
+	// BaseURL is an OpenAI-compatible endpoint such as Ollama or llama.cpp's server
+	BaseURL string `json:"baseUrl"`
+	// Model is used for every task (local servers usually host one model)
+	Model string `json:"model"`
+	// ContextWindow is the model's input window in tokens (match the server's context size)
+	ContextWindow int `json:"contextWindow"`
+}
+
+// NetworkSettings configures outbound HTTP (proxies come from HTTP_PROXY/HTTPS_PROXY/NO_PROXY)
+type NetworkSettings struct {
+	// CABundle is a PEM file of extra root CAs (e.g. a corporate proxy's), trusted with the system roots
+	CABundle string `json:"caBundle"`
+	// TLS holds per-endpoint settings: anthropic, openai, gemini, local, bedrock, llmguard, downloads, updates
+	TLS map[string]TLSSettings `json:"tls"`
+}
+
+// TLSSettings configures TLS for one endpoint
+type TLSSettings struct {
+	// CABundle replaces network.caBundle for this endpoint
+	CABundle string `json:"caBundle"`
+	// ClientCert and ClientKey are PEM files for mutual TLS
+	ClientCert string `json:"clientCert"`
+	ClientKey  string `json:"clientKey"`
+	// InsecureSkipVerify disables certificate checks (last resort for broken proxies)
+	InsecureSkipVerify bool `json:"insecureSkipVerify"`
+}
+
+// GuardSettings configures security scanning of prompts and generated code
+type GuardSettings struct {
+	// Enabled turns scanning on
+	Enabled bool `json:"enabled"`
+	// Endpoint is an LLM Guard API URL (LLMGUARD_URL overrides); empty = built-in rules only
+	Endpoint string `json:"endpoint"`
+	// APIKey authenticates to LLM Guard (LLMGUARD_TOKEN overrides)
+	APIKey string `json:"apiKey"`
+	// Scanners limits which scanners can flag a request, e.g. ["PromptInjection", "Secrets"] (empty = all)
+	Scanners []string `json:"scanners"`
+	// Threshold flags results scoring at or above it (0 = use each scanner's verdict)
+	Threshold float64 `json:"threshold"`
+	// FailurePolicy is "open" (warn and continue when the service is unreachable) or "closed" (block)
+	FailurePolicy string `json:"failurePolicy"`
+	// LocalRules enables the built-in secret and prompt-injection patterns: used alone without an
+	// endpoint, and as the fallback when the service is unreachable
+	LocalRules bool `json:"localRules"`
+}
+
+// RedactionSettings configures masking of secrets before anything is sent to a provider
+type RedactionSettings struct {
+	// Enabled masks known credential formats in prompts, attached files and workspace context
+	Enabled bool `json:"enabled"`
+	// Entropy also masks long random-looking tokens (keys without a known prefix)
+	Entropy bool `json:"entropy"`
+	// Allow lists substrings of values that must never be masked (e.g. public test keys)
+	Allow []string `json:"allow"`
+}
+
+// RateLimitSettings caps requests to one provider (0 = unlimited)
+type RateLimitSettings struct {
+	// RequestsPerMinute is the most requests started in any minute
+	RequestsPerMinute int `json:"rpm"`
+	// TokensPerMinute is the most input plus output tokens in any minute
+	TokensPerMinute int `json:"tpm"`
+}
+
+// AuditSettings configures the per-session log of LLM calls in ~/.bjarne/audit
+type AuditSettings struct {
+	// Enabled records every prompt, model, response hash and token count
+	Enabled bool `json:"enabled"`
+	// MaxSessions keeps at most this many session logs (0 = unlimited)
+	MaxSessions int `json:"maxSessions"`
+	// MaxAgeDays deletes session logs older than this (0 = never)
+	MaxAgeDays int `json:"maxAgeDays"`
+}
+
+// SaveLocationSettings configures where /save puts files when no target is given, and how
+// it protects the files it overwrites
+// Templates use {file} (file name), {name} (without extension) and {ext}; a trailing / is a directory
+type SaveLocationSettings struct {
+	// Source places source files, e.g. "src/{file}" (empty = detected layout, else the current directory)
+	Source string `json:"source,omitempty"`
+	// Header places headers, e.g. "include/{file}" (empty = next to the sources)
+	Header string `json:"header,omitempty"`
+	// Test places test files (test_*.cpp, *_test.cpp), e.g. "tests/" (empty = next to the sources)
+	Test string `json:"test,omitempty"`
+	// DetectLayout uses existing src/, include/ and tests/ directories when no template applies
+	DetectLayout bool `json:"detectLayout"`
+	// Backup keeps overwritten files: "bak" (<file>.bak), "stash" (~/.bjarne/backups/<time>/) or "off"
+	Backup string `json:"backup"`
+	// Index adds saved files to the workspace and semantic indexes built by /init
+	Index bool `json:"index"`
+}
+
+// HookSettings configures the git pre-commit hook (bjarne hook install)
+type HookSettings struct {
+	// Gates run on staged files; keep them fast and leave the sanitizers to CI
+	Gates []string `json:"gates"`
+}
+
+// Guard failure policies
+const (
+	GuardFailOpen   = "open"
+	GuardFailClosed = "closed"
+)
+
+// DisplaySettings configures how output is presented
+type DisplaySettings struct {
+	// Mode is "scrollback" (print to terminal history) or "altscreen" (full-screen pager)
+	Mode string `json:"mode"`
+	// Highlight enables syntax highlighting of code (disabled when NO_COLOR is set)
+	Highlight bool `json:"highlight"`
+}
+
+// NotificationSettings configures desktop notifications and the terminal bell, for events that
+// happen while you are in another window
+type NotificationSettings struct {
+	// ValidationDone is a generated task passing validation, or failing it for good
+	ValidationDone NotifyEvent `json:"validationDone"`
+	// BudgetWarning is the session nearing tokens.maxPerSession
+	BudgetWarning NotifyEvent `json:"budgetWarning"`
+	// EscalationExhausted is every fix attempt and escalation model failing
+	EscalationExhausted NotifyEvent `json:"escalationExhausted"`
+	// MinSeconds skips validation notifications for tasks that finish sooner (0 = always notify)
+	MinSeconds int `json:"minSeconds"`
+}
+
+// NotifyEvent chooses how one kind of event is announced
+type NotifyEvent struct {
+	// Desktop shows a native notification (osascript, notify-send or PowerShell)
+	Desktop bool `json:"desktop"`
+	// Bell rings the terminal bell
+	Bell bool `json:"bell"`
+}
+
+// LoggingSettings configures bjarne's log file, for diagnosing provider, container and index problems
+type LoggingSettings struct {
+	// Level is the least severe record written: debug, info, warn, error or off; --log-level overrides it
+	Level string `json:"level"`
+	// Format is text (key=value lines) or json (one object per line)
+	Format string `json:"format"`
+	// File is the log path (default ~/.bjarne/logs/bjarne.log)
+	File string `json:"file,omitempty"`
+	// MaxSizeMB rotates the file once it grows past this size (0 = never rotate)
+	MaxSizeMB int `json:"maxSizeMB"`
+	// MaxFiles is how many rotated files (bjarne.log.1, .2, ...) are kept
+	MaxFiles int `json:"maxFiles"`
+	// Subsystems overrides the level for provider, container, index or tui
+	Subsystems map[string]string `json:"subsystems,omitempty"`
 }
 
 // ThemeSettings configures the UI appearance
 type ThemeSettings struct {
-	// Name is the theme preset name
+	// Name is a theme preset or one of the Custom themes
 	Name string `json:"name"`
+	// Custom defines themes by name (a name shared with a preset replaces it)
+	Custom map[string]CustomTheme `json:"custom,omitempty"`
+}
+
+// CustomTheme is a user-defined theme. Colors are "#rrggbb" (or "#rgb") hex, drawn in
+// truecolor where the terminal supports it, or built-in color names; unset ones come from Base
+type CustomTheme struct {
+	// Base is the preset the theme starts from (default "default")
+	Base string `json:"base,omitempty"`
+	// Highlight is the chroma style for code (default: the base preset's)
+	Highlight string `json:"highlight,omitempty"`
+	Prompt    string `json:"prompt,omitempty"`
+	Success   string `json:"success,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Warning   string `json:"warning,omitempty"`
+	Info      string `json:"info,omitempty"`
+	Accent    string `json:"accent,omitempty"`
 }
 
 // ThemePreset defines colors for a complete theme
@@ -75,25 +487,135 @@ func DefaultSettings() *Settings {
 			Reflection: "global.anthropic.claude-haiku-4-5-20251001-v1:0", // Haiku for quick classification
 			Generate:   "global.anthropic.claude-haiku-4-5-20251001-v1:0", // Default gen (overridden by complexity)
 			Oracle:     "global.anthropic.claude-opus-4-5-20251101-v1:0",  // Opus for COMPLEX
+			Complexity: ComplexityModels{
+				Easy:   ModelHaiku,
+				Medium: ModelSonnet,
+			},
 			Escalation: []string{
 				"global.anthropic.claude-sonnet-4-5-20250929-v1:0", // Haiku → Sonnet
 				"global.anthropic.claude-opus-4-5-20251101-v1:0",   // Sonnet → Opus
 			},
+			EscalationAttempts: []int{5, 5, 5},
 		},
 		Validation: ValidationSettings{
 			MaxIterations:     3,
 			EscalateOnFailure: true,
+			LearnFixes:        true,
+			Strategy:          StrategyFix,
+			RegenerateAfter:   defaultRegenerateAfter,
+		},
+		Review: ReviewSettings{
+			Threshold:      defaultReviewThreshold,
+			ConsensusModel: ModelSonnet,
+			Consistency:    true,
+		},
+		Approval: ApprovalSettings{
+			Enabled:        false,
+			AutoAcceptEasy: true,
+		},
+		Save: SaveLocationSettings{
+			DetectLayout: true,
+			Backup:       BackupBak,
+			Index:        true,
+		},
+		BestOf: BestOfSettings{
+			Candidates: 1,
 		},
 		Tokens: TokenSettings{
 			MaxPerResponse: 8192,
 			MaxPerSession:  150000,
+			AutoCompact:    true,
+			CompactAt:      40000,
 		},
 		Container: ContainerSettings{
-			Image: defaultValidatorImage,
+			Image:        defaultValidatorImage,
+			Profiles:     defaultImageProfiles(),
+			CheckUpdates: true,
+		},
+		Format: FormatSettings{
+			Check:     true,
+			Style:     defaultFormatStyle,
+			AutoApply: true,
+		},
+		ClangTidy: ClangTidySettings{
+			MaxWarnings: -1,
+		},
+		Hang: HangSettings{
+			Timeout:   30,
+			Backtrace: true,
+		},
+		Stress: StressSettings{
+			Iterations: 20,
+			Chaos:      true,
+		},
+		Exceptions: ExceptionSettings{
+			MaxInjections: 100,
+		},
+		ModelCheck: ModelCheckSettings{
+			Unroll: 3,
+		},
+		Valgrind: ValgrindSettings{
+			Tool: ValgrindToolOff,
+		},
+		Templates: TemplateSettings{
+			Types: []string{"int", "std::string", moveOnlyType, "const int"},
+		},
+		Sandbox: SandboxSettings{
+			Network: SandboxNetworkNone,
+		},
+		Artifacts: ArtifactSettings{
+			Sanitizers: true,
+		},
+		Manifests: ManifestSettings{
+			Enabled: true,
+		},
+		Dependencies: DependencySettings{
+			Manager: DependencyManagerVcpkg,
+		},
+		Naming: NamingSettings{
+			Mode: NamingModeWarn,
+		},
+		Display: DisplaySettings{
+			Mode:      DisplayScrollback,
+			Highlight: true,
 		},
 		Theme: ThemeSettings{
 			Name: "default",
 		},
+		Notifications: NotificationSettings{
+			ValidationDone:      NotifyEvent{Desktop: true},
+			BudgetWarning:       NotifyEvent{Desktop: true},
+			EscalationExhausted: NotifyEvent{Desktop: true},
+			MinSeconds:          30,
+		},
+		Logging: LoggingSettings{
+			Level:     LogLevelWarn,
+			Format:    LogFormatText,
+			MaxSizeMB: 10,
+			MaxFiles:  3,
+		},
+		Guard: GuardSettings{
+			Enabled:       true,
+			FailurePolicy: GuardFailOpen,
+			LocalRules:    true,
+		},
+		Redaction: RedactionSettings{
+			Enabled: true,
+			Entropy: true,
+		},
+		Audit: AuditSettings{
+			Enabled:     true,
+			MaxSessions: 200,
+			MaxAgeDays:  90,
+		},
+		Hook: HookSettings{
+			Gates: []string{"clang-tidy", "compile"},
+		},
+		Local: LocalSettings{
+			BaseURL:       defaultLocalBaseURL,
+			Model:         defaultLocalModel,
+			ContextWindow: defaultLocalContextWindow,
+		},
 	}
 }
 
@@ -127,7 +649,7 @@ func LoadSettings() (*Settings, error) {
 
 	// Parse JSON, keeping defaults for missing fields
 	if err := json.Unmarshal(data, settings); err != nil {
-		return settings, err
+		return settings, settingsParseError(path, data, err)
 	}
 
 	return settings, nil
@@ -152,9 +674,19 @@ func SaveSettings(settings *Settings) error {
 		return err
 	}
 
-	return os.WriteFile(path, data, 0600)
+	// Another session may be saving too: take turns, and replace the file in one
+	// step so a session loading it never reads half a file
+	lock, err := lockWait(path+".lock", settingsLockTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to lock settings: %w", err)
+	}
+	defer func() { _ = lock.Unlock() }()
+	return writeFileAtomic(path, data, 0600)
 }
 
+// settingsLockTimeout bounds the wait for another session saving its settings
+const settingsLockTimeout = 5 * time.Second
+
 // ANSI color codes (256-color mode for richer themes)
 var colorCodes = map[string]string{
 	// Basic colors
@@ -247,16 +779,21 @@ var ThemePresets = map[string]ThemePreset{
 
 // Theme provides color formatting based on settings
 type Theme struct {
-	preset ThemePreset
+	preset    ThemePreset
+	highlight string // Chroma style for code
 }
 
-// NewTheme creates a theme from settings
+// NewTheme creates a theme from settings: a custom theme, else a preset (unknown names use default)
 func NewTheme(settings *ThemeSettings) *Theme {
-	preset, ok := ThemePresets[settings.Name]
+	if custom, ok := settings.Custom[settings.Name]; ok {
+		return custom.theme()
+	}
+	name := settings.Name
+	preset, ok := ThemePresets[name]
 	if !ok {
-		preset = ThemePresets["default"]
+		name, preset = "default", ThemePresets["default"]
 	}
-	return &Theme{preset: preset}
+	return &Theme{preset: preset, highlight: chromaStyles[name]}
 }
 
 // Prompt formats text with the prompt color
@@ -313,6 +850,9 @@ func getColorCode(color string) string {
 	if code, ok := colorCodes[color]; ok {
 		return code
 	}
+	if r, g, b, ok := parseHexColor(color); ok {
+		return hexColorCode(r, g, b)
+	}
 	return colorCodes["white"]
 }
 
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// settingsFormField is one editable setting in the /settings form
+type settingsFormField struct {
+	Group   string
+	Path    string                     // settings.json key path, e.g. "validation.maxIterations"
+	Choices func(s *Settings) []string // Allowed values for a string setting (nil = free text)
+}
+
+// settingsFormFields are the settings /settings edits, in display order
+var settingsFormFields = []settingsFormField{
+	{Group: "Models", Path: "models.generate"},
+	{Group: "Models", Path: "models.chat"},
+	{Group: "Models", Path: "models.reflection"},
+	{Group: "Models", Path: "models.oracle"},
+	{Group: "Models", Path: "models.complexity.easy"},
+	{Group: "Models", Path: "models.complexity.medium"},
+	{Group: "Models", Path: "models.complexity.complex"},
+	{Group: "Validation", Path: "validation.maxIterations"},
+	{Group: "Validation", Path: "validation.escalateOnFailure"},
+	{Group: "Validation", Path: "validation.strategy", Choices: func(*Settings) []string { return []string{StrategyFix, StrategyRegenerate} }},
+	{Group: "Validation", Path: "validation.regenerateAfter"},
+	{Group: "Validation", Path: "review.threshold"},
+	{Group: "Validation", Path: "hang.timeout"},
+	{Group: "Validation", Path: "hang.backtrace"},
+	{Group: "Validation", Path: "stress.enabled"},
+	{Group: "Validation", Path: "stress.iterations"},
+	{Group: "Validation", Path: "exceptions.enabled"},
+	{Group: "Validation", Path: "exceptions.maxInjections"},
+	{Group: "Validation", Path: "modelCheck.enabled"},
+	{Group: "Validation", Path: "modelCheck.unroll"},
+	{Group: "Validation", Path: "valgrind.tool", Choices: func(*Settings) []string { return valgrindTools }},
+	{Group: "Validation", Path: "templates.enabled"},
+	{Group: "Validation", Path: "sandbox.network", Choices: func(*Settings) []string { return sandboxNetworks }},
+	{Group: "Validation", Path: "artifacts.export"},
+	{Group: "Validation", Path: "artifacts.sanitizers"},
+	{Group: "Validation", Path: "manifests.enabled"},
+	{Group: "Validation", Path: "attestation.enabled"},
+	{Group: "Validation", Path: "attestation.key"},
+	{Group: "Tokens", Path: "tokens.maxPerResponse"},
+	{Group: "Tokens", Path: "tokens.maxPerSession"},
+	{Group: "Tokens", Path: "tokens.autoCompact"},
+	{Group: "Tokens", Path: "tokens.compactAt"},
+	{Group: "Container", Path: "container.image"},
+	{Group: "Theme", Path: "theme.name", Choices: func(s *Settings) []string { return s.Theme.Names() }},
+	{Group: "Notifications", Path: "notifications.validationDone.desktop"},
+	{Group: "Notifications", Path: "notifications.validationDone.bell"},
+	{Group: "Notifications", Path: "notifications.escalationExhausted.desktop"},
+	{Group: "Notifications", Path: "notifications.escalationExhausted.bell"},
+	{Group: "Notifications", Path: "notifications.budgetWarning.desktop"},
+	{Group: "Notifications", Path: "notifications.budgetWarning.bell"},
+	{Group: "Notifications", Path: "notifications.minSeconds"},
+	{Group: "Logging", Path: "logging.level", Choices: func(*Settings) []string { return logLevels }},
+	{Group: "Logging", Path: "logging.format", Choices: func(*Settings) []string { return []string{LogFormatText, LogFormatJSON} }},
+}
+
+// settingsForm is the state of the /settings editor: it edits the session's settings in place
+type settingsForm struct {
+	settings *Settings
+	fields   []settingsFormField
+	cursor   int
+	editing  bool   // The selected field's value is being typed in the input
+	err      string // Why the last change was rejected
+	changed  bool   // A setting changed since the form was opened
+}
+
+// newSettingsForm opens the editor on settings
+func newSettingsForm(settings *Settings) *settingsForm {
+	return &settingsForm{settings: settings, fields: settingsFormFields}
+}
+
+// current returns the selected field
+func (f *settingsForm) current() settingsFormField {
+	return f.fields[f.cursor]
+}
+
+// move selects the field delta rows away, stopping at either end
+func (f *settingsForm) move(delta int) {
+	f.cursor = max(0, min(len(f.fields)-1, f.cursor+delta))
+	f.err = ""
+}
+
+// field returns the settable value behind a form field
+func (f *settingsForm) field(path string) reflect.Value {
+	return settingValue(reflect.ValueOf(f.settings).Elem(), strings.Split(path, "."))
+}
+
+// value renders a field's value as it is typed in
+func (f *settingsForm) value(path string) string {
+	return fmt.Sprint(f.field(path).Interface())
+}
+
+// toggles reports whether the selected field changes with left/right rather than typing
+func (f *settingsForm) toggles() bool {
+	return f.current().Choices != nil || f.field(f.current().Path).Kind() == reflect.Bool
+}
+
+// cycle flips a boolean or steps through the choices of the selected field
+func (f *settingsForm) cycle(delta int) error {
+	field := f.current()
+	v := f.field(field.Path)
+	if v.Kind() == reflect.Bool {
+		return f.set(strconv.FormatBool(!v.Bool()))
+	}
+	choices := field.Choices(f.settings)
+	i := indexOf(choices, v.String())
+	n := len(choices)
+	return f.set(choices[((i+delta)%n+n)%n])
+}
+
+// set parses text into the selected field, keeping the old value when it does not validate
+func (f *settingsForm) set(text string) error {
+	path := f.current().Path
+	v := f.field(path)
+	old := reflect.ValueOf(v.Interface())
+	text = strings.TrimSpace(text)
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(text)
+	case reflect.Int:
+		n, err := strconv.Atoi(text)
+		if err != nil {
+			return f.reject(fmt.Errorf("%s: expected a whole number, got %q", path, text))
+		}
+		v.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return f.reject(fmt.Errorf("%s: expected true or false, got %q", path, text))
+		}
+		v.SetBool(b)
+	}
+
+	for _, issue := range f.settings.Validate() {
+		if issue.Path == path {
+			v.Set(old)
+			return f.reject(fmt.Errorf("%s: %s", path, issue.Message))
+		}
+	}
+	if !reflect.DeepEqual(old.Interface(), v.Interface()) {
+		f.changed = true
+	}
+	f.err = ""
+	return nil
+}
+
+// reject records why a change was refused
+func (f *settingsForm) reject(err error) error {
+	f.err = err.Error()
+	return err
+}
+
+// indexOf returns the position of s in list, or -1
+func indexOf(list []string, s string) int {
+	for i, item := range list {
+		if item == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// settingsFormHeight is the lines the form takes below the output (title, groups, fields, help, error)
+func settingsFormHeight() int {
+	groups := 0
+	for i, field := range settingsFormFields {
+		if i == 0 || field.Group != settingsFormFields[i-1].Group {
+			groups++
+		}
+	}
+	return len(settingsFormFields) + groups + 3
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// selectSetting moves the form's cursor to path
+func selectSetting(t *testing.T, f *settingsForm, path string) {
+	t.Helper()
+	for i, field := range f.fields {
+		if field.Path == path {
+			f.cursor = i
+			return
+		}
+	}
+	t.Fatalf("no form field %s", path)
+}
+
+func TestSettingsFormSet(t *testing.T) {
+	tests := []struct {
+		path    string
+		text    string
+		want    string
+		wantErr string
+	}{
+		{"validation.maxIterations", " 7 ", "7", ""},
+		{"validation.maxIterations", "many", "3", "expected a whole number"},
+		{"validation.maxIterations", "0", "3", "must be at least 1"},
+		{"models.generate", "opus", "opus", ""},
+		{"models.generate", "sonet", DefaultSettings().Models.Generate, `did you mean "sonnet"`},
+		{"tokens.autoCompact", "false", "false", ""},
+		{"tokens.autoCompact", "maybe", "true", "expected true or false"},
+		{"theme.name", "nord", "nord", ""},
+		{"theme.name", "neon", "default", "unknown theme"},
+		{"container.image", "example.com/validator:2", "example.com/validator:2", ""},
+	}
+	for _, tt := range tests {
+		f := newSettingsForm(DefaultSettings())
+		selectSetting(t, f, tt.path)
+		err := f.set(tt.text)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("set %s=%q: %v", tt.path, tt.text, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr) || f.err != err.Error()):
+			t.Errorf("set %s=%q error = %v (form shows %q), want %q", tt.path, tt.text, err, f.err, tt.wantErr)
+		}
+		if got := f.value(tt.path); got != tt.want {
+			t.Errorf("after set %s=%q value = %q, want %q", tt.path, tt.text, got, tt.want)
+		}
+		if f.changed != (tt.wantErr == "") {
+			t.Errorf("set %s=%q changed = %v", tt.path, tt.text, f.changed)
+		}
+	}
+}
+
+func TestSettingsFormCycle(t *testing.T) {
+	f := newSettingsForm(DefaultSettings())
+
+	selectSetting(t, f, "validation.strategy")
+	if !f.toggles() {
+		t.Fatal("strategy should toggle")
+	}
+	if err := f.cycle(1); err != nil || f.settings.Validation.Strategy != StrategyRegenerate {
+		t.Errorf("strategy after cycle = %q, %v", f.settings.Validation.Strategy, err)
+	}
+	if err := f.cycle(1); err != nil || f.settings.Validation.Strategy != StrategyFix {
+		t.Errorf("strategy should wrap around, got %q, %v", f.settings.Validation.Strategy, err)
+	}
+
+	selectSetting(t, f, "theme.name")
+	if err := f.cycle(-1); err != nil || f.settings.Theme.Name != "nord" {
+		t.Errorf("theme before default = %q, %v", f.settings.Theme.Name, err)
+	}
+
+	selectSetting(t, f, "validation.escalateOnFailure")
+	before := f.settings.Validation.EscalateOnFailure
+	if err := f.cycle(1); err != nil || f.settings.Validation.EscalateOnFailure == before {
+		t.Errorf("escalateOnFailure did not flip: %v", err)
+	}
+
+	selectSetting(t, f, "models.chat")
+	if f.toggles() {
+		t.Error("models.chat is typed, not toggled")
+	}
+
+	f.cursor = 0
+	f.move(-1)
+	if f.cursor != 0 {
+		t.Errorf("cursor = %d, want it to stop at the top", f.cursor)
+	}
+	f.move(len(f.fields) + 5)
+	if f.cursor != len(f.fields)-1 {
+		t.Errorf("cursor = %d, want it to stop at the bottom", f.cursor)
+	}
+}
+
+func TestConfigSyncSettings(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Provider = ProviderAnthropic
+	cfg.Settings.Models.Generate = "opus"
+	cfg.Settings.Tokens.MaxPerSession = 1000
+	cfg.Settings.Theme.Name = "nord"
+	cfg.syncSettings()
+
+	if cfg.GenerateModel != "opus" || cfg.MaxTotalTokens != 1000 || cfg.WarnTokenThreshold != 800 {
+		t.Errorf("derived values not updated: %+v", cfg)
+	}
+	if cfg.Theme.preset != ThemePresets["nord"] {
+		t.Errorf("theme = %+v, want nord", cfg.Theme.preset)
+	}
+	if cfg.Provider != ProviderAnthropic {
+		t.Errorf("provider = %q, want it kept", cfg.Provider)
+	}
+}
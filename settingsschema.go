@@ -0,0 +1,675 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// SettingsIssue is one problem found in a settings file
+type SettingsIssue struct {
+	Path    string // Key path such as "review.threshold" or "models.escalation[1]" ("" = the whole file)
+	Line    int    // 1-based line in the file (0 = unknown)
+	Message string
+}
+
+// String renders the issue as "line N: path: message"
+func (i SettingsIssue) String() string {
+	var sb strings.Builder
+	if i.Line > 0 {
+		fmt.Fprintf(&sb, "line %d: ", i.Line)
+	}
+	if i.Path != "" {
+		sb.WriteString(i.Path + ": ")
+	}
+	sb.WriteString(i.Message)
+	return sb.String()
+}
+
+// Provider names accepted in settings (ParseProviderType's aliases included)
+var settingsProviders = []string{"bedrock", "aws", "anthropic", "claude", "openai", "gpt", "gemini", "google", "local", "ollama"}
+
+// tlsEndpoints are the keys network.tls understands
+var tlsEndpoints = []string{endpointAnthropic, endpointOpenAI, endpointGemini, endpointLocal, endpointBedrock, endpointLLMGuard, endpointDownloads, endpointUpdates}
+
+// ValidateSettingsFile checks a settings.json or .bjarne.toml file; a missing file has no issues
+func ValidateSettingsFile(path string) ([]SettingsIssue, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read settings: %w", err)
+	}
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		return checkProjectSettings(data), nil
+	}
+	return checkSettingsData(data), nil
+}
+
+// settingsFiles returns settings.json and the working directory's .bjarne.toml
+func settingsFiles() []string {
+	var files []string
+	if path, err := SettingsPath(); err == nil {
+		files = append(files, path)
+	}
+	if cwd, err := os.Getwd(); err == nil {
+		files = append(files, filepath.Join(cwd, projectSettingsFile))
+	}
+	return files
+}
+
+// checkSettingsData validates settings.json content against the Settings schema:
+// JSON syntax, value types, unknown keys, and values bjarne would reject or misread
+func checkSettingsData(data []byte) []SettingsIssue {
+	w := &settingsWalker{data: data, dec: json.NewDecoder(bytes.NewReader(data)), lines: make(map[string]int)}
+	w.dec.UseNumber()
+	err := w.value(reflect.TypeOf(Settings{}), "")
+	if err == nil {
+		if _, extra := w.dec.Token(); extra != io.EOF {
+			err = errors.New("unexpected data after the settings object")
+		}
+	}
+	if err != nil {
+		// Nothing after a syntax error can be trusted
+		return append(w.issues, SettingsIssue{Path: w.path, Line: w.line(w.dec.InputOffset()), Message: syntaxMessage(err)})
+	}
+
+	// Mistyped values keep their defaults, and are already reported
+	issues := w.issues
+	settings := DefaultSettings()
+	_ = json.Unmarshal(data, settings)
+	for _, issue := range settings.Validate() {
+		issue.Line = w.lines[issue.Path]
+		issues = append(issues, issue)
+	}
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].Line < issues[j].Line })
+	return issues
+}
+
+// syntaxMessage describes a JSON syntax error without encoding/json's prefix
+func syntaxMessage(err error) string {
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) || strings.Contains(err.Error(), "unexpected end of JSON input") {
+		return "unexpected end of file (missing } or ]?)"
+	}
+	return "invalid JSON: " + err.Error()
+}
+
+// settingsParseError makes a json.Unmarshal error from LoadSettings point at the line and key
+func settingsParseError(path string, data []byte, err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		return fmt.Errorf("%s line %d: %w", path, 1+bytes.Count(data[:syntaxErr.Offset], []byte("\n")), err)
+	case errors.As(err, &typeErr):
+		return fmt.Errorf("%s line %d: %s: expected %s, got %s", path, 1+bytes.Count(data[:typeErr.Offset], []byte("\n")), typeErr.Field, describeType(typeErr.Type), typeErr.Value)
+	}
+	return fmt.Errorf("%s: %w", path, err)
+}
+
+// settingsWalker reads settings.json token by token, matching keys against the Settings
+// struct so that every unknown key and mistyped value is found (json.Unmarshal stops at the first)
+type settingsWalker struct {
+	data   []byte
+	dec    *json.Decoder
+	path   string         // Key being read, for syntax errors
+	lines  map[string]int // Line of each key path
+	issues []SettingsIssue
+}
+
+// line converts a byte offset into a 1-based line number
+func (w *settingsWalker) line(offset int64) int {
+	if offset > int64(len(w.data)) {
+		offset = int64(len(w.data))
+	}
+	return 1 + bytes.Count(w.data[:offset], []byte("\n"))
+}
+
+// addIssue records a problem at the current position
+func (w *settingsWalker) addIssue(path, format string, args ...any) {
+	w.issues = append(w.issues, SettingsIssue{Path: path, Line: w.line(w.dec.InputOffset()), Message: fmt.Sprintf(format, args...)})
+}
+
+// value reads the next value, which should fit t
+func (w *settingsWalker) value(t reflect.Type, path string) error {
+	w.path = path
+	tok, err := w.dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil // null keeps the default
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map:
+		if tok != json.Delim('{') {
+			w.addIssue(path, "expected %s, got %s", describeType(t), describeToken(tok))
+			return w.skip(tok)
+		}
+		for w.dec.More() {
+			keyTok, err := w.dec.Token()
+			if err != nil {
+				return err
+			}
+			key, _ := keyTok.(string)
+			keyPath := key
+			if path != "" {
+				keyPath = path + "." + key
+			}
+			w.lines[keyPath] = w.line(w.dec.InputOffset())
+			var elem reflect.Type
+			if t.Kind() == reflect.Map {
+				elem = t.Elem()
+			} else if field, ok := settingsField(t, key); ok {
+				elem = field.Type
+			} else {
+				w.addIssue(keyPath, "unknown key%s", suggestKey(t, key))
+				if err := w.skipValue(); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := w.value(elem, keyPath); err != nil {
+				return err
+			}
+		}
+		_, err := w.dec.Token() // }
+		return err
+
+	case reflect.Slice:
+		if tok != json.Delim('[') {
+			w.addIssue(path, "expected %s, got %s", describeType(t), describeToken(tok))
+			return w.skip(tok)
+		}
+		for i := 0; w.dec.More(); i++ {
+			itemPath := fmt.Sprintf("%s[%d]", path, i)
+			w.lines[itemPath] = w.line(w.dec.InputOffset())
+			if err := w.value(t.Elem(), itemPath); err != nil {
+				return err
+			}
+		}
+		_, err := w.dec.Token() // ]
+		return err
+	}
+
+	ok := false
+	switch v := tok.(type) {
+	case string:
+		ok = t.Kind() == reflect.String
+	case bool:
+		ok = t.Kind() == reflect.Bool
+	case json.Number:
+		switch t.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			_, err := strconv.ParseInt(string(v), 10, t.Bits())
+			ok = err == nil
+		case reflect.Float32, reflect.Float64:
+			ok = true
+		}
+	}
+	if !ok && t.Kind() != reflect.Interface {
+		w.addIssue(path, "expected %s, got %s", describeType(t), describeToken(tok))
+	}
+	return w.skip(tok)
+}
+
+// skipValue reads and discards the next value
+func (w *settingsWalker) skipValue() error {
+	tok, err := w.dec.Token()
+	if err != nil {
+		return err
+	}
+	return w.skip(tok)
+}
+
+// skip discards the rest of a value whose first token has been read
+func (w *settingsWalker) skip(tok json.Token) error {
+	depth := 0
+	for {
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+		var err error
+		if tok, err = w.dec.Token(); err != nil {
+			return err
+		}
+	}
+}
+
+// settingsField finds the struct field a JSON key decodes into, matching as
+// encoding/json does: exact tag first, then case-insensitively
+func settingsField(t reflect.Type, key string) (reflect.StructField, bool) {
+	var folded *reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := jsonFieldName(f)
+		if name == "" {
+			continue
+		}
+		if name == key {
+			return f, true
+		}
+		if folded == nil && strings.EqualFold(name, key) {
+			folded = &f
+		}
+	}
+	if folded != nil {
+		return *folded, true
+	}
+	return reflect.StructField{}, false
+}
+
+// jsonFieldName returns the key a struct field is read from ("" = not read from JSON)
+func jsonFieldName(f reflect.StructField) string {
+	if !f.IsExported() {
+		return ""
+	}
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return ""
+	}
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name
+	}
+	return f.Name
+}
+
+// suggestKey returns " (did you mean \"x\"?)" for a key one or two edits from a real one
+func suggestKey(t reflect.Type, key string) string {
+	best, bestDist := "", 3
+	for i := 0; i < t.NumField(); i++ {
+		name := jsonFieldName(t.Field(i))
+		if name == "" {
+			continue
+		}
+		if d := editDistance(strings.ToLower(key), strings.ToLower(name)); d < bestDist {
+			best, bestDist = name, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (did you mean %q?)", best)
+}
+
+// editDistance is the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// describeType names a Go type the way a settings.json author thinks of it
+func describeType(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map:
+		return "an object"
+	case reflect.Slice, reflect.Array:
+		return "a list"
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "a whole number"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	}
+	return t.String()
+}
+
+// describeToken names the JSON value that was found instead
+func describeToken(tok json.Token) string {
+	switch v := tok.(type) {
+	case json.Delim:
+		if v == '{' {
+			return "an object"
+		}
+		return "a list"
+	case string:
+		return strconv.Quote(truncateError(v, 40))
+	case json.Number:
+		return string(v)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return fmt.Sprint(tok)
+}
+
+// Validate reports values that parse but that bjarne would reject, ignore or misread
+func (s *Settings) Validate() []SettingsIssue {
+	var issues []SettingsIssue
+	add := func(path, format string, args ...any) {
+		issues = append(issues, SettingsIssue{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+	check := func(path string, err error) {
+		if err != nil {
+			add(path, "%v", err)
+		}
+	}
+	atLeast := func(path string, v, lo int) {
+		if v < lo {
+			add(path, "must be at least %d (got %d)", lo, v)
+		}
+	}
+	between := func(path string, v, lo, hi float64) {
+		if v < lo || v > hi {
+			add(path, "must be between %g and %g (got %g)", lo, hi, v)
+		}
+	}
+	model := func(path, id string) {
+		check(path, validateModelID(id))
+	}
+
+	if s.Provider != "" && !slices.Contains(settingsProviders, strings.ToLower(s.Provider)) {
+		add("provider", "unknown provider %q (use bedrock, anthropic, openai, gemini or local)", s.Provider)
+	}
+
+	// Models
+	model("models.chat", s.Models.Chat)
+	model("models.reflection", s.Models.Reflection)
+	model("models.generate", s.Models.Generate)
+	model("models.oracle", s.Models.Oracle)
+	model("models.complexity.easy", s.Models.Complexity.Easy)
+	model("models.complexity.medium", s.Models.Complexity.Medium)
+	model("models.complexity.complex", s.Models.Complexity.Complex)
+	for i, m := range s.Models.Escalation {
+		model(fmt.Sprintf("models.escalation[%d]", i), m)
+	}
+	for i, n := range s.Models.EscalationAttempts {
+		atLeast(fmt.Sprintf("models.escalationAttempts[%d]", i), n, 1)
+	}
+	for _, name := range sortedKeys(s.Models.ContextWindows) {
+		atLeast("models.contextWindows."+name, s.Models.ContextWindows[name], 1)
+	}
+
+	// Validation and review
+	atLeast("validation.maxIterations", s.Validation.MaxIterations, 1)
+	_, err := parseFixStrategy(s.Validation.Strategy)
+	check("validation.strategy", err)
+	atLeast("validation.regenerateAfter", s.Validation.RegenerateAfter, 1)
+	model("review.model", s.Review.Model)
+	if s.Review.Threshold < 1 || s.Review.Threshold > 100 {
+		add("review.threshold", "must be between 1 and 100 (got %d)", s.Review.Threshold)
+	}
+	_, err = parseConsensus(s.Review.Consensus)
+	check("review.consensus", err)
+	model("review.consensusModel", s.Review.ConsensusModel)
+
+	// Generation
+	if s.BestOf.Candidates < 1 || s.BestOf.Candidates > maxCandidates {
+		add("bestOf.candidates", "must be between 1 and %d (got %d)", maxCandidates, s.BestOf.Candidates)
+	}
+	for i, m := range s.BestOf.Models {
+		model(fmt.Sprintf("bestOf.models[%d]", i), m)
+	}
+	if t := s.Generation.Temperature; t != nil {
+		between("generation.temperature", *t, 0, 2)
+	}
+	if p := s.Generation.TopP; p != nil {
+		between("generation.topP", *p, 0, 1)
+	}
+	atLeast("tokens.maxPerResponse", s.Tokens.MaxPerResponse, 1)
+	atLeast("tokens.maxPerSession", s.Tokens.MaxPerSession, 0)
+	atLeast("tokens.compactAt", s.Tokens.CompactAt, 0)
+
+	// Container and gates
+	if _, err := resolveSessionImage(s.Container); err != nil {
+		add("container.profile", "%v", err)
+	}
+	atLeast("clangTidy.maxWarnings", s.ClangTidy.MaxWarnings, -1)
+	if t := s.Hang.Timeout; t < 0 || t > maxHangTimeout {
+		add("hang.timeout", "must be between 0 (off) and %d seconds (got %d)", maxHangTimeout, t)
+	}
+	if n := s.Stress.Iterations; n < 1 || n > maxStressIterations {
+		add("stress.iterations", "must be between 1 and %d (got %d)", maxStressIterations, n)
+	}
+	if n := s.Exceptions.MaxInjections; n < 1 || n > maxExceptionInjections {
+		add("exceptions.maxInjections", "must be between 1 and %d (got %d)", maxExceptionInjections, n)
+	}
+	if n := s.ModelCheck.Unroll; n < 1 || n > maxModelCheckUnroll {
+		add("modelCheck.unroll", "must be between 1 and %d (got %d)", maxModelCheckUnroll, n)
+	}
+	if t := s.Valgrind.Tool; t != "" && !slices.Contains(valgrindTools, t) {
+		add("valgrind.tool", "unknown tool %q (use off, helgrind or drd)", t)
+	}
+	if n := s.Sandbox.Network; n != "" && !slices.Contains(sandboxNetworks, n) {
+		add("sandbox.network", "unknown mode %q (use none or netns)", n)
+	}
+	for i, t := range s.Templates.Types {
+		if strings.TrimSpace(t) == "" || strings.ContainsAny(t, ";{}") {
+			add(fmt.Sprintf("templates.types[%d]", i), "must be a C++ type such as int or std::string (got %q)", t)
+		}
+	}
+	if m := s.Dependencies.Manager; m != "" && m != DependencyManagerVcpkg && m != DependencyManagerConan {
+		add("dependencies.manager", "unknown package manager %q (use vcpkg or conan)", m)
+	}
+	if m := s.Naming.Mode; m != "" && m != NamingModeOff && m != NamingModeWarn && m != NamingModeFix {
+		add("naming.mode", "unknown mode %q (use off, warn or fix)", m)
+	}
+	_, err = parseBackupMode(s.Save.Backup)
+	check("save.backup", err)
+	_, err = ParseGateList(strings.Join(s.Hook.Gates, ","))
+	check("hook.gates", err)
+
+	// Display
+	if m := s.Display.Mode; m != "" && m != DisplayScrollback && m != DisplayAltScreen {
+		add("display.mode", "unknown mode %q (use %s or %s)", m, DisplayScrollback, DisplayAltScreen)
+	}
+	if name := s.Theme.Name; name != "" && !slices.Contains(s.Theme.Names(), name) {
+		add("theme.name", "unknown theme %q (use %s)", name, strings.Join(s.Theme.Names(), ", "))
+	}
+	for _, name := range sortedKeys(s.Theme.Custom) {
+		custom, path := s.Theme.Custom[name], "theme.custom."+name
+		if custom.Base != "" {
+			if _, ok := ThemePresets[custom.Base]; !ok {
+				add(path+".base", "unknown preset %q (use %s)", custom.Base, strings.Join(AvailableThemes(), ", "))
+			}
+		}
+		if custom.Highlight != "" {
+			if _, ok := styles.Registry[custom.Highlight]; !ok {
+				add(path+".highlight", "unknown chroma style %q", custom.Highlight)
+			}
+		}
+		colors := custom.colors()
+		for _, key := range sortedKeys(colors) {
+			if c := colors[key]; c != "" && !validThemeColor(c) {
+				add(path+"."+key, "invalid color %q (use #rrggbb or a built-in color name)", c)
+			}
+		}
+	}
+
+	atLeast("notifications.minSeconds", s.Notifications.MinSeconds, 0)
+	if _, err := parseLogLevel(s.Logging.Level); err != nil {
+		add("logging.level", "%v", err)
+	}
+	if f := s.Logging.Format; f != "" && f != LogFormatText && f != LogFormatJSON {
+		add("logging.format", "unknown format %q (use %s or %s)", f, LogFormatText, LogFormatJSON)
+	}
+	atLeast("logging.maxSizeMB", s.Logging.MaxSizeMB, 0)
+	atLeast("logging.maxFiles", s.Logging.MaxFiles, 0)
+	for _, name := range sortedKeys(s.Logging.Subsystems) {
+		if !slices.Contains(logSubsystems, name) {
+			add("logging.subsystems."+name, "unknown subsystem (use %s)", strings.Join(logSubsystems, ", "))
+		} else if _, err := parseLogLevel(s.Logging.Subsystems[name]); err != nil {
+			add("logging.subsystems."+name, "%v", err)
+		}
+	}
+
+	// Providers and network
+	if s.Local.BaseURL != "" {
+		if u, err := url.Parse(s.Local.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("local.baseUrl", "not an http(s) URL: %q", s.Local.BaseURL)
+		}
+	}
+	atLeast("local.contextWindow", s.Local.ContextWindow, 0)
+	for _, name := range sortedKeys(s.Network.TLS) {
+		if !slices.Contains(tlsEndpoints, name) {
+			add("network.tls."+name, "unknown endpoint (use %s)", strings.Join(tlsEndpoints, ", "))
+		}
+	}
+	for _, name := range sortedKeys(s.RateLimits) {
+		if !slices.Contains(settingsProviders, name) {
+			add("rateLimits."+name, "unknown provider (use bedrock, anthropic, openai, gemini or local)")
+		}
+		atLeast("rateLimits."+name+".rpm", s.RateLimits[name].RequestsPerMinute, 0)
+		atLeast("rateLimits."+name+".tpm", s.RateLimits[name].TokensPerMinute, 0)
+	}
+	if p := s.Guard.FailurePolicy; p != "" && p != GuardFailOpen && p != GuardFailClosed {
+		add("guard.failurePolicy", "unknown policy %q (use %s or %s)", p, GuardFailOpen, GuardFailClosed)
+	}
+	between("guard.threshold", s.Guard.Threshold, 0, 1)
+	atLeast("audit.maxSessions", s.Audit.MaxSessions, 0)
+	atLeast("audit.maxAgeDays", s.Audit.MaxAgeDays, 0)
+	return issues
+}
+
+// validateModelID accepts "" (use the default), a canonical name, or something shaped like a
+// full model ID; a bare word that is not haiku, sonnet or opus is almost always a typo
+func validateModelID(id string) error {
+	if id == "" || IsCanonicalModel(id) {
+		return nil
+	}
+	if strings.ContainsAny(id, " \t\n") {
+		return fmt.Errorf("invalid model %q (model IDs contain no spaces)", id)
+	}
+	if !strings.ContainsAny(id, "-.:/") {
+		for _, c := range []string{ModelHaiku, ModelSonnet, ModelOpus} {
+			if editDistance(strings.ToLower(id), c) <= 2 {
+				return fmt.Errorf("unknown model %q (did you mean %q?)", id, c)
+			}
+		}
+		return fmt.Errorf("unknown model %q (use haiku, sonnet, opus or a full model ID)", id)
+	}
+	return nil
+}
+
+// maxStartupIssues bounds the settings problems listed when bjarne starts
+const maxStartupIssues = 5
+
+// printSettingsIssues lists problems in the settings files under the startup status line
+func printSettingsIssues() {
+	for _, path := range settingsFiles() {
+		issues, err := ValidateSettingsFile(path)
+		if err != nil || len(issues) == 0 {
+			continue
+		}
+		fmt.Printf("    \033[93m●\033[0m %s has %d problem(s); affected settings use their defaults or may misbehave\n", path, len(issues))
+		for i, issue := range issues {
+			if i == maxStartupIssues {
+				fmt.Printf("      ... and %d more (bjarne config validate lists them all)\n", len(issues)-i)
+				break
+			}
+			fmt.Printf("      %s\n", issue)
+		}
+	}
+}
+
+// configUsage is printed for unknown `bjarne config` arguments
+const configUsage = `Usage:
+  bjarne config validate [settings.json | .bjarne.toml]   Check settings files (default: both)
+  bjarne config show [--origin]                           Print the effective settings`
+
+// runConfig implements `bjarne config`
+func runConfig(args []string) int {
+	switch {
+	case len(args) >= 1 && len(args) <= 2 && args[0] == "validate":
+		return runConfigValidate(args[1:])
+	case len(args) == 1 && args[0] == "show":
+		return runConfigShow(false)
+	case len(args) == 2 && args[0] == "show" && args[1] == "--origin":
+		return runConfigShow(true)
+	}
+	fmt.Fprintln(os.Stderr, configUsage)
+	return 1
+}
+
+// runConfigValidate checks the named settings file, or settings.json and .bjarne.toml
+func runConfigValidate(args []string) int {
+	files := args
+	if len(files) == 0 {
+		files = settingsFiles()
+	}
+	failed, checked := false, 0
+	for _, path := range files {
+		if _, err := os.Stat(path); err != nil {
+			if os.IsNotExist(err) && len(args) == 0 {
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		checked++
+		issues, err := ValidateSettingsFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if len(issues) == 0 {
+			fmt.Printf("\033[92m✓\033[0m %s is valid\n", path)
+			continue
+		}
+		failed = true
+		fmt.Printf("\033[91m✗\033[0m %s: %d problem(s)\n", path, len(issues))
+		for _, issue := range issues {
+			fmt.Println("  " + issue.String())
+		}
+	}
+	if checked == 0 {
+		fmt.Println("No settings files; bjarne uses the defaults")
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// runConfigShow prints the effective settings, optionally with where each came from
+func runConfigShow(origins bool) int {
+	cfg := LoadConfig()
+	cfg.Layers.Show(os.Stdout, origins)
+	for _, issue := range cfg.Layers.Issues {
+		fmt.Fprintf(os.Stderr, "\033[93mwarning:\033[0m %s\n", issue)
+	}
+	return 0
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckSettingsDataDefaults(t *testing.T) {
+	data, err := json.MarshalIndent(DefaultSettings(), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issues := checkSettingsData(data); len(issues) != 0 {
+		t.Errorf("default settings have issues: %v", issues)
+	}
+	if issues := checkSettingsData([]byte("{}")); len(issues) != 0 {
+		t.Errorf("empty settings have issues: %v", issues)
+	}
+}
+
+func TestCheckSettingsData(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want []string // Rendered issues, in order
+	}{
+		{
+			name: "unknown keys",
+			json: "{\n  \"validaton\": {},\n  \"review\": {\"treshold\": 80, \"colour\": 1}\n}",
+			want: []string{
+				`line 2: validaton: unknown key (did you mean "validation"?)`,
+				`line 3: review.treshold: unknown key (did you mean "threshold"?)`,
+				`line 3: review.colour: unknown key`,
+			},
+		},
+		{
+			name: "keys match case-insensitively like encoding/json",
+			json: `{"Review": {"THRESHOLD": 80}}`,
+		},
+		{
+			name: "types",
+			json: "{\n  \"tokens\": {\"maxPerResponse\": \"big\"},\n  \"validation\": {\"maxIterations\": 2.5},\n  \"hook\": {\"gates\": \"compile\"},\n  \"models\": {\"escalation\": [\"opus\", 3]}\n}",
+			want: []string{
+				`line 2: tokens.maxPerResponse: expected a whole number, got "big"`,
+				`line 3: validation.maxIterations: expected a whole number, got 2.5`,
+				`line 4: hook.gates: expected a list, got "compile"`,
+				`line 5: models.escalation[1]: expected a string, got 3`,
+			},
+		},
+		{
+			name: "values",
+			json: "{\n  \"provider\": \"antropic\",\n  \"models\": {\"generate\": \"sonet\"},\n  \"review\": {\"threshold\": 150},\n  \"generation\": {\"temperature\": 3},\n  \"rateLimits\": {\"openai\": {\"rpm\": -1}},\n  \"bestOf\": {\"candidates\": 100}\n}",
+			want: []string{
+				`line 2: provider: unknown provider "antropic" (use bedrock, anthropic, openai, gemini or local)`,
+				`line 3: models.generate: unknown model "sonet" (did you mean "sonnet"?)`,
+				`line 4: review.threshold: must be between 1 and 100 (got 150)`,
+				`line 5: generation.temperature: must be between 0 and 2 (got 3)`,
+				`line 6: rateLimits.openai.rpm: must be at least 0 (got -1)`,
+				`line 7: bestOf.candidates: must be between 1 and 8 (got 100)`,
+			},
+		},
+		{
+			name: "null keeps the default",
+			json: `{"review": null, "generation": {"temperature": null}}`,
+		},
+		{
+			name: "syntax error",
+			json: "{\n  \"review\": {\n    \"threshold\": 80,\n  }\n}",
+			want: []string{"line 3: review.threshold: invalid JSON"},
+		},
+		{
+			name: "truncated",
+			json: "{\n  \"review\": {",
+			want: []string{"line 2: review: unexpected end of file"},
+		},
+		{
+			name: "not an object",
+			json: `["a"]`,
+			want: []string{"line 1: expected an object, got a list"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := checkSettingsData([]byte(tt.json))
+			if len(issues) != len(tt.want) {
+				t.Fatalf("got %d issues, want %d: %v", len(issues), len(tt.want), issues)
+			}
+			for i, issue := range issues {
+				if !strings.HasPrefix(issue.String(), tt.want[i]) {
+					t.Errorf("issue %d = %q, want %q", i, issue.String(), tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidateModelID(t *testing.T) {
+	tests := []struct {
+		id      string
+		wantErr string
+	}{
+		{"", ""},
+		{"sonnet", ""},
+		{"claude-sonnet-4-5-20250929", ""},
+		{"global.anthropic.claude-opus-4-5-20251101-v1:0", ""},
+		{"gpt-4o", ""},
+		{"qwen2.5-coder:7b", ""},
+		{"hiaku", `did you mean "haiku"`},
+		{"gpt", "use haiku, sonnet, opus or a full model ID"},
+		{"claude sonnet", "contain no spaces"},
+	}
+	for _, tt := range tests {
+		err := validateModelID(tt.id)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("validateModelID(%q) = %v, want nil", tt.id, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("validateModelID(%q) = %v, want error containing %q", tt.id, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoadSettingsReportsLine(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	path := filepath.Join(home, ".bjarne", "settings.json")
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("{\n  \"review\": {\n    \"threshold\": \"high\"\n  }\n}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, err := LoadSettings()
+	if err == nil {
+		t.Fatal("LoadSettings accepted a string threshold")
+	}
+	for _, want := range []string{"line 3", "review.threshold", "expected a whole number"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+
+	issues, err := ValidateSettingsFile(path)
+	if err != nil || len(issues) != 1 || issues[0].Line != 3 {
+		t.Errorf("ValidateSettingsFile = %v, %v", issues, err)
+	}
+	if issues, err := ValidateSettingsFile(filepath.Join(home, "missing.json")); err != nil || issues != nil {
+		t.Errorf("missing file: %v, %v", issues, err)
+	}
+	if err := os.WriteFile(path, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSettings(); err == nil || errors.Is(err, os.ErrNotExist) {
+		t.Errorf("truncated file: %v", err)
+	}
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// sizeSymbolsMarker starts the rom-size stage's nm symbol table
+const sizeSymbolsMarker = "bjarne-size-symbols:"
+
+// sizeBloatyMarker starts the rom-size stage's bloaty breakdown, printed when the image has bloaty
+const sizeBloatyMarker = "bjarne-size-bloaty:"
+
+// templateBloatInstances is how many instantiations of one template are reported as bloat
+const templateBloatInstances = 4
+
+// templateBloatBytes is the code size at which a template with several instantiations is reported as bloat
+const templateBloatBytes = 4096
+
+// projectReportsDir holds the reports domain validators write, relative to the project root
+var projectReportsDir = filepath.Join(".bjarne", "reports")
+
+// anonymousNamespace is how demangled names spell a symbol in an unnamed namespace
+const anonymousNamespace = "(anonymous namespace)"
+
+// binarySizePattern matches the built binary's size in the rom-size stage's output
+var binarySizePattern = regexp.MustCompile(`Binary size: (\d+) bytes`)
+
+// SizeSymbol is one symbol of the built binary with its size
+type SizeSymbol struct {
+	Name string
+	Size int
+	Kind byte // nm's symbol type: T/t code, W/w weak code (template instantiations), D/d/B/b/R/r data
+}
+
+// isCode reports whether the symbol is a function
+func (s SizeSymbol) isCode() bool {
+	switch s.Kind {
+	case 'T', 't', 'W', 'w':
+		return true
+	}
+	return false
+}
+
+// TemplateBloat is the code one template's instantiations add to the binary
+type TemplateBloat struct {
+	Template  string
+	Instances int
+	Bytes     int
+}
+
+// SizeProfile is what the rom-size stage found in the built binary
+type SizeProfile struct {
+	Binary    int          // File size in bytes
+	Code      int          // Bytes in function symbols
+	Data      int          // Bytes in data symbols
+	Symbols   []SizeSymbol // Largest first
+	Templates []TemplateBloat
+	Bloaty    string // bloaty's breakdown, when the image has it
+}
+
+// sizeProfileCommand builds the code as small as it goes and prints its size and symbol table
+func (c *ContainerRuntime) sizeProfileCommand(filename string) string {
+	build := c.cxx(c.stdFlag() + " -Os -ffunction-sections -fdata-sections -Wl,--gc-sections -o /tmp/rom_test /src/" + filename)
+	return fmt.Sprintf("%s && SIZE=$(stat -c%%s /tmp/rom_test 2>/dev/null || stat -f%%z /tmp/rom_test) && "+
+		"echo \"Binary size: ${SIZE} bytes\" && NM=$(command -v llvm-nm || command -v nm) && "+
+		"echo '%s' && $NM -C -S --size-sort -t d /tmp/rom_test; "+
+		"if command -v bloaty >/dev/null 2>&1; then echo '%s'; bloaty -n 20 -d compileunits,symbols /tmp/rom_test; fi; true",
+		build, sizeSymbolsMarker, sizeBloatyMarker)
+}
+
+// parseSizeProfile reads the rom-size stage's output
+func parseSizeProfile(output string) SizeProfile {
+	var p SizeProfile
+	if m := binarySizePattern.FindStringSubmatch(output); m != nil {
+		p.Binary, _ = strconv.Atoi(m[1])
+	}
+	section := ""
+	var bloaty []string
+	seen := make(map[string]bool) // Addresses already counted: constructor and destructor aliases share their code
+	for _, line := range strings.Split(output, "\n") {
+		switch strings.TrimSpace(line) {
+		case sizeSymbolsMarker, sizeBloatyMarker:
+			section = strings.TrimSpace(line)
+			continue
+		}
+		switch section {
+		case sizeSymbolsMarker:
+			if s, ok := parseNMLine(line); ok && !seen[strings.Fields(line)[0]] {
+				seen[strings.Fields(line)[0]] = true
+				p.Symbols = append(p.Symbols, s)
+				if s.isCode() {
+					p.Code += s.Size
+				} else {
+					p.Data += s.Size
+				}
+			}
+		case sizeBloatyMarker:
+			bloaty = append(bloaty, line)
+		}
+	}
+	sort.SliceStable(p.Symbols, func(i, j int) bool { return p.Symbols[i].Size > p.Symbols[j].Size })
+	p.Templates = templateBloat(p.Symbols)
+	p.Bloaty = strings.TrimSpace(strings.Join(bloaty, "\n"))
+	return p
+}
+
+// parseNMLine reads "address size type name" from nm -S -t d; demangled names may contain spaces
+func parseNMLine(line string) (SizeSymbol, bool) {
+	fields := strings.SplitN(strings.TrimSpace(line), " ", 4)
+	if len(fields) != 4 || len(fields[2]) != 1 {
+		return SizeSymbol{}, false
+	}
+	size, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return SizeSymbol{}, false
+	}
+	return SizeSymbol{Name: fields[3], Size: size, Kind: fields[2][0]}, true
+}
+
+// templateKey names the template a demangled function was instantiated from, collapsing its
+// template arguments and parameters ("Matrix<float, 4>::mul(Matrix<float, 4> const&)" is "Matrix<>::mul()")
+// It reports false for functions that are not template instantiations
+func templateKey(name string) (string, bool) {
+	var sb strings.Builder
+	depth, templated, start := 0, false, 0 // start is where the name begins, after any return type
+	for i := 0; i < len(name); i++ {
+		if depth == 0 && strings.HasPrefix(name[i:], anonymousNamespace) {
+			sb.WriteString(anonymousNamespace)
+			i += len(anonymousNamespace) - 1
+			continue
+		}
+		if depth == 0 && strings.HasPrefix(name[i:], "operator") {
+			// Copy an operator name such as operator<< or operator() whole, so its '<' opens nothing
+			j := i + len("operator")
+			if strings.HasPrefix(name[j:], "()") {
+				j += 2
+			}
+			for j < len(name) && strings.IndexByte("<>=!+-*/%^&|~[]", name[j]) >= 0 {
+				j++
+			}
+			for j < len(name) && name[j] == ' ' {
+				j++
+			}
+			sb.WriteString(name[i:j])
+			i = j - 1
+			continue
+		}
+		switch ch := name[i]; {
+		case ch == '<':
+			if depth == 0 {
+				sb.WriteString("<>")
+				templated = true
+			}
+			depth++
+		case ch == '>' && depth > 0:
+			depth--
+		case depth > 0:
+		case ch == '(':
+			return sb.String()[start:] + "()", templated
+		default:
+			sb.WriteByte(ch)
+			if ch == ' ' {
+				start = sb.Len() // What came before is the return type nm prints for function templates
+			}
+		}
+	}
+	return sb.String(), templated
+}
+
+// templateBloat totals the code each of the project's templates adds, largest first
+// Standard library templates are left out: the code cannot shrink them except by using them less
+func templateBloat(symbols []SizeSymbol) []TemplateBloat {
+	byKey := make(map[string]*TemplateBloat)
+	var order []string
+	for _, s := range symbols {
+		key, ok := templateKey(s.Name)
+		if !ok || !s.isCode() || strings.HasPrefix(key, "std::") || strings.HasPrefix(key, "__") {
+			continue
+		}
+		t := byKey[key]
+		if t == nil {
+			t = &TemplateBloat{Template: key}
+			byKey[key] = t
+			order = append(order, key)
+		}
+		t.Instances++
+		t.Bytes += s.Size
+	}
+	result := make([]TemplateBloat, 0, len(order))
+	for _, key := range order {
+		result = append(result, *byKey[key])
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Bytes > result[j].Bytes })
+	return result
+}
+
+// isBloat reports whether a template's instantiations are worth a warning
+func (t TemplateBloat) isBloat() bool {
+	return t.Instances >= templateBloatInstances || (t.Instances > 1 && t.Bytes >= templateBloatBytes)
+}
+
+// bloat returns the templates worth a warning
+func (p SizeProfile) bloat() []TemplateBloat {
+	var bloat []TemplateBloat
+	for _, t := range p.Templates {
+		if t.isBloat() {
+			bloat = append(bloat, t)
+		}
+	}
+	return bloat
+}
+
+// overBudget returns the functions larger than maxFnBytes (none when it is 0)
+func (p SizeProfile) overBudget(maxFnBytes int) []SizeSymbol {
+	var over []SizeSymbol
+	for _, s := range p.Symbols {
+		if maxFnBytes > 0 && s.isCode() && s.Size > maxFnBytes {
+			over = append(over, s)
+		}
+	}
+	return over
+}
+
+// Summary describes the binary's size against its limits, listing the top largest symbols
+func (p SizeProfile) Summary(maxKB, maxFnBytes, top int) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Binary size: %d bytes (%d KB), limit %d KB\n", p.Binary, p.Binary/1024, maxKB)
+	fmt.Fprintf(&sb, "Code %d bytes, data %d bytes in %d symbol(s)\n", p.Code, p.Data, len(p.Symbols))
+	if n := min(top, len(p.Symbols)); n > 0 {
+		sb.WriteString("Largest symbols:\n")
+		writeSymbols(&sb, p.Symbols[:n])
+	}
+	for _, t := range p.bloat() {
+		fmt.Fprintf(&sb, "%s: %s instantiated %d times, %d bytes of code; move code that does not depend on the template arguments into a non-template base or helper [template-bloat]\n",
+			LevelWarning, t.Template, t.Instances, t.Bytes)
+	}
+	for _, s := range p.overBudget(maxFnBytes) {
+		fmt.Fprintf(&sb, "%s: %s is %d bytes, over the %d-byte function budget [function-size]\n", LevelError, s.Name, s.Size, maxFnBytes)
+	}
+	if p.Binary/1024 > maxKB {
+		fmt.Fprintf(&sb, "ERROR: Binary size %dKB exceeds limit %dKB", p.Binary/1024, maxKB)
+	} else {
+		fmt.Fprintf(&sb, "ROM size check PASSED: %dKB <= %dKB", p.Binary/1024, maxKB)
+	}
+	return sb.String()
+}
+
+// Report is the full profile: every symbol, every template's instantiations and bloaty's breakdown
+func (p SizeProfile) Report() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Binary size: %d bytes\nCode: %d bytes\nData: %d bytes\n\nSymbols (%d, largest first):\n", p.Binary, p.Code, p.Data, len(p.Symbols))
+	writeSymbols(&sb, p.Symbols)
+	if len(p.Templates) > 0 {
+		sb.WriteString("\nTemplate instantiations:\n")
+		for _, t := range p.Templates {
+			fmt.Fprintf(&sb, "%8d  %3dx  %s\n", t.Bytes, t.Instances, t.Template)
+		}
+	}
+	if p.Bloaty != "" {
+		sb.WriteString("\nbloaty:\n" + p.Bloaty + "\n")
+	}
+	return sb.String()
+}
+
+// writeSymbols writes one "size type name" line per symbol
+func writeSymbols(sb *strings.Builder, symbols []SizeSymbol) {
+	for _, s := range symbols {
+		fmt.Fprintf(sb, "%8d  %c  %s\n", s.Size, s.Kind, s.Name)
+	}
+}
+
+// Metrics are the profile's numbers for the domain validation result
+func (p SizeProfile) Metrics(maxKB, maxFnBytes int) map[string]interface{} {
+	metrics := map[string]interface{}{
+		"max_kb":         maxKB,
+		"max_fn_bytes":   maxFnBytes,
+		"binary_bytes":   p.Binary,
+		"code_bytes":     p.Code,
+		"data_bytes":     p.Data,
+		"symbols":        len(p.Symbols),
+		"template_bloat": len(p.bloat()),
+		"over_budget":    len(p.overBudget(maxFnBytes)),
+	}
+	if len(p.Symbols) > 0 {
+		metrics["largest_symbol"] = p.Symbols[0].Name
+		metrics["largest_symbol_bytes"] = p.Symbols[0].Size
+	}
+	return metrics
+}
+
+// writeDomainReport saves a domain validator's report as .bjarne/reports/<id>.txt and returns its path
+func writeDomainReport(root string, r DomainValidationResult) (string, error) {
+	dir := filepath.Join(root, projectReportsDir)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return "", fmt.Errorf("failed to create reports directory: %w", err)
+	}
+	path := filepath.Join(dir, string(r.ValidatorID)+".txt")
+	if err := os.WriteFile(path, []byte(r.Report), 0600); err != nil {
+		return "", fmt.Errorf("failed to write report: %w", err)
+	}
+	return path, nil
+}
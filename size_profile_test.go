@@ -0,0 +1,105 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const sizeProfileOutput = `Binary size: 20912 bytes
+` + sizeSymbolsMarker + `
+0000000000000032 0000000000000032 r __abi_tag
+0000000000005220 0000000000000077 W Matrix<int, 2>::sum() const
+0000000000005298 0000000000000077 W Matrix<int, 3>::sum() const
+0000000000005740 0000000000000077 W std::_Vector_base<int, std::allocator<int> >::~_Vector_base()
+0000000000005740 0000000000000077 W std::_Vector_base<int, std::allocator<int> >::~_Vector_base()
+0000000000005376 0000000000000084 W Matrix<long, 4>::sum() const
+0000000000005460 0000000000000114 W Matrix<double, 5>::sum() const
+0000000000004539 0000000000000421 T main
+` + sizeBloatyMarker + `
+    FILE SIZE        VM SIZE
+ 100.0%  20.4Ki 100.0%  4.12Ki    TOTAL
+`
+
+func TestParseSizeProfile(t *testing.T) {
+	p := parseSizeProfile(sizeProfileOutput)
+	if p.Binary != 20912 || p.Code != 77*3+84+114+421 || p.Data != 32 {
+		t.Errorf("parseSizeProfile() = binary %d, code %d, data %d", p.Binary, p.Code, p.Data)
+	}
+	if len(p.Symbols) != 7 || p.Symbols[0].Name != "main" || p.Symbols[0].Kind != 'T' {
+		t.Errorf("parseSizeProfile() symbols = %+v, want 7 with main first and the aliased destructor once", p.Symbols)
+	}
+	if len(p.Templates) != 1 || p.Templates[0] != (TemplateBloat{"Matrix<>::sum()", 4, 77*2 + 84 + 114}) {
+		t.Errorf("parseSizeProfile() templates = %+v, want Matrix<>::sum() without the standard library", p.Templates)
+	}
+	if !strings.Contains(p.Bloaty, "TOTAL") {
+		t.Errorf("parseSizeProfile() bloaty = %q", p.Bloaty)
+	}
+}
+
+func TestTemplateKey(t *testing.T) {
+	tests := []struct {
+		name      string
+		want      string
+		templated bool
+	}{
+		{"Matrix<float, 4>::mul(Matrix<float, 4> const&) const", "Matrix<>::mul()", true},
+		{"int* std::copy<int const*, int*>(int const*, int const*, int*)", "std::copy<>()", true},
+		{"double twice<double>(double)", "twice<>()", true},
+		{"std::ostream& operator<< <Point>(std::ostream&, Point const&)", "operator<< <>()", true},
+		{"Buffer<char>::operator()(int)", "Buffer<>::operator()()", true},
+		{"(anonymous namespace)::pool<16>::get()", "(anonymous namespace)::pool<>::get()", true},
+		{"operator new(unsigned long)", "operator new()", false},
+		{"main", "main", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, templated := templateKey(tt.name)
+			if got != tt.want || templated != tt.templated {
+				t.Errorf("templateKey() = %q, %v; want %q, %v", got, templated, tt.want, tt.templated)
+			}
+		})
+	}
+}
+
+func TestSizeProfileSummary(t *testing.T) {
+	p := parseSizeProfile(sizeProfileOutput)
+	tests := []struct {
+		name       string
+		maxKB      int
+		maxFnBytes int
+		want       []string
+	}{
+		{"within limits", 256, 0, []string{"Largest symbols:", "     421  T  main", "[template-bloat]", "ROM size check PASSED: 20KB <= 256KB"}},
+		{"binary too large", 16, 0, []string{"ERROR: Binary size 20KB exceeds limit 16KB"}},
+		{"function over budget", 256, 100, []string{"error: main is 421 bytes, over the 100-byte function budget [function-size]", "Matrix<double, 5>::sum() const is 114 bytes"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := p.Summary(tt.maxKB, tt.maxFnBytes, 3)
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("Summary() = %q, missing %q", got, want)
+				}
+			}
+		})
+	}
+	if m := p.Metrics(256, 100); m["over_budget"] != 2 || m["template_bloat"] != 1 || m["largest_symbol"] != "main" {
+		t.Errorf("Metrics() = %v", m)
+	}
+}
+
+func TestWriteDomainReport(t *testing.T) {
+	root := t.TempDir()
+	path, err := writeDomainReport(root, DomainValidationResult{ValidatorID: ValidatorROMSize, Report: "Binary size: 1 bytes\n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(root, projectReportsDir, "rom-size.txt"); path != want {
+		t.Errorf("writeDomainReport() = %q, want %q", path, want)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "Binary size: 1 bytes\n" {
+		t.Errorf("report = %q, %v", data, err)
+	}
+}
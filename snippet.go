@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// snippetContext is how many lines are shown above and below a diagnostic's line
+const snippetContext = 2
+
+// maxSnippetLines bounds the source quoted in one fix prompt section
+const maxSnippetLines = 60
+
+// sourceWindow is a range of 1-based lines in one file, with the lines diagnostics point at
+type sourceWindow struct {
+	start, end int
+	marked     map[int]bool
+}
+
+// sourceSnippets quotes the numbered lines around each diagnostic's location in files, so the
+// model sees exactly which code a finding is about. Overlapping windows are merged, lines a
+// diagnostic points at are marked with ">", and diagnostics outside files are skipped
+func sourceSnippets(diags []Diagnostic, files []CodeFile) string {
+	if len(files) == 0 {
+		return ""
+	}
+	contents := make(map[string][]string, len(files))
+	known := make(map[string]bool, len(files))
+	for _, f := range files {
+		contents[f.Filename] = splitLines(f.Content)
+		known[f.Filename] = true
+	}
+
+	var order []string
+	windows := make(map[string][]*sourceWindow)
+	for _, d := range diags {
+		if d.Level == LevelNote {
+			continue
+		}
+		name, line, _ := locateDiagnostic(d, files[0].Filename, known)
+		lines := contents[name]
+		if line < 1 || line > len(lines) {
+			continue
+		}
+		if _, ok := windows[name]; !ok {
+			order = append(order, name)
+		}
+		windows[name] = addSourceWindow(windows[name], line, len(lines))
+	}
+
+	var sb strings.Builder
+	budget := maxSnippetLines
+	for _, name := range order {
+		if budget <= 0 {
+			break
+		}
+		sb.WriteString(name + ":\n")
+		for i, w := range windows[name] {
+			if i > 0 {
+				sb.WriteString("     ...\n")
+			}
+			for n := w.start; n <= w.end && budget > 0; n, budget = n+1, budget-1 {
+				marker := " "
+				if w.marked[n] {
+					marker = ">"
+				}
+				sb.WriteString(fmt.Sprintf("%s%5d | %s\n", marker, n, strings.TrimRight(contents[name][n-1], "\r")))
+			}
+		}
+	}
+	return sb.String()
+}
+
+// addSourceWindow adds the window around line to a file's windows, merging any it overlaps
+func addSourceWindow(windows []*sourceWindow, line, total int) []*sourceWindow {
+	w := &sourceWindow{
+		start:  max(1, line-snippetContext),
+		end:    min(total, line+snippetContext),
+		marked: map[int]bool{line: true},
+	}
+	var merged []*sourceWindow
+	for _, other := range windows {
+		if other.end+1 < w.start || w.end+1 < other.start {
+			merged = append(merged, other)
+			continue
+		}
+		w.start, w.end = min(w.start, other.start), max(w.end, other.end)
+		for n := range other.marked {
+			w.marked[n] = true
+		}
+	}
+	merged = append(merged, w)
+	sort.Slice(merged, func(i, j int) bool { return merged[i].start < merged[j].start })
+	return merged
+}
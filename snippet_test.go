@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// numberedSource returns n lines "line 1" ... "line n"
+func numberedSource(n int) string {
+	var lines []string
+	for i := 1; i <= n; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func TestSourceSnippets(t *testing.T) {
+	files := []CodeFile{
+		{Filename: "main.cpp", Content: numberedSource(30)},
+		{Filename: "util.h", Content: numberedSource(5)},
+	}
+
+	tests := []struct {
+		name  string
+		diags []Diagnostic
+		want  string
+	}{
+		{
+			name:  "window around a line",
+			diags: []Diagnostic{{File: "/src/main.cpp", Line: 10, Level: LevelWarning}},
+			want:  "main.cpp:\n     8 | line 8\n     9 | line 9\n>   10 | line 10\n    11 | line 11\n    12 | line 12\n",
+		},
+		{
+			name: "overlapping windows merge",
+			diags: []Diagnostic{
+				{File: "/src/main.cpp", Line: 4, Level: LevelError},
+				{File: "/src/main.cpp", Line: 1, Level: LevelError},
+			},
+			want: "main.cpp:\n>    1 | line 1\n     2 | line 2\n     3 | line 3\n>    4 | line 4\n     5 | line 5\n     6 | line 6\n",
+		},
+		{
+			name: "separate windows and files",
+			diags: []Diagnostic{
+				{File: "/src/util.h", Line: 5, Level: LevelWarning},
+				{File: "/src/main.cpp", Line: 20, Level: LevelWarning},
+				{File: "/src/main.cpp", Line: 1, Level: LevelWarning},
+			},
+			want: "util.h:\n     3 | line 3\n     4 | line 4\n>    5 | line 5\n" +
+				"main.cpp:\n>    1 | line 1\n     2 | line 2\n     3 | line 3\n     ...\n" +
+				"    18 | line 18\n    19 | line 19\n>   20 | line 20\n    21 | line 21\n    22 | line 22\n",
+		},
+		{
+			name:  "sanitizer stack frame",
+			diags: []Diagnostic{{Level: LevelError, Context: "main at /src/util.h:2"}},
+			want:  "util.h:\n     1 | line 1\n>    2 | line 2\n     3 | line 3\n     4 | line 4\n",
+		},
+		{
+			name: "notes, system headers and bad lines are skipped",
+			diags: []Diagnostic{
+				{File: "/src/main.cpp", Line: 3, Level: LevelNote},
+				{File: "/usr/include/c++/13/vector", Line: 3, Level: LevelError},
+				{File: "/src/main.cpp", Line: 99, Level: LevelError},
+			},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sourceSnippets(tt.diags, files); got != tt.want {
+				t.Errorf("sourceSnippets() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSourceSnippetsBudget(t *testing.T) {
+	files := []CodeFile{{Filename: "code.cpp", Content: numberedSource(500)}}
+	var diags []Diagnostic
+	for line := 10; line <= 500; line += 10 {
+		diags = append(diags, Diagnostic{File: "/src/code.cpp", Line: line, Level: LevelWarning})
+	}
+	got := sourceSnippets(diags, files)
+	if n := strings.Count(got, " | "); n != maxSnippetLines {
+		t.Errorf("sourceSnippets() quoted %d lines, want the budget of %d", n, maxSnippetLines)
+	}
+}
+
+func TestFormatErrorForLLMIncludesSource(t *testing.T) {
+	files := []CodeFile{{Filename: "code.cpp", Content: "int main() {\n    int x;\n    return x;\n}\n"}}
+	out := FormatErrorForLLM("clang-tidy", "/src/code.cpp:3:12: warning: variable 'x' is uninitialized [cppcoreguidelines-init-variables]", files)
+	if !strings.Contains(out, "code.cpp:3 cppcoreguidelines-init-variables") {
+		t.Errorf("FormatErrorForLLM() lost the diagnostic: %q", out)
+	}
+	if !strings.Contains(out, "Source:\ncode.cpp:\n") || !strings.Contains(out, ">    3 |     return x;") {
+		t.Errorf("FormatErrorForLLM() = %q, want the numbered source with line 3 marked", out)
+	}
+
+	if out := FormatErrorForLLM("clang-tidy", "/src/code.cpp:3:12: warning: x [misc-x]", nil); strings.Contains(out, "Source:") {
+		t.Errorf("FormatErrorForLLM() without files = %q, want no source section", out)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// stackFramesShown bounds the backtrace quoted in a hang or crash report
+const stackFramesShown = 12
+
+var (
+	// gdb: "#1  0x0000555555555189 in spin (n=3) at /src/code.cpp:7"; eu-stack: "#0  0x000055555555513d spin"
+	debuggerFramePattern = regexp.MustCompile(`^#(\d+)\s+(?:0x[0-9a-fA-F]+\s+)?(?:in\s+)?(.+?)(?:\s+at\s+(\S+):(\d+))?$`)
+	// eu-stack -s puts the source location on the line after the frame
+	debuggerSourcePattern = regexp.MustCompile(`^\s+(/[^\s:]+):(\d+)(?::\d+)?$`)
+)
+
+// stackFrame is one frame of a backtrace printed by gdb or eu-stack
+type stackFrame struct {
+	Text string // The frame as the debugger printed it, without the address
+	File string // Source file, when known
+	Line int
+}
+
+// parseStackFrames collects the frames from a debugger's backtrace, skipping other lines
+func parseStackFrames(lines []string) []stackFrame {
+	var frames []stackFrame
+	for _, line := range lines {
+		if m := debuggerSourcePattern.FindStringSubmatch(line); m != nil && len(frames) > 0 {
+			last := &frames[len(frames)-1]
+			if last.File == "" {
+				last.File = m[1]
+				last.Line, _ = strconv.Atoi(m[2])
+				last.Text += " at " + m[1] + ":" + m[2]
+			}
+			continue
+		}
+		m := debuggerFramePattern.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		f := stackFrame{Text: "#" + m[1] + " " + m[2], File: m[3]}
+		if m[3] != "" {
+			f.Text += " at " + m[3] + ":" + m[4]
+			f.Line, _ = strconv.Atoi(m[4])
+		}
+		frames = append(frames, f)
+	}
+	return frames
+}
+
+// stackLocation is the innermost frame in the program's own sources, or the innermost
+// frame when none has source information
+func stackLocation(frames []stackFrame) string {
+	for _, f := range frames {
+		if strings.HasPrefix(f.File, "/src/") {
+			return strings.TrimSpace(strings.SplitN(f.Text, " ", 2)[1])
+		}
+	}
+	if len(frames) > 0 {
+		return strings.TrimSpace(strings.SplitN(frames[0].Text, " ", 2)[1])
+	}
+	return ""
+}
+
+// formatStackFrames indents the frames, up to stackFramesShown of them
+func formatStackFrames(frames []stackFrame) string {
+	var lines []string
+	for i, f := range frames {
+		if i == stackFramesShown {
+			lines = append(lines, fmt.Sprintf("  ... %d more frame(s)", len(frames)-i))
+			break
+		}
+		lines = append(lines, "  "+f.Text)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// stackDiagnostics points the source snippets at the frames in the program's own files
+func stackDiagnostics(frames []stackFrame, message string) []Diagnostic {
+	var diags []Diagnostic
+	for _, f := range frames {
+		if strings.HasPrefix(f.File, "/src/") && f.Line > 0 {
+			diags = append(diags, Diagnostic{File: f.File, Line: f.Line, Level: LevelError, Message: message})
+		}
+	}
+	return diags
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Fix strategies decide how bjarne retries after validation fails
+const (
+	StrategyFix        = "fix"        // Patch the failing code on every attempt (default)
+	StrategyRegenerate = "regenerate" // Patch, but start over from scratch after a run of failed patches
+)
+
+// fixStrategies lists the strategies accepted by settings and /strategy
+var fixStrategies = []string{StrategyFix, StrategyRegenerate}
+
+// defaultRegenerateAfter is how many failed patches the regenerate strategy allows before starting over
+const defaultRegenerateAfter = 3
+
+// maxHistoryKinds bounds the failure kinds summarized for a regeneration
+const maxHistoryKinds = 8
+
+// maxFallbackLines bounds the raw errors quoted when no failure could be parsed
+const maxFallbackLines = 20
+
+// parseFixStrategy validates a strategy name ("" = the default)
+func parseFixStrategy(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return StrategyFix, nil
+	}
+	if !slices.Contains(fixStrategies, name) {
+		return "", fmt.Errorf("unknown strategy %q (use %s)", name, strings.Join(fixStrategies, " or "))
+	}
+	return name, nil
+}
+
+// parseStrategyArgs parses /strategy arguments: a strategy and, for regenerate, an optional threshold
+func parseStrategyArgs(args []string, threshold int) (string, int, error) {
+	strategy, err := parseFixStrategy(args[0])
+	if err != nil {
+		return "", 0, err
+	}
+	if len(args) > 1 {
+		if strategy != StrategyRegenerate {
+			return "", 0, fmt.Errorf("only the regenerate strategy takes a threshold")
+		}
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 {
+			return "", 0, fmt.Errorf("invalid threshold %q (must be a positive number of failed fixes)", args[1])
+		}
+		threshold = n
+	}
+	return strategy, threshold, nil
+}
+
+// regenerateDue reports whether the next attempt should start over rather than patch
+// fixesSinceFresh counts the patches made since the code was last generated from scratch
+func regenerateDue(strategy string, threshold, fixesSinceFresh int) bool {
+	if threshold < 1 {
+		threshold = defaultRegenerateAfter
+	}
+	return strategy == StrategyRegenerate && fixesSinceFresh >= threshold
+}
+
+// failureHistory counts the kinds of failure met across the attempts at one request
+type failureHistory struct {
+	attempts int
+	counts   map[string]int
+	kinds    map[string]FixPattern
+}
+
+// Add records one failed validation's failures
+func (h *failureHistory) Add(sigs map[string]FixPattern) {
+	if h.counts == nil {
+		h.counts = make(map[string]int)
+		h.kinds = make(map[string]FixPattern)
+	}
+	h.attempts++
+	for sig, p := range sigs {
+		h.counts[sig]++
+		h.kinds[sig] = p
+	}
+}
+
+// Summary lists the recurring failures, most frequent first
+func (h *failureHistory) Summary() string {
+	sigs := make([]string, 0, len(h.counts))
+	for sig := range h.counts {
+		sigs = append(sigs, sig)
+	}
+	sort.Slice(sigs, func(i, j int) bool {
+		if h.counts[sigs[i]] != h.counts[sigs[j]] {
+			return h.counts[sigs[i]] > h.counts[sigs[j]]
+		}
+		return sigs[i] < sigs[j]
+	})
+
+	var sb strings.Builder
+	for i, sig := range sigs {
+		if i == maxHistoryKinds {
+			sb.WriteString(fmt.Sprintf("- ... and %d more\n", len(sigs)-i))
+			break
+		}
+		p := h.kinds[sig]
+		label := p.Gate
+		if p.Check != "" && p.Check != p.Gate {
+			label += " " + p.Check
+		}
+		sb.WriteString(fmt.Sprintf("- [%s] %s (in %d of %d attempts)\n", label, p.Message, h.counts[sig], h.attempts))
+	}
+	return sb.String()
+}
+
+// regenerationPrompt asks for a fresh design of request after repeated failed fixes
+// fallback describes the failures when none could be parsed (the last raw errors)
+func regenerationPrompt(request string, history *failureHistory, fallback string) string {
+	summary := history.Summary()
+	if summary == "" {
+		lines := strings.Split(strings.TrimSpace(fallback), "\n")
+		if len(lines) > maxFallbackLines {
+			lines = append(lines[:maxFallbackLines], "...")
+		}
+		summary = strings.Join(lines, "\n") + "\n"
+	}
+	return fmt.Sprintf(RegenerateFromScratchPromptTemplate, request, history.attempts, summary)
+}
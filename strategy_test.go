@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseStrategyArgs(t *testing.T) {
+	tests := []struct {
+		args      []string
+		strategy  string
+		threshold int
+		wantErr   bool
+	}{
+		{[]string{"fix"}, StrategyFix, 3, false},
+		{[]string{"Regenerate"}, StrategyRegenerate, 3, false},
+		{[]string{"regenerate", "5"}, StrategyRegenerate, 5, false},
+		{[]string{"regenerate", "0"}, "", 0, true},
+		{[]string{"regenerate", "many"}, "", 0, true},
+		{[]string{"fix", "2"}, "", 0, true},
+		{[]string{"rewrite"}, "", 0, true},
+	}
+	for _, tt := range tests {
+		strategy, threshold, err := parseStrategyArgs(tt.args, 3)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseStrategyArgs(%v) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			continue
+		}
+		if strategy != tt.strategy || threshold != tt.threshold {
+			t.Errorf("parseStrategyArgs(%v) = %q, %d; want %q, %d", tt.args, strategy, threshold, tt.strategy, tt.threshold)
+		}
+	}
+
+	if s, err := parseFixStrategy(""); s != StrategyFix || err != nil {
+		t.Errorf("parseFixStrategy(\"\") = %q, %v; want the default", s, err)
+	}
+}
+
+func TestRegenerateDue(t *testing.T) {
+	tests := []struct {
+		strategy  string
+		threshold int
+		fixes     int
+		want      bool
+	}{
+		{StrategyFix, 3, 10, false},
+		{StrategyRegenerate, 3, 2, false},
+		{StrategyRegenerate, 3, 3, true},
+		{StrategyRegenerate, 1, 1, true},
+		{StrategyRegenerate, 0, 2, false}, // Unset threshold falls back to the default
+		{StrategyRegenerate, 0, defaultRegenerateAfter, true},
+	}
+	for _, tt := range tests {
+		if got := regenerateDue(tt.strategy, tt.threshold, tt.fixes); got != tt.want {
+			t.Errorf("regenerateDue(%q, %d, %d) = %v, want %v", tt.strategy, tt.threshold, tt.fixes, got, tt.want)
+		}
+	}
+}
+
+func TestFailureHistorySummary(t *testing.T) {
+	leak := FixPattern{Gate: "asan", Check: "asan", Message: "detected memory leaks"}
+	race := FixPattern{Gate: "tsan", Check: "tsan", Message: "data race"}
+	tidy := FixPattern{Gate: "clang-tidy", Check: "bugprone-use-after-move", Message: "'_' used after it was moved"}
+
+	var h failureHistory
+	h.Add(map[string]FixPattern{leak.signature(): leak, race.signature(): race})
+	h.Add(map[string]FixPattern{race.signature(): race, tidy.signature(): tidy})
+	h.Add(map[string]FixPattern{race.signature(): race})
+
+	want := "- [tsan] data race (in 3 of 3 attempts)\n" +
+		"- [asan] detected memory leaks (in 1 of 3 attempts)\n" +
+		"- [clang-tidy bugprone-use-after-move] '_' used after it was moved (in 1 of 3 attempts)\n"
+	if got := h.Summary(); got != want {
+		t.Errorf("Summary() =\n%s\nwant\n%s", got, want)
+	}
+
+	var many failureHistory
+	sigs := make(map[string]FixPattern)
+	for i := 0; i < maxHistoryKinds+2; i++ {
+		p := FixPattern{Gate: "compile", Message: fmt.Sprintf("error %c", 'a'+i)}
+		sigs[p.signature()] = p
+	}
+	many.Add(sigs)
+	if got := many.Summary(); !strings.HasSuffix(got, "- ... and 2 more\n") || strings.Count(got, "\n") != maxHistoryKinds+1 {
+		t.Errorf("Summary() with %d kinds =\n%s", len(sigs), got)
+	}
+}
+
+func TestRegenerationPrompt(t *testing.T) {
+	race := FixPattern{Gate: "tsan", Check: "tsan", Message: "data race"}
+	var h failureHistory
+	h.Add(map[string]FixPattern{race.signature(): race})
+	h.Add(map[string]FixPattern{race.signature(): race})
+
+	got := regenerationPrompt("a thread-safe counter", &h, "ignored")
+	for _, want := range []string{"a thread-safe counter", "2", "- [tsan] data race (in 2 of 2 attempts)"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("regenerationPrompt() missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "ignored") {
+		t.Error("regenerationPrompt() quoted the raw errors despite a summary")
+	}
+
+	var empty failureHistory
+	empty.attempts = 1
+	got = regenerationPrompt("a counter", &empty, numberedSource(maxFallbackLines+5))
+	if !strings.Contains(got, fmt.Sprintf("line %d\n...", maxFallbackLines)) || strings.Contains(got, fmt.Sprintf("line %d\n", maxFallbackLines+1)) {
+		t.Errorf("regenerationPrompt() fallback not truncated:\n%s", got)
+	}
+}
+
+func TestRegenerateThreshold(t *testing.T) {
+	m := Model{config: &Config{}}
+	if got := m.regenerateThreshold(); got != defaultRegenerateAfter {
+		t.Errorf("regenerateThreshold() = %d, want %d", got, defaultRegenerateAfter)
+	}
+	m.regenAfter = 5
+	if got := m.regenerateThreshold(); got != 5 {
+		t.Errorf("regenerateThreshold() = %d, want 5", got)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+// stressMarker starts the stress gate's report of a failing iteration
+const stressMarker = "bjarne: stress:"
+
+// maxStressIterations bounds stress.iterations
+const maxStressIterations = 1000
+
+// stressShimFile is the delay-injection hooks' name in the mounted source directory
+const stressShimFile = "bjarne_stress.cpp"
+
+// stressShim perturbs thread schedules: -finstrument-functions calls the enter hook on every
+// function entry, where a per-thread generator seeded from BJARNE_STRESS_SEED now and then
+// yields or sleeps for a few microseconds. The same seed gives the same delays, so a failing
+// iteration can be rerun
+const stressShim = `#include <sched.h>
+#include <stdint.h>
+#include <stdlib.h>
+#include <unistd.h>
+
+extern "C" {
+static uint32_t bjarne_stress_seed = 0;
+static uint32_t bjarne_stress_threads = 0;
+static __thread uint32_t bjarne_stress_state = 0;
+
+__attribute__((no_instrument_function)) void __cyg_profile_func_enter(void*, void*) {
+    if (bjarne_stress_state == 0) {
+        if (bjarne_stress_seed == 0) {
+            const char* env = getenv("BJARNE_STRESS_SEED");
+            bjarne_stress_seed = env ? static_cast<uint32_t>(strtoul(env, nullptr, 10)) | 1u : 1u;
+        }
+        bjarne_stress_state = bjarne_stress_seed * 2654435761u + __atomic_add_fetch(&bjarne_stress_threads, 1u, __ATOMIC_RELAXED);
+    }
+    bjarne_stress_state = bjarne_stress_state * 1103515245u + 12345u;
+    uint32_t r = (bjarne_stress_state >> 16) & 1023u;
+    if (r < 4) {
+        usleep(r * 100 + 50);
+    } else if (r < 48) {
+        sched_yield();
+    }
+}
+
+__attribute__((no_instrument_function)) void __cyg_profile_func_exit(void*, void*) {}
+}
+`
+
+var stressFailurePattern = regexp.MustCompile(regexp.QuoteMeta(stressMarker) + ` iteration (\d+) of (\d+) failed \(seed (\d+)`)
+
+// SetStressSettings configures the stress gate
+func (c *ContainerRuntime) SetStressSettings(settings StressSettings) {
+	c.stress = settings
+}
+
+// stressEnabled reports whether the stress gate runs: stress.enabled, or asked for with --gates stress
+func (c *ContainerRuntime) stressEnabled() bool {
+	return (c.stress.Enabled || slices.Contains(c.gates.Only, "stress")) && c.gates.Enabled("stress")
+}
+
+// stressIterations is stress.iterations, defaulting when unset
+func (c *ContainerRuntime) stressIterations() int {
+	if c.stress.Iterations > 0 {
+		return c.stress.Iterations
+	}
+	return DefaultSettings().Stress.Iterations
+}
+
+// stageTimeout is the seconds the container of a stage may run: 2 minutes, plus a run timeout
+// for every stress iteration or injected allocation failure (a hang's watchdog bounds each one),
+// or GenMC's time limit
+func (c *ContainerRuntime) stageTimeout(stage string) int {
+	const timeout = 120
+	var runs int
+	switch stage {
+	case "stress":
+		runs = c.stressIterations()
+	case "exceptions":
+		runs = c.exceptionInjections() + 1 // The first run counts the allocations
+	case "model-check":
+		return timeout + modelCheckTimeout // GenMC bounds its own exploration
+	default:
+		return timeout
+	}
+	perRun := c.runTimeout()
+	if perRun <= 0 {
+		perRun = maxHangTimeout
+	}
+	return timeout + runs*perRun
+}
+
+// stressGate runs threaded code repeatedly under TSAN with seeded random delays, after a single TSAN run passed
+// srcArgs are the compiler's include flags and sources; it reports false when the gate is off
+func (c *ContainerRuntime) stressGate(tmpDir, srcArgs string, runStage func(stage string, command ...string) ValidationResult) (ValidationResult, bool, error) {
+	if !c.stressEnabled() {
+		return ValidationResult{}, false, nil
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, stressShimFile), []byte(stressShim), 0600); err != nil {
+		return ValidationResult{}, false, fmt.Errorf("failed to write stress hooks: %w", err)
+	}
+	return runStage("stress", "sh", "-c", c.stressCommand(srcArgs)), true, nil
+}
+
+// stressCommand builds the TSAN binary with the delay hooks and runs it stress.iterations times
+// Iteration i uses seed i; the first failure stops the loop with stressMarker and the seed
+// With stress.chaos, even iterations run an uninstrumented build under rr's chaos mode when rr works in the container
+func (c *ContainerRuntime) stressCommand(srcArgs string) string {
+	n := c.stressIterations()
+	instrument := " -finstrument-functions-after-inlining -g -fno-omit-frame-pointer "
+	build := c.cxx(c.stdFlag() + " -fsanitize=thread" + instrument + "-o /tmp/stress " + srcArgs + " /src/" + stressShimFile)
+	tsanRun := c.programCommand("BJARNE_STRESS_SEED=$i ", "/tmp/stress")
+	run := tsanRun
+	if c.stress.Chaos {
+		build += " && { rr=0; if command -v rr >/dev/null 2>&1 && rr record -n -o /tmp/rr-probe true >/dev/null 2>&1; then " +
+			c.cxx(c.stdFlag()+" -O1"+instrument+"-o /tmp/stress-rr "+srcArgs+" /src/"+stressShimFile) + " 2>&1 && rr=1; fi; }"
+		chaosRun := c.programCommand("BJARNE_STRESS_SEED=$i _RR_TRACE_DIR=/tmp/rr ", "rr record --chaos -n /tmp/stress-rr")
+		run = fmt.Sprintf("if [ $rr = 1 ] && [ $((i %% 2)) = 0 ]; then %s; else %s; fi", chaosRun, tsanRun)
+	}
+	return fmt.Sprintf("%s && i=1; while [ $i -le %d ]; do %s; s=$?; "+
+		"if [ $s -ne 0 ]; then echo \"%s iteration $i of %d failed (seed $i, exit $s)\" >&2; exit $s; fi; i=$((i+1)); done; "+
+		"echo \"%d iteration(s) passed\"",
+		build, n, run, stressMarker, n, n)
+}
+
+// StressFailure is the iteration that failed the stress gate
+type StressFailure struct {
+	Iteration, Iterations, Seed int
+}
+
+// parseStressFailure finds the failing iteration in the stress gate's output, or returns nil
+func parseStressFailure(output string) *StressFailure {
+	m := stressFailurePattern.FindStringSubmatch(output)
+	if m == nil {
+		return nil
+	}
+	f := &StressFailure{}
+	f.Iteration, _ = strconv.Atoi(m[1])
+	f.Iterations, _ = strconv.Atoi(m[2])
+	f.Seed, _ = strconv.Atoi(m[3])
+	return f
+}
+
+// promptText explains the failure for the fix prompt
+func (f *StressFailure) promptText() string {
+	return fmt.Sprintf("Found under stress testing: a single TSAN run passed, but run %d of %d (with seeded random delays between function calls, seed %d) failed. "+
+		"The bug depends on thread timing: protect all shared state, and do not rely on the order threads start, finish or acquire locks.",
+		f.Iteration, f.Iterations, f.Seed)
+}
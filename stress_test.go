@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestStressEnabled(t *testing.T) {
+	tests := []struct {
+		name   string
+		stress StressSettings
+		gates  GateSelection
+		want   bool
+	}{
+		{"off by default", StressSettings{}, GateSelection{}, false},
+		{"enabled", StressSettings{Enabled: true}, GateSelection{}, true},
+		{"asked for with --gates", StressSettings{}, GateSelection{Only: []string{"tsan", "stress"}}, true},
+		{"skipped", StressSettings{Enabled: true}, GateSelection{Skip: []string{"stress"}}, false},
+		{"not in --gates", StressSettings{Enabled: true}, GateSelection{Only: []string{"tsan"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &ContainerRuntime{stress: tt.stress, gates: tt.gates}
+			if got := c.stressEnabled(); got != tt.want {
+				t.Errorf("stressEnabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStressCommand(t *testing.T) {
+	c := &ContainerRuntime{stress: StressSettings{Iterations: 5}}
+	got := c.stressCommand("/src/code.cpp")
+	for _, want := range []string{"-fsanitize=thread -finstrument-functions-after-inlining", "/src/code.cpp /src/" + stressShimFile, "-le 5", "BJARNE_STRESS_SEED=$i /tmp/stress", "iteration $i of 5 failed"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("stressCommand() = %q, missing %q", got, want)
+		}
+	}
+	if strings.Contains(got, "rr record") {
+		t.Errorf("stressCommand() without chaos = %q, want no rr", got)
+	}
+
+	c.stress.Chaos = true
+	if got := c.stressCommand("/src/code.cpp"); !strings.Contains(got, "rr record --chaos -n /tmp/stress-rr") {
+		t.Errorf("stressCommand() with chaos = %q, missing the rr run", got)
+	}
+	if got, want := c.stageTimeout("stress"), 120+5*maxHangTimeout; got != want {
+		t.Errorf("stageTimeout(stress) = %d, want %d", got, want)
+	}
+	if got := c.stageTimeout("tsan"); got != 120 {
+		t.Errorf("stageTimeout(tsan) = %d, want 120", got)
+	}
+}
+
+func TestStressLoop(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs /bin/sh")
+	}
+	c := &ContainerRuntime{stress: StressSettings{Iterations: 4, Chaos: true}}
+	// Stand in for the compiler and the built program: iteration 3 fails
+	script := strings.Replace(c.stressCommand(""), c.cxx(""), "true ", 1)
+	script = strings.Replace(script, "rr=0; if", "rr=0; if false &&", 1)
+	script = strings.ReplaceAll(script, "/tmp/stress", `sh -c '[ "$BJARNE_STRESS_SEED" != 3 ]'`)
+	out, err := exec.Command("sh", "-c", script).CombinedOutput()
+	if err == nil {
+		t.Fatalf("stress loop passed, want a failure (output %q)", out)
+	}
+	f := parseStressFailure(string(out))
+	if f == nil || f.Iteration != 3 || f.Iterations != 4 || f.Seed != 3 {
+		t.Errorf("parseStressFailure(%q) = %+v, want iteration 3 of 4", out, f)
+	}
+}
+
+func TestFormatErrorForLLMStress(t *testing.T) {
+	report := "WARNING: ThreadSanitizer: data race (pid=12)\n" +
+		"  Write of size 4 at 0x7b04 by thread T2:\n" +
+		"    #0 worker() /src/code.cpp:5:14 (stress+0x1234)\n" +
+		stressMarker + " iteration 7 of 20 failed (seed 7, exit 66)\n"
+	got := FormatErrorForLLM("stress", report, nil)
+	for _, want := range []string{"[stress] Found under stress testing", "run 7 of 20", "seed 7", "data race"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("FormatErrorForLLM() = %q, missing %q", got, want)
+		}
+	}
+	if parseStressFailure("WARNING: ThreadSanitizer: data race") != nil {
+		t.Error("parseStressFailure() found a failure without the marker")
+	}
+}
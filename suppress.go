@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// projectSuppressionsFile records accepted findings, relative to the project root
+var projectSuppressionsFile = filepath.Join(".bjarne", "suppressions.json")
+
+// suppressibleGates are the gates whose findings can be accepted; sanitizer and compiler
+// failures are real defects and always have to be fixed
+var suppressibleGates = []string{"clang-tidy", "cppcheck"}
+
+// nolintCommentPattern matches a trailing NOLINT comment, ignored when fingerprinting a line
+var nolintCommentPattern = regexp.MustCompile(`\s*//\s*NOLINT.*$`)
+
+// Suppression is one accepted finding
+// It matches the same check on a line with the same code, wherever that line moves; File is
+// for review only, since generated code is validated as code.cpp whatever it is saved as
+type Suppression struct {
+	Gate        string    `json:"gate"`
+	Check       string    `json:"check"`
+	File        string    `json:"file"`
+	Fingerprint string    `json:"fingerprint"` // Hash of the check and the normalized source line
+	Code        string    `json:"code"`        // The source line, for review
+	Message     string    `json:"message"`
+	Reason      string    `json:"reason,omitempty"`
+	Added       time.Time `json:"added"`
+}
+
+// suppressionsFile is the on-disk form of .bjarne/suppressions.json
+type suppressionsFile struct {
+	Suppressions []Suppression `json:"suppressions"`
+}
+
+// LoadSuppressions reads the project's suppressions (none if the file does not exist)
+func LoadSuppressions(root string) ([]Suppression, error) {
+	data, err := os.ReadFile(filepath.Join(root, projectSuppressionsFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", projectSuppressionsFile, err)
+	}
+	var file suppressionsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", projectSuppressionsFile, err)
+	}
+	return file.Suppressions, nil
+}
+
+// SaveSuppressions writes the project's suppressions, sorted so the file diffs cleanly
+func SaveSuppressions(root string, suppressions []Suppression) error {
+	sorted := append([]Suppression(nil), suppressions...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].File != sorted[j].File {
+			return sorted[i].File < sorted[j].File
+		}
+		return sorted[i].Check < sorted[j].Check
+	})
+
+	data, err := json.MarshalIndent(suppressionsFile{Suppressions: sorted}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode suppressions: %w", err)
+	}
+	path := filepath.Join(root, projectSuppressionsFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", projectSuppressionsFile, err)
+	}
+	return nil
+}
+
+// SetSuppressions sets the accepted findings that no longer fail their gate
+func (c *ContainerRuntime) SetSuppressions(suppressions []Suppression) {
+	c.suppressions = suppressions
+}
+
+// loadProjectSuppressions gives the container the working directory's suppressions
+func loadProjectSuppressions(container *ContainerRuntime) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+	suppressions, err := LoadSuppressions(cwd)
+	if err != nil {
+		return err
+	}
+	container.SetSuppressions(suppressions)
+	return nil
+}
+
+// findingFingerprint identifies a check firing on a line of code, ignoring whitespace
+// and NOLINT comments so re-indenting or annotating the line keeps it suppressed
+func findingFingerprint(check, line string) string {
+	line = nolintCommentPattern.ReplaceAllString(line, "")
+	return sha256Hex(check + "\n" + strings.Join(strings.Fields(line), " "))[:16]
+}
+
+// sourceLine returns a 1-based line of content (empty if out of range)
+func sourceLine(content string, line int) string {
+	lines := strings.Split(content, "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	return strings.TrimRight(lines[line-1], "\r")
+}
+
+// newSuppression records a finding in one of files, if its gate and location allow it
+func newSuppression(f GateFinding, files []CodeFile, reason string) (Suppression, bool) {
+	check := strings.TrimSuffix(f.Check, warningsAsErrorsSuffix)
+	if !slices.Contains(suppressibleGates, f.Stage) || check == "" || check == "clang-diagnostic-error" || f.Line == 0 {
+		return Suppression{}, false
+	}
+	for _, file := range files {
+		if file.Filename != f.File {
+			continue
+		}
+		code := sourceLine(file.Content, f.Line)
+		if strings.TrimSpace(code) == "" {
+			return Suppression{}, false
+		}
+		message, _, _ := strings.Cut(f.Message, "\n")
+		return Suppression{
+			Gate:        f.Stage,
+			Check:       check,
+			File:        f.File,
+			Fingerprint: findingFingerprint(check, code),
+			Code:        strings.TrimSpace(nolintCommentPattern.ReplaceAllString(code, "")),
+			Message:     message,
+			Reason:      reason,
+			Added:       time.Now().UTC(),
+		}, true
+	}
+	return Suppression{}, false
+}
+
+// suppressionLine finds the 1-based line of its file that a suppression was recorded for,
+// preferring one not yet annotated when the same code appears twice (0 if none matches)
+func suppressionLine(s Suppression, files []CodeFile) int {
+	found := 0
+	for _, file := range files {
+		if file.Filename != s.File {
+			continue
+		}
+		lines := strings.Split(file.Content, "\n")
+		for i, code := range lines {
+			if findingFingerprint(s.Check, strings.TrimRight(code, "\r")) != s.Fingerprint {
+				continue
+			}
+			annotated := strings.Contains(code, "NOLINT") ||
+				i > 0 && strings.Contains(lines[i-1], "cppcheck-suppress "+s.Check)
+			if !annotated {
+				return i + 1
+			}
+			if found == 0 {
+				found = i + 1
+			}
+		}
+	}
+	return found
+}
+
+// suppresses reports whether a diagnostic from gate matches a recorded suppression
+// Source lines are read from the files mounted for the gate in tmpDir
+func (c *ContainerRuntime) suppresses(tmpDir, gate string, d Diagnostic) bool {
+	if len(c.suppressions) == 0 || d.Check == "" || d.Line == 0 || d.File == "" {
+		return false
+	}
+	name := filepath.Base(filepath.FromSlash(d.File))
+	data, err := os.ReadFile(filepath.Join(tmpDir, name)) //nolint:gosec // name is a file bjarne wrote to tmpDir
+	if err != nil {
+		return false
+	}
+	check := strings.TrimSuffix(d.Check, warningsAsErrorsSuffix)
+	fingerprint := findingFingerprint(check, sourceLine(string(data), d.Line))
+	for _, s := range c.suppressions {
+		if s.Gate == gate && s.Check == check && s.Fingerprint == fingerprint {
+			return true
+		}
+	}
+	return false
+}
+
+// applySuppressions passes a failed cppcheck stage whose findings are all suppressed
+// (clang-tidy drops suppressed findings before its policy is applied)
+func (c *ContainerRuntime) applySuppressions(tmpDir string, r ValidationResult) ValidationResult {
+	if r.Success || gateName(r.Stage) != "cppcheck" || len(c.suppressions) == 0 {
+		return r
+	}
+	suppressed := 0
+	for _, d := range stageDiagnostics(r) {
+		if d.Level == LevelNote {
+			continue
+		}
+		if !c.suppresses(tmpDir, "cppcheck", d) {
+			return r
+		}
+		suppressed++
+	}
+	if suppressed > 0 {
+		r.Success, r.Error, r.Suppressed = true, "", suppressed
+	}
+	return r
+}
+
+// addSuppressionComment annotates a finding's line so the tool itself ignores it:
+// a trailing // NOLINT(check) for clang-tidy, or a // cppcheck-suppress line above for cppcheck
+// Returns content unchanged when the line cannot take a comment (a macro continuation)
+// or cppcheck reported only a severity instead of a check id
+func addSuppressionComment(content string, s Suppression, line int) string {
+	lines := strings.Split(content, "\n")
+	if line < 1 || line > len(lines) {
+		return content
+	}
+	code := strings.TrimRight(lines[line-1], "\r")
+	if strings.HasSuffix(code, "\\") {
+		return content
+	}
+
+	switch s.Gate {
+	case "clang-tidy":
+		if strings.Contains(code, "NOLINT") {
+			return content
+		}
+		lines[line-1] = code + " // NOLINT(" + s.Check + ")" + lines[line-1][len(code):]
+	case "cppcheck":
+		if strings.HasPrefix(s.Check, "cppcheck-") {
+			return content
+		}
+		if line > 1 && strings.Contains(lines[line-2], "cppcheck-suppress "+s.Check) {
+			return content
+		}
+		indent := code[:len(code)-len(strings.TrimLeft(code, " \t"))]
+		comment := indent + "// cppcheck-suppress " + s.Check
+		lines = append(lines[:line-1], append([]string{comment}, lines[line-1:]...)...)
+	}
+	return strings.Join(lines, "\n")
+}
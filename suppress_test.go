@@ -0,0 +1,201 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const suppressCode = `#include <cstdio>
+
+int main() {
+    int *p = new int(4);
+    std::printf("%d\n", *p);
+    return 0;
+}
+`
+
+func TestFindingFingerprint(t *testing.T) {
+	base := findingFingerprint("modernize-use-auto", "    int *p = new int(4);")
+	tests := []struct {
+		name  string
+		check string
+		line  string
+		same  bool
+	}{
+		{"reindented", "modernize-use-auto", "int  *p =   new int(4);", true},
+		{"annotated", "modernize-use-auto", "    int *p = new int(4); // NOLINT(modernize-use-auto)", true},
+		{"other check", "cppcoreguidelines-owning-memory", "    int *p = new int(4);", false},
+		{"changed code", "modernize-use-auto", "    int *p = new int(5);", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := findingFingerprint(tt.check, tt.line) == base; got != tt.same {
+				t.Errorf("fingerprint equal = %v, want %v", got, tt.same)
+			}
+		})
+	}
+}
+
+func TestSuppressionsRoundTrip(t *testing.T) {
+	root := t.TempDir()
+	if got, err := LoadSuppressions(root); err != nil || got != nil {
+		t.Fatalf("LoadSuppressions() without a file = %v, %v; want nil, nil", got, err)
+	}
+
+	want := []Suppression{
+		{Gate: "cppcheck", Check: "nullPointer", File: "b.cpp", Fingerprint: "2"},
+		{Gate: "clang-tidy", Check: "modernize-use-auto", File: "a.cpp", Fingerprint: "1", Reason: "legacy API"},
+	}
+	if err := SaveSuppressions(root, want); err != nil {
+		t.Fatalf("SaveSuppressions() error: %v", err)
+	}
+	got, err := LoadSuppressions(root)
+	if err != nil {
+		t.Fatalf("LoadSuppressions() error: %v", err)
+	}
+	if len(got) != 2 || got[0].File != "a.cpp" || got[0].Reason != "legacy API" || got[1].Check != "nullPointer" {
+		t.Errorf("LoadSuppressions() = %+v, want both entries sorted by file", got)
+	}
+
+	if err := os.WriteFile(filepath.Join(root, projectSuppressionsFile), []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSuppressions(root); err == nil {
+		t.Error("LoadSuppressions() accepted invalid JSON")
+	}
+}
+
+func TestNewSuppression(t *testing.T) {
+	files := []CodeFile{{Filename: "code.cpp", Content: suppressCode}}
+	tests := []struct {
+		name    string
+		finding GateFinding
+		ok      bool
+	}{
+		{"clang-tidy finding", GateFinding{File: "code.cpp", Line: 4, Stage: "clang-tidy", Check: "modernize-use-auto"}, true},
+		{"promoted check", GateFinding{File: "code.cpp", Line: 4, Stage: "clang-tidy", Check: "modernize-use-auto" + warningsAsErrorsSuffix}, true},
+		{"compiler error", GateFinding{File: "code.cpp", Line: 4, Stage: "clang-tidy", Check: "clang-diagnostic-error"}, false},
+		{"sanitizer finding", GateFinding{File: "code.cpp", Line: 5, Stage: "asan", Check: "heap-use-after-free"}, false},
+		{"no location", GateFinding{File: "code.cpp", Stage: "cppcheck", Check: "nullPointer"}, false},
+		{"blank line", GateFinding{File: "code.cpp", Line: 2, Stage: "cppcheck", Check: "nullPointer"}, false},
+		{"unknown file", GateFinding{File: "other.cpp", Line: 4, Stage: "clang-tidy", Check: "modernize-use-auto"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, ok := newSuppression(tt.finding, files, "")
+			if ok != tt.ok {
+				t.Fatalf("newSuppression() ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && (s.Check != "modernize-use-auto" || s.Code != "int *p = new int(4);") {
+				t.Errorf("newSuppression() = %+v", s)
+			}
+		})
+	}
+}
+
+func TestAddSuppressionComment(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		s       Suppression
+		line    int
+		want    string
+	}{
+		{
+			name:    "clang-tidy NOLINT",
+			content: "int main() {\n    int *p = new int(4);\n}",
+			s:       Suppression{Gate: "clang-tidy", Check: "modernize-use-auto"},
+			line:    2,
+			want:    "int main() {\n    int *p = new int(4); // NOLINT(modernize-use-auto)\n}",
+		},
+		{
+			name:    "cppcheck line above",
+			content: "int main() {\n    return *p;\n}",
+			s:       Suppression{Gate: "cppcheck", Check: "nullPointer"},
+			line:    2,
+			want:    "int main() {\n    // cppcheck-suppress nullPointer\n    return *p;\n}",
+		},
+		{
+			name:    "cppcheck severity without an id",
+			content: "int main() {\n    return *p;\n}",
+			s:       Suppression{Gate: "cppcheck", Check: "cppcheck-error"},
+			line:    2,
+			want:    "int main() {\n    return *p;\n}",
+		},
+		{
+			name:    "macro continuation",
+			content: "#define F(x) \\\n    (x + 1)",
+			s:       Suppression{Gate: "clang-tidy", Check: "bugprone-macro-parentheses"},
+			line:    1,
+			want:    "#define F(x) \\\n    (x + 1)",
+		},
+		{
+			name:    "already annotated",
+			content: "int x = 0; // NOLINT",
+			s:       Suppression{Gate: "clang-tidy", Check: "misc-x"},
+			line:    1,
+			want:    "int x = 0; // NOLINT",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := addSuppressionComment(tt.content, tt.s, tt.line); got != tt.want {
+				t.Errorf("addSuppressionComment() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSuppressionLine(t *testing.T) {
+	content := "int *a = new int(4);\nint *a = new int(4); // NOLINT(modernize-use-auto)\n"
+	files := []CodeFile{{Filename: "code.cpp", Content: content}}
+	s := Suppression{Check: "modernize-use-auto", File: "code.cpp", Fingerprint: findingFingerprint("modernize-use-auto", "int *a = new int(4);")}
+	if got := suppressionLine(s, files); got != 1 {
+		t.Errorf("suppressionLine() = %d, want the unannotated line 1", got)
+	}
+	files[0].Content = "// NOLINT\n" + content[strings.Index(content, "\n")+1:]
+	if got := suppressionLine(s, files); got != 2 {
+		t.Errorf("suppressionLine() = %d, want the only matching line 2", got)
+	}
+}
+
+func TestApplySuppressions(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "code.cpp"), []byte(suppressCode), 0600); err != nil {
+		t.Fatal(err)
+	}
+	files := []CodeFile{{Filename: "code.cpp", Content: suppressCode}}
+	tidy, _ := newSuppression(GateFinding{File: "code.cpp", Line: 4, Stage: "clang-tidy", Check: "modernize-use-auto"}, files, "")
+	cppcheck, _ := newSuppression(GateFinding{File: "code.cpp", Line: 5, Stage: "cppcheck", Check: "nullPointer"}, files, "")
+
+	c := &ContainerRuntime{}
+	c.SetClangTidySettings(ClangTidySettings{MaxWarnings: 0})
+	c.SetSuppressions([]Suppression{tidy, cppcheck})
+
+	t.Run("clang-tidy", func(t *testing.T) {
+		out := "/src/code.cpp:4:5: warning: use auto when initializing with new [modernize-use-auto]\n"
+		got := c.applyTidyPolicy(tmpDir, ValidationResult{Stage: "clang-tidy", Success: true, Output: out})
+		if !got.Success || got.Suppressed != 1 {
+			t.Errorf("applyTidyPolicy() = success %v, %d suppressed; want a pass with 1 suppressed", got.Success, got.Suppressed)
+		}
+	})
+
+	t.Run("cppcheck", func(t *testing.T) {
+		out := "/src/code.cpp:5:25: error: Null pointer dereference: p [nullPointer]\n"
+		got := c.applySuppressions(tmpDir, ValidationResult{Stage: "cppcheck", Success: false, Error: out})
+		if !got.Success || got.Error != "" || got.Suppressed != 1 {
+			t.Errorf("applySuppressions() = %+v, want a pass with 1 suppressed", got)
+		}
+	})
+
+	t.Run("cppcheck with an unsuppressed finding", func(t *testing.T) {
+		out := "/src/code.cpp:5:25: error: Null pointer dereference: p [nullPointer]\n" +
+			"/src/code.cpp:4:5: error: Memory leak: p [memleak]\n"
+		got := c.applySuppressions(tmpDir, ValidationResult{Stage: "cppcheck", Success: false, Error: out})
+		if got.Success {
+			t.Error("applySuppressions() passed a stage with an unsuppressed finding")
+		}
+	})
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// projectTemplatesDir holds templates shared with a team (workspace-relative)
+var projectTemplatesDir = filepath.Join(".bjarne", "templates")
+
+// templateExt is the extension of template files
+const templateExt = ".md"
+
+// templateNamePattern restricts template names to safe file names
+var templateNamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// templatePlaceholderPattern matches {{name}} and {{name:default}}
+var templatePlaceholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][\w-]*)\s*(?::([^}]*))?\}\}`)
+
+// PromptTemplate is a reusable prompt skeleton (/template)
+type PromptTemplate struct {
+	Name    string
+	Text    string
+	Path    string
+	Project bool // From the project's .bjarne/templates rather than ~/.bjarne/templates
+}
+
+// TemplatePlaceholder is a value asked for when a template is used
+type TemplatePlaceholder struct {
+	Name    string
+	Default string
+}
+
+// Placeholders returns the template's placeholders in order of first appearance
+// A placeholder used more than once is asked for once; its first default wins
+func (t *PromptTemplate) Placeholders() []TemplatePlaceholder {
+	var placeholders []TemplatePlaceholder
+	seen := make(map[string]int)
+	for _, m := range templatePlaceholderPattern.FindAllStringSubmatch(t.Text, -1) {
+		def := strings.TrimSpace(m[2])
+		if i, ok := seen[m[1]]; ok {
+			if placeholders[i].Default == "" {
+				placeholders[i].Default = def
+			}
+			continue
+		}
+		seen[m[1]] = len(placeholders)
+		placeholders = append(placeholders, TemplatePlaceholder{Name: m[1], Default: def})
+	}
+	return placeholders
+}
+
+// Summary returns the first non-empty line of the template
+func (t *PromptTemplate) Summary() string {
+	for _, line := range strings.Split(t.Text, "\n") {
+		if line = strings.TrimSpace(strings.TrimLeft(line, "# ")); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// fillTemplate replaces placeholders with values; placeholders without a value keep their default
+func fillTemplate(text string, values map[string]string) string {
+	return templatePlaceholderPattern.ReplaceAllStringFunc(text, func(s string) string {
+		m := templatePlaceholderPattern.FindStringSubmatch(s)
+		if v, ok := values[m[1]]; ok {
+			return v
+		}
+		return strings.TrimSpace(m[2])
+	})
+}
+
+// templateFill tracks the placeholders still to be answered for /template use
+type templateFill struct {
+	template     *PromptTemplate
+	placeholders []TemplatePlaceholder
+	values       map[string]string
+	next         int
+}
+
+// newTemplateFill starts filling in a template
+func newTemplateFill(t *PromptTemplate) *templateFill {
+	return &templateFill{template: t, placeholders: t.Placeholders(), values: make(map[string]string)}
+}
+
+// done reports whether every placeholder has a value
+func (f *templateFill) done() bool {
+	return f.next >= len(f.placeholders)
+}
+
+// current returns the placeholder being asked for
+func (f *templateFill) current() TemplatePlaceholder {
+	return f.placeholders[f.next]
+}
+
+// answer records the value for the current placeholder (blank = its default)
+func (f *templateFill) answer(value string) {
+	p := f.current()
+	if value = strings.TrimSpace(value); value == "" {
+		value = p.Default
+	}
+	f.values[p.Name] = value
+	f.next++
+}
+
+// prompt returns the template filled in with the answers so far
+func (f *templateFill) prompt() string {
+	return strings.TrimSpace(fillTemplate(f.template.Text, f.values))
+}
+
+// TemplateLibrary finds templates in ~/.bjarne/templates and the project's .bjarne/templates
+// Project templates take precedence, so a team can override personal ones
+type TemplateLibrary struct {
+	userDir    string
+	projectDir string // "" outside a project
+}
+
+// userTemplatesDir returns ~/.bjarne/templates
+func userTemplatesDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".bjarne", "templates"), nil
+}
+
+// validateTemplateName rejects names that are not safe file names
+func validateTemplateName(name string) error {
+	if !templateNamePattern.MatchString(name) || strings.Trim(name, ".") == "" {
+		return fmt.Errorf("invalid template name %q (use letters, digits, '.', '_' or '-', up to 64 characters)", name)
+	}
+	return nil
+}
+
+// dirs returns the directories to search, project first
+func (l TemplateLibrary) dirs() []string {
+	var dirs []string
+	if l.projectDir != "" {
+		dirs = append(dirs, l.projectDir)
+	}
+	if l.userDir != "" {
+		dirs = append(dirs, l.userDir)
+	}
+	return dirs
+}
+
+// Load reads the template with the given name
+func (l TemplateLibrary) Load(name string) (*PromptTemplate, error) {
+	if err := validateTemplateName(name); err != nil {
+		return nil, err
+	}
+	for _, dir := range l.dirs() {
+		path := filepath.Join(dir, name+templateExt)
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template %q: %w", name, err)
+		}
+		return &PromptTemplate{Name: name, Text: string(data), Path: path, Project: dir == l.projectDir}, nil
+	}
+	return nil, fmt.Errorf("no template named %q", name)
+}
+
+// List returns the available templates, sorted by name
+// Unreadable files are skipped
+func (l TemplateLibrary) List() []*PromptTemplate {
+	seen := make(map[string]bool)
+	var templates []*PromptTemplate
+	for _, dir := range l.dirs() {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			name, ok := strings.CutSuffix(e.Name(), templateExt)
+			if !ok || e.IsDir() || seen[name] || validateTemplateName(name) != nil {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+			if err != nil {
+				continue
+			}
+			seen[name] = true
+			templates = append(templates, &PromptTemplate{Name: name, Text: string(data), Path: filepath.Join(dir, e.Name()), Project: dir == l.projectDir})
+		}
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates
+}
+
+// Save writes a template to the user library, or to the project's when project is set
+// It returns the path written
+func (l TemplateLibrary) Save(name, text string, project bool) (string, error) {
+	if err := validateTemplateName(name); err != nil {
+		return "", err
+	}
+	dir := l.userDir
+	if project {
+		if l.projectDir == "" {
+			return "", fmt.Errorf("no project directory for shared templates")
+		}
+		dir = l.projectDir
+	}
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return "", fmt.Errorf("failed to create template directory: %w", err)
+	}
+	path := filepath.Join(dir, name+templateExt)
+	if err := os.WriteFile(path, []byte(strings.TrimSpace(text)+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("failed to write template: %w", err)
+	}
+	return path, nil
+}
+
+// Delete removes the template Load would find and returns its path
+func (l TemplateLibrary) Delete(name string) (string, error) {
+	t, err := l.Load(name)
+	if err != nil {
+		return "", err
+	}
+	if err := os.Remove(t.Path); err != nil {
+		return "", fmt.Errorf("failed to delete template %q: %w", name, err)
+	}
+	return t.Path, nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestTemplatePlaceholders(t *testing.T) {
+	tmpl := &PromptTemplate{Text: "A {{ verb }} handler for {{endpoint}} using {{framework:drogon}}.\nLog every {{verb:GET}} to {{ log : stderr }}."}
+	want := []TemplatePlaceholder{
+		{Name: "verb", Default: "GET"},
+		{Name: "endpoint"},
+		{Name: "framework", Default: "drogon"},
+		{Name: "log", Default: "stderr"},
+	}
+	if got := tmpl.Placeholders(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Placeholders() = %+v, want %+v", got, want)
+	}
+
+	fill := newTemplateFill(tmpl)
+	for _, answer := range []string{"POST", "/orders", "", "  "} {
+		if fill.done() {
+			t.Fatal("fill finished early")
+		}
+		fill.answer(answer)
+	}
+	if !fill.done() {
+		t.Fatal("fill should be done after every placeholder is answered")
+	}
+	if got, want := fill.prompt(), "A POST handler for /orders using drogon.\nLog every POST to stderr."; got != want {
+		t.Errorf("prompt() = %q, want %q", got, want)
+	}
+}
+
+func TestTemplateLibrary(t *testing.T) {
+	root := t.TempDir()
+	lib := TemplateLibrary{userDir: filepath.Join(root, "user"), projectDir: filepath.Join(root, "project")}
+
+	if _, err := lib.Save("api-handler", "# REST handler\n\nA {{verb}} handler", false); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if _, err := lib.Save("bench", "Benchmark {{what}}", false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lib.Save("api-handler", "Team handler for {{endpoint}}", true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lib.Save("../escape", "x", false); err == nil {
+		t.Error("Save() should reject names that are not file names")
+	}
+
+	got, err := lib.Load("api-handler")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !got.Project || got.Text != "Team handler for {{endpoint}}\n" {
+		t.Errorf("Load() = %+v, want the project template", got)
+	}
+
+	list := lib.List()
+	if len(list) != 2 || list[0].Name != "api-handler" || !list[0].Project || list[1].Name != "bench" || list[1].Project {
+		t.Errorf("List() = %+v", list)
+	}
+
+	if _, err := lib.Delete("api-handler"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if got, err := lib.Load("api-handler"); err != nil || got.Project || got.Summary() != "REST handler" {
+		t.Errorf("after deleting the project copy, Load() = %+v, %v; want the user template", got, err)
+	}
+	if _, err := lib.Load("missing"); err == nil {
+		t.Error("Load() of a missing template should fail")
+	}
+
+	if _, err := (TemplateLibrary{userDir: lib.userDir}).Save("x", "y", true); err == nil {
+		t.Error("Save(project) without a project directory should fail")
+	}
+	if _, err := os.Stat(filepath.Join(lib.userDir, "bench"+templateExt)); err != nil {
+		t.Errorf("template file not written: %v", err)
+	}
+}
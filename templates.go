@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// templatesMarker starts the instantiations appended to the checked file
+const templatesMarker = "// bjarne: template instantiations"
+
+// moveOnlyType is the templates.types entry for a type that can be moved but not copied
+const moveOnlyType = "move-only"
+
+// moveOnlyDefinition defines the move-only type; it compares, so only copying is missing
+const moveOnlyDefinition = `struct bjarne_move_only {
+    int value = 0;
+    bjarne_move_only() = default;
+    bjarne_move_only(bjarne_move_only&&) = default;
+    bjarne_move_only& operator=(bjarne_move_only&&) = default;
+    bjarne_move_only(const bjarne_move_only&) = delete;
+    bjarne_move_only& operator=(const bjarne_move_only&) = delete;
+    bool operator==(const bjarne_move_only& o) const { return value == o.value; }
+    bool operator<(const bjarne_move_only& o) const { return value < o.value; }
+};`
+
+// templatesHint explains a templates gate failure in the fix prompt
+const templatesHint = "Instantiating the templates with other types failed: they assume more about T than their declaration says. " +
+	"Either make them work for these types (no copies of a move-only T, no assignment to a const T) or state the requirements " +
+	"in the declaration: a requires-clause or concept in C++20, std::enable_if before it, so unsupported types are rejected up front."
+
+var templateStartPattern = regexp.MustCompile(`\btemplate\s*<`)
+
+var templateTypeParamPattern = regexp.MustCompile(`^(?:typename|class)\s+\w+(?:\s*=.*)?$`)
+
+var templateConstrainedParamPattern = regexp.MustCompile(`^[\w:]+(?:<.*>)?\s+\w+(?:\s*=.*)?$`)
+
+var templateClassPattern = regexp.MustCompile(`^(?:requires\b[^{]*?)?\b(?:class|struct)\s+(?:\w+\s+)*?(\w+)\s*(?:final\s*)?(?::[^{]*)?$`)
+
+// nonTypeParamTypes start a non-type template parameter such as "int N", which is not instantiated with types
+var nonTypeParamTypes = []string{"int", "unsigned", "long", "short", "char", "bool", "size_t", "std::size_t", "auto", "std::ptrdiff_t"}
+
+// notFunctionNames precede a parenthesis in a declaration without naming the function
+var notFunctionNames = []string{"requires", "decltype", "sizeof", "alignof", "noexcept", "operator", "enable_if_t", "enable_if"}
+
+// templateDecl is a class or function template at file scope with a single type parameter
+type templateDecl struct {
+	Name        string
+	Class       bool
+	Constrained bool // A concept, requires-clause or enable_if limits the types it accepts
+}
+
+// SetTemplateSettings configures the template instantiation gate
+func (c *ContainerRuntime) SetTemplateSettings(settings TemplateSettings) {
+	c.templates = settings
+}
+
+// templatesEnabled reports whether the templates gate runs: templates.enabled, or asked for with --gates templates
+func (c *ContainerRuntime) templatesEnabled() bool {
+	return (c.templates.Enabled || slices.Contains(c.gates.Only, "templates")) && c.gates.Enabled("templates")
+}
+
+// templatesGate instantiates the code's templates with each of templates.types
+// It reports false when the gate is off or the code defines no templates it can instantiate
+func (c *ContainerRuntime) templatesGate(ctx context.Context, files []CodeFile, progress ProgressCallback) (ValidationResult, bool, error) {
+	if !c.templatesEnabled() {
+		return ValidationResult{}, false, nil
+	}
+	var found []templateDecl
+	for _, f := range files {
+		if isSourceFile(f.Filename) || isHeaderFile(f.Filename) {
+			found = append(found, findTemplates(f.Content)...)
+		}
+	}
+	// An overloaded function template's address is ambiguous, so overloads are left out
+	count := make(map[string]int, len(found))
+	for _, d := range found {
+		count[d.Name]++
+	}
+	var decls []templateDecl
+	for _, d := range found {
+		if d.Class || count[d.Name] == 1 {
+			decls = append(decls, d)
+		}
+	}
+	types := c.templates.Types
+	if len(types) == 0 {
+		types = DefaultSettings().Templates.Types
+	}
+	return c.runAppendedChecks(ctx, "templates", files, templatesMarker, templateInstantiations(decls, types, c.standardAtLeast("c++20")), progress)
+}
+
+// standardAtLeast reports whether validation builds with std or a newer standard
+func (c *ContainerRuntime) standardAtLeast(std string) bool {
+	return strings.Replace(strings.TrimPrefix(c.stdFlag(), "-std="), "gnu++", "c++", 1) >= std
+}
+
+// templateInstantiations returns the code instantiating each template with each type
+// Unconstrained templates must accept every type: class templates are explicitly instantiated, which
+// compiles every member function, and function templates have their address taken. Constrained ones
+// only have to compile for the types their constraints admit, which C++20's requires-expressions test;
+// before C++20 they are left out
+func templateInstantiations(decls []templateDecl, types []string, cpp20 bool) []string {
+	var checks []string
+	for i, d := range decls {
+		if d.Constrained && !cpp20 {
+			continue
+		}
+		fn := fmt.Sprintf("bjarne_instantiate_%d", i+1)
+		if d.Constrained {
+			probe, use := "&"+d.Name+"<T>", "auto p = &"+d.Name+"<T>; (void)p;"
+			if d.Class {
+				probe, use = "typename "+d.Name+"<T>", "(void)sizeof("+d.Name+"<T>);"
+			}
+			checks = append(checks, fmt.Sprintf("template <typename T> void %s() { if constexpr (requires { %s; }) { %s } }", fn, probe, use))
+		}
+		for j, t := range types {
+			t = templateTypeName(t)
+			switch {
+			case d.Constrained:
+				checks = append(checks, fmt.Sprintf("template void %s<%s>();", fn, t))
+			case d.Class:
+				checks = append(checks, fmt.Sprintf("template class %s<%s>;", d.Name, t))
+			default:
+				checks = append(checks, fmt.Sprintf("inline void %s_%d() { auto p = &%s<%s>; (void)p; }", fn, j+1, d.Name, t))
+			}
+		}
+	}
+	if len(checks) == 0 {
+		return nil
+	}
+	preamble := []string{"#include <string>", "#include <utility>"}
+	if slices.Contains(types, moveOnlyType) {
+		preamble = append(preamble, moveOnlyDefinition)
+	}
+	return append(preamble, checks...)
+}
+
+// templateTypeName is the C++ type for a templates.types entry
+func templateTypeName(t string) string {
+	if t == moveOnlyType {
+		return "bjarne_move_only"
+	}
+	return t
+}
+
+// findTemplates returns the class and function templates defined at file scope with a single type parameter
+// Specializations, variadic and non-type parameters, and templates inside classes or namespaces are skipped
+func findTemplates(code string) []templateDecl {
+	var decls []templateDecl
+	for _, loc := range templateStartPattern.FindAllStringIndex(code, -1) {
+		if braceDepth(code[:loc[0]]) != 0 {
+			continue
+		}
+		end := matchingAngle(code, loc[1])
+		if end < 0 {
+			continue
+		}
+		param := strings.TrimSpace(code[loc[1]:end])
+		if param == "" || strings.Contains(param, "...") || len(splitTopLevel(param)) != 1 {
+			continue
+		}
+		constrained := false
+		switch {
+		case templateTypeParamPattern.MatchString(param):
+		case templateConstrainedParamPattern.MatchString(param) && !isNonTypeParam(param):
+			constrained = true
+		default:
+			continue
+		}
+
+		// The declaration runs to its body; one ending in ';' first is a declaration or a variable template
+		rest := code[end+1:]
+		body := strings.IndexAny(rest, "{;")
+		if body < 0 || rest[body] != '{' {
+			continue
+		}
+		decl := strings.TrimSpace(rest[:body])
+		constrained = constrained || strings.Contains(decl, "requires") || strings.Contains(decl, "enable_if")
+		if m := templateClassPattern.FindStringSubmatch(decl); m != nil {
+			decls = append(decls, templateDecl{Name: m[1], Class: true, Constrained: constrained})
+		} else if name := templateFunctionName(decl); name != "" {
+			decls = append(decls, templateDecl{Name: name, Constrained: constrained})
+		}
+	}
+	return decls
+}
+
+// templateFunctionName finds the name before a function template's parameter list, or "" when decl is not a function
+func templateFunctionName(decl string) string {
+	depth := 0
+	for i, r := range decl {
+		switch r {
+		case '(':
+			if depth == 0 {
+				j := i
+				for j > 0 && (isIdentByte(decl[j-1]) || decl[j-1] == ' ') {
+					j--
+				}
+				name := strings.TrimSpace(decl[j:i])
+				if k := strings.LastIndex(name, " "); k >= 0 {
+					name = name[k+1:]
+				}
+				if name != "" && !slices.Contains(notFunctionNames, name) {
+					return name
+				}
+			}
+			depth++
+		case ')':
+			depth--
+		case '=':
+			if depth == 0 {
+				return "" // A variable template's initializer
+			}
+		}
+	}
+	return ""
+}
+
+// isIdentByte reports whether b can appear in a C++ identifier
+func isIdentByte(b byte) bool {
+	return b == '_' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9'
+}
+
+// isNonTypeParam reports whether a template parameter is a value such as "int N" rather than a constrained type
+func isNonTypeParam(param string) bool {
+	kind := strings.Fields(param)[0]
+	return slices.Contains(nonTypeParamTypes, kind) || strings.ContainsAny(param, "*&")
+}
+
+// matchingAngle returns the index of the '>' closing the '<' before start, or -1
+func matchingAngle(code string, start int) int {
+	depth := 1
+	for i := start; i < len(code); i++ {
+		switch code[i] {
+		case '<':
+			depth++
+		case '>':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		case '{', ';':
+			return -1
+		}
+	}
+	return -1
+}
+
+// splitTopLevel splits a template parameter list at the commas outside nested brackets
+func splitTopLevel(params string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, r := range params {
+		switch r {
+		case '<', '(':
+			depth++
+		case '>', ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, params[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, params[start:])
+}
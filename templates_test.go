@@ -0,0 +1,111 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFindTemplates(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want []templateDecl
+	}{
+		{"class", "template <typename T>\nclass Stack {\n    T top() const;\n};\n", []templateDecl{{Name: "Stack", Class: true}}},
+		{"struct with base", "template <class T> struct Box : Base<T> { T v; };", []templateDecl{{Name: "Box", Class: true}}},
+		{"function", "template <typename T>\nT sum(const std::vector<T>& v) { return {}; }", []templateDecl{{Name: "sum"}}},
+		{"concept parameter", "template <std::integral T>\nT gcd(T a, T b) { return a; }", []templateDecl{{Name: "gcd", Constrained: true}}},
+		{"requires clause", "template <typename T>\n    requires std::copyable<T>\nclass Cache { };", []templateDecl{{Name: "Cache", Class: true, Constrained: true}}},
+		{"trailing requires", "template <typename T>\nT twice(T x) requires (sizeof(T) > 1) { return x + x; }", []templateDecl{{Name: "twice", Constrained: true}}},
+		{"enable_if", "template <typename T, typename = void>\nvoid f(T) {}\ntemplate <typename T>\nstd::enable_if_t<std::is_integral_v<T>, T> half(T x) { return x / 2; }", []templateDecl{{Name: "half", Constrained: true}}},
+		{"non-type parameter", "template <int N>\nint times(int x) { return N * x; }", nil},
+		{"variadic", "template <typename... Ts>\nvoid log(Ts... args) {}", nil},
+		{"specialization", "template <>\nstruct Hash<int> { };", nil},
+		{"partial specialization", "template <typename T>\nstruct Traits<T*> { };", nil},
+		{"declaration only", "template <typename T>\nT parse(const std::string& s);", nil},
+		{"variable template", "template <typename T>\nconstexpr bool is_small = sizeof(T) < 4;", nil},
+		{"concept", "template <typename T>\nconcept Addable = requires(T a) { a + a; };", nil},
+		{"operator", "template <typename T>\nbool operator==(const Box<T>& a, const Box<T>& b) { return true; }", nil},
+		{"member template", "struct S {\n    template <typename T>\n    void set(T v) {}\n};", nil},
+		{"in a namespace", "namespace util {\ntemplate <typename T>\nT id(T x) { return x; }\n}", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := findTemplates(tt.code)
+			if len(got) != len(tt.want) {
+				t.Fatalf("findTemplates() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("findTemplates()[%d] = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestTemplateInstantiations(t *testing.T) {
+	decls := []templateDecl{{Name: "Stack", Class: true}, {Name: "sum"}, {Name: "gcd", Constrained: true}}
+	types := []string{"int", moveOnlyType}
+
+	got := strings.Join(templateInstantiations(decls, types, true), "\n")
+	for _, want := range []string{
+		"struct bjarne_move_only {",
+		"template class Stack<int>;",
+		"template class Stack<bjarne_move_only>;",
+		"inline void bjarne_instantiate_2_2() { auto p = &sum<bjarne_move_only>; (void)p; }",
+		"template <typename T> void bjarne_instantiate_3() { if constexpr (requires { &gcd<T>; }) { auto p = &gcd<T>; (void)p; } }",
+		"template void bjarne_instantiate_3<int>();",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("templateInstantiations() = %q, missing %q", got, want)
+		}
+	}
+
+	if got := strings.Join(templateInstantiations(decls, types, false), "\n"); strings.Contains(got, "gcd") {
+		t.Errorf("templateInstantiations() before C++20 = %q, want constrained templates left out", got)
+	}
+	if got := templateInstantiations(decls, []string{"int"}, true); strings.Contains(strings.Join(got, "\n"), "bjarne_move_only") {
+		t.Errorf("templateInstantiations() = %q, defines the move-only type it does not use", got)
+	}
+	if got := templateInstantiations(nil, types, true); got != nil {
+		t.Errorf("templateInstantiations(no templates) = %q, want none", got)
+	}
+}
+
+func TestTemplatesEnabled(t *testing.T) {
+	c := &ContainerRuntime{}
+	if c.templatesEnabled() {
+		t.Error("templatesEnabled() = true by default")
+	}
+	c.gates.Only = []string{"compile", "templates"}
+	if !c.templatesEnabled() {
+		t.Error("templatesEnabled() = false with --gates templates")
+	}
+	c.gates = GateSelection{Skip: []string{"templates"}}
+	c.templates.Enabled = true
+	if c.templatesEnabled() {
+		t.Error("templatesEnabled() = true with --skip templates")
+	}
+
+	c.standard = "c++17"
+	if c.standardAtLeast("c++20") {
+		t.Error("standardAtLeast(c++20) = true for c++17")
+	}
+	c.standard = "gnu++23"
+	if !c.standardAtLeast("c++20") {
+		t.Error("standardAtLeast(c++20) = false for gnu++23")
+	}
+}
+
+func TestFormatErrorForLLMTemplates(t *testing.T) {
+	code := "#include <vector>\ntemplate <typename T>\nclass Stack {\npublic:\n    T top() const { return items.back(); }\nprivate:\n    std::vector<T> items;\n};\n"
+	output := "/src/code.cpp:5:36: error: call to deleted constructor of 'bjarne_move_only'\n" +
+		"/src/code.cpp:20:16: note: in instantiation of member function 'Stack<bjarne_move_only>::top' requested here\n"
+	got := FormatErrorForLLM("templates", output, []CodeFile{{Filename: "code.cpp", Content: code}})
+	for _, want := range []string{"[templates] Instantiating the templates with other types failed", "deleted constructor", "Source:", "return items.back();"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("FormatErrorForLLM() = %q, missing %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// theme builds the Theme for a custom theme, filling unset colors from its base preset
+func (c CustomTheme) theme() *Theme {
+	base := c.Base
+	preset, ok := ThemePresets[base]
+	if !ok {
+		base, preset = "default", ThemePresets["default"]
+	}
+	for _, color := range []struct {
+		dst *string
+		src string
+	}{
+		{&preset.Prompt, c.Prompt},
+		{&preset.Success, c.Success},
+		{&preset.Error, c.Error},
+		{&preset.Warning, c.Warning},
+		{&preset.Info, c.Info},
+		{&preset.Accent, c.Accent},
+	} {
+		if color.src != "" {
+			*color.dst = color.src
+		}
+	}
+	highlight := c.Highlight
+	if highlight == "" {
+		highlight = chromaStyles[base]
+	}
+	return &Theme{preset: preset, highlight: highlight}
+}
+
+// colors returns a custom theme's colors by key, for validation
+func (c CustomTheme) colors() map[string]string {
+	return map[string]string{
+		"prompt": c.Prompt, "success": c.Success, "error": c.Error,
+		"warning": c.Warning, "info": c.Info, "accent": c.Accent,
+	}
+}
+
+// Names lists the themes that can be selected: the presets, then custom themes
+func (s *ThemeSettings) Names() []string {
+	names := AvailableThemes()
+	var custom []string
+	for name := range s.Custom {
+		if _, ok := ThemePresets[name]; !ok {
+			custom = append(custom, name)
+		}
+	}
+	sort.Strings(custom)
+	return append(names, custom...)
+}
+
+// validThemeColor reports whether color is "#rgb"/"#rrggbb" hex or a built-in color name
+func validThemeColor(color string) bool {
+	if _, ok := colorCodes[color]; ok && color != "reset" {
+		return true
+	}
+	_, _, _, ok := parseHexColor(color)
+	return ok
+}
+
+// parseHexColor reads "#rrggbb" or "#rgb"
+func parseHexColor(color string) (r, g, b uint8, ok bool) {
+	hex, found := strings.CutPrefix(color, "#")
+	if !found {
+		return 0, 0, 0, false
+	}
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return 0, 0, 0, false
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	return uint8(v >> 16), uint8(v >> 8), uint8(v), true
+}
+
+// truecolorSupported reports whether the terminal advertises 24-bit color
+func truecolorSupported() bool {
+	colorterm := os.Getenv("COLORTERM")
+	return colorterm == "truecolor" || colorterm == "24bit"
+}
+
+// hexColorCode returns the foreground escape for an RGB color: truecolor where the terminal
+// supports it, else the nearest of the 256 colors
+func hexColorCode(r, g, b uint8) string {
+	if truecolorSupported() {
+		return fmt.Sprintf("\033[38;2;%d;%d;%dm", r, g, b)
+	}
+	return fmt.Sprintf("\033[38;5;%dm", nearest256(r, g, b))
+}
+
+// cubeLevels are the channel values of the 6x6x6 color cube in the 256-color palette
+var cubeLevels = [6]int{0, 95, 135, 175, 215, 255}
+
+// nearest256 finds the closest 256-color palette entry (cube or gray ramp) to an RGB color
+func nearest256(r, g, b uint8) int {
+	nearestLevel := func(v uint8) int {
+		best := 0
+		for i, level := range cubeLevels {
+			if abs(int(v)-level) < abs(int(v)-cubeLevels[best]) {
+				best = i
+			}
+		}
+		return best
+	}
+	ri, gi, bi := nearestLevel(r), nearestLevel(g), nearestLevel(b)
+	cube := 16 + 36*ri + 6*gi + bi
+	cubeDist := colorDistance(r, g, b, cubeLevels[ri], cubeLevels[gi], cubeLevels[bi])
+
+	// Gray ramp: 232-255 are 8, 18, ..., 238
+	avg := (int(r) + int(g) + int(b)) / 3
+	step := min(max((avg-8+5)/10, 0), 23)
+	grayLevel := 8 + 10*step
+	if colorDistance(r, g, b, grayLevel, grayLevel, grayLevel) < cubeDist {
+		return 232 + step
+	}
+	return cube
+}
+
+// colorDistance is the squared RGB distance between two colors
+func colorDistance(r, g, b uint8, r2, g2, b2 int) int {
+	dr, dg, db := int(r)-r2, int(g)-g2, int(b)-b2
+	return dr*dr + dg*dg + db*db
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// lipglossColor converts a theme color to lipgloss: hex passes through (lipgloss adapts it to the
+// terminal), built-in names become their ANSI palette index
+func lipglossColor(color string) lipgloss.Color {
+	if r, g, b, ok := parseHexColor(color); ok {
+		return lipgloss.Color(fmt.Sprintf("#%02x%02x%02x", r, g, b))
+	}
+	code := strings.TrimSuffix(strings.TrimPrefix(getColorCode(color), "\033["), "m")
+	if index, ok := strings.CutPrefix(code, "38;5;"); ok {
+		return lipgloss.Color(index)
+	}
+	n, err := strconv.Atoi(code)
+	switch {
+	case err != nil:
+		return lipgloss.Color("15")
+	case n >= 90: // Bright colors 90-97 are palette 8-15
+		return lipgloss.Color(strconv.Itoa(n - 90 + 8))
+	default:
+		return lipgloss.Color(strconv.Itoa(n - 30))
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestParseHexColor(t *testing.T) {
+	tests := []struct {
+		color   string
+		r, g, b uint8
+		ok      bool
+	}{
+		{"#88c0d0", 0x88, 0xc0, 0xd0, true},
+		{"#FFF", 0xff, 0xff, 0xff, true},
+		{"#0a0", 0x00, 0xaa, 0x00, true},
+		{"88c0d0", 0, 0, 0, false},
+		{"#88c0d", 0, 0, 0, false},
+		{"#zzzzzz", 0, 0, 0, false},
+	}
+	for _, tt := range tests {
+		r, g, b, ok := parseHexColor(tt.color)
+		if ok != tt.ok || r != tt.r || g != tt.g || b != tt.b {
+			t.Errorf("parseHexColor(%q) = %d,%d,%d,%v", tt.color, r, g, b, ok)
+		}
+	}
+}
+
+func TestHexColorCode(t *testing.T) {
+	t.Setenv("COLORTERM", "truecolor")
+	if got := getColorCode("#88c0d0"); got != "\033[38;2;136;192;208m" {
+		t.Errorf("truecolor code = %q", got)
+	}
+	t.Setenv("COLORTERM", "")
+	if got := getColorCode("#ff0000"); got != "\033[38;5;196m" {
+		t.Errorf("256-color code = %q, want the palette's red", got)
+	}
+	if got := getColorCode("nord_blue"); got != colorCodes["nord_blue"] {
+		t.Errorf("named color code = %q", got)
+	}
+}
+
+func TestNearest256(t *testing.T) {
+	tests := []struct {
+		r, g, b uint8
+		want    int
+	}{
+		{0, 0, 0, 16},
+		{255, 255, 255, 231},
+		{0, 0, 255, 21},
+		{128, 128, 128, 244}, // Gray ramp beats the cube
+		{95, 135, 175, 67},
+	}
+	for _, tt := range tests {
+		if got := nearest256(tt.r, tt.g, tt.b); got != tt.want {
+			t.Errorf("nearest256(%d,%d,%d) = %d, want %d", tt.r, tt.g, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestLipglossColor(t *testing.T) {
+	tests := []struct {
+		color string
+		want  lipgloss.Color
+	}{
+		{"blue", "12"},
+		{"red", "9"},
+		{"black", "0"},
+		{"nord_blue", "67"},
+		{"#abc", "#aabbcc"},
+		{"#88C0D0", "#88c0d0"},
+		{"no-such-color", "15"},
+	}
+	for _, tt := range tests {
+		if got := lipglossColor(tt.color); got != tt.want {
+			t.Errorf("lipglossColor(%q) = %q, want %q", tt.color, got, tt.want)
+		}
+	}
+}
+
+func TestCustomTheme(t *testing.T) {
+	settings := &ThemeSettings{
+		Name: "mine",
+		Custom: map[string]CustomTheme{
+			"mine":  {Base: "nord", Prompt: "#ff8800", Error: "red"},
+			"plain": {},
+			"nord":  {Accent: "#ffffff"}, // Replaces the preset of the same name
+		},
+	}
+	theme := NewTheme(settings)
+	want := ThemePresets["nord"]
+	want.Prompt, want.Error = "#ff8800", "red"
+	if theme.preset != want {
+		t.Errorf("preset = %+v, want %+v", theme.preset, want)
+	}
+	if theme.highlight != "nord" {
+		t.Errorf("highlight = %q, want the base's nord", theme.highlight)
+	}
+
+	settings.Name = "plain"
+	if theme := NewTheme(settings); theme.preset != ThemePresets["default"] || theme.highlight != chromaStyles["default"] {
+		t.Errorf("plain theme = %+v", theme)
+	}
+	settings.Name = "nord"
+	if theme := NewTheme(settings); theme.preset.Accent != "#ffffff" || theme.preset.Prompt != ThemePresets["default"].Prompt {
+		t.Errorf("custom nord = %+v, want default colors with a white accent", theme.preset)
+	}
+
+	names := settings.Names()
+	if got := strings.Join(names[len(names)-2:], ","); got != "mine,plain" {
+		t.Errorf("Names ends with %s, want the custom themes after the presets", got)
+	}
+	if len(names) != len(AvailableThemes())+2 {
+		t.Errorf("Names = %v, want nord listed once", names)
+	}
+}
+
+func TestValidateCustomTheme(t *testing.T) {
+	s := DefaultSettings()
+	s.Theme.Name = "mine"
+	s.Theme.Custom = map[string]CustomTheme{
+		"mine": {Base: "solarised", Highlight: "no-such-style", Prompt: "#12345", Info: "cyan"},
+	}
+	var got []string
+	for _, issue := range s.Validate() {
+		if strings.HasPrefix(issue.Path, "theme.") {
+			got = append(got, issue.Path+": "+issue.Message)
+		}
+	}
+	want := []string{
+		`theme.custom.mine.base: unknown preset "solarised" (use default, matrix, solarized, gruvbox, dracula, nord)`,
+		`theme.custom.mine.highlight: unknown chroma style "no-such-style"`,
+		`theme.custom.mine.prompt: invalid color "#12345" (use #rrggbb or a built-in color name)`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("issues:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	s.Theme.Name = "other"
+	if issues := s.Validate(); len(issues) == 0 || !strings.Contains(issues[0].Message, "mine") {
+		t.Errorf("unknown theme issue should list custom themes: %v", issues)
+	}
+}
+
+func TestThemeFromEnv(t *testing.T) {
+	_, project := setupLayers(t, `{"theme": {"custom": {"mine": {"prompt": "#ff8800"}}}}`, "")
+	t.Setenv("BJARNE_THEME", "mine")
+	l, err := LoadSettingsLayers(project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Settings.Theme.Name != "mine" {
+		t.Errorf("theme.name = %q, want the custom theme from BJARNE_THEME", l.Settings.Theme.Name)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// warningsAsErrorsSuffix marks a check that a .clang-tidy WarningsAsErrors entry promoted to an error
+const warningsAsErrorsSuffix = ",-warnings-as-errors"
+
+// tidyPolicyPrefix starts the line that explains a policy failure in a stage's error output
+const tidyPolicyPrefix = "clang-tidy policy: "
+
+// tidyVerdict counts a clang-tidy run's findings by how the policy treats them
+type tidyVerdict struct {
+	Blocking []string // "check (file:line)" for each blocking finding
+	Warnings int      // Findings counted against the warning budget
+	Advisory int      // Findings reported but never failing
+}
+
+// SetClangTidySettings configures which clang-tidy findings fail the gate
+func (c *ContainerRuntime) SetClangTidySettings(settings ClangTidySettings) {
+	c.tidy = settings
+}
+
+// judge classifies clang-tidy diagnostics under the policy
+// Compiler errors always block. A finding matching Advisory never blocks, even when
+// WarningsAsErrors promoted it; one matching Blocking, or promoted, always does; the
+// rest count against MaxWarnings
+func (s ClangTidySettings) judge(diags []Diagnostic) tidyVerdict {
+	var v tidyVerdict
+	for _, d := range diags {
+		if d.Level == LevelNote {
+			continue
+		}
+		check, promoted := strings.CutSuffix(d.Check, warningsAsErrorsSuffix)
+		name := check
+		if name == "" {
+			name = string(d.Level)
+		}
+		switch {
+		case d.Level == LevelError && !promoted:
+			v.Blocking = append(v.Blocking, fmt.Sprintf("%s (%s:%d)", name, d.File, d.Line))
+		case matchesCheck(s.Advisory, check):
+			v.Advisory++
+		case promoted || matchesCheck(s.Blocking, check):
+			v.Blocking = append(v.Blocking, fmt.Sprintf("%s (%s:%d)", name, d.File, d.Line))
+		default:
+			v.Warnings++
+		}
+	}
+	return v
+}
+
+// Passed reports whether the findings are within the policy
+func (v tidyVerdict) Passed(maxWarnings int) bool {
+	return len(v.Blocking) == 0 && (maxWarnings < 0 || v.Warnings <= maxWarnings)
+}
+
+// Reason explains a failing verdict in one line
+func (v tidyVerdict) Reason(maxWarnings int) string {
+	var parts []string
+	if n := len(v.Blocking); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d blocking finding(s): %s", n, strings.Join(v.Blocking, ", ")))
+	}
+	if maxWarnings >= 0 && v.Warnings > maxWarnings {
+		parts = append(parts, fmt.Sprintf("%d warnings exceed the budget of %d", v.Warnings, maxWarnings))
+	}
+	return tidyPolicyPrefix + strings.Join(parts, "; ")
+}
+
+// applyTidyPolicy decides a clang-tidy stage's pass/fail from its findings instead of the exit code
+// Suppressed findings are dropped first. A failed run with no parsable findings (a crash,
+// a missing tool) keeps its result
+func (c *ContainerRuntime) applyTidyPolicy(tmpDir string, r ValidationResult) ValidationResult {
+	if r.Skipped {
+		return r
+	}
+	text := strings.TrimSpace(r.Output + "\n" + r.Error)
+	diags := ParseClangTidyOutput(text)
+	if len(diags) == 0 {
+		return r
+	}
+
+	var kept []Diagnostic
+	for _, d := range diags {
+		if d.Level != LevelNote && c.suppresses(tmpDir, "clang-tidy", d) {
+			r.Suppressed++
+			continue
+		}
+		kept = append(kept, d)
+	}
+
+	v := c.tidy.judge(kept)
+	if v.Passed(c.tidy.MaxWarnings) {
+		r.Success, r.Error = true, ""
+		return r
+	}
+	r.Success = false
+	r.Error = text + "\n" + v.Reason(c.tidy.MaxWarnings)
+	return r
+}
+
+// matchesCheck reports whether a check name (possibly a comma-separated alias list) matches a pattern
+func matchesCheck(patterns []string, check string) bool {
+	for _, name := range strings.Split(check, ",") {
+		for _, p := range patterns {
+			if ok, _ := path.Match(p, strings.TrimSpace(name)); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const tidyOutput = `/src/code.cpp:3:5: warning: use auto when initializing with new [modernize-use-auto]
+/src/code.cpp:7:9: warning: the value returned by this function should be used [bugprone-unused-return-value]
+/src/code.cpp:7:9: note: cast the expression to void to silence this warning
+/src/code.cpp:9:1: warning: function 'f' has cognitive complexity of 30 [readability-function-cognitive-complexity]
+/src/code.cpp:12:3: error: narrowing conversion from 'long' to 'int' [cppcoreguidelines-narrowing-conversions,-warnings-as-errors]
+`
+
+func TestClangTidyJudge(t *testing.T) {
+	diags := ParseClangTidyOutput(tidyOutput)
+
+	tests := []struct {
+		name         string
+		policy       ClangTidySettings
+		wantBlocking int
+		wantWarnings int
+		wantAdvisory int
+	}{
+		{
+			name:         "no policy: only promoted findings block",
+			policy:       ClangTidySettings{MaxWarnings: -1},
+			wantBlocking: 1,
+			wantWarnings: 3,
+		},
+		{
+			name:         "blocking and advisory families",
+			policy:       ClangTidySettings{Blocking: []string{"bugprone-*"}, Advisory: []string{"modernize-*", "readability-*"}},
+			wantBlocking: 2,
+			wantAdvisory: 2,
+		},
+		{
+			name:         "advisory demotes a promoted check",
+			policy:       ClangTidySettings{Advisory: []string{"cppcoreguidelines-*"}},
+			wantWarnings: 3,
+			wantAdvisory: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := tt.policy.judge(diags)
+			if len(v.Blocking) != tt.wantBlocking || v.Warnings != tt.wantWarnings || v.Advisory != tt.wantAdvisory {
+				t.Errorf("judge() = %d blocking %v, %d warnings, %d advisory; want %d, %d, %d",
+					len(v.Blocking), v.Blocking, v.Warnings, v.Advisory, tt.wantBlocking, tt.wantWarnings, tt.wantAdvisory)
+			}
+		})
+	}
+}
+
+func TestClangTidyJudgeCompilerErrors(t *testing.T) {
+	diags := ParseClangTidyOutput("/src/code.cpp:4:1: error: unknown type name 'strin' [clang-diagnostic-error]\n")
+	v := ClangTidySettings{Advisory: []string{"*"}}.judge(diags)
+	if len(v.Blocking) != 1 {
+		t.Errorf("compiler errors must block even when every check is advisory, got %+v", v)
+	}
+}
+
+func TestApplyTidyPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      ClangTidySettings
+		result      ValidationResult
+		wantSuccess bool
+		wantReason  string
+	}{
+		{
+			name:        "warnings over budget fail a clean exit",
+			policy:      ClangTidySettings{MaxWarnings: 2, Advisory: []string{"cppcoreguidelines-*"}},
+			result:      ValidationResult{Stage: "clang-tidy", Success: true, Output: tidyOutput},
+			wantSuccess: false,
+			wantReason:  "3 warnings exceed the budget of 2",
+		},
+		{
+			name:        "blocking family fails",
+			policy:      ClangTidySettings{MaxWarnings: -1, Blocking: []string{"bugprone-*"}, Advisory: []string{"cppcoreguidelines-*"}},
+			result:      ValidationResult{Stage: "clang-tidy:main.cpp", Success: true, Output: tidyOutput},
+			wantSuccess: false,
+			wantReason:  "bugprone-unused-return-value (/src/code.cpp:7)",
+		},
+		{
+			name:        "advisory demotes a WarningsAsErrors failure",
+			policy:      ClangTidySettings{MaxWarnings: -1, Advisory: []string{"cppcoreguidelines-*"}},
+			result:      ValidationResult{Stage: "clang-tidy", Success: false, Output: tidyOutput, Error: "1 warning treated as error"},
+			wantSuccess: true,
+		},
+		{
+			name:        "a failure without findings stands",
+			policy:      ClangTidySettings{MaxWarnings: -1},
+			result:      ValidationResult{Stage: "clang-tidy", Success: false, Error: "clang-tidy: command not found"},
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &ContainerRuntime{}
+			c.SetClangTidySettings(tt.policy)
+			got := c.applyTidyPolicy("", tt.result)
+			if got.Success != tt.wantSuccess {
+				t.Fatalf("Success = %v, want %v (error %q)", got.Success, tt.wantSuccess, got.Error)
+			}
+			if tt.wantReason != "" && !strings.Contains(got.Error, tidyPolicyPrefix) {
+				t.Errorf("Error has no policy line: %q", got.Error)
+			}
+			if !strings.Contains(got.Error, tt.wantReason) {
+				t.Errorf("Error = %q, want it to mention %q", got.Error, tt.wantReason)
+			}
+			if tt.wantSuccess && got.Error != "" {
+				t.Errorf("passing result kept error %q", got.Error)
+			}
+		})
+	}
+}
+
+func TestFormatStageErrorShowsTidyPolicy(t *testing.T) {
+	c := &ContainerRuntime{}
+	c.SetClangTidySettings(ClangTidySettings{MaxWarnings: 0, Advisory: []string{"cppcoreguidelines-*"}})
+	r := c.applyTidyPolicy("", ValidationResult{Stage: "clang-tidy", Success: true, Output: tidyOutput})
+
+	formatted := formatStageError(r.Stage, r.Error, PathMap{})
+	if !strings.Contains(formatted, "modernize-use-auto") || !strings.Contains(formatted, "exceed the budget of 0") {
+		t.Errorf("formatStageError() = %q, want the findings and the policy reason", formatted)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// CountTokens estimates the tokens a BPE tokenizer produces for text
+// It mirrors the pre-tokenization of cl100k/o200k-style tokenizers (which Claude's and Gemini's
+// counts track closely for code): words take one leading space, digits group in threes,
+// punctuation pairs up, and long or camelCase identifiers split into several tokens
+func CountTokens(text string) int {
+	tokens := 0
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+
+		// A single space joins the word or punctuation after it
+		if r == ' ' && i+1 < len(text) {
+			next, _ := utf8.DecodeRuneInString(text[i+1:])
+			if unicode.IsLetter(next) || isTokenPunct(next) {
+				i += size
+				continue
+			}
+		}
+
+		j := i + size
+		switch {
+		case r < utf8.RuneSelf && unicode.IsLetter(r):
+			for j < len(text) && text[j] < utf8.RuneSelf && unicode.IsLetter(rune(text[j])) {
+				j++
+			}
+			tokens += wordTokens(text[i:j])
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			tokens++ // About one token per ideograph or syllable
+		case unicode.IsLetter(r):
+			runes := 1
+			for j < len(text) {
+				next, n := utf8.DecodeRuneInString(text[j:])
+				if next < utf8.RuneSelf || !unicode.IsLetter(next) || unicode.In(next, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+					break
+				}
+				j += n
+				runes++
+			}
+			tokens += (runes + 1) / 2
+		case unicode.IsDigit(r):
+			digits := 1
+			for j < len(text) && text[j] >= '0' && text[j] <= '9' {
+				j++
+				digits++
+			}
+			tokens += (digits + 2) / 3
+		case unicode.IsSpace(r):
+			newline := r == '\n'
+			for j < len(text) && (text[j] == ' ' || text[j] == '\t' || text[j] == '\n' || text[j] == '\r') {
+				newline = newline || text[j] == '\n'
+				j++
+			}
+			tokens++
+			if newline && text[j-1] == ' ' {
+				tokens++ // Indentation after a line break is its own token
+			}
+		case isTokenPunct(r):
+			for j < len(text) && isTokenPunct(rune(text[j])) {
+				j++
+			}
+			tokens += (j - i + 1) / 2
+		default:
+			tokens += (size + 1) / 2 // Emoji and symbols take a token per one or two bytes
+		}
+		i = j
+	}
+	return tokens
+}
+
+// isTokenPunct reports ASCII punctuation and symbols
+func isTokenPunct(r rune) bool {
+	return r < utf8.RuneSelf && (unicode.IsPunct(r) || unicode.IsSymbol(r))
+}
+
+// wordTokens estimates an ASCII word: each camelCase part up to eight letters is usually one
+// token, longer parts split about every six letters
+func wordTokens(word string) int {
+	tokens := 0
+	start := 0
+	for k := 1; k <= len(word); k++ {
+		boundary := k == len(word) ||
+			(unicode.IsLower(rune(word[k-1])) && unicode.IsUpper(rune(word[k]))) ||
+			(k+1 < len(word) && unicode.IsUpper(rune(word[k-1])) && unicode.IsUpper(rune(word[k])) && unicode.IsLower(rune(word[k+1])))
+		if !boundary {
+			continue
+		}
+		if n := k - start; n <= 8 {
+			tokens++
+		} else {
+			tokens += (n + 5) / 6
+		}
+		start = k
+	}
+	return tokens
+}
+
+// fillUsageEstimate counts tokens locally for whatever usage a provider did not report
+// (streaming responses, local servers without usage)
+func fillUsageEstimate(result *GenerateResult, systemPrompt string, messages []Message) {
+	if result == nil {
+		return
+	}
+	if result.InputTokens == 0 {
+		result.InputTokens = estimateRequestTokens(systemPrompt, messages)
+		result.Estimated = true
+	}
+	if result.OutputTokens == 0 && result.Text != "" {
+		result.OutputTokens = CountTokens(result.Text)
+		result.Estimated = true
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCountTokens(t *testing.T) {
+	// Expected values are cl100k counts; the estimate may differ by a token or two
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{"empty", "", 0},
+		{"sentence", "Hello, world!", 4},
+		{"code", "int main() { return 0; }", 9},
+		{"long number", "1234567", 3},
+		{"camelCase", "getUserName", 3},
+		{"include", "#include <vector>", 4},
+		{"indented line", "\n    x = 1;", 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CountTokens(tt.text)
+			if diff := got - tt.want; diff < -2 || diff > 2 {
+				t.Errorf("CountTokens(%q) = %d, want about %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountTokensScalesWithCode(t *testing.T) {
+	snippet := `template <typename T>
+class RingBuffer {
+public:
+    explicit RingBuffer(std::size_t capacity) : data_(capacity) {}
+    bool push(const T& value) {
+        if (size_ == data_.size()) return false;
+        data_[(head_ + size_++) % data_.size()] = value;
+        return true;
+    }
+private:
+    std::vector<T> data_;
+    std::size_t head_ = 0, size_ = 0;
+};
+`
+	one := CountTokens(snippet)
+	// Code runs about three to four characters per token
+	if perToken := float64(len(snippet)) / float64(one); perToken < 2.5 || perToken > 4.5 {
+		t.Errorf("CountTokens() = %d for %d chars (%.1f chars/token)", one, len(snippet), perToken)
+	}
+	if ten := CountTokens(strings.Repeat(snippet, 10)); ten < 9*one || ten > 11*one {
+		t.Errorf("CountTokens(10x) = %d, want about %d", ten, 10*one)
+	}
+}
+
+func TestFillUsageEstimate(t *testing.T) {
+	messages := []Message{{Role: "user", Content: "write a ring buffer"}}
+
+	reported := &GenerateResult{Text: "ok", InputTokens: 10, OutputTokens: 1}
+	fillUsageEstimate(reported, "system", messages)
+	if reported.Estimated || reported.InputTokens != 10 {
+		t.Errorf("fillUsageEstimate() replaced reported usage: %+v", reported)
+	}
+
+	streamed := &GenerateResult{Text: "int main() { return 0; }"}
+	fillUsageEstimate(streamed, "system", messages)
+	if !streamed.Estimated || streamed.InputTokens == 0 || streamed.OutputTokens != CountTokens(streamed.Text) {
+		t.Errorf("fillUsageEstimate() = %+v", streamed)
+	}
+}
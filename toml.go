@@ -0,0 +1,372 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseTOML reads the subset of TOML that project settings use into nested maps:
+// [table] headers, dotted and quoted keys, basic and literal strings (including their triple-quoted
+// multi-line forms), integers, floats, booleans, arrays and inline tables, and # comments
+// Dates and [[arrays of tables]] are not supported
+// lines records the line each key path (joined with ".") was set on
+func parseTOML(data string) (root map[string]any, lines map[string]int, err error) {
+	p := &tomlParser{src: strings.ReplaceAll(data, "\r\n", "\n"), line: 1, lines: make(map[string]int)}
+	root = make(map[string]any)
+	table := root
+	var tablePath []string
+	for {
+		p.skipBlank()
+		if p.eof() {
+			return root, p.lines, nil
+		}
+		if p.peek() == '[' {
+			if strings.HasPrefix(p.src[p.pos:], "[[") {
+				return nil, nil, p.errorf("arrays of tables ([[...]]) are not supported")
+			}
+			p.pos++
+			if tablePath, err = p.key(); err != nil {
+				return nil, nil, err
+			}
+			p.skipSpace()
+			if p.eof() || p.peek() != ']' {
+				return nil, nil, p.errorf("expected ] after table name")
+			}
+			p.pos++
+			if table, err = p.table(root, tablePath); err != nil {
+				return nil, nil, err
+			}
+		} else if err := p.keyValue(table, tablePath); err != nil {
+			return nil, nil, err
+		}
+		if err := p.endOfLine(); err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+// tomlParser walks the document; pos is the next unread byte
+type tomlParser struct {
+	src   string
+	pos   int
+	line  int
+	lines map[string]int
+}
+
+// errorf reports a problem at the current line
+func (p *tomlParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+func (p *tomlParser) eof() bool  { return p.pos >= len(p.src) }
+func (p *tomlParser) peek() byte { return p.src[p.pos] }
+
+// skipSpace moves past spaces and tabs
+func (p *tomlParser) skipSpace() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.pos++
+	}
+}
+
+// skipComment moves past a # comment, up to the line break
+func (p *tomlParser) skipComment() {
+	if !p.eof() && p.peek() == '#' {
+		for !p.eof() && p.peek() != '\n' {
+			p.pos++
+		}
+	}
+}
+
+// skipBlank moves past whitespace, line breaks and comments
+func (p *tomlParser) skipBlank() {
+	for {
+		p.skipSpace()
+		p.skipComment()
+		if p.eof() || p.peek() != '\n' {
+			return
+		}
+		p.pos++
+		p.line++
+	}
+}
+
+// endOfLine requires nothing but a comment before the next line
+func (p *tomlParser) endOfLine() error {
+	p.skipSpace()
+	p.skipComment()
+	if p.eof() {
+		return nil
+	}
+	if p.peek() != '\n' {
+		return p.errorf("unexpected %q after value", p.src[p.pos:strings.IndexByte(p.src[p.pos:]+"\n", '\n')+p.pos])
+	}
+	p.pos++
+	p.line++
+	return nil
+}
+
+// key reads a bare, quoted or dotted key
+func (p *tomlParser) key() ([]string, error) {
+	var parts []string
+	for {
+		p.skipSpace()
+		if p.eof() {
+			return nil, p.errorf("expected a key")
+		}
+		switch c := p.peek(); {
+		case c == '"' || c == '\'':
+			s, err := p.stringValue()
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, s)
+		default:
+			start := p.pos
+			for !p.eof() && isTOMLBareKeyChar(p.peek()) {
+				p.pos++
+			}
+			if start == p.pos {
+				return nil, p.errorf("expected a key, got %q", string(c))
+			}
+			parts = append(parts, p.src[start:p.pos])
+		}
+		p.skipSpace()
+		if p.eof() || p.peek() != '.' {
+			return parts, nil
+		}
+		p.pos++
+	}
+}
+
+// isTOMLBareKeyChar reports whether c can appear in an unquoted key
+func isTOMLBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// table returns the table at path, creating it (and its parents) as needed
+func (p *tomlParser) table(root map[string]any, path []string) (map[string]any, error) {
+	t := root
+	for i, name := range path {
+		switch v := t[name].(type) {
+		case nil:
+			child := make(map[string]any)
+			t[name] = child
+			t = child
+		case map[string]any:
+			t = v
+		default:
+			return nil, p.errorf("%s is already a value, not a table", strings.Join(path[:i+1], "."))
+		}
+	}
+	return t, nil
+}
+
+// keyValue reads "key = value" into table
+func (p *tomlParser) keyValue(table map[string]any, tablePath []string) error {
+	key, err := p.key()
+	if err != nil {
+		return err
+	}
+	if p.eof() || p.peek() != '=' {
+		return p.errorf("expected = after %s", strings.Join(key, "."))
+	}
+	p.pos++
+	p.skipSpace()
+	line := p.line
+	value, err := p.value()
+	if err != nil {
+		return err
+	}
+	parent, err := p.table(table, key[:len(key)-1])
+	if err != nil {
+		return err
+	}
+	name := key[len(key)-1]
+	if _, dup := parent[name]; dup {
+		return fmt.Errorf("line %d: duplicate key %s", line, strings.Join(key, "."))
+	}
+	parent[name] = value
+	p.lines[strings.Join(append(append([]string{}, tablePath...), key...), ".")] = line
+	return nil
+}
+
+// value reads any value
+func (p *tomlParser) value() (any, error) {
+	if p.eof() {
+		return nil, p.errorf("expected a value")
+	}
+	switch p.peek() {
+	case '"', '\'':
+		return p.stringValue()
+	case '[':
+		return p.array()
+	case '{':
+		return p.inlineTable()
+	}
+	start := p.pos
+	for !p.eof() && strings.IndexByte(" \t\n#,]}", p.peek()) < 0 {
+		p.pos++
+	}
+	word := p.src[start:p.pos]
+	switch word {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "":
+		return nil, p.errorf("expected a value")
+	}
+	if n, err := strconv.ParseInt(word, 0, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(strings.ReplaceAll(word, "_", ""), 64); err == nil {
+		return f, nil
+	}
+	return nil, p.errorf("unsupported value %q (strings need quotes)", word)
+}
+
+// stringValue reads a basic "..." or literal '...' string, or their triple-quoted forms
+func (p *tomlParser) stringValue() (string, error) {
+	quote := p.src[p.pos : p.pos+1]
+	if triple := strings.Repeat(quote, 3); strings.HasPrefix(p.src[p.pos:], triple) {
+		p.pos += 3
+		end := strings.Index(p.src[p.pos:], triple)
+		if end < 0 {
+			return "", p.errorf("unterminated %s string", triple)
+		}
+		body := p.src[p.pos : p.pos+end]
+		p.line += strings.Count(body, "\n")
+		p.pos += end + 3
+		body = strings.TrimPrefix(body, "\n") // A newline right after the opening quotes is trimmed
+		if quote == "'" {
+			return body, nil
+		}
+		s, err := strconv.Unquote(`"` + quoteTOMLBody(body) + `"`)
+		if err != nil {
+			return "", p.errorf("invalid escape in string")
+		}
+		return s, nil
+	}
+
+	p.pos++
+	for i := p.pos; i < len(p.src) && p.src[i] != '\n'; i++ {
+		if quote == `"` && p.src[i] == '\\' {
+			i++
+			continue
+		}
+		if p.src[i] == quote[0] {
+			raw := p.src[p.pos:i]
+			p.pos = i + 1
+			if quote == "'" {
+				return raw, nil
+			}
+			s, err := strconv.Unquote(`"` + raw + `"`)
+			if err != nil {
+				return "", p.errorf("invalid escape in string %q", raw)
+			}
+			return s, nil
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+// quoteTOMLBody escapes the raw line breaks, tabs and quotes of a """ string so that
+// strconv.Unquote can interpret its escape sequences
+func quoteTOMLBody(body string) string {
+	var sb strings.Builder
+	for i := 0; i < len(body); i++ {
+		switch c := body[i]; c {
+		case '\\':
+			sb.WriteByte(c)
+			if i+1 < len(body) {
+				i++
+				sb.WriteByte(body[i])
+			}
+		case '"':
+			sb.WriteString(`\"`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\t':
+			sb.WriteString(`\t`)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// array reads [a, b, ...], which may span lines
+func (p *tomlParser) array() ([]any, error) {
+	p.pos++ // [
+	items := []any{}
+	for {
+		p.skipBlank()
+		if p.eof() {
+			return nil, p.errorf("unterminated array")
+		}
+		if p.peek() == ']' {
+			p.pos++
+			return items, nil
+		}
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, v)
+		p.skipBlank()
+		switch {
+		case p.eof():
+			return nil, p.errorf("unterminated array")
+		case p.peek() == ',':
+			p.pos++
+		case p.peek() != ']':
+			return nil, p.errorf("expected , or ] in array")
+		}
+	}
+}
+
+// inlineTable reads {a = 1, b = "x"} on one line
+func (p *tomlParser) inlineTable() (map[string]any, error) {
+	p.pos++ // {
+	t := make(map[string]any)
+	p.skipSpace()
+	if !p.eof() && p.peek() == '}' {
+		p.pos++
+		return t, nil
+	}
+	for {
+		key, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		if p.eof() || p.peek() != '=' {
+			return nil, p.errorf("expected = in inline table")
+		}
+		p.pos++
+		p.skipSpace()
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		parent, err := p.table(t, key[:len(key)-1])
+		if err != nil {
+			return nil, err
+		}
+		parent[key[len(key)-1]] = v
+		p.skipSpace()
+		if p.eof() {
+			return nil, p.errorf("unterminated inline table")
+		}
+		switch p.peek() {
+		case ',':
+			p.pos++
+			p.skipSpace()
+		case '}':
+			p.pos++
+			return t, nil
+		default:
+			return nil, p.errorf("expected , or } in inline table")
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTOML(t *testing.T) {
+	doc := `# project settings
+provider = "local"   # trailing comment
+count = 1_000
+hex = 0x10
+ratio = 0.5
+on = true
+
+[review]
+threshold = 85
+rubric = [
+  "no raw new",  # one per line
+  'C:\paths\stay',
+]
+
+[models.complexity]
+easy = "haiku"
+
+[container]
+profiles.qt = { image = "qt:6", digest = "" }
+"quoted key" = """
+first line
+second "line"\t!"""
+literal = '''
+keep \n as is'''
+empty = []
+`
+	got, lines, err := parseTOML(doc)
+	if err != nil {
+		t.Fatalf("parseTOML: %v", err)
+	}
+	want := map[string]any{
+		"provider": "local",
+		"count":    int64(1000),
+		"hex":      int64(16),
+		"ratio":    0.5,
+		"on":       true,
+		"review": map[string]any{
+			"threshold": int64(85),
+			"rubric":    []any{"no raw new", `C:\paths\stay`},
+		},
+		"models": map[string]any{"complexity": map[string]any{"easy": "haiku"}},
+		"container": map[string]any{
+			"profiles":   map[string]any{"qt": map[string]any{"image": "qt:6", "digest": ""}},
+			"quoted key": "first line\nsecond \"line\"\t!",
+			"literal":    `keep \n as is`,
+			"empty":      []any{},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseTOML =\n%#v\nwant\n%#v", got, want)
+	}
+	for key, line := range map[string]int{"provider": 2, "review.threshold": 9, "models.complexity.easy": 16, "container.profiles.qt": 19, "container.literal": 23} {
+		if lines[key] != line {
+			t.Errorf("line of %s = %d, want %d", key, lines[key], line)
+		}
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	tests := []struct {
+		doc  string
+		want string
+	}{
+		{"a = 1\na = 2", "line 2: duplicate key a"},
+		{"a = 1\n[a]\nb = 2", "line 2: a is already a value"},
+		{"[[servers]]", "arrays of tables"},
+		{"when = 1979-05-27", "unsupported value"},
+		{"name = bare", "strings need quotes"},
+		{"name = \"open", "unterminated string"},
+		{"list = [1, 2", "unterminated array"},
+		{"a = 1 b = 2", "unexpected"},
+		{"= 1", "expected a key"},
+		{"[review", "expected ]"},
+	}
+	for _, tt := range tests {
+		_, _, err := parseTOML(tt.doc)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("parseTOML(%q) error = %v, want %q", tt.doc, err, tt.want)
+		}
+	}
+}
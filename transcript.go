@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// transcriptVersion is the format version written by /export
+const transcriptVersion = 1
+
+// maxCodeVersions bounds the code versions a session keeps for /export
+const maxCodeVersions = 50
+
+// CodeVersion is the code after one validation run, with the gates it passed
+type CodeVersion struct {
+	Time  time.Time        `json:"time"`
+	Model string           `json:"model,omitempty"`
+	Files []CodeFile       `json:"files"`
+	Gates []checkpointGate `json:"gates,omitempty"`
+}
+
+// Passed reports whether every gate that ran passed
+func (v CodeVersion) Passed() bool {
+	for _, g := range v.Gates {
+		if !g.Passed {
+			return false
+		}
+	}
+	return len(v.Gates) > 0
+}
+
+// Transcript is a session exported by /export and seeded back with /import
+type Transcript struct {
+	Version      int               `json:"version"`
+	Exported     time.Time         `json:"exported"`
+	Session      string            `json:"session,omitempty"`
+	Prompt       string            `json:"prompt,omitempty"`
+	Conversation []Message         `json:"conversation"`
+	Code         []CodeFile        `json:"code,omitempty"`
+	Validated    bool              `json:"validated"`
+	Versions     []CodeVersion     `json:"versions,omitempty"`
+	DoD          *DefinitionOfDone `json:"dod,omitempty"`
+	Examples     *ExampleTests     `json:"examples,omitempty"`
+}
+
+// Markdown transcript markers; they are HTML comments, so the file still reads as plain markdown
+const (
+	mdMarkerPrefix = "<!-- bjarne:"
+	mdMarkerSuffix = " -->"
+)
+
+// mdMarkerPattern matches a marker line, capturing its kind and argument
+var mdMarkerPattern = regexp.MustCompile(`^<!-- bjarne:(\w+)(?: (.*?))? -->$`)
+
+// WriteTranscript writes t to path as JSON, or as markdown for .md files
+func WriteTranscript(path string, t *Transcript) error {
+	var data []byte
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		var err error
+		if data, err = json.MarshalIndent(t, "", "  "); err != nil {
+			return fmt.Errorf("failed to encode transcript: %w", err)
+		}
+	case ".md", ".markdown":
+		data = []byte(t.Markdown())
+	default:
+		return fmt.Errorf("unsupported transcript format %q (use .md or .json)", filepath.Ext(path))
+	}
+	if err := saveToPath(path, string(data)); err != nil {
+		return fmt.Errorf("failed to write transcript: %w", err)
+	}
+	return nil
+}
+
+// ReadTranscript reads a transcript written by WriteTranscript
+func ReadTranscript(path string) (*Transcript, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %w", err)
+	}
+	var t *Transcript
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		t = &Transcript{}
+		if err := json.Unmarshal(data, t); err != nil {
+			return nil, fmt.Errorf("failed to parse transcript: %w", err)
+		}
+	case ".md", ".markdown":
+		t = parseMarkdownTranscript(string(data))
+	default:
+		return nil, fmt.Errorf("unsupported transcript format %q (use .md or .json)", filepath.Ext(path))
+	}
+	if t.Version > transcriptVersion {
+		return nil, fmt.Errorf("transcript format %d is newer than this bjarne supports (%d)", t.Version, transcriptVersion)
+	}
+	if len(t.Conversation) == 0 && len(t.Code) == 0 {
+		return nil, fmt.Errorf("%s has no conversation or code to import", path)
+	}
+	return t, nil
+}
+
+// mdMarker renders a marker line
+func mdMarker(kind, arg string) string {
+	if arg != "" {
+		kind += " " + arg
+	}
+	return mdMarkerPrefix + kind + mdMarkerSuffix + "\n"
+}
+
+// Markdown renders the transcript for reading; the markers let /import read it back
+func (t *Transcript) Markdown() string {
+	var sb strings.Builder
+	sb.WriteString("# bjarne session")
+	if t.Session != "" {
+		sb.WriteString(" " + t.Session)
+	}
+	sb.WriteString("\n\n")
+	sb.WriteString(mdMarker("meta", fmt.Sprintf("version=%d validated=%t", t.Version, t.Validated)))
+	sb.WriteString(fmt.Sprintf("Exported %s.\n", t.Exported.Format("2006-01-02 15:04:05")))
+	if t.Prompt != "" {
+		sb.WriteString(mdMarker("prompt", ""))
+		sb.WriteString("**Request:** " + strings.TrimSpace(t.Prompt) + "\n")
+	}
+
+	sb.WriteString("\n" + mdMarker("end", "") + "\n## Conversation\n")
+	for _, msg := range t.Conversation {
+		heading := "You"
+		if msg.Role == "assistant" {
+			heading = "bjarne"
+		}
+		sb.WriteString("\n" + mdMarker("message", msg.Role))
+		sb.WriteString("### " + heading + "\n\n")
+		sb.WriteString(strings.TrimRight(msg.Content, "\n") + "\n")
+	}
+
+	if len(t.Versions) > 0 {
+		sb.WriteString("\n" + mdMarker("end", "") + "\n## Validation history\n\n")
+		for i, v := range t.Versions {
+			status := "failed"
+			if v.Passed() {
+				status = "passed"
+			}
+			sb.WriteString(fmt.Sprintf("%d. %s: %s", i+1, v.Time.Format("15:04:05"), status))
+			var gates []string
+			for _, g := range v.Gates {
+				if g.Skipped {
+					continue
+				}
+				mark := "ok"
+				if !g.Passed {
+					mark = "FAIL"
+				}
+				gates = append(gates, g.Stage+" "+mark)
+			}
+			if len(gates) > 0 {
+				sb.WriteString(" (" + strings.Join(gates, ", ") + ")")
+			}
+			if v.Model != "" {
+				sb.WriteString(", " + shortModelName(v.Model))
+			}
+			sb.WriteString("\n")
+		}
+	}
+
+	if len(t.Code) > 0 {
+		status := "not validated"
+		if t.Validated {
+			status = "validated"
+		}
+		sb.WriteString("\n" + mdMarker("end", "") + "\n## Current code (" + status + ")\n")
+		for _, f := range t.Code {
+			sb.WriteString("\n" + mdMarker("file", f.Filename))
+			sb.WriteString("### " + f.Filename + "\n\n```cpp\n")
+			sb.WriteString(strings.TrimRight(f.Content, "\n") + "\n```\n")
+		}
+	}
+	return sb.String()
+}
+
+// parseMarkdownTranscript reads the conversation, request and code back from Markdown output
+// Version history, the Definition of Done and example tests are only kept by JSON exports
+func parseMarkdownTranscript(text string) *Transcript {
+	t := &Transcript{Version: transcriptVersion}
+	var kind, arg string
+	var body []string
+
+	flush := func() {
+		content := strings.Trim(strings.Join(body, "\n"), "\n")
+		switch kind {
+		case "prompt":
+			t.Prompt = strings.TrimPrefix(content, "**Request:** ")
+		case "message":
+			// Drop the "### You" / "### bjarne" heading
+			if _, rest, ok := strings.Cut(content, "\n"); ok && strings.HasPrefix(content, "### ") {
+				content = strings.TrimLeft(rest, "\n")
+			}
+			t.Conversation = append(t.Conversation, Message{Role: arg, Content: content})
+		case "file":
+			start := strings.Index(content, "```cpp\n")
+			end := strings.LastIndex(content, "\n```")
+			if start >= 0 && end > start {
+				t.Code = append(t.Code, CodeFile{Filename: arg, Content: content[start+len("```cpp\n"):end] + "\n"})
+			}
+		}
+		kind, arg, body = "", "", nil
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		m := mdMarkerPattern.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			body = append(body, line)
+			continue
+		}
+		flush()
+		kind, arg = m[1], m[2]
+		if kind == "meta" {
+			for _, field := range strings.Fields(arg) {
+				key, value, _ := strings.Cut(field, "=")
+				switch key {
+				case "version":
+					t.Version, _ = strconv.Atoi(value)
+				case "validated":
+					t.Validated = value == "true"
+				}
+			}
+			kind = ""
+		}
+	}
+	flush()
+	return t
+}
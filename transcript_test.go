@@ -0,0 +1,97 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sampleTranscript() *Transcript {
+	return &Transcript{
+		Version:  transcriptVersion,
+		Exported: time.Date(2026, 10, 16, 15, 30, 0, 0, time.UTC),
+		Session:  "20261016-153012-9f3a",
+		Prompt:   "a thread-safe queue",
+		Conversation: []Message{
+			{Role: "user", Content: "a thread-safe queue"},
+			{Role: "assistant", Content: "### Plan\n\nA mutex-guarded deque.\n\n```cpp\nstruct Q {};\n```"},
+		},
+		Code:      []CodeFile{{Filename: "queue.h", Content: "#pragma once\nstruct Q {};\n"}, {Filename: "main.cpp", Content: "int main() {}\n"}},
+		Validated: true,
+		Versions: []CodeVersion{
+			{Time: time.Date(2026, 10, 16, 15, 31, 0, 0, time.UTC), Gates: []checkpointGate{{Stage: "compile", Passed: true}, {Stage: "tsan", Passed: false}}},
+			{Time: time.Date(2026, 10, 16, 15, 32, 0, 0, time.UTC), Gates: []checkpointGate{{Stage: "compile", Passed: true}, {Stage: "tsan", Passed: true}}},
+		},
+		DoD: &DefinitionOfDone{ThreadSafe: true},
+	}
+}
+
+func TestTranscriptRoundTrip(t *testing.T) {
+	for _, name := range []string{"session.json", "session.md"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			if err := WriteTranscript(path, sampleTranscript()); err != nil {
+				t.Fatalf("WriteTranscript() error = %v", err)
+			}
+			got, err := ReadTranscript(path)
+			if err != nil {
+				t.Fatalf("ReadTranscript() error = %v", err)
+			}
+			want := sampleTranscript()
+			if got.Prompt != want.Prompt || !got.Validated || len(got.Conversation) != 2 || len(got.Code) != 2 {
+				t.Fatalf("ReadTranscript() = %+v", got)
+			}
+			for i, msg := range want.Conversation {
+				if got.Conversation[i].Role != msg.Role || got.Conversation[i].Content != msg.Content {
+					t.Errorf("message %d = %+v, want %+v", i, got.Conversation[i], msg)
+				}
+			}
+			for i, f := range want.Code {
+				if got.Code[i] != f {
+					t.Errorf("file %d = %+v, want %+v", i, got.Code[i], f)
+				}
+			}
+		})
+	}
+}
+
+func TestTranscriptMarkdown(t *testing.T) {
+	md := sampleTranscript().Markdown()
+	for _, want := range []string{
+		"# bjarne session 20261016-153012-9f3a",
+		"**Request:** a thread-safe queue",
+		"### You\n\na thread-safe queue",
+		"1. 15:31:00: failed (compile ok, tsan FAIL)",
+		"2. 15:32:00: passed",
+		"## Current code (validated)",
+		"### queue.h\n\n```cpp\n#pragma once",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown missing %q:\n%s", want, md)
+		}
+	}
+}
+
+func TestReadTranscriptErrors(t *testing.T) {
+	dir := t.TempDir()
+	if err := WriteTranscript(filepath.Join(dir, "session.txt"), sampleTranscript()); err == nil {
+		t.Error("WriteTranscript(.txt) should fail")
+	}
+
+	empty := filepath.Join(dir, "empty.md")
+	if err := saveToFile(empty, "# notes\n"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadTranscript(empty); err == nil {
+		t.Error("ReadTranscript() of a file without a conversation should fail")
+	}
+
+	newer := filepath.Join(dir, "newer.json")
+	if err := saveToFile(newer, `{"version": 99, "conversation": [{"role": "user", "content": "hi"}]}`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadTranscript(newer); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("ReadTranscript() of a newer format = %v", err)
+	}
+}
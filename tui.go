@@ -3,9 +3,13 @@ package main
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -22,12 +26,20 @@ const (
 	StateInput         State = iota
 	StateClassifying         // Quick classification with Haiku
 	StateThinking            // Full analysis with model based on complexity
+	StateDefiningDone        // Asking for testable acceptance criteria (COMPLEX tasks)
 	StateAcknowledging       // Processing user's response to clarifying questions
+	StatePlanning            // Drafting a file manifest (COMPLEX projects)
 	StateGenerating
 	StateValidating
-	StateFixing    // Attempting to fix failed code
-	StateReviewing // LLM code review gate
-	StateRevealing // Animated code reveal
+	StateFixing       // Attempting to fix failed code
+	StateReviewing    // LLM code review gate
+	StateRevealing    // Animated code reveal
+	StateApproving    // Waiting for Approve / Regenerate / Edit-prompt
+	StatePullingImage // Updating or rolling back the validator image (/image)
+	StateCompacting   // Summarizing older turns (/compact, or before a fix near the token budget)
+	StateConfirmSave  // Waiting for y/n before /save overwrites existing files
+	StateFillTemplate // Asking for the placeholders of a /template use
+	StateSettings     // Editing settings in the /settings form
 )
 
 // Box drawing characters for visual sections
@@ -88,18 +100,21 @@ type Styles struct {
 	Cross     lipgloss.Style
 }
 
-func NewStyles() *Styles {
+// NewStyles creates the TUI styles from the theme's colors
+func NewStyles(theme *Theme) *Styles {
+	color := func(c string) lipgloss.Style { return lipgloss.NewStyle().Foreground(lipglossColor(c)) }
+	p := theme.preset
 	return &Styles{
-		Prompt:    lipgloss.NewStyle().Foreground(lipgloss.Color("12")), // Blue
-		Success:   lipgloss.NewStyle().Foreground(lipgloss.Color("10")), // Green
-		Error:     lipgloss.NewStyle().Foreground(lipgloss.Color("9")),  // Red
-		Warning:   lipgloss.NewStyle().Foreground(lipgloss.Color("11")), // Yellow
-		Info:      lipgloss.NewStyle().Foreground(lipgloss.Color("14")), // Cyan
-		Accent:    lipgloss.NewStyle().Foreground(lipgloss.Color("13")), // Magenta
+		Prompt:    color(p.Prompt),
+		Success:   color(p.Success),
+		Error:     color(p.Error),
+		Warning:   color(p.Warning),
+		Info:      color(p.Info),
+		Accent:    color(p.Accent),
 		Dim:       lipgloss.NewStyle().Foreground(lipgloss.Color("8")),  // Gray
 		Code:      lipgloss.NewStyle().Foreground(lipgloss.Color("15")), // White
-		Checkmark: lipgloss.NewStyle().Foreground(lipgloss.Color("10")), // Green
-		Cross:     lipgloss.NewStyle().Foreground(lipgloss.Color("9")),  // Red
+		Checkmark: color(p.Success),
+		Cross:     color(p.Error),
 	}
 }
 
@@ -114,26 +129,63 @@ type Model struct {
 	state          State
 	statusMsg      string
 	startTime      time.Time
+	taskStart      time.Time // When the current request started, for notifications
 	tokenCount     int
 	currentCode    string     // For backwards compatibility and single-file projects
 	currentFiles   []CodeFile // Multi-file project support
 	validated      bool
-	analyzed       bool              // True after first analysis, subsequent inputs go to generation
-	originalPrompt string            // Store original prompt to parse examples
-	examples       *ExampleTests     // Parsed example tests from prompt
-	dod            *DefinitionOfDone // Definition of Done for complex tasks
-	difficulty     string            // EASY, MEDIUM, COMPLEX from classification
-	intent         string            // NEW, CONTINUE, QUESTION from classification
-	savedPath      string            // Path where code was last saved (empty = unsaved)
-	historyPath    string            // Path to auto-saved history file
+	analyzed       bool               // True after first analysis, subsequent inputs go to generation
+	originalPrompt string             // Store original prompt to parse examples
+	examples       *ExampleTests      // Parsed example tests from prompt
+	dod            *DefinitionOfDone  // Definition of Done for complex tasks
+	awaitingDoD    bool               // Next input answers the Definition of Done questions
+	testsPending   bool               // Parsed examples await confirmation before classification
+	pendingPrompt  string             // Prompt content held while examples are confirmed
+	plan           *ProjectPlan       // File manifest for a scaffolded COMPLEX project
+	planPending    bool               // Plan shown; Enter generates it file by file
+	planSkipped    bool               // Scaffolding declined for this task (generate in one pass)
+	scaffoldQueue  []PlannedFile      // Planned files still to write, in dependency order
+	scaffoldTries  int                // Rewrites of the current file after failed syntax checks
+	difficulty     string             // EASY, MEDIUM, COMPLEX from classification
+	intent         string             // NEW, CONTINUE, QUESTION from classification
+	savedPath      string             // Path where code was last saved (empty = unsaved)
+	workspacePaths PathMap            // Where the generated files were last saved, for finding locations
+	previousFiles  []CodeFile         // Last accepted code (for the approval diff)
+	manualEdit     bool               // Code being validated was edited by hand (/edit)
+	failedResults  []ValidationResult // Gates of the last failed validation, for /suppress
+	lastResults    []ValidationResult // Gates of the last validation, recorded by /checkpoint
+	pendingImages  []ImageAttachment  // Images attached with /image, sent with the next prompt
+	bestOf         int                // Candidates per generation (best-of-N, 1 = off)
+	strategy       string             // How failures are retried (StrategyFix or StrategyRegenerate)
+	regenAfter     int                // Failed fixes in a row before the regenerate strategy starts over
+	reviewMode     string             // Review consensus mode (ConsensusOff = one reviewer)
+	reviewMin      int                // Review confidence below which a fix is requested
+	reviewChecks   bool               // The reviewer writes functional checks that are run against the code
+	checkAnalysis  bool               // Reviewed code is compared with the analysis for divergences
+	analysis       string             // What the analysis (and acknowledgment) said would be built
+	modelOverride  string             // Model pinned with /model (empty = complexity-based)
+	turns          undoHistory        // Session state before each prompt, for /undo and /redo
+	sessionID      string             // Session ID (shared with the audit log), names the checkpoint directory
+	historyPath    string             // Path to auto-saved history file
+	pendingSave    *pendingSave       // /save waiting for overwrite confirmation
+	templateFill   *templateFill      // /template use waiting for placeholder values
+	settingsForm   *settingsForm      // Open /settings editor
+	gates          *gateProgress      // Gates of the validation in progress
+	versions       []CodeVersion      // Code after each validation, for /export
+	codeModel      string             // Model that wrote the current code, recorded in the history manifest
 
 	// Escalation tracking
-	currentIteration   int      // Current fix attempt within current model
-	currentModelIndex  int      // Index into escalation chain (-1 = generate model)
-	totalFixAttempts   int      // Total fix attempts across all models (for display)
-	lastValidationErrs string   // Last validation errors for fix prompt
-	modelsUsed         []string // Track which models we've tried
-	reviewFailures     int      // Count consecutive review failures (max 2 before showing code)
+	currentIteration   int                   // Current fix attempt within current model
+	currentModelIndex  int                   // Index into escalation chain (-1 = generate model)
+	totalFixAttempts   int                   // Total fix attempts across all models (for display)
+	fixesSinceFresh    int                   // Fix attempts since the code was last generated from scratch
+	failures           failureHistory        // Kinds of failure met while fixing the current request
+	lastValidationErrs string                // Last validation errors for fix prompt
+	fixKnowledge       *FixKnowledge         // Fixes that worked in past sessions (nil = learning off)
+	failureSigs        map[string]FixPattern // Kinds of failure in the last failed validation
+	pendingFix         *pendingFix           // Fix awaiting validation, to learn from if it works
+	modelsUsed         []string              // Track which models we've tried
+	reviewFailures     int                   // Count consecutive review failures (max 2 before showing code)
 
 	// Exit confirmation
 	ctrlCPressed bool      // True if Ctrl+C was pressed once
@@ -157,6 +209,8 @@ type Model struct {
 	workspaceIndex  *WorkspaceIndex  // Indexed codebase for context
 	vectorIndex     *VectorIndex     // Semantic search index with embeddings
 	llmGuard        *LLMGuardClient  // Optional LLM security scanner
+	redactor        *Redactor        // Masks secrets before they reach the provider
+	redactedSeen    map[string]bool  // Secrets already reported this session
 	validatorConfig *ValidatorConfig // Domain-specific validator settings
 
 	// For async operations
@@ -167,12 +221,53 @@ type Model struct {
 	width  int
 	height int
 
+	// Output pane for alt-screen mode (nil in scrollback mode)
+	pager *outputPager
+
+	// Syntax highlighting for displayed code (toggled with /highlight)
+	highlight bool
+
+	// Previous prompts for Up/Down recall (~/.bjarne/prompt_history)
+	history *promptHistory
+
+	// Prompt overrides from ~/.bjarne/prompts (reloaded with /prompts reload)
+	prompts *PromptSet
+
+	// Project rules from BJARNE.md at the workspace root
+	projectRules *ProjectRules
+
+	// Validator image in use and what selected it (/config image)
+	image ImageSelection
+
+	// Working digests per image for /image rollback, and the ref already recorded this session
+	imageHistory  *ImageHistory
+	imageRecorded string
+
+	// When the current state began, and the time in it last reported (plain mode)
+	stateSince        time.Time
+	progressAnnounced time.Duration
+
 	// Debug logging
-	debugMode    bool   // When true, log validation errors to file
-	debugLogPath string // Path to debug log file
 }
 
 // Messages for async operations
+type imageUpdateAvailableMsg struct {
+	ref string
+}
+
+type imageDigestMsg struct {
+	ref    string
+	digest string
+}
+
+type imagePullDoneMsg struct {
+	ref      string // Reference to use from now on
+	from     string // Digest before the pull
+	to       string // Digest after the pull
+	rollback bool
+	err      error
+}
+
 type classificationDoneMsg struct {
 	result *GenerateResult
 	err    error
@@ -183,6 +278,29 @@ type thinkingDoneMsg struct {
 	err    error
 }
 
+type dodQuestionsDoneMsg struct {
+	result *GenerateResult
+	err    error
+}
+
+type planDoneMsg struct {
+	result *GenerateResult
+	err    error
+}
+
+// scaffoldFileDoneMsg is sent when one planned file has been written
+type scaffoldFileDoneMsg struct {
+	file   PlannedFile
+	result *GenerateResult
+	err    error
+}
+
+// scaffoldCheckDoneMsg is sent when a scaffolded file's syntax check finishes
+type scaffoldCheckDoneMsg struct {
+	result ValidationResult
+	err    error
+}
+
 type generatingDoneMsg struct {
 	result *GenerateResult
 	err    error
@@ -193,9 +311,19 @@ type acknowledgeDoneMsg struct {
 	err    error
 }
 
+// vectorIndexLoadedMsg hands the index loaded in the background at startup to the TUI
+type vectorIndexLoadedMsg struct {
+	index *VectorIndex
+}
+
 type validationDoneMsg struct {
-	results []ValidationResult
-	err     error
+	results      []ValidationResult
+	formatted    []CodeFile // clang-format output for the final code (nil if not applied)
+	artifacts    []string   // Binaries exported to .bjarne/artifacts
+	artifactsErr error      // Why the binaries could not be exported
+	manifest     string     // Path of the validation manifest written for bjarne reproduce
+	manifestErr  error      // Why the manifest could not be written
+	err          error
 }
 
 type fixDoneMsg struct {
@@ -203,11 +331,22 @@ type fixDoneMsg struct {
 	err    error
 }
 
+type compactDoneMsg struct {
+	result   *GenerateResult
+	messages int  // Turns summarized
+	fix      bool // Continue with the pending fix afterwards
+	err      error
+}
+
 type reviewDoneMsg struct {
-	result     *GenerateResult
-	confidence int    // 0-100 confidence score
-	summary    string // One-line summary for user
-	err        error
+	result      *GenerateResult
+	confidence  int               // 0-100 confidence score
+	summary     string            // One-line summary for user
+	breakdown   string            // Each reviewer's score in consensus mode
+	checks      *FunctionalChecks // The reviewer's checks, in functional mode
+	divergences []string          // Where the code departs from the analysis
+	checkErr    error             // Why the consistency check could not run
+	err         error
 }
 
 type tickMsg time.Time
@@ -221,6 +360,23 @@ type codeRevealMsg struct {
 // codeRevealDoneMsg indicates code reveal animation is complete
 type codeRevealDoneMsg struct{}
 
+// bestOfDoneMsg is sent when all best-of-N candidates have been generated and validated
+type bestOfDoneMsg struct {
+	candidates []Candidate
+}
+
+// compareDoneMsg is sent when both /compare runs have finished
+type compareDoneMsg struct {
+	candidates []Candidate
+}
+
+// editDoneMsg is sent when the external editor opened by /edit exits
+type editDoneMsg struct {
+	dir   string
+	files []CodeFile // Code as it was before editing
+	err   error
+}
+
 // NewModel creates a new bubbletea model
 func NewModel(provider LLMProvider, container *ContainerRuntime, cfg *Config) Model {
 	// Create textarea for input
@@ -236,7 +392,7 @@ func NewModel(provider LLMProvider, container *ContainerRuntime, cfg *Config) Mo
 	ta.BlurredStyle.CursorLine = lipgloss.NewStyle()
 	ta.FocusedStyle.Prompt = lipgloss.NewStyle()
 	ta.BlurredStyle.Prompt = lipgloss.NewStyle()
-	ta.KeyMap.InsertNewline.SetEnabled(false) // Enter submits, Shift+Enter for newlines if needed
+	ta.KeyMap.InsertNewline.SetEnabled(false) // Enter submits, Alt+Enter inserts a newline
 
 	// Create spinner - simple ASCII
 	s := spinner.New()
@@ -245,17 +401,48 @@ func NewModel(provider LLMProvider, container *ContainerRuntime, cfg *Config) Mo
 		FPS:    time.Millisecond * 100,
 	}
 
+	var pager *outputPager
+	if cfg.Settings.Display.Mode == DisplayAltScreen && !plainMode {
+		pager = newOutputPager(120, 24-pagerReservedLines)
+	}
+
+	historyPath, _ := promptHistoryPath()
+	imageHistoryFile, _ := imageHistoryPath()
+	strategy, err := parseFixStrategy(cfg.Settings.Validation.Strategy)
+	if err != nil {
+		strategy = StrategyFix
+	}
+	reviewMode, err := parseConsensus(cfg.Settings.Review.Consensus)
+	if err != nil {
+		reviewMode = ConsensusOff
+	}
+
 	return Model{
+		pager:           pager,
+		highlight:       cfg.Settings.Display.Highlight && !colorDisabled(),
+		history:         loadPromptHistory(historyPath),
+		imageHistory:    loadImageHistory(imageHistoryFile),
+		fixKnowledge:    projectFixKnowledge(cfg.Settings.Validation),
+		bestOf:          bestOfCandidates(cfg.Settings.BestOf.Candidates),
+		strategy:        strategy,
+		regenAfter:      cfg.Settings.Validation.RegenerateAfter,
+		reviewMode:      reviewMode,
+		reviewMin:       cfg.Settings.Review.Threshold,
+		reviewChecks:    cfg.Settings.Review.Functional,
+		checkAnalysis:   cfg.Settings.Review.Consistency,
+		sessionID:       newSessionID(time.Now()),
 		textarea:        ta,
 		spinner:         s,
-		styles:          NewStyles(),
+		styles:          NewStyles(cfg.Theme),
 		state:           StateInput,
 		provider:        provider,
 		container:       container,
 		config:          cfg,
-		tokenTracker:    NewTokenTracker(cfg.MaxTotalTokens, cfg.WarnTokenThreshold),
+		tokenTracker:    sessionTokenTracker(cfg),
 		conversation:    []Message{},
-		llmGuard:        NewLLMGuardClient(),
+		llmGuard:        NewLLMGuardClient(cfg.Settings.Guard),
+		redactor:        NewRedactor(cfg.Settings.Redaction),
+		redactedSeen:    make(map[string]bool),
 		validatorConfig: DefaultValidatorConfig(),
 		ctx:             context.Background(),
 		width:           120, // Default, will be updated on WindowSizeMsg
@@ -264,10 +451,35 @@ func NewModel(provider LLMProvider, container *ContainerRuntime, cfg *Config) Mo
 }
 
 func (m Model) Init() tea.Cmd {
-	return tea.Batch(textarea.Blink, m.spinner.Tick)
+	cmds := []tea.Cmd{textarea.Blink, m.spinner.Tick}
+	if m.container != nil && m.config.Settings.Container.CheckUpdates && !offlineMode {
+		cmds = append(cmds, checkImageUpdate(m.container))
+	}
+	return tea.Batch(cmds...)
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	prev := m.state
+	next, cmd := m.update(msg)
+	if plainMode {
+		if nm, ok := next.(Model); ok && nm.state != prev {
+			nm.announceState()
+			return nm, cmd
+		}
+	}
+	return next, cmd
+}
+
+// announceState says what the session is doing now, for plain mode
+func (m *Model) announceState() {
+	m.stateSince = time.Now()
+	m.progressAnnounced = 0
+	if sentence, ok := stateSentences[m.state]; ok {
+		m.addOutput(sentence)
+	}
+}
+
+func (m Model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
 
 	switch msg := msg.(type) {
@@ -280,12 +492,34 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			inputWidth = 40
 		}
 		m.textarea.SetWidth(inputWidth)
+		if m.pager != nil {
+			m.pager.SetSize(msg.Width, m.pagerHeight())
+		}
 		return m, nil
 
 	case tea.KeyMsg:
 		// Reset Ctrl+C state on any other key press
 		if msg.Type != tea.KeyCtrlC {
 			m.ctrlCPressed = false
+
+			// Scrolling/search keys for the alt-screen output pane
+			if m.pager != nil && m.pager.HandleKey(msg) {
+				return m, nil
+			}
+		}
+
+		// Approval prompt takes single-key answers
+		if m.state == StateApproving && msg.Type != tea.KeyCtrlC && msg.Type != tea.KeyEsc {
+			return m.handleApprovalKey(msg)
+		}
+		if m.state == StateConfirmSave && msg.Type != tea.KeyCtrlC {
+			return m.handleSaveConfirmKey(msg)
+		}
+		if m.state == StateFillTemplate && ((msg.Type == tea.KeyEnter && !msg.Alt) || msg.Type == tea.KeyEsc) {
+			return m.handleTemplateKey(msg)
+		}
+		if m.state == StateSettings && msg.Type != tea.KeyCtrlC {
+			return m.handleSettingsKey(msg)
 		}
 
 		switch msg.Type {
@@ -313,9 +547,28 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 		case tea.KeyEnter:
+			// Alt+Enter inserts a newline for multi-line prompts
+			if msg.Alt && m.state == StateInput {
+				m.textarea.InsertString("\n")
+				return m, nil
+			}
 			if m.state == StateInput {
 				input := strings.TrimSpace(m.textarea.Value())
 				if input == "" {
+					if m.testsPending {
+						return m.confirmExampleTests()
+					}
+					if m.planPending {
+						return m.startScaffolding()
+					}
+					return m, nil
+				}
+				m.history.Add(input)
+
+				// Drag-and-drop pastes the image path
+				if path, ok := droppedImagePath(input); ok {
+					m.textarea.Reset()
+					m.attachImage(path)
 					return m, nil
 				}
 
@@ -335,13 +588,23 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 				m.textarea.Reset()
 				m.textarea.Blur()
+				m.turns.Push(m.snapshotTurn(input))
 
 				// If already analyzed, user response goes to acknowledgment then generation
 				if m.analyzed {
 					// Show what the user typed
 					m.addOutput("")
 					m.addOutput(m.styles.Prompt.Render("> ") + input)
-					m.conversation = append(m.conversation, Message{Role: "user", Content: input})
+					if m.planPending {
+						// New input may change the design; plan again from the updated conversation
+						m.planPending = false
+						m.plan = nil
+					}
+					if m.awaitingDoD {
+						m.awaitingDoD = false
+						m.applyDefinitionOfDone(input)
+					}
+					m.conversation = append(m.conversation, m.userMessage(m.attachFiles(input)))
 					return m.startAcknowledging()
 				}
 
@@ -349,10 +612,22 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m.startClassifying(input)
 			}
 			return m, nil
+
+		case tea.KeyTab:
+			if m.state == StateInput {
+				m.completeInput()
+				return m, nil
+			}
+
+		case tea.KeyUp, tea.KeyDown:
+			// Recall previous prompts when the cursor is on the first/last line
+			if m.state == StateInput && m.recallHistory(msg.Type == tea.KeyUp) {
+				return m, nil
+			}
 		}
 
 		// Handle input in input state
-		if m.state == StateInput {
+		if m.state == StateInput || m.state == StateFillTemplate {
 			var cmd tea.Cmd
 			m.textarea, cmd = m.textarea.Update(msg)
 			cmds = append(cmds, cmd)
@@ -375,7 +650,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 		// Parse the classification result (INTENT COMPLEXITY) - internal use only
-		m.tokenTracker.Add(msg.result.InputTokens, msg.result.OutputTokens)
+		m.addUsage(msg.result)
 		classification := strings.TrimSpace(strings.ToUpper(msg.result.Text))
 		parts := strings.Fields(classification)
 
@@ -421,11 +696,13 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.textarea.Focus()
 			return m, nil
 		}
-		m.tokenTracker.Add(msg.result.InputTokens, msg.result.OutputTokens)
+		m.addUsage(msg.result)
 		m.conversation = append(m.conversation, Message{Role: "assistant", Content: msg.result.Text})
 
 		// Parse and clean the response (remove difficulty tag if present)
 		_, reflection := parseDifficulty(msg.result.Text)
+		needsDoD := m.dod == nil || !m.dod.hasCriteria()
+		expected, reflection := parseExpectedOutput(reflection)
 		cleanText := stripMarkdown(reflection)
 
 		// Handle based on intent
@@ -453,6 +730,10 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, textarea.Blink
 		}
 
+		// Remember what was promised, to check the code against it after generation
+		m.analysis = reflection
+		m.expectOutput(expected)
+
 		// Auto-proceed for EASY tasks or CONTINUE intent (no questions)
 		if (m.difficulty == "EASY" || m.intent == "CONTINUE") && !containsQuestion(reflection) {
 			m.conversation = append(m.conversation, Message{Role: "user", Content: GenerateNowPrompt})
@@ -467,11 +748,43 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		m.addOutput("")
 
+		// New COMPLEX tasks also collect a Definition of Done before generating
+		if m.difficulty == "COMPLEX" && m.intent == "NEW" && needsDoD {
+			return m.startDefiningDone()
+		}
+
 		m.analyzed = true // Next input goes to acknowledgment
 		m.state = StateInput
 		m.textarea.Focus()
 		return m, textarea.Blink
 
+	case dodQuestionsDoneMsg:
+		if msg.err != nil {
+			if m.ctx.Err() == context.Canceled {
+				return m, nil
+			}
+			// Not fatal - the analysis has been shown, continue without a DoD
+			m.addOutput(m.styles.Dim.Render("Skipping Definition of Done: " + msg.err.Error()))
+		} else {
+			m.addUsage(msg.result)
+
+			// Fold the questions into the analysis turn to keep user/assistant alternation
+			if n := len(m.conversation); n > 0 && m.conversation[n-1].Role == "assistant" {
+				m.conversation[n-1].Content += "\n\n" + msg.result.Text
+			}
+
+			for _, line := range wrapText(stripMarkdown(msg.result.Text), 76) {
+				m.addOutput(line)
+			}
+			m.addOutput("")
+			m.awaitingDoD = true
+		}
+
+		m.analyzed = true
+		m.state = StateInput
+		m.textarea.Focus()
+		return m, textarea.Blink
+
 	case acknowledgeDoneMsg:
 		if msg.err != nil {
 			if m.ctx.Err() == context.Canceled {
@@ -482,7 +795,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.textarea.Focus()
 			return m, nil
 		}
-		m.tokenTracker.Add(msg.result.InputTokens, msg.result.OutputTokens)
+		m.addUsage(msg.result)
 		m.conversation = append(m.conversation, Message{Role: "assistant", Content: msg.result.Text})
 
 		// Check if acknowledgment already contains code (LLM jumped ahead)
@@ -501,10 +814,12 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			m.currentFiles = files
 			m.currentCode = code
+			m.enforceNamingConventions()
 			return m.startValidation()
 		}
 
 		// Show acknowledgment (no code - need to generate)
+		m.analysis += "\n\n" + msg.result.Text
 		m.addOutput("")
 		m.addOutput(m.styles.Info.Render("bjarne: ") + stripMarkdown(msg.result.Text))
 
@@ -512,6 +827,92 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.conversation = append(m.conversation, Message{Role: "user", Content: GenerateNowPrompt})
 		return m.startGenerating()
 
+	case planDoneMsg:
+		if msg.err != nil {
+			if m.ctx.Err() == context.Canceled {
+				return m, nil
+			}
+			m.addOutput(m.styles.Dim.Render("Planning failed (" + msg.err.Error() + "); generating in one pass."))
+			m.planSkipped = true
+			return m.startGenerating()
+		}
+		m.addUsage(msg.result)
+
+		plan := ParseProjectPlan(msg.result.Text)
+		if plan == nil || len(plan.Files) < 2 {
+			// Not worth scaffolding - generate in one pass
+			m.planSkipped = true
+			return m.startGenerating()
+		}
+
+		// Hold generation until the plan is confirmed; the generate request is re-sent by scaffolding
+		if n := len(m.conversation); n > 0 && m.conversation[n-1].Role == "user" && m.conversation[n-1].Content == GenerateNowPrompt {
+			m.conversation = m.conversation[:n-1]
+		}
+		m.plan = plan
+		m.planPending = true
+		m.addOutput("")
+		m.addOutput(m.styles.Info.Render("Project plan:"))
+		m.showPlan()
+		m.addOutput(m.styles.Dim.Render("  Enter to write the files one at a time · /plan add|rm|deps|purpose to adjust · /plan off for one pass"))
+		m.state = StateInput
+		m.textarea.Focus()
+		return m, textarea.Blink
+
+	case scaffoldFileDoneMsg:
+		if msg.err != nil {
+			if m.ctx.Err() == context.Canceled {
+				return m, nil
+			}
+			m.addOutput(m.styles.Error.Render("Generation failed: " + msg.err.Error()))
+			m.stopScaffolding()
+			return m, textarea.Blink
+		}
+		m.addUsage(msg.result)
+
+		file, ok := scaffoldedFile(msg.result.Text, msg.file.Path)
+		if !ok {
+			m.addOutput(m.styles.Warning.Render("No code for " + msg.file.Path + " in the response."))
+			m.stopScaffolding()
+			return m, textarea.Blink
+		}
+		m.currentFiles = append(m.currentFiles, file)
+		m.currentCode = joinCodeFiles(m.currentFiles)
+
+		if !isSourceFile(file.Filename) && !isHeaderFile(file.Filename) {
+			m.addOutput(fmt.Sprintf("  - %s", file.Filename))
+			m.scaffoldQueue = m.scaffoldQueue[1:]
+			m.scaffoldTries = 0
+			return m.scaffoldNext()
+		}
+		return m.startScaffoldCheck(file.Filename)
+
+	case scaffoldCheckDoneMsg:
+		current := m.scaffoldQueue[0]
+		if msg.err != nil {
+			if m.ctx.Err() == context.Canceled {
+				return m, nil
+			}
+			// Checks are advisory; the full gates still run on the finished project
+			m.addOutput(fmt.Sprintf("  %s %s: syntax check unavailable: %s", m.styles.Warning.Render("?"), current.Path, msg.err.Error()))
+		} else if !msg.result.Success {
+			if m.scaffoldTries < maxScaffoldRetries {
+				m.scaffoldTries++
+				m.addOutput(fmt.Sprintf("  %s %s does not compile, rewriting…", m.styles.Warning.Render("✗"), current.Path))
+				errs := FormatErrorForLLM(msg.result.Stage, msg.result.Error, m.currentFiles)
+				attempt := m.currentFiles[len(m.currentFiles)-1]
+				m.currentFiles = m.currentFiles[:len(m.currentFiles)-1]
+				m.currentCode = joinCodeFiles(m.currentFiles)
+				return m.scaffoldFile(current, &attempt, errs)
+			}
+			m.addOutput(fmt.Sprintf("  %s %s still does not compile; leaving it to the fix loop", m.styles.Error.Render("✗"), current.Path))
+		} else {
+			m.addOutput(fmt.Sprintf("  %s %s", m.styles.Success.Render("✓"), current.Path))
+		}
+		m.scaffoldQueue = m.scaffoldQueue[1:]
+		m.scaffoldTries = 0
+		return m.scaffoldNext()
+
 	case generatingDoneMsg:
 		if msg.err != nil {
 			if m.ctx.Err() == context.Canceled {
@@ -522,13 +923,19 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.textarea.Focus()
 			return m, nil
 		}
-		m.tokenTracker.Add(msg.result.InputTokens, msg.result.OutputTokens)
+		m.addUsage(msg.result)
 		m.conversation = append(m.conversation, Message{Role: "assistant", Content: msg.result.Text})
 
 		// LLM Guard: Scan generated output for embedded secrets
 		if m.llmGuard != nil && m.llmGuard.IsEnabled() {
 			scanResult, err := m.llmGuard.ScanOutput(msg.result.Text)
-			if err != nil {
+			if err != nil && m.llmGuard.FailClosed() {
+				m.addOutput(m.styles.Error.Render("Output security scan unavailable, generation discarded (guard.failurePolicy is closed): ") + err.Error())
+				m.conversation = m.conversation[:len(m.conversation)-1]
+				m.state = StateInput
+				m.textarea.Focus()
+				return m, nil
+			} else if err != nil {
 				m.addOutput(m.styles.Warning.Render("Output security scan unavailable: ") + err.Error())
 			} else if !scanResult.IsValid {
 				m.addOutput("")
@@ -568,14 +975,57 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 
+		m.enforceNamingConventions()
 		return m.startValidation()
 
+	case imageUpdateAvailableMsg:
+		m.addOutput("")
+		m.addOutput(m.styles.Warning.Render("A newer validator image is available: " + msg.ref))
+		m.addOutput(m.styles.Dim.Render("  /image update to pull it · /image rollback returns to the current one if validation regresses"))
+		return m, nil
+
+	case imageDigestMsg:
+		if msg.digest != "" {
+			m.imageRecorded = msg.ref
+			if err := m.imageHistory.MarkWorking(msg.ref, msg.digest); err != nil {
+				tuiLog.Warn("failed to record working image", "ref", msg.ref, "err", err)
+			}
+		}
+		return m, nil
+
+	case imagePullDoneMsg:
+		return m.finishImagePull(msg)
+
+	case vectorIndexLoadedMsg:
+		if m.vectorIndex != nil {
+			_ = msg.index.Close() // /index already built one
+			return m, nil
+		}
+		m.vectorIndex = msg.index
+		return m, nil
+
+	case compactDoneMsg:
+		return m.finishCompaction(msg)
+
+	case gateProgressMsg:
+		// Events from a cancelled or earlier run are still read so its stream can finish
+		if msg.progress == m.gates && m.state == StateValidating {
+			m.gates.update(msg, time.Now())
+			if plainMode {
+				m.addOutput(gateSentence(msg.stage, msg.result))
+			}
+		}
+		return m, waitForValidation(msg.events)
+
 	case validationDoneMsg:
+		manualEdit := m.manualEdit
+		m.manualEdit = false
+
 		if msg.err != nil {
 			if m.ctx.Err() == context.Canceled {
 				return m, nil
 			}
-			m.debugLog("Validation system error: %s", msg.err.Error())
+			containerLog.Error("validation failed to run", "err", msg.err)
 			m.addOutput(m.styles.Error.Render("Validation error: " + msg.err.Error()))
 			m.state = StateInput
 			m.textarea.Focus()
@@ -583,8 +1033,12 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 		// Log all validation results to debug file
-		m.debugLogValidationResults(msg.results)
+		m.logValidationResults(msg.results)
 
+		m.learnFromFix(msg.results)
+
+		m.lastResults = msg.results
+		m.recordVersion(msg.results)
 		allPassed := true
 		var failedErrors []string
 		for _, r := range msg.results {
@@ -592,28 +1046,53 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				allPassed = false
 				if r.Error != "" {
 					// Use parsed, compact format for LLM instead of raw stderr
-					failedErrors = append(failedErrors, FormatErrorForLLM(r.Stage, r.Error))
+					failedErrors = append(failedErrors, FormatErrorForLLM(r.Stage, r.Error, m.currentCodeFiles()))
 				}
 			}
 		}
 
 		if allPassed {
+			m.failedResults = nil
+			m.setCodeFiles(msg.formatted)
+			m.showRunOutput(msg.results)
+			m.showArtifacts(msg.artifacts, msg.artifactsErr)
+			m.showManifest(msg.manifest, msg.manifestErr)
+			if manualEdit {
+				// Hand-edited code skips the LLM review
+				m.lastConfidence = 100
+				m.lastSummary = "Manually edited; LLM review skipped."
+				return m.presentValidatedCode()
+			}
 			// All sanitizer gates passed - now do LLM code review
-			return m.startReviewing(msg.results)
+			next, cmd := m.startReviewing(msg.results)
+			return next, tea.Batch(cmd, next.recordWorkingImage())
 		}
 
 		// Validation failed - check if escalation is enabled and we can retry
 		m.lastValidationErrs = strings.Join(failedErrors, "\n")
+		m.failureSigs = failureSignatures(msg.results)
+		m.failures.Add(m.failureSigs)
+		m.failedResults = msg.results
 
-		canRetry := m.config.EscalateOnFailure && m.canEscalate()
+		// Hand-edited code is not sent back to the LLM for fixing
+		canRetry := m.config.EscalateOnFailure && m.canEscalate() && !manualEdit
 		m.showValidationFailure(msg.results, !canRetry) // isFinal = !canRetry
 
 		if canRetry {
 			return m.startFix()
 		}
 
-		// No more escalation possible
-		m.showEscalationExhausted()
+		if manualEdit {
+			m.notify(NotifyValidationDone, "Validation failed")
+			m.addOutput("")
+			m.addOutput(m.styles.Dim.Render("Use /edit to fix the issues, or ask bjarne to fix them."))
+		} else {
+			// No more escalation possible
+			m.showEscalationExhausted()
+		}
+		if len(m.suppressionCandidates("")) > 0 {
+			m.addOutput(m.styles.Dim.Render("A static-analysis finding is acceptable? /suppress lists the ones you can accept."))
+		}
 		m.resetEscalation()
 		m.state = StateInput
 		m.textarea.Focus()
@@ -629,8 +1108,13 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.textarea.Focus()
 			return m, nil
 		}
-		m.tokenTracker.Add(msg.result.InputTokens, msg.result.OutputTokens)
+		m.addUsage(msg.result)
 		m.conversation = append(m.conversation, Message{Role: "assistant", Content: msg.result.Text})
+		m.pendingFix = &pendingFix{failures: m.failureSigs, response: msg.result.Text, before: m.currentCode}
+
+		if len(m.currentFiles) > 1 {
+			return m.applyMultiFileFix(msg.result.Text)
+		}
 
 		code := extractCode(msg.result.Text)
 		if code == "" {
@@ -646,6 +1130,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 		m.currentCode = code
+		m.enforceNamingConventions()
 		return m.startValidation()
 
 	case reviewDoneMsg:
@@ -657,25 +1142,31 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.addOutput(m.styles.Warning.Render("Code review unavailable: " + msg.err.Error()))
 			m.lastConfidence = 80 // Reasonable default
 			m.lastSummary = "Sanitizers passed; review unavailable."
-			return m.showValidatedCode()
+			return m.presentValidatedCode()
 		}
 
 		// Store confidence and summary for display
 		m.lastConfidence = msg.confidence
 		m.lastSummary = msg.summary
 
-		// Confidence-based decision:
-		// >= 70: Accept the code (user can decide based on summary)
-		// < 70: Try to improve if we can, otherwise show anyway
-		if msg.confidence >= 70 {
-			m.addOutput(m.styles.Success.Render(fmt.Sprintf("  └─ Gate: review... %d%% confidence", msg.confidence)))
+		// Confidence-based decision against the review threshold (70 by default):
+		// at or above: Accept the code (user can decide based on summary)
+		// below: Try to improve if we can, otherwise show anyway
+		m.showFunctionalChecks(msg.checks)
+		m.showDivergences(msg.divergences, msg.checkErr)
+		gateLine := fmt.Sprintf("  %s Gate: review... %d%% confidence", treeEnd, msg.confidence)
+		if msg.breakdown != "" {
+			gateLine += " (" + msg.breakdown + ")"
+		}
+		if msg.confidence >= m.reviewThreshold() {
+			m.addOutput(m.styles.Success.Render(gateLine))
 			m.reviewFailures = 0
-			return m.showValidatedCode()
+			return m.presentValidatedCode()
 		}
 
 		// Low confidence - try to fix if possible
 		m.reviewFailures++
-		m.addOutput(m.styles.Warning.Render(fmt.Sprintf("  └─ Gate: review... %d%% confidence", msg.confidence)))
+		m.addOutput(m.styles.Warning.Render(gateLine))
 		m.addOutput(m.styles.Dim.Render("     " + msg.summary))
 
 		// Limit review retries to 2 - don't loop forever on pedantic reviews
@@ -683,7 +1174,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.addOutput("")
 			m.addOutput(m.styles.Warning.Render("Review confidence remains low but sanitizers pass."))
 			m.addOutput(m.styles.Dim.Render("(Showing code - review the summary and decide if changes are needed)"))
-			return m.showValidatedCode()
+			return m.presentValidatedCode()
 		}
 
 		m.lastValidationErrs = "Code review (" + fmt.Sprintf("%d%%", msg.confidence) + "): " + msg.summary
@@ -698,14 +1189,90 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Can't escalate - show code with low confidence (user decides)
 		m.addOutput("")
 		m.addOutput(m.styles.Warning.Render("Code passed sanitizers. Review the summary below."))
-		return m.showValidatedCode()
+		return m.presentValidatedCode()
 
 	case tickMsg:
-		// Update elapsed time display
+		// Update elapsed time display (plain mode reports progress in sentences instead)
+		if elapsed := time.Since(m.stateSince); plainMode && reportsProgress(m.state) && elapsed-m.progressAnnounced >= plainProgressInterval {
+			m.progressAnnounced = elapsed.Truncate(plainProgressInterval)
+			sentence := progressSentence(m.state, m.progressAnnounced)
+			if m.state == StateValidating && m.gates != nil {
+				if g := m.gates.running(); g != nil {
+					sentence = fmt.Sprintf("Still running the %s gate (%d seconds).", g.Stage, int(g.elapsed(time.Now()).Seconds()))
+				}
+			}
+			m.addOutput(sentence)
+		}
 		return m, tea.Tick(time.Second, func(t time.Time) tea.Msg {
 			return tickMsg(t)
 		})
 
+	case editDoneMsg:
+		return m.finishEdit(msg)
+
+	case compareDoneMsg:
+		if m.ctx.Err() == context.Canceled {
+			return m, nil
+		}
+		a, b := msg.candidates[0], msg.candidates[1]
+		m.trackTokens(a.InputTokens+b.InputTokens, a.OutputTokens+b.OutputTokens)
+
+		m.addOutput("")
+		m.addOutput(m.styles.Info.Render("Model comparison:"))
+		for _, line := range formatComparison(a, b) {
+			m.addOutput(line)
+		}
+
+		if a.Code != "" && b.Code != "" {
+			if diff := UnifiedDiff(shortModelName(a.Model), shortModelName(b.Model), a.Code, b.Code, 3); diff != "" {
+				m.addOutput("")
+				m.addOutput(m.renderCode(strings.TrimRight(diff, "\n"), "diff"))
+			} else {
+				m.addOutput(m.styles.Dim.Render("  Both models produced identical code."))
+			}
+		}
+		m.addOutput("")
+		m.state = StateInput
+		m.textarea.Focus()
+		return m, textarea.Blink
+
+	case bestOfDoneMsg:
+		if m.ctx.Err() == context.Canceled {
+			return m, nil
+		}
+		for _, c := range msg.candidates {
+			m.trackTokens(c.InputTokens, c.OutputTokens)
+		}
+
+		ranked := rankCandidates(msg.candidates)
+		m.addOutput("")
+		m.addOutput(m.styles.Info.Render(fmt.Sprintf("Compared %d candidates:", len(ranked))))
+		for _, line := range formatCandidateTable(ranked) {
+			m.addOutput(line)
+		}
+
+		best := ranked[0]
+		if best.Err != nil {
+			m.addOutput(m.styles.Error.Render("No candidate produced usable code: " + best.Err.Error()))
+			m.state = StateInput
+			m.textarea.Focus()
+			return m, nil
+		}
+
+		// Continue the normal pipeline with the winner
+		m.conversation = append(m.conversation, Message{Role: "assistant", Content: best.Text})
+		m.currentFiles = best.Files
+		m.currentCode = best.Code
+		m.codeModel = best.Model
+		if !best.Passed() {
+			return m.Update(validationDoneMsg{results: best.Results})
+		}
+		m.setCodeFiles(best.Formatted)
+		m.lastResults = best.Results
+		m.recordVersion(best.Results)
+		m.showValidationSuccess(best.Results)
+		return m.Update(reviewDoneMsg{confidence: best.Confidence, summary: best.Summary, err: best.ReviewErr})
+
 	case codeRevealMsg:
 		// Reveal next line of code
 		if msg.currentLine < len(msg.lines) {
@@ -749,14 +1316,31 @@ func (m Model) View() string {
 		if m.hasUnsavedCode() {
 			b.WriteString(m.styles.Warning.Render("[*] "))
 		}
+		if m.modelOverride != "" {
+			b.WriteString(m.styles.Dim.Render("[" + m.modelOverride + "] "))
+		}
 		b.WriteString(m.styles.Prompt.Render(">") + " ")
 		b.WriteString(m.textarea.View())
 
-	case StateClassifying, StateThinking, StateAcknowledging, StateGenerating, StateValidating, StateFixing, StateReviewing:
+	case StateClassifying, StateThinking, StateDefiningDone, StateAcknowledging, StatePlanning, StateGenerating, StateValidating, StateFixing, StateReviewing, StatePullingImage, StateCompacting:
+		if plainMode {
+			// A line that never changes: the state and its progress are announced as output
+			b.WriteString("Working. Press Escape to interrupt.")
+			break
+		}
 		// Claude Code-style status: * Doing something… (esc to interrupt · 3s)
 		elapsed := time.Since(m.startTime).Seconds()
 		status := fmt.Sprintf("esc to interrupt · %.0fs", elapsed)
+		if queued := queuedRequests(); queued > 0 {
+			status = fmt.Sprintf("queued: %d waiting for rate limit · %s", queued, status)
+		}
+		if m.modelOverride != "" {
+			status = m.modelOverride + " · " + status
+		}
 
+		if m.state == StateValidating && m.gates != nil {
+			b.WriteString(m.gateProgressView())
+		}
 		b.WriteString(m.styles.Accent.Render("* "))
 		b.WriteString(m.statusMsg)
 		b.WriteString(m.styles.Dim.Render(" (" + status + ")"))
@@ -764,6 +1348,39 @@ func (m Model) View() string {
 	case StateRevealing:
 		// Don't show progress - the scrolling code is visual feedback
 		b.WriteString("")
+
+	case StateApproving:
+		b.WriteString(m.styles.Accent.Render("? "))
+		b.WriteString(fmt.Sprintf("%s / %s / %s ",
+			m.styles.Success.Render("[a]pprove"),
+			m.styles.Warning.Render("[r]egenerate"),
+			m.styles.Info.Render("[e]dit prompt")))
+		b.WriteString(m.styles.Dim.Render("(esc to discard)"))
+
+	case StateConfirmSave:
+		b.WriteString(m.styles.Accent.Render("? "))
+		b.WriteString(fmt.Sprintf("Overwrite %d file(s)? %s / %s",
+			m.pendingSave.overwrites(),
+			m.styles.Warning.Render("[y]es"),
+			m.styles.Info.Render("[n]o")))
+
+	case StateFillTemplate:
+		p := m.templateFill.current()
+		b.WriteString(m.styles.Accent.Render("? ") + p.Name)
+		if p.Default != "" {
+			b.WriteString(m.styles.Dim.Render(" [" + p.Default + "]"))
+		}
+		b.WriteString(": ")
+		b.WriteString(m.textarea.View())
+
+	case StateSettings:
+		b.WriteString(m.settingsFormView())
+	}
+
+	// Alt-screen mode: output pane above the input/status line
+	if m.pager != nil {
+		footer := m.styles.Dim.Render(m.pager.StatusLine())
+		return m.pager.View() + "\n" + footer + "\n" + b.String()
 	}
 
 	return b.String()
@@ -772,83 +1389,118 @@ func (m Model) View() string {
 // Helper methods
 
 func (m *Model) addOutput(line string) {
+	if m.pager != nil {
+		m.pager.Append(line)
+		return
+	}
 	// Print directly to stdout for permanent history (scrollback)
 	fmt.Println(line)
 }
 
-// debugLog writes a message to the debug log file if debug mode is enabled
-func (m *Model) debugLog(format string, args ...interface{}) {
-	if !m.debugMode || m.debugLogPath == "" {
+// logValidationResults logs the validated code and each gate's result (subsystem tui)
+// The code and gate output are logged at debug level, so they are only written with /debug or --log-level debug
+func (m *Model) logValidationResults(results []ValidationResult) {
+	ctx := context.Background()
+	if !tuiLog.Enabled(ctx, slog.LevelDebug) {
 		return
 	}
+	tuiLog.Debug("validation results", "prompt", m.originalPrompt, "difficulty", m.difficulty, "intent", m.intent)
+	if len(m.currentFiles) > 1 {
+		for _, f := range m.currentFiles {
+			tuiLog.Debug("validated file", "file", f.Filename, "content", f.Content)
+		}
+	} else if m.currentCode != "" {
+		tuiLog.Debug("validated file", "file", "code.cpp", "content", m.currentCode)
+	}
+	for _, r := range results {
+		tuiLog.Debug("gate", "stage", r.Stage, "success", r.Success, "duration", r.Duration.Round(time.Millisecond), "output", r.Output, "error", r.Error)
+	}
+}
 
-	// Open file in append mode
-	f, err := os.OpenFile(m.debugLogPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+// attachFiles inlines files mentioned as @path in the prompt and reports what was attached
+func (m *Model) attachFiles(prompt string) string {
+	cwd, err := os.Getwd()
 	if err != nil {
-		return
+		return prompt
+	}
+	content, attached, errs := ExpandAttachments(prompt, cwd)
+	for _, a := range attached {
+		note := fmt.Sprintf("  Attached %s (%d lines)", a.Path, a.Lines)
+		if a.Truncated {
+			note += " [truncated]"
+		}
+		m.addOutput(m.styles.Dim.Render(note))
 	}
-	defer func() { _ = f.Close() }()
+	for _, e := range errs {
+		m.addOutput(m.styles.Warning.Render("  " + e.Error()))
+	}
+	return content
+}
 
-	// Write timestamp and message
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
-	msg := fmt.Sprintf(format, args...)
-	_, _ = fmt.Fprintf(f, "[%s] %s\n", timestamp, msg)
+// redact masks secrets in text bound for the provider and reports newly masked ones
+func (m *Model) redact(text, source string) string {
+	masked, found := m.redactor.Redact(text)
+	var fresh []RedactedSecret
+	for _, f := range found {
+		if !m.redactedSeen[f.Value] {
+			m.redactedSeen[f.Value] = true
+			fresh = append(fresh, f)
+		}
+	}
+	if len(fresh) > 0 {
+		m.addOutput(m.styles.Warning.Render(fmt.Sprintf("  Masked %d secret(s) in %s before sending: %s", len(fresh), source, summarizeRedactions(fresh))))
+	}
+	return masked
 }
 
-// debugLogValidationResults logs detailed validation results to the debug file
-func (m *Model) debugLogValidationResults(results []ValidationResult) {
-	if !m.debugMode {
+// attachImage queues an image to be sent with the next prompt
+func (m *Model) attachImage(path string) {
+	if !providerSupportsImages(m.provider) {
+		m.addOutput(m.styles.Error.Render(fmt.Sprintf("%s does not support image input", m.provider.Name())))
 		return
 	}
-
-	m.debugLog("=== Validation Results ===")
-	m.debugLog("Original prompt: %s", m.originalPrompt)
-	m.debugLog("Difficulty: %s, Intent: %s", m.difficulty, m.intent)
-	m.debugLog("")
-
-	// Log the generated code
-	if len(m.currentFiles) > 1 {
-		for _, f := range m.currentFiles {
-			m.debugLog("--- File: %s ---", f.Filename)
-			m.debugLog("%s", f.Content)
-		}
-	} else if m.currentCode != "" {
-		m.debugLog("--- Generated Code ---")
-		m.debugLog("%s", m.currentCode)
+	img, err := LoadImage(path)
+	if err != nil {
+		m.addOutput(m.styles.Error.Render("Error: " + err.Error()))
+		return
 	}
-	m.debugLog("")
+	m.pendingImages = append(m.pendingImages, img)
+	m.addOutput(m.styles.Success.Render(fmt.Sprintf("Attached %s (%s, %d KB)", img.Name, img.MediaType, len(img.Data)/1024)))
+	m.addOutput(m.styles.Dim.Render("  It will be sent with your next prompt."))
+}
 
-	// Log each validation result
-	for _, r := range results {
-		status := "PASS"
-		if !r.Success {
-			status = "FAIL"
-		}
-		m.debugLog("[%s] Stage: %s (%.2fs)", status, r.Stage, r.Duration.Seconds())
-		if r.Output != "" {
-			m.debugLog("  Output: %s", r.Output)
-		}
-		if r.Error != "" {
-			m.debugLog("  Error: %s", r.Error)
-		}
-	}
-	m.debugLog("=== End Validation Results ===")
-	m.debugLog("")
+// userMessage builds a user message, including any images queued with /image
+func (m *Model) userMessage(content string) Message {
+	msg := Message{Role: "user", Content: content, Images: m.pendingImages}
+	m.pendingImages = nil
+	return msg
 }
 
 func (m *Model) startClassifying(prompt string) (Model, tea.Cmd) {
 	m.state = StateClassifying
 	m.statusMsg = "Thinking…"
 	m.startTime = time.Now()
+	m.taskStart = m.startTime
 	m.tokenCount = 0
+	m.testsPending = false
+	m.pendingPrompt = ""
+
+	// Mask secrets before anything is sent, including files inlined from @mentions
+	prompt = m.redact(prompt, "your prompt")
+	content := m.redact(m.attachFiles(prompt), "attached files")
 
 	// LLM Guard: Scan prompt for security issues (prompt injection, secrets, toxicity)
 	if m.llmGuard != nil && m.llmGuard.IsEnabled() {
-		scanResult, err := m.llmGuard.ScanPrompt(prompt)
-		if err != nil {
+		scanResult, err := m.llmGuard.ScanPrompt(content)
+		if err != nil && m.llmGuard.FailClosed() {
 			m.addOutput("")
-			m.addOutput(m.styles.Warning.Render("Security scan unavailable: ") + err.Error())
-		} else if !scanResult.IsValid {
+			m.addOutput(m.styles.Error.Render("Security scan unavailable, request blocked (guard.failurePolicy is closed): ") + err.Error())
+			m.state = StateInput
+			return *m, nil
+		} else if err != nil {
+			m.addOutput("")
+			m.addOutput(m.styles.Warning.Render("Security scan unavailable: ") + err.Error())
+		} else if !scanResult.IsValid {
 			// Prompt failed security scan - reject it
 			m.addOutput("")
 			m.addOutput(m.styles.Error.Render("Security scan blocked this request:"))
@@ -862,10 +1514,57 @@ func (m *Model) startClassifying(prompt string) (Model, tea.Cmd) {
 
 	// Store original prompt and parse example tests
 	m.originalPrompt = prompt
+	m.analysis = ""
 	m.examples = ParseExampleTests(prompt)
+	input := parseRunInput(prompt)
+	if input == nil && m.dod != nil {
+		input = m.dod.Input // Set with /stdin; kept for the next request
+	}
+	m.dod = nil
+	if input != nil {
+		m.dod = &DefinitionOfDone{Input: input}
+	}
+	m.awaitingDoD = false
+	m.plan = nil
+	m.planPending = false
+	m.planSkipped = false
+
+	// Let the user check parsed examples before anything is generated
+	if m.examples != nil && len(m.examples.Tests) > 0 {
+		m.pendingPrompt = content
+		m.testsPending = true
+		m.state = StateInput
+		m.textarea.Focus()
+		m.addOutput("")
+		m.addOutput(m.styles.Info.Render("Example tests parsed from your request:"))
+		m.showExampleTests()
+		m.addOutput(m.styles.Dim.Render("  Enter to continue · /tests add|set|rm|clear to edit"))
+		return *m, textarea.Blink
+	}
+
+	return m.classify(content)
+}
+
+// confirmExampleTests continues with the request held while its examples were reviewed
+func (m *Model) confirmExampleTests() (Model, tea.Cmd) {
+	content := m.pendingPrompt
+	m.testsPending = false
+	m.pendingPrompt = ""
+	m.textarea.Blur()
+	if m.examples != nil && len(m.examples.Tests) == 0 {
+		m.examples = nil
+	}
+	return m.classify(content)
+}
+
+// classify sends the request for intent/complexity classification
+func (m *Model) classify(content string) (Model, tea.Cmd) {
+	m.state = StateClassifying
+	m.statusMsg = "Thinking…"
+	m.startTime = time.Now()
 
 	// Add user message to conversation
-	m.conversation = append(m.conversation, Message{Role: "user", Content: prompt})
+	m.conversation = append(m.conversation, m.userMessage(content))
 
 	// Create cancelable context
 	ctx, cancel := context.WithCancel(context.Background())
@@ -887,18 +1586,46 @@ func (m *Model) doClassification(ctx context.Context) tea.Cmd {
 	}
 }
 
-// getModelForComplexity returns the appropriate model based on task complexity
+// getModelForComplexity returns the appropriate model based on task complexity (settings: models.complexity)
+// A model pinned with /model takes precedence
 func (m *Model) getModelForComplexity(difficulty string) string {
+	if m.modelOverride != "" {
+		return m.resolveModel(m.modelOverride)
+	}
+
+	var model string
 	switch difficulty {
 	case "EASY":
-		return "global.anthropic.claude-haiku-4-5-20251001-v1:0"
-	case "MEDIUM":
-		return "global.anthropic.claude-sonnet-4-5-20250929-v1:0"
+		model = m.config.ComplexityModels.Easy
 	case "COMPLEX":
-		return m.config.OracleModel // Opus
-	default:
-		return "global.anthropic.claude-sonnet-4-5-20250929-v1:0" // Default to Sonnet
+		model = m.config.ComplexityModels.Complex
+		if model == "" {
+			model = m.config.OracleModel
+		}
+	default: // MEDIUM and unclassified
+		model = m.config.ComplexityModels.Medium
+	}
+	if model == "" {
+		model = m.config.GenerateModel
+	}
+	return m.resolveModel(model)
+}
+
+// resolveModel maps canonical names (haiku/sonnet/opus) to the provider's model ID
+func (m *Model) resolveModel(model string) string {
+	if IsCanonicalModel(model) && m.provider != nil {
+		return m.provider.MapModel(model)
+	}
+	return model
+}
+
+// escalationLadder builds the fix ladder for the current complexity from settings
+func (m *Model) escalationLadder() []escalationStep {
+	escalation := make([]string, len(m.config.EscalationModels))
+	for i, model := range m.config.EscalationModels {
+		escalation[i] = m.resolveModel(model)
 	}
+	return buildEscalationLadder(m.getModelForComplexity(m.difficulty), escalation, m.config.EscalationAttempts)
 }
 
 func (m *Model) startThinking(model string) (Model, tea.Cmd) {
@@ -925,14 +1652,15 @@ func (m *Model) doThinking(ctx context.Context, model string, intent string) tea
 		if intent == "QUESTION" {
 			systemPrompt = QuestionSystemPrompt
 		}
-		result, err := m.provider.Generate(ctx, model, systemPrompt, m.conversation, m.config.MaxTokens)
+		result, err := m.provider.Generate(ctx, model, m.withProjectRules(m.prompts.WithPersona(systemPrompt)), m.conversation, m.config.MaxTokens)
 		return thinkingDoneMsg{result: result, err: err}
 	}
 }
 
-func (m *Model) startAcknowledging() (Model, tea.Cmd) {
-	m.state = StateAcknowledging
-	m.statusMsg = "Thinking…"
+// startDefiningDone asks for testable acceptance criteria after the analysis of a COMPLEX task
+func (m *Model) startDefiningDone() (Model, tea.Cmd) {
+	m.state = StateDefiningDone
+	m.statusMsg = "Defining done…"
 	m.startTime = time.Now()
 	m.tokenCount = 0
 
@@ -940,150 +1668,452 @@ func (m *Model) startAcknowledging() (Model, tea.Cmd) {
 	m.ctx = ctx
 	m.cancelFn = cancel
 
+	messages := append(append([]Message(nil), m.conversation...), Message{Role: "user", Content: DoDRequestPrompt})
+	systemPrompt := m.withProjectRules(DoDPrompt)
+
 	return *m, tea.Batch(
 		m.spinner.Tick,
-		m.doAcknowledging(ctx),
+		func() tea.Msg {
+			result, err := m.provider.Generate(ctx, m.config.ChatModel, systemPrompt, messages, m.config.MaxTokens)
+			return dodQuestionsDoneMsg{result: result, err: err}
+		},
 		tea.Tick(time.Second, func(t time.Time) tea.Msg { return tickMsg(t) }),
 	)
 }
 
-func (m *Model) doAcknowledging(ctx context.Context) tea.Cmd {
-	return func() tea.Msg {
-		result, err := m.provider.Generate(ctx, m.config.ChatModel, AcknowledgeSystemPrompt, m.conversation, m.config.MaxTokens)
-		return acknowledgeDoneMsg{result: result, err: err}
+// showExampleTests prints the current example tests as a numbered table
+func (m *Model) showExampleTests() {
+	if m.examples == nil || len(m.examples.Tests) == 0 {
+		m.addOutput(m.styles.Dim.Render("  No example tests. Add one with /tests add fact(5) -> 120"))
+		return
+	}
+	for _, line := range m.examples.FormatTable() {
+		m.addOutput("  " + line)
 	}
 }
 
-func (m *Model) startGenerating() (Model, tea.Cmd) {
-	m.state = StateGenerating
+// learnFromFix records the fix that just ran as the way to clear each failure it cleared
+func (m *Model) learnFromFix(results []ValidationResult) {
+	fix := m.pendingFix
+	m.pendingFix = nil
+	learned, err := m.fixKnowledge.learn(fix, results, m.currentCode)
+	if err != nil {
+		tuiLog.Warn("failed to save fix knowledge", "err", err)
+		return
+	}
+	if learned > 0 {
+		tuiLog.Info("learned fixes", "patterns", learned)
+	}
+}
 
-	// Use model based on complexity (EASY=Haiku, MEDIUM=Sonnet, COMPLEX=Opus)
-	model := m.getModelForComplexity(m.difficulty)
+// failedFindings returns the located findings of the gates that failed
+func (m *Model) failedFindings(results []ValidationResult) []GateFinding {
+	var failed []ValidationResult
+	for _, r := range results {
+		if !r.Success {
+			failed = append(failed, r)
+		}
+	}
+	files := m.currentCodeFiles()
+	return gateFindings(files[0].Filename, files, failed)
+}
 
-	m.statusMsg = "Writing code…"
-	m.startTime = time.Now()
-	m.tokenCount = 0
+// suppressionCandidates returns the findings of the last failed validation that can be suppressed
+func (m *Model) suppressionCandidates(reason string) []Suppression {
+	if len(m.failedResults) == 0 {
+		return nil
+	}
+	var candidates []Suppression
+	files := m.currentCodeFiles()
+	for _, f := range m.failedFindings(m.failedResults) {
+		if s, ok := newSuppression(f, files, reason); ok {
+			candidates = append(candidates, s)
+		}
+	}
+	return candidates
+}
 
-	// Reset escalation state for fresh generation cycle
-	m.resetEscalation()
+// suppressFindings handles /suppress [<n>|all [reason] | list | rm <n>]
+// Accepted findings are recorded in .bjarne/suppressions.json, annotated in the code where the
+// tool supports it, and the code is validated again
+func (m *Model) suppressFindings(args string) (Model, tea.Cmd) {
+	sub, rest, _ := strings.Cut(strings.TrimSpace(args), " ")
+	rest = strings.TrimSpace(rest)
+	cwd, _ := os.Getwd()
+	recorded, err := LoadSuppressions(cwd)
+	if err != nil {
+		m.addOutput(m.styles.Error.Render(err.Error()))
+		return *m, nil
+	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	m.ctx = ctx
-	m.cancelFn = cancel
+	switch strings.ToLower(sub) {
+	case "":
+		candidates := m.suppressionCandidates("")
+		if len(candidates) == 0 {
+			m.addOutput(m.styles.Dim.Render("No clang-tidy or cppcheck findings to suppress in the last failed validation."))
+			return *m, nil
+		}
+		m.addOutput("")
+		m.addOutput(m.styles.Info.Render("Findings that can be suppressed:"))
+		for i, s := range candidates {
+			m.addOutput(fmt.Sprintf("  %d. %s [%s] %s", i+1, s.Gate, s.Check, s.Message))
+			m.addOutput(m.styles.Dim.Render("       " + s.Code))
+		}
+		m.addOutput(m.styles.Dim.Render("Use /suppress <n> [reason] or /suppress all [reason]"))
+		return *m, nil
 
-	return *m, tea.Batch(
-		m.spinner.Tick,
-		m.doGenerating(ctx, model),
-		tea.Tick(time.Second, func(t time.Time) tea.Msg { return tickMsg(t) }),
-	)
-}
+	case "list":
+		if len(recorded) == 0 {
+			m.addOutput(m.styles.Dim.Render("No suppressions in " + projectSuppressionsFile))
+			return *m, nil
+		}
+		m.addOutput("")
+		m.addOutput(m.styles.Info.Render("Suppressions in " + projectSuppressionsFile + ":"))
+		for i, s := range recorded {
+			line := fmt.Sprintf("  %d. %s [%s] %s: %s", i+1, s.Gate, s.Check, s.File, s.Code)
+			if s.Reason != "" {
+				line += m.styles.Dim.Render("  (" + s.Reason + ")")
+			}
+			m.addOutput(line)
+		}
+		return *m, nil
 
-func (m *Model) doGenerating(ctx context.Context, model string) tea.Cmd {
-	return func() tea.Msg {
-		systemPrompt := m.buildSystemPrompt()
-		result, err := m.provider.Generate(ctx, model, systemPrompt, m.conversation, m.config.MaxTokens)
-		return generatingDoneMsg{result: result, err: err}
+	case "rm", "remove", "del":
+		n, err := strconv.Atoi(rest)
+		if err != nil || n < 1 || n > len(recorded) {
+			m.addOutput(m.styles.Error.Render(fmt.Sprintf("Use: /suppress rm <n> (1-%d, see /suppress list)", len(recorded))))
+			return *m, nil
+		}
+		removed := recorded[n-1]
+		recorded = append(recorded[:n-1], recorded[n:]...)
+		if err := SaveSuppressions(cwd, recorded); err != nil {
+			m.addOutput(m.styles.Error.Render(err.Error()))
+			return *m, nil
+		}
+		m.container.SetSuppressions(recorded)
+		m.addOutput(m.styles.Success.Render(fmt.Sprintf("Removed suppression of %s on: %s", removed.Check, removed.Code)))
+		return *m, nil
 	}
-}
 
-// buildSystemPrompt creates the system prompt, including workspace context if indexed
-func (m *Model) buildSystemPrompt() string {
-	prompt := GenerationSystemPrompt
+	candidates := m.suppressionCandidates(rest)
+	var chosen []Suppression
+	if strings.EqualFold(sub, "all") {
+		chosen = candidates
+	} else if n, err := strconv.Atoi(sub); err == nil && n >= 1 && n <= len(candidates) {
+		chosen = candidates[n-1 : n]
+	}
+	if len(chosen) == 0 {
+		m.addOutput(m.styles.Error.Render("Nothing to suppress. Run /suppress to list the findings, then /suppress <n> [reason]."))
+		return *m, nil
+	}
 
-	// Try semantic search with vector index first (better context)
-	if m.vectorIndex != nil && len(m.conversation) > 0 {
-		// Use the last user message as the query
-		var query string
-		for i := len(m.conversation) - 1; i >= 0; i-- {
-			if m.conversation[i].Role == "user" {
-				query = m.conversation[i].Content
-				break
+	known := make(map[string]bool, len(recorded))
+	for _, s := range recorded {
+		known[s.Gate+"\x00"+s.Check+"\x00"+s.Fingerprint] = true
+	}
+	for _, s := range chosen {
+		if key := s.Gate + "\x00" + s.Check + "\x00" + s.Fingerprint; !known[key] {
+			known[key] = true
+			recorded = append(recorded, s)
+		}
+	}
+	if err := SaveSuppressions(cwd, recorded); err != nil {
+		m.addOutput(m.styles.Error.Render(err.Error()))
+		return *m, nil
+	}
+	m.container.SetSuppressions(recorded)
+
+	// Annotate from the bottom up so inserted cppcheck lines do not shift later findings
+	files := m.currentCodeFiles()
+	sort.SliceStable(chosen, func(i, j int) bool { return suppressionLine(chosen[i], files) > suppressionLine(chosen[j], files) })
+	for _, s := range chosen {
+		for i := range files {
+			if files[i].Filename == s.File {
+				files[i].Content = addSuppressionComment(files[i].Content, s, suppressionLine(s, files))
 			}
 		}
+	}
+	m.setCodeFiles(files)
+
+	m.addOutput(m.styles.Success.Render(fmt.Sprintf("Suppressed %d finding(s) in %s", len(chosen), projectSuppressionsFile)))
+	m.failedResults = nil
+	m.validated = false
+	m.savedPath = ""
+	m.manualEdit = true // Only comments changed: no LLM fix or review
+	m.addOutput(m.styles.Info.Render("Re-validating..."))
+	return m.startValidation()
+}
 
-		if query != "" {
-			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-			defer cancel()
+// editExampleTests handles /tests [add <case> | set <n> <case> | rm <n> | clear]
+func (m *Model) editExampleTests(args string) {
+	sub, rest, _ := strings.Cut(strings.TrimSpace(args), " ")
+	rest = strings.TrimSpace(rest)
 
-			// Retrieve up to 20 relevant chunks (similar to Cody)
-			chunks, err := m.vectorIndex.SearchSimilar(ctx, query, 20)
-			if err == nil && len(chunks) > 0 {
-				var contextBuilder strings.Builder
-				contextBuilder.WriteString("<relevant_code_context>\n")
-				contextBuilder.WriteString("The following code from the project is semantically relevant to the request.\n")
-				contextBuilder.WriteString("Use these patterns and styles when generating code:\n\n")
+	switch strings.ToLower(sub) {
+	case "":
+		// Show only
 
-				// Track total size to avoid exceeding token limits (~8000 chars ≈ 2000 tokens)
-				const maxContextChars = 8000
-				totalChars := 0
+	case "add":
+		tc, ok := ParseTestCase(rest)
+		if !ok {
+			m.addOutput(m.styles.Error.Render("Could not parse test case. Use: /tests add fact(5) -> 120"))
+			return
+		}
+		if m.examples == nil {
+			m.examples = &ExampleTests{}
+		}
+		m.examples.AddTest(tc)
+
+	case "set":
+		numStr, caseText, _ := strings.Cut(rest, " ")
+		n, err := strconv.Atoi(numStr)
+		tc, ok := ParseTestCase(strings.TrimSpace(caseText))
+		if err != nil || !ok {
+			m.addOutput(m.styles.Error.Render("Use: /tests set <n> fact(5) -> 120"))
+			return
+		}
+		if m.examples == nil {
+			m.examples = &ExampleTests{}
+		}
+		if err := m.examples.SetTest(n, tc); err != nil {
+			m.addOutput(m.styles.Error.Render(err.Error()))
+			return
+		}
 
-				for i, chunk := range chunks {
-					// Get file path from chunk
-					filePath := m.getChunkFilePath(chunk.FileID)
+	case "rm", "remove", "del":
+		n, err := strconv.Atoi(rest)
+		if err != nil {
+			m.addOutput(m.styles.Error.Render("Use: /tests rm <n>"))
+			return
+		}
+		if m.examples == nil {
+			m.examples = &ExampleTests{}
+		}
+		if err := m.examples.RemoveTest(n); err != nil {
+			m.addOutput(m.styles.Error.Render(err.Error()))
+			return
+		}
 
-					header := fmt.Sprintf("// [%d] %s::%s (%s)\n", i+1, filePath, chunk.Name, chunk.Type)
-					content := chunk.Content
+	case "clear":
+		m.examples = nil
 
-					// Check if adding this chunk would exceed limit
-					chunkSize := len(header) + len(content) + 10
-					if totalChars+chunkSize > maxContextChars && totalChars > 0 {
-						contextBuilder.WriteString(fmt.Sprintf("\n// ... and %d more relevant chunks (truncated for context window)\n", len(chunks)-i))
-						break
-					}
+	default:
+		m.addOutput(m.styles.Error.Render("Unknown /tests command: " + sub))
+		m.addOutput(m.styles.Dim.Render("  Usage: /tests [add <case> | set <n> <case> | rm <n> | clear]"))
+		return
+	}
 
-					contextBuilder.WriteString(header)
-					contextBuilder.WriteString(content)
-					contextBuilder.WriteString("\n\n")
-					totalChars += chunkSize
-				}
-				contextBuilder.WriteString("</relevant_code_context>\n")
+	m.addOutput("Example tests:")
+	m.showExampleTests()
+	if m.testsPending {
+		m.addOutput(m.styles.Dim.Render("  Enter to continue with your request"))
+	}
+}
 
-				prompt += "\n\n" + contextBuilder.String()
-				prompt += "\nIMPORTANT: Generate code that integrates with this codebase:"
-				prompt += "\n- Match the naming conventions (case, prefixes, suffixes)"
-				prompt += "\n- Use the same include patterns and header structure"
-				prompt += "\n- Follow the coding style (braces, spacing, etc.)"
-				prompt += "\n- Reuse existing types, utilities, and patterns where applicable"
-				return prompt
-			}
+// expectOutput records the output the analysis expects, replacing an earlier expectation
+// (nil clears it: a follow-up request may change what the program prints)
+func (m *Model) expectOutput(expected *ExpectedOutput) {
+	if expected == nil {
+		if m.dod != nil && m.dod.ExpectedOutput != nil {
+			dod := cloneDoD(m.dod)
+			dod.ExpectedOutput = nil
+			m.dod = dod
 		}
+		return
+	}
+	dod := cloneDoD(m.dod)
+	if dod == nil {
+		dod = &DefinitionOfDone{}
+	}
+	dod.ExpectedOutput = expected
+	m.dod = dod
+	what := "the expected output"
+	if expected.Regex {
+		what = "the expected pattern"
 	}
+	m.addOutput(m.styles.Dim.Render("Output gate: the program must print " + what))
+}
 
-	// Fall back to workspace index (structural context)
-	if m.workspaceIndex != nil && len(m.workspaceIndex.Files) > 0 {
-		context := m.workspaceIndex.GetContextForPrompt(2000) // ~2000 tokens max
-		if context != "" {
-			prompt += "\n\n" + context
-			prompt += "\n\nIMPORTANT: Generate code that integrates with this codebase:"
-			prompt += "\n- Match existing naming conventions and coding style"
-			prompt += "\n- Use compatible types and include patterns"
-			prompt += "\n- Code should fit naturally alongside existing files"
+// editRunInput handles /stdin [set <text> | load <file> | args <words> | timeout <seconds> | clear]
+// The input is kept on the Definition of Done, so undo, checkpoints and transcripts carry it
+func (m *Model) editRunInput(args string) {
+	sub, rest, _ := strings.Cut(strings.TrimSpace(args), " ")
+	rest = strings.TrimSpace(rest)
+
+	in := RunInput{}
+	if m.dod != nil && m.dod.Input != nil {
+		in = *m.dod.Input
+	}
+	switch strings.ToLower(sub) {
+	case "":
+		// Show only
+
+	case "set":
+		if rest == "" {
+			m.addOutput(m.styles.Error.Render(`Use: /stdin set <text> (\n starts a new line)`))
+			return
+		}
+		in.Stdin = unescapeStdin(rest)
+
+	case "load":
+		if rest == "" {
+			m.addOutput(m.styles.Error.Render("Use: /stdin load <file>"))
+			return
+		}
+		stdin, err := loadRunInputFile(rest)
+		if err != nil {
+			m.addOutput(m.styles.Error.Render(err.Error()))
+			return
+		}
+		in.Stdin = stdin
+
+	case "args":
+		words, err := splitArgs(rest)
+		if err != nil {
+			m.addOutput(m.styles.Error.Render(err.Error()))
+			return
+		}
+		in.Args = words
+
+	case "timeout":
+		n, err := strconv.Atoi(rest)
+		if err != nil || n < 1 || n > maxHangTimeout {
+			m.addOutput(m.styles.Error.Render(fmt.Sprintf("Use: /stdin timeout <seconds> (1-%d)", maxHangTimeout)))
+			return
 		}
+		in.Timeout = n
+
+	case "clear":
+		in = RunInput{}
+
+	default:
+		m.addOutput(m.styles.Error.Render("Unknown /stdin command: " + sub))
+		m.addOutput(m.styles.Dim.Render("  Usage: /stdin [set <text> | load <file> | args <words> | timeout <seconds> | clear]"))
+		return
 	}
 
-	return prompt
+	if sub != "" {
+		dod := cloneDoD(m.dod)
+		if dod == nil {
+			dod = &DefinitionOfDone{}
+		}
+		dod.Input = nil
+		if !in.empty() {
+			dod.Input = &in
+		}
+		m.dod = dod
+	}
+	if m.dod == nil || m.dod.Input.empty() {
+		m.addOutput("Run input: none (the program runs without stdin or arguments)")
+		m.addOutput(m.styles.Dim.Render("  Usage: /stdin [set <text> | load <file> | args <words> | timeout <seconds> | clear]"))
+		return
+	}
+	m.addOutput("Run input (piped into the asan, ubsan, msan, tsan and run stages):")
+	for _, line := range m.dod.Input.Describe() {
+		m.addOutput("  " + line)
+	}
 }
 
-// getChunkFilePath retrieves the file path for a chunk's file ID
-func (m *Model) getChunkFilePath(fileID int64) string {
-	if m.vectorIndex == nil {
-		return "unknown"
+// showRunOutput shows what the program printed in the run stage when it was given input
+func (m *Model) showRunOutput(results []ValidationResult) {
+	if m.dod == nil || m.dod.Input.empty() {
+		return
 	}
+	for _, r := range results {
+		if r.Stage == "run" && !r.Skipped {
+			m.addOutput(m.styles.Dim.Render("Program output with the /stdin input:"))
+			m.addOutput(quoteOutput(normalizeOutput(r.Output)))
+		}
+	}
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
-	defer cancel()
+// showArtifacts reports the binaries exported after validation passed
+func (m *Model) showArtifacts(files []string, err error) {
+	if err != nil {
+		m.addOutput(m.styles.Warning.Render("Could not export the binaries: " + err.Error()))
+	} else if len(files) > 0 {
+		m.addOutput(m.styles.Dim.Render(fmt.Sprintf("Binaries saved to %s: %s (build flags in %s)",
+			filepath.ToSlash(projectArtifactsDir), strings.Join(files, ", "), artifactsManifest)))
+	}
+}
 
-	path, err := m.vectorIndex.GetFilePath(ctx, fileID)
+// writeValidationManifest records the validation that just passed in the project's .bjarne/manifests
+func (m *Model) writeValidationManifest(ctx context.Context, container *ContainerRuntime, results []ValidationResult) (string, error) {
+	manifest := container.NewValidationManifest(ctx, m.currentCodeFiles(), m.examples, m.dod, results)
+	if m.provider != nil {
+		manifest.Provider = m.provider.Name()
+	}
+	manifest.Models = map[string]string{"reflection": m.config.ReflectionModel}
+	if m.codeModel != "" {
+		manifest.Models["code"] = m.codeModel
+	}
+	if len(m.conversation) > 0 {
+		manifest.PromptsHash = requestHash(m.buildSystemPrompt(), m.conversation)
+	}
+	cwd, err := os.Getwd()
 	if err != nil {
-		return "unknown"
+		return "", fmt.Errorf("failed to get working directory: %w", err)
 	}
-	return path
+	path, err := WriteValidationManifest(cwd, manifest)
+	if err != nil {
+		return "", err
+	}
+	if a := m.config.Settings.Attestation; a.Enabled {
+		if err := WriteAttestation(path, manifest, attestationPath(path), a.Key, a.Sigstore); err != nil {
+			return path, fmt.Errorf("failed to attest the validation: %w", err)
+		}
+	}
+	return path, nil
 }
 
-func (m *Model) startValidation() (Model, tea.Cmd) {
-	m.state = StateValidating
-	m.statusMsg = "Validating…"
+// showManifest reports where the validation manifest was written
+func (m *Model) showManifest(path string, err error) {
+	if path != "" {
+		if cwd, cwdErr := os.Getwd(); cwdErr == nil {
+			if rel, relErr := filepath.Rel(cwd, path); relErr == nil {
+				path = rel
+			}
+		}
+		m.addOutput(m.styles.Dim.Render("Replay this validation with: bjarne reproduce " + filepath.ToSlash(path)))
+		if err == nil && m.config.Settings.Attestation.Enabled {
+			m.addOutput(m.styles.Dim.Render("Provenance attestation: " + filepath.ToSlash(attestationPath(path))))
+		}
+	}
+	if err != nil {
+		m.addOutput(m.styles.Warning.Render("Could not write the validation manifest: " + err.Error()))
+	}
+}
+
+// applyDefinitionOfDone parses the user's answers into a DoD enforced by validation
+func (m *Model) applyDefinitionOfDone(answer string) {
+	dod := ParseDefinitionOfDone(answer)
+	if !dod.HasTestableRequirements() {
+		m.addOutput(m.styles.Dim.Render("No testable criteria found; validating with the standard gates only."))
+		return
+	}
+
+	if m.dod != nil && dod.ExpectedOutput == nil {
+		dod.ExpectedOutput = m.dod.ExpectedOutput // From the analysis
+	}
+	if m.dod != nil && dod.Input == nil {
+		dod.Input = m.dod.Input // From /stdin
+	}
+	m.dod = dod
+	m.examples = dod.MergeExamples(m.examples)
+	m.addOutput(m.styles.Success.Render("Definition of Done: ") + dod.FormatDoDSummary())
+	if dod.MaxTimeMs > 0 {
+		m.addOutput(m.styles.Dim.Render(fmt.Sprintf("  Benchmark gate: %d calls in under %dms", dod.BenchmarkN, dod.MaxTimeMs)))
+	}
+	if !dod.Input.empty() {
+		m.addOutput(m.styles.Dim.Render("  The program is run with the given stdin/args (see /stdin)"))
+	}
+}
+
+func (m *Model) startAcknowledging() (Model, tea.Cmd) {
+	m.state = StateAcknowledging
+	m.statusMsg = "Thinking…"
 	m.startTime = time.Now()
+	m.tokenCount = 0
 
 	ctx, cancel := context.WithCancel(context.Background())
 	m.ctx = ctx
@@ -1091,231 +2121,1712 @@ func (m *Model) startValidation() (Model, tea.Cmd) {
 
 	return *m, tea.Batch(
 		m.spinner.Tick,
-		m.doValidation(ctx),
+		m.doAcknowledging(ctx),
 		tea.Tick(time.Second, func(t time.Time) tea.Msg { return tickMsg(t) }),
 	)
 }
 
-func (m *Model) doValidation(ctx context.Context) tea.Cmd {
+func (m *Model) doAcknowledging(ctx context.Context) tea.Cmd {
 	return func() tea.Msg {
-		var results []ValidationResult
-		var err error
-
-		// Use multi-file validation if we have multiple files
-		if len(m.currentFiles) > 1 {
-			results, err = m.container.ValidateMultiFileCodeWithExamples(ctx, m.currentFiles, m.examples, m.dod)
-		} else {
-			// Single file validation (backwards compatible)
-			results, err = m.container.ValidateCodeWithExamples(ctx, m.currentCode, "code.cpp", m.examples, m.dod)
+		result, err := m.provider.Generate(ctx, m.config.ChatModel, m.withProjectRules(m.prompts.WithPersona(AcknowledgeSystemPrompt)), m.conversation, m.config.MaxTokens)
+		return acknowledgeDoneMsg{result: result, err: err}
+	}
+}
+
+func (m *Model) startGenerating() (Model, tea.Cmd) {
+	if m.bestOf > 1 {
+		return m.startBestOf()
+	}
+	if m.needsPlan() {
+		return m.startPlanning()
+	}
+
+	m.state = StateGenerating
+
+	// Use model based on complexity (EASY=Haiku, MEDIUM=Sonnet, COMPLEX=Opus)
+	model := m.getModelForComplexity(m.difficulty)
+
+	m.statusMsg = "Writing code…"
+	m.startTime = time.Now()
+	m.tokenCount = 0
+
+	// Reset escalation state for fresh generation cycle
+	m.resetEscalation()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.ctx = ctx
+	m.cancelFn = cancel
+
+	m.codeModel = model
+	return *m, tea.Batch(
+		m.spinner.Tick,
+		m.doGenerating(ctx, model),
+		tea.Tick(time.Second, func(t time.Time) tea.Msg { return tickMsg(t) }),
+	)
+}
+
+func (m *Model) doGenerating(ctx context.Context, model string) tea.Cmd {
+	return func() tea.Msg {
+		systemPrompt := m.buildSystemPrompt()
+		result, err := m.provider.Generate(ctx, model, systemPrompt, m.conversation, m.config.MaxTokens)
+		return generatingDoneMsg{result: result, err: err}
+	}
+}
+
+// needsPlan reports whether generation should start with a file manifest
+func (m *Model) needsPlan() bool {
+	return m.difficulty == "COMPLEX" && m.intent == "NEW" && m.plan == nil && !m.planSkipped
+}
+
+// startPlanning asks for a file manifest in place of the pending generate request
+func (m *Model) startPlanning() (Model, tea.Cmd) {
+	m.state = StatePlanning
+	m.statusMsg = "Planning files…"
+	m.startTime = time.Now()
+	m.tokenCount = 0
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.ctx = ctx
+	m.cancelFn = cancel
+
+	messages := append([]Message(nil), m.conversation...)
+	if n := len(messages); n > 0 && messages[n-1].Role == "user" && messages[n-1].Content == GenerateNowPrompt {
+		messages = messages[:n-1]
+	}
+	messages = append(messages, Message{Role: "user", Content: PlanManifestPrompt})
+	model := m.getModelForComplexity(m.difficulty)
+	systemPrompt := m.buildSystemPrompt()
+
+	return *m, tea.Batch(
+		m.spinner.Tick,
+		func() tea.Msg {
+			result, err := m.provider.Generate(ctx, model, systemPrompt, messages, m.config.MaxTokens)
+			return planDoneMsg{result: result, err: err}
+		},
+		tea.Tick(time.Second, func(t time.Time) tea.Msg { return tickMsg(t) }),
+	)
+}
+
+// showPlan prints the current plan as a numbered table
+func (m *Model) showPlan() {
+	if m.plan == nil || len(m.plan.Files) == 0 {
+		m.addOutput(m.styles.Dim.Render("  No plan. COMPLEX new projects are planned before generation."))
+		return
+	}
+	for _, line := range m.plan.FormatTable() {
+		m.addOutput("  " + line)
+	}
+}
+
+// editPlan handles /plan [add <file> [purpose] | rm <n> | deps <n> <files|none> | purpose <n> <text> | go | off]
+func (m *Model) editPlan(args string) (Model, tea.Cmd) {
+	sub, rest, _ := strings.Cut(strings.TrimSpace(args), " ")
+	rest = strings.TrimSpace(rest)
+	numArg, value, _ := strings.Cut(rest, " ")
+	n, numErr := strconv.Atoi(numArg)
+	value = strings.TrimSpace(value)
+
+	sub = strings.ToLower(sub)
+	if sub != "" && m.plan == nil {
+		m.addOutput(m.styles.Error.Render("No plan to change."))
+		return *m, nil
+	}
+
+	var err error
+	switch sub {
+	case "":
+		// Show only
+
+	case "add":
+		name, purpose, _ := strings.Cut(rest, " ")
+		err = m.plan.Add(name, strings.TrimSpace(purpose))
+
+	case "rm", "remove", "del":
+		if numErr != nil {
+			err = fmt.Errorf("use: /plan rm <n>")
+			break
 		}
+		err = m.plan.Remove(n)
 
-		// If core validation passed, run domain-specific validators
-		if err == nil && allPassed(results) && m.validatorConfig != nil {
-			domainResults := m.runDomainValidators(ctx)
-			for _, dr := range domainResults {
-				results = append(results, ValidationResult{
-					Stage:   string(dr.ValidatorID),
-					Success: dr.Success,
-					Output:  dr.Output,
-				})
-			}
+	case "deps":
+		if numErr != nil || value == "" {
+			err = fmt.Errorf("use: /plan deps <n> <file, file|none>")
+			break
+		}
+		err = m.plan.SetDeps(n, value)
+
+	case "purpose":
+		if numErr != nil || value == "" {
+			err = fmt.Errorf("use: /plan purpose <n> <text>")
+			break
+		}
+		err = m.plan.SetPurpose(n, value)
+
+	case "go":
+		if !m.planPending {
+			err = fmt.Errorf("the plan has already been generated")
+			break
+		}
+		m.textarea.Blur()
+		return m.startScaffolding()
+
+	case "off":
+		pending := m.planPending
+		m.plan = nil
+		m.planPending = false
+		m.planSkipped = true
+		if !pending {
+			m.addOutput(m.styles.Dim.Render("Plan discarded."))
+			return *m, nil
 		}
+		m.addOutput(m.styles.Dim.Render("Plan discarded; generating in one pass."))
+		m.textarea.Blur()
+		m.conversation = append(m.conversation, Message{Role: "user", Content: GenerateNowPrompt})
+		return m.startGenerating()
+
+	default:
+		m.addOutput(m.styles.Error.Render("Unknown /plan command: " + sub))
+		m.addOutput(m.styles.Dim.Render("  Usage: /plan [add <file> [purpose] | rm <n> | deps <n> <files|none> | purpose <n> <text> | go | off]"))
+		return *m, nil
+	}
 
-		return validationDoneMsg{results: results, err: err}
+	if err != nil {
+		m.addOutput(m.styles.Error.Render(err.Error()))
+		return *m, nil
+	}
+	m.addOutput("Project plan:")
+	m.showPlan()
+	if m.planPending {
+		m.addOutput(m.styles.Dim.Render("  Enter to write the files one at a time"))
 	}
+	return *m, nil
 }
 
-// startReviewing initiates the LLM code review gate
-func (m *Model) startReviewing(results []ValidationResult) (Model, tea.Cmd) {
-	m.state = StateReviewing
-	m.statusMsg = "Reviewing code…"
+// startScaffolding writes the planned files one at a time in dependency order
+func (m *Model) startScaffolding() (Model, tea.Cmd) {
+	m.planPending = false
+	m.currentFiles = nil
+	m.currentCode = ""
+	m.scaffoldQueue = m.plan.Order()
+	m.scaffoldTries = 0
+	m.resetEscalation()
+
+	m.addOutput("")
+	m.addOutput(m.styles.Info.Render(fmt.Sprintf("Writing %d files:", len(m.scaffoldQueue))))
+	return m.scaffoldNext()
+}
+
+// scaffoldNext writes the next planned file, or validates the project when all are written
+func (m *Model) scaffoldNext() (Model, tea.Cmd) {
+	if len(m.scaffoldQueue) == 0 {
+		return m.finishScaffolding()
+	}
+	return m.scaffoldFile(m.scaffoldQueue[0], nil, "")
+}
+
+// scaffoldFile asks for one planned file with the files written so far as context
+// A failed attempt and its errors are included when the file is being rewritten
+func (m *Model) scaffoldFile(f PlannedFile, attempt *CodeFile, errs string) (Model, tea.Cmd) {
+	m.state = StateGenerating
+	total := len(m.plan.Files)
+	m.statusMsg = fmt.Sprintf("Writing %s (%d/%d)…", f.Path, total-len(m.scaffoldQueue)+1, total)
 	m.startTime = time.Now()
+	m.tokenCount = 0
 
-	// Show sanitizer gate results
-	m.showValidationSuccess(results)
+	ctx, cancel := context.WithCancel(context.Background())
+	m.ctx = ctx
+	m.cancelFn = cancel
+
+	messages := append(append([]Message(nil), m.conversation...), Message{Role: "user", Content: m.plan.FilePrompt(f, m.currentFiles)})
+	if attempt != nil {
+		messages = append(messages,
+			Message{Role: "assistant", Content: formatFilesForPrompt([]CodeFile{*attempt})},
+			Message{Role: "user", Content: fmt.Sprintf(ScaffoldRetryPromptTemplate, f.Path, errs)})
+	}
+	model := m.getModelForComplexity(m.difficulty)
+	systemPrompt := m.buildSystemPrompt()
+
+	return *m, tea.Batch(
+		m.spinner.Tick,
+		func() tea.Msg {
+			result, err := m.provider.Generate(ctx, model, systemPrompt, messages, m.config.MaxTokens)
+			return scaffoldFileDoneMsg{file: f, result: result, err: err}
+		},
+		tea.Tick(time.Second, func(t time.Time) tea.Msg { return tickMsg(t) }),
+	)
+}
+
+// startScaffoldCheck compiles a freshly written file against the files before it
+func (m *Model) startScaffoldCheck(target string) (Model, tea.Cmd) {
+	m.state = StateValidating
+	m.statusMsg = "Checking " + target + "…"
+	m.startTime = time.Now()
 
 	ctx, cancel := context.WithCancel(context.Background())
 	m.ctx = ctx
 	m.cancelFn = cancel
 
+	files := append([]CodeFile(nil), m.currentFiles...)
 	return *m, tea.Batch(
 		m.spinner.Tick,
-		m.doReview(ctx),
+		func() tea.Msg {
+			result, err := m.container.CheckSyntax(ctx, files, target)
+			return scaffoldCheckDoneMsg{result: result, err: err}
+		},
 		tea.Tick(time.Second, func(t time.Time) tea.Msg { return tickMsg(t) }),
 	)
 }
 
-// doReview performs the LLM code review
-func (m *Model) doReview(ctx context.Context) tea.Cmd {
-	return func() tea.Msg {
-		// Build review prompt with original request and generated code
-		reviewPrompt := fmt.Sprintf(CodeReviewPrompt, m.originalPrompt, m.currentCode)
+// stopScaffolding abandons a scaffold run; the plan stays so Enter can start it again
+func (m *Model) stopScaffolding() {
+	m.scaffoldQueue = nil
+	m.planPending = true
+	m.addOutput(m.styles.Dim.Render("  Enter to write the plan again · /plan off for one pass"))
+	m.state = StateInput
+	m.textarea.Focus()
+}
 
-		// Use Haiku for fast review
-		result, err := m.provider.Generate(ctx, m.config.ReflectionModel, "", []Message{
-			{Role: "user", Content: reviewPrompt},
-		}, 200)
+// finishScaffolding records the written project in the conversation and runs the full gates
+func (m *Model) finishScaffolding() (Model, tea.Cmd) {
+	m.conversation = append(m.conversation,
+		Message{Role: "user", Content: GenerateNowPrompt},
+		Message{Role: "assistant", Content: formatFilesForPrompt(m.currentFiles)})
+	m.currentCode = joinCodeFiles(m.currentFiles)
+	m.enforceNamingConventions()
+	return m.startValidation()
+}
 
-		if err != nil {
-			return reviewDoneMsg{err: err}
-		}
+// startBestOf generates several candidates in parallel and keeps the best one
+func (m *Model) startBestOf() (Model, tea.Cmd) {
+	m.state = StateGenerating
+	m.statusMsg = fmt.Sprintf("Writing and validating %d candidates…", m.bestOf)
+	m.startTime = time.Now()
+	m.tokenCount = 0
+	m.resetEscalation()
 
-		// Parse response: CONFIDENCE: <score>\nSUMMARY: <text>
-		response := strings.TrimSpace(result.Text)
-		confidence, summary := parseReviewResponse(response)
+	ctx, cancel := context.WithCancel(context.Background())
+	m.ctx = ctx
+	m.cancelFn = cancel
 
-		return reviewDoneMsg{result: result, confidence: confidence, summary: summary}
+	var specs []candidateSpec
+	for _, model := range candidateModels(m.config.Settings.BestOf.Models, m.getModelForComplexity(m.difficulty), m.bestOf) {
+		specs = append(specs, candidateSpec{model: model})
+	}
+	req := candidateRequest{
+		provider:       m.provider,
+		container:      m.container,
+		systemPrompt:   m.buildSystemPrompt(),
+		conversation:   append([]Message(nil), m.conversation...),
+		maxTokens:      m.config.MaxTokens,
+		originalPrompt: m.originalPrompt,
+		review:         m.reviewPanel(),
+		prompts:        m.prompts,
+		examples:       m.examples,
+		dod:            m.dod,
+		autoFormat:     m.config.Settings.Format.AutoApply,
 	}
+
+	return *m, tea.Batch(
+		m.spinner.Tick,
+		func() tea.Msg {
+			return bestOfDoneMsg{candidates: generateCandidates(ctx, req, specs)}
+		},
+		tea.Tick(time.Second, func(t time.Time) tea.Msg { return tickMsg(t) }),
+	)
 }
 
-// parseReviewResponse extracts confidence score and summary from review output
-func parseReviewResponse(response string) (int, string) {
-	confidence := 85 // Default to reasonable confidence if parsing fails
-	summary := "Code review completed."
+// startCompare runs the same prompt through two models and validates both
+func (m *Model) startCompare(prompt string, specs []candidateSpec) (Model, tea.Cmd) {
+	m.state = StateGenerating
+	m.statusMsg = fmt.Sprintf("Comparing %s vs %s…", shortModelName(specs[0].model), shortModelName(specs[1].model))
+	m.startTime = time.Now()
+	m.tokenCount = 0
 
-	lines := strings.Split(response, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		upper := strings.ToUpper(line)
+	ctx, cancel := context.WithCancel(context.Background())
+	m.ctx = ctx
+	m.cancelFn = cancel
 
-		if strings.HasPrefix(upper, "CONFIDENCE:") {
-			// Extract number after CONFIDENCE:
-			numStr := strings.TrimPrefix(line, line[:len("CONFIDENCE:")])
-			numStr = strings.TrimSpace(numStr)
-			// Remove any trailing % or text
-			for i, c := range numStr {
-				if c < '0' || c > '9' {
-					numStr = numStr[:i]
-					break
-				}
-			}
-			if n, err := fmt.Sscanf(numStr, "%d", &confidence); n == 1 && err == nil {
-				// Clamp to 0-100
-				if confidence < 0 {
-					confidence = 0
-				}
-				if confidence > 100 {
-					confidence = 100
-				}
-			}
-		} else if strings.HasPrefix(upper, "SUMMARY:") {
-			summary = strings.TrimPrefix(line, line[:len("SUMMARY:")])
-			summary = strings.TrimSpace(summary)
-		}
+	req := candidateRequest{
+		provider:       m.provider,
+		container:      m.container,
+		systemPrompt:   m.buildSystemPrompt(),
+		conversation:   []Message{{Role: "user", Content: m.attachFiles(prompt)}},
+		maxTokens:      m.config.MaxTokens,
+		originalPrompt: prompt,
+		review:         m.reviewPanel(),
+		prompts:        m.prompts,
+		examples:       ParseExampleTests(prompt),
 	}
 
-	return confidence, summary
+	return *m, tea.Batch(
+		m.spinner.Tick,
+		func() tea.Msg {
+			return compareDoneMsg{candidates: generateCandidates(ctx, req, specs)}
+		},
+		tea.Tick(time.Second, func(t time.Time) tea.Msg { return tickMsg(t) }),
+	)
 }
 
-// showValidatedCode displays the final validated code and transitions to reveal
-func (m *Model) showValidatedCode() (Model, tea.Cmd) {
-	m.validated = true
-	m.analyzed = false // Reset for next prompt
-	m.savedPath = ""   // Reset saved state for new code
-	m.resetEscalation()
+// withProjectRules appends the project's BJARNE.md rules (if any) to a system prompt
+func (m *Model) withProjectRules(prompt string) string {
+	if section := m.projectRules.PromptSection(); section != "" {
+		return prompt + "\n\n" + section
+	}
+	return prompt
+}
 
-	// Auto-save to history
-	m.historyPath = m.autoSaveToHistory()
+// buildSystemPrompt creates the system prompt, including workspace context if indexed
+func (m *Model) buildSystemPrompt() string {
+	prompt := m.withProjectRules(m.prompts.Generation())
+	if section := m.dod.PromptSection(); section != "" {
+		prompt += "\n\n" + section
+	}
+	if section := m.config.Settings.Dependencies.PromptSection(); section != "" {
+		prompt += "\n\n" + section
+	}
+	if section := m.container.Fixtures().PromptSection(); section != "" {
+		prompt += "\n\n" + section
+	}
 
-	m.addOutput("")
-	m.addOutput(m.styles.Success.Render("  >> All validation gates passed"))
+	// Naming conventions derived from the workspace index
+	if m.config.Settings.Naming.Mode != NamingModeOff {
+		if hint := m.workspaceIndex.DetectNamingConventions().PromptHint(); hint != "" {
+			prompt += "\n\n" + hint
+		}
+	}
 
-	// Show confidence score and summary
-	confidenceStyle := m.styles.Success
-	if m.lastConfidence < 70 {
-		confidenceStyle = m.styles.Warning
-	} else if m.lastConfidence < 90 {
-		confidenceStyle = m.styles.Info
+	// Try semantic search with vector index first (better context)
+	if m.vectorIndex != nil && len(m.conversation) > 0 {
+		// Use the last user message as the query
+		var query string
+		for i := len(m.conversation) - 1; i >= 0; i-- {
+			if m.conversation[i].Role == "user" {
+				query = m.conversation[i].Content
+				break
+			}
+		}
+
+		if query != "" {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			// Retrieve up to 20 relevant chunks (similar to Cody)
+			chunks, err := m.vectorIndex.SearchSimilar(ctx, query, 20)
+			if err == nil && len(chunks) > 0 {
+				var contextBuilder strings.Builder
+				contextBuilder.WriteString("<relevant_code_context>\n")
+				contextBuilder.WriteString("The following code from the project is semantically relevant to the request.\n")
+				contextBuilder.WriteString("Use these patterns and styles when generating code:\n\n")
+
+				// Track total size to avoid exceeding token limits (~8000 chars ≈ 2000 tokens)
+				const maxContextChars = 8000
+				totalChars := 0
+
+				for i, chunk := range chunks {
+					// Get file path from chunk
+					filePath := m.getChunkFilePath(chunk.FileID)
+
+					header := fmt.Sprintf("// [%d] %s::%s (%s)\n", i+1, filePath, chunk.Name, chunk.Type)
+					content := chunk.Content
+
+					// Check if adding this chunk would exceed limit
+					chunkSize := len(header) + len(content) + 10
+					if totalChars+chunkSize > maxContextChars && totalChars > 0 {
+						contextBuilder.WriteString(fmt.Sprintf("\n// ... and %d more relevant chunks (truncated for context window)\n", len(chunks)-i))
+						break
+					}
+
+					contextBuilder.WriteString(header)
+					contextBuilder.WriteString(content)
+					contextBuilder.WriteString("\n\n")
+					totalChars += chunkSize
+				}
+				contextBuilder.WriteString("</relevant_code_context>\n")
+
+				prompt += "\n\n" + m.redact(contextBuilder.String(), "workspace context")
+				prompt += "\nIMPORTANT: Generate code that integrates with this codebase:"
+				prompt += "\n- Match the naming conventions (case, prefixes, suffixes)"
+				prompt += "\n- Use the same include patterns and header structure"
+				prompt += "\n- Follow the coding style (braces, spacing, etc.)"
+				prompt += "\n- Reuse existing types, utilities, and patterns where applicable"
+				return prompt
+			}
+		}
+	}
+
+	// Fall back to workspace index (structural context)
+	if m.workspaceIndex != nil && len(m.workspaceIndex.Files) > 0 {
+		context := m.redact(m.workspaceIndex.GetContextForPrompt(2000), "workspace context") // ~2000 tokens max
+		if context != "" {
+			prompt += "\n\n" + context
+			prompt += "\n\nIMPORTANT: Generate code that integrates with this codebase:"
+			prompt += "\n- Match existing naming conventions and coding style"
+			prompt += "\n- Use compatible types and include patterns"
+			prompt += "\n- Code should fit naturally alongside existing files"
+		}
+	}
+
+	return prompt
+}
+
+// getChunkFilePath retrieves the file path for a chunk's file ID
+func (m *Model) getChunkFilePath(fileID int64) string {
+	if m.vectorIndex == nil {
+		return "unknown"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	path, err := m.vectorIndex.GetFilePath(ctx, fileID)
+	if err != nil {
+		return "unknown"
+	}
+	return path
+}
+
+// enforceNamingConventions checks generated identifiers against the project's naming style
+// In "fix" mode deviations are renamed before validation, in "warn" mode they're only reported
+func (m *Model) enforceNamingConventions() {
+	mode := m.config.Settings.Naming.Mode
+	if mode == NamingModeOff {
+		return
+	}
+	conv := m.workspaceIndex.DetectNamingConventions()
+	if conv == nil {
+		return
+	}
+
+	// A multi-file project is checked as a whole and each rename applied to every file,
+	// so declarations, definitions and calls in other files keep matching
+	files := m.currentFiles
+	if len(files) <= 1 {
+		files = []CodeFile{{Filename: "main.cpp", Content: m.currentCode}}
+	}
+	violations := CheckNamingConventions(joinCodeFiles(files), conv)
+	if mode == NamingModeFix && len(violations) > 0 {
+		files, violations = ApplyNamingFixes(files, violations)
+		if len(m.currentFiles) > 1 {
+			m.currentFiles = files
+			m.currentCode = joinCodeFiles(files)
+		} else {
+			m.currentCode = files[0].Content
+			if len(m.currentFiles) == 1 {
+				m.currentFiles[0].Content = m.currentCode
+			}
+		}
+	}
+
+	if len(violations) == 0 {
+		return
+	}
+
+	m.addOutput("")
+	if mode == NamingModeFix {
+		m.addOutput(m.styles.Info.Render(fmt.Sprintf("Renamed %d identifier(s) to match project naming:", len(violations))))
+	} else {
+		m.addOutput(m.styles.Warning.Render(fmt.Sprintf("%d identifier(s) deviate from project naming:", len(violations))))
+	}
+	for _, v := range violations {
+		m.addOutput(m.styles.Dim.Render(fmt.Sprintf("  %s %s -> %s", v.Kind, v.Name, v.Suggested)))
+	}
+}
+
+func (m *Model) startValidation() (Model, tea.Cmd) {
+	m.state = StateValidating
+	m.statusMsg = "Validating…"
+	m.startTime = time.Now()
+	m.gates = &gateProgress{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.ctx = ctx
+	m.cancelFn = cancel
+
+	return *m, tea.Batch(
+		m.spinner.Tick,
+		m.doValidation(ctx),
+		tea.Tick(time.Second, func(t time.Time) tea.Msg { return tickMsg(t) }),
+	)
+}
+
+// doValidation validates the current code, streaming a gateProgressMsg as each gate starts and
+// finishes before the final validationDoneMsg
+func (m *Model) doValidation(ctx context.Context) tea.Cmd {
+	gates := m.gates
+	return func() tea.Msg {
+		events := make(chan tea.Msg)
+		progress := func(stage string, running bool, result *ValidationResult) {
+			events <- gateProgressMsg{progress: gates, stage: stage, running: running, result: result, events: events}
+		}
+		go func() {
+			defer close(events)
+			events <- m.validate(ctx, progress)
+		}()
+		return <-events
+	}
+}
+
+// validate runs the validation gates and domain validators on the current code
+func (m *Model) validate(ctx context.Context, progress ProgressCallback) validationDoneMsg {
+	var results []ValidationResult
+	var err error
+
+	// Keep the binaries the gates build, to export them if everything passes
+	container := m.container
+	var artifactsErr error
+	if m.config.Settings.Artifacts.Export {
+		if container, artifactsErr = m.container.WithArtifacts(m.config.Settings.Artifacts); artifactsErr != nil {
+			container = m.container
+		}
+		defer container.DiscardArtifacts()
+	}
+
+	// Use multi-file validation if we have multiple files
+	if len(m.currentFiles) > 1 {
+		results, err = container.ValidateMultiFileCodeWithProgress(ctx, m.currentFiles, m.examples, m.dod, progress)
+	} else {
+		// Single file validation (backwards compatible)
+		results, err = container.ValidateCodeWithDoD(ctx, m.currentCode, "code.cpp", m.examples, m.dod, progress)
+	}
+
+	gateResults := results // The container gates, which a manifest can replay
+
+	// If core validation passed, run domain-specific validators
+	if err == nil && allPassed(results) && m.validatorConfig != nil {
+		domainResults := m.runDomainValidators(ctx)
+		for _, dr := range domainResults {
+			if dr.Report != "" {
+				cwd, _ := os.Getwd()
+				if path, reportErr := writeDomainReport(cwd, dr); reportErr != nil {
+					dr.Output += "\n" + reportErr.Error()
+				} else {
+					dr.Output += "\nFull report: " + path
+				}
+			}
+			results = append(results, domainStageResult(dr))
+		}
+	}
+
+	// Export the binaries of code that passed every gate
+	var artifacts []string
+	if err == nil && artifactsErr == nil && allPassed(results) {
+		cwd, _ := os.Getwd()
+		artifacts, artifactsErr = container.ExportArtifacts(ctx, cwd, m.currentCodeFiles())
+	}
+
+	// Record how the code passed, for bjarne reproduce
+	var manifest string
+	var manifestErr error
+	if err == nil && allPassed(results) && m.config.Settings.Manifests.Enabled {
+		manifest, manifestErr = m.writeValidationManifest(ctx, container, gateResults)
+	}
+
+	// Auto-apply formatting to code that passed every gate
+	var formatted []CodeFile
+	if err == nil && allPassed(results) && m.config.Settings.Format.AutoApply {
+		if f, fmtErr := m.container.FormatFiles(ctx, m.currentCodeFiles()); fmtErr == nil {
+			formatted = f
+		}
+	}
+
+	return validationDoneMsg{results: results, formatted: formatted, artifacts: artifacts, artifactsErr: artifactsErr,
+		manifest: manifest, manifestErr: manifestErr, err: err}
+}
+
+// setCodeFiles replaces the current code (e.g. with its clang-formatted or hand-edited version)
+func (m *Model) setCodeFiles(files []CodeFile) {
+	if len(files) == 0 {
+		return
+	}
+	if len(m.currentFiles) > 1 {
+		m.currentFiles = files
+		m.currentCode = joinCodeFiles(files)
+		return
+	}
+	m.currentCode = files[0].Content
+	if len(m.currentFiles) == 1 {
+		m.currentFiles[0].Content = m.currentCode
+	}
+}
+
+// startReviewing initiates the LLM code review gate
+func (m *Model) startReviewing(results []ValidationResult) (Model, tea.Cmd) {
+	m.state = StateReviewing
+	m.statusMsg = "Reviewing code…"
+	m.startTime = time.Now()
+
+	// Show sanitizer gate results
+	m.showValidationSuccess(results)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.ctx = ctx
+	m.cancelFn = cancel
+
+	return *m, tea.Batch(
+		m.spinner.Tick,
+		m.doReview(ctx),
+		tea.Tick(time.Second, func(t time.Time) tea.Msg { return tickMsg(t) }),
+	)
+}
+
+// doReview performs the LLM code review
+func (m *Model) doReview(ctx context.Context) tea.Cmd {
+	panel := m.reviewPanel()
+	files := m.currentCodeFiles()
+	analysis := ""
+	if m.checkAnalysis {
+		analysis = m.analysis
+	}
+	return func() tea.Msg {
+		// Compare the code with the analysis while it is reviewed
+		var divergences []string
+		var checkUsage *GenerateResult
+		var checkErr error
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			if strings.TrimSpace(analysis) != "" {
+				divergences, checkUsage, checkErr = checkConsistency(ctx, m.provider, panel.models[0], m.originalPrompt, analysis, joinCodeFiles(files))
+			}
+		}()
+
+		// Review the original request and generated code (the reflection model by default, for speed)
+		v, err := panel.Assess(ctx, m.provider, m.prompts, m.container, m.originalPrompt, m.currentCode, files)
+		<-done
+		if err != nil {
+			return reviewDoneMsg{err: err}
+		}
+		if checkUsage != nil {
+			v.Usage.InputTokens += checkUsage.InputTokens
+			v.Usage.OutputTokens += checkUsage.OutputTokens
+		}
+		return reviewDoneMsg{result: &v.Usage, confidence: v.Confidence, summary: v.Summary, breakdown: v.Breakdown(panel.consensus),
+			checks: v.Checks, divergences: divergences, checkErr: checkErr}
+	}
+}
+
+// parseReviewResponse extracts confidence score and summary from review output
+func parseReviewResponse(response string) (int, string) {
+	confidence := 85 // Default to reasonable confidence if parsing fails
+	summary := "Code review completed."
+
+	lines := strings.Split(response, "\n")
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		upper := strings.ToUpper(line)
+
+		if strings.HasPrefix(upper, "CONFIDENCE:") {
+			// Extract number after CONFIDENCE:
+			numStr := strings.TrimPrefix(line, line[:len("CONFIDENCE:")])
+			numStr = strings.TrimSpace(numStr)
+			// Remove any trailing % or text
+			for i, c := range numStr {
+				if c < '0' || c > '9' {
+					numStr = numStr[:i]
+					break
+				}
+			}
+			if n, err := fmt.Sscanf(numStr, "%d", &confidence); n == 1 && err == nil {
+				// Clamp to 0-100
+				if confidence < 0 {
+					confidence = 0
+				}
+				if confidence > 100 {
+					confidence = 100
+				}
+			}
+		} else if strings.HasPrefix(upper, "SUMMARY:") {
+			summary = strings.TrimPrefix(line, line[:len("SUMMARY:")])
+			summary = strings.TrimSpace(summary)
+		}
+	}
+
+	return confidence, summary
+}
+
+// currentCodeFiles returns the current code as a file list (single-file code becomes code.cpp)
+func (m *Model) currentCodeFiles() []CodeFile {
+	if len(m.currentFiles) > 1 {
+		return m.currentFiles
+	}
+	return []CodeFile{{Filename: "code.cpp", Content: m.currentCode}}
+}
+
+// completeInput tab-completes the input, listing the options when the completion is ambiguous
+func (m *Model) completeInput() {
+	input := m.textarea.Value()
+	completed, candidates := completeInput(input)
+	if completed != input {
+		m.textarea.SetValue(completed)
+		return
+	}
+	if len(candidates) > 1 {
+		m.addOutput(m.styles.Dim.Render(strings.Join(candidates, "  ")))
+	}
+}
+
+// recallHistory replaces the input with an older (up) or newer prompt from history
+// Returns false when the key should move the cursor within a multi-line input instead
+func (m *Model) recallHistory(up bool) bool {
+	if up && m.textarea.Line() > 0 {
+		return false
+	}
+	if !up && m.textarea.Line() < m.textarea.LineCount()-1 {
+		return false
+	}
+
+	var entry string
+	var ok bool
+	if up {
+		entry, ok = m.history.Prev(m.textarea.Value())
+	} else {
+		entry, ok = m.history.Next()
+	}
+	if !ok {
+		return false
+	}
+	m.textarea.SetValue(entry)
+	return true
+}
+
+// presentValidatedCode shows validated code, asking for approval first when configured
+func (m *Model) presentValidatedCode() (Model, tea.Cmd) {
+	m.notify(NotifyValidationDone, "Validation passed")
+	if m.needsApproval() {
+		return m.startApproval()
+	}
+	return m.showValidatedCode()
+}
+
+// needsApproval reports whether validated code must be approved before reveal/save
+func (m *Model) needsApproval() bool {
+	approval := m.config.Settings.Approval
+	if !approval.Enabled {
+		return false
+	}
+	return !(approval.AutoAcceptEasy && m.difficulty == "EASY")
+}
+
+// startApproval summarises the validated code and waits for Approve / Regenerate / Edit-prompt
+func (m *Model) startApproval() (Model, tea.Cmd) {
+	m.addOutput("")
+	m.addOutput(m.styles.Success.Render("  >> All validation gates passed"))
+	m.addOutput(fmt.Sprintf("  Confidence: %d%%", m.lastConfidence))
+	if m.lastSummary != "" {
+		m.addOutput(fmt.Sprintf("  %s", m.styles.Dim.Render(m.lastSummary)))
+	}
+
+	// File summary with sizes and change counts against the last accepted version
+	previous := make(map[string]string)
+	for _, f := range m.previousFiles {
+		previous[f.Filename] = f.Content
+	}
+
+	m.addOutput("")
+	m.addOutput("Files:")
+	var diffs []string
+	for _, f := range m.currentCodeFiles() {
+		lines := len(splitLines(f.Content))
+		info := fmt.Sprintf("  %-24s %4d lines  %6d bytes", f.Filename, lines, len(f.Content))
+
+		if old, ok := previous[f.Filename]; ok {
+			added, removed := DiffStats(LineDiff(old, f.Content))
+			info += "  " + m.styles.Success.Render(fmt.Sprintf("+%d", added)) + " " + m.styles.Error.Render(fmt.Sprintf("-%d", removed))
+			if diff := UnifiedDiff("previous/"+f.Filename, f.Filename, old, f.Content, 3); diff != "" {
+				diffs = append(diffs, diff)
+			}
+		} else if len(m.previousFiles) > 0 {
+			info += "  " + m.styles.Success.Render("(new)")
+		}
+		m.addOutput(info)
+	}
+
+	if len(diffs) > 0 {
+		m.addOutput("")
+		m.addOutput("Changes since last accepted version:")
+		m.addOutput(m.renderCode(strings.TrimRight(strings.Join(diffs, ""), "\n"), "diff"))
+	}
+
+	m.addOutput("")
+	m.state = StateApproving
+	m.textarea.Blur()
+	return *m, nil
+}
+
+// handleApprovalKey processes the answer to the approval prompt
+func (m *Model) handleApprovalKey(msg tea.KeyMsg) (Model, tea.Cmd) {
+	answer := ""
+	if msg.Type == tea.KeyEnter {
+		answer = "a"
+	} else if msg.Type == tea.KeyRunes {
+		answer = strings.ToLower(string(msg.Runes))
+	}
+
+	switch answer {
+	case "a", "y":
+		return m.showValidatedCode()
+
+	case "r":
+		m.addOutput(m.styles.Warning.Render("Regenerating..."))
+		m.conversation = append(m.conversation, Message{Role: "user", Content: RegeneratePrompt})
+		return m.startGenerating()
+
+	case "e":
+		// Start over with the original prompt in the input box for editing
+		m.conversation = []Message{}
+		m.analyzed = false
+		m.awaitingDoD = false
+		m.validated = false
+		m.resetEscalation()
+		m.state = StateInput
+		m.textarea.SetValue(m.originalPrompt)
+		m.textarea.Focus()
+		m.addOutput(m.styles.Dim.Render("Edit your request and press Enter"))
+		return *m, textarea.Blink
+	}
+
+	return *m, nil
+}
+
+// finishEdit reads back code edited by /edit, shows the diff and re-runs the gates
+func (m *Model) finishEdit(msg editDoneMsg) (Model, tea.Cmd) {
+	defer func() { _ = os.RemoveAll(msg.dir) }()
+	m.textarea.Focus()
+
+	if msg.err != nil {
+		m.addOutput(m.styles.Error.Render("Editor failed: " + msg.err.Error()))
+		return *m, nil
+	}
+
+	edited, err := readEditFiles(msg.dir, msg.files)
+	if err != nil {
+		m.addOutput(m.styles.Error.Render("Error: " + err.Error()))
+		return *m, nil
+	}
+
+	var diffs []string
+	for i, f := range edited {
+		if diff := UnifiedDiff("a/"+f.Filename, "b/"+f.Filename, msg.files[i].Content, f.Content, 3); diff != "" {
+			diffs = append(diffs, diff)
+		}
+	}
+	if len(diffs) == 0 {
+		m.addOutput(m.styles.Dim.Render("No changes."))
+		return *m, nil
+	}
+
+	m.addOutput("")
+	m.addOutput(m.styles.Info.Render("Your changes:"))
+	m.addOutput(m.renderCode(strings.TrimRight(strings.Join(diffs, ""), "\n"), "diff"))
+
+	m.setCodeFiles(edited)
+	m.validated = false
+	m.savedPath = ""
+	m.manualEdit = true
+	m.addOutput("")
+	m.addOutput(m.styles.Info.Render("Re-validating edited code..."))
+	return m.startValidation()
+}
+
+// showValidatedCode displays the final validated code and transitions to reveal
+func (m *Model) showValidatedCode() (Model, tea.Cmd) {
+	m.validated = true
+	m.analyzed = false // Reset for next prompt
+	m.savedPath = ""   // Reset saved state for new code
+	m.resetEscalation()
+
+	// Auto-save to history
+	m.historyPath = m.autoSaveToHistory()
+
+	m.addOutput("")
+	m.addOutput(m.styles.Success.Render("  >> All validation gates passed"))
+
+	// Show confidence score and summary
+	confidenceStyle := m.styles.Success
+	if m.lastConfidence < m.reviewThreshold() {
+		confidenceStyle = m.styles.Warning
+	} else if m.lastConfidence < 90 {
+		confidenceStyle = m.styles.Info
+	}
+	m.addOutput("")
+	m.addOutput(fmt.Sprintf("  Confidence: %s", confidenceStyle.Render(fmt.Sprintf("%d%%", m.lastConfidence))))
+	if m.lastSummary != "" {
+		m.addOutput(fmt.Sprintf("  %s", m.styles.Dim.Render(m.lastSummary)))
+	}
+
+	// Remember the accepted version for the next approval diff
+	m.previousFiles = append([]CodeFile(nil), m.currentCodeFiles()...)
+
+	// Build reveal lines with file separators for multi-file projects
+	m.revealLines = m.buildRevealLines()
+	m.revealCurrentLine = 0
+	m.state = StateRevealing
+
+	// Start the reveal animation
+	return *m, func() tea.Msg {
+		return codeRevealMsg{
+			lines:       m.revealLines,
+			currentLine: 0,
+		}
+	}
+}
+
+// Escalation helper methods
+
+// resetEscalation resets escalation state for a new generation cycle
+func (m *Model) resetEscalation() {
+	m.currentIteration = 0
+	m.currentModelIndex = -1
+	m.totalFixAttempts = 0
+	m.fixesSinceFresh = 0
+	m.failures = failureHistory{}
+	m.lastValidationErrs = ""
+	m.modelsUsed = nil
+	m.reviewFailures = 0
+}
+
+// canEscalate checks if we can attempt another fix (total attempts across the ladder)
+func (m *Model) canEscalate() bool {
+	return m.totalFixAttempts < ladderAttempts(m.escalationLadder())
+}
+
+// getCurrentModel returns the current model to use for fixes
+// Climbs the escalation ladder (settings: models.escalation, models.escalationAttempts) as attempts accumulate
+func (m *Model) getCurrentModel() string {
+	return modelForAttempt(m.escalationLadder(), m.totalFixAttempts)
+}
+
+// advanceEscalation increments the fix attempt counter
+func (m *Model) advanceEscalation() {
+	m.totalFixAttempts++
+	m.fixesSinceFresh++
+}
+
+// reviewUsage lists the /review options
+const reviewUsage = "/review threshold <1-100> | /review consensus average|min|off | /review functional|consistency on|off"
+
+// directValidationPrefix starts the stand-in request for code validated with /validate
+const directValidationPrefix = "(Direct validation of "
+
+// regenerateThreshold is the failed-fix count at which the regenerate strategy starts over
+func (m *Model) regenerateThreshold() int {
+	if m.regenAfter < 1 {
+		return defaultRegenerateAfter
+	}
+	return m.regenAfter
+}
+
+// snapshotTurn captures the session state for /undo; prompt is what the next turn starts with
+func (m *Model) snapshotTurn(prompt string) turnSnapshot {
+	s := turnSnapshot{
+		prompt:             prompt,
+		conversation:       append([]Message(nil), m.conversation...),
+		currentCode:        m.currentCode,
+		currentFiles:       append([]CodeFile(nil), m.currentFiles...),
+		validated:          m.validated,
+		analyzed:           m.analyzed,
+		originalPrompt:     m.originalPrompt,
+		analysis:           m.analysis,
+		examples:           cloneExamples(m.examples),
+		dod:                cloneDoD(m.dod),
+		awaitingDoD:        m.awaitingDoD,
+		plan:               clonePlan(m.plan),
+		planPending:        m.planPending,
+		planSkipped:        m.planSkipped,
+		difficulty:         m.difficulty,
+		intent:             m.intent,
+		failedResults:      m.failedResults,
+		lastResults:        m.lastResults,
+		versions:           m.versions,
+		lastConfidence:     m.lastConfidence,
+		lastSummary:        m.lastSummary,
+		currentIteration:   m.currentIteration,
+		currentModelIndex:  m.currentModelIndex,
+		totalFixAttempts:   m.totalFixAttempts,
+		fixesSinceFresh:    m.fixesSinceFresh,
+		failures:           m.failures.clone(),
+		lastValidationErrs: m.lastValidationErrs,
+		failureSigs:        m.failureSigs,
+		modelsUsed:         append([]string(nil), m.modelsUsed...),
+		reviewFailures:     m.reviewFailures,
+	}
+	if m.tokenTracker != nil {
+		s.tokens = *m.tokenTracker
+	}
+	return s
+}
+
+// restoreTurn puts the session back to a snapshot taken by snapshotTurn
+func (m *Model) restoreTurn(s turnSnapshot) {
+	m.conversation = s.conversation
+	m.currentCode = s.currentCode
+	m.currentFiles = s.currentFiles
+	m.validated = s.validated
+	m.analyzed = s.analyzed
+	m.originalPrompt = s.originalPrompt
+	m.analysis = s.analysis
+	m.examples = s.examples
+	m.dod = s.dod
+	m.awaitingDoD = s.awaitingDoD
+	m.plan = s.plan
+	m.planPending = s.planPending
+	m.planSkipped = s.planSkipped
+	m.difficulty = s.difficulty
+	m.intent = s.intent
+	m.failedResults = s.failedResults
+	m.lastResults = s.lastResults
+	m.versions = s.versions
+	m.lastConfidence = s.lastConfidence
+	m.lastSummary = s.lastSummary
+	m.currentIteration = s.currentIteration
+	m.currentModelIndex = s.currentModelIndex
+	m.totalFixAttempts = s.totalFixAttempts
+	m.fixesSinceFresh = s.fixesSinceFresh
+	m.failures = s.failures
+	m.lastValidationErrs = s.lastValidationErrs
+	m.failureSigs = s.failureSigs
+	m.modelsUsed = s.modelsUsed
+	m.reviewFailures = s.reviewFailures
+	if m.tokenTracker != nil {
+		*m.tokenTracker = s.tokens
+	}
+
+	// Work in flight belonged to the state being left
+	m.pendingFix = nil
+	m.testsPending = false
+	m.pendingPrompt = ""
+	m.scaffoldQueue = nil
+	m.manualEdit = false
+}
+
+// showRestoredTurn summarizes the session after /undo or /redo
+func (m *Model) showRestoredTurn() {
+	turns := 0
+	for _, msg := range m.conversation {
+		if msg.Role == "user" {
+			turns++
+		}
+	}
+	code := "no code"
+	if len(m.currentFiles) > 1 {
+		code = fmt.Sprintf("%d files", len(m.currentFiles))
+	} else if m.currentCode != "" {
+		code = fmt.Sprintf("%d lines of code", strings.Count(m.currentCode, "\n")+1)
+	}
+	input, output, _ := m.tokenTracker.GetUsage()
+	m.addOutput(m.styles.Dim.Render(fmt.Sprintf("  %d message(s) from you, %s, %d in / %d out tokens", turns, code, input, output)))
+	if m.awaitingDoD || m.analyzed {
+		m.addOutput(m.styles.Dim.Render("  Your next message answers bjarne's last analysis."))
+	}
+}
+
+// recordVersion keeps the code just validated for /export
+func (m *Model) recordVersion(results []ValidationResult) {
+	m.versions = append(m.versions, CodeVersion{
+		Time:  time.Now(),
+		Model: m.codeModel,
+		Files: append([]CodeFile(nil), m.currentCodeFiles()...),
+		Gates: checkpointGates(results),
+	})
+	if len(m.versions) > maxCodeVersions {
+		m.versions = m.versions[len(m.versions)-maxCodeVersions:]
+	}
+}
+
+// transcript captures the session for /export
+func (m *Model) transcript() *Transcript {
+	t := &Transcript{
+		Version:      transcriptVersion,
+		Exported:     time.Now(),
+		Session:      m.sessionID,
+		Prompt:       m.originalPrompt,
+		Conversation: m.conversation,
+		Validated:    m.validated,
+		Versions:     m.versions,
+		DoD:          m.dod,
+		Examples:     m.examples,
+	}
+	if m.currentCode != "" || len(m.currentFiles) > 0 {
+		t.Code = m.currentCodeFiles()
+	}
+	return t
+}
+
+// inFlight reports whether quitting now would lose work: a running task, code awaiting approval
+// or saving, or failed gates waiting for a fix
+func (m *Model) inFlight() bool {
+	if len(m.conversation) == 0 && m.currentCode == "" && len(m.currentFiles) == 0 {
+		return false
+	}
+	switch m.state {
+	case StateApproving, StateRevealing, StateConfirmSave:
+		return true
+	}
+	return reportsProgress(m.state) || m.hasUnsavedCode() || len(m.failedResults) > 0
+}
+
+// recoveryState captures the session and its pending fix context for /recover
+func (m *Model) recoveryState() *recoveryState {
+	r := &recoveryState{
+		Transcript:    *m.transcript(),
+		FailedResults: m.failedResults,
+		FixContext:    m.lastValidationErrs,
+	}
+	r.Dir, _ = os.Getwd()
+	if reportsProgress(m.state) {
+		r.Task = stateSentences[m.state]
+	}
+	r.Unsaved = len(r.Code) > 0 && m.savedPath == ""
+	return r
+}
+
+// shutdown runs once the TUI has exited: it stops the running task, keeps unfinished work for
+// /recover and closes the vector index. The task's containers are killed on exit
+func (m *Model) shutdown() {
+	if m.cancelFn != nil {
+		m.cancelFn()
+	}
+	if m.inFlight() {
+		path, err := recoveryPath()
+		if err == nil {
+			err = saveRecovery(path, m.recoveryState())
+		}
+		if err != nil {
+			fmt.Printf("\033[93mCould not save the unfinished session:\033[0m %v\n", err)
+		} else {
+			fmt.Println("Saved the unfinished session. Start bjarne again and type /recover to continue it.")
+		}
+	}
+	if m.vectorIndex != nil {
+		_ = m.vectorIndex.Close()
+		m.vectorIndex = nil
+	}
+}
+
+// recoverSession handles /recover [discard]: continue the session saved at quit, or delete it
+func (m *Model) recoverSession(discard bool) {
+	path, err := recoveryPath()
+	var r *recoveryState
+	if err == nil {
+		r, err = loadRecovery(path)
+	}
+	switch {
+	case err != nil:
+		m.addOutput(m.styles.Error.Render("Error: " + err.Error()))
+		return
+	case r == nil:
+		m.addOutput("No unfinished session to recover.")
+		return
+	}
+	if discard {
+		if err := os.Remove(path); err != nil {
+			m.addOutput(m.styles.Error.Render("Error: " + err.Error()))
+			return
+		}
+		m.addOutput("Deleted the unfinished session.")
+		return
+	}
+
+	m.importTranscript(&r.Transcript, path)
+	m.failedResults = r.FailedResults
+	m.lastValidationErrs = r.FixContext
+	_ = os.Remove(path)
+	switch {
+	case r.Task != "":
+		m.addOutput(m.styles.Dim.Render("  bjarne quit while " + strings.ToLower(r.Task[:1]) + r.Task[1:] + " Send a message to carry on."))
+	case len(r.FailedResults) > 0:
+		m.addOutput(m.styles.Dim.Render("  The last validation failed. Ask bjarne to fix it, or use /edit."))
+	case r.Unsaved:
+		m.addOutput(m.styles.Dim.Render("  The validated code has not been saved yet. Use /save."))
+	}
+}
+
+// importTranscript seeds the session from an exported transcript; /undo returns to the old session
+func (m *Model) importTranscript(t *Transcript, path string) {
+	m.turns.Push(m.snapshotTurn("/import " + path))
+
+	m.conversation = append([]Message(nil), t.Conversation...)
+	m.originalPrompt = t.Prompt
+	m.currentFiles = nil
+	m.currentCode = ""
+	if len(t.Code) > 0 {
+		m.currentFiles = append([]CodeFile(nil), t.Code...)
+		m.currentCode = joinCodeFiles(t.Code)
+		if len(t.Code) == 1 {
+			m.currentCode = t.Code[0].Content
+		}
+	}
+	m.validated = t.Validated && len(t.Code) > 0
+	m.versions = append([]CodeVersion(nil), t.Versions...)
+	m.dod = t.DoD
+	m.examples = t.Examples
+	m.analyzed = false
+	m.awaitingDoD = false
+	m.analysis = ""
+	m.plan = nil
+	m.planPending = false
+	m.failedResults = nil
+	m.lastResults = nil
+	m.savedPath = ""
+	m.manualEdit = false
+	m.resetEscalation()
+
+	code := "no code"
+	if len(t.Code) > 0 {
+		code = fmt.Sprintf("%d file(s)", len(t.Code))
+		if !m.validated {
+			code += ", not validated"
+		}
+	}
+	m.addOutput(m.styles.Success.Render(fmt.Sprintf("✓ Imported %d message(s) and %s from %s", len(t.Conversation), code, path)))
+	if !t.Exported.IsZero() {
+		m.addOutput(m.styles.Dim.Render("  Exported " + t.Exported.Format("2006-01-02 15:04") + "; /undo returns to the previous session"))
+	}
+}
+
+// templateLibrary returns the user's templates and the current project's shared ones
+func (m *Model) templateLibrary() (TemplateLibrary, error) {
+	userDir, err := userTemplatesDir()
+	if err != nil {
+		return TemplateLibrary{}, err
+	}
+	lib := TemplateLibrary{userDir: userDir}
+	if cwd, err := os.Getwd(); err == nil {
+		lib.projectDir = filepath.Join(cwd, projectTemplatesDir)
+	}
+	return lib, nil
+}
+
+const templateUsage = "Usage: /template [list] | show|use|delete <name> | save <name> [--project] [text]"
+
+// templateCommand handles /template; input is the whole command line, so saved text keeps its layout
+func (m *Model) templateCommand(input string) {
+	lib, err := m.templateLibrary()
+	if err != nil {
+		m.addOutput(m.styles.Error.Render("Error: " + err.Error()))
+		return
+	}
+	parts := strings.Fields(input)
+	if len(parts) < 2 || strings.ToLower(parts[1]) == "list" {
+		templates := lib.List()
+		if len(templates) == 0 {
+			m.addOutput("No templates yet. Save one with /template save <name> [text]")
+			return
+		}
+		m.addOutput("Templates:")
+		for _, t := range templates {
+			scope := ""
+			if t.Project {
+				scope = " (project)"
+			}
+			m.addOutput(fmt.Sprintf("  %-20s %s%s", t.Name, truncateError(t.Summary(), 50), m.styles.Dim.Render(scope)))
+		}
+		m.addOutput(m.styles.Dim.Render("/template show|use|delete <name>"))
+		return
+	}
+	if len(parts) < 3 {
+		m.addOutput(templateUsage)
+		return
+	}
+
+	action, name := strings.ToLower(parts[1]), parts[2]
+	switch action {
+	case "save":
+		// Everything after the name (and --project) is the template text
+		rest := strings.TrimSpace(input)
+		for _, p := range parts[:3] {
+			rest = strings.TrimSpace(rest[len(p):])
+		}
+		project := false
+		if after, ok := strings.CutPrefix(rest, "--project"); ok && (after == "" || strings.TrimSpace(after) != after) {
+			project, rest = true, strings.TrimSpace(after)
+		}
+		text := rest
+		if text == "" {
+			text = m.originalPrompt
+		}
+		if strings.TrimSpace(text) == "" {
+			m.addOutput("Nothing to save: give the template text, or make a request first to save it as a template")
+			return
+		}
+		path, err := lib.Save(name, text, project)
+		if err != nil {
+			m.addOutput(m.styles.Error.Render("Error: " + err.Error()))
+			return
+		}
+		m.addOutput(m.styles.Success.Render("Saved template " + name + " to " + path))
+		if rest == "" {
+			m.addOutput(m.styles.Dim.Render("  Edit it to add placeholders such as {{endpoint}} or {{framework:drogon}}"))
+		}
+
+	case "show":
+		t, err := lib.Load(name)
+		if err != nil {
+			m.addOutput(m.styles.Error.Render("Error: " + err.Error()))
+			return
+		}
+		m.addOutput(m.styles.Dim.Render(t.Path))
+		for _, line := range strings.Split(strings.TrimRight(t.Text, "\n"), "\n") {
+			m.addOutput("  " + line)
+		}
+
+	case "use":
+		t, err := lib.Load(name)
+		if err != nil {
+			m.addOutput(m.styles.Error.Render("Error: " + err.Error()))
+			return
+		}
+		fill := newTemplateFill(t)
+		if fill.done() {
+			m.finishTemplate(fill)
+			return
+		}
+		m.templateFill = fill
+		m.state = StateFillTemplate
+		m.addOutput(fmt.Sprintf("Filling in %s: %d placeholder(s). Enter keeps the [default], Esc cancels.", name, len(fill.placeholders)))
+
+	case "delete":
+		path, err := lib.Delete(name)
+		if err != nil {
+			m.addOutput(m.styles.Error.Render("Error: " + err.Error()))
+			return
+		}
+		m.addOutput(m.styles.Success.Render("Deleted " + path))
+
+	default:
+		m.addOutput(templateUsage)
+	}
+}
+
+// handleTemplateKey answers the current placeholder on Enter and cancels on Esc
+func (m *Model) handleTemplateKey(msg tea.KeyMsg) (Model, tea.Cmd) {
+	fill := m.templateFill
+	if msg.Type == tea.KeyEsc {
+		m.templateFill = nil
+		m.state = StateInput
+		m.textarea.Reset()
+		m.addOutput(m.styles.Dim.Render("Template cancelled"))
+		return *m, nil
+	}
+	p := fill.current()
+	fill.answer(m.textarea.Value())
+	m.textarea.Reset()
+	m.addOutput(m.styles.Dim.Render("  " + p.Name + ": " + fill.values[p.Name]))
+	if fill.done() {
+		m.templateFill = nil
+		m.state = StateInput
+		m.finishTemplate(fill)
+	}
+	return *m, nil
+}
+
+// finishTemplate puts the filled-in template in the input for review before it is sent
+func (m *Model) finishTemplate(fill *templateFill) {
+	m.textarea.SetValue(fill.prompt())
+	m.textarea.Focus()
+	m.addOutput(m.styles.Success.Render("✓ Template " + fill.template.Name + " is ready: review it and press Enter to send"))
+}
+
+// checkpointStore returns the store for this session's checkpoints
+func (m *Model) checkpointStore() (CheckpointStore, error) {
+	dir, err := checkpointDir(m.sessionID)
+	if err != nil {
+		return CheckpointStore{}, err
+	}
+	return CheckpointStore{dir: dir}, nil
+}
+
+// checkpointCommand handles /checkpoint [name]: save the current code, or list checkpoints
+func (m *Model) checkpointCommand(name string) {
+	store, err := m.checkpointStore()
+	if err != nil {
+		m.addOutput(m.styles.Error.Render("Error: " + err.Error()))
+		return
+	}
+
+	if name == "" {
+		checkpoints := store.List()
+		if len(checkpoints) == 0 {
+			m.addOutput("No checkpoints in this session. Usage: /checkpoint <name>")
+			return
+		}
+		m.addOutput("Checkpoints:")
+		for _, c := range checkpoints {
+			m.addOutput(fmt.Sprintf("  %-16s %s  %d file(s), %s", c.Name, c.Created.Format("15:04:05"), len(c.Files), c.Status()))
+		}
+		return
+	}
+
+	if m.currentCode == "" && len(m.currentFiles) == 0 {
+		m.addOutput("No code to checkpoint yet.")
+		return
+	}
+	c := &Checkpoint{
+		Name:      name,
+		Created:   time.Now(),
+		Prompt:    m.originalPrompt,
+		Files:     append([]CodeFile(nil), m.currentCodeFiles()...),
+		DoD:       m.dod,
+		Examples:  m.examples,
+		Validated: m.validated,
+	}
+	if m.validated {
+		c.Confidence = m.lastConfidence
+	}
+	if m.validated || m.failedResults != nil {
+		c.Gates = checkpointGates(m.lastResults)
+	}
+	if err := store.Save(c); err != nil {
+		m.addOutput(m.styles.Error.Render("Error: " + err.Error()))
+		return
+	}
+	m.addOutput(m.styles.Success.Render(fmt.Sprintf("Checkpoint %q saved (%d file(s), %s)", name, len(c.Files), c.Status())))
+}
+
+// loadCheckpoint reads a named checkpoint, reporting problems in the output
+func (m *Model) loadCheckpoint(command, name string) *Checkpoint {
+	if name == "" {
+		m.addOutput(fmt.Sprintf("Usage: %s <name> (see /checkpoint for the list)", command))
+		return nil
+	}
+	store, err := m.checkpointStore()
+	if err == nil {
+		var c *Checkpoint
+		if c, err = store.Load(name); err == nil {
+			return c
+		}
+	}
+	m.addOutput(m.styles.Error.Render("Error: " + err.Error()))
+	return nil
+}
+
+// restoreCheckpoint handles /restore <name>; the previous state stays reachable with /undo
+func (m *Model) restoreCheckpoint(name string) {
+	c := m.loadCheckpoint("/restore", name)
+	if c == nil {
+		return
+	}
+	m.turns.Push(m.snapshotTurn("/restore " + name))
+
+	if len(c.Files) > 1 {
+		m.currentFiles = append([]CodeFile(nil), c.Files...)
+		m.currentCode = joinCodeFiles(c.Files)
+	} else if len(c.Files) == 1 {
+		m.currentFiles = []CodeFile{c.Files[0]}
+		m.currentCode = c.Files[0].Content
+	}
+	m.dod = c.DoD
+	m.examples = c.Examples
+	m.validated = c.Validated
+	m.lastConfidence = c.Confidence
+	m.failedResults = nil
+	m.lastResults = nil
+	m.savedPath = ""
+	m.manualEdit = false
+	m.resetEscalation()
+
+	m.addOutput(m.styles.Success.Render(fmt.Sprintf("Restored checkpoint %q from %s (%d file(s), %s)", c.Name, c.Created.Format("15:04:05"), len(c.Files), c.Status())))
+	if !c.Validated {
+		m.addOutput(m.styles.Dim.Render("  This code did not pass validation when checkpointed; /validate or ask for a fix."))
+	}
+}
+
+// diffCheckpoint handles /diff <name>: the changes from a checkpoint to the current code
+func (m *Model) diffCheckpoint(name string) {
+	c := m.loadCheckpoint("/diff", name)
+	if c == nil {
+		return
+	}
+	diff, added, removed := checkpointDiff(c, m.currentCodeFiles())
+	if diff == "" {
+		m.addOutput(m.styles.Dim.Render(fmt.Sprintf("No changes since checkpoint %q.", c.Name)))
+		return
+	}
+	m.addOutput("")
+	m.addOutput(m.styles.Info.Render(fmt.Sprintf("Changes since checkpoint %q (+%d -%d):", c.Name, added, removed)))
+	m.addOutput(m.renderCode(strings.TrimRight(diff, "\n"), "diff"))
+}
+
+// showFunctionalChecks prints the outcome of the reviewer's checks (nil = functional review off)
+func (m *Model) showFunctionalChecks(fc *FunctionalChecks) {
+	switch {
+	case fc == nil:
+	case !fc.Ran:
+		m.addOutput(m.styles.Dim.Render("  └─ Gate: functional... inconclusive (" + fc.Error + ")"))
+	case fc.AllPassed():
+		m.addOutput(m.styles.Success.Render(fmt.Sprintf("  └─ Gate: functional... %d/%d checks passed", fc.Passed, fc.Total)))
+	default:
+		m.addOutput(m.styles.Warning.Render(fmt.Sprintf("  └─ Gate: functional... %d/%d checks passed", fc.Passed, fc.Total)))
+		for _, f := range fc.Failures {
+			m.addOutput(m.styles.Dim.Render("     FAIL: " + f))
+		}
+		if fc.Error != "" {
+			m.addOutput(m.styles.Dim.Render("     " + fc.Error))
+		}
+	}
+}
+
+// showDivergences warns where the code departs from what the analysis said would be built
+func (m *Model) showDivergences(divergences []string, err error) {
+	if err != nil {
+		m.addOutput(m.styles.Dim.Render("  └─ Consistency with the analysis not checked: " + err.Error()))
+		return
+	}
+	if len(divergences) == 0 {
+		return
+	}
+	m.addOutput(m.styles.Warning.Render("  └─ The code departs from the analysis:"))
+	for _, d := range divergences {
+		m.addOutput(m.styles.Warning.Render("     - " + d))
 	}
-	m.addOutput("")
-	m.addOutput(fmt.Sprintf("  Confidence: %s", confidenceStyle.Render(fmt.Sprintf("%d%%", m.lastConfidence))))
-	if m.lastSummary != "" {
-		m.addOutput(fmt.Sprintf("  %s", m.styles.Dim.Render(m.lastSummary)))
+}
+
+// reviewThreshold is the review confidence below which a fix is requested
+func (m *Model) reviewThreshold() int {
+	if m.reviewMin < 1 {
+		return defaultReviewThreshold
 	}
+	return m.reviewMin
+}
 
-	// Build reveal lines with file separators for multi-file projects
-	m.revealLines = m.buildRevealLines()
-	m.revealCurrentLine = 0
-	m.state = StateRevealing
+// reviewPanel returns the reviewers for this session's review gate
+func (m *Model) reviewPanel() reviewPanel {
+	settings := m.config.Settings.Review
+	settings.Consensus, settings.Functional = m.reviewMode, m.reviewChecks
+	return newReviewPanel(settings, m.config.ReflectionModel, m.provider)
+}
 
-	// Start the reveal animation
-	return *m, func() tea.Msg {
-		return codeRevealMsg{
-			lines:       m.revealLines,
-			currentLine: 0,
+// startRegeneration discards the failing code and the conversation about it, and generates
+// the request afresh with a summary of the failures so far (regenerate strategy)
+// It counts as a fix attempt, so it climbs the escalation ladder like one
+func (m *Model) startRegeneration() (Model, tea.Cmd) {
+	failedFixes := m.fixesSinceFresh
+	m.advanceEscalation()
+	m.fixesSinceFresh = 0
+	model := m.getCurrentModel()
+
+	m.addOutput(m.styles.Warning.Render(fmt.Sprintf("%d fixes in a row failed; discarding the code and starting over...", failedFixes)))
+	m.conversation = []Message{{Role: "user", Content: regenerationPrompt(m.originalPrompt, &m.failures, m.lastValidationErrs)}}
+	m.pendingFix = nil
+
+	m.state = StateGenerating
+	m.statusMsg = fmt.Sprintf("Regenerating from scratch (%d/%d)…", m.totalFixAttempts, ladderAttempts(m.escalationLadder()))
+	m.startTime = time.Now()
+	m.tokenCount = 0
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.ctx = ctx
+	m.cancelFn = cancel
+
+	m.codeModel = model
+	return *m, tea.Batch(
+		m.spinner.Tick,
+		m.doGenerating(ctx, model),
+		tea.Tick(time.Second, func(t time.Time) tea.Msg { return tickMsg(t) }),
+	)
+}
+
+// startFix asks for a fix of the last validation errors, compacting the conversation
+// first when the token tracker or the model's context window says it has grown too large
+func (m *Model) startFix() (Model, tea.Cmd) {
+	// Code validated with /validate has no request to regenerate from
+	if regenerateDue(m.strategy, m.regenerateThreshold(), m.fixesSinceFresh) && !strings.HasPrefix(m.originalPrompt, directValidationPrefix) {
+		return m.startRegeneration()
+	}
+	if m.config.Settings.Tokens.AutoCompact && (m.tokenTracker.ShouldCompact() || m.nearContextLimit(m.getCurrentModel())) {
+		if old, _ := splitForCompaction(m.conversation); len(old) >= 2 {
+			return m.startCompaction(true)
 		}
 	}
+	return m.sendFix()
 }
 
-// Escalation helper methods
-
-// resetEscalation resets escalation state for a new generation cycle
-func (m *Model) resetEscalation() {
-	m.currentIteration = 0
-	m.currentModelIndex = -1
-	m.totalFixAttempts = 0
-	m.lastValidationErrs = ""
-	m.modelsUsed = nil
-	m.reviewFailures = 0
+// nearContextLimit reports whether the conversation fills most of the model's input window
+// Compacting then keeps the turns that fitContext would otherwise drop unsummarized
+func (m *Model) nearContextLimit(model string) bool {
+	limit := providerInputLimit(m.provider, model, m.config.MaxTokens)
+	if limit == 0 {
+		return false
+	}
+	need := estimateRequestTokens(m.withProjectRules(m.prompts.Generation()), m.conversation) + estimateTokens(m.currentCode+m.lastValidationErrs)
+	return need > limit*3/4
 }
 
-// canEscalate checks if we can attempt another fix
-func (m *Model) canEscalate() bool {
-	// Maximum total fix attempts across all models
-	const maxTotalAttempts = 15
-
-	return m.totalFixAttempts < maxTotalAttempts
+// addUsage records a response's tokens and notes turns left out to fit the context window
+func (m *Model) addUsage(result *GenerateResult) {
+	m.trackTokens(result.InputTokens, result.OutputTokens)
+	if result.Estimated {
+		m.tokenTracker.EstimatedTokens += result.InputTokens + result.OutputTokens
+	}
+	if result.Dropped > 0 {
+		m.addOutput(m.styles.Dim.Render(fmt.Sprintf("  Left out the %d oldest messages to fit the model's context window (/compact summarizes them instead)", result.Dropped)))
+	}
 }
 
-// getCurrentModel returns the current model to use for fixes
-// Escalates to more powerful models after several attempts
-func (m *Model) getCurrentModel() string {
-	// Escalation thresholds: try base model first, then escalate
-	// Attempts 1-5: base model (based on complexity)
-	// Attempts 6-10: Sonnet (if not already)
-	// Attempts 11-15: Opus
-
-	baseModel := m.getModelForComplexity(m.difficulty)
-	sonnet := "global.anthropic.claude-sonnet-4-5-20250929-v1:0"
-	opus := m.config.OracleModel
-
-	if m.totalFixAttempts <= 5 {
-		return baseModel
-	} else if m.totalFixAttempts <= 10 {
-		// Escalate to at least Sonnet
-		if m.difficulty == "EASY" {
-			return sonnet
-		}
-		return baseModel // MEDIUM/COMPLEX already at Sonnet or Opus
-	} else {
-		// Final escalation to Opus
-		return opus
+// trackTokens records tokens, showing (and notifying) the warning when the session nears its budget
+func (m *Model) trackTokens(input, output int) {
+	if ok, warning := m.tokenTracker.Add(input, output); ok && warning != "" {
+		m.addOutput(m.styles.Warning.Render(warning))
+		m.notify(NotifyBudgetWarning, warning)
 	}
 }
 
-// advanceEscalation increments the fix attempt counter
-func (m *Model) advanceEscalation() {
-	m.totalFixAttempts++
+// notify announces an event with a desktop notification and/or the terminal bell, as configured
+// in notifications settings. Validation events end the task they report on
+func (m *Model) notify(event, message string) {
+	var elapsed time.Duration
+	if !m.taskStart.IsZero() {
+		elapsed = time.Since(m.taskStart)
+	}
+	if event != NotifyBudgetWarning {
+		m.taskStart = time.Time{}
+	}
+
+	how := m.config.Settings.Notifications.announce(event, elapsed)
+	if how.Bell {
+		ringBell()
+	}
+	if !how.Desktop {
+		return
+	}
+	if m.originalPrompt != "" {
+		message += ": " + truncateError(m.originalPrompt, 80)
+	}
+	if err := desktopNotify("bjarne", message); err != nil {
+		tuiLog.Warn("desktop notification failed", "err", err)
+	}
 }
 
-func (m *Model) startFix() (Model, tea.Cmd) {
+// sendFix adds the fix request to the conversation and generates it
+func (m *Model) sendFix() (Model, tea.Cmd) {
 	m.advanceEscalation()
 
 	currentModel := m.getCurrentModel()
 
 	m.state = StateFixing
-	m.statusMsg = fmt.Sprintf("Fixing issues (%d/15)…", m.totalFixAttempts)
+	m.statusMsg = fmt.Sprintf("Fixing issues (%d/%d)…", m.totalFixAttempts, ladderAttempts(m.escalationLadder()))
 	m.startTime = time.Now()
 	m.tokenCount = 0
 
-	// Add fix request to conversation with current code and errors
-	fixPrompt := fmt.Sprintf(IterationPromptTemplate, m.currentCode, m.lastValidationErrs)
+	// Add fix request to conversation with current code and errors, and any fixes that
+	// cleared the same errors before. Multi-file projects are sent per file so the fix can
+	// patch only what failed
+	errs := m.lastValidationErrs
+	if hints := m.fixKnowledge.Hints(m.failureSigs); hints != "" {
+		errs += "\n" + hints
+		m.addOutput(m.styles.Dim.Render("  Including fixes that worked before for these errors"))
+	}
+	fixPrompt := m.prompts.Iteration(m.currentCode, errs)
+	if len(m.currentFiles) > 1 {
+		fixPrompt = m.prompts.MultiFileIteration(m.currentFiles, errs)
+	}
 	m.conversation = append(m.conversation, Message{Role: "user", Content: fixPrompt})
 
 	ctx, cancel := context.WithCancel(context.Background())
 	m.ctx = ctx
 	m.cancelFn = cancel
 
+	m.codeModel = currentModel
 	return *m, tea.Batch(
 		m.spinner.Tick,
 		m.doFix(ctx, currentModel),
@@ -1323,6 +3834,80 @@ func (m *Model) startFix() (Model, tea.Cmd) {
 	)
 }
 
+// applyMultiFileFix merges the files returned by a fix into the project and re-validates
+func (m *Model) applyMultiFileFix(response string) (Model, tea.Cmd) {
+	files, changed := applyFilePatches(m.currentFiles, extractMultipleFiles(response))
+	if len(changed) == 0 {
+		m.addOutput(m.styles.Warning.Render("Fix response changed no project files, retrying..."))
+		if m.canEscalate() {
+			return m.startFix()
+		}
+		m.showEscalationExhausted()
+		m.resetEscalation()
+		m.state = StateInput
+		m.textarea.Focus()
+		return *m, nil
+	}
+
+	m.addOutput(m.styles.Dim.Render("  Patched " + strings.Join(changed, ", ")))
+	m.setCodeFiles(files)
+	m.enforceNamingConventions()
+	return m.startValidation()
+}
+
+// startCompaction summarizes all but the latest exchange with the reflection model
+func (m *Model) startCompaction(fix bool) (Model, tea.Cmd) {
+	old, _ := splitForCompaction(m.conversation)
+
+	m.state = StateCompacting
+	m.statusMsg = fmt.Sprintf("Compacting %d earlier messages…", len(old))
+	m.startTime = time.Now()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.ctx = ctx
+	m.cancelFn = cancel
+
+	messages := compactionRequest(old)
+	return *m, tea.Batch(
+		m.spinner.Tick,
+		func() tea.Msg {
+			result, err := m.provider.Generate(ctx, m.config.ReflectionModel, CompactionSystemPrompt, messages, m.config.MaxTokens)
+			return compactDoneMsg{result: result, messages: len(old), fix: fix, err: err}
+		},
+		tea.Tick(time.Second, func(t time.Time) tea.Msg { return tickMsg(t) }),
+	)
+}
+
+// finishCompaction swaps the summarized turns for the summary, then resumes the fix if one was pending
+func (m *Model) finishCompaction(msg compactDoneMsg) (Model, tea.Cmd) {
+	if msg.err != nil {
+		if m.ctx.Err() == context.Canceled {
+			return *m, nil
+		}
+		m.addOutput(m.styles.Warning.Render("Compaction failed: " + msg.err.Error()))
+		if msg.fix {
+			return m.sendFix()
+		}
+		m.state = StateInput
+		m.textarea.Focus()
+		return *m, nil
+	}
+
+	m.addUsage(msg.result)
+	_, recent := splitForCompaction(m.conversation)
+	m.conversation = compactedConversation(msg.result.Text, recent, m.currentCode, m.lastValidationErrs)
+	m.tokenTracker.Compacted()
+	m.addOutput(m.styles.Dim.Render(fmt.Sprintf("  Compacted %d earlier messages (~%d tokens) into a ~%d-token summary",
+		msg.messages, msg.result.InputTokens, msg.result.OutputTokens)))
+
+	if msg.fix {
+		return m.sendFix()
+	}
+	m.state = StateInput
+	m.textarea.Focus()
+	return *m, nil
+}
+
 func (m *Model) doFix(ctx context.Context, model string) tea.Cmd {
 	return func() tea.Msg {
 		systemPrompt := m.buildSystemPrompt()
@@ -1332,6 +3917,7 @@ func (m *Model) doFix(ctx context.Context, model string) tea.Cmd {
 }
 
 func (m *Model) showEscalationExhausted() {
+	m.notify(NotifyEscalationExhausted, "All fix attempts exhausted")
 	m.addOutput("")
 	m.addOutput(m.styles.Error.Render("All fix attempts exhausted."))
 	m.addOutput("")
@@ -1356,9 +3942,9 @@ func (m *Model) showValidationSuccess(results []ValidationResult) float64 {
 	m.addOutput("")
 
 	// Success box header
-	m.addOutput(strings.Repeat("=", 80))
+	m.addOutput(rule())
 	m.addOutput(m.styles.Success.Render("SUCCESS! Validated code:"))
-	m.addOutput(strings.Repeat("=", 80))
+	m.addOutput(rule())
 	m.addOutput("```cpp")
 
 	// Return total time - animation will handle the rest
@@ -1382,92 +3968,436 @@ func (m *Model) showValidationFailure(results []ValidationResult, isFinal bool)
 		return
 	}
 
-	// Final failure - show code
-	m.addOutput("")
-	m.addOutput(strings.Repeat("=", 80))
-	m.addOutput(m.styles.Error.Render("FAILED! Validation did not pass."))
-	m.addOutput(strings.Repeat("=", 80))
-	m.addOutput("")
-	m.addOutput(m.styles.Warning.Render("Generated code (failed validation):"))
+	// Final failure - show code
+	m.addOutput("")
+	m.addOutput(rule())
+	m.addOutput(m.styles.Error.Render("FAILED! Validation did not pass."))
+	m.addOutput(rule())
+	m.addOutput("")
+	if findings := m.failedFindings(results); len(findings) > 0 {
+		m.addOutput(m.styles.Warning.Render("Findings:"))
+		for i, f := range findings {
+			if i == watchMaxFindings {
+				m.addOutput(m.styles.Dim.Render(fmt.Sprintf("  ... %d more", len(findings)-i)))
+				break
+			}
+			m.addOutput("  " + formatFinding(m.workspacePaths.Resolve(f.File), f))
+		}
+		m.addOutput("")
+	}
+	m.addOutput(m.styles.Warning.Render("Generated code (failed validation):"))
+
+	// Show full code (multi-file aware)
+	if len(m.currentFiles) > 1 {
+		for _, f := range m.currentFiles {
+			m.addOutput("")
+			m.addOutput(m.styles.Info.Render(fmt.Sprintf("// === %s ===", f.Filename)))
+			m.addOutput("```cpp")
+			m.addOutput(m.renderCode(f.Content, f.Filename))
+			m.addOutput("```")
+		}
+	} else {
+		m.addOutput("```cpp")
+		m.addOutput(m.renderCode(m.currentCode, "cpp"))
+		m.addOutput("```")
+	}
+	m.addOutput("")
+	m.addOutput("You can refine your request or ask bjarne to fix specific issues.")
+}
+
+// buildRevealLines creates the lines to reveal, with file separators for multi-file projects
+func (m *Model) buildRevealLines() []string {
+	if len(m.currentFiles) <= 1 {
+		// Single file - just split by lines
+		return strings.Split(m.renderCode(m.currentCode, "cpp"), "\n")
+	}
+
+	// Multi-file project - add file headers
+	var lines []string
+	for i, f := range m.currentFiles {
+		if i > 0 {
+			lines = append(lines, "```")
+			lines = append(lines, "")
+		}
+		lines = append(lines, m.styles.Info.Render(fmt.Sprintf("// === %s ===", f.Filename)))
+		if i > 0 {
+			lines = append(lines, "```cpp")
+		}
+		lines = append(lines, strings.Split(m.renderCode(f.Content, f.Filename), "\n")...)
+	}
+	return lines
+}
+
+// renderCode returns code highlighted for display, or unchanged when highlighting is off
+func (m *Model) renderCode(code, lang string) string {
+	if !m.highlight {
+		return code
+	}
+	return highlightCode(code, lang, m.config.Theme.highlight)
+}
+
+// autoSaveToHistory saves validated code to ~/.bjarne/history/ with timestamp
+func (m *Model) autoSaveToHistory() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	historyDir := filepath.Join(homeDir, ".bjarne", "history")
+	if err := os.MkdirAll(historyDir, 0750); err != nil {
+		return ""
+	}
+
+	// Name with the timestamp and this session's suffix, so concurrent sessions never collide
+	now := time.Now()
+
+	if len(m.currentFiles) > 1 {
+		// Multi-file: save as directory
+		dirName := historyName(now, m.sessionID, true)
+		dirPath := filepath.Join(historyDir, dirName)
+		if err := os.MkdirAll(dirPath, 0750); err != nil {
+			return ""
+		}
+		for _, f := range m.currentFiles {
+			filePath := filepath.Join(dirPath, f.Filename)
+			_ = os.WriteFile(filePath, []byte(f.Content), 0600)
+		}
+		m.recordHistory(historyDir, dirName)
+		return dirPath
+	}
+
+	// Single file
+	filename := historyName(now, m.sessionID, false)
+	filePath := filepath.Join(historyDir, filename)
+	if err := os.WriteFile(filePath, []byte(m.currentCode), 0600); err != nil {
+		return ""
+	}
+	m.recordHistory(historyDir, filename)
+	return filePath
+}
+
+// recordHistory describes an auto-saved output in the history manifest (/history)
+func (m *Model) recordHistory(dir, id string) {
+	names := []string{"code.cpp"}
+	if len(m.currentFiles) > 0 {
+		names = make([]string, len(m.currentFiles))
+		for i, f := range m.currentFiles {
+			names[i] = f.Filename
+		}
+	}
+	_ = loadHistoryManifest(dir).Add(HistoryEntry{
+		ID:         id,
+		Created:    time.Now(),
+		Prompt:     m.originalPrompt,
+		Files:      names,
+		Model:      m.codeModel,
+		Gates:      checkpointGates(m.lastResults),
+		Confidence: m.lastConfidence,
+		Session:    m.sessionID,
+	})
+}
+
+// maxHistoryListed bounds the entries /history lists
+const maxHistoryListed = 20
+
+// historyCommand handles /history [show|validate|restore|delete <n>]
+// It returns true when the loaded entry should be re-validated
+func (m *Model) historyCommand(args []string) bool {
+	dir, err := historyDir()
+	if err != nil {
+		m.addOutput(m.styles.Error.Render("Error: " + err.Error()))
+		return false
+	}
+	manifest := loadHistoryManifest(dir)
+
+	if len(args) == 0 || strings.ToLower(args[0]) == "list" {
+		entries := manifest.List()
+		if len(entries) == 0 {
+			m.addOutput("No auto-saved code yet.")
+			return false
+		}
+		m.addOutput(fmt.Sprintf("Auto-saved code in %s:", dir))
+		for i, e := range entries {
+			if i == maxHistoryListed {
+				m.addOutput(m.styles.Dim.Render(fmt.Sprintf("  ... and %d older", len(entries)-maxHistoryListed)))
+				break
+			}
+			m.addOutput(fmt.Sprintf("  %2d  %s  %s", i+1, e.Created.Format("2006-01-02 15:04"), historyPromptSummary(e)))
+			var details []string
+			if len(e.Files) > 1 {
+				details = append(details, fmt.Sprintf("%d files", len(e.Files)))
+			}
+			if gates := e.GateSummary(); gates != "" {
+				details = append(details, gates)
+			}
+			if e.Model != "" {
+				details = append(details, shortModelName(e.Model))
+			}
+			if len(details) > 0 {
+				m.addOutput(m.styles.Dim.Render("      " + strings.Join(details, ", ")))
+			}
+		}
+		m.addOutput(m.styles.Dim.Render("/history show|validate|restore|delete <n>"))
+		return false
+	}
+
+	if len(args) < 2 {
+		m.addOutput(historyUsage)
+		return false
+	}
+	action := strings.ToLower(args[0])
+	entry, err := manifest.Find(args[1])
+	if err != nil {
+		m.addOutput(m.styles.Error.Render("Error: " + err.Error()))
+		return false
+	}
+
+	if action == "delete" {
+		if err := manifest.Delete(entry); err != nil {
+			m.addOutput(m.styles.Error.Render("Error: " + err.Error()))
+			return false
+		}
+		m.addOutput(m.styles.Success.Render("Deleted " + entry.ID))
+		return false
+	}
+
+	files, err := manifest.Files(entry)
+	if err != nil {
+		m.addOutput(m.styles.Error.Render("Error: " + err.Error()))
+		return false
+	}
+	switch action {
+	case "show":
+		m.addOutput("")
+		m.addOutput(m.styles.Warning.Render(fmt.Sprintf("%s: %s", entry.ID, historyPromptSummary(entry))))
+		for _, f := range files {
+			m.addOutput("")
+			if len(files) > 1 {
+				m.addOutput(m.styles.Info.Render(fmt.Sprintf("// === %s ===", f.Filename)))
+			}
+			m.addOutput("```cpp")
+			m.addOutput(m.renderCode(f.Content, f.Filename))
+			m.addOutput("```")
+		}
+		return false
+
+	case "restore", "validate":
+		m.turns.Push(m.snapshotTurn("/history " + action + " " + args[1]))
+		m.currentFiles = files
+		m.currentCode = joinCodeFiles(files)
+		if len(files) == 1 {
+			m.currentCode = files[0].Content
+		}
+		if entry.Prompt != "" {
+			m.originalPrompt = entry.Prompt
+		}
+		m.codeModel = entry.Model
+		m.lastConfidence = entry.Confidence
+		m.failedResults = nil
+		m.lastResults = nil
+		m.savedPath = ""
+		m.manualEdit = false
+		m.resetEscalation()
+		if action == "validate" {
+			m.validated = false
+			m.addOutput("")
+			m.addOutput(m.styles.Info.Render(fmt.Sprintf("Re-validating %s...", entry.ID)))
+			return true
+		}
+		// Only code that passed every gate is auto-saved
+		m.validated = true
+		m.addOutput(m.styles.Success.Render(fmt.Sprintf("Restored %s (%d file(s)); /undo returns to the previous code", entry.ID, len(files))))
+		return false
+	}
+
+	m.addOutput(historyUsage)
+	return false
+}
+
+// historyUsage describes the /history actions
+const historyUsage = "Usage: /history [show|validate|restore|delete <n>]"
+
+// historyPromptSummary is a one-line description of an entry's request
+func historyPromptSummary(e HistoryEntry) string {
+	if e.Prompt == "" {
+		return e.ID + " (no metadata)"
+	}
+	return truncateError(strings.Join(strings.Fields(e.Prompt), " "), 60)
+}
+
+// startSave handles /save [--force] [file|dir]
+// It returns true when the save waits for confirmation because it would overwrite files
+func (m *Model) startSave(args []string) bool {
+	force := false
+	var target string
+	for _, arg := range args {
+		switch arg {
+		case "--force", "-f":
+			force = true
+		default:
+			target = arg
+		}
+	}
+	if m.currentCode == "" && len(m.currentFiles) == 0 {
+		m.addOutput(m.styles.Error.Render("No code to save."))
+		return false
+	}
+
+	save := &pendingSave{}
+	switch {
+	case len(m.currentFiles) > 1 && target != "":
+		if strings.HasSuffix(target, "/") || strings.HasSuffix(target, "\\") || !strings.Contains(target, ".") {
+			// Save all files to this directory
+			for _, f := range m.currentFiles {
+				save.writes = append(save.writes, saveWrite{Path: filepath.Join(target, f.Filename), Content: f.Content})
+			}
+			save.savedPath = target
+			save.paths = PathMap{Dir: target}
+		} else {
+			// Single filename - save combined (backwards compatible)
+			save.writes = []saveWrite{{Path: target, Content: m.currentCode}}
+			save.savedPath = target
+			save.note = "(all files combined into single file)" // Lines no longer match the generated files
+		}
 
-	// Show full code (multi-file aware)
-	if len(m.currentFiles) > 1 {
+	case len(m.currentFiles) > 1:
+		// No target specified - place the files with the save templates or the project layout
+		paths := planSavePaths(m.currentFiles, m.config.Settings.Save, ".")
 		for _, f := range m.currentFiles {
-			m.addOutput("")
-			m.addOutput(m.styles.Info.Render(fmt.Sprintf("// === %s ===", f.Filename)))
-			m.addOutput("```cpp")
-			m.addOutput(f.Content)
-			m.addOutput("```")
+			save.writes = append(save.writes, saveWrite{Path: paths[f.Filename], Content: f.Content})
 		}
-	} else {
-		m.addOutput("```cpp")
-		m.addOutput(m.currentCode)
-		m.addOutput("```")
+		save.savedPath = "."
+		save.paths = PathMap{Files: paths}
+
+	default:
+		filename := target
+		if filename == "" {
+			// No target specified - name the file after its code and place it like a project file
+			name := saveFileName(m.currentCode)
+			if len(m.currentFiles) == 1 && m.currentFiles[0].Filename != "code.cpp" {
+				name = m.currentFiles[0].Filename
+			}
+			filename = planSavePaths([]CodeFile{{Filename: name}}, m.config.Settings.Save, ".")[name]
+		}
+		save.writes = []saveWrite{{Path: filename, Content: m.currentCode}}
+		save.savedPath = filename
+		save.paths = PathMap{Files: map[string]string{"code.cpp": filename}}
 	}
-	m.addOutput("")
-	m.addOutput("You can refine your request or ask bjarne to fix specific issues.")
-}
 
-// buildRevealLines creates the lines to reveal, with file separators for multi-file projects
-func (m *Model) buildRevealLines() []string {
-	if len(m.currentFiles) <= 1 {
-		// Single file - just split by lines
-		return strings.Split(m.currentCode, "\n")
+	if findOverwrites(save.writes) == 0 || force {
+		m.commitSave(save)
+		return false
 	}
 
-	// Multi-file project - add file headers
-	var lines []string
-	for i, f := range m.currentFiles {
-		if i > 0 {
-			lines = append(lines, "```")
-			lines = append(lines, "")
-		}
-		lines = append(lines, m.styles.Info.Render(fmt.Sprintf("// === %s ===", f.Filename)))
-		if i > 0 {
-			lines = append(lines, "```cpp")
-		}
-		lines = append(lines, strings.Split(f.Content, "\n")...)
+	m.addOutput("")
+	m.addOutput(m.styles.Warning.Render("This save would overwrite existing files:"))
+	m.addOutput(m.renderCode(strings.TrimRight(overwritePreview(save.writes), "\n"), "diff"))
+	if mode, _ := parseBackupMode(m.config.Settings.Save.Backup); mode != BackupOff {
+		m.addOutput(m.styles.Dim.Render("  The current versions will be backed up (" + mode + ")."))
 	}
-	return lines
+	m.pendingSave = save
+	m.state = StateConfirmSave
+	return true
 }
 
-// autoSaveToHistory saves validated code to ~/.bjarne/history/ with timestamp
-func (m *Model) autoSaveToHistory() string {
-	homeDir, err := os.UserHomeDir()
+// commitSave writes a save's files, backing up the ones it overwrites
+func (m *Model) commitSave(save *pendingSave) {
+	mode, err := parseBackupMode(m.config.Settings.Save.Backup)
 	if err != nil {
-		return ""
+		mode = BackupBak
 	}
-
-	historyDir := filepath.Join(homeDir, ".bjarne", "history")
-	if err := os.MkdirAll(historyDir, 0750); err != nil {
-		return ""
+	stashDir := ""
+	if mode == BackupStash {
+		if stashDir, err = stashDirFor(time.Now()); err != nil {
+			mode = BackupBak
+		}
 	}
 
-	// Generate filename with timestamp
-	timestamp := time.Now().Format("2006-01-02_150405")
-	var filename string
+	m.addOutput("")
+	var written []string
+	for _, w := range save.writes {
+		if w.Overwrites {
+			backup, err := backupFile(w, mode, stashDir)
+			if err != nil {
+				m.addOutput(m.styles.Error.Render(fmt.Sprintf("Not saving %s: %s", w.Path, err.Error())))
+				continue
+			}
+			if backup != "" {
+				m.addOutput(m.styles.Dim.Render(fmt.Sprintf("  Backed up %s to %s", w.Path, backup)))
+			}
+		}
+		if err := saveToPath(w.Path, w.Content); err != nil {
+			m.addOutput(m.styles.Error.Render(fmt.Sprintf("Error saving %s: %s", w.Path, err.Error())))
+			continue
+		}
+		written = append(written, w.Path)
+		if len(save.writes) > 1 {
+			m.addOutput(m.styles.Success.Render(fmt.Sprintf("✓ Saved %s", w.Path)))
+			continue
+		}
+		m.addOutput(m.styles.Success.Render("✓ Saved to " + w.Path))
+		if save.note != "" {
+			m.addOutput(m.styles.Dim.Render("  " + save.note))
+		} else {
+			m.addOutput(m.styles.Dim.Render(fmt.Sprintf("  %d bytes written", len(w.Content))))
+		}
+	}
+	if len(written) == len(save.writes) {
+		m.savedPath = save.savedPath // Mark as saved
+		m.workspacePaths = save.paths
+	}
+	if m.config.Settings.Save.Index {
+		m.indexSavedFiles(written)
+	}
+}
 
-	if len(m.currentFiles) > 1 {
-		// Multi-file: save as directory
-		dirName := fmt.Sprintf("%s_project", timestamp)
-		dirPath := filepath.Join(historyDir, dirName)
-		if err := os.MkdirAll(dirPath, 0750); err != nil {
-			return ""
+// indexSavedFiles adds files written by /save to the workspace indexes, so the next prompt
+// can refer to them without another /init
+func (m *Model) indexSavedFiles(paths []string) {
+	var indexed []string
+	root := "."
+	if m.workspaceIndex != nil {
+		root = m.workspaceIndex.RootPath
+		if updated := m.workspaceIndex.UpdateFiles(paths); len(updated) > 0 {
+			if err := SaveIndex(m.workspaceIndex, root); err != nil {
+				m.addOutput(m.styles.Warning.Render("  Failed to update the workspace index: " + err.Error()))
+			} else {
+				indexed = append(indexed, fmt.Sprintf("%d file(s) in the workspace index", len(updated)))
+			}
 		}
-		for _, f := range m.currentFiles {
-			filePath := filepath.Join(dirPath, f.Filename)
-			_ = os.WriteFile(filePath, []byte(f.Content), 0600)
+	}
+	if m.vectorIndex != nil {
+		chunks, err := m.vectorIndex.IndexFiles(context.Background(), root, paths)
+		if err != nil {
+			m.addOutput(m.styles.Warning.Render("  Failed to update the semantic index: " + err.Error()))
+		} else if chunks > 0 {
+			indexed = append(indexed, fmt.Sprintf("%d chunk(s) in the semantic index", chunks))
 		}
-		return dirPath
 	}
+	if len(indexed) > 0 {
+		m.addOutput(m.styles.Dim.Render("  Indexed " + strings.Join(indexed, " and ")))
+	}
+}
 
-	// Single file
-	filename = fmt.Sprintf("%s.cpp", timestamp)
-	filePath := filepath.Join(historyDir, filename)
-	if err := os.WriteFile(filePath, []byte(m.currentCode), 0600); err != nil {
-		return ""
+// handleSaveConfirmKey processes the answer to the overwrite prompt
+func (m *Model) handleSaveConfirmKey(msg tea.KeyMsg) (Model, tea.Cmd) {
+	save := m.pendingSave
+	answer := ""
+	if msg.Type == tea.KeyRunes {
+		answer = strings.ToLower(string(msg.Runes))
 	}
-	return filePath
+	switch {
+	case answer == "y":
+		m.commitSave(save)
+	case answer == "n" || msg.Type == tea.KeyEsc || msg.Type == tea.KeyEnter:
+		m.addOutput(m.styles.Dim.Render("Save cancelled; nothing was written."))
+	default:
+		return *m, nil
+	}
+	m.pendingSave = nil
+	m.state = StateInput
+	m.textarea.Focus()
+	return *m, textarea.Blink
 }
 
 // hasUnsavedCode returns true if there's validated code that hasn't been explicitly saved
@@ -1522,13 +4452,43 @@ func (m Model) handleCommand(input string) (Model, tea.Cmd) {
 		m.addOutput("Commands:")
 		m.addOutput("  /help, /h              Show this help")
 		m.addOutput("  /config [category]     Configure validators (game, hft, embedded, security, perf)")
-		m.addOutput("  /debug                 Toggle debug logging (saves validation errors to file)")
+		m.addOutput("  /config models         Show/edit complexity models and the escalation ladder")
+		m.addOutput("  /config image [name]   Show/switch validator image profiles (pin, unpin, <name> project)")
+		m.addOutput("  /settings              Edit models, validation, tokens, image, theme and notifications in a form")
+		m.addOutput("  /image update|rollback Pull a newer validator image, or return to the previous one")
+		m.addOutput("  /debug                 Toggle debug logging (code, gate output and provider calls to the log file)")
 		m.addOutput("  /init                  Index current directory for context-aware generation")
 		m.addOutput("  /validate <file>, /v   Validate existing file without AI generation")
 		m.addOutput("  /save [file|dir], /s   Save code (multi-file: /save dir/ or /save)")
+		m.addOutput("  /save --force [...]    Overwrite existing files without asking (they are still backed up)")
+		m.addOutput("  /undo, /redo           Roll back the last prompt and its responses, or reapply it")
+		m.addOutput("  /checkpoint [name]     Snapshot the code under a name (no name = list checkpoints)")
+		m.addOutput("  /restore <name>        Return to a checkpoint's code, DoD and validation status")
+		m.addOutput("  /diff <name>           Show the changes since a checkpoint")
+		m.addOutput("  /history [action <n>]  Browse auto-saved code (show, validate, restore, delete)")
+		m.addOutput("  /export <file.md|.json> Write the conversation, code versions and gate results")
+		m.addOutput("  /import <file>         Continue a session from an exported transcript")
+		m.addOutput("  /recover [discard]     Continue (or delete) the session saved when bjarne quit mid-task")
+		m.addOutput("  /template [action]     Reusable prompts with {{placeholders}} (list, show, use, save, delete)")
 		m.addOutput("  /clear, /c             Clear conversation and start fresh")
 		m.addOutput("  /code, /show           Show last generated code")
+		m.addOutput("  /edit, /e              Edit code in $EDITOR and re-validate")
+		m.addOutput("  /bestof [n|off]        Generate n candidates in parallel and keep the best")
+		m.addOutput("  /strategy [fix|regenerate [n]] Patch failures, or start over after n failed fixes")
+		m.addOutput("  /review [threshold|consensus|functional|consistency] Configure the review gate")
+		m.addOutput("  /compare <a> <b> [req] Run a request through two models and compare results")
+		m.addOutput("  /highlight             Toggle syntax highlighting")
+		m.addOutput("  /theme [name|preview]  Switch the color theme, or preview themes (also custom ones)")
+		m.addOutput("  /model [name|auto]     Pin the generation model, or return to automatic selection")
+		m.addOutput("  /temp [value|default]  Set temperature (also: top-p, seed, reset)")
+		m.addOutput("  /prompts [reload]      Show or reload prompt overrides and BJARNE.md")
+		m.addOutput("  /tests [add|set|rm]    Show or edit the example tests checked by validation")
+		m.addOutput("  /plan [add|rm|deps|go] Show or adjust the file plan for a COMPLEX project")
+		m.addOutput("  /stdin [set|load|args] Show or set the stdin and arguments the program is run with")
+		m.addOutput("  /suppress [n|all|list] Accept clang-tidy/cppcheck findings of the last failed run")
+		m.addOutput("  /image <path>          Attach an image (diagram, photo) to the next prompt")
 		m.addOutput("  /tokens, /t            Show token usage")
+		m.addOutput("  /compact               Summarize older turns to free up the token budget")
 		m.addOutput("  /quit, /q              Exit bjarne")
 		m.addOutput("")
 		m.addOutput("Natural Language:")
@@ -1536,6 +4496,14 @@ func (m Model) handleCommand(input string) (Model, tea.Cmd) {
 		m.addOutput("  \"start fresh\"          Same as /clear")
 		m.addOutput("  \"show code\"            Same as /code")
 		m.addOutput("")
+		m.addOutput("Keys:")
+		m.addOutput("  Alt+Enter              Insert a newline (multi-line prompt)")
+		m.addOutput("  Up/Down                Recall previous prompts")
+		m.addOutput("  Tab                    Complete commands, validator names and paths")
+		m.addOutput("")
+		m.addOutput("Attachments:")
+		m.addOutput("  @path/to/file.h        Include a file in the request context")
+		m.addOutput("")
 		m.addOutput("Indicators:")
 		m.addOutput("  [*] >                  Unsaved validated code (auto-saved to ~/.bjarne/history/)")
 		m.addOutput("")
@@ -1587,6 +4555,9 @@ func (m Model) handleCommand(input string) (Model, tea.Cmd) {
 		m.addOutput(fmt.Sprintf("  Lines:     %d", index.Summary.TotalLines))
 		m.addOutput("")
 		m.addOutput(m.styles.Dim.Render("Saved to " + IndexFileName))
+		if conv := index.DetectNamingConventions(); conv != nil {
+			m.addOutput(fmt.Sprintf("  Naming:    %s", conv.Summary()))
+		}
 
 		// Build vector index for semantic search
 		m.addOutput("")
@@ -1646,23 +4617,36 @@ func (m Model) handleCommand(input string) (Model, tea.Cmd) {
 		m.addOutput(m.styles.Info.Render("Context will be included in code generation prompts."))
 
 	case "/config":
+		if len(parts) > 1 && strings.EqualFold(parts[1], "models") {
+			m.configureModels(parts[2:])
+			break
+		}
+		if len(parts) > 1 && strings.EqualFold(parts[1], "image") {
+			if len(parts) == 3 && strings.EqualFold(parts[2], "update") {
+				return m.startImageUpdate()
+			}
+			if len(parts) == 3 && strings.EqualFold(parts[2], "rollback") {
+				return m.startImageRollback()
+			}
+			m.configureImage(parts[2:])
+			break
+		}
 		m.showValidatorConfig(parts[1:])
 
+	case "/settings":
+		m.openSettings()
+		return m, nil
+
+	case "/theme":
+		m.themeCommand(parts[1:])
+
 	case "/debug":
-		m.debugMode = !m.debugMode
+		on := !debugLogging.Load()
+		path := setDebugLogging(on)
 		m.addOutput("")
-		if m.debugMode {
-			// Set up debug log path
-			homeDir, err := os.UserHomeDir()
-			if err != nil {
-				m.debugLogPath = "bjarne-debug.log"
-			} else {
-				debugDir := filepath.Join(homeDir, ".bjarne")
-				_ = os.MkdirAll(debugDir, 0750)
-				m.debugLogPath = filepath.Join(debugDir, "debug.log")
-			}
-			m.addOutput(m.styles.Success.Render("Debug logging enabled"))
-			m.addOutput(fmt.Sprintf("Log file: %s", m.styles.Dim.Render(m.debugLogPath)))
+		if on {
+			m.addOutput(m.styles.Success.Render("Debug logging enabled for every subsystem"))
+			m.addOutput(fmt.Sprintf("Log file: %s", m.styles.Dim.Render(path)))
 		} else {
 			m.addOutput(m.styles.Warning.Render("Debug logging disabled"))
 		}
@@ -1674,14 +4658,29 @@ func (m Model) handleCommand(input string) (Model, tea.Cmd) {
 		m.validated = false
 		m.analyzed = false
 		m.originalPrompt = ""
+		m.analysis = ""
 		m.examples = nil
 		m.dod = nil
+		m.awaitingDoD = false
+		m.testsPending = false
+		m.pendingPrompt = ""
+		m.plan = nil
+		m.planPending = false
+		m.planSkipped = false
+		m.scaffoldQueue = nil
 		m.difficulty = ""
 		m.intent = ""
 		m.savedPath = ""
+		m.workspacePaths = PathMap{}
+		m.failureSigs = nil
+		m.pendingFix = nil
 		m.historyPath = ""
+		m.previousFiles = nil
+		m.pendingImages = nil
 		m.resetEscalation()
 		m.tokenTracker.Reset()
+		m.turns.Clear()
+		m.versions = nil
 		m.workspaceIndex = nil // Also clear the index on /clear
 		if m.vectorIndex != nil {
 			_ = m.vectorIndex.Close()
@@ -1689,6 +4688,71 @@ func (m Model) handleCommand(input string) (Model, tea.Cmd) {
 		}
 		m.addOutput("Conversation cleared.")
 
+	case "/undo":
+		s, ok := m.turns.Undo(m.snapshotTurn(""))
+		if !ok {
+			m.addOutput("Nothing to undo.")
+			break
+		}
+		m.restoreTurn(s)
+		m.addOutput(m.styles.Success.Render("Undid: " + truncateError(s.prompt, 60)))
+		m.showRestoredTurn()
+
+	case "/redo":
+		s, ok := m.turns.Redo(m.snapshotTurn(""))
+		if !ok {
+			m.addOutput("Nothing to redo.")
+			break
+		}
+		m.restoreTurn(s)
+		m.addOutput(m.styles.Success.Render("Redid: " + truncateError(s.prompt, 60)))
+		m.showRestoredTurn()
+
+	case "/history":
+		if m.historyCommand(parts[1:]) {
+			m.textarea.Reset()
+			m.textarea.Blur()
+			return m.startValidation()
+		}
+
+	case "/template", "/templates":
+		m.templateCommand(input)
+
+	case "/export":
+		if len(parts) < 2 {
+			m.addOutput("Usage: /export <file.md|file.json>")
+			break
+		}
+		if err := WriteTranscript(parts[1], m.transcript()); err != nil {
+			m.addOutput(m.styles.Error.Render("Error: " + err.Error()))
+			break
+		}
+		m.addOutput(m.styles.Success.Render(fmt.Sprintf("✓ Exported %d message(s) and %d code version(s) to %s", len(m.conversation), len(m.versions), parts[1])))
+
+	case "/import":
+		if len(parts) < 2 {
+			m.addOutput("Usage: /import <file.md|file.json>")
+			break
+		}
+		t, err := ReadTranscript(parts[1])
+		if err != nil {
+			m.addOutput(m.styles.Error.Render("Error: " + err.Error()))
+			break
+		}
+		m.importTranscript(t, parts[1])
+
+	case "/recover":
+		m.recoverSession(len(parts) > 1 && strings.EqualFold(parts[1], "discard"))
+
+	case "/checkpoint":
+		m.checkpointCommand(strings.Join(parts[1:], " "))
+
+	case "/restore":
+		m.restoreCheckpoint(strings.Join(parts[1:], " "))
+
+	case "/diff":
+		m.diffCheckpoint(strings.Join(parts[1:], " "))
+
 	case "/code", "/show":
 		if m.currentCode == "" && len(m.currentFiles) == 0 {
 			m.addOutput("No code generated yet.")
@@ -1700,92 +4764,315 @@ func (m Model) handleCommand(input string) (Model, tea.Cmd) {
 				m.addOutput("")
 				m.addOutput(m.styles.Info.Render(fmt.Sprintf("// === %s ===", f.Filename)))
 				m.addOutput("```cpp")
-				m.addOutput(f.Content)
+				m.addOutput(m.renderCode(f.Content, f.Filename))
 				m.addOutput("```")
 			}
 		} else {
 			m.addOutput("")
 			m.addOutput(m.styles.Warning.Render("Last generated code:"))
 			m.addOutput("```cpp")
-			m.addOutput(m.currentCode)
+			m.addOutput(m.renderCode(m.currentCode, "cpp"))
 			m.addOutput("```")
 		}
 
-	case "/save", "/s":
+	case "/edit", "/e":
 		if m.currentCode == "" && len(m.currentFiles) == 0 {
-			m.addOutput(m.styles.Error.Render("No code to save."))
-		} else if len(m.currentFiles) > 1 {
-			// Multi-file project - save all files
-			if len(parts) >= 2 {
-				// User specified a directory or single filename
-				targetDir := parts[1]
-				// Check if it looks like a directory
-				if strings.HasSuffix(targetDir, "/") || strings.HasSuffix(targetDir, "\\") || !strings.Contains(targetDir, ".") {
-					// Save all files to this directory
-					if err := os.MkdirAll(targetDir, 0750); err != nil {
-						m.addOutput(m.styles.Error.Render("Error creating directory: " + err.Error()))
-						break
-					}
-					m.addOutput("")
-					savedCount := 0
-					for _, f := range m.currentFiles {
-						filePath := filepath.Join(targetDir, f.Filename)
-						if err := saveToFile(filePath, f.Content); err != nil {
-							m.addOutput(m.styles.Error.Render(fmt.Sprintf("Error saving %s: %s", f.Filename, err.Error())))
-						} else {
-							m.addOutput(m.styles.Success.Render(fmt.Sprintf("✓ Saved %s", filePath)))
-							savedCount++
-						}
-					}
-					if savedCount == len(m.currentFiles) {
-						m.savedPath = targetDir // Mark as saved
-					}
-				} else {
-					// Single filename - save combined (backwards compatible)
-					if err := saveToFile(targetDir, m.currentCode); err != nil {
-						m.addOutput(m.styles.Error.Render("Error saving: " + err.Error()))
-					} else {
-						m.addOutput("")
-						m.addOutput(m.styles.Success.Render("✓ Saved to " + targetDir))
-						m.addOutput(m.styles.Dim.Render("  (all files combined into single file)"))
-						m.savedPath = targetDir // Mark as saved
-					}
-				}
+			m.addOutput("No code generated yet.")
+			break
+		}
+		files := m.currentCodeFiles()
+		dir, paths, err := writeEditFiles(files)
+		if err != nil {
+			m.addOutput(m.styles.Error.Render("Error: " + err.Error()))
+			break
+		}
+		m.textarea.Reset()
+		m.textarea.Blur()
+		return m, tea.ExecProcess(editorCommand(paths), func(err error) tea.Msg {
+			return editDoneMsg{dir: dir, files: files, err: err}
+		})
+
+	case "/image", "/img":
+		// Validator image maintenance shortcuts (same as /config image update|rollback)
+		if len(parts) == 2 && strings.EqualFold(parts[1], "update") {
+			return m.startImageUpdate()
+		}
+		if len(parts) == 2 && strings.EqualFold(parts[1], "rollback") {
+			return m.startImageRollback()
+		}
+		if len(parts) < 2 {
+			m.addOutput(m.styles.Error.Render("Usage: /image <path>"))
+			m.addOutput(m.styles.Dim.Render("  Or drag an image file into the terminal and press Enter."))
+			break
+		}
+		m.attachImage(strings.TrimSpace(strings.TrimPrefix(input, parts[0])))
+
+	case "/compare":
+		if len(parts) < 3 {
+			m.addOutput(m.styles.Error.Render("Usage: /compare <modelA> <modelB> [request]"))
+			m.addOutput(m.styles.Dim.Render("  Models: haiku, sonnet, opus, a full model ID, or provider:model (e.g. gemini:sonnet)"))
+			m.addOutput(m.styles.Dim.Render("  Without a request, the last request is used."))
+			break
+		}
+		prompt := strings.TrimSpace(textAfterFields(input, 3))
+		if prompt == "" {
+			prompt = m.originalPrompt
+		}
+		if prompt == "" {
+			m.addOutput(m.styles.Error.Render("No request to compare - add one after the model names."))
+			break
+		}
+
+		var specs []candidateSpec
+		for _, arg := range parts[1:3] {
+			spec, err := resolveModelSpec(context.Background(), m.config, m.provider, arg)
+			if err != nil {
+				m.addOutput(m.styles.Error.Render("Error: " + err.Error()))
+				break
+			}
+			specs = append(specs, spec)
+		}
+		if len(specs) < 2 {
+			break
+		}
+		m.textarea.Reset()
+		m.textarea.Blur()
+		return m.startCompare(prompt, specs)
+
+	case "/bestof":
+		if len(parts) < 2 {
+			if m.bestOf > 1 {
+				m.addOutput(fmt.Sprintf("Best-of-N: %d candidates per generation", m.bestOf))
 			} else {
-				// No target specified - save to current directory with original filenames
-				m.addOutput("")
-				savedCount := 0
-				for _, f := range m.currentFiles {
-					if err := saveToFile(f.Filename, f.Content); err != nil {
-						m.addOutput(m.styles.Error.Render(fmt.Sprintf("Error saving %s: %s", f.Filename, err.Error())))
-					} else {
-						m.addOutput(m.styles.Success.Render(fmt.Sprintf("✓ Saved %s", f.Filename)))
-						savedCount++
-					}
-				}
-				if savedCount == len(m.currentFiles) {
-					m.savedPath = "." // Mark as saved to current dir
-				}
+				m.addOutput("Best-of-N: off")
+			}
+			m.addOutput(m.styles.Dim.Render("  Usage: /bestof <n> | /bestof off"))
+			break
+		}
+		n := 1
+		if arg := strings.ToLower(parts[1]); arg != "off" {
+			v, err := strconv.Atoi(arg)
+			if err != nil || v < 1 || v > maxCandidates {
+				m.addOutput(m.styles.Error.Render(fmt.Sprintf("Candidates must be 1-%d or off", maxCandidates)))
+				break
+			}
+			n = v
+		}
+		m.bestOf = n
+		if n > 1 {
+			m.addOutput(m.styles.Success.Render(fmt.Sprintf("Best-of-N enabled: %d candidates per generation", n)))
+		} else {
+			m.addOutput(m.styles.Dim.Render("Best-of-N disabled"))
+		}
+
+	case "/strategy":
+		if len(parts) < 2 {
+			if m.strategy == StrategyRegenerate {
+				m.addOutput(fmt.Sprintf("Fix strategy: regenerate from scratch after %d failed fixes in a row", m.regenerateThreshold()))
+			} else {
+				m.addOutput("Fix strategy: fix (patch the code after every failure)")
 			}
+			m.addOutput(m.styles.Dim.Render("  Usage: /strategy fix | /strategy regenerate [n]"))
+			break
+		}
+		strategy, threshold, err := parseStrategyArgs(parts[1:], m.regenerateThreshold())
+		if err != nil {
+			m.addOutput(m.styles.Error.Render(err.Error()))
+			break
+		}
+		m.strategy, m.regenAfter = strategy, threshold
+		if strategy == StrategyRegenerate {
+			m.addOutput(m.styles.Success.Render(fmt.Sprintf("Fix strategy: regenerate from scratch after %d failed fixes in a row", threshold)))
 		} else {
-			// Single file
-			if len(parts) < 2 {
-				m.addOutput(m.styles.Error.Render("Usage: /save <filename>"))
+			m.addOutput(m.styles.Success.Render("Fix strategy: fix (patch the code after every failure)"))
+		}
+
+	case "/review":
+		if len(parts) < 2 {
+			panel := m.reviewPanel()
+			names := make([]string, len(panel.models))
+			for i, model := range panel.models {
+				names[i] = shortModelName(model)
+			}
+			m.addOutput(fmt.Sprintf("Review gate: %s, fix below %d%% confidence", strings.Join(names, " + "), m.reviewThreshold()))
+			if panel.consensus != ConsensusOff {
+				m.addOutput(fmt.Sprintf("  Consensus: %s of both reviewers' confidence", panel.consensus))
+			}
+			if len(panel.rubric) > 0 {
+				m.addOutput(fmt.Sprintf("  Rubric: %d custom criteria (review.rubric in settings)", len(panel.rubric)))
+			}
+			if panel.functional {
+				m.addOutput("  Functional: the reviewer writes checks from the request, which are run against the code")
+			}
+			if m.checkAnalysis {
+				m.addOutput("  Consistency: the code is compared with the analysis")
+			}
+			m.addOutput(m.styles.Dim.Render("  Usage: " + reviewUsage))
+			break
+		}
+		if len(parts) < 3 {
+			m.addOutput(m.styles.Error.Render("Usage: " + reviewUsage))
+			break
+		}
+		switch parts[1] {
+		case "threshold":
+			n, err := parseReviewThreshold(parts[2])
+			if err != nil {
+				m.addOutput(m.styles.Error.Render(err.Error()))
+				break
+			}
+			m.reviewMin = n
+			m.addOutput(m.styles.Success.Render(fmt.Sprintf("Review threshold: %d%%", n)))
+		case "consensus":
+			mode, err := parseConsensus(parts[2])
+			if err != nil {
+				m.addOutput(m.styles.Error.Render(err.Error()))
+				break
+			}
+			m.reviewMode = mode
+			if mode == ConsensusOff {
+				m.addOutput(m.styles.Success.Render("Review consensus off: one reviewer"))
 			} else {
-				filename := parts[1]
-				if err := saveToFile(filename, m.currentCode); err != nil {
-					m.addOutput(m.styles.Error.Render("Error saving: " + err.Error()))
-				} else {
-					m.addOutput("")
-					m.addOutput(m.styles.Success.Render("✓ Saved to " + filename))
-					// Show file size for confirmation
-					if info, err := os.Stat(filename); err == nil {
-						m.addOutput(m.styles.Dim.Render(fmt.Sprintf("  %d bytes written", info.Size())))
-					}
-					m.savedPath = filename // Mark as saved
+				m.addOutput(m.styles.Success.Render(fmt.Sprintf("Review consensus: %s of two reviewers", mode)))
+			}
+		case "functional":
+			switch parts[2] {
+			case "on":
+				m.reviewChecks = true
+				m.addOutput(m.styles.Success.Render("Functional review on: the reviewer's checks are run against the code"))
+			case "off":
+				m.reviewChecks = false
+				m.addOutput(m.styles.Success.Render("Functional review off"))
+			default:
+				m.addOutput(m.styles.Error.Render("Usage: /review functional on|off"))
+			}
+		case "consistency":
+			switch parts[2] {
+			case "on":
+				m.checkAnalysis = true
+				m.addOutput(m.styles.Success.Render("Consistency check on: reviewed code is compared with the analysis"))
+			case "off":
+				m.checkAnalysis = false
+				m.addOutput(m.styles.Success.Render("Consistency check off"))
+			default:
+				m.addOutput(m.styles.Error.Render("Usage: /review consistency on|off"))
+			}
+		default:
+			m.addOutput(m.styles.Error.Render("Unknown /review option: " + parts[1]))
+		}
+
+	case "/model", "/m":
+		if len(parts) < 2 {
+			if m.modelOverride != "" {
+				m.addOutput(fmt.Sprintf("Model: %s (pinned)", m.modelOverride))
+			} else {
+				m.addOutput("Model: auto (chosen by task complexity)")
+			}
+			m.addOutput(m.styles.Dim.Render("  Usage: /model <haiku|sonnet|opus|model-id> | /model auto"))
+			break
+		}
+		name := parts[1]
+		if IsCanonicalModel(strings.ToLower(name)) || strings.EqualFold(name, "auto") {
+			name = strings.ToLower(name)
+		}
+		if name == "auto" {
+			m.modelOverride = ""
+			m.addOutput(m.styles.Success.Render("Model selection: auto (by task complexity)"))
+			break
+		}
+		m.modelOverride = name
+		m.addOutput(m.styles.Success.Render(fmt.Sprintf("Model pinned: %s", name)))
+		if resolved := m.resolveModel(name); resolved != name {
+			m.addOutput(m.styles.Dim.Render("  " + resolved))
+		}
+
+	case "/temp", "/temperature":
+		gen := &m.config.Settings.Generation
+		maxTemp := providerMaxTemperature(m.provider)
+		if err := applyGenerationArgs(gen, parts[1:], maxTemp); err != nil {
+			m.addOutput(m.styles.Error.Render(fmt.Sprintf("%v (%s)", err, m.provider.Name())))
+			break
+		}
+		m.provider.SetGeneration(*gen)
+		m.addOutput("Generation: " + formatGeneration(*gen))
+		if len(parts) < 2 {
+			m.addOutput(m.styles.Dim.Render(fmt.Sprintf("  Usage: /temp <0-%g|default> | /temp top-p <0-1|default> | /temp seed <n|off> | /temp reset", maxTemp)))
+		}
+		if gen.Temperature != nil && gen.TopP != nil && maxTemp == maxClaudeTemperature {
+			m.addOutput(m.styles.Warning.Render(fmt.Sprintf("  %s takes a temperature or a top-p, not both; top-p is not sent while a temperature is set", m.provider.Name())))
+		}
+		if gen.Seed != nil && !providerSupportsSeed(m.provider) {
+			m.addOutput(m.styles.Warning.Render(fmt.Sprintf("  %s does not support seeds; output will still vary", m.provider.Name())))
+		}
+
+	case "/prompts":
+		if len(parts) >= 2 && strings.EqualFold(parts[1], "reload") {
+			for _, err := range m.prompts.Reload() {
+				m.addOutput(m.styles.Warning.Render(err.Error()))
+			}
+			if cwd, err := os.Getwd(); err == nil {
+				rules, err := LoadProjectRules(cwd)
+				if err != nil {
+					m.addOutput(m.styles.Warning.Render(err.Error()))
 				}
+				m.projectRules = rules
 			}
 		}
+		if m.projectRules != nil {
+			m.addOutput("Project rules: " + m.projectRules.Status())
+		}
+		sources := m.prompts.Sources()
+		if len(sources) == 0 {
+			m.addOutput("Prompts: built-in (no overrides in " + m.prompts.Dir() + ")")
+		} else {
+			m.addOutput("Prompt overrides:")
+			for _, src := range sources {
+				m.addOutput("  " + src)
+			}
+		}
+		m.addOutput(m.styles.Dim.Render("  Files: " + strings.Join(promptNames, ", ") + " (.md or .txt) · /prompts reload"))
+
+	case "/tests":
+		_, args, _ := strings.Cut(strings.TrimSpace(input), " ")
+		m.editExampleTests(args)
+
+	case "/plan":
+		_, args, _ := strings.Cut(strings.TrimSpace(input), " ")
+		m.textarea.Reset()
+		return m.editPlan(args)
+
+	case "/stdin":
+		_, args, _ := strings.Cut(strings.TrimSpace(input), " ")
+		m.editRunInput(args)
+
+	case "/suppress":
+		_, args, _ := strings.Cut(strings.TrimSpace(input), " ")
+		m.textarea.Reset()
+		return m.suppressFindings(args)
+
+	case "/highlight":
+		m.highlight = !m.highlight
+		if m.highlight {
+			m.addOutput(m.styles.Success.Render("Syntax highlighting enabled"))
+		} else {
+			m.addOutput(m.styles.Dim.Render("Syntax highlighting disabled"))
+		}
+
+	case "/save", "/s":
+		if m.startSave(parts[1:]) {
+			m.textarea.Reset()
+			m.textarea.Blur()
+			return m, nil
+		}
+
+	case "/compact":
+		if old, _ := splitForCompaction(m.conversation); len(old) < 2 {
+			m.addOutput(m.styles.Dim.Render("Nothing to compact yet."))
+			m.textarea.Reset()
+			return m, nil
+		}
+		m.textarea.Reset()
+		return m.startCompaction(false)
 
 	case "/tokens", "/t":
 		input, output, total := m.tokenTracker.GetUsage()
@@ -1794,6 +5081,9 @@ func (m Model) handleCommand(input string) (Model, tea.Cmd) {
 		m.addOutput(fmt.Sprintf("  Input tokens:  %d", input))
 		m.addOutput(fmt.Sprintf("  Output tokens: %d", output))
 		m.addOutput(fmt.Sprintf("  Total tokens:  %d", total))
+		if estimated := m.tokenTracker.EstimatedTokens; estimated > 0 {
+			m.addOutput(m.styles.Dim.Render(fmt.Sprintf("  (%d counted locally; the provider did not report usage for some requests)", estimated)))
+		}
 		m.addOutput("")
 
 	case "/validate", "/v":
@@ -1817,7 +5107,7 @@ func (m Model) handleCommand(input string) (Model, tea.Cmd) {
 		// Store the code for validation
 		m.currentCode = string(content)
 		m.currentFiles = []CodeFile{{Filename: filepath.Base(filename), Content: string(content)}}
-		m.originalPrompt = fmt.Sprintf("(Direct validation of %s)", filename)
+		m.originalPrompt = directValidationPrefix + filename + ")"
 
 		// Show what we're validating
 		m.addOutput("")
@@ -1837,6 +5127,10 @@ func (m Model) handleCommand(input string) (Model, tea.Cmd) {
 
 // printSplashScreen displays the bjarne logo and version
 func printSplashScreen() {
+	if plainMode {
+		fmt.Printf("bjarne %s - AI-assisted C/C++ with mandatory validation\n\n", Version)
+		return
+	}
 	// ASCII art logo - stylized "bjarne" text
 	// Use dynamic box characters to handle macOS terminal issues
 	top := boxTopLeft + strings.Repeat(boxHorizontal, 62) + boxTopRight
@@ -1872,6 +5166,25 @@ func StartTUI() error {
 		return err
 	}
 
+	container.applySettings(cfg.Settings)
+	SetNetworkSettings(cfg.Settings.Network)
+	// Lock a session ID of our own: it names the audit log, checkpoints and auto-saves
+	sessionID := newSessionID(time.Now())
+	var otherRunning []string
+	if dir, err := sessionsDir(); err == nil {
+		if session, err := startSession(dir, time.Now()); err == nil {
+			defer func() { _ = session.Release() }()
+			sessionID = session.ID
+			otherRunning = otherSessions(dir, session.ID)
+		}
+	}
+	audit, auditErr := startAuditSession(cfg.Settings.Audit, sessionID)
+	SetAuditLog(audit)
+	image, imageErr := resolveSessionImage(cfg.Settings.Container)
+	if imageErr == nil {
+		container.SetImage(image.Ref)
+	}
+
 	providerCfg := cfg.GetProviderConfig()
 	provider, err := NewProvider(ctx, providerCfg)
 	if err != nil {
@@ -1881,6 +5194,17 @@ func StartTUI() error {
 
 	// Show status line
 	fmt.Printf("    \033[92m●\033[0m %s  \033[92m●\033[0m %s", container.GetBinary(), provider.Name())
+	if auditErr != nil {
+		fmt.Printf("  \033[93m●\033[0m audit log off: %v", auditErr)
+	}
+	if len(otherRunning) > 0 {
+		fmt.Printf("  \033[92m●\033[0m %d other session(s) running", len(otherRunning))
+	}
+	if imageErr != nil {
+		fmt.Printf("  \033[93m●\033[0m %v", imageErr)
+	} else if image.Profile != "" && image.Profile != defaultImageProfile {
+		fmt.Printf("  \033[92m●\033[0m image: %s", image.Profile)
+	}
 
 	// Load workspace index (fast, from disk cache)
 	var workspaceIndex *WorkspaceIndex
@@ -1889,13 +5213,74 @@ func StartTUI() error {
 		workspaceIndex = idx
 		fmt.Printf("  \033[92m●\033[0m %d files indexed", idx.Summary.TotalFiles)
 	}
+	projectRules, rulesErr := LoadProjectRules(cwd)
+	if rulesErr != nil {
+		fmt.Printf("  \033[93m●\033[0m %v", rulesErr)
+	} else if projectRules != nil {
+		fmt.Printf("  \033[92m●\033[0m %s", projectRules.Status())
+	}
+	if suppressions, err := LoadSuppressions(cwd); err != nil {
+		fmt.Printf("  \033[93m●\033[0m %v", err)
+	} else if len(suppressions) > 0 {
+		container.SetSuppressions(suppressions)
+		fmt.Printf("  \033[92m●\033[0m %d suppression(s)", len(suppressions))
+	}
+	if rules, err := LoadASTRules(cwd); err != nil {
+		fmt.Printf("  \033[93m●\033[0m %v", err)
+	} else if len(rules) > 0 {
+		container.SetASTRules(rules)
+		fmt.Printf("  \033[92m●\033[0m %d AST rule(s)", len(rules))
+	}
+	if fixtures, err := LoadFixtures(cwd); err != nil {
+		fmt.Printf("  \033[93m●\033[0m %v", err)
+	} else if fixtures != nil {
+		container.SetFixtures(fixtures)
+		fmt.Printf("  \033[92m●\033[0m %s", fixtures.Status())
+	}
+	if plugins, err := loadValidatorPlugins(); err != nil {
+		fmt.Printf("  \033[93m●\033[0m %v", err)
+	} else if len(plugins) > 0 {
+		container.SetValidatorPlugins(plugins)
+		fmt.Printf("  \033[92m●\033[0m %d validator plugin(s)", len(plugins))
+	}
 	fmt.Println()
+	if offlineMode {
+		fmt.Printf("    \033[93m●\033[0m offline mode, disabled: %s\n", strings.Join(offlineDegraded(), ", "))
+	}
+	printSettingsIssues()
+	prompts, promptErrs := loadUserPromptSet()
+	for _, err := range promptErrs {
+		fmt.Printf("    \033[93m●\033[0m %v\n", err)
+	}
+	if path, err := recoveryPath(); err == nil {
+		if r, err := loadRecovery(path); err != nil {
+			fmt.Printf("    \033[93m●\033[0m %v\n", err)
+		} else if r != nil {
+			fmt.Printf("    \033[93m●\033[0m Unfinished session: %s\n", r.Summary())
+			fmt.Println("      /recover continues it, /recover discard deletes it")
+		}
+	}
 	fmt.Println()
 	fmt.Println("    Type your request or /help for commands")
 
 	// Create model and start TUI immediately
 	m := NewModel(provider, container, cfg)
+	m.sessionID = sessionID
 	m.workspaceIndex = workspaceIndex
+	m.projectRules = projectRules
+	m.prompts = prompts
+	m.validatorConfig.AddPlugins(container.ValidatorPlugins())
+	m.image = image
+	if imageErr != nil {
+		m.image = ImageSelection{Ref: container.ImageName(), Source: "default"}
+	}
+
+	// Scrollback mode (default) doesn't use WithAltScreen() - keeps normal terminal scrollback history
+	opts := []tea.ProgramOption{tea.WithInputTTY()}
+	if cfg.Settings.Display.Mode == DisplayAltScreen && !plainMode {
+		opts = append(opts, tea.WithAltScreen())
+	}
+	p := tea.NewProgram(m, opts...)
 
 	// Do slow operations in background AFTER TUI starts
 	go func() {
@@ -1915,42 +5300,600 @@ func StartTUI() error {
 				modelCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 				_ = vi.EnsureModel(modelCtx, nil)
 				cancel()
-				m.vectorIndex = vi
+				p.Send(vectorIndexLoadedMsg{index: vi})
 			} else {
 				_ = vi.Close()
 			}
 		}
 	}()
 
-	// Don't use WithAltScreen() - keeps normal terminal scrollback history
-	p := tea.NewProgram(m, tea.WithInputTTY())
-
-	_, err = p.Run()
+	final, err := p.Run()
+	if fm, ok := final.(Model); ok {
+		fm.shutdown()
+	}
 	return err
 }
 
-// showValidatorConfig displays and manages validator configuration
-func (m *Model) showValidatorConfig(args []string) {
+// themeCommand lists, switches (and saves) or previews color themes (/theme)
+func (m *Model) themeCommand(args []string) {
+	settings := &m.config.Settings.Theme
+	m.addOutput("")
+	if len(args) > 0 && strings.EqualFold(args[0], "preview") {
+		names := settings.Names()
+		if len(args) > 1 {
+			names = args[1:]
+		}
+		for _, name := range names {
+			if !slices.Contains(settings.Names(), name) {
+				m.addOutput(m.styles.Error.Render("Unknown theme: " + name))
+				continue
+			}
+			m.addOutput(m.themeSwatch(name))
+		}
+		if len(args) == 2 {
+			m.addOutput("")
+			m.addOutput(highlightCode("int main() {\n    return 0; // ok\n}", "cpp", NewTheme(&ThemeSettings{Name: args[1], Custom: settings.Custom}).highlight))
+		}
+		return
+	}
+
+	if len(args) > 0 {
+		name := args[0]
+		if !slices.Contains(settings.Names(), name) {
+			m.addOutput(m.styles.Error.Render("Unknown theme: " + name))
+			m.addOutput(m.styles.Dim.Render("  Themes: " + strings.Join(settings.Names(), ", ")))
+			return
+		}
+		settings.Name = name
+		m.applySettings()
+		if err := m.config.SaveSettings(); err != nil {
+			m.addOutput(m.styles.Warning.Render("Theme changed for this session, but saving failed: " + err.Error()))
+		} else {
+			m.addOutput(m.styles.Success.Render("Theme: " + name + " (saved to ~/.bjarne/settings.json)"))
+		}
+		return
+	}
+
+	for _, name := range settings.Names() {
+		marker := " "
+		if name == settings.Name {
+			marker = "●"
+		}
+		m.addOutput(fmt.Sprintf("  %s %s", marker, m.themeSwatch(name)))
+	}
+	m.addOutput(m.styles.Dim.Render("  Usage: /theme <name> | /theme preview [name...]; define your own under theme.custom in settings.json"))
+}
+
+// themeSwatch renders a theme's name and each of its colors on one line
+func (m *Model) themeSwatch(name string) string {
+	styles := NewStyles(NewTheme(&ThemeSettings{Name: name, Custom: m.config.Settings.Theme.Custom}))
+	return fmt.Sprintf("%-12s %s %s %s %s %s %s", name,
+		styles.Prompt.Render("prompt"), styles.Success.Render("success"), styles.Error.Render("error"),
+		styles.Warning.Render("warning"), styles.Info.Render("info"), styles.Accent.Render("accent"))
+}
+
+// openSettings shows the /settings form over the input
+func (m *Model) openSettings() {
+	m.settingsForm = newSettingsForm(m.config.Settings)
+	m.state = StateSettings
+	m.textarea.Reset()
+	m.textarea.Blur()
+	if m.pager != nil {
+		m.pager.SetSize(m.width, m.pagerHeight())
+	}
+}
+
+// pagerHeight is the output pane's height, leaving room for the /settings form when it is open
+func (m *Model) pagerHeight() int {
+	if m.state == StateSettings {
+		return max(1, m.height-pagerReservedLines-settingsFormHeight())
+	}
+	if m.state == StateValidating && m.gates != nil {
+		return max(1, m.height-pagerReservedLines-len(m.gates.gates))
+	}
+	return m.height - pagerReservedLines
+}
+
+// gateProgressView lists the validation's gates above the status line: ✓/✗ with the time each
+// took, and a spinner with a live timer for the one running
+func (m *Model) gateProgressView() string {
+	var b strings.Builder
+	now := time.Now()
+	for _, g := range m.gates.gates {
+		mark := m.spinner.View()
+		switch {
+		case g.Result == nil:
+		case g.Result.Success:
+			mark = m.styles.Checkmark.Render("✓")
+		default:
+			mark = m.styles.Cross.Render("✗")
+		}
+		fmt.Fprintf(&b, "  %s %s %s\n", mark, g.Stage, m.styles.Dim.Render(fmt.Sprintf("%.1fs", g.elapsed(now).Seconds())))
+	}
+	return b.String()
+}
+
+// handleSettingsKey navigates and edits the /settings form
+// Each accepted change applies at once; settings.json is written when the form closes
+func (m *Model) handleSettingsKey(msg tea.KeyMsg) (Model, tea.Cmd) {
+	form := m.settingsForm
+	if form.editing {
+		switch {
+		case msg.Type == tea.KeyEsc:
+			form.editing = false
+			form.err = ""
+			m.textarea.Reset()
+			m.textarea.Blur()
+		case msg.Type == tea.KeyEnter:
+			if form.set(m.textarea.Value()) == nil {
+				form.editing = false
+				m.textarea.Reset()
+				m.textarea.Blur()
+				m.applySettings()
+			}
+		default:
+			var cmd tea.Cmd
+			m.textarea, cmd = m.textarea.Update(msg)
+			return *m, cmd
+		}
+		return *m, nil
+	}
+
+	key := msg.String()
+	switch {
+	case msg.Type == tea.KeyEsc || key == "q":
+		m.closeSettings()
+		return *m, textarea.Blink
+	case msg.Type == tea.KeyUp || key == "k":
+		form.move(-1)
+	case msg.Type == tea.KeyDown || key == "j":
+		form.move(1)
+	case form.toggles() && (msg.Type == tea.KeyLeft || msg.Type == tea.KeyRight || msg.Type == tea.KeyEnter || key == " "):
+		delta := 1
+		if msg.Type == tea.KeyLeft {
+			delta = -1
+		}
+		if form.cycle(delta) == nil {
+			m.applySettings()
+		}
+	case msg.Type == tea.KeyEnter:
+		form.editing = true
+		form.err = ""
+		m.textarea.SetValue(form.value(form.current().Path))
+		m.textarea.Focus()
+		return *m, textarea.Blink
+	}
+	return *m, nil
+}
+
+// closeSettings leaves the /settings form, saving to settings.json if anything changed
+func (m *Model) closeSettings() {
+	form := m.settingsForm
+	m.settingsForm = nil
+	m.state = StateInput
+	m.textarea.Reset()
+	m.textarea.Focus()
+	if m.pager != nil {
+		m.pager.SetSize(m.width, m.pagerHeight())
+	}
+	if !form.changed {
+		m.addOutput(m.styles.Dim.Render("Settings unchanged"))
+		return
+	}
+	if err := m.config.SaveSettings(); err != nil {
+		m.addOutput(m.styles.Warning.Render("Settings apply to this session, but saving failed: " + err.Error()))
+		return
+	}
+	m.addOutput(m.styles.Success.Render("Settings saved to ~/.bjarne/settings.json"))
+}
+
+// applySettings puts edited settings into effect for the rest of the session
+func (m *Model) applySettings() {
+	m.config.syncSettings()
+	m.styles = NewStyles(m.config.Theme)
+	s := m.config.Settings
+	m.tokenTracker.MaxTokens = m.config.MaxTotalTokens
+	m.tokenTracker.WarnAt = m.config.WarnTokenThreshold
+	m.tokenTracker.CompactAt = s.Tokens.CompactAt
+	if strategy, err := parseFixStrategy(s.Validation.Strategy); err == nil {
+		m.strategy = strategy
+	}
+	m.regenAfter = s.Validation.RegenerateAfter
+	m.reviewMin = s.Review.Threshold
+	if m.container != nil {
+		m.container.applySettings(s)
+	}
+	if err := configureLogging(s.Logging); err != nil {
+		m.addOutput(m.styles.Warning.Render("Logging unchanged: " + err.Error()))
+	}
+
+	// container.image only selects the validator when no profile or environment variable does
+	image := s.Container.Image
+	if image == "" {
+		image = defaultValidatorImage
+	}
+	if m.image.Profile == "" && m.image.Source == "settings" && m.image.Ref != image {
+		m.useImage(ImageSelection{Ref: image, Source: "settings"})
+	}
+}
+
+// settingsFormView renders the /settings form: one row per field under its group
+func (m *Model) settingsFormView() string {
+	form := m.settingsForm
+	var b strings.Builder
+	b.WriteString(m.styles.Accent.Render("Settings") + m.styles.Dim.Render("  ↑/↓ select · enter edit · ←/→ change · esc save & close") + "\n")
+	for i, field := range form.fields {
+		if i == 0 || field.Group != form.fields[i-1].Group {
+			b.WriteString(m.styles.Warning.Render(field.Group) + "\n")
+		}
+		marker := "  "
+		if i == form.cursor {
+			marker = m.styles.Prompt.Render("> ")
+		}
+		value := form.value(field.Path)
+		if i == form.cursor && form.editing {
+			value = m.textarea.View()
+		} else if field.Path == "container.image" && m.image.Source != "settings" {
+			value += m.styles.Dim.Render(fmt.Sprintf("  (in use: %s from %s)", m.image.Ref, m.image.Source))
+		}
+		if m.config.Layers != nil {
+			if origin := m.config.Layers.origins[field.Path]; origin.layer > layerUser {
+				value += m.styles.Dim.Render("  (overridden by " + origin.source + ")")
+			}
+		}
+		fmt.Fprintf(&b, "%s  %-28s %s\n", marker, field.Path, value)
+	}
+	if form.err != "" {
+		b.WriteString(m.styles.Error.Render(form.err))
+	}
+	return b.String()
+}
+
+// configureModels shows or edits the complexity→model mapping and escalation ladder (/config models)
+func (m *Model) configureModels(args []string) {
+	m.addOutput("")
+	models := &m.config.Settings.Models
+
+	if len(args) > 0 {
+		key := strings.ToLower(args[0])
+		value := strings.Join(args[1:], " ")
+		if value == "" && key != "reset" {
+			m.addOutput(m.styles.Error.Render("Usage: /config models <easy|medium|complex> <model>"))
+			m.addOutput(m.styles.Dim.Render("       /config models escalation <model,model,...>"))
+			m.addOutput(m.styles.Dim.Render("       /config models attempts <n,n,...>"))
+			m.addOutput(m.styles.Dim.Render("       /config models reset"))
+			return
+		}
+
+		switch key {
+		case "easy":
+			models.Complexity.Easy = value
+		case "medium":
+			models.Complexity.Medium = value
+		case "complex":
+			models.Complexity.Complex = value
+		case "escalation":
+			models.Escalation = parseModelList(value)
+		case "attempts":
+			counts, err := parseAttemptList(value)
+			if err != nil {
+				m.addOutput(m.styles.Error.Render(err.Error()))
+				return
+			}
+			models.EscalationAttempts = counts
+		case "reset":
+			defaults := DefaultSettings().Models
+			models.Complexity = defaults.Complexity
+			models.Escalation = defaults.Escalation
+			models.EscalationAttempts = defaults.EscalationAttempts
+		default:
+			m.addOutput(m.styles.Error.Render("Unknown setting: " + key))
+			return
+		}
+
+		m.config.ComplexityModels = models.Complexity
+		m.config.EscalationModels = models.Escalation
+		m.config.EscalationAttempts = models.EscalationAttempts
+		if err := m.config.SaveSettings(); err != nil {
+			m.addOutput(m.styles.Warning.Render("Updated for this session, but saving failed: " + err.Error()))
+		} else {
+			m.addOutput(m.styles.Success.Render("Saved to ~/.bjarne/settings.json"))
+		}
+		m.addOutput("")
+	}
+
+	complexModel := models.Complexity.Complex
+	if complexModel == "" {
+		complexModel = "(oracle) " + m.config.OracleModel
+	}
+	m.addOutput(m.styles.Warning.Render("Complexity models:"))
+	m.addOutput(fmt.Sprintf("  EASY     %s", models.Complexity.Easy))
+	m.addOutput(fmt.Sprintf("  MEDIUM   %s", models.Complexity.Medium))
+	m.addOutput(fmt.Sprintf("  COMPLEX  %s", complexModel))
+	m.addOutput("")
+
+	m.addOutput(m.styles.Warning.Render("Escalation ladder:"))
+	m.addOutput(m.styles.Dim.Render("  (complexity model first, then each escalation model)"))
+	escalation := append([]string{"<complexity model>"}, models.Escalation...)
+	total := 0
+	for i, model := range escalation {
+		attempts := defaultRungAttempts
+		if i < len(models.EscalationAttempts) && models.EscalationAttempts[i] > 0 {
+			attempts = models.EscalationAttempts[i]
+		}
+		total += attempts
+		m.addOutput(fmt.Sprintf("  %d. %-50s %d attempts", i+1, model, attempts))
+	}
+	m.addOutput(m.styles.Dim.Render(fmt.Sprintf("  %d fix attempts in total", total)))
+	m.addOutput("")
+}
+
+// configureImage lists, switches, pins, or unpins validator image profiles (/config image)
+func (m *Model) configureImage(args []string) {
+	m.addOutput("")
+	settings := &m.config.Settings.Container
+
+	if len(args) > 0 {
+		key := strings.ToLower(args[0])
+		switch key {
+		case "pin":
+			if !m.pinImage(args[1:]) {
+				return
+			}
+			m.addOutput("")
+		case "unpin":
+			if m.image.Profile == "" {
+				m.addOutput(m.styles.Error.Render("No image profile is active"))
+				return
+			}
+			profile := settings.Profiles[m.image.Profile]
+			profile.Digest = ""
+			settings.Profiles[m.image.Profile] = profile
+			m.useImage(ImageSelection{Ref: profile.Ref(), Profile: m.image.Profile, Source: m.image.Source})
+			if err := m.config.SaveSettings(); err != nil {
+				m.addOutput(m.styles.Warning.Render("Unpinned for this session, but saving failed: " + err.Error()))
+			} else {
+				m.addOutput(m.styles.Success.Render(fmt.Sprintf("Unpinned %s (saved to ~/.bjarne/settings.json)", m.image.Profile)))
+			}
+			m.addOutput("")
+		default:
+			profile, ok := settings.Profiles[key]
+			if !ok || profile.Image == "" {
+				m.addOutput(m.styles.Error.Render("Unknown image profile: " + key))
+				m.addOutput(m.styles.Dim.Render("Usage: /config image <" + strings.Join(settings.ProfileNames(), "|") + "> [project]"))
+				m.addOutput(m.styles.Dim.Render("       /config image pin [sha256:<digest>]"))
+				m.addOutput(m.styles.Dim.Render("       /config image unpin"))
+				m.addOutput(m.styles.Dim.Render("       /config image update|rollback"))
+				return
+			}
+			if len(args) > 1 && strings.EqualFold(args[1], "project") {
+				cwd, _ := os.Getwd()
+				if err := SaveProjectImageProfile(cwd, key); err != nil {
+					m.addOutput(m.styles.Error.Render(err.Error()))
+					return
+				}
+				m.useImage(ImageSelection{Ref: profile.Ref(), Profile: key, Source: projectImageFile})
+				m.addOutput(m.styles.Success.Render(fmt.Sprintf("Using %s for this project (saved to %s)", key, projectImageFile)))
+			} else {
+				m.useImage(ImageSelection{Ref: profile.Ref(), Profile: key, Source: "session"})
+				m.addOutput(m.styles.Success.Render(fmt.Sprintf("Using %s for this session", key)))
+			}
+			if !m.container.ImageExists(context.Background()) {
+				m.addOutput(m.styles.Warning.Render(fmt.Sprintf("%s is not pulled yet: %s pull %s", m.image.Ref, m.container.GetBinary(), m.image.Ref)))
+			}
+			m.addOutput("")
+		}
+	}
+
+	m.addOutput(m.styles.Warning.Render("Validator images:"))
+	for _, name := range settings.ProfileNames() {
+		profile := settings.Profiles[name]
+		marker := " "
+		if name == m.image.Profile {
+			marker = "●"
+		}
+		line := fmt.Sprintf("  %s %-10s %s", marker, name, profile.Image)
+		if profile.Digest != "" {
+			line += m.styles.Dim.Render(" (pinned " + shortDigest(profile.Digest) + ")")
+		}
+		m.addOutput(line)
+	}
+	m.addOutput(m.styles.Dim.Render(fmt.Sprintf("  Active: %s (from %s)", m.image.Ref, m.image.Source)))
+	if rec, ok := m.imageHistory.Images[imageRepo(m.image.Ref)]; ok {
+		if rec.Working != "" {
+			m.addOutput(m.styles.Dim.Render("  Last working digest: " + rec.Working))
+		}
+		if rec.Previous != "" {
+			m.addOutput(m.styles.Dim.Render(fmt.Sprintf("  Before update on %s: %s (/image rollback)", rec.Updated.Format("2006-01-02"), rec.Previous)))
+		}
+	}
 	m.addOutput("")
+}
+
+// pinImage stores a digest for the active profile (the local image's digest by default)
+func (m *Model) pinImage(args []string) bool {
+	settings := &m.config.Settings.Container
+	if m.image.Profile == "" {
+		m.addOutput(m.styles.Error.Render("No image profile is active; select one with /config image <name>"))
+		return false
+	}
+
+	digest := ""
+	if len(args) > 0 {
+		digest = args[0]
+	} else {
+		digest = m.container.GetLocalImageDigest(context.Background())
+	}
+	if err := ValidateDigest(digest); err != nil {
+		m.addOutput(m.styles.Error.Render(fmt.Sprintf("Cannot pin %s: %v", m.image.Profile, err)))
+		m.addOutput(m.styles.Dim.Render("Pull the image first or pass the digest: /config image pin sha256:<digest>"))
+		return false
+	}
+
+	profile := settings.Profiles[m.image.Profile]
+	profile.Digest = digest
+	settings.Profiles[m.image.Profile] = profile
+	m.useImage(ImageSelection{Ref: profile.Ref(), Profile: m.image.Profile, Source: m.image.Source})
+	if err := m.config.SaveSettings(); err != nil {
+		m.addOutput(m.styles.Warning.Render("Pinned for this session, but saving failed: " + err.Error()))
+	} else {
+		m.addOutput(m.styles.Success.Render(fmt.Sprintf("Pinned %s to %s (saved to ~/.bjarne/settings.json)", m.image.Profile, shortDigest(digest))))
+	}
+	return true
+}
+
+// useImage switches the validator image for the rest of the session
+func (m *Model) useImage(sel ImageSelection) {
+	m.image = sel
+	m.container.SetImage(sel.Ref)
+}
+
+// checkImageUpdate asks the registry whether the validator image has a newer digest
+func checkImageUpdate(container *ContainerRuntime) tea.Cmd {
+	ref := container.ImageName()
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), imageUpdateCheckTimeout)
+		defer cancel()
+		if !container.CheckForUpdate(ctx) {
+			return nil
+		}
+		return imageUpdateAvailableMsg{ref: ref}
+	}
+}
+
+// recordWorkingImage remembers the validator digest after a passing run (once per image per session)
+func (m *Model) recordWorkingImage() tea.Cmd {
+	ref := m.container.ImageName()
+	if m.imageRecorded == ref {
+		return nil
+	}
+	container := m.container
+	return func() tea.Msg {
+		return imageDigestMsg{ref: ref, digest: container.LocalDigest(context.Background(), ref)}
+	}
+}
+
+// startImageUpdate pulls the newest image for the active tag, keeping the old digest for rollback
+func (m *Model) startImageUpdate() (Model, tea.Cmd) {
+	if offlineMode {
+		m.addOutput(m.styles.Error.Render("Image update " + errOffline.Error()))
+		return *m, nil
+	}
+	ref := m.container.ImageName()
+	if _, digest, pinned := strings.Cut(ref, "@"); pinned {
+		m.addOutput(m.styles.Error.Render("The validator image is pinned to " + shortDigest(digest)))
+		m.addOutput(m.styles.Dim.Render("Use /config image unpin to follow updates again"))
+		return *m, nil
+	}
+	container := m.container
+	return m.startImagePull("Pulling "+ref+"…", func(ctx context.Context) imagePullDoneMsg {
+		from := container.LocalDigest(ctx, ref)
+		if err := container.FetchImage(ctx, ref); err != nil {
+			return imagePullDoneMsg{err: err}
+		}
+		return imagePullDoneMsg{ref: ref, from: from, to: container.LocalDigest(ctx, ref)}
+	})
+}
+
+// startImageRollback switches back to the digest that worked before the last update
+func (m *Model) startImageRollback() (Model, tea.Cmd) {
+	ref := m.container.ImageName()
+	current := m.container.LocalDigest(context.Background(), ref)
+	target, err := m.imageHistory.RollbackTarget(ref, current)
+	if err != nil {
+		m.addOutput(m.styles.Error.Render("Cannot roll back: " + err.Error()))
+		return *m, nil
+	}
+	container := m.container
+	pinned := imageRepo(ref) + "@" + target
+	return m.startImagePull("Rolling back to "+shortDigest(target)+"…", func(ctx context.Context) imagePullDoneMsg {
+		if !container.HasImage(ctx, pinned) {
+			if err := container.FetchImage(ctx, pinned); err != nil {
+				return imagePullDoneMsg{err: err, rollback: true}
+			}
+		}
+		return imagePullDoneMsg{ref: pinned, from: current, to: target, rollback: true}
+	})
+}
+
+// startImagePull runs an image pull in the background
+func (m *Model) startImagePull(status string, pull func(ctx context.Context) imagePullDoneMsg) (Model, tea.Cmd) {
+	m.state = StatePullingImage
+	m.statusMsg = status
+	m.startTime = time.Now()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.ctx = ctx
+	m.cancelFn = cancel
+
+	return *m, tea.Batch(
+		m.spinner.Tick,
+		func() tea.Msg { return pull(ctx) },
+		tea.Tick(time.Second, func(t time.Time) tea.Msg { return tickMsg(t) }),
+	)
+}
+
+// finishImagePull switches to the pulled image and records history for rollback
+func (m *Model) finishImagePull(msg imagePullDoneMsg) (Model, tea.Cmd) {
+	if msg.err != nil && m.ctx.Err() == context.Canceled {
+		return *m, nil
+	}
+	m.state = StateInput
+	m.textarea.Focus()
 
-	// Map short category names to full names
-	categoryMap := map[string]ValidatorCategory{
-		"game":     CategoryGame,
-		"hft":      CategoryHFT,
-		"embedded": CategoryEmbedded,
-		"security": CategorySecurity,
-		"perf":     CategoryPerformance,
-		"core":     CategoryCore,
+	if msg.err != nil {
+		m.addOutput(m.styles.Error.Render("Image pull failed: " + msg.err.Error()))
+		return *m, nil
 	}
 
+	if !msg.rollback {
+		if msg.from == msg.to {
+			m.addOutput(m.styles.Success.Render("Validator image is up to date"))
+			return *m, nil
+		}
+		if err := m.imageHistory.BeforeUpdate(msg.ref, msg.from); err != nil {
+			m.addOutput(m.styles.Warning.Render("Could not record the previous digest: " + err.Error()))
+		}
+		m.imageRecorded = ""
+		m.addOutput(m.styles.Success.Render(fmt.Sprintf("Updated %s (%s -> %s)", msg.ref, shortDigest(msg.from), shortDigest(msg.to))))
+		m.addOutput(m.styles.Dim.Render("  If validation behaves differently, /image rollback returns to the previous image"))
+		return *m, nil
+	}
+
+	m.useImage(ImageSelection{Ref: msg.ref, Profile: m.image.Profile, Source: m.image.Source})
+	m.imageRecorded = ""
+	m.addOutput(m.styles.Success.Render(fmt.Sprintf("Rolled back %s to %s", imageRepo(msg.ref), shortDigest(msg.to))))
+	if m.image.Profile == "" {
+		m.addOutput(m.styles.Dim.Render("  For this session only; select an image profile to keep the pin"))
+		return *m, nil
+	}
+	profile := m.config.Settings.Container.Profiles[m.image.Profile]
+	profile.Digest = msg.to
+	m.config.Settings.Container.Profiles[m.image.Profile] = profile
+	if err := m.config.SaveSettings(); err != nil {
+		m.addOutput(m.styles.Warning.Render("Rolled back for this session, but saving the pin failed: " + err.Error()))
+	} else {
+		m.addOutput(m.styles.Dim.Render(fmt.Sprintf("  Pinned profile %s; /config image unpin to follow updates again", m.image.Profile)))
+	}
+	return *m, nil
+}
+
+// showValidatorConfig displays and manages validator configuration
+func (m *Model) showValidatorConfig(args []string) {
+	m.addOutput("")
+
 	// If arg provided, toggle that category or specific validator
 	if len(args) > 0 {
 		arg := strings.ToLower(args[0])
 
 		// Check if it's a category
-		if cat, ok := categoryMap[arg]; ok {
+		cat, ok := configCategories[arg]
+		if _, isPluginCategory := m.validatorConfig.ByCategory()[ValidatorCategory(arg)]; !ok && isPluginCategory {
+			cat, ok = ValidatorCategory(arg), true
+		}
+		if ok {
 			// Toggle entire category
-			validators := GetValidatorsByCategory()[cat]
+			validators := m.validatorConfig.ByCategory()[cat]
 			// Check if any are enabled
 			anyEnabled := false
 			for _, v := range validators {
@@ -1971,7 +5914,19 @@ func (m *Model) showValidatorConfig(args []string) {
 		} else {
 			// Try to find validator by ID
 			found := false
-			for _, v := range AllValidators() {
+			for _, v := range m.validatorConfig.Validators() {
+				if strings.EqualFold(string(v.ID), arg) && len(args) > 1 {
+					// /config <validator> key=value... sets its arguments and enables it
+					if err := m.validatorConfig.CheckArg(v.ID, strings.Join(args[1:], " ")); err != nil {
+						m.addOutput(m.styles.Error.Render(err.Error()))
+					} else {
+						m.validatorConfig.SetArg(v.ID, strings.Join(args[1:], " "))
+						m.validatorConfig.Enabled[v.ID] = true
+						m.addOutput(m.styles.Success.Render(fmt.Sprintf("Enabled: %s (%s)", v.Name, m.validatorConfig.GetArg(v.ID))))
+					}
+					found = true
+					break
+				}
 				if strings.EqualFold(string(v.ID), arg) {
 					newState := m.validatorConfig.Toggle(v.ID)
 					if newState {
@@ -1994,8 +5949,8 @@ func (m *Model) showValidatorConfig(args []string) {
 	m.addOutput(m.styles.Accent.Render("Validator Configuration"))
 	m.addOutput("")
 
-	byCategory := GetValidatorsByCategory()
-	categoryOrder := []ValidatorCategory{CategoryCore, CategoryGame, CategoryHFT, CategoryEmbedded, CategorySecurity, CategoryPerformance}
+	byCategory := m.validatorConfig.ByCategory()
+	categoryOrder := []ValidatorCategory{CategoryCore, CategoryGame, CategoryHFT, CategoryEmbedded, CategorySecurity, CategoryPerformance, CategoryGPU}
 	categoryNames := map[ValidatorCategory]string{
 		CategoryCore:        "Core (always run)",
 		CategoryGame:        "Game Development (/config game)",
@@ -2003,7 +5958,17 @@ func (m *Model) showValidatorConfig(args []string) {
 		CategoryEmbedded:    "Embedded Systems (/config embedded)",
 		CategorySecurity:    "Security (/config security)",
 		CategoryPerformance: "Performance (/config perf)",
+		CategoryGPU:         "GPU/CUDA (/config gpu)",
 	}
+	var pluginCategories []ValidatorCategory
+	for cat := range byCategory {
+		if _, ok := categoryNames[cat]; !ok {
+			pluginCategories = append(pluginCategories, cat)
+			categoryNames[cat] = fmt.Sprintf("%s plugins (/config %s)", cat, cat)
+		}
+	}
+	sort.Slice(pluginCategories, func(i, j int) bool { return pluginCategories[i] < pluginCategories[j] })
+	categoryOrder = append(categoryOrder, pluginCategories...)
 
 	for _, cat := range categoryOrder {
 		validators := byCategory[cat]
@@ -2025,7 +5990,7 @@ func (m *Model) showValidatorConfig(args []string) {
 		m.addOutput("")
 	}
 
-	m.addOutput(m.styles.Dim.Render("Usage: /config <category|validator> to toggle"))
+	m.addOutput(m.styles.Dim.Render("Usage: /config <category|validator> to toggle, /config <validator> key=value to set its arguments"))
 }
 
 // allPassed checks if all validation results passed
@@ -2040,30 +6005,9 @@ func allPassed(results []ValidationResult) bool {
 
 // runDomainValidators executes enabled domain-specific validators
 func (m *Model) runDomainValidators(ctx context.Context) []DomainValidationResult {
-	// Create temp directory for validation
-	tmpDir, err := os.MkdirTemp("", "bjarne-domain-*")
-	if err != nil {
-		return nil
+	files := m.currentFiles
+	if len(files) == 0 {
+		files = []CodeFile{{Filename: "code.cpp", Content: m.currentCode}}
 	}
-	defer func() { _ = os.RemoveAll(tmpDir) }()
-
-	// Determine code and filename
-	var code, filename string
-	if len(m.currentFiles) > 0 {
-		// Use main file for domain validation
-		code = m.currentFiles[0].Content
-		filename = m.currentFiles[0].Filename
-	} else {
-		code = m.currentCode
-		filename = "code.cpp"
-	}
-
-	// Write code to temp directory
-	codePath := filepath.Join(tmpDir, filename)
-	if err := os.WriteFile(codePath, []byte(code), 0600); err != nil {
-		return nil
-	}
-
-	// Run domain validators
-	return m.container.RunDomainValidators(ctx, tmpDir, code, filename, m.validatorConfig)
+	return m.container.RunDomainValidatorsOn(ctx, files, m.validatorConfig)
 }
@@ -5,12 +5,14 @@ import (
 )
 
 func TestEscalationLogic(t *testing.T) {
-	// Create a minimal model for testing escalation
+	// Create a minimal model for testing escalation (no provider: canonical names stay as-is)
 	cfg := &Config{
-		GenerateModel:     "haiku",
-		OracleModel:       "opus",
-		EscalationModels:  []string{"sonnet", "opus"},
-		EscalateOnFailure: true,
+		GenerateModel:      "haiku",
+		OracleModel:        "opus",
+		ComplexityModels:   ComplexityModels{Easy: "haiku", Medium: "sonnet"},
+		EscalationModels:   []string{"sonnet", "opus"},
+		EscalationAttempts: []int{5, 5, 5},
+		EscalateOnFailure:  true,
 	}
 
 	t.Run("initial state", func(t *testing.T) {
@@ -22,8 +24,8 @@ func TestEscalationLogic(t *testing.T) {
 		}
 		// EASY uses Haiku initially
 		got := m.getCurrentModel()
-		if got != "global.anthropic.claude-haiku-4-5-20251001-v1:0" {
-			t.Errorf("getCurrentModel() = %q, want haiku model", got)
+		if got != "haiku" {
+			t.Errorf("getCurrentModel() = %q, want haiku", got)
 		}
 	})
 
@@ -35,6 +37,12 @@ func TestEscalationLogic(t *testing.T) {
 		if got != "opus" {
 			t.Errorf("getCurrentModel() = %q, want opus", got)
 		}
+
+		// Opus is the top of the ladder - no de-escalation to Sonnet
+		m.totalFixAttempts = 6
+		if got := m.getCurrentModel(); got != "opus" {
+			t.Errorf("attempt 6: getCurrentModel() = %q, want opus", got)
+		}
 	})
 
 	t.Run("canEscalate allows 15 attempts", func(t *testing.T) {
@@ -63,7 +71,7 @@ func TestEscalationLogic(t *testing.T) {
 		for i := 0; i < 5; i++ {
 			m.advanceEscalation()
 			got := m.getCurrentModel()
-			if got != "global.anthropic.claude-haiku-4-5-20251001-v1:0" {
+			if got != "haiku" {
 				t.Errorf("attempt %d: getCurrentModel() = %q, want haiku", m.totalFixAttempts, got)
 			}
 		}
@@ -71,7 +79,7 @@ func TestEscalationLogic(t *testing.T) {
 		// Attempts 6-10: Sonnet
 		m.advanceEscalation() // attempt 6
 		got := m.getCurrentModel()
-		if got != "global.anthropic.claude-sonnet-4-5-20250929-v1:0" {
+		if got != "sonnet" {
 			t.Errorf("attempt 6: getCurrentModel() = %q, want sonnet", got)
 		}
 
@@ -89,8 +97,14 @@ func TestEscalationLogic(t *testing.T) {
 
 		// MEDIUM starts with Sonnet
 		got := m.getCurrentModel()
-		if got != "global.anthropic.claude-sonnet-4-5-20250929-v1:0" {
-			t.Errorf("MEDIUM getCurrentModel() = %q, want sonnet model", got)
+		if got != "sonnet" {
+			t.Errorf("MEDIUM getCurrentModel() = %q, want sonnet", got)
+		}
+
+		// Attempts 6-10 stay on Sonnet (already at that rung)
+		m.totalFixAttempts = 8
+		if got := m.getCurrentModel(); got != "sonnet" {
+			t.Errorf("attempt 8: getCurrentModel() = %q, want sonnet", got)
 		}
 
 		// At attempt 11+, should use Opus
@@ -100,4 +114,42 @@ func TestEscalationLogic(t *testing.T) {
 			t.Errorf("attempt 11: getCurrentModel() = %q, want opus", got)
 		}
 	})
+
+	t.Run("pinned model overrides complexity", func(t *testing.T) {
+		m := Model{config: cfg, difficulty: "EASY", modelOverride: "opus"}
+		m.resetEscalation()
+
+		if got := m.getModelForComplexity("EASY"); got != "opus" {
+			t.Errorf("getModelForComplexity() = %q, want pinned opus", got)
+		}
+		if got := m.getCurrentModel(); got != "opus" {
+			t.Errorf("getCurrentModel() = %q, want pinned opus", got)
+		}
+
+		m.modelOverride = ""
+		if got := m.getModelForComplexity("EASY"); got != "haiku" {
+			t.Errorf("after auto: getModelForComplexity() = %q, want haiku", got)
+		}
+	})
+
+	t.Run("custom ladder from settings", func(t *testing.T) {
+		custom := *cfg
+		custom.ComplexityModels = ComplexityModels{Easy: "my-small-model"}
+		custom.EscalationModels = []string{"my-large-model"}
+		custom.EscalationAttempts = []int{2, 3}
+
+		m := Model{config: &custom, difficulty: "EASY"}
+		m.resetEscalation()
+
+		want := []string{"my-small-model", "my-small-model", "my-large-model", "my-large-model", "my-large-model"}
+		for i, w := range want {
+			m.advanceEscalation()
+			if got := m.getCurrentModel(); got != w {
+				t.Errorf("attempt %d: getCurrentModel() = %q, want %q", i+1, got, w)
+			}
+		}
+		if m.canEscalate() {
+			t.Error("should be exhausted after 2+3 attempts")
+		}
+	})
 }
@@ -0,0 +1,133 @@
+package main
+
+// maxUndoSteps bounds the turns /undo can roll back
+const maxUndoSteps = 20
+
+// turnSnapshot is the session state before (or after) one prompt and its responses
+type turnSnapshot struct {
+	prompt string // The prompt the turn started with, for display
+
+	conversation   []Message
+	currentCode    string
+	currentFiles   []CodeFile
+	validated      bool
+	analyzed       bool
+	originalPrompt string
+	analysis       string
+	examples       *ExampleTests
+	dod            *DefinitionOfDone
+	awaitingDoD    bool
+	plan           *ProjectPlan
+	planPending    bool
+	planSkipped    bool
+	difficulty     string
+	intent         string
+	failedResults  []ValidationResult
+	lastResults    []ValidationResult
+	versions       []CodeVersion
+	lastConfidence int
+	lastSummary    string
+	tokens         TokenTracker
+
+	// Escalation state
+	currentIteration   int
+	currentModelIndex  int
+	totalFixAttempts   int
+	fixesSinceFresh    int
+	failures           failureHistory
+	lastValidationErrs string
+	failureSigs        map[string]FixPattern
+	modelsUsed         []string
+	reviewFailures     int
+}
+
+// undoHistory holds the snapshots for /undo and /redo
+// A new prompt clears the redo side, as in an editor
+type undoHistory struct {
+	undo []turnSnapshot
+	redo []turnSnapshot
+}
+
+// Push records the state before a new turn, dropping the oldest beyond maxUndoSteps
+func (h *undoHistory) Push(s turnSnapshot) {
+	h.undo = append(h.undo, s)
+	if len(h.undo) > maxUndoSteps {
+		h.undo = h.undo[len(h.undo)-maxUndoSteps:]
+	}
+	h.redo = nil
+}
+
+// Undo returns the state before the last turn and keeps current for Redo
+func (h *undoHistory) Undo(current turnSnapshot) (turnSnapshot, bool) {
+	if len(h.undo) == 0 {
+		return turnSnapshot{}, false
+	}
+	s := h.undo[len(h.undo)-1]
+	h.undo = h.undo[:len(h.undo)-1]
+	current.prompt = s.prompt
+	h.redo = append(h.redo, current)
+	return s, true
+}
+
+// Redo returns the state after the last undone turn and keeps current for Undo
+func (h *undoHistory) Redo(current turnSnapshot) (turnSnapshot, bool) {
+	if len(h.redo) == 0 {
+		return turnSnapshot{}, false
+	}
+	s := h.redo[len(h.redo)-1]
+	h.redo = h.redo[:len(h.redo)-1]
+	current.prompt = s.prompt
+	h.undo = append(h.undo, current)
+	return s, true
+}
+
+// Clear forgets all snapshots
+func (h *undoHistory) Clear() {
+	h.undo, h.redo = nil, nil
+}
+
+// cloneExamples copies example tests so later /tests edits do not reach a snapshot
+func cloneExamples(e *ExampleTests) *ExampleTests {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	c.Tests = append([]TestCase(nil), e.Tests...)
+	return &c
+}
+
+// clonePlan copies a project plan so later /plan edits do not reach a snapshot
+func clonePlan(p *ProjectPlan) *ProjectPlan {
+	if p == nil {
+		return nil
+	}
+	c := &ProjectPlan{Files: make([]PlannedFile, len(p.Files))}
+	for i, f := range p.Files {
+		f.DependsOn = append([]string(nil), f.DependsOn...)
+		c.Files[i] = f
+	}
+	return c
+}
+
+// cloneDoD copies a Definition of Done
+func cloneDoD(d *DefinitionOfDone) *DefinitionOfDone {
+	if d == nil {
+		return nil
+	}
+	c := *d
+	return &c
+}
+
+// clone copies a failure history so later failures do not reach a snapshot
+func (h failureHistory) clone() failureHistory {
+	c := failureHistory{attempts: h.attempts}
+	if h.counts != nil {
+		c.counts = make(map[string]int, len(h.counts))
+		c.kinds = make(map[string]FixPattern, len(h.kinds))
+		for sig, n := range h.counts {
+			c.counts[sig] = n
+			c.kinds[sig] = h.kinds[sig]
+		}
+	}
+	return c
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestUndoHistory(t *testing.T) {
+	var h undoHistory
+	if _, ok := h.Undo(turnSnapshot{}); ok {
+		t.Fatal("Undo() on an empty history succeeded")
+	}
+
+	h.Push(turnSnapshot{prompt: "first", currentCode: ""})
+	h.Push(turnSnapshot{prompt: "second", currentCode: "v1"})
+
+	s, ok := h.Undo(turnSnapshot{currentCode: "v2"})
+	if !ok || s.prompt != "second" || s.currentCode != "v1" {
+		t.Fatalf("Undo() = %+v, %v; want the state before the second prompt", s, ok)
+	}
+	s, ok = h.Redo(turnSnapshot{currentCode: "v1"})
+	if !ok || s.prompt != "second" || s.currentCode != "v2" {
+		t.Fatalf("Redo() = %+v, %v; want the state after the second prompt", s, ok)
+	}
+	if _, ok := h.Redo(turnSnapshot{}); ok {
+		t.Error("Redo() with nothing undone succeeded")
+	}
+
+	// A new prompt discards what was undone
+	h.Undo(turnSnapshot{currentCode: "v2"})
+	h.Push(turnSnapshot{prompt: "third", currentCode: "v1"})
+	if _, ok := h.Redo(turnSnapshot{}); ok {
+		t.Error("Redo() after a new prompt succeeded")
+	}
+
+	for i := 0; i < maxUndoSteps+5; i++ {
+		h.Push(turnSnapshot{})
+	}
+	if len(h.undo) != maxUndoSteps {
+		t.Errorf("history kept %d snapshots, want %d", len(h.undo), maxUndoSteps)
+	}
+}
+
+func TestSnapshotRestoreTurn(t *testing.T) {
+	m := Model{config: &Config{}, tokenTracker: NewTokenTracker(0, 0)}
+	m.currentCode = "int main() {}"
+	m.currentFiles = []CodeFile{{Filename: "code.cpp", Content: m.currentCode}}
+	m.conversation = []Message{{Role: "user", Content: "hello"}, {Role: "assistant", Content: "analysis"}}
+	m.analyzed = true
+	m.examples = &ExampleTests{Tests: []TestCase{{FunctionCall: "f(1)", Expected: "2"}}}
+	m.plan = &ProjectPlan{Files: []PlannedFile{{Path: "a.h", DependsOn: []string{"b.h"}}}}
+	m.tokenTracker.Add(100, 50)
+	m.totalFixAttempts = 2
+	race := FixPattern{Gate: "tsan", Message: "data race"}
+	m.failures.Add(map[string]FixPattern{race.signature(): race})
+
+	s := m.snapshotTurn("use a lock-free queue")
+
+	// The turn changes everything, including in place
+	m.currentCode = "int main() { return 1; }"
+	m.conversation = append(m.conversation, Message{Role: "user", Content: "use a lock-free queue"})
+	m.analyzed = false
+	m.examples.Tests[0].Expected = "3"
+	m.plan.Files[0].DependsOn[0] = "c.h"
+	m.tokenTracker.Add(1000, 500)
+	m.totalFixAttempts = 5
+	m.failures.Add(map[string]FixPattern{race.signature(): race})
+
+	m.restoreTurn(s)
+	if m.currentCode != "int main() {}" || len(m.conversation) != 2 || !m.analyzed {
+		t.Errorf("restoreTurn() left code %q, %d messages, analyzed %v", m.currentCode, len(m.conversation), m.analyzed)
+	}
+	if m.examples.Tests[0].Expected != "2" || m.plan.Files[0].DependsOn[0] != "b.h" {
+		t.Error("restoreTurn() returned examples or a plan edited after the snapshot")
+	}
+	if in, out, _ := m.tokenTracker.GetUsage(); in != 100 || out != 50 {
+		t.Errorf("restoreTurn() token usage = %d/%d, want 100/50", in, out)
+	}
+	if m.totalFixAttempts != 2 || m.failures.attempts != 1 || m.failures.counts[race.signature()] != 1 {
+		t.Errorf("restoreTurn() escalation = %d attempts, history %+v", m.totalFixAttempts, m.failures)
+	}
+}
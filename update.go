@@ -30,7 +30,10 @@ func CheckForUpdate(ctx context.Context) (string, bool, error) {
 	}
 
 	// Create HTTP client with timeout
-	client := &http.Client{Timeout: 5 * time.Second}
+	client, err := NewHTTPClient(endpointUpdates, 5*time.Second)
+	if err != nil {
+		return "", false, err
+	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, githubReleaseURL, nil)
 	if err != nil {
@@ -123,6 +126,9 @@ func GetUpdateCommand() string {
 
 // PrintUpdateNotice prints an update notification if a newer version is available
 func PrintUpdateNotice() {
+	if offlineMode {
+		return
+	}
 	// Run check with short timeout (non-blocking to startup)
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
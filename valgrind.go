@@ -0,0 +1,120 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Valgrind thread checkers (settings: valgrind.tool)
+const (
+	ValgrindToolOff      = "off"
+	ValgrindToolHelgrind = "helgrind"
+	ValgrindToolDRD      = "drd"
+)
+
+// valgrindTools are the tools valgrind.tool accepts, "off" first
+var valgrindTools = []string{ValgrindToolOff, ValgrindToolHelgrind, ValgrindToolDRD}
+
+// valgrindPrefixPattern is the "==PID== " valgrind puts before each line of its report
+var valgrindPrefixPattern = regexp.MustCompile(`^==\d+== ?`)
+
+// valgrindFramePattern matches a frame with source: "at 0x109199: worker (code.c:8)"
+// With --fullpath-after= the file is the full path, e.g. /src/code.c
+var valgrindFramePattern = regexp.MustCompile(`^(?:at|by) 0x[0-9A-Fa-f]+: (.+) \((\S+):(\d+)\)$`)
+
+// valgrindErrorPattern matches the first line of a Helgrind or DRD error
+var valgrindErrorPattern = regexp.MustCompile(`^(Possible data race|Conflicting (load|store)|Thread #\d+:? |` +
+	`(Mutex|Destroying|Recursive|Reader-writer lock|Barrier|Condition variable|Semaphore|Probably a race|The object at address)\b)`)
+
+// valgrindContextPattern matches lines that explain an error, kept with its frames
+var valgrindContextPattern = regexp.MustCompile(`^(This conflicts with|Locks held:|Address 0x|Location 0x|Other segment|Lock at|Required order|followed by a later)`)
+
+// SetValgrindSettings configures the Helgrind/DRD gate
+func (c *ContainerRuntime) SetValgrindSettings(settings ValgrindSettings) {
+	c.valgrind = settings
+}
+
+// valgrindTool is the thread checker to run, or "" when the gate is off
+// --gates helgrind or --gates drd picks that tool whatever valgrind.tool says
+func (c *ContainerRuntime) valgrindTool() string {
+	tool := c.valgrind.Tool
+	for _, name := range c.gates.Only {
+		if name == ValgrindToolHelgrind || name == ValgrindToolDRD {
+			tool = name
+			break
+		}
+	}
+	if tool != ValgrindToolHelgrind && tool != ValgrindToolDRD || !c.gates.Enabled(tool) {
+		return ""
+	}
+	return tool
+}
+
+// valgrindGate runs raw-pthread code under Helgrind or DRD after TSAN passed
+// Their lock-order and pthread API checks complement TSAN's race detection; it reports false when the gate is off
+func (c *ContainerRuntime) valgrindGate(usesPthreads bool, srcArgs string, runStage func(stage string, command ...string) ValidationResult) (ValidationResult, bool) {
+	tool := c.valgrindTool()
+	if tool == "" || !usesPthreads {
+		return ValidationResult{}, false
+	}
+	return runStage(tool, "sh", "-c", c.valgrindCommand(tool, srcArgs)), true
+}
+
+// valgrindCommand builds the program without sanitizers (valgrind cannot run them) and runs it under the tool
+func (c *ContainerRuntime) valgrindCommand(tool, srcArgs string) string {
+	run := "valgrind --tool=" + tool + " -q --error-exitcode=1 --fullpath-after= /tmp/valgrind"
+	return c.cxx(c.stdFlag()+" -g -O1 -pthread -o /tmp/valgrind "+srcArgs) + " && " + c.programCommand("", run)
+}
+
+// codeUsesPthreads reports whether code calls the pthread API directly, where Helgrind and DRD know the most
+func codeUsesPthreads(code string) bool {
+	return strings.Contains(code, "<pthread.h>") || strings.Contains(code, "pthread_create")
+}
+
+// ParseValgrindOutput extracts Helgrind or DRD errors as Diagnostics
+// Frames go into Context as "func at file:line", as for the sanitizers, so locations and snippets work the same way
+func ParseValgrindOutput(output, tool string) []Diagnostic {
+	var diagnostics []Diagnostic
+	var current *Diagnostic
+	for _, line := range strings.Split(output, "\n") {
+		if !valgrindPrefixPattern.MatchString(line) {
+			continue
+		}
+		line = strings.TrimSpace(valgrindPrefixPattern.ReplaceAllString(line, ""))
+		if line == "" {
+			continue
+		}
+		// Separators and thread announcements end an error; their frames are where threads started
+		announcement := strings.HasPrefix(line, "---") || strings.Contains(line, " was created") || strings.Contains(line, " is the program's root thread")
+		if announcement || valgrindErrorPattern.MatchString(line) {
+			if current != nil {
+				diagnostics = append(diagnostics, *current)
+				current = nil
+			}
+			if !announcement {
+				current = &Diagnostic{Level: LevelError, Message: line, Check: tool}
+			}
+			continue
+		}
+		if current == nil || len(current.Context) >= 500 {
+			continue
+		}
+		entry := ""
+		if m := valgrindFramePattern.FindStringSubmatch(line); m != nil {
+			entry = m[1] + " at " + m[2] + ":" + m[3]
+		} else if valgrindContextPattern.MatchString(line) {
+			entry = line
+		}
+		if entry == "" {
+			continue
+		}
+		if current.Context != "" {
+			current.Context += "\n"
+		}
+		current.Context += entry
+	}
+	if current != nil {
+		diagnostics = append(diagnostics, *current)
+	}
+	return diagnostics
+}
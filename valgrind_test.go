@@ -0,0 +1,115 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const helgrindRace = `==12== ---Thread-Announcement------------------------------------------
+==12==
+==12== Thread #3 was created
+==12==    at 0x49A6C3F: clone (clone.S:76)
+==12==    by 0x4850F5A: pthread_create@* (hg_intercepts.c:445)
+==12==    by 0x1091F4: main (/src/code.cpp:14)
+==12==
+==12== ----------------------------------------------------------------
+==12==
+==12== Possible data race during write of size 4 at 0x10C014 by thread #3
+==12== Locks held: none
+==12==    at 0x109199: worker(void*) (/src/code.cpp:6)
+==12==    by 0x4850B1A: mythread_wrapper (/build/valgrind/helgrind/hg_intercepts.c:406)
+==12==
+==12== This conflicts with a previous write of size 4 by thread #2
+==12== Locks held: none
+==12==    at 0x109199: worker(void*) (/src/code.cpp:6)
+==12==
+==12==  Address 0x10c014 is 0 bytes inside data symbol "counter"
+==12==
+==12== ----------------------------------------------------------------
+==12==
+==12== Thread #1: lock order "0x10C040 before 0x10C080" violated
+==12==
+==12== Observed (incorrect) order is: acquisition of lock at 0x10C080
+==12==    at 0x484BE3C: pthread_mutex_lock (hg_intercepts.c:942)
+==12==    by 0x1091C8: lock_both() (/src/code.cpp:10)
+`
+
+const drdRace = `==40== Thread 3:
+==40== Conflicting store by thread 3 at 0x0010c014 size 4
+==40==    at 0x109199: worker(void*) (/src/code.cpp:6)
+==40==    by 0x4845E3D: vgDrd_thread_wrapper (drd_pthread_intercepts.c:449)
+==40== Location 0x10c014 is 0 bytes inside global var "counter"
+==40== declared at code.cpp:3
+`
+
+func TestParseValgrindOutput(t *testing.T) {
+	diags := ParseValgrindOutput(helgrindRace, "helgrind")
+	if len(diags) != 2 {
+		t.Fatalf("ParseValgrindOutput(helgrind) = %+v, want 2 errors", diags)
+	}
+	race := diags[0]
+	if race.Check != "helgrind" || !strings.HasPrefix(race.Message, "Possible data race during write") {
+		t.Errorf("race = %+v", race)
+	}
+	for _, want := range []string{"worker(void*) at /src/code.cpp:6", "This conflicts with a previous write", "Address 0x10c014"} {
+		if !strings.Contains(race.Context, want) {
+			t.Errorf("race.Context = %q, missing %q", race.Context, want)
+		}
+	}
+	if strings.Contains(race.Context, "main") {
+		t.Errorf("race.Context = %q, includes the thread announcement's frames", race.Context)
+	}
+	if name, line, _ := locateDiagnostic(race, "code.cpp", map[string]bool{"code.cpp": true}); name != "code.cpp" || line != 6 {
+		t.Errorf("locateDiagnostic(race) = %s:%d, want code.cpp:6", name, line)
+	}
+	if !strings.Contains(diags[1].Message, "lock order") || !strings.Contains(diags[1].Context, "lock_both() at /src/code.cpp:10") {
+		t.Errorf("lock order = %+v", diags[1])
+	}
+
+	diags = ParseValgrindOutput(drdRace, "drd")
+	if len(diags) != 1 || !strings.HasPrefix(diags[0].Message, "Conflicting store by thread 3") || !strings.Contains(diags[0].Context, "Location 0x10c014") {
+		t.Errorf("ParseValgrindOutput(drd) = %+v", diags)
+	}
+	if diags := ParseValgrindOutput("WARNING: ThreadSanitizer: data race", "drd"); len(diags) != 0 {
+		t.Errorf("ParseValgrindOutput(tsan) = %+v, want none", diags)
+	}
+}
+
+func TestValgrindTool(t *testing.T) {
+	tests := []struct {
+		name  string
+		tool  string
+		gates GateSelection
+		want  string
+	}{
+		{"off", ValgrindToolOff, GateSelection{}, ""},
+		{"unset", "", GateSelection{}, ""},
+		{"helgrind", ValgrindToolHelgrind, GateSelection{}, "helgrind"},
+		{"drd", ValgrindToolDRD, GateSelection{}, "drd"},
+		{"asked for with --gates", ValgrindToolOff, GateSelection{Only: []string{"tsan", "drd"}}, "drd"},
+		{"skipped", ValgrindToolHelgrind, GateSelection{Skip: []string{"helgrind"}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &ContainerRuntime{valgrind: ValgrindSettings{Tool: tt.tool}, gates: tt.gates}
+			if got := c.valgrindTool(); got != tt.want {
+				t.Errorf("valgrindTool() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	c := &ContainerRuntime{valgrind: ValgrindSettings{Tool: ValgrindToolDRD}}
+	if got := c.valgrindCommand("drd", "/src/code.cpp"); !strings.Contains(got, "valgrind --tool=drd -q --error-exitcode=1") || strings.Contains(got, "-fsanitize") {
+		t.Errorf("valgrindCommand() = %q", got)
+	}
+}
+
+func TestFormatErrorForLLMValgrind(t *testing.T) {
+	code := "#include <pthread.h>\nint counter = 0;\n\nvoid* worker(void*) {\n    for (int i = 0; i < 1000; i++)\n        counter++;\n    return nullptr;\n}\n"
+	got := FormatErrorForLLM("helgrind", helgrindRace, []CodeFile{{Filename: "code.cpp", Content: code}})
+	for _, want := range []string{"[helgrind]", "Possible data race", "Source:", "counter++;"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("FormatErrorForLLM() = %q, missing %q", got, want)
+		}
+	}
+}
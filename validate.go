@@ -0,0 +1,511 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// stdinName stands for piped code in progress lines and the summary
+const stdinName = "<stdin>"
+
+// stdinFilename names piped code in the container when --filename is not given
+const stdinFilename = "stdin.cpp"
+
+// defaultValidateJobs is how many files --validate checks at once; each runs its own containers
+const defaultValidateJobs = 4
+
+// validateUsage is printed for bad `bjarne --validate` arguments
+const validateUsage = `Usage: bjarne --validate [options] <file.cpp | dir | glob | -> ...
+  -                  Read code from stdin
+  -r, --recursive    Include subdirectories of directory arguments
+  --include <globs>  Comma-separated patterns for files found in directories (default: C/C++ sources)
+  --exclude <globs>  Comma-separated patterns to leave out, e.g. 'third_party/**,*_test.cpp'
+  -j, --jobs N       Files to validate in parallel (default: 4)
+  --gates <list>     Run only these gates, e.g. clang-tidy,compile,asan
+  --skip <list>      Skip these gates, e.g. msan,tsan
+  --lang <std>       C++ standard: c++11, c++14, c++17 (default), c++20, c++23
+  --filename <name>  Name for code read from stdin (default: stdin.cpp)`
+
+// validateOptions are the parsed `bjarne --validate` arguments
+type validateOptions struct {
+	Files    []string // Paths to validate; "-" reads stdin
+	Standard string   // Normalized --lang value (empty = default)
+	Filename string   // Name given to stdin input in the container
+	Jobs     int      // Files validated concurrently
+	Gates    GateSelection
+
+	Recursive bool     // Walk subdirectories of directory arguments
+	Include   []string // Glob patterns selecting files inside directories (empty = C/C++ sources)
+	Exclude   []string // Glob patterns removing files found in directories or by globs
+}
+
+// validateInput is one piece of code to validate
+type validateInput struct {
+	Name     string // Shown in output: the path, or "<stdin>"
+	Filename string // Base name in the container
+	Content  string
+	Err      error
+}
+
+// parseValidateArgs parses the arguments after --validate
+func parseValidateArgs(args []string) (validateOptions, error) {
+	opts := validateOptions{Jobs: defaultValidateJobs}
+	value := func(i int) (string, error) {
+		if i+1 >= len(args) || args[i+1] == "" {
+			return "", fmt.Errorf("%s needs a value", args[i])
+		}
+		return args[i+1], nil
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--lang" || arg == "--std":
+			v, err := value(i)
+			if err != nil {
+				return opts, err
+			}
+			if opts.Standard, err = ParseStandard(v); err != nil {
+				return opts, err
+			}
+			i++
+		case arg == "--jobs" || arg == "-j":
+			v, err := value(i)
+			if err != nil {
+				return opts, err
+			}
+			if opts.Jobs, err = strconv.Atoi(v); err != nil || opts.Jobs < 1 {
+				return opts, fmt.Errorf("%s must be a positive number, got %q", arg, v)
+			}
+			i++
+		case arg == "--gates" || arg == "--skip":
+			v, err := value(i)
+			if err != nil {
+				return opts, err
+			}
+			gates, err := ParseGateList(v)
+			if err != nil {
+				return opts, err
+			}
+			if arg == "--gates" {
+				opts.Gates.Only = append(opts.Gates.Only, gates...)
+			} else {
+				opts.Gates.Skip = append(opts.Gates.Skip, gates...)
+			}
+			i++
+		case arg == "--recursive" || arg == "-r":
+			opts.Recursive = true
+		case arg == "--include" || arg == "--exclude":
+			v, err := value(i)
+			if err != nil {
+				return opts, err
+			}
+			patterns, err := parseGlobList(v)
+			if err != nil {
+				return opts, err
+			}
+			if arg == "--include" {
+				opts.Include = append(opts.Include, patterns...)
+			} else {
+				opts.Exclude = append(opts.Exclude, patterns...)
+			}
+			i++
+		case arg == "--filename":
+			v, err := value(i)
+			if err != nil {
+				return opts, err
+			}
+			if !isSourceFile(v) {
+				return opts, fmt.Errorf("--filename %s is not a C/C++ source file name", v)
+			}
+			opts.Filename = filepath.Base(v)
+			i++
+		case arg == "-":
+			for _, f := range opts.Files {
+				if f == "-" {
+					return opts, fmt.Errorf("stdin (-) can only be given once")
+				}
+			}
+			opts.Files = append(opts.Files, arg)
+		case strings.HasPrefix(arg, "-"):
+			return opts, fmt.Errorf("unknown validate option: %s", arg)
+		default:
+			opts.Files = append(opts.Files, arg)
+		}
+	}
+
+	if len(opts.Files) == 0 {
+		return opts, fmt.Errorf("no files given")
+	}
+	if opts.Filename != "" && !slices.Contains(opts.Files, "-") {
+		return opts, fmt.Errorf("--filename only applies to code read from stdin (-)")
+	}
+	if !anyGateEnabled(opts.Gates) {
+		return opts, fmt.Errorf("--skip removes every gate given to --gates")
+	}
+	return opts, nil
+}
+
+// parseGlobList splits a comma-separated list of glob patterns and checks their syntax
+func parseGlobList(list string) ([]string, error) {
+	var patterns []string
+	for _, p := range strings.Split(list, ",") {
+		p = strings.Trim(strings.TrimPrefix(filepath.ToSlash(strings.TrimSpace(p)), "./"), "/")
+		if p == "" {
+			continue
+		}
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+		patterns = append(patterns, p)
+	}
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("empty pattern list")
+	}
+	return patterns, nil
+}
+
+// matchGlob matches a slash-separated relative path against a pattern
+// A pattern without a slash matches the file name or any directory name on the path
+// (like .gitignore); "**" matches any number of directories
+func matchGlob(pattern, rel string) bool {
+	segments := strings.Split(rel, "/")
+	if !strings.Contains(pattern, "/") {
+		for _, s := range segments {
+			if ok, _ := path.Match(pattern, s); ok {
+				return true
+			}
+		}
+		return false
+	}
+	return matchSegments(strings.Split(pattern, "/"), segments)
+}
+
+// matchSegments matches path segments against pattern segments, expanding "**"
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
+
+// matchesAny reports whether rel matches one of the patterns
+func matchesAny(patterns []string, rel string) bool {
+	for _, p := range patterns {
+		if matchGlob(p, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// expandValidatePaths replaces directory and glob arguments with the files they select
+// Files named explicitly are kept as given, even when an --exclude pattern matches them
+func expandValidatePaths(opts validateOptions) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+	add := func(paths ...string) {
+		for _, p := range paths {
+			if p == "-" || !seen[p] {
+				seen[p] = true
+				files = append(files, p)
+			}
+		}
+	}
+
+	for _, arg := range opts.Files {
+		info, err := os.Stat(arg)
+		switch {
+		case arg == "-" || (err == nil && !info.IsDir()):
+			add(arg)
+		case err == nil:
+			found, err := walkValidateDir(arg, opts.Recursive, func(rel string) bool {
+				if len(opts.Include) == 0 {
+					return isSourceFile(rel) && !matchesAny(opts.Exclude, rel)
+				}
+				return matchesAny(opts.Include, rel) && !matchesAny(opts.Exclude, rel)
+			})
+			if err != nil {
+				return nil, err
+			}
+			if len(found) == 0 {
+				hint := ""
+				if !opts.Recursive {
+					hint = " (use --recursive to include subdirectories)"
+				}
+				return nil, fmt.Errorf("no C/C++ files to validate in %s%s", arg, hint)
+			}
+			add(found...)
+		case strings.ContainsAny(arg, "*?["):
+			found, err := expandValidateGlob(arg, opts.Exclude)
+			if err != nil {
+				return nil, err
+			}
+			add(found...)
+		default:
+			add(arg) // Reported as unreadable when the inputs are read
+		}
+	}
+	return files, nil
+}
+
+// walkValidateDir lists the files under dir that keep selects, skipping the directories the indexer skips
+func walkValidateDir(dir string, recursive bool, keep func(rel string) bool) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if d.IsDir() {
+			if p != dir && (!recursive || strings.HasPrefix(d.Name(), ".") || skipDirs[d.Name()]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if keep(filepath.ToSlash(rel)) {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", dir, err)
+	}
+	return files, nil
+}
+
+// expandValidateGlob lists the files matching a glob argument such as 'src/**/*.cpp'
+// The shell has usually expanded globs already; this covers quoted patterns and Windows
+func expandValidateGlob(pattern string, exclude []string) ([]string, error) {
+	pattern = filepath.ToSlash(pattern)
+	segments := strings.Split(pattern, "/")
+	literal := 0
+	for literal < len(segments)-1 && !strings.ContainsAny(segments[literal], "*?[") {
+		literal++
+	}
+	base := strings.Join(segments[:literal], "/")
+	if base == "" && strings.HasPrefix(pattern, "/") {
+		base = "/"
+	} else if base == "" {
+		base = "."
+	}
+	rest := strings.Join(segments[literal:], "/")
+	if _, err := path.Match(rest, ""); err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+
+	recursive := strings.Contains(rest, "/") || strings.Contains(rest, "**")
+	files, err := walkValidateDir(filepath.FromSlash(base), recursive, func(rel string) bool {
+		return matchSegments(strings.Split(rest, "/"), strings.Split(rel, "/")) && !matchesAny(exclude, rel)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files match %s", pattern)
+	}
+	return files, nil
+}
+
+// readValidateInputs reads each file, and stdin for "-"
+func readValidateInputs(opts validateOptions, stdin io.Reader) []validateInput {
+	inputs := make([]validateInput, 0, len(opts.Files))
+	for _, path := range opts.Files {
+		in := validateInput{Name: path, Filename: filepath.Base(path)}
+		var data []byte
+		if path == "-" {
+			in.Name, in.Filename = stdinName, stdinFilename
+			if opts.Filename != "" {
+				in.Filename = opts.Filename
+			}
+			data, in.Err = io.ReadAll(stdin)
+		} else {
+			data, in.Err = os.ReadFile(path) //nolint:gosec // path is given on the command line
+		}
+		in.Content = string(data)
+		if in.Err == nil && strings.TrimSpace(in.Content) == "" {
+			in.Err = fmt.Errorf("no code to validate")
+		}
+		inputs = append(inputs, in)
+	}
+	return inputs
+}
+
+// runValidateOnly validates files without entering the REPL
+func runValidateOnly(opts validateOptions) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	files, err := expandValidatePaths(opts)
+	if err != nil {
+		fmt.Printf("\033[91mError:\033[0m %v\n", err)
+		return 1
+	}
+	opts.Files = files
+
+	container, err := newValidationRuntime(ctx, LoadConfig())
+	if err != nil {
+		fmt.Print(FormatUserError(err))
+		return 1
+	}
+	fmt.Printf("Using container runtime: %s\n", container.GetBinary())
+	container.SetStandard(opts.Standard)
+	container.SetGateSelection(opts.Gates)
+
+	inputs := readValidateInputs(opts, os.Stdin)
+	if len(opts.Gates.Only) > 0 || len(opts.Gates.Skip) > 0 {
+		fmt.Printf("Gates: %s\n", opts.Gates)
+	}
+	if len(inputs) > 1 && opts.Jobs > 1 {
+		fmt.Printf("Validating %d files, %d at a time\n", len(inputs), min(opts.Jobs, len(inputs)))
+	}
+
+	finished := 0
+	outcomes := validateInputs(ctx, inputs, opts.Jobs, func(ctx context.Context, in validateInput) ciFileResult {
+		return validateInputCode(ctx, container, in)
+	}, func(r ciFileResult) {
+		if ctx.Err() != nil {
+			return // Cut short by Ctrl+C, not a real result
+		}
+		finished++
+		printValidateResult(r, finished, len(inputs))
+	})
+
+	// Cancelling ctx has killed the running containers, and their temp directories are gone
+	if ctx.Err() != nil {
+		fmt.Printf("\n\033[93mInterrupted:\033[0m stopped the running containers (%d of %d file(s) finished)\n", finished, len(inputs))
+		return 1
+	}
+
+	if len(outcomes) > 1 {
+		sortValidateResults(outcomes)
+		fmt.Printf("\n%s", ciSummaryTable(outcomes))
+	}
+
+	allPassed := len(outcomes) == len(inputs)
+	for _, r := range outcomes {
+		allPassed = allPassed && r.Passed()
+	}
+	if allPassed {
+		fmt.Printf("\n\033[92mAll files passed validation!\033[0m\n")
+		return 0
+	}
+	fmt.Printf("\n\033[91mSome files failed validation.\033[0m\n")
+	return 1
+}
+
+// validateInputs runs validate over inputs with up to jobs workers, calling done as each finishes
+// done is never called concurrently. When ctx is cancelled, inputs not yet started are dropped
+func validateInputs(ctx context.Context, inputs []validateInput, jobs int, validate func(context.Context, validateInput) ciFileResult, done func(ciFileResult)) []ciFileResult {
+	queue := make(chan validateInput)
+	var (
+		mu       sync.Mutex
+		outcomes []ciFileResult
+		wg       sync.WaitGroup
+	)
+	for w := 0; w < min(jobs, len(inputs)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for in := range queue {
+				r := validate(ctx, in)
+				mu.Lock()
+				outcomes = append(outcomes, r)
+				done(r)
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for _, in := range inputs {
+		if ctx.Err() != nil {
+			break
+		}
+		select {
+		case queue <- in:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(queue)
+	wg.Wait()
+	return outcomes
+}
+
+// validateInputCode runs one input through the full pipeline
+func validateInputCode(ctx context.Context, container *ContainerRuntime, in validateInput) ciFileResult {
+	r := ciFileResult{Path: in.Name, Err: in.Err}
+	if in.Err != nil {
+		return r
+	}
+	start := time.Now()
+	files := []CodeFile{{Filename: in.Filename, Content: in.Content}}
+	r.Results, r.Err = container.ValidateCode(ctx, in.Content, in.Filename)
+	if r.Err == nil {
+		r.Results = container.withPluginValidators(ctx, files, r.Results)
+	}
+	r.Findings = gateFindings(in.Filename, files, r.Results)
+	r.Duration = time.Since(start)
+	return r
+}
+
+// printValidateResult prints one finished file's gates
+func printValidateResult(r ciFileResult, n, total int) {
+	progress := ""
+	if total > 1 {
+		progress = fmt.Sprintf("[%d/%d] ", n, total)
+	}
+	if r.Err != nil {
+		fmt.Printf("\n\033[91m%sERROR %s:\033[0m %v\n", progress, r.Path, r.Err)
+		return
+	}
+	fmt.Printf("\n\033[93m%s%s\033[0m (%.1fs)\n", progress, r.Path, r.Duration.Seconds())
+	fmt.Print(FormatResults(r.Results, r.Paths()))
+	if r.Passed() {
+		fmt.Printf("\033[92m%s passed all validation!\033[0m\n", r.Path)
+	}
+}
+
+// sortValidateResults orders the summary by result (PASS, then FAIL, then ERROR) and then by
+// path, so the problems end up just above the final verdict
+func sortValidateResults(outcomes []ciFileResult) {
+	rank := map[string]int{"PASS": 0, "FAIL": 1, "ERROR": 2}
+	sort.SliceStable(outcomes, func(i, j int) bool {
+		ri, rj := rank[ciResultLabel(outcomes[i])], rank[ciResultLabel(outcomes[j])]
+		if ri != rj {
+			return ri < rj
+		}
+		return outcomes[i].Path < outcomes[j].Path
+	})
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseValidateArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    validateOptions
+		wantErr string
+	}{
+		{
+			name: "files",
+			args: []string{"a.cpp", "b.cpp"},
+			want: validateOptions{Files: []string{"a.cpp", "b.cpp"}, Jobs: defaultValidateJobs},
+		},
+		{
+			name: "jobs",
+			args: []string{"-j", "8", "a.cpp", "--jobs", "2"},
+			want: validateOptions{Files: []string{"a.cpp"}, Jobs: 2},
+		},
+		{
+			name: "stdin with lang and filename",
+			args: []string{"--lang", "c++20", "--filename", "queue.cpp", "-"},
+			want: validateOptions{Files: []string{"-"}, Standard: "c++20", Filename: "queue.cpp", Jobs: defaultValidateJobs},
+		},
+		{
+			name: "std alias",
+			args: []string{"-", "--std", "cpp23"},
+			want: validateOptions{Files: []string{"-"}, Standard: "c++23", Jobs: defaultValidateJobs},
+		},
+		{
+			name: "directory filters",
+			args: []string{"src", "-r", "--include", "*.cpp, *.cc", "--exclude", "third_party/**", "--exclude", "./gen/"},
+			want: validateOptions{Files: []string{"src"}, Jobs: defaultValidateJobs, Recursive: true,
+				Include: []string{"*.cpp", "*.cc"}, Exclude: []string{"third_party/**", "gen"}},
+		},
+		{
+			name: "gate selection",
+			args: []string{"--gates", "clang-tidy,compile", "--gates", "asan", "--skip", "msan,tsan", "a.cpp"},
+			want: validateOptions{Files: []string{"a.cpp"}, Jobs: defaultValidateJobs,
+				Gates: GateSelection{Only: []string{"clang-tidy", "compile", "asan"}, Skip: []string{"msan", "tsan"}}},
+		},
+		{name: "unknown gate", args: []string{"--skip", "valgrind", "a.cpp"}, wantErr: "unknown gate"},
+		{name: "nothing left to run", args: []string{"--gates", "asan", "--skip", "asan", "a.cpp"}, wantErr: "removes every gate"},
+		{name: "bad pattern", args: []string{"--include", "[a-", "src"}, wantErr: "invalid pattern"},
+		{name: "empty pattern list", args: []string{"--exclude", ",", "src"}, wantErr: "empty pattern list"},
+		{name: "zero jobs", args: []string{"--jobs", "0", "a.cpp"}, wantErr: "positive number"},
+		{name: "bad jobs", args: []string{"-j", "many", "a.cpp"}, wantErr: "positive number"},
+		{name: "no files", args: []string{"--lang", "c++20"}, wantErr: "no files"},
+		{name: "missing lang value", args: []string{"-", "--lang"}, wantErr: "needs a value"},
+		{name: "bad lang", args: []string{"--lang", "c99", "-"}, wantErr: "unsupported language standard"},
+		{name: "filename without stdin", args: []string{"--filename", "x.cpp", "a.cpp"}, wantErr: "only applies to code read from stdin"},
+		{name: "filename not C++", args: []string{"--filename", "x.py", "-"}, wantErr: "not a C/C++ source file name"},
+		{name: "stdin twice", args: []string{"-", "-"}, wantErr: "only be given once"},
+		{name: "unknown option", args: []string{"--bogus", "a.cpp"}, wantErr: "unknown validate option"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseValidateArgs(tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseValidateArgs() error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseValidateArgs() error = %v", err)
+			}
+			if strings.Join(got.Files, ",") != strings.Join(tt.want.Files, ",") ||
+				got.Standard != tt.want.Standard || got.Filename != tt.want.Filename || got.Jobs != tt.want.Jobs ||
+				got.Recursive != tt.want.Recursive || strings.Join(got.Include, ",") != strings.Join(tt.want.Include, ",") ||
+				strings.Join(got.Exclude, ",") != strings.Join(tt.want.Exclude, ",") || got.Gates.String() != tt.want.Gates.String() {
+				t.Errorf("parseValidateArgs() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadValidateInputs(t *testing.T) {
+	code := "int main() { return 0; }\n"
+
+	inputs := readValidateInputs(validateOptions{Files: []string{"-"}}, strings.NewReader(code))
+	if len(inputs) != 1 || inputs[0].Err != nil {
+		t.Fatalf("readValidateInputs() = %+v", inputs)
+	}
+	if inputs[0].Name != "<stdin>" || inputs[0].Filename != stdinFilename || inputs[0].Content != code {
+		t.Errorf("stdin input = %+v", inputs[0])
+	}
+
+	inputs = readValidateInputs(validateOptions{Files: []string{"-"}, Filename: "ring.cpp"}, strings.NewReader(code))
+	if inputs[0].Filename != "ring.cpp" {
+		t.Errorf("stdin filename = %q, want ring.cpp", inputs[0].Filename)
+	}
+
+	inputs = readValidateInputs(validateOptions{Files: []string{"-", "missing.cpp"}}, strings.NewReader("  \n"))
+	if inputs[0].Err == nil || !strings.Contains(inputs[0].Err.Error(), "no code") {
+		t.Errorf("empty stdin error = %v, want no code to validate", inputs[0].Err)
+	}
+	if inputs[1].Err == nil || inputs[1].Name != "missing.cpp" {
+		t.Errorf("missing file input = %+v, want a read error", inputs[1])
+	}
+}
+
+func TestValidateInputsRunsInParallel(t *testing.T) {
+	var inputs []validateInput
+	for i := 0; i < 9; i++ {
+		inputs = append(inputs, validateInput{Name: fmt.Sprintf("f%d.cpp", i)})
+	}
+
+	var mu sync.Mutex
+	running, peak := 0, 0
+	validate := func(ctx context.Context, in validateInput) ciFileResult {
+		mu.Lock()
+		running++
+		peak = max(peak, running)
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return ciFileResult{Path: in.Name}
+	}
+
+	var reported []string
+	outcomes := validateInputs(context.Background(), inputs, 3, validate, func(r ciFileResult) {
+		reported = append(reported, r.Path)
+	})
+	if len(outcomes) != len(inputs) || len(reported) != len(inputs) {
+		t.Fatalf("got %d outcomes and %d reports, want %d", len(outcomes), len(reported), len(inputs))
+	}
+	if peak != 3 {
+		t.Errorf("peak concurrency = %d, want 3", peak)
+	}
+}
+
+func TestValidateInputsStopsWhenCancelled(t *testing.T) {
+	inputs := make([]validateInput, 5)
+	ctx, cancel := context.WithCancel(context.Background())
+	outcomes := validateInputs(ctx, inputs, 1, func(ctx context.Context, in validateInput) ciFileResult {
+		cancel()
+		return ciFileResult{}
+	}, func(ciFileResult) {})
+	if len(outcomes) >= len(inputs) {
+		t.Errorf("validated %d inputs after cancellation, want fewer than %d", len(outcomes), len(inputs))
+	}
+}
+
+func TestSortValidateResults(t *testing.T) {
+	outcomes := []ciFileResult{
+		{Path: "z.cpp", Results: []ValidationResult{{Stage: "compile", Success: true}}},
+		{Path: "b.cpp", Err: errors.New("no code to validate")},
+		{Path: "c.cpp", Results: []ValidationResult{{Stage: "asan", Success: false}}},
+		{Path: "a.cpp", Results: []ValidationResult{{Stage: "compile", Success: true}}},
+	}
+	sortValidateResults(outcomes)
+
+	var got []string
+	for _, r := range outcomes {
+		got = append(got, r.Path)
+	}
+	if strings.Join(got, ",") != "a.cpp,z.cpp,c.cpp,b.cpp" {
+		t.Errorf("sorted order = %v, want passes, then failures, then errors", got)
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		rel     string
+		want    bool
+	}{
+		{"*.cpp", "main.cpp", true},
+		{"*.cpp", "net/socket.cpp", true},
+		{"*.cpp", "net/socket.h", false},
+		{"third_party", "third_party/json/json.cpp", true},
+		{"third_party/**", "third_party/json/json.cpp", true},
+		{"third_party/**", "src/third_party.cpp", false},
+		{"**/*_test.cpp", "net/tcp/conn_test.cpp", true},
+		{"**/*_test.cpp", "conn_test.cpp", true},
+		{"net/*.cpp", "net/tcp/conn.cpp", false},
+		{"net/*/*.cpp", "net/tcp/conn.cpp", true},
+		{"net/**/conn.cpp", "net/conn.cpp", true},
+	}
+
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.rel); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.rel, got, tt.want)
+		}
+	}
+}
+
+func TestExpandValidatePaths(t *testing.T) {
+	root := t.TempDir()
+	for _, rel := range []string{
+		"main.cpp", "util.cc", "util.h", "notes.md",
+		"net/socket.cpp", "net/socket_test.cpp",
+		"third_party/json/json.cpp", "build/gen.cpp", ".cache/tmp.cpp",
+	} {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("int x;\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	empty := filepath.Join(root, "empty")
+	if err := os.Mkdir(empty, 0750); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		opts    validateOptions
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "top level sources",
+			opts: validateOptions{Files: []string{root}},
+			want: []string{"main.cpp", "util.cc"},
+		},
+		{
+			name: "recursive with excludes",
+			opts: validateOptions{Files: []string{root}, Recursive: true, Exclude: []string{"third_party/**", "*_test.cpp"}},
+			want: []string{"main.cpp", "net/socket.cpp", "util.cc"},
+		},
+		{
+			name: "include patterns",
+			opts: validateOptions{Files: []string{root}, Recursive: true, Include: []string{"*.cc", "net/*.cpp"}},
+			want: []string{"net/socket.cpp", "net/socket_test.cpp", "util.cc"},
+		},
+		{
+			name: "quoted glob",
+			opts: validateOptions{Files: []string{filepath.Join(root, "**", "*_test.cpp")}},
+			want: []string{"net/socket_test.cpp"},
+		},
+		{
+			name: "explicit files kept and deduplicated",
+			opts: validateOptions{Files: []string{"-", filepath.Join(root, "util.h"), root, filepath.Join(root, "main.cpp")}, Exclude: []string{"*.h"}},
+			want: []string{"-", "util.h", "main.cpp", "util.cc"},
+		},
+		{
+			name:    "empty directory",
+			opts:    validateOptions{Files: []string{empty}},
+			wantErr: "use --recursive",
+		},
+		{
+			name:    "glob without matches",
+			opts:    validateOptions{Files: []string{filepath.Join(root, "*.cxx")}},
+			wantErr: "no files match",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandValidatePaths(tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expandValidatePaths() error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expandValidatePaths() error = %v", err)
+			}
+			var rels []string
+			for _, path := range got {
+				if path == "-" {
+					rels = append(rels, path)
+					continue
+				}
+				rel, _ := filepath.Rel(root, path)
+				rels = append(rels, filepath.ToSlash(rel))
+			}
+			if strings.Join(rels, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("expandValidatePaths() = %v, want %v", rels, tt.want)
+			}
+		})
+	}
+}
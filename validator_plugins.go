@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// validatorPluginManifest declares a plugin in its directory under ~/.bjarne/validators
+const validatorPluginManifest = "validator.json"
+
+// validatorPluginsDir is where plugins are copied in the validation directory
+const validatorPluginsDir = "bjarne_validators"
+
+// validatorPluginIDPattern limits plugin IDs to ones safe as stage names, directory names and /config arguments
+var validatorPluginIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// validatorArgNamePattern limits argument names to ones usable in an environment variable name
+var validatorArgNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// ValidatorPlugin is a domain validator from ~/.bjarne/validators/<id>/validator.json
+// Its command runs in the validation container with the source as its argument; a non-zero exit fails it
+type ValidatorPlugin struct {
+	ID          ValidatorID        `json:"id"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Category    ValidatorCategory  `json:"category"`
+	Command     string             `json:"command"`            // Executable in the plugin's directory
+	Args        []ValidatorArgSpec `json:"args,omitempty"`     // The arguments /config can set
+	Advisory    bool               `json:"advisory,omitempty"` // Report a failure without failing validation
+	Enabled     bool               `json:"enabled,omitempty"`  // Run without enabling it in /config
+	Dir         string             `json:"-"`                  // Host directory the plugin was loaded from
+}
+
+// ValidatorArgSpec declares one of a plugin's arguments
+type ValidatorArgSpec struct {
+	Name        string `json:"name"`
+	Type        string `json:"type,omitempty"` // string (default), int, number or bool
+	Default     string `json:"default,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// validatorPluginsPath returns ~/.bjarne/validators
+func validatorPluginsPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".bjarne", "validators"), nil
+}
+
+// LoadValidatorPlugins reads every plugin directory under dir (none if it does not exist), sorted by ID
+func LoadValidatorPlugins(dir string) ([]ValidatorPlugin, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read validator plugins: %w", err)
+	}
+	var plugins []ValidatorPlugin
+	seen := make(map[ValidatorID]string)
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		p, err := loadValidatorPlugin(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("validator plugin %s: %w", e.Name(), err)
+		}
+		if other, ok := seen[p.ID]; ok {
+			return nil, fmt.Errorf("validator plugins %s and %s both use id %q", other, e.Name(), p.ID)
+		}
+		seen[p.ID] = e.Name()
+		plugins = append(plugins, p)
+	}
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].ID < plugins[j].ID })
+	return plugins, nil
+}
+
+// loadValidatorPlugin reads and checks one plugin's manifest
+func loadValidatorPlugin(dir string) (ValidatorPlugin, error) {
+	data, err := os.ReadFile(filepath.Join(dir, validatorPluginManifest))
+	if err != nil {
+		return ValidatorPlugin{}, fmt.Errorf("failed to read %s: %w", validatorPluginManifest, err)
+	}
+	var p ValidatorPlugin
+	if err := json.Unmarshal(data, &p); err != nil {
+		return ValidatorPlugin{}, fmt.Errorf("invalid %s: %w", validatorPluginManifest, err)
+	}
+	if !validatorPluginIDPattern.MatchString(string(p.ID)) {
+		return ValidatorPlugin{}, fmt.Errorf("id %q may only use lowercase letters, digits, '_' and '-'", p.ID)
+	}
+	for _, v := range AllValidators() {
+		if v.ID == p.ID {
+			return ValidatorPlugin{}, fmt.Errorf("id %q is a built-in validator", p.ID)
+		}
+	}
+	if isSelectableGate(string(p.ID)) {
+		return ValidatorPlugin{}, fmt.Errorf("id %q is a validation gate", p.ID)
+	}
+	if p.Category == "" || !validatorPluginIDPattern.MatchString(string(p.Category)) {
+		return ValidatorPlugin{}, fmt.Errorf("category %q may only use lowercase letters, digits, '_' and '-'", p.Category)
+	}
+	if p.Command == "" || filepath.IsAbs(p.Command) || filepath.Base(p.Command) != p.Command {
+		return ValidatorPlugin{}, fmt.Errorf("command %q must name an executable in the plugin's directory", p.Command)
+	}
+	info, err := os.Stat(filepath.Join(dir, p.Command))
+	if err != nil {
+		return ValidatorPlugin{}, fmt.Errorf("command %s: %w", p.Command, err)
+	}
+	if info.Mode()&0111 == 0 {
+		return ValidatorPlugin{}, fmt.Errorf("command %s is not executable", p.Command)
+	}
+	for _, a := range p.Args {
+		if !validatorArgNamePattern.MatchString(a.Name) {
+			return ValidatorPlugin{}, fmt.Errorf("argument name %q may only use lowercase letters, digits and '_'", a.Name)
+		}
+		switch a.Type {
+		case "", "string", "int", "number", "bool":
+		default:
+			return ValidatorPlugin{}, fmt.Errorf("argument %s has unknown type %q (use string, int, number or bool)", a.Name, a.Type)
+		}
+		if a.Default != "" {
+			if err := checkValidatorArg(a, a.Default); err != nil {
+				return ValidatorPlugin{}, fmt.Errorf("default: %w", err)
+			}
+		}
+	}
+	if p.Name == "" {
+		p.Name = string(p.ID)
+	}
+	p.Dir = dir
+	return p, nil
+}
+
+// Info describes the plugin the way /config lists the built-in validators
+func (p ValidatorPlugin) Info() ValidatorInfo {
+	description := p.Description
+	if p.Advisory {
+		description += " (advisory)"
+	}
+	var defaults []string
+	for _, a := range p.Args {
+		defaults = append(defaults, a.Name+"="+a.Default)
+	}
+	return ValidatorInfo{
+		ID:          p.ID,
+		Name:        p.Name,
+		Description: strings.TrimSpace(description),
+		Category:    p.Category,
+		Enabled:     p.Enabled,
+		RequiresArg: len(p.Args) > 0,
+		ArgHelp:     strings.Join(defaults, " "),
+	}
+}
+
+// checkValidatorArg reports a value that does not have its argument's type
+func checkValidatorArg(spec ValidatorArgSpec, value string) error {
+	var err error
+	switch spec.Type {
+	case "int":
+		_, err = strconv.Atoi(value)
+	case "number":
+		_, err = strconv.ParseFloat(value, 64)
+	case "bool":
+		_, err = strconv.ParseBool(value)
+	}
+	if err != nil {
+		return fmt.Errorf("argument %s=%s is not a valid %s", spec.Name, value, spec.Type)
+	}
+	return nil
+}
+
+// pluginArgs checks an argument string like "max_ms=10 topic=/scan" against the plugin's arguments
+// and returns every declared argument's value, defaults filled in
+func (p ValidatorPlugin) pluginArgs(arg string) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, field := range strings.FieldsFunc(arg, func(r rune) bool { return r == ' ' || r == ',' }) {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return nil, fmt.Errorf("argument %q is not key=value", field)
+		}
+		values[key] = value
+	}
+	specs := make(map[string]bool, len(p.Args))
+	for _, spec := range p.Args {
+		specs[spec.Name] = true
+		value, ok := values[spec.Name]
+		if !ok || value == "" {
+			if spec.Required && spec.Default == "" {
+				return nil, fmt.Errorf("argument %s is required", spec.Name)
+			}
+			values[spec.Name] = spec.Default
+			continue
+		}
+		if err := checkValidatorArg(spec, value); err != nil {
+			return nil, err
+		}
+	}
+	for key := range values {
+		if !specs[key] {
+			return nil, fmt.Errorf("%s has no argument %s", p.ID, key)
+		}
+	}
+	return values, nil
+}
+
+// pluginCommand runs the plugin's executable on the source; its arguments and
+// the project's standard are passed as BJARNE_* environment variables
+func (c *ContainerRuntime) pluginCommand(p ValidatorPlugin, filename string, args map[string]string) []string {
+	source := "/src/" + filename
+	command := []string{"env", "BJARNE_SOURCE=" + source, "BJARNE_STD=" + strings.TrimPrefix(c.stdFlag(), "-std=")}
+	names := make([]string, 0, len(args))
+	for name := range args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		command = append(command, "BJARNE_ARG_"+strings.ToUpper(name)+"="+args[name])
+	}
+	return append(command, fmt.Sprintf("/src/%s/%s/%s", validatorPluginsDir, p.ID, p.Command), source)
+}
+
+// runPluginValidator copies the plugin into the validation directory and runs it there
+func (c *ContainerRuntime) runPluginValidator(ctx context.Context, tmpDir, filename string, p ValidatorPlugin, arg string) DomainValidationResult {
+	args, err := p.pluginArgs(arg)
+	if err != nil {
+		return DomainValidationResult{ValidatorID: p.ID, Output: err.Error()}
+	}
+	if err := copyTree(p.Dir, filepath.Join(tmpDir, validatorPluginsDir, string(p.ID))); err != nil {
+		return DomainValidationResult{ValidatorID: p.ID, Output: fmt.Sprintf("failed to copy plugin: %v", err)}
+	}
+
+	result := c.runValidationStage(ctx, tmpDir, string(p.ID), c.pluginCommand(p, filename, args)...)
+	output := result.Output
+	if !result.Success {
+		output = strings.TrimSpace(output + "\n" + result.Error)
+	}
+	if !result.Success && p.Advisory {
+		return DomainValidationResult{ValidatorID: p.ID, Success: true, Output: output + "\n(advisory: reported without failing validation)"}
+	}
+	return DomainValidationResult{ValidatorID: p.ID, Success: result.Success, Output: output}
+}
+
+// copyTree copies a directory's regular files, keeping their modes so executables still run
+func copyTree(src, dest string) error {
+	return filepath.WalkDir(src, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0750)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, info.Mode().Perm())
+	})
+}
+
+// AddPlugins makes the plugins available to /config and RunDomainValidators
+func (vc *ValidatorConfig) AddPlugins(plugins []ValidatorPlugin) {
+	for _, p := range plugins {
+		vc.Plugins = append(vc.Plugins, p)
+		info := p.Info()
+		vc.Enabled[p.ID] = info.Enabled
+		if info.RequiresArg {
+			vc.Args[p.ID] = info.ArgHelp
+		}
+	}
+}
+
+// CheckArg reports an argument string a plugin would reject; built-in validators parse their own
+func (vc *ValidatorConfig) CheckArg(id ValidatorID, arg string) error {
+	for _, p := range vc.Plugins {
+		if p.ID == id {
+			_, err := p.pluginArgs(arg)
+			return err
+		}
+	}
+	return nil
+}
+
+// loadValidatorPlugins reads the plugins in ~/.bjarne/validators
+func loadValidatorPlugins() ([]ValidatorPlugin, error) {
+	dir, err := validatorPluginsPath()
+	if err != nil {
+		return nil, err
+	}
+	return LoadValidatorPlugins(dir)
+}
+
+// SetValidatorPlugins gives the runtime the plugins loaded from ~/.bjarne/validators
+func (c *ContainerRuntime) SetValidatorPlugins(plugins []ValidatorPlugin) {
+	c.plugins = plugins
+}
+
+// ValidatorPlugins returns the plugins the runtime was given
+func (c *ContainerRuntime) ValidatorPlugins() []ValidatorPlugin {
+	return c.plugins
+}
+
+// withPluginValidators runs the plugins on files once every gate in results has passed and appends
+// their results; headless modes have no /config, so only plugins whose manifest sets "enabled" run
+func (c *ContainerRuntime) withPluginValidators(ctx context.Context, files []CodeFile, results []ValidationResult) []ValidationResult {
+	if len(c.plugins) == 0 || len(results) == 0 || !allPassed(results) {
+		return results
+	}
+	config := DefaultValidatorConfig()
+	config.AddPlugins(c.plugins)
+	for _, dr := range c.RunDomainValidatorsOn(ctx, files, config) {
+		results = append(results, domainStageResult(dr))
+	}
+	return results
+}
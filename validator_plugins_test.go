@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+const ros2Plugin = `{
+  "id": "ros2",
+  "name": "ROS 2 Node",
+  "description": "Check node lifecycle and QoS settings",
+  "category": "robotics",
+  "command": "check.sh",
+  "args": [
+    {"name": "max_ms", "type": "int", "default": "10"},
+    {"name": "topic", "required": true}
+  ],
+  "advisory": true
+}`
+
+// writePlugin creates a plugin directory with its manifest and an executable check.sh
+func writePlugin(t *testing.T, dir, name, manifest string) {
+	t.Helper()
+	pluginDir := filepath.Join(dir, name)
+	if err := os.MkdirAll(pluginDir, 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(pluginDir, validatorPluginManifest), []byte(manifest), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(pluginDir, "check.sh"), []byte("#!/bin/sh\nexit 0\n"), 0700); err != nil { //nolint:gosec // the plugin's executable
+		t.Fatal(err)
+	}
+}
+
+func TestLoadValidatorPlugins(t *testing.T) {
+	dir := t.TempDir()
+	if plugins, err := LoadValidatorPlugins(filepath.Join(dir, "missing")); err != nil || plugins != nil {
+		t.Fatalf("LoadValidatorPlugins(no directory) = %v, %v", plugins, err)
+	}
+	writePlugin(t, dir, "ros2", ros2Plugin)
+	plugins, err := LoadValidatorPlugins(dir)
+	if err != nil || len(plugins) != 1 {
+		t.Fatalf("LoadValidatorPlugins() = %+v, %v", plugins, err)
+	}
+	p := plugins[0]
+	if p.ID != "ros2" || p.Category != "robotics" || !p.Advisory || p.Dir != filepath.Join(dir, "ros2") {
+		t.Errorf("LoadValidatorPlugins() = %+v", p)
+	}
+	if info := p.Info(); info.Description != "Check node lifecycle and QoS settings (advisory)" || info.ArgHelp != "max_ms=10 topic=" {
+		t.Errorf("Info() = %+v", info)
+	}
+
+	tests := []struct {
+		name     string
+		manifest string
+		wantErr  string
+	}{
+		{"builtin", `{"id": "latency", "category": "hft", "command": "check.sh"}`, "built-in validator"},
+		{"gate", `{"id": "asan", "category": "core", "command": "check.sh"}`, "built-in validator"},
+		{"bad-id", `{"id": "ROS 2", "category": "robotics", "command": "check.sh"}`, "may only use"},
+		{"no-category", `{"id": "cuda", "command": "check.sh"}`, "category"},
+		{"escape", `{"id": "cuda", "category": "gpu", "command": "../check.sh"}`, "must name an executable"},
+		{"missing", `{"id": "cuda", "category": "gpu", "command": "run.sh"}`, "run.sh"},
+		{"bad-type", `{"id": "cuda", "category": "gpu", "command": "check.sh", "args": [{"name": "sm", "type": "float"}]}`, "unknown type"},
+		{"bad-default", `{"id": "cuda", "category": "gpu", "command": "check.sh", "args": [{"name": "sm", "type": "int", "default": "x"}]}`, "not a valid int"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writePlugin(t, dir, tt.name, tt.manifest)
+			if _, err := LoadValidatorPlugins(dir); err == nil || !strings.Contains(err.Error(), tt.wantErr) || !strings.Contains(err.Error(), tt.name) {
+				t.Errorf("LoadValidatorPlugins() error = %v, want %q naming the plugin", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPluginArgs(t *testing.T) {
+	p := ValidatorPlugin{ID: "ros2", Args: []ValidatorArgSpec{
+		{Name: "max_ms", Type: "int", Default: "10"},
+		{Name: "topic", Required: true},
+	}}
+	tests := []struct {
+		arg     string
+		want    map[string]string
+		wantErr string
+	}{
+		{"topic=/scan", map[string]string{"max_ms": "10", "topic": "/scan"}, ""},
+		{"max_ms=5, topic=/odom", map[string]string{"max_ms": "5", "topic": "/odom"}, ""},
+		{"max_ms=5", nil, "topic is required"},
+		{"max_ms=fast topic=/scan", nil, "not a valid int"},
+		{"topic=/scan qos=2", nil, "no argument qos"},
+		{"topic", nil, "not key=value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			got, err := p.pluginArgs(tt.arg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("pluginArgs() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("pluginArgs()[%s] = %q, want %q", k, got[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestPluginCommand(t *testing.T) {
+	c := &ContainerRuntime{}
+	p := ValidatorPlugin{ID: "ros2", Command: "check.sh"}
+	got := strings.Join(c.pluginCommand(p, "node.cpp", map[string]string{"topic": "/scan", "max_ms": "10"}), " ")
+	want := "env BJARNE_SOURCE=/src/node.cpp BJARNE_STD=c++17 BJARNE_ARG_MAX_MS=10 BJARNE_ARG_TOPIC=/scan /src/" + validatorPluginsDir + "/ros2/check.sh /src/node.cpp"
+	if got != want {
+		t.Errorf("pluginCommand() = %q, want %q", got, want)
+	}
+}
+
+func TestValidatorConfigPlugins(t *testing.T) {
+	cfg := DefaultValidatorConfig()
+	cfg.AddPlugins([]ValidatorPlugin{
+		{ID: "ros2", Name: "ROS 2 Node", Category: "robotics", Args: []ValidatorArgSpec{{Name: "topic", Default: "/scan"}}},
+		{ID: "cuda-mem", Name: "CUDA memcheck", Category: CategoryPerformance, Enabled: true},
+	})
+	if cfg.IsEnabled("ros2") || !cfg.IsEnabled("cuda-mem") {
+		t.Errorf("plugins enabled = %v, %v; want the manifest's default", cfg.IsEnabled("ros2"), cfg.IsEnabled("cuda-mem"))
+	}
+	if cfg.GetArg("ros2") != "topic=/scan" {
+		t.Errorf("GetArg(ros2) = %q, want its defaults", cfg.GetArg("ros2"))
+	}
+	byCategory := cfg.ByCategory()
+	if len(byCategory["robotics"]) != 1 || len(byCategory[CategoryPerformance]) != len(GetValidatorsByCategory()[CategoryPerformance])+1 {
+		t.Errorf("ByCategory() = %+v", byCategory)
+	}
+	cfg.EnableCategory("robotics")
+	if !cfg.IsEnabled("ros2") {
+		t.Error("ros2 should be enabled after EnableCategory(robotics)")
+	}
+	if err := cfg.CheckArg("ros2", "qos=2"); err == nil {
+		t.Error("CheckArg(ros2, qos=2) should reject an undeclared argument")
+	}
+	if err := cfg.CheckArg(ValidatorLatency, "p99_us=50"); err != nil {
+		t.Errorf("CheckArg(latency) = %v", err)
+	}
+}
+
+func TestWithPluginValidators(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs /bin/sh")
+	}
+	// Stands in for the container runtime: every plugin run fails
+	bin := filepath.Join(t.TempDir(), "podman")
+	if err := os.WriteFile(bin, []byte("#!/bin/sh\necho 'topic not published'\nexit 1\n"), 0700); err != nil { //nolint:gosec // the test runs it
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	c := &ContainerRuntime{binary: bin, imageName: "validator"}
+	c.SetValidatorPlugins([]ValidatorPlugin{
+		{ID: "ros2", Command: "check.sh", Dir: dir},
+		{ID: "cuda-mem", Command: "check.sh", Dir: dir, Enabled: true},
+	})
+	files := []CodeFile{{Filename: "util.h", Content: "int f();"}, {Filename: "main.cpp", Content: "int main() {}"}}
+
+	passed := []ValidationResult{{Stage: "compile", Success: true}}
+	got := c.withPluginValidators(context.Background(), files, passed)
+	if len(got) != 2 || got[1].Stage != "cuda-mem" || got[1].Success || !strings.Contains(got[1].Output, "topic not published") {
+		t.Errorf("withPluginValidators() = %+v; want the enabled plugin's failure appended", got)
+	}
+	failed := []ValidationResult{{Stage: "compile", Success: false}}
+	if got := c.withPluginValidators(context.Background(), files, failed); len(got) != 1 {
+		t.Errorf("withPluginValidators() after a failed gate = %+v, want no plugins run", got)
+	}
+}
@@ -9,6 +9,7 @@ const (
 	ValidatorCppcheck   ValidatorID = "cppcheck"
 	ValidatorIWYU       ValidatorID = "iwyu"
 	ValidatorComplexity ValidatorID = "complexity"
+	ValidatorFormat     ValidatorID = "format"
 	ValidatorCompile    ValidatorID = "compile"
 	ValidatorASAN       ValidatorID = "asan"
 	ValidatorUBSAN      ValidatorID = "ubsan"
@@ -26,9 +27,10 @@ const (
 	ValidatorShaderCheck  ValidatorID = "shader-check"  // Validate shader compilation
 
 	// F-011: High-Frequency Trading
-	ValidatorLatency  ValidatorID = "latency"   // Measure p50/p95/p99 latency
-	ValidatorLockFree ValidatorID = "lock-free" // Verify lock-free algorithms
-	ValidatorCache    ValidatorID = "cache"     // Check cache-friendly access patterns
+	ValidatorLatency    ValidatorID = "latency"     // Measure p50/p95/p99 latency
+	ValidatorLockFree   ValidatorID = "lock-free"   // Verify lock-free algorithms
+	ValidatorCache      ValidatorID = "cache"       // Check cache-friendly access patterns
+	ValidatorAllocCount ValidatorID = "alloc-count" // Allocations per benchmark iteration
 
 	// F-012: Embedded Systems
 	ValidatorStackSize ValidatorID = "stack-size" // Check stack usage
@@ -46,6 +48,11 @@ const (
 	ValidatorMemProfile ValidatorID = "mem-prof"   // Memory profiling
 	ValidatorCPUProfile ValidatorID = "cpu-prof"   // CPU profiling
 	ValidatorFlameGraph ValidatorID = "flamegraph" // Flame graph generation
+
+	// GPU / CUDA
+	ValidatorCUDACompile      ValidatorID = "cuda-compile"      // nvcc compilation
+	ValidatorComputeSanitizer ValidatorID = "compute-sanitizer" // memcheck/racecheck on a GPU
+	ValidatorCUDAStatic       ValidatorID = "cuda-static"       // Missing cudaFree, unchecked launches
 )
 
 // ValidatorCategory groups validators by domain
@@ -58,6 +65,7 @@ const (
 	CategoryEmbedded    ValidatorCategory = "embedded"
 	CategorySecurity    ValidatorCategory = "security"
 	CategoryPerformance ValidatorCategory = "performance"
+	CategoryGPU         ValidatorCategory = "gpu"
 )
 
 // ValidatorInfo describes a validation gate
@@ -79,6 +87,7 @@ func AllValidators() []ValidatorInfo {
 		{ValidatorCppcheck, "cppcheck", "Deep static analysis", CategoryCore, true, false, ""},
 		{ValidatorIWYU, "include-what-you-use", "Header hygiene (advisory)", CategoryCore, true, false, ""},
 		{ValidatorComplexity, "complexity", "Cyclomatic complexity check (CCN≤15)", CategoryCore, true, false, ""},
+		{ValidatorFormat, "clang-format", "Formatting check (.clang-format or default style)", CategoryCore, true, false, ""},
 		{ValidatorCompile, "compile", "Compile with -Wall -Wextra -Werror", CategoryCore, true, false, ""},
 		{ValidatorASAN, "AddressSanitizer", "Memory errors (heap/stack overflow, use-after-free)", CategoryCore, true, false, ""},
 		{ValidatorUBSAN, "UBSanitizer", "Undefined behavior", CategoryCore, true, false, ""},
@@ -95,13 +104,14 @@ func AllValidators() []ValidatorInfo {
 		// HFT (F-011)
 		{ValidatorLatency, "Latency", "Measure p50/p95/p99 latency", CategoryHFT, false, true, "p99_us=100"},
 		{ValidatorLockFree, "Lock-Free", "Verify lock-free properties", CategoryHFT, false, false, ""},
-		{ValidatorCache, "Cache Analysis", "Check cache-friendly patterns", CategoryHFT, false, false, ""},
+		{ValidatorCache, "Cache Analysis", "Check cache-friendly patterns", CategoryHFT, false, true, "c2c=off"},
+		{ValidatorAllocCount, "Allocation Count", "Allocations per benchmark iteration against a budget", CategoryHFT, false, true, "max_per_iter=0 iterations=1000"},
 
 		// Embedded (F-012)
 		{ValidatorStackSize, "Stack Size", "Analyze stack usage", CategoryEmbedded, false, true, "max_kb=8"},
 		{ValidatorInterrupt, "Interrupt Safety", "Check ISR constraints", CategoryEmbedded, false, false, ""},
 		{ValidatorRealTime, "Real-Time", "WCET analysis", CategoryEmbedded, false, true, "deadline_us=1000"},
-		{ValidatorROMSize, "ROM Size", "Check binary size", CategoryEmbedded, false, true, "max_kb=256"},
+		{ValidatorROMSize, "ROM Size", "Check binary size and profile it by symbol", CategoryEmbedded, false, true, "max_kb=256 max_fn_bytes=0 top=10"},
 
 		// Security (F-013)
 		{ValidatorFuzz, "Fuzzing", "AFL++/libFuzzer testing", CategorySecurity, false, true, "iterations=10000"},
@@ -113,6 +123,11 @@ func AllValidators() []ValidatorInfo {
 		{ValidatorMemProfile, "Memory Profile", "Heap profiling", CategoryPerformance, false, false, ""},
 		{ValidatorCPUProfile, "CPU Profile", "CPU sampling", CategoryPerformance, false, false, ""},
 		{ValidatorFlameGraph, "Flame Graph", "Generate flame graph", CategoryPerformance, false, false, ""},
+
+		// GPU / CUDA
+		{ValidatorCUDACompile, "CUDA Compile", "Compile with nvcc (gpu image)", CategoryGPU, false, false, ""},
+		{ValidatorComputeSanitizer, "Compute Sanitizer", "memcheck/racecheck (needs a GPU)", CategoryGPU, false, false, ""},
+		{ValidatorCUDAStatic, "CUDA Pitfalls", "Missing cudaFree, unchecked launches and API errors", CategoryGPU, false, false, ""},
 	}
 }
 
@@ -120,6 +135,7 @@ func AllValidators() []ValidatorInfo {
 type ValidatorConfig struct {
 	Enabled map[ValidatorID]bool
 	Args    map[ValidatorID]string // Additional arguments per validator
+	Plugins []ValidatorPlugin      // Validators from ~/.bjarne/validators
 }
 
 // DefaultValidatorConfig returns the default validator configuration
@@ -149,6 +165,24 @@ func GetValidatorsByCategory() map[ValidatorCategory][]ValidatorInfo {
 	return result
 }
 
+// Validators returns the built-in validators followed by the plugins
+func (vc *ValidatorConfig) Validators() []ValidatorInfo {
+	validators := AllValidators()
+	for _, p := range vc.Plugins {
+		validators = append(validators, p.Info())
+	}
+	return validators
+}
+
+// ByCategory returns the built-in validators and plugins grouped by category
+func (vc *ValidatorConfig) ByCategory() map[ValidatorCategory][]ValidatorInfo {
+	result := make(map[ValidatorCategory][]ValidatorInfo)
+	for _, v := range vc.Validators() {
+		result[v.Category] = append(result[v.Category], v)
+	}
+	return result
+}
+
 // IsEnabled checks if a validator is enabled
 func (vc *ValidatorConfig) IsEnabled(id ValidatorID) bool {
 	enabled, ok := vc.Enabled[id]
@@ -173,7 +207,7 @@ func (vc *ValidatorConfig) GetArg(id ValidatorID) string {
 
 // EnableCategory enables all validators in a category
 func (vc *ValidatorConfig) EnableCategory(cat ValidatorCategory) {
-	for _, v := range AllValidators() {
+	for _, v := range vc.Validators() {
 		if v.Category == cat {
 			vc.Enabled[v.ID] = true
 		}
@@ -182,7 +216,7 @@ func (vc *ValidatorConfig) EnableCategory(cat ValidatorCategory) {
 
 // DisableCategory disables all validators in a category
 func (vc *ValidatorConfig) DisableCategory(cat ValidatorCategory) {
-	for _, v := range AllValidators() {
+	for _, v := range vc.Validators() {
 		if v.Category == cat {
 			vc.Enabled[v.ID] = false
 		}
@@ -5,6 +5,7 @@ import (
 	"crypto/sha256"
 	"database/sql"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"math"
@@ -24,6 +25,8 @@ type VectorIndex struct {
 	db        *sql.DB
 	modelPath string
 	embedder  *Embedder
+	lockPath  string // Writer lock shared by every bjarne process using the database
+	readOnly  bool
 }
 
 // ChunkType identifies what kind of code chunk this is
@@ -54,8 +57,19 @@ type VectorIndexConfig struct {
 	DBPath       string // Path to SQLite database
 	ModelDir     string // Directory for model files
 	EmbeddingDim int    // Embedding dimension (384 for BGE-small)
+	ReadOnly     bool   // Open for searching only (e.g. the MCP server), never taking the writer lock
 }
 
+// Several bjarne processes can share index.db: SQLite's WAL journal lets them read while
+// one writes, and a lock file next to the database keeps the writers taking turns
+const (
+	indexBusyTimeout = 5 * time.Second  // How long SQLite retries a busy database
+	indexLockTimeout = 10 * time.Second // How long a small update waits for another writer
+)
+
+// errIndexReadOnly reports a write to an index opened with ReadOnly
+var errIndexReadOnly = errors.New("the semantic index was opened read-only")
+
 // Model download configuration
 const (
 	BGESmallModelURL  = "https://huggingface.co/BAAI/bge-small-en-v1.5/resolve/main/onnx/model.onnx"
@@ -87,21 +101,51 @@ func NewVectorIndex(cfg VectorIndexConfig) (*VectorIndex, error) {
 	}
 
 	// Open SQLite database with sqlite-vec extension
-	db, err := sql.Open("sqlite3", cfg.DBPath+"?_journal_mode=WAL")
+	busy := fmt.Sprintf("_busy_timeout=%d", indexBusyTimeout.Milliseconds())
+	dsn := cfg.DBPath + "?_journal_mode=WAL&" + busy
+	if cfg.ReadOnly {
+		dsn = "file:" + filepath.ToSlash(cfg.DBPath) + "?mode=ro&" + busy
+	}
+	db, err := sql.Open("sqlite3", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	indexLog.Debug("opened", "db", cfg.DBPath, "readOnly", cfg.ReadOnly)
+	vi := &VectorIndex{
+		db:        db,
+		modelPath: cfg.ModelDir,
+		lockPath:  cfg.DBPath + ".lock",
+		readOnly:  cfg.ReadOnly,
+	}
+	if cfg.ReadOnly {
+		return vi, nil
+	}
 
 	// Initialize schema
+	lock, err := vi.lockWriter(indexLockTimeout)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	defer func() { _ = lock.Unlock() }()
 	if err := initVectorSchema(db, cfg.EmbeddingDim); err != nil {
 		_ = db.Close()
 		return nil, fmt.Errorf("failed to initialize schema: %w", err)
 	}
+	return vi, nil
+}
 
-	return &VectorIndex{
-		db:        db,
-		modelPath: cfg.ModelDir,
-	}, nil
+// lockWriter takes the index's writer lock, waiting up to wait for another process to finish
+func (vi *VectorIndex) lockWriter(wait time.Duration) (*fileLock, error) {
+	if vi.readOnly {
+		return nil, errIndexReadOnly
+	}
+	lock, err := lockWait(vi.lockPath, wait)
+	if errors.Is(err, errLocked) {
+		indexLog.Info("writer lock held by another session", "lock", vi.lockPath, "waited", wait)
+		return nil, fmt.Errorf("another bjarne session is updating the semantic index: %w", err)
+	}
+	return lock, err
 }
 
 // initVectorSchema creates the database schema
@@ -208,12 +252,19 @@ func (vi *VectorIndex) EnsureModel(ctx context.Context, progressFn func(string))
 
 // downloadFile downloads a file from URL to destination with progress
 func downloadFile(ctx context.Context, url, dest string, progressFn func(string)) error {
+	if offlineMode {
+		return errOffline
+	}
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	client, err := NewHTTPClient(endpointDownloads, 0)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
 	if err != nil {
 		return err
 	}
@@ -270,6 +321,13 @@ func (vi *VectorIndex) IndexWorkspaceWithEmbeddings(ctx context.Context, rootPat
 		return fmt.Errorf("failed to resolve path: %w", err)
 	}
 
+	// A full index takes minutes: fail at once rather than queue behind another session's
+	lock, err := vi.lockWriter(0)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = lock.Unlock() }()
+
 	// First pass: scan files and extract chunks
 	if progressFn != nil {
 		progressFn("Scanning source files...")
@@ -298,62 +356,101 @@ func (vi *VectorIndex) IndexWorkspaceWithEmbeddings(ctx context.Context, rootPat
 			return nil
 		}
 
-		relPath, _ := filepath.Rel(absRoot, path)
 		fileCount++
-
 		if progressFn != nil && fileCount%10 == 0 {
 			progressFn(fmt.Sprintf("  Scanned %d files...", fileCount))
 		}
 
-		// Check if file needs re-indexing
-		content, readErr := os.ReadFile(path)
-		if readErr != nil {
-			return nil //nolint:nilerr // Skip unreadable files intentionally
-		}
+		allChunks = append(allChunks, vi.prepareFile(ctx, absRoot, path)...)
+		return nil
+	})
 
-		hash := sha256.Sum256(content)
-		hashStr := hex.EncodeToString(hash[:16])
+	if err != nil {
+		return fmt.Errorf("failed to scan directory: %w", err)
+	}
 
-		info, infoErr := d.Info()
-		if infoErr != nil {
-			return nil //nolint:nilerr // Skip files we can't stat
-		}
+	if progressFn != nil {
+		progressFn(fmt.Sprintf("Found %d chunks in %d files", len(allChunks), fileCount))
+	}
+	indexLog.Info("indexing workspace", "root", absRoot, "files", fileCount, "chunks", len(allChunks))
 
-		// Check if file is already indexed with same hash
-		var existingHash string
-		queryErr := vi.db.QueryRowContext(ctx, "SELECT hash FROM files WHERE path = ?", relPath).Scan(&existingHash)
-		if queryErr == nil && existingHash == hashStr {
-			return nil // File unchanged, skip
-		}
+	return vi.storeChunks(ctx, allChunks, progressFn)
+}
 
-		// Delete old data for this file
-		_, _ = vi.db.ExecContext(ctx, "DELETE FROM files WHERE path = ?", relPath)
+// IndexFiles adds or refreshes individual files (e.g. code just written by /save) without
+// rescanning the workspace; paths outside rootPath and non-C/C++ files are skipped
+// Returns the number of chunks stored
+func (vi *VectorIndex) IndexFiles(ctx context.Context, rootPath string, paths []string) (int, error) {
+	absRoot, err := filepath.Abs(rootPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve path: %w", err)
+	}
+	lock, err := vi.lockWriter(indexLockTimeout)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = lock.Unlock() }()
 
-		// Insert file record
-		result, insertErr := vi.db.ExecContext(ctx,
-			"INSERT INTO files (path, hash, mod_time, indexed_at) VALUES (?, ?, ?, ?)",
-			relPath, hashStr, info.ModTime().Unix(), time.Now().Unix())
-		if insertErr != nil {
-			return nil //nolint:nilerr // Skip files that fail to insert
+	var chunks []CodeChunk
+	for _, path := range paths {
+		absPath, err := filepath.Abs(path)
+		if err != nil || !sourceExtensions[strings.ToLower(filepath.Ext(absPath))] {
+			continue
+		}
+		if rel, err := filepath.Rel(absRoot, absPath); err != nil || strings.HasPrefix(rel, "..") {
+			continue
 		}
+		chunks = append(chunks, vi.prepareFile(ctx, absRoot, absPath)...)
+	}
+	return len(chunks), vi.storeChunks(ctx, chunks, nil)
+}
 
-		fileID, _ := result.LastInsertId()
+// prepareFile records a source file and returns its chunks, ready to store
+// Unchanged and unreadable files yield no chunks
+func (vi *VectorIndex) prepareFile(ctx context.Context, absRoot, path string) []CodeChunk {
+	relPath, _ := filepath.Rel(absRoot, path)
 
-		// Extract chunks from file
-		chunks := extractChunks(string(content), fileID, relPath)
-		allChunks = append(allChunks, chunks...)
+	// Check if file needs re-indexing
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil // Skip unreadable files
+	}
 
-		return nil
-	})
+	hash := sha256.Sum256(content)
+	hashStr := hex.EncodeToString(hash[:16])
 
+	info, err := os.Stat(path)
 	if err != nil {
-		return fmt.Errorf("failed to scan directory: %w", err)
+		return nil // Skip files we can't stat
 	}
 
-	if progressFn != nil {
-		progressFn(fmt.Sprintf("Found %d chunks in %d files", len(allChunks), fileCount))
+	// Check if file is already indexed with same hash
+	var existingHash string
+	queryErr := vi.db.QueryRowContext(ctx, "SELECT hash FROM files WHERE path = ?", relPath).Scan(&existingHash)
+	if queryErr == nil && existingHash == hashStr {
+		return nil // File unchanged, skip
+	}
+
+	// Delete old data for this file
+	_, _ = vi.db.ExecContext(ctx, "DELETE FROM files WHERE path = ?", relPath)
+
+	// Insert file record
+	result, err := vi.db.ExecContext(ctx,
+		"INSERT INTO files (path, hash, mod_time, indexed_at) VALUES (?, ?, ?, ?)",
+		relPath, hashStr, info.ModTime().Unix(), time.Now().Unix())
+	if err != nil {
+		indexLog.Warn("failed to record file", "path", relPath, "err", err)
+		return nil // Skip files that fail to insert
 	}
 
+	fileID, _ := result.LastInsertId()
+
+	// Extract chunks from file
+	return extractChunks(string(content), fileID, relPath)
+}
+
+// storeChunks inserts chunks into the database and embeds them when the model is loaded
+func (vi *VectorIndex) storeChunks(ctx context.Context, allChunks []CodeChunk, progressFn func(string)) error {
 	if len(allChunks) == 0 {
 		return nil
 	}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// watchPollInterval is how often --watch rescans the tree
+const watchPollInterval = time.Second
+
+// watchMaxFindings bounds the findings printed per failed file
+const watchMaxFindings = 8
+
+// fileStamp identifies one version of a watched file
+type fileStamp struct {
+	ModTime time.Time
+	Size    int64
+}
+
+// watcher re-validates C/C++ files under a directory as they change
+type watcher struct {
+	root     string
+	check    fileCheckFunc
+	interval time.Duration // Between rescans
+	notify   bool
+	passed   map[string]bool // Last result per file, for "fixed" and "broken" transitions
+}
+
+// runWatch implements `bjarne --watch <dir> [--notify]`
+func runWatch(args []string) int {
+	root, notify := ".", false
+	for _, arg := range args {
+		switch {
+		case arg == "--notify":
+			notify = true
+		case strings.HasPrefix(arg, "-"):
+			fmt.Fprintf(os.Stderr, "Unknown watch option: %s\nUsage: bjarne --watch [dir] [--notify]\n", arg)
+			return 1
+		default:
+			root = arg
+		}
+	}
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		fmt.Fprintf(os.Stderr, "Error: %s is not a directory\n", root)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	container, err := newValidationRuntime(ctx, LoadConfig())
+	if err != nil {
+		fmt.Print(FormatUserError(err))
+		return 1
+	}
+
+	w := &watcher{root: root, check: containerCheck(container), interval: watchPollInterval, notify: notify, passed: make(map[string]bool)}
+	if err := w.run(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// watchBatch is a set of files being validated in the background
+type watchBatch struct {
+	files    []string
+	reported map[string]bool
+	results  <-chan ciFileResult
+	cancel   context.CancelFunc
+}
+
+// run polls the tree until ctx ends, validating changed files in the background
+// A change to a file that is being validated cancels that run and queues it again
+func (w *watcher) run(ctx context.Context) error {
+	snapshot, err := scanWatchTree(w.root)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Watching %d C/C++ file(s) in %s. Save a file to validate it; Ctrl+C stops.\n", len(snapshot), w.root)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	pending := make(map[string]bool)
+	var batch *watchBatch
+	defer func() {
+		if batch != nil {
+			batch.cancel()
+		}
+	}()
+
+	for {
+		var results <-chan ciFileResult
+		if batch != nil {
+			results = batch.results
+		}
+
+		select {
+		case <-ctx.Done():
+			fmt.Println("\nStopped watching.")
+			return nil
+
+		case <-ticker.C:
+			next, err := scanWatchTree(w.root)
+			if err != nil {
+				return err
+			}
+			changed, removed := diffSnapshots(snapshot, next)
+			snapshot = next
+			for _, path := range removed {
+				delete(w.passed, path)
+				delete(pending, path)
+			}
+			if len(changed) == 0 {
+				break
+			}
+			for _, path := range affectedFiles(changed, snapshot) {
+				pending[path] = true
+			}
+			if batch != nil && overlaps(batch.files, pending) {
+				batch.cancel() // Its unreported files are queued again when the batch drains
+			}
+
+		case r, ok := <-results:
+			if !ok {
+				for _, path := range batch.files {
+					if _, exists := snapshot[path]; exists && !batch.reported[path] {
+						pending[path] = true
+					}
+				}
+				batch.cancel()
+				batch = nil
+				break
+			}
+			batch.reported[r.Path] = true
+			w.report(r)
+		}
+
+		if batch == nil && len(pending) > 0 {
+			batch = w.start(ctx, sortedKeys(pending))
+			pending = make(map[string]bool)
+		}
+	}
+}
+
+// start validates files one at a time in the background, sending each finished result
+// The channel closes when the batch ends or is cancelled (a cancelled file is not sent)
+func (w *watcher) start(ctx context.Context, files []string) *watchBatch {
+	out := make(chan ciFileResult)
+	b := &watchBatch{files: files, reported: make(map[string]bool), results: out}
+	ctx, b.cancel = context.WithCancel(ctx)
+
+	go func() {
+		defer close(out)
+		for _, path := range files {
+			fmt.Printf("%s validating %s ...\n", watchTimestamp(), path)
+			r := validateCIFile(ctx, w.check, path, readDiskFile)
+			if ctx.Err() != nil {
+				return
+			}
+			select {
+			case out <- r:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return b
+}
+
+// report prints one file's result and notifies when it starts or stops failing
+func (w *watcher) report(r ciFileResult) {
+	passed := r.Passed()
+	was, seen := w.passed[r.Path]
+	w.passed[r.Path] = passed
+
+	transition := ""
+	switch {
+	case seen && passed && !was:
+		transition = " (fixed)"
+	case seen && !passed && was:
+		transition = " (broken)"
+	}
+
+	if passed {
+		fmt.Printf("%s \033[92mPASS\033[0m %s%s: %s, %.1fs\n", watchTimestamp(), r.Path, transition, ciGateSummary(r), r.Duration.Seconds())
+	} else {
+		fmt.Printf("%s \033[91m%s\033[0m %s%s: %s\n", watchTimestamp(), ciResultLabel(r), r.Path, transition, ciGateSummary(r))
+		for i, f := range r.Findings {
+			if i == watchMaxFindings {
+				fmt.Printf("    ... %d more\n", len(r.Findings)-i)
+				break
+			}
+			fmt.Printf("    %s\n", formatFinding(r.FindingPath(f), f))
+		}
+	}
+
+	if w.notify && (!seen && !passed || transition != "") {
+		title := "bjarne: " + filepath.Base(r.Path) + " passed"
+		if !passed {
+			title = "bjarne: " + filepath.Base(r.Path) + " failed"
+		}
+		if err := desktopNotify(title, ciGateSummary(r)); err != nil {
+			fmt.Printf("    (notification failed: %v)\n", err)
+			w.notify = false
+		}
+	}
+}
+
+// formatFinding renders a finding as one compiler-style line
+func formatFinding(path string, f GateFinding) string {
+	loc := fileLocation(path, f.Line, f.Column)
+	message, _, _ := strings.Cut(f.Message, "\n")
+	if f.Check != "" && f.Check != f.Stage {
+		message += " [" + f.Check + "]"
+	}
+	return fmt.Sprintf("%s: %s: %s", loc, f.Level, message)
+}
+
+// watchTimestamp prefixes watch output lines
+func watchTimestamp() string {
+	return "\033[90m" + time.Now().Format("15:04:05") + "\033[0m"
+}
+
+// scanWatchTree records the C/C++ files under root, skipping the directories the indexer skips
+func scanWatchTree(root string) (map[string]fileStamp, error) {
+	files := make(map[string]fileStamp)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return nil //nolint:nilerr // Files can vanish mid-scan while an editor saves
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != root && (strings.HasPrefix(name, ".") || skipDirs[name]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isSourceFile(path) && !isHeaderFile(path) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil //nolint:nilerr
+		}
+		files[path] = fileStamp{ModTime: info.ModTime(), Size: info.Size()}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", root, err)
+	}
+	return files, nil
+}
+
+// diffSnapshots returns the files added or modified, and the files removed, between scans
+func diffSnapshots(old, next map[string]fileStamp) (changed, removed []string) {
+	for path, stamp := range next {
+		if prev, ok := old[path]; !ok || prev != stamp {
+			changed = append(changed, path)
+		}
+	}
+	for path := range old {
+		if _, ok := next[path]; !ok {
+			removed = append(removed, path)
+		}
+	}
+	sort.Strings(changed)
+	sort.Strings(removed)
+	return changed, removed
+}
+
+// affectedFiles adds to the changed files every file in the same directory that includes one
+// of them, directly or through other headers, since their validation builds against it
+func affectedFiles(changed []string, snapshot map[string]fileStamp) []string {
+	affected := make(map[string]bool)
+	headersByDir := make(map[string][]string)
+	for _, path := range changed {
+		affected[path] = true
+		if isHeaderFile(path) {
+			dir := filepath.Dir(path)
+			headersByDir[dir] = append(headersByDir[dir], filepath.Base(path))
+		}
+	}
+
+	for path := range snapshot {
+		headers := headersByDir[filepath.Dir(path)]
+		if affected[path] || len(headers) == 0 {
+			continue
+		}
+		for _, f := range collectLocalFiles(path, readDiskFile) {
+			if f.Filename != filepath.Base(path) && slices.Contains(headers, f.Filename) {
+				affected[path] = true
+				break
+			}
+		}
+	}
+	return sortedKeys(affected)
+}
+
+// overlaps reports whether any of files is in set
+func overlaps(files []string, set map[string]bool) bool {
+	for _, f := range files {
+		if set[f] {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedKeys returns a map's keys (a set's members) in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
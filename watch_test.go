@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestScanAndDiffSnapshots(t *testing.T) {
+	root := t.TempDir()
+	write := func(rel, content string) {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("main.cpp", "int main() {}\n")
+	write("lib/util.h", "#pragma once\n")
+	write("notes.txt", "not code\n")
+	write("build/gen.cpp", "// generated\n")
+	write(".cache/x.cpp", "// hidden\n")
+
+	before, err := scanWatchTree(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(before) != 2 {
+		t.Fatalf("scanWatchTree() found %v, want main.cpp and lib/util.h", before)
+	}
+
+	write("lib/util.h", "#pragma once\nint x();\n")
+	write("lib/util.cpp", "int x() { return 1; }\n")
+	if err := os.Remove(filepath.Join(root, "main.cpp")); err != nil {
+		t.Fatal(err)
+	}
+	after, _ := scanWatchTree(root)
+
+	changed, removed := diffSnapshots(before, after)
+	if strings.Join(changed, ",") != filepath.Join(root, "lib/util.cpp")+","+filepath.Join(root, "lib/util.h") {
+		t.Errorf("changed = %v", changed)
+	}
+	if len(removed) != 1 || removed[0] != filepath.Join(root, "main.cpp") {
+		t.Errorf("removed = %v", removed)
+	}
+}
+
+func TestAffectedFiles(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"main.cpp":  "#include \"queue.h\"\nint main() {}\n",
+		"queue.h":   "#include \"node.h\"\n",
+		"node.h":    "#pragma once\n",
+		"other.cpp": "int y;\n",
+	}
+	snapshot := make(map[string]fileStamp)
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		snapshot[path] = fileStamp{}
+	}
+
+	got := affectedFiles([]string{filepath.Join(root, "node.h")}, snapshot)
+	var names []string
+	for _, path := range got {
+		names = append(names, filepath.Base(path))
+	}
+	if strings.Join(names, ",") != "main.cpp,node.h,queue.h" {
+		t.Errorf("affectedFiles(node.h) = %v, want node.h and everything that includes it", names)
+	}
+}
+
+func TestWatcherRevalidatesOnChange(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "main.cpp")
+	if err := os.WriteFile(path, []byte("int main() {}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var checked []string
+	check := func(ctx context.Context, files []CodeFile, target string, progress ProgressCallback) ([]ValidationResult, error) {
+		mu.Lock()
+		checked = append(checked, files[0].Content)
+		mu.Unlock()
+		return []ValidationResult{{Stage: "compile", Success: !strings.Contains(files[0].Content, "oops")}}, nil
+	}
+
+	w := &watcher{root: root, check: check, interval: 10 * time.Millisecond, passed: make(map[string]bool)}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- w.run(ctx) }()
+
+	waitFor := func(n int) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			mu.Lock()
+			got := len(checked)
+			mu.Unlock()
+			if got >= n {
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		t.Fatalf("timed out waiting for %d validations", n)
+	}
+
+	time.Sleep(30 * time.Millisecond) // Let the first scan record the file
+	if err := os.WriteFile(path, []byte("int main() { oops; }\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(1)
+	time.Sleep(30 * time.Millisecond) // Let the result be reported
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(checked) != 1 || !strings.Contains(checked[0], "oops") {
+		t.Errorf("validated %q, want the saved contents once", checked)
+	}
+	if passed, ok := w.passed[path]; !ok || passed {
+		t.Errorf("recorded result = %v, %v; want a failure", passed, ok)
+	}
+}
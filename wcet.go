@@ -0,0 +1,649 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// wcetIRMarker starts the LLVM IR the real-time stage prints
+const wcetIRMarker = "bjarne-wcet-ir:"
+
+// Loop bounds, from best to worst for timing analysis
+const (
+	boundConstant = "constant" // Exits after at most a compile-time number of iterations
+	boundInput    = "input"    // Exits after a number of iterations fixed when the loop starts
+	boundNone     = "none"     // No exit compares an induction variable with a loop-invariant value
+)
+
+// irAllocators are the allocation functions whose calls have unbounded WCET
+var irAllocators = map[string]string{
+	"@_Znwm": "operator new", "@_Znam": "operator new[]",
+	"@_ZnwmSt11align_val_t": "operator new", "@_ZnamSt11align_val_t": "operator new[]",
+	"@_ZnwmRKSt9nothrow_t": "operator new", "@_ZnamRKSt9nothrow_t": "operator new[]",
+	"@malloc": "malloc", "@calloc": "calloc", "@realloc": "realloc", "@aligned_alloc": "aligned_alloc",
+}
+
+var (
+	irDefinePattern     = regexp.MustCompile(`^define\b.*?\s(@[-\w.$]+)\((.*)\).*?(?:!dbg (![0-9]+))?\s*\{$`)
+	irLabelPattern      = regexp.MustCompile(`^([-\w.$]+):`)
+	irSuccessorPattern  = regexp.MustCompile(`\blabel %([-\w.$]+)`)
+	irCallPattern       = regexp.MustCompile(`(?:^|\s)(?:call|invoke)\s(?:.*?\s)?([@%][-\w.$]+)\(`)
+	irDefPattern        = regexp.MustCompile(`^(%[-\w.$]+) = (.*)$`)
+	irDbgPattern        = regexp.MustCompile(`!dbg (![0-9]+)`)
+	irLoopPattern       = regexp.MustCompile(`!llvm\.loop (![0-9]+)`)
+	irArgPattern        = regexp.MustCompile(`(%[-\w.$]+)\s*$`)
+	irMetadataPattern   = regexp.MustCompile(`^(![0-9]+) = (.*)$`)
+	irLocationPattern   = regexp.MustCompile(`!DILocation\(line: (\d+)(?:, column: \d+)?, scope: ![0-9]+(?:, inlinedAt: (![0-9]+))?`)
+	irSubprogramPattern = regexp.MustCompile(`!DISubprogram\(name: "([^"]*)".*?file: (![0-9]+), line: (\d+)`)
+	irFilePattern       = regexp.MustCompile(`!DIFile\(filename: "([^"]*)"`)
+	irFirstNodePattern  = regexp.MustCompile(`^distinct !\{![0-9]+, (![0-9]+)`)
+)
+
+// irInst is one instruction of a basic block with its debug location
+type irInst struct {
+	Text string
+	Dbg  string // Metadata ID of its !DILocation, or ""
+}
+
+// irBlock is a basic block and the labels it branches to
+type irBlock struct {
+	Label string
+	Insts []irInst
+	Succs []string
+}
+
+// irCall is a call or invoke in a function (Callee "" is an indirect call)
+type irCall struct {
+	Block  int
+	Callee string
+	Dbg    string
+}
+
+// irFunction is a defined function of the module
+type irFunction struct {
+	Name    string // IR name, e.g. @_Z3sumPKii
+	Display string // Source name from its debug info
+	Line    int
+	User    bool // Defined in the checked source file rather than a header
+	Args    map[string]bool
+	Blocks  []irBlock
+	Calls   []irCall
+}
+
+// irModule is the part of an LLVM IR module the WCET pass reads
+type irModule struct {
+	Functions []*irFunction
+	Metadata  map[string]string
+}
+
+// parseIR reads the functions and metadata of textual LLVM IR
+// source is the checked file's name; functions whose debug info places them there are the user's
+func parseIR(ir, source string) *irModule {
+	m := &irModule{Metadata: make(map[string]string)}
+	var fn *irFunction
+	var dbgIDs []string
+	for _, raw := range strings.Split(ir, "\n") {
+		line := strings.TrimRight(raw, " \r")
+		if fn == nil {
+			if d := irDefinePattern.FindStringSubmatch(line); d != nil {
+				fn = &irFunction{Name: d[1], Display: strings.TrimPrefix(d[1], "@"), Args: make(map[string]bool)}
+				for _, arg := range strings.Split(d[2], ",") {
+					if a := irArgPattern.FindStringSubmatch(arg); a != nil {
+						fn.Args[a[1]] = true
+					}
+				}
+				dbgIDs = append(dbgIDs, d[3])
+				m.Functions = append(m.Functions, fn)
+			} else if md := irMetadataPattern.FindStringSubmatch(line); md != nil {
+				m.Metadata[md[1]] = md[2]
+			}
+			continue
+		}
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "}":
+			fn = nil
+		case trimmed == "" || strings.HasPrefix(trimmed, ";"):
+		case irLabelPattern.MatchString(line):
+			fn.Blocks = append(fn.Blocks, irBlock{Label: irLabelPattern.FindStringSubmatch(line)[1]})
+		default:
+			if len(fn.Blocks) == 0 {
+				fn.Blocks = append(fn.Blocks, irBlock{Label: "entry"})
+			}
+			b := &fn.Blocks[len(fn.Blocks)-1]
+			inst := irInst{Text: trimmed}
+			if d := irDbgPattern.FindStringSubmatch(trimmed); d != nil {
+				inst.Dbg = d[1]
+			}
+			b.Insts = append(b.Insts, inst)
+			if !strings.Contains(trimmed, " phi ") {
+				for _, s := range irSuccessorPattern.FindAllStringSubmatch(trimmed, -1) {
+					b.Succs = append(b.Succs, s[1])
+				}
+			}
+			if c := irCallPattern.FindStringSubmatch(trimmed); c != nil && !strings.HasPrefix(c[1], "@llvm.") {
+				call := irCall{Block: len(fn.Blocks) - 1, Dbg: inst.Dbg}
+				if strings.HasPrefix(c[1], "@") {
+					call.Callee = c[1]
+				}
+				fn.Calls = append(fn.Calls, call)
+			}
+		}
+	}
+	for i, f := range m.Functions {
+		sp := irSubprogramPattern.FindStringSubmatch(m.Metadata[dbgIDs[i]])
+		if sp == nil {
+			continue
+		}
+		f.Display = sp[1]
+		f.Line, _ = strconv.Atoi(sp[3])
+		if file := irFilePattern.FindStringSubmatch(m.Metadata[sp[2]]); file != nil {
+			f.User = strings.HasSuffix(file[1], source)
+		}
+	}
+	return m
+}
+
+// line returns the source line of a debug location, following inlining out to the function it was inlined into
+func (m *irModule) line(dbg string) int {
+	for seen := 0; dbg != "" && seen < 64; seen++ {
+		loc := irLocationPattern.FindStringSubmatch(m.Metadata[dbg])
+		if loc == nil {
+			return 0
+		}
+		if loc[2] == "" {
+			n, _ := strconv.Atoi(loc[1])
+			return n
+		}
+		dbg = loc[2]
+	}
+	return 0
+}
+
+// irLoop is a natural loop of a function
+type irLoop struct {
+	Header int
+	Blocks map[int]bool
+	Line   int
+	Bound  string
+	Limit  string // The constant or value the loop is bounded by
+}
+
+// loops finds the function's natural loops from its back edges and classifies their bounds
+func (m *irModule) loops(f *irFunction) []irLoop {
+	index := make(map[string]int, len(f.Blocks))
+	for i, b := range f.Blocks {
+		index[b.Label] = i
+	}
+	preds := make([][]int, len(f.Blocks))
+	for i, b := range f.Blocks {
+		for _, s := range b.Succs {
+			if j, ok := index[s]; ok {
+				preds[j] = append(preds[j], i)
+			}
+		}
+	}
+	dom := dominators(len(f.Blocks), preds)
+
+	byHeader := make(map[int]*irLoop)
+	var headers []int
+	for latch, b := range f.Blocks {
+		for _, s := range b.Succs {
+			header, ok := index[s]
+			if !ok || !dom[latch][header] {
+				continue
+			}
+			loop := byHeader[header]
+			if loop == nil {
+				loop = &irLoop{Header: header, Blocks: map[int]bool{header: true}}
+				byHeader[header] = loop
+				headers = append(headers, header)
+			}
+			// The body is everything that reaches the latch without passing the header
+			work := []int{latch}
+			for len(work) > 0 {
+				n := work[len(work)-1]
+				work = work[:len(work)-1]
+				if loop.Blocks[n] {
+					continue
+				}
+				loop.Blocks[n] = true
+				work = append(work, preds[n]...)
+			}
+			if loop.Line == 0 {
+				loop.Line = m.loopLine(f.Blocks[latch])
+			}
+		}
+	}
+	sort.Ints(headers)
+
+	defs := make(map[string]irDef)
+	for i, b := range f.Blocks {
+		for _, inst := range b.Insts {
+			if d := irDefPattern.FindStringSubmatch(inst.Text); d != nil {
+				defs[d[1]] = irDef{Text: d[2], Block: i}
+			}
+		}
+	}
+	loops := make([]irLoop, 0, len(headers))
+	for _, h := range headers {
+		loop := byHeader[h]
+		if loop.Line == 0 {
+			for _, inst := range f.Blocks[h].Insts {
+				if l := m.line(inst.Dbg); l > 0 {
+					loop.Line = l
+					break
+				}
+			}
+		}
+		loop.Bound, loop.Limit = loopBound(f, loop, index, defs)
+		loops = append(loops, *loop)
+	}
+	return loops
+}
+
+// loopLine is where clang's !llvm.loop metadata on the latch's branch says the loop starts
+func (m *irModule) loopLine(latch irBlock) int {
+	if len(latch.Insts) == 0 {
+		return 0
+	}
+	id := irLoopPattern.FindStringSubmatch(latch.Insts[len(latch.Insts)-1].Text)
+	if id == nil {
+		return 0
+	}
+	if first := irFirstNodePattern.FindStringSubmatch(m.Metadata[id[1]]); first != nil {
+		return m.line(first[1])
+	}
+	return 0
+}
+
+// dominators returns, for each block, the set of blocks that dominate it (block 0 is the entry)
+func dominators(n int, preds [][]int) []map[int]bool {
+	dom := make([]map[int]bool, n)
+	for i := range dom {
+		dom[i] = make(map[int]bool, n)
+		if i == 0 {
+			dom[i][0] = true
+			continue
+		}
+		for j := 0; j < n; j++ {
+			dom[i][j] = true
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for i := 1; i < n; i++ {
+			next := make(map[int]bool)
+			first := true
+			for _, p := range preds[i] {
+				if first {
+					for d := range dom[p] {
+						next[d] = true
+					}
+					first = false
+					continue
+				}
+				for d := range next {
+					if !dom[p][d] {
+						delete(next, d)
+					}
+				}
+			}
+			next[i] = true
+			if len(next) != len(dom[i]) {
+				dom[i], changed = next, true
+			}
+		}
+	}
+	return dom
+}
+
+// irDef is the instruction defining a value and its block
+type irDef struct {
+	Text  string
+	Block int
+}
+
+// loopBound finds the best bound among the loop's exits: a conditional branch out of the loop on
+// an icmp of an induction variable (a header phi, or an add/sub/getelementptr of one) against a
+// constant or a value that does not change inside the loop
+func loopBound(f *irFunction, loop *irLoop, index map[string]int, defs map[string]irDef) (string, string) {
+	bound, limit := boundNone, ""
+	for b := range loop.Blocks {
+		insts := f.Blocks[b].Insts
+		if len(insts) == 0 {
+			continue
+		}
+		exits := false
+		for _, s := range f.Blocks[b].Succs {
+			if i, ok := index[s]; !ok || !loop.Blocks[i] {
+				exits = true
+			}
+		}
+		term := strings.Fields(insts[len(insts)-1].Text)
+		if !exits || len(term) < 3 || term[0] != "br" || term[1] != "i1" {
+			continue
+		}
+		cond, ok := defs[strings.TrimSuffix(term[2], ",")]
+		if !ok || !strings.HasPrefix(cond.Text, "icmp ") {
+			continue
+		}
+		fields := strings.SplitN(cond.Text, " ", 4) // icmp <pred> <type> a, b
+		if len(fields) < 4 {
+			continue
+		}
+		operands := strings.Split(strings.Split(fields[3], ", !dbg")[0], ", ")
+		if len(operands) != 2 {
+			continue
+		}
+		for _, pair := range [][2]string{{operands[0], operands[1]}, {operands[1], operands[0]}} {
+			if !isInduction(pair[0], loop, defs) {
+				continue
+			}
+			switch kind, value := invariant(pair[1], f, loop, defs); {
+			case kind == boundConstant:
+				return boundConstant, value
+			case kind == boundInput && bound == boundNone:
+				bound, limit = boundInput, value
+			}
+		}
+	}
+	return bound, limit
+}
+
+// stripCasts follows integer casts back to the value they convert
+func stripCasts(v string, defs map[string]irDef) string {
+	for i := 0; i < 8; i++ {
+		d, ok := defs[v]
+		if !ok {
+			return v
+		}
+		op := strings.Fields(d.Text)
+		if len(op) < 3 || (op[0] != "zext" && op[0] != "sext" && op[0] != "trunc") {
+			return v
+		}
+		// zext [nneg] i32 %x to i64
+		for _, tok := range op[1:] {
+			if strings.HasPrefix(tok, "%") {
+				v = tok
+				break
+			}
+		}
+	}
+	return v
+}
+
+// isInduction reports whether v steps once per iteration: a phi in the loop's header, or an
+// add, sub or getelementptr of one
+func isInduction(v string, loop *irLoop, defs map[string]irDef) bool {
+	v = stripCasts(v, defs)
+	d, ok := defs[v]
+	if !ok || !loop.Blocks[d.Block] {
+		return false
+	}
+	op := strings.Fields(d.Text)
+	if len(op) == 0 {
+		return false
+	}
+	if op[0] == "phi" {
+		return d.Block == loop.Header
+	}
+	if op[0] != "add" && op[0] != "sub" && op[0] != "getelementptr" {
+		return false
+	}
+	for _, tok := range op[1:] {
+		tok = strings.TrimSuffix(tok, ",")
+		if p, ok := defs[stripCasts(tok, defs)]; ok && p.Block == loop.Header && strings.HasPrefix(p.Text, "phi ") {
+			return true
+		}
+	}
+	return false
+}
+
+// invariant classifies the value an induction variable is compared with: a constant, a value fixed
+// before the loop starts (named by its source variable), or neither
+func invariant(v string, f *irFunction, loop *irLoop, defs map[string]irDef) (string, string) {
+	if _, err := strconv.ParseInt(v, 10, 64); err == nil || v == "null" {
+		return boundConstant, v
+	}
+	root := stripCasts(v, defs)
+	if f.Args[root] {
+		return boundInput, strings.TrimPrefix(root, "%")
+	}
+	if d, ok := defs[root]; ok && !loop.Blocks[d.Block] {
+		return boundInput, strings.TrimPrefix(root, "%")
+	}
+	return boundNone, ""
+}
+
+// WCETFinding is one thing the real-time pass found in the code
+type WCETFinding struct {
+	Check    string // wcet-unbounded-loop, wcet-input-bound, wcet-recursion, wcet-dynamic-dispatch or wcet-allocation
+	Level    DiagnosticLevel
+	Function string
+	Line     int
+	Message  string
+}
+
+// String formats the finding like a compiler diagnostic
+func (f WCETFinding) String(filename string) string {
+	return fmt.Sprintf("/src/%s:%d:1: %s: %s [%s]", filename, f.Line, f.Level, f.Message, f.Check)
+}
+
+// WCETAnalysis is what the real-time pass found
+type WCETAnalysis struct {
+	Findings                                  []WCETFinding
+	Loops, ConstantLoops, InputLoops, Unbound int
+	Recursive, Dispatch, Allocations          int
+}
+
+// analyzeWCET finds what keeps the checked file's functions from having a provable worst-case
+// execution time: loops without a bound, recursion, and indirect calls or heap allocation on hot
+// paths (inside loops, or in functions called from them)
+func analyzeWCET(ir, source string) WCETAnalysis {
+	m := parseIR(ir, source)
+	var a WCETAnalysis
+	byName := make(map[string]*irFunction, len(m.Functions))
+	for _, f := range m.Functions {
+		byName[f.Name] = f
+	}
+
+	// Hot code: calls inside loops, and every call of a function reached from one
+	inLoop := make(map[*irFunction]map[int]bool)
+	hot := make(map[*irFunction]bool)
+	var work []*irFunction
+	for _, f := range m.Functions {
+		inLoop[f] = make(map[int]bool)
+		for _, loop := range m.loops(f) {
+			for b := range loop.Blocks {
+				inLoop[f][b] = true
+			}
+			if !f.User {
+				continue
+			}
+			a.Loops++
+			switch loop.Bound {
+			case boundConstant:
+				a.ConstantLoops++
+			case boundInput:
+				a.InputLoops++
+				a.Findings = append(a.Findings, WCETFinding{"wcet-input-bound", LevelNote, f.Display, loop.Line,
+					fmt.Sprintf("loop in %s runs up to '%s' times; its WCET grows with that value, so bound it for the deadline", f.Display, loop.Limit)})
+			default:
+				a.Unbound++
+				a.Findings = append(a.Findings, WCETFinding{"wcet-unbounded-loop", LevelWarning, f.Display, loop.Line,
+					fmt.Sprintf("loop in %s has no provable bound: no exit compares a counter with a constant or a value fixed before the loop; add an iteration limit", f.Display)})
+			}
+		}
+		for _, c := range f.Calls {
+			if callee := byName[c.Callee]; inLoop[f][c.Block] && callee != nil && !hot[callee] {
+				hot[callee] = true
+				work = append(work, callee)
+			}
+		}
+	}
+	for len(work) > 0 {
+		f := work[len(work)-1]
+		work = work[:len(work)-1]
+		for _, c := range f.Calls {
+			if callee := byName[c.Callee]; callee != nil && !hot[callee] {
+				hot[callee] = true
+				work = append(work, callee)
+			}
+		}
+	}
+
+	allocates := allocatingFunctions(m.Functions, byName)
+	for _, f := range m.Functions {
+		if !f.User {
+			continue
+		}
+		for _, c := range f.Calls {
+			if !hot[f] && !inLoop[f][c.Block] {
+				continue
+			}
+			line := m.line(c.Dbg)
+			switch {
+			case c.Callee == "":
+				a.Dispatch++
+				a.Findings = append(a.Findings, WCETFinding{"wcet-dynamic-dispatch", LevelWarning, f.Display, line,
+					fmt.Sprintf("indirect call (virtual function, function pointer or std::function) on a hot path in %s; its target and timing are not known statically", f.Display)})
+			case irAllocators[c.Callee] != "":
+				a.Allocations++
+				a.Findings = append(a.Findings, WCETFinding{"wcet-allocation", LevelWarning, f.Display, line,
+					fmt.Sprintf("%s on a hot path in %s has unbounded WCET; allocate before the time-critical code runs", irAllocators[c.Callee], f.Display)})
+			case allocates[c.Callee] != "":
+				a.Allocations++
+				a.Findings = append(a.Findings, WCETFinding{"wcet-allocation", LevelWarning, f.Display, line,
+					fmt.Sprintf("call to %s on a hot path in %s allocates with %s, which has unbounded WCET; reserve capacity before the time-critical code runs",
+						byName[c.Callee].Display, f.Display, allocates[c.Callee])})
+			}
+		}
+	}
+
+	for _, f := range recursiveFunctions(m.Functions, byName) {
+		if f.User {
+			a.Recursive++
+			a.Findings = append(a.Findings, WCETFinding{"wcet-recursion", LevelWarning, f.Display, f.Line,
+				fmt.Sprintf("%s is recursive; its stack depth and WCET depend on the input, so rewrite it as a bounded loop", f.Display)})
+		}
+	}
+
+	sort.SliceStable(a.Findings, func(i, j int) bool { return a.Findings[i].Line < a.Findings[j].Line })
+	return a
+}
+
+// allocatingFunctions maps each defined function that allocates, directly or through its callees, to the allocator
+func allocatingFunctions(functions []*irFunction, byName map[string]*irFunction) map[string]string {
+	allocates := make(map[string]string)
+	for changed := true; changed; {
+		changed = false
+		for _, f := range functions {
+			if allocates[f.Name] != "" {
+				continue
+			}
+			for _, c := range f.Calls {
+				via := irAllocators[c.Callee]
+				if via == "" && byName[c.Callee] != nil {
+					via = allocates[c.Callee]
+				}
+				if via != "" {
+					allocates[f.Name], changed = via, true
+					break
+				}
+			}
+		}
+	}
+	return allocates
+}
+
+// recursiveFunctions returns the functions that can call themselves, directly or through others,
+// using Tarjan's strongly connected components over the direct calls
+func recursiveFunctions(functions []*irFunction, byName map[string]*irFunction) []*irFunction {
+	index := make(map[*irFunction]int)
+	low := make(map[*irFunction]int)
+	onStack := make(map[*irFunction]bool)
+	var stack, recursive []*irFunction
+	next := 0
+
+	var visit func(f *irFunction)
+	visit = func(f *irFunction) {
+		index[f], low[f] = next, next
+		next++
+		stack = append(stack, f)
+		onStack[f] = true
+		self := false
+		for _, c := range f.Calls {
+			callee := byName[c.Callee]
+			if callee == nil {
+				continue
+			}
+			if callee == f {
+				self = true
+			}
+			if _, seen := index[callee]; !seen {
+				visit(callee)
+				low[f] = min(low[f], low[callee])
+			} else if onStack[callee] {
+				low[f] = min(low[f], index[callee])
+			}
+		}
+		if low[f] != index[f] {
+			return
+		}
+		var component []*irFunction
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == f {
+				break
+			}
+		}
+		if len(component) > 1 || self {
+			recursive = append(recursive, component...)
+		}
+	}
+	for _, f := range functions {
+		if _, seen := index[f]; !seen {
+			visit(f)
+		}
+	}
+	return recursive
+}
+
+// Summary describes the analysis in one line
+func (a WCETAnalysis) Summary(deadlineUs int) string {
+	return fmt.Sprintf("Real-time analysis (deadline: %dus): %d loop(s): %d with a constant bound, %d bounded by input, %d without a provable bound; "+
+		"%d recursive function(s); %d indirect call(s) and %d allocation(s) on hot paths",
+		deadlineUs, a.Loops, a.ConstantLoops, a.InputLoops, a.Unbound, a.Recursive, a.Dispatch, a.Allocations)
+}
+
+// warnings counts the findings that are not notes
+func (a WCETAnalysis) warnings() int {
+	n := 0
+	for _, f := range a.Findings {
+		if f.Level != LevelNote {
+			n++
+		}
+	}
+	return n
+}
+
+// Metrics are the analysis's counts for the domain validation result
+func (a WCETAnalysis) Metrics(deadlineUs int) map[string]interface{} {
+	return map[string]interface{}{
+		"deadline_us":         deadlineUs,
+		"loops":               a.Loops,
+		"constant_loops":      a.ConstantLoops,
+		"input_bounded_loops": a.InputLoops,
+		"unbounded_loops":     a.Unbound,
+		"recursive_functions": a.Recursive,
+		"dynamic_dispatch":    a.Dispatch,
+		"hot_allocations":     a.Allocations,
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// wcetIR is clang -O1 -g output, trimmed to what the pass reads, for:
+//
+//	3  int sum(const int* a, int n) {
+//	5      for (int i = 0; i < n; ++i)
+//	6          s += a[i];
+//	10 void poll(volatile int* flag) {
+//	11     while (!*flag) {}
+//	14 int fact(int n) {
+//	15     if (n < 2) return 1;
+//	16     return n * fact(n - 1);
+//	20 void update(Shape** shapes, std::vector<int>& v) {
+//	21     for (int i = 0; i < 16; ++i) {
+//	22         shapes[i]->draw();
+//	23         v.push_back(i);
+//	25     int* p = new int;
+const wcetIR = `define dso_local noundef i32 @_Z3sumPKii(ptr nocapture noundef readonly %a, i32 noundef %n) local_unnamed_addr #0 !dbg !10 {
+entry:
+  %cmp4 = icmp sgt i32 %n, 0, !dbg !20
+  br i1 %cmp4, label %for.body.preheader, label %for.cond.cleanup, !dbg !21
+
+for.body.preheader:                               ; preds = %entry
+  %wide.trip.count = zext nneg i32 %n to i64, !dbg !20
+  br label %for.body, !dbg !21
+
+for.cond.cleanup:                                 ; preds = %for.body, %entry
+  %s.0.lcssa = phi i32 [ 0, %entry ], [ %add, %for.body ], !dbg !22
+  ret i32 %s.0.lcssa, !dbg !23
+
+for.body:                                         ; preds = %for.body.preheader, %for.body
+  %indvars.iv = phi i64 [ 0, %for.body.preheader ], [ %indvars.iv.next, %for.body ]
+  %s.05 = phi i32 [ 0, %for.body.preheader ], [ %add, %for.body ]
+  %arrayidx = getelementptr inbounds i32, ptr %a, i64 %indvars.iv, !dbg !24
+  %0 = load i32, ptr %arrayidx, align 4, !dbg !24
+  %add = add nsw i32 %0, %s.05, !dbg !25
+  %indvars.iv.next = add nuw nsw i64 %indvars.iv, 1, !dbg !26
+  %exitcond.not = icmp eq i64 %indvars.iv.next, %wide.trip.count, !dbg !20
+  br i1 %exitcond.not, label %for.cond.cleanup, label %for.body, !dbg !21, !llvm.loop !27
+}
+
+define dso_local void @_Z4pollPVi(ptr noundef %flag) local_unnamed_addr #0 !dbg !30 {
+entry:
+  br label %while.cond, !dbg !31
+
+while.cond:                                       ; preds = %while.cond, %entry
+  %0 = load volatile i32, ptr %flag, align 4, !dbg !32
+  %tobool.not = icmp eq i32 %0, 0, !dbg !32
+  br i1 %tobool.not, label %while.cond, label %while.end, !dbg !31, !llvm.loop !33
+
+while.end:                                        ; preds = %while.cond
+  ret void, !dbg !34
+}
+
+define dso_local noundef i32 @_Z4facti(i32 noundef %n) local_unnamed_addr #0 !dbg !40 {
+entry:
+  %cmp = icmp slt i32 %n, 2, !dbg !41
+  br i1 %cmp, label %return, label %if.end, !dbg !41
+
+if.end:                                           ; preds = %entry
+  %sub = add nsw i32 %n, -1, !dbg !42
+  %call = tail call noundef i32 @_Z4facti(i32 noundef %sub), !dbg !42
+  %mul = mul nsw i32 %call, %n, !dbg !42
+  br label %return, !dbg !42
+
+return:                                           ; preds = %entry, %if.end
+  %retval.0 = phi i32 [ %mul, %if.end ], [ 1, %entry ]
+  ret i32 %retval.0, !dbg !43
+}
+
+define dso_local void @_Z6updatePP5Shape(ptr noundef %shapes, ptr noundef %v) local_unnamed_addr #0 !dbg !50 {
+entry:
+  br label %for.body, !dbg !51
+
+for.body:                                         ; preds = %entry, %for.body
+  %i.07 = phi i32 [ 0, %entry ], [ %inc, %for.body ]
+  %idx = zext nneg i32 %i.07 to i64, !dbg !52
+  %arrayidx = getelementptr inbounds ptr, ptr %shapes, i64 %idx, !dbg !52
+  %0 = load ptr, ptr %arrayidx, align 8, !dbg !52
+  %vtable = load ptr, ptr %0, align 8, !dbg !52
+  %1 = load ptr, ptr %vtable, align 8, !dbg !52
+  call void %1(ptr noundef nonnull align 8 dereferenceable(8) %0), !dbg !52
+  call void @_ZNSt6vectorIiSaIiEE9push_backERKi(ptr noundef nonnull align 8 dereferenceable(24) %v, ptr noundef nonnull align 4 dereferenceable(4) %arrayidx), !dbg !53
+  %inc = add nuw nsw i32 %i.07, 1, !dbg !54
+  %exitcond.not = icmp eq i32 %inc, 16, !dbg !55
+  br i1 %exitcond.not, label %for.end, label %for.body, !dbg !51, !llvm.loop !56
+
+for.end:                                          ; preds = %for.body
+  %p = tail call noalias noundef nonnull dereferenceable(4) ptr @_Znwm(i64 noundef 4), !dbg !57
+  ret void, !dbg !58
+}
+
+define linkonce_odr dso_local void @_ZNSt6vectorIiSaIiEE9push_backERKi(ptr noundef nonnull align 8 dereferenceable(24) %this, ptr noundef nonnull align 4 dereferenceable(4) %x) local_unnamed_addr #0 comdat align 2 !dbg !60 {
+entry:
+  %call = tail call noalias noundef nonnull ptr @_Znwm(i64 noundef 4), !dbg !61
+  ret void, !dbg !61
+}
+
+declare noundef nonnull ptr @_Znwm(i64 noundef) local_unnamed_addr #1
+
+!1 = !DIFile(filename: "/src/code.cpp", directory: "/tmp")
+!2 = !DIFile(filename: "/usr/include/c++/v1/vector", directory: "")
+!10 = distinct !DISubprogram(name: "sum", linkageName: "_Z3sumPKii", scope: !1, file: !1, line: 3, type: !11, scopeLine: 3, spFlags: DISPFlagDefinition | DISPFlagOptimized, unit: !0)
+!11 = !DISubroutineType(types: !12)
+!12 = !{}
+!20 = !DILocation(line: 5, column: 23, scope: !10)
+!21 = !DILocation(line: 5, column: 5, scope: !10)
+!22 = !DILocation(line: 0, scope: !10)
+!23 = !DILocation(line: 7, column: 5, scope: !10)
+!24 = !DILocation(line: 6, column: 14, scope: !10)
+!25 = !DILocation(line: 6, column: 11, scope: !10)
+!26 = !DILocation(line: 5, column: 29, scope: !10)
+!27 = distinct !{!27, !21, !28, !29}
+!28 = !DILocation(line: 6, column: 18, scope: !10)
+!29 = !{!"llvm.loop.mustprogress"}
+!30 = distinct !DISubprogram(name: "poll", linkageName: "_Z4pollPVi", scope: !1, file: !1, line: 10, type: !11, scopeLine: 10, spFlags: DISPFlagDefinition | DISPFlagOptimized, unit: !0)
+!31 = !DILocation(line: 11, column: 5, scope: !30)
+!32 = !DILocation(line: 11, column: 12, scope: !30)
+!33 = distinct !{!33, !31, !32}
+!34 = !DILocation(line: 12, column: 1, scope: !30)
+!40 = distinct !DISubprogram(name: "fact", linkageName: "_Z4facti", scope: !1, file: !1, line: 14, type: !11, scopeLine: 14, spFlags: DISPFlagDefinition | DISPFlagOptimized, unit: !0)
+!41 = !DILocation(line: 15, column: 11, scope: !40)
+!42 = !DILocation(line: 16, column: 12, scope: !40)
+!43 = !DILocation(line: 17, column: 1, scope: !40)
+!50 = distinct !DISubprogram(name: "update", linkageName: "_Z6updatePP5Shape", scope: !1, file: !1, line: 20, type: !11, scopeLine: 20, spFlags: DISPFlagDefinition | DISPFlagOptimized, unit: !0)
+!51 = !DILocation(line: 21, column: 5, scope: !50)
+!52 = !DILocation(line: 22, column: 19, scope: !50)
+!53 = !DILocation(line: 23, column: 11, scope: !50)
+!54 = !DILocation(line: 21, column: 29, scope: !50)
+!55 = !DILocation(line: 21, column: 23, scope: !50)
+!56 = distinct !{!56, !51, !53, !29}
+!57 = !DILocation(line: 25, column: 14, scope: !50)
+!58 = !DILocation(line: 26, column: 1, scope: !50)
+!60 = distinct !DISubprogram(name: "push_back", linkageName: "_ZNSt6vectorIiSaIiEE9push_backERKi", scope: !2, file: !2, line: 1187, type: !11, scopeLine: 1187, spFlags: DISPFlagDefinition | DISPFlagOptimized, unit: !0)
+!61 = !DILocation(line: 1188, column: 9, scope: !60)
+`
+
+func TestAnalyzeWCET(t *testing.T) {
+	a := analyzeWCET(wcetIR, "code.cpp")
+	want := []string{
+		"/src/code.cpp:5:1: note: loop in sum runs up to 'n' times; its WCET grows with that value, so bound it for the deadline [wcet-input-bound]",
+		"/src/code.cpp:11:1: warning: loop in poll has no provable bound",
+		"/src/code.cpp:14:1: warning: fact is recursive",
+		"/src/code.cpp:22:1: warning: indirect call (virtual function, function pointer or std::function) on a hot path in update",
+		"/src/code.cpp:23:1: warning: call to push_back on a hot path in update allocates with operator new",
+	}
+	if len(a.Findings) != len(want) {
+		t.Fatalf("analyzeWCET() = %v, want %d findings", a.Findings, len(want))
+	}
+	for i, f := range a.Findings {
+		if got := f.String("code.cpp"); !strings.HasPrefix(got, want[i]) {
+			t.Errorf("finding %d = %q, want %q...", i, got, want[i])
+		}
+	}
+	if a.Loops != 3 || a.ConstantLoops != 1 || a.InputLoops != 1 || a.Unbound != 1 || a.Recursive != 1 || a.Dispatch != 1 || a.Allocations != 1 {
+		t.Errorf("analyzeWCET() counts = %+v", a)
+	}
+	if a.warnings() != 4 {
+		t.Errorf("warnings() = %d, want 4", a.warnings())
+	}
+}
+
+func TestParseIR(t *testing.T) {
+	m := parseIR(wcetIR, "code.cpp")
+	if len(m.Functions) != 5 {
+		t.Fatalf("parseIR() found %d functions, want 5", len(m.Functions))
+	}
+	sum, pushBack := m.Functions[0], m.Functions[4]
+	if sum.Display != "sum" || sum.Line != 3 || !sum.User || !sum.Args["%n"] || len(sum.Blocks) != 4 {
+		t.Errorf("sum = %+v", sum)
+	}
+	if pushBack.Display != "push_back" || pushBack.User {
+		t.Errorf("push_back = %+v, want a library function", pushBack)
+	}
+	loops := m.loops(sum)
+	if len(loops) != 1 || loops[0].Line != 5 || loops[0].Bound != boundInput || loops[0].Limit != "n" {
+		t.Errorf("loops(sum) = %+v, want one loop on line 5 bounded by n", loops)
+	}
+}
+
+func TestRecursiveFunctions(t *testing.T) {
+	ir := `define void @a() {
+  call void @b()
+  ret void
+}
+
+define void @b() {
+  call void @a()
+  ret void
+}
+
+define void @c() {
+  call void @a()
+  ret void
+}
+`
+	m := parseIR(ir, "code.cpp")
+	byName := make(map[string]*irFunction)
+	for _, f := range m.Functions {
+		byName[f.Name] = f
+	}
+	var names []string
+	for _, f := range recursiveFunctions(m.Functions, byName) {
+		names = append(names, f.Name)
+	}
+	if got := strings.Join(names, " "); got != "@b @a" {
+		t.Errorf("recursiveFunctions() = %q, want the mutually recursive @a and @b", got)
+	}
+}
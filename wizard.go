@@ -0,0 +1,361 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/x/term"
+)
+
+// initProviders are offered by `bjarne init`, in display order
+var initProviders = []struct {
+	Type        ProviderType
+	Description string
+}{
+	{ProviderBedrock, "AWS Bedrock (credentials from the environment or ~/.aws)"},
+	{ProviderAnthropic, "Anthropic API"},
+	{ProviderOpenAI, "OpenAI API"},
+	{ProviderGemini, "Google Gemini API"},
+	{ProviderLocal, "Local OpenAI-compatible server (Ollama, llama.cpp)"},
+}
+
+// initCheckTimeout bounds the test request that checks the provider's credentials
+const initCheckTimeout = 60 * time.Second
+
+// initTestProgram is validated to check that the container works end to end
+const initTestProgram = "#include <cstdio>\n\nint main() {\n    std::puts(\"hello from bjarne\");\n    return 0;\n}\n"
+
+// initWizard asks the setup questions on a terminal
+type initWizard struct {
+	in         *bufio.Reader
+	out        io.Writer
+	readSecret func() (string, error) // Reads a line without echoing it
+}
+
+// ask prints a question and returns the answer, or def when the answer is blank
+func (w *initWizard) ask(question, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(w.out, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(w.out, "%s: ", question)
+	}
+	answer, err := w.in.ReadString('\n')
+	if err != nil && (err != io.EOF || answer == "") {
+		return "", fmt.Errorf("failed to read answer: %w", err)
+	}
+	if answer = strings.TrimSpace(answer); answer == "" {
+		return def, nil
+	}
+	return answer, nil
+}
+
+// confirm asks a yes/no question
+func (w *initWizard) confirm(question string, def bool) (bool, error) {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	fmt.Fprintf(w.out, "%s [%s] ", question, hint)
+	answer, err := w.in.ReadString('\n')
+	if err != nil && (err != io.EOF || answer == "") {
+		return false, fmt.Errorf("failed to read answer: %w", err)
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "":
+		return def, nil
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
+
+// chooseProvider asks for a provider by number or name
+func (w *initWizard) chooseProvider(current ProviderType) (ProviderType, error) {
+	def := "1"
+	fmt.Fprintln(w.out, "Which LLM provider should bjarne use?")
+	for i, p := range initProviders {
+		fmt.Fprintf(w.out, "  %d. %-10s %s\n", i+1, p.Type, p.Description)
+		if p.Type == current {
+			def = fmt.Sprint(i + 1)
+		}
+	}
+	for {
+		answer, err := w.ask("Provider", def)
+		if err != nil {
+			return "", err
+		}
+		for i, p := range initProviders {
+			if answer == fmt.Sprint(i+1) || strings.EqualFold(answer, string(p.Type)) {
+				return p.Type, nil
+			}
+		}
+		fmt.Fprintf(w.out, "Choose 1-%d or a provider name.\n", len(initProviders))
+	}
+}
+
+// providerKeyEnv returns the variable an API key was found in for a provider ("" = none)
+// The provider-specific variable wins, as for /compare
+func providerKeyEnv(p ProviderType) string {
+	for _, name := range []string{"BJARNE_" + strings.ToUpper(string(p)) + "_API_KEY", "BJARNE_API_KEY"} {
+		if os.Getenv(name) != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// credentials fills in the provider configuration, asking for whatever the environment lacks
+// It returns the lines the user should add to their shell profile
+func (w *initWizard) credentials(pcfg *ProviderConfig) ([]string, error) {
+	switch pcfg.Provider {
+	case ProviderBedrock:
+		region, err := w.ask("AWS region", firstNonEmpty(os.Getenv("AWS_REGION"), "us-east-1"))
+		if err != nil {
+			return nil, err
+		}
+		pcfg.Region = region
+		if os.Getenv("AWS_REGION") != region {
+			return []string{"export AWS_REGION=" + region}, nil
+		}
+		return nil, nil
+
+	case ProviderLocal:
+		url, err := w.ask("Server URL", firstNonEmpty(pcfg.Local.BaseURL, defaultLocalBaseURL))
+		if err != nil {
+			return nil, err
+		}
+		model, err := w.ask("Model", firstNonEmpty(pcfg.Local.Model, defaultLocalModel))
+		if err != nil {
+			return nil, err
+		}
+		pcfg.Local.BaseURL, pcfg.Local.Model = url, model
+		return nil, nil
+	}
+
+	if name := providerKeyEnv(pcfg.Provider); name != "" {
+		pcfg.APIKey = os.Getenv(name)
+		fmt.Fprintf(w.out, "Using the API key in $%s\n", name)
+		return nil, nil
+	}
+	fmt.Fprint(w.out, "API key (not shown, not saved): ")
+	key, err := w.readSecret()
+	fmt.Fprintln(w.out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API key: %w", err)
+	}
+	pcfg.APIKey = strings.TrimSpace(key)
+	if pcfg.APIKey == "" {
+		return nil, fmt.Errorf("an API key is required for %s", pcfg.Provider)
+	}
+	return []string{"export BJARNE_API_KEY=<your key>"}, nil
+}
+
+// firstNonEmpty returns the first non-empty value
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// checkProvider sends a tiny request to prove the credentials work
+func checkProvider(ctx context.Context, pcfg *ProviderConfig) (string, error) {
+	provider, err := NewProvider(ctx, pcfg)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(ctx, initCheckTimeout)
+	defer cancel()
+	model := provider.DefaultModel()
+	if _, err := provider.Generate(ctx, model, "", []Message{{Role: "user", Content: "Reply with OK."}}, 16); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s (%s)", provider.Name(), shortModelName(model)), nil
+}
+
+// initStep is one line of the wizard's closing summary
+type initStep struct {
+	Name   string
+	OK     bool
+	Detail string
+}
+
+// formatInitSummary renders the wizard's results
+func formatInitSummary(steps []initStep) string {
+	var sb strings.Builder
+	for _, s := range steps {
+		mark := "\033[92m✓\033[0m"
+		if !s.OK {
+			mark = "\033[93m!\033[0m"
+		}
+		fmt.Fprintf(&sb, "  %s %-12s %s\n", mark, s.Name, s.Detail)
+	}
+	return sb.String()
+}
+
+// runInit implements `bjarne init`: guided first-run setup
+func runInit(args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintf(os.Stderr, "Unknown init option: %s\nUsage: bjarne init\n", args[0])
+		return 1
+	}
+	w := &initWizard{in: bufio.NewReader(os.Stdin), out: os.Stdout}
+	w.readSecret = func() (string, error) {
+		if !term.IsTerminal(os.Stdin.Fd()) {
+			return w.in.ReadString('\n')
+		}
+		key, err := term.ReadPassword(os.Stdin.Fd())
+		return string(key), err
+	}
+	if err := w.run(context.Background()); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// run asks the questions, checks each part of the setup and writes the settings
+func (w *initWizard) run(ctx context.Context) error {
+	settings, err := LoadSettings()
+	if err != nil {
+		return fmt.Errorf("existing settings are invalid (fix or remove them first): %w", err)
+	}
+	cfg := LoadConfig()
+	var steps []initStep
+
+	fmt.Fprintln(w.out, "bjarne setup")
+	fmt.Fprintln(w.out, "Press Enter to accept the [default] answer.")
+	fmt.Fprintln(w.out)
+
+	// Provider and credentials
+	providerType, err := w.chooseProvider(cfg.Provider)
+	if err != nil {
+		return err
+	}
+	pcfg := cfg.GetProviderConfig()
+	pcfg.Provider = providerType
+	pcfg.Local = settings.Local
+	exports, err := w.credentials(pcfg)
+	if err != nil {
+		return err
+	}
+	if env := os.Getenv("BJARNE_PROVIDER"); env != "" && ParseProviderType(env) != providerType {
+		exports = append(exports, "unset BJARNE_PROVIDER   # it overrides the provider in settings.json")
+	}
+	SetNetworkSettings(settings.Network)
+	fmt.Fprintln(w.out, "Checking credentials...")
+	if name, err := checkProvider(ctx, pcfg); err != nil {
+		fmt.Fprint(w.out, FormatUserError(err))
+		steps = append(steps, initStep{"provider", false, fmt.Sprintf("%s: %s", providerType, truncateError(err.Error(), 80))})
+	} else {
+		fmt.Fprintf(w.out, "\033[92m✓\033[0m %s answered\n", name)
+		steps = append(steps, initStep{"provider", true, name})
+	}
+	fmt.Fprintln(w.out)
+
+	// Container runtime, image and a trivial validation
+	steps = append(steps, w.setupContainer(ctx, settings)...)
+
+	// Settings
+	settings.Provider = string(providerType)
+	if providerType == ProviderLocal {
+		settings.Local = pcfg.Local
+	}
+	path, _ := SettingsPath()
+	if err := SaveSettings(settings); err != nil {
+		steps = append(steps, initStep{"settings", false, err.Error()})
+	} else {
+		steps = append(steps, initStep{"settings", true, "written to " + path})
+	}
+
+	// Workspace index
+	if cwd, err := os.Getwd(); err == nil {
+		index, err := w.confirm(fmt.Sprintf("Index %s for context-aware generation?", cwd), false)
+		if err != nil {
+			return err
+		}
+		if index {
+			steps = append(steps, indexForInit(cwd))
+		}
+	}
+
+	fmt.Fprintln(w.out)
+	fmt.Fprintln(w.out, "Setup summary:")
+	fmt.Fprint(w.out, formatInitSummary(steps))
+	if len(exports) > 0 {
+		fmt.Fprintln(w.out)
+		fmt.Fprintln(w.out, "Add to your shell profile (keys are never written to settings.json):")
+		for _, line := range exports {
+			fmt.Fprintln(w.out, "  "+line)
+		}
+	}
+	fmt.Fprintln(w.out)
+	fmt.Fprintln(w.out, "Run 'bjarne' to start, or 'bjarne init' again to change these answers.")
+	return nil
+}
+
+// setupContainer finds the container runtime, pulls the validator image if needed,
+// and validates a hello-world program to prove the pipeline works
+func (w *initWizard) setupContainer(ctx context.Context, settings *Settings) []initStep {
+	container, err := DetectContainerRuntime()
+	if err != nil {
+		fmt.Fprint(w.out, FormatUserError(err))
+		return []initStep{{"container", false, "no podman or docker found"}}
+	}
+	steps := []initStep{{"container", true, container.GetBinary()}}
+
+	image, err := resolveSessionImage(settings.Container)
+	if err != nil {
+		return append(steps, initStep{"image", false, err.Error()})
+	}
+	container.SetImage(image.Ref)
+	if !container.ImageExists(ctx) {
+		pull, err := w.confirm(fmt.Sprintf("Pull the validator image %s (about 500MB)?", image.Ref), true)
+		if err != nil || !pull {
+			return append(steps, initStep{"image", false, "not pulled; bjarne offers to pull it on first validation"})
+		}
+		if err := container.PullImage(ctx); err != nil {
+			return append(steps, initStep{"image", false, err.Error()})
+		}
+	}
+	steps = append(steps, initStep{"image", true, image.Ref})
+
+	fmt.Fprintln(w.out, "Validating a test program...")
+	container.SetFormatSettings(settings.Format)
+	container.SetGateSelection(GateSelection{Only: []string{"compile", "run"}})
+	results, err := container.ValidateCode(ctx, initTestProgram, "hello.cpp")
+	switch {
+	case err != nil:
+		steps = append(steps, initStep{"validation", false, err.Error()})
+	case !allPassed(results):
+		for _, r := range results {
+			if !r.Success {
+				steps = append(steps, initStep{"validation", false, r.Stage + ": " + truncateError(firstLines(r.Error, 1), 80)})
+				break
+			}
+		}
+	default:
+		steps = append(steps, initStep{"validation", true, "hello world compiled and ran"})
+	}
+	fmt.Fprintln(w.out)
+	return steps
+}
+
+// indexForInit builds the structural workspace index, as /init does
+func indexForInit(cwd string) initStep {
+	index, err := IndexWorkspace(cwd, nil)
+	if err == nil {
+		err = SaveIndex(index, cwd)
+	}
+	if err != nil {
+		return initStep{"index", false, err.Error()}
+	}
+	return initStep{"index", true, fmt.Sprintf("%d files in %s (run /init in bjarne for the semantic index)", index.Summary.TotalFiles, IndexFileName)}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// newTestWizard answers the wizard's questions from input
+func newTestWizard(input, secret string) (*initWizard, *strings.Builder) {
+	out := &strings.Builder{}
+	return &initWizard{
+		in:  bufio.NewReader(strings.NewReader(input)),
+		out: out,
+		readSecret: func() (string, error) {
+			if secret == "" {
+				return "", errors.New("no terminal")
+			}
+			return secret, nil
+		},
+	}, out
+}
+
+func TestInitWizardChooseProvider(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		current ProviderType
+		want    ProviderType
+	}{
+		{"default is the current provider", "\n", ProviderGemini, ProviderGemini},
+		{"by number", "2\n", ProviderBedrock, ProviderAnthropic},
+		{"by name", "Local\n", ProviderBedrock, ProviderLocal},
+		{"asks again after a bad answer", "9\nopenai\n", ProviderBedrock, ProviderOpenAI},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, _ := newTestWizard(tt.input, "")
+			got, err := w.chooseProvider(tt.current)
+			if err != nil || got != tt.want {
+				t.Errorf("chooseProvider() = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+
+	w, _ := newTestWizard("", "")
+	if _, err := w.chooseProvider(ProviderBedrock); err == nil {
+		t.Error("chooseProvider() should fail when input ends")
+	}
+}
+
+func TestInitWizardConfirm(t *testing.T) {
+	w, _ := newTestWizard("\nn\nyes\n", "")
+	for i, want := range []bool{true, false, true} {
+		if got, err := w.confirm("Continue?", true); err != nil || got != want {
+			t.Errorf("answer %d: confirm() = %t, %v; want %t", i, got, err, want)
+		}
+	}
+}
+
+func TestInitWizardCredentials(t *testing.T) {
+	t.Setenv("BJARNE_API_KEY", "")
+	t.Setenv("BJARNE_OPENAI_API_KEY", "")
+	t.Setenv("AWS_REGION", "eu-west-1")
+
+	t.Run("local server", func(t *testing.T) {
+		w, _ := newTestWizard("http://gpu:8080/v1\n\n", "")
+		pcfg := &ProviderConfig{Provider: ProviderLocal}
+		exports, err := w.credentials(pcfg)
+		if err != nil || len(exports) != 0 {
+			t.Fatalf("credentials() = %v, %v", exports, err)
+		}
+		if pcfg.Local.BaseURL != "http://gpu:8080/v1" || pcfg.Local.Model != defaultLocalModel {
+			t.Errorf("local settings = %+v", pcfg.Local)
+		}
+	})
+
+	t.Run("bedrock region", func(t *testing.T) {
+		w, _ := newTestWizard("us-west-2\n", "")
+		pcfg := &ProviderConfig{Provider: ProviderBedrock}
+		exports, err := w.credentials(pcfg)
+		if err != nil || pcfg.Region != "us-west-2" || len(exports) != 1 || exports[0] != "export AWS_REGION=us-west-2" {
+			t.Errorf("credentials() = %v, %v, region %q", exports, err, pcfg.Region)
+		}
+	})
+
+	t.Run("key from the environment", func(t *testing.T) {
+		t.Setenv("BJARNE_OPENAI_API_KEY", "sk-env")
+		w, out := newTestWizard("", "")
+		pcfg := &ProviderConfig{Provider: ProviderOpenAI}
+		if exports, err := w.credentials(pcfg); err != nil || len(exports) != 0 || pcfg.APIKey != "sk-env" {
+			t.Errorf("credentials() = %v, %v, key %q", exports, err, pcfg.APIKey)
+		}
+		if !strings.Contains(out.String(), "$BJARNE_OPENAI_API_KEY") {
+			t.Errorf("output should name the variable: %q", out.String())
+		}
+	})
+
+	t.Run("key typed in", func(t *testing.T) {
+		w, out := newTestWizard("", "sk-typed\n")
+		pcfg := &ProviderConfig{Provider: ProviderAnthropic}
+		exports, err := w.credentials(pcfg)
+		if err != nil || pcfg.APIKey != "sk-typed" || len(exports) != 1 {
+			t.Errorf("credentials() = %v, %v, key %q", exports, err, pcfg.APIKey)
+		}
+		if strings.Contains(out.String()+strings.Join(exports, ""), "sk-typed") {
+			t.Error("the key must not be echoed")
+		}
+	})
+
+	t.Run("no key", func(t *testing.T) {
+		w, _ := newTestWizard("", "")
+		if _, err := w.credentials(&ProviderConfig{Provider: ProviderGemini}); err == nil {
+			t.Error("credentials() should fail without a key")
+		}
+	})
+}
+
+func TestFormatInitSummary(t *testing.T) {
+	got := formatInitSummary([]initStep{
+		{"provider", true, "anthropic (sonnet)"},
+		{"image", false, "not pulled"},
+	})
+	if !strings.Contains(got, "✓\033[0m provider     anthropic (sonnet)") || !strings.Contains(got, "!\033[0m image        not pulled") {
+		t.Errorf("formatInitSummary() = %q", got)
+	}
+}
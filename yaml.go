@@ -0,0 +1,360 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseYAML reads the subset of YAML that bjarne's task files use into maps, slices and scalars:
+// block mappings and sequences, plain and quoted scalars, [a, b] flow sequences,
+// | and > block scalars, and # comments. Anchors, tags and multiple documents are not supported
+func parseYAML(data string) (any, error) {
+	p := &yamlParser{lines: strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n")}
+	p.skip()
+	if p.pos >= len(p.lines) {
+		return nil, nil
+	}
+	if strings.TrimSpace(p.lines[p.pos]) == "---" {
+		p.pos++
+		p.skip()
+	}
+	v, err := p.node(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.skip(); p.pos < len(p.lines) {
+		return nil, p.errorf("unexpected indentation")
+	}
+	return v, nil
+}
+
+// yamlParser walks the lines of a document; pos is the next unread line
+type yamlParser struct {
+	lines []string
+	pos   int
+}
+
+// errorf reports a problem at the current line
+func (p *yamlParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("line %d: %s", p.pos+1, fmt.Sprintf(format, args...))
+}
+
+// skip moves past blank and comment-only lines
+func (p *yamlParser) skip() {
+	for p.pos < len(p.lines) {
+		text := strings.TrimSpace(p.lines[p.pos])
+		if text != "" && !strings.HasPrefix(text, "#") {
+			return
+		}
+		p.pos++
+	}
+}
+
+// peek returns the indentation and content of the next significant line (ok = false at the end)
+func (p *yamlParser) peek() (int, string, bool) {
+	p.skip()
+	if p.pos >= len(p.lines) {
+		return 0, "", false
+	}
+	line := p.lines[p.pos]
+	if strings.HasPrefix(strings.TrimLeft(line, " "), "\t") {
+		return 0, "", false
+	}
+	indent := len(line) - len(strings.TrimLeft(line, " "))
+	return indent, stripYAMLComment(line[indent:]), true
+}
+
+// isSeqItem reports whether a line starts a sequence item
+func isSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// node parses the block starting at the next significant line, which must be indented at least minIndent
+func (p *yamlParser) node(minIndent int) (any, error) {
+	indent, text, ok := p.peek()
+	if !ok || indent < minIndent {
+		return nil, nil
+	}
+	if isSeqItem(text) {
+		return p.sequence(indent)
+	}
+	return p.mapping(indent)
+}
+
+// sequence parses "- item" lines at indent
+func (p *yamlParser) sequence(indent int) ([]any, error) {
+	var items []any
+	for {
+		i, text, ok := p.peek()
+		if !ok || i != indent || !isSeqItem(text) {
+			return items, nil
+		}
+		rest := strings.TrimLeft(strings.TrimPrefix(text, "-"), " ")
+		if rest == "" {
+			p.pos++
+			item, err := p.node(indent + 1)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+			continue
+		}
+		if _, _, isKey := splitYAMLKey(rest); isKey {
+			// "- key: value" starts a mapping indented to where the key is
+			itemIndent := indent + len(text) - len(rest)
+			p.lines[p.pos] = strings.Repeat(" ", itemIndent) + rest
+			item, err := p.mapping(itemIndent)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+			continue
+		}
+		value, err := p.scalarOrBlock(rest, indent)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, value)
+	}
+}
+
+// mapping parses "key: value" lines at indent
+func (p *yamlParser) mapping(indent int) (map[string]any, error) {
+	m := make(map[string]any)
+	for {
+		i, text, ok := p.peek()
+		if !ok || i < indent || (i == indent && isSeqItem(text)) {
+			return m, nil
+		}
+		if i > indent {
+			return nil, p.errorf("unexpected indentation")
+		}
+		key, value, isKey := splitYAMLKey(text)
+		if !isKey {
+			return nil, p.errorf("expected \"key: value\", got %q", text)
+		}
+		if _, dup := m[key]; dup {
+			return nil, p.errorf("duplicate key %q", key)
+		}
+		if value != "" {
+			v, err := p.scalarOrBlock(value, indent)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = v
+			continue
+		}
+
+		// Nested block: deeper lines, or a sequence at the same indentation
+		p.pos++
+		next, nextText, ok := p.peek()
+		switch {
+		case ok && next > indent:
+			v, err := p.node(next)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = v
+		case ok && next == indent && isSeqItem(nextText):
+			v, err := p.sequence(indent)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = v
+		default:
+			m[key] = nil
+		}
+	}
+}
+
+// scalarOrBlock parses the value on the current line (consuming it), reading a block scalar's
+// following lines when the value is a | or > indicator; parentIndent is the indentation of its key
+func (p *yamlParser) scalarOrBlock(value string, parentIndent int) (any, error) {
+	line := p.pos
+	p.pos++
+	if value[0] != '|' && value[0] != '>' {
+		v, err := parseYAMLScalar(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line+1, err)
+		}
+		return v, nil
+	}
+
+	chomp := strings.TrimLeft(value[1:], "0123456789")
+	if chomp != "" && chomp != "-" && chomp != "+" {
+		return nil, fmt.Errorf("line %d: unsupported block scalar header %q", line+1, value)
+	}
+	var body []string
+	blockIndent := -1
+	for ; p.pos < len(p.lines); p.pos++ {
+		raw := p.lines[p.pos]
+		trimmed := strings.TrimLeft(raw, " ")
+		indent := len(raw) - len(trimmed)
+		if trimmed == "" {
+			body = append(body, "")
+			continue
+		}
+		if indent <= parentIndent {
+			break
+		}
+		if blockIndent < 0 {
+			blockIndent = indent
+		}
+		if indent < blockIndent {
+			break
+		}
+		body = append(body, raw[blockIndent:])
+	}
+
+	// Trailing blank lines belong to the block only with the keep (+) indicator
+	content := len(body)
+	for content > 0 && body[content-1] == "" {
+		content--
+	}
+	trailing := len(body) - content
+	body = body[:content]
+
+	var text string
+	if value[0] == '|' {
+		text = strings.Join(body, "\n")
+	} else {
+		text = foldYAMLLines(body)
+	}
+	switch {
+	case content == 0:
+		return "", nil
+	case chomp == "-":
+	case chomp == "+":
+		text += strings.Repeat("\n", trailing+1)
+	default:
+		text += "\n"
+	}
+	return text, nil
+}
+
+// foldYAMLLines joins the lines of a > block: single line breaks become spaces,
+// each blank line becomes a line break, and more-indented lines keep their breaks
+func foldYAMLLines(lines []string) string {
+	var sb strings.Builder
+	for i, line := range lines {
+		switch {
+		case i == 0:
+		case line == "":
+			sb.WriteString("\n")
+			continue
+		case lines[i-1] == "":
+			// The blank line before already broke the line
+		case strings.HasPrefix(line, " ") || strings.HasPrefix(lines[i-1], " "):
+			sb.WriteString("\n")
+		default:
+			sb.WriteString(" ")
+		}
+		sb.WriteString(line)
+	}
+	return sb.String()
+}
+
+// splitYAMLKey splits "key: value" (or "key:") into its key and value
+func splitYAMLKey(text string) (string, string, bool) {
+	if text[0] == '"' || text[0] == '\'' {
+		end := strings.IndexByte(text[1:], text[0])
+		if end < 0 {
+			return "", "", false
+		}
+		key, rest := text[1:end+1], text[end+2:]
+		if rest != ":" && !strings.HasPrefix(rest, ": ") {
+			return "", "", false
+		}
+		return key, strings.TrimSpace(rest[1:]), true
+	}
+	if text[0] == '[' || text[0] == '{' {
+		return "", "", false
+	}
+	for i := 0; i < len(text); i++ {
+		if text[i] == ':' && (i == len(text)-1 || text[i+1] == ' ') {
+			return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+// stripYAMLComment removes a trailing " # comment" that is not inside quotes
+func stripYAMLComment(text string) string {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if c == '\\' && quote == '"' {
+				i++
+			}
+		case c == '"' || c == '\'':
+			if i == 0 || text[i-1] == ' ' || text[i-1] == '[' || text[i-1] == ',' {
+				quote = c
+			}
+		case c == '#' && (i == 0 || text[i-1] == ' '):
+			return strings.TrimRight(text[:i], " ")
+		}
+	}
+	return strings.TrimRight(text, " ")
+}
+
+// parseYAMLScalar converts a single-line value: quoted strings, [a, b] lists,
+// booleans, null, integers and numbers; anything else is a plain string
+func parseYAMLScalar(value string) (any, error) {
+	switch value[0] {
+	case '"':
+		s, err := strconv.Unquote(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quoted string %s", value)
+		}
+		return s, nil
+	case '\'':
+		if len(value) < 2 || value[len(value)-1] != '\'' {
+			return nil, fmt.Errorf("invalid quoted string %s", value)
+		}
+		return strings.ReplaceAll(value[1:len(value)-1], "''", "'"), nil
+	case '[':
+		if value[len(value)-1] != ']' {
+			return nil, fmt.Errorf("unterminated list %s", value)
+		}
+		items := []any{}
+		inner := strings.TrimSpace(value[1 : len(value)-1])
+		if inner == "" {
+			return items, nil
+		}
+		for _, item := range strings.Split(inner, ",") {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				return nil, fmt.Errorf("empty item in list %s", value)
+			}
+			v, err := parseYAMLScalar(item)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, v)
+		}
+		return items, nil
+	case '{':
+		return nil, fmt.Errorf("flow mappings are not supported: %s", value)
+	}
+
+	switch value {
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	case "null", "Null", "NULL", "~":
+		return nil, nil
+	}
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return f, nil
+	}
+	return value, nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	doc := `# Nightly katas
+defaults:
+  std: c++20
+  gates: [compile, asan, "ubsan"]
+tasks:
+  - name: fizzbuzz   # the classic
+    prompt: |
+      FizzBuzz up to n.
+
+      Print one value per line.
+    output: 'katas/fizz # buzz.cpp'
+  - name: lru
+    prompt: >-
+      An LRU cache
+      with O(1) get and put.
+    maxIterations: 2
+    gates:
+    - compile
+    - tsan
+  -
+    name: empty
+    prompt: "tab\tseparated"
+    model: ~
+`
+	got, err := parseYAML(doc)
+	if err != nil {
+		t.Fatalf("parseYAML() error = %v", err)
+	}
+	want := map[string]any{
+		"defaults": map[string]any{
+			"std":   "c++20",
+			"gates": []any{"compile", "asan", "ubsan"},
+		},
+		"tasks": []any{
+			map[string]any{
+				"name":   "fizzbuzz",
+				"prompt": "FizzBuzz up to n.\n\nPrint one value per line.\n",
+				"output": "katas/fizz # buzz.cpp",
+			},
+			map[string]any{
+				"name":          "lru",
+				"prompt":        "An LRU cache with O(1) get and put.",
+				"maxIterations": int64(2),
+				"gates":         []any{"compile", "tsan"},
+			},
+			map[string]any{
+				"name":   "empty",
+				"prompt": "tab\tseparated",
+				"model":  nil,
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseYAML() =\n%#v\nwant\n%#v", got, want)
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+	}{
+		{"bad indentation", "a: 1\n    b: 2\n"},
+		{"duplicate key", "a: 1\na: 2\n"},
+		{"not a mapping", "a: 1\njust text\n"},
+		{"flow mapping", "a: {b: 1}\n"},
+		{"unterminated list", "a: [1, 2\n"},
+		{"bad quote", "a: \"open\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseYAML(tt.doc); err == nil {
+				t.Errorf("parseYAML(%q) should fail", tt.doc)
+			}
+		})
+	}
+}